@@ -0,0 +1,47 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/mickamy/xplain/internal/gitdiff"
+)
+
+func changedCommand(args []string) error {
+	fs := flag.NewFlagSet("changed", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	fs.Usage = func() {
+		_, _ = fmt.Fprintf(os.Stdout, "Usage: xplain changed --since origin/main [--until HEAD] [--dir queries]\n\nOptions:\n")
+		fs.PrintDefaults()
+	}
+
+	var (
+		dir   = fs.String("dir", ".", "Directory to search for changed files")
+		since = fs.String("since", "origin/main", "Git ref to compare against (merge base)")
+		until = fs.String("until", "HEAD", "Git ref holding the changes")
+		ext   = fs.String("ext", ".sql", "File extension to report")
+	)
+
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			fs.SetOutput(os.Stdout)
+			fs.Usage()
+			return nil
+		}
+		return err
+	}
+
+	files, err := gitdiff.ChangedFiles(context.Background(), *dir, *since, *until, *ext)
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		_, _ = fmt.Fprintln(os.Stdout, filepath.Join(*dir, file))
+	}
+	return nil
+}
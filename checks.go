@@ -0,0 +1,46 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/mickamy/xplain/internal/assert"
+	"github.com/mickamy/xplain/internal/sqlfile"
+)
+
+// sqlChecks reads the "-- xplain:" directives of a statement: its run timeout and its assertions.
+func sqlChecks(sqlText string) (time.Duration, []assert.Assertion, error) {
+	directives, err := sqlfile.ParseDirectives(sqlText)
+	if err != nil {
+		return 0, nil, err
+	}
+	assertions := make([]assert.Assertion, 0, len(directives.Assertions))
+	for _, spec := range directives.Assertions {
+		a, err := assert.Parse(spec)
+		if err != nil {
+			return 0, nil, fmt.Errorf("directive: %w", err)
+		}
+		assertions = append(assertions, a)
+	}
+	return directives.Timeout, assertions, nil
+}
+
+// reportAssertions prints assertion outcomes and returns an exit error when any of them failed.
+func reportAssertions(w io.Writer, results []assert.Result) error {
+	if len(results) == 0 {
+		return nil
+	}
+	_, _ = fmt.Fprintln(w, "Assertions:")
+	for _, r := range results {
+		icon := "✅"
+		if !r.Passed {
+			icon = "❌"
+		}
+		_, _ = fmt.Fprintf(w, "  - %s %s: %s\n", icon, r.Assertion, r.Detail)
+	}
+	if failed := assert.Failed(results); failed > 0 {
+		return &exitCodeError{code: 2, msg: fmt.Sprintf("%d of %d assertions failed", failed, len(results))}
+	}
+	return nil
+}
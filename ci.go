@@ -0,0 +1,338 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mickamy/xplain/internal/analyzer"
+	"github.com/mickamy/xplain/internal/assert"
+	"github.com/mickamy/xplain/internal/config"
+	"github.com/mickamy/xplain/internal/diff"
+	"github.com/mickamy/xplain/internal/fingerprint"
+	"github.com/mickamy/xplain/internal/gitdiff"
+	"github.com/mickamy/xplain/internal/parser"
+	"github.com/mickamy/xplain/internal/redact"
+	"github.com/mickamy/xplain/internal/runner"
+	"github.com/mickamy/xplain/internal/sqlfile"
+)
+
+// exitCodeError reports a failure that should terminate the CLI with a specific exit code.
+type exitCodeError struct {
+	code int
+	msg  string
+}
+
+func (e *exitCodeError) Error() string {
+	return e.msg
+}
+
+// ciTarget is one statement to check: where its SQL lives, where its baseline is stored, and its template variables.
+type ciTarget struct {
+	label string
+	// name is the query's name in a manifest, which per-query configuration can be keyed by.
+	name         string
+	sqlPath      string
+	baselinePath string
+	vars         sqlfile.Vars
+	// assertions are checked in addition to the file's own directives.
+	assertions []assert.Assertion
+}
+
+// ciResult captures the outcome for one SQL file.
+type ciResult struct {
+	label     string
+	sqlPath   string
+	baseline  *analyzer.PlanAnalysis
+	current   *analyzer.PlanAnalysis
+	report    *diff.Report
+	regressed bool
+	checks    []assert.Result
+	err       error
+}
+
+// failed reports whether the query regressed or broke one of its assertions.
+func (r ciResult) failed() bool {
+	return r.regressed || assert.Failed(r.checks) > 0
+}
+
+func ciCommand(args []string) error {
+	fs := flag.NewFlagSet("ci", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	fs.Usage = func() {
+		_, _ = fmt.Fprintf(os.Stdout, "Usage: xplain ci --url <url> [--dir queries] [--since origin/main] [--baselines dir] [--update-baselines]\n\nOptions:\n")
+		fs.PrintDefaults()
+	}
+
+	envURL := os.Getenv("DATABASE_URL")
+
+	var (
+		urlFlag     = fs.String("url", envURL, "PostgreSQL connection string; defaults to $DATABASE_URL")
+		service     = fs.String("service", "", "Connection service name from ~/.pg_service.conf or $PGSERVICEFILE; overrides --url")
+		dir         = fs.String("dir", ".", "Directory containing the .sql files to check")
+		since       = fs.String("since", "origin/main", "Only check .sql files changed since this git ref")
+		until       = fs.String("until", "HEAD", "End of the git range used with --since")
+		all         = fs.Bool("all", false, "Check every .sql file under --dir instead of the git changes")
+		baselineDir = fs.String("baselines", "", "Directory holding baseline plans (default from config, .xplain/baselines)")
+		update      = fs.Bool("update-baselines", false, "Write the captured plans as the new baselines")
+		failPercent = fs.Float64("fail-percent", 20, "Fail when execution time regresses by more than this percent")
+		summaryPath = fs.String("summary", os.Getenv("GITHUB_STEP_SUMMARY"), "Append the Markdown summary to this file; defaults to $GITHUB_STEP_SUMMARY")
+		timeout     = fs.Duration("timeout", 0, "Optional execution timeout per query, e.g. 45s")
+		format      = fs.String("format", "md", "Output format: md, or diagnostics for one JSON finding per line (editor problem matchers)")
+		configPath  = fs.String("config", "", "Path to configuration file (JSON). Falls back to $XPLAIN_CONFIG")
+		vars        = sqlfile.Vars{}
+		checks      assert.List
+	)
+	fs.Var(vars, "var", "SQL template variable as name=value (repeatable)")
+	fs.Var(&checks, "assert", "Assertion checked for every query, e.g. 'no-seq-scan' (repeatable)")
+
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			fs.SetOutput(os.Stdout)
+			fs.Usage()
+			return nil
+		}
+		return err
+	}
+	if err := applyConfigPath(*configPath); err != nil {
+		return err
+	}
+	if err := checkCIFormat(*format); err != nil {
+		return err
+	}
+	connection := resolveConnection(*urlFlag, *service)
+	if connection == "" {
+		return fmt.Errorf("--url or --service is required, or set $DATABASE_URL or $PGSERVICE")
+	}
+	if *baselineDir == "" {
+		*baselineDir = config.Active().Output.BaselineDir
+	}
+
+	ctx := context.Background()
+	files, err := ciFiles(ctx, *dir, *since, *until, *all)
+	if err != nil {
+		return err
+	}
+
+	var results []ciResult
+	for _, rel := range files {
+		target := ciTarget{
+			label:        rel,
+			sqlPath:      filepath.Join(*dir, rel),
+			baselinePath: filepath.Join(*baselineDir, strings.TrimSuffix(rel, filepath.Ext(rel))+".json"),
+			vars:         vars,
+			assertions:   checks,
+		}
+		results = append(results, ciCheck(ctx, connection, target, *update, *failPercent, *timeout))
+	}
+
+	return ciFinish(results, "# xplain ci", *format, *summaryPath, *failPercent)
+}
+
+func checkCIFormat(format string) error {
+	if format != "md" && format != "diagnostics" {
+		return fmt.Errorf("unsupported format %q (expected md or diagnostics)", format)
+	}
+	return nil
+}
+
+// ciFinish prints the summary (or diagnostics), appends the Markdown summary to summaryPath when set, and maps the
+// results to an exit status.
+func ciFinish(results []ciResult, heading, format, summaryPath string, failPercent float64) error {
+	summary := ciMarkdown(results, heading, failPercent)
+	if format == "diagnostics" {
+		if err := writeDiagnostics(os.Stdout, results, failPercent); err != nil {
+			return err
+		}
+	} else {
+		fmt.Print(summary)
+	}
+	if summaryPath != "" {
+		file, err := os.OpenFile(summaryPath, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+		if err != nil {
+			return fmt.Errorf("open summary: %w", err)
+		}
+		_, err = file.WriteString(summary)
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return fmt.Errorf("write summary: %w", err)
+		}
+	}
+
+	var failed, regressed int
+	for _, r := range results {
+		switch {
+		case r.err != nil:
+			failed++
+		case r.failed():
+			regressed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d queries could not be checked", failed, len(results))
+	}
+	if regressed > 0 {
+		return &exitCodeError{code: 2, msg: fmt.Sprintf("%d of %d queries regressed or failed assertions", regressed, len(results))}
+	}
+	return nil
+}
+
+func ciFiles(ctx context.Context, dir, since, until string, all bool) ([]string, error) {
+	if !all {
+		return gitdiff.ChangedFiles(ctx, dir, since, until, ".sql")
+	}
+	var files []string
+	err := filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() && strings.HasPrefix(d.Name(), ".") && path != dir {
+			return filepath.SkipDir
+		}
+		if !d.IsDir() && strings.EqualFold(filepath.Ext(path), ".sql") {
+			rel, err := filepath.Rel(dir, path)
+			if err != nil {
+				return err
+			}
+			files = append(files, rel)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("walk %s: %w", dir, err)
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// ciCheck captures a fresh plan for the target and compares it with its stored baseline.
+func ciCheck(ctx context.Context, dsn string, target ciTarget, update bool, failPercent float64, timeout time.Duration) ciResult {
+	result := ciResult{label: target.label, sqlPath: target.sqlPath}
+	baselinePath := target.baselinePath
+
+	sqlText, err := sqlfile.Load(target.sqlPath, target.vars)
+	if err != nil {
+		result.err = err
+		return result
+	}
+	directiveTimeout, assertions, err := sqlChecks(sqlText)
+	if err != nil {
+		result.err = err
+		return result
+	}
+	if timeout == 0 {
+		timeout = directiveTimeout
+	}
+	run, err := runner.Run(ctx, dsn, sqlText, runner.Options{Timeout: timeout})
+	if err != nil {
+		result.err = err
+		return result
+	}
+	plan, err := parser.ParseJSON(bytes.NewReader(run.Plan))
+	if err != nil {
+		result.err = err
+		return result
+	}
+	plan.Metadata = &run.Metadata
+	plan.QueryText = run.Metadata.Query
+	opts, err := analysisOptions()
+	if err == nil {
+		result.current, err = analyzer.AnalyzeWith(plan, opts)
+	}
+	if err != nil {
+		result.err = err
+		return result
+	}
+	result.current.Name = target.name
+	result.checks = assert.Evaluate(result.current, append(assertions, target.assertions...))
+
+	if _, baseline, err := loadAnalysis(baselinePath); err == nil {
+		result.baseline = baseline
+		if result.report, err = diff.Compare(baseline, result.current, diff.Options{}); err != nil {
+			result.err = err
+			return result
+		}
+		summary := result.report.Summary
+		critical := config.Active().ForQuery(fingerprint.Of(result.current), result.current.Name).Diff.CriticalDeltaMs
+		result.regressed = summary.PercentExecution > failPercent && summary.DeltaExecutionMs >= critical
+	} else if !errors.Is(err, os.ErrNotExist) {
+		result.err = fmt.Errorf("load baseline: %w", err)
+		return result
+	}
+
+	if update {
+		envelope, err := run.Envelope()
+		if err == nil {
+			err = os.MkdirAll(filepath.Dir(baselinePath), 0o755)
+		}
+		if err == nil {
+			err = os.WriteFile(baselinePath, envelope, 0o644)
+		}
+		if err != nil {
+			result.err = fmt.Errorf("write baseline: %w", err)
+		}
+	}
+	return result
+}
+
+func ciMarkdown(results []ciResult, heading string, failPercent float64) string {
+	var b strings.Builder
+	b.WriteString(heading + "\n\n")
+	if len(results) == 0 {
+		b.WriteString("- No queries to check\n")
+		return b.String()
+	}
+	b.WriteString("| Query | Baseline (ms) | Current (ms) | Δ % | Status |\n")
+	b.WriteString("|---|---:|---:|---:|---|\n")
+	for _, r := range results {
+		switch {
+		case r.err != nil:
+			_, _ = fmt.Fprintf(&b, "| %s | – | – | – | ❌ %s |\n", r.label, markdownCell(r.err.Error()))
+		case r.report == nil:
+			status := "🆕 no baseline"
+			if failed := assert.Failed(r.checks); failed > 0 {
+				status = fmt.Sprintf("❌ %d assertion(s) failed", failed)
+			}
+			_, _ = fmt.Fprintf(&b, "| %s | – | %.2f | – | %s |\n", r.label, r.current.TotalTimeMs, status)
+		default:
+			status := "✅ ok"
+			if r.regressed {
+				status = fmt.Sprintf("🔥 regressed more than %.0f%%", failPercent)
+			} else if failed := assert.Failed(r.checks); failed > 0 {
+				status = fmt.Sprintf("❌ %d assertion(s) failed", failed)
+			}
+			s := r.report.Summary
+			_, _ = fmt.Fprintf(&b, "| %s | %.2f | %.2f | %+.1f%% | %s |\n", r.label, s.BaseExecutionMs, s.TargetExecutionMs, s.PercentExecution, status)
+		}
+	}
+	for _, r := range results {
+		if !r.failed() {
+			continue
+		}
+		_, _ = fmt.Fprintf(&b, "\n## %s\n\n", r.label)
+		for _, check := range r.checks {
+			if !check.Passed {
+				_, _ = fmt.Fprintf(&b, "- ❌ `%s`: %s\n", check.Assertion, check.Detail)
+			}
+		}
+		if r.regressed {
+			b.WriteString(strings.TrimPrefix(r.report.Markdown(), "# xplain diff\n\n"))
+		}
+	}
+	return b.String()
+}
+
+// markdownCell flattens text so it fits in a single Markdown table cell, scrubbing connection secrets.
+func markdownCell(text string) string {
+	return strings.ReplaceAll(strings.Join(strings.Fields(redact.String(text)), " "), "|", "\\|")
+}
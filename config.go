@@ -0,0 +1,208 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/mickamy/xplain/internal/config"
+)
+
+func configCommand(args []string) error {
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		configUsage()
+		if len(args) > 0 && (args[0] == "-h" || args[0] == "--help") {
+			return nil
+		}
+		return fmt.Errorf("config: expected a subcommand (validate or init)")
+	}
+
+	sub := args[0]
+	fs := flag.NewFlagSet("config "+sub, flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	fs.Usage = func() {
+		configUsage()
+		_, _ = fmt.Fprintf(os.Stdout, "\nOptions:\n")
+		fs.PrintDefaults()
+	}
+	var (
+		path        *string
+		format      *string
+		force       *bool
+		interactive *bool
+		sets        stringList
+	)
+	if sub == "init" {
+		path = fs.String("path", "xplain.json", "Configuration file to write")
+		format = fs.String("format", "jsonc", "File format: jsonc (a comment above every setting) or json")
+		force = fs.Bool("force", false, "Overwrite the file if it exists")
+		interactive = fs.Bool("interactive", stdinIsTerminal(), "Prompt for the most tuned thresholds (default when stdin is a terminal)")
+		fs.Var(&sets, "set", "Setting as key=value, e.g. insights.hotspot_warning_percent=0.2 (repeatable)")
+	} else {
+		path = fs.String("path", "", "Configuration file (JSON) to check. Falls back to $XPLAIN_CONFIG, then the built-in defaults")
+	}
+	if err := fs.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			fs.SetOutput(os.Stdout)
+			fs.Usage()
+			return nil
+		}
+		return err
+	}
+
+	switch sub {
+	case "validate":
+		return validateConfig(os.Stdout, *path)
+	case "init":
+		var prompt io.Reader
+		if *interactive {
+			prompt = os.Stdin
+		}
+		return initConfig(os.Stdout, prompt, *path, *format, sets, *force)
+	default:
+		configUsage()
+		return fmt.Errorf("config: unknown subcommand %q (expected validate or init)", sub)
+	}
+}
+
+// validateConfig prints the problems in the configuration file at path, then the effective configuration
+// with the defaults filled in. It fails when there is any problem, so CI can gate config changes on it.
+func validateConfig(w io.Writer, path string) error {
+	path = strings.TrimSpace(path)
+	if path == "" {
+		path = strings.TrimSpace(os.Getenv("XPLAIN_CONFIG"))
+	}
+	data := []byte("{}")
+	if path != "" {
+		var err error
+		if data, err = os.ReadFile(path); err != nil {
+			return fmt.Errorf("read config: %w", err)
+		}
+	}
+	cfg, problems, err := config.Validate(data)
+	if err != nil {
+		return err
+	}
+	source := path
+	if source == "" {
+		source = "built-in defaults"
+	}
+	for _, p := range problems {
+		_, _ = fmt.Fprintf(w, "%s: %s\n", source, p)
+	}
+	if len(problems) == 0 {
+		_, _ = fmt.Fprintf(w, "%s: ok\n", source)
+	}
+	out, err := json.MarshalIndent(cfg, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode config: %w", err)
+	}
+	_, _ = fmt.Fprintf(w, "\nEffective configuration:\n%s\n", out)
+	if len(problems) > 0 {
+		return &exitCodeError{code: 2, msg: fmt.Sprintf("%d configuration problems in %s", len(problems), source)}
+	}
+	return nil
+}
+
+// initPrompts are the settings config init asks about interactively: the ones most often tuned first.
+var initPrompts = []string{
+	"insights.hotspot_warning_percent",
+	"insights.hotspot_critical_percent",
+	"insights.buffer_warning_blocks",
+	"insights.buffer_critical_blocks",
+	"diff.min_self_delta_ms",
+	"diff.min_percent_change",
+	"output.dir",
+	"analysis.parallel_time",
+}
+
+// initConfig writes a starter configuration to path: the defaults with the --set values applied and, when
+// prompt is non-nil, the answers read from it. The result is validated before anything is written.
+func initConfig(w io.Writer, prompt io.Reader, path, format string, sets []string, force bool) error {
+	path = strings.TrimSpace(path)
+	if path == "" {
+		return fmt.Errorf("config init: --path is required")
+	}
+	if !force {
+		if _, err := os.Stat(path); err == nil {
+			return fmt.Errorf("config init: %s already exists (use --force to overwrite)", path)
+		}
+	}
+	cfg := config.Default()
+	for _, set := range sets {
+		key, value, ok := strings.Cut(set, "=")
+		if !ok {
+			return fmt.Errorf("config init: --set %q: expected key=value", set)
+		}
+		if err := cfg.Set(strings.TrimSpace(key), value); err != nil {
+			return err
+		}
+	}
+	if prompt != nil {
+		in := bufio.NewScanner(prompt)
+		for _, key := range initPrompts {
+			current, _ := cfg.Get(key)
+			_, _ = fmt.Fprintf(w, "%s\n  %s [%s]: ", key, config.Describe(key), current)
+			if !in.Scan() {
+				_, _ = fmt.Fprintln(w)
+				break
+			}
+			answer := strings.TrimSpace(in.Text())
+			if answer == "" {
+				continue
+			}
+			if err := cfg.Set(key, answer); err != nil {
+				return err
+			}
+		}
+		if err := in.Err(); err != nil {
+			return fmt.Errorf("config init: read answers: %w", err)
+		}
+	}
+	out, err := config.Encode(cfg, format)
+	if err != nil {
+		return err
+	}
+	_, problems, err := config.Validate(out)
+	if err != nil {
+		return err
+	}
+	if len(problems) > 0 {
+		for _, p := range problems {
+			_, _ = fmt.Fprintf(w, "%s\n", p)
+		}
+		return &exitCodeError{code: 2, msg: fmt.Sprintf("config init: %d configuration problems, nothing written", len(problems))}
+	}
+	if err := os.WriteFile(path, out, 0o644); err != nil {
+		return fmt.Errorf("config init: %w", err)
+	}
+	_, _ = fmt.Fprintf(w, "wrote %s; point --config or $XPLAIN_CONFIG at it\n", path)
+	return nil
+}
+
+// stdinIsTerminal reports whether the standard input is interactive, so config init only prompts a person.
+func stdinIsTerminal() bool {
+	info, err := os.Stdin.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+func configUsage() {
+	_, _ = fmt.Fprintf(os.Stdout, `Usage: xplain config <subcommand> [options]
+
+Subcommands:
+  validate  Check a configuration file and print the effective configuration: xplain config validate --path xplain.json
+  init      Write a starter configuration with every default explained: xplain config init --path xplain.json
+
+validate reports keys xplain does not know (they are otherwise ignored), warning thresholds at or above their
+critical counterpart and percentages outside 0-1, and exits with status 2 when it finds any.
+
+init prompts for the most tuned thresholds when stdin is a terminal (--interactive=false skips that) and takes
+--set key=value for any setting. The default jsonc format puts a // comment above each setting; xplain reads
+such files like plain JSON.
+`)
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/mickamy/xplain/internal/insight"
+)
+
+// ciDiagnostic is one finding in the diagnostics format. Fields are emitted in a fixed order on a single line so an
+// editor problem matcher (e.g. a VS Code task) can pick them out with one regular expression.
+type ciDiagnostic struct {
+	File     string `json:"file"`
+	Line     int    `json:"line"`
+	Column   int    `json:"column"`
+	Severity string `json:"severity"`
+	Code     string `json:"code"`
+	Message  string `json:"message"`
+}
+
+// writeDiagnostics reports failures, regressions, failed assertions and unacknowledged insights as JSON lines
+// anchored at the first line of each query's SQL.
+func writeDiagnostics(w io.Writer, results []ciResult, failPercent float64) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false)
+	for _, r := range results {
+		file := filepath.ToSlash(r.sqlPath)
+		if file == "" {
+			file = r.label
+		}
+		line := firstCodeLine(r.sqlPath)
+		var diags []ciDiagnostic
+		add := func(severity, code, message string) {
+			diags = append(diags, ciDiagnostic{
+				File: file, Line: line, Column: 1, Severity: severity, Code: code,
+				Message: strings.Join(strings.Fields(message), " "),
+			})
+		}
+
+		if r.err != nil {
+			add("error", "error", r.err.Error())
+		} else {
+			if r.regressed {
+				s := r.report.Summary
+				add("error", "regression", fmt.Sprintf("execution time regressed %+.1f%% (%.2f ms -> %.2f ms), more than %.0f%%",
+					s.PercentExecution, s.BaseExecutionMs, s.TargetExecutionMs, failPercent))
+			}
+			for _, check := range r.checks {
+				if !check.Passed {
+					add("error", "assertion", fmt.Sprintf("%s: %s", check.Assertion, check.Detail))
+				}
+			}
+			for _, msg := range insight.BuildMessages(r.current) {
+				if msg.Acknowledged != "" {
+					continue
+				}
+				severity := "warning"
+				if msg.Severity == insight.SeverityInfo {
+					severity = "info"
+				}
+				add(severity, msg.Rule, msg.Text)
+			}
+		}
+
+		for _, d := range diags {
+			if err := encoder.Encode(d); err != nil {
+				return fmt.Errorf("write diagnostics: %w", err)
+			}
+		}
+	}
+	return nil
+}
+
+// firstCodeLine returns the 1-based line of the first statement line in a SQL file, skipping blank lines and
+// comments such as directives. It falls back to line 1 when the file cannot be read.
+func firstCodeLine(path string) int {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 1
+	}
+	for i, line := range strings.Split(string(data), "\n") {
+		if trimmed := strings.TrimSpace(line); trimmed != "" && !strings.HasPrefix(trimmed, "--") {
+			return i + 1
+		}
+	}
+	return 1
+}
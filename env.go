@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/mickamy/xplain/internal/model"
+	"github.com/mickamy/xplain/internal/runner"
+)
+
+func envCommand(args []string) error {
+	fs := flag.NewFlagSet("env", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	fs.Usage = func() {
+		_, _ = fmt.Fprintf(os.Stdout, "Usage: xplain env --url <url> [--out env.json]\n\n"+
+			"Snapshots planner settings, installed extensions and table statistics ages so diff --base-env/--target-env\n"+
+			"can explain plan changes caused by the environment rather than the query.\n\nOptions:\n")
+		fs.PrintDefaults()
+	}
+
+	var (
+		urlFlag    = fs.String("url", os.Getenv("DATABASE_URL"), "PostgreSQL connection string; defaults to $DATABASE_URL")
+		service    = fs.String("service", "", "Connection service name from ~/.pg_service.conf or $PGSERVICEFILE; overrides --url")
+		outPath    = fs.String("out", "", "Path to write the snapshot JSON, or \"clipboard\" (defaults to stdout)")
+		timeout    = fs.Duration("timeout", 0, "Optional timeout, e.g. 45s")
+		configPath = fs.String("config", "", "Path to configuration file (JSON). Falls back to $XPLAIN_CONFIG")
+	)
+
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			fs.SetOutput(os.Stdout)
+			fs.Usage()
+			return nil
+		}
+		return err
+	}
+	if err := applyConfigPath(*configPath); err != nil {
+		return err
+	}
+	connection := resolveConnection(*urlFlag, *service)
+	if connection == "" {
+		return fmt.Errorf("--url or --service is required, or set $DATABASE_URL or $PGSERVICE")
+	}
+
+	env, err := runner.CaptureEnvironment(context.Background(), connection, runner.Options{Timeout: *timeout})
+	if err != nil {
+		return err
+	}
+	payload, err := json.MarshalIndent(env, "", "  ")
+	if err != nil {
+		return err
+	}
+	payload = append(payload, '\n')
+	if *outPath == "" {
+		_, err = os.Stdout.Write(payload)
+		return err
+	}
+	return writeOutput(*outPath, payload)
+}
+
+// loadEnvironment reads a snapshot written by xplain env; an empty path yields nil.
+func loadEnvironment(path string) (*model.Environment, error) {
+	if path == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var env model.Environment
+	if err := json.Unmarshal(data, &env); err != nil {
+		return nil, fmt.Errorf("environment %s: %w", path, err)
+	}
+	return &env, nil
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/mickamy/xplain/internal/focus"
+	"github.com/mickamy/xplain/internal/parser"
+)
+
+func extractCommand(args []string) error {
+	fs := flag.NewFlagSet("extract", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	fs.Usage = func() {
+		_, _ = fmt.Fprintf(os.Stdout, "Usage: xplain extract --input plan.json --node 0.1.2 [--out sub.json]\n\n"+
+			"Writes the subtree under one node as a standalone EXPLAIN (FORMAT JSON) plan that report, diff and score accept.\n\nOptions:\n")
+		fs.PrintDefaults()
+	}
+
+	var (
+		input     = fs.String("input", "", "Path to EXPLAIN input (JSON, YAML or text), or \"clipboard\"")
+		format    = fs.String("format", "auto", "Input format: auto, json, yaml, text or cockroach")
+		from      = fs.String("from", "explain", "Input source: explain, or auto-explain, csvlog or pgbadger to extract from a server log, csvlog file or pgBadger JSON export")
+		planIndex = fs.Int("plan", 1, "1-based plan to extract from when the input holds several")
+		nodeSpec  = fs.String("node", "", "Subtree root: a path such as 0.1.2 or 'relation=orders,type=Seq Scan' (first match)")
+		output    = fs.String("out", "", "Output path, or \"clipboard\" (stdout if omitted)")
+	)
+
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			fs.SetOutput(os.Stdout)
+			fs.Usage()
+			return nil
+		}
+		return err
+	}
+	if *input == "" {
+		return fmt.Errorf("--input is required")
+	}
+	if *nodeSpec == "" {
+		return fmt.Errorf("--node is required")
+	}
+	selector, err := focus.Parse(*nodeSpec)
+	if err != nil {
+		return err
+	}
+
+	plans, err := loadReportPlans(*input, *from, parser.Options{Format: *format})
+	if err != nil {
+		return err
+	}
+	if *planIndex < 1 || *planIndex > len(plans) {
+		return fmt.Errorf("--plan %d out of range (input holds %d plans)", *planIndex, len(plans))
+	}
+	node, err := selector.Find(plans[*planIndex-1].analysis)
+	if err != nil {
+		return err
+	}
+	content, err := parser.EncodePlan(node.Node)
+	if err != nil {
+		return err
+	}
+	content = append(content, '\n')
+	if *output == "" {
+		_, err = os.Stdout.Write(content)
+		return err
+	}
+	return writeOutput(*output, content)
+}
@@ -0,0 +1,257 @@
+package advisor
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/mickamy/xplain/internal/analyzer"
+	"github.com/mickamy/xplain/internal/model"
+)
+
+// Column is one key of a proposed index.
+type Column struct {
+	Expr      string
+	Direction string
+	Nulls     string
+}
+
+// String renders the column as it appears inside CREATE INDEX.
+func (c Column) String() string {
+	parts := []string{c.Expr}
+	if c.Direction != "" {
+		parts = append(parts, c.Direction)
+	}
+	if c.Nulls != "" {
+		parts = append(parts, c.Nulls)
+	}
+	return strings.Join(parts, " ")
+}
+
+// Index describes an index proposal for a single relation.
+type Index struct {
+	Schema  string
+	Table   string
+	Columns []Column
+	// Prefix is the number of leading equality columns; their relative order does not matter.
+	Prefix int
+}
+
+// QualifiedTable returns the table name prefixed by its schema when known.
+func (i Index) QualifiedTable() string {
+	if i.Schema != "" {
+		return i.Schema + "." + i.Table
+	}
+	return i.Table
+}
+
+// DDL renders the proposal as a CREATE INDEX statement.
+func (i Index) DDL() string {
+	cols := make([]string, 0, len(i.Columns))
+	for _, c := range i.Columns {
+		cols = append(cols, c.String())
+	}
+	return fmt.Sprintf("CREATE INDEX ON %s (%s);", i.QualifiedTable(), strings.Join(cols, ", "))
+}
+
+// ForSort proposes an index that delivers the ordering required by a Sort node, led by the
+// equality predicates of the scanned relation so the index can be walked in order.
+func ForSort(sort *analyzer.NodeStats) (Index, bool) {
+	if sort == nil || sort.Node == nil || len(sort.Node.SortKey) == 0 {
+		return Index{}, false
+	}
+	scans := relationScans(sort)
+	if len(scans) == 0 {
+		return Index{}, false
+	}
+
+	keys := make([]Column, 0, len(sort.Node.SortKey))
+	var scan *analyzer.NodeStats
+	for _, raw := range sort.Node.SortKey {
+		qualifier, col := parseSortKey(raw)
+		candidate := pickScan(scans, qualifier)
+		if candidate == nil || (scan != nil && candidate != scan) {
+			return Index{}, false
+		}
+		scan = candidate
+		keys = append(keys, col)
+	}
+
+	idx := Index{Schema: scan.Node.Schema, Table: scan.Node.RelationName}
+	seen := map[string]struct{}{}
+	for _, col := range EqualityColumns(scan.Node.Filter) {
+		if _, ok := seen[col]; ok {
+			continue
+		}
+		seen[col] = struct{}{}
+		idx.Columns = append(idx.Columns, Column{Expr: col})
+	}
+	idx.Prefix = len(idx.Columns)
+	for _, key := range keys {
+		if _, ok := seen[key.Expr]; ok {
+			continue
+		}
+		idx.Columns = append(idx.Columns, key)
+	}
+	return idx, true
+}
+
+func relationScans(root *analyzer.NodeStats) []*analyzer.NodeStats {
+	var out []*analyzer.NodeStats
+	var walk func(*analyzer.NodeStats)
+	walk = func(n *analyzer.NodeStats) {
+		if n.Node.RelationName != "" {
+			out = append(out, n)
+		}
+		for _, child := range n.Children {
+			walk(child)
+		}
+	}
+	walk(root)
+	return out
+}
+
+func pickScan(scans []*analyzer.NodeStats, qualifier string) *analyzer.NodeStats {
+	if qualifier == "" {
+		if len(scans) == 1 {
+			return scans[0]
+		}
+		return nil
+	}
+	for _, s := range scans {
+		if s.Node.Alias == qualifier || (s.Node.Alias == "" && s.Node.RelationName == qualifier) {
+			return s
+		}
+	}
+	return nil
+}
+
+var qualifiedIdent = regexp.MustCompile(`^([A-Za-z_][\w$]*)\.([A-Za-z_][\w$]*)$`)
+
+func parseSortKey(raw string) (string, Column) {
+	expr := strings.TrimSpace(raw)
+	var col Column
+	upper := strings.ToUpper(expr)
+	for _, suffix := range []string{" NULLS FIRST", " NULLS LAST"} {
+		if strings.HasSuffix(upper, suffix) {
+			col.Nulls = strings.TrimSpace(suffix)
+			expr = strings.TrimSpace(expr[:len(expr)-len(suffix)])
+			upper = strings.ToUpper(expr)
+		}
+	}
+	for _, suffix := range []string{" DESC", " ASC"} {
+		if strings.HasSuffix(upper, suffix) {
+			if suffix == " DESC" {
+				col.Direction = "DESC"
+			}
+			expr = strings.TrimSpace(expr[:len(expr)-len(suffix)])
+		}
+	}
+	var qualifier string
+	if m := qualifiedIdent.FindStringSubmatch(expr); m != nil {
+		qualifier, expr = m[1], m[2]
+	}
+	col.Expr = expr
+	return qualifier, col
+}
+
+var equalityPredicate = regexp.MustCompile(`\(*(?:[A-Za-z_][\w$]*\.)?([A-Za-z_][\w$]*)\)*(?:::[\w ]+?)?\s*=\s*(?:'(?:[^']|'')*'(?:::[\w ]+)?|-?\d+(?:\.\d+)?|\$\d+)`)
+
+// EqualityColumns extracts columns compared against constants in a plan filter expression.
+// Expressions containing OR are ignored because no single index prefix serves them.
+func EqualityColumns(filter string) []string {
+	if filter == "" || strings.Contains(strings.ToUpper(filter), " OR ") {
+		return nil
+	}
+	var out []string
+	for _, m := range equalityPredicate.FindAllStringSubmatch(filter, -1) {
+		out = append(out, m[1])
+	}
+	return out
+}
+
+// ServedBy reports whether an existing index already delivers the proposal: it must be a valid,
+// non-partial btree whose leading keys are the equality prefix (in any order) followed by the
+// ordering keys with matching direction, or with every direction reversed for a backward scan.
+func (i Index) ServedBy(existing model.Index) bool {
+	if !strings.EqualFold(existing.Table, i.Table) || (i.Schema != "" && !strings.EqualFold(existing.Schema, i.Schema)) {
+		return false
+	}
+	if existing.Method != "" && existing.Method != "btree" {
+		return false
+	}
+	if !existing.Valid || existing.Partial || len(existing.Columns) < len(i.Columns) {
+		return false
+	}
+
+	prefix := map[string]struct{}{}
+	for _, col := range i.Columns[:i.Prefix] {
+		prefix[normalizeExpr(col.Expr)] = struct{}{}
+	}
+	for _, col := range existing.Columns[:i.Prefix] {
+		key := normalizeExpr(col.Expr)
+		if _, ok := prefix[key]; !ok {
+			return false
+		}
+		delete(prefix, key)
+	}
+
+	reversed := -1
+	for k, want := range i.Columns[i.Prefix:] {
+		have := existing.Columns[i.Prefix+k]
+		if normalizeExpr(have.Expr) != normalizeExpr(want.Expr) {
+			return false
+		}
+		matches := have.Desc == want.desc() && have.NullsFirst == want.nullsFirst()
+		flipped := have.Desc != want.desc() && have.NullsFirst != want.nullsFirst()
+		switch {
+		case matches && reversed != 1:
+			reversed = 0
+		case flipped && reversed != 0:
+			reversed = 1
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// FindExisting returns the first catalog index that already serves the proposal.
+func FindExisting(cat *model.Catalog, want Index) (model.Index, bool) {
+	if cat == nil {
+		return model.Index{}, false
+	}
+	for _, idx := range cat.Indexes {
+		if want.ServedBy(idx) {
+			return idx, true
+		}
+	}
+	return model.Index{}, false
+}
+
+func (c Column) desc() bool {
+	return c.Direction == "DESC"
+}
+
+func (c Column) nullsFirst() bool {
+	switch c.Nulls {
+	case "NULLS FIRST":
+		return true
+	case "NULLS LAST":
+		return false
+	default:
+		return c.desc()
+	}
+}
+
+// normalizeExpr strips decoration that differs between plan text and pg_get_indexdef output,
+// e.g. lower((email)::text) versus lower(email::text).
+func normalizeExpr(expr string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case '(', ')', ' ', '"':
+			return -1
+		}
+		return r
+	}, strings.ToLower(expr))
+}
@@ -0,0 +1,172 @@
+package advisor_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/mickamy/xplain/internal/advisor"
+	"github.com/mickamy/xplain/internal/model"
+	"github.com/mickamy/xplain/test"
+)
+
+func TestForSortIncludesEqualityPrefix(t *testing.T) {
+	analysis := test.LoadSampleAnalysis(t, "pagination_offset.json")
+	sort := analysis.Limit.Sort
+	if sort == nil {
+		t.Fatalf("expected sort under limit")
+	}
+
+	idx, ok := advisor.ForSort(sort)
+	if !ok {
+		t.Fatalf("expected index proposal for sort")
+	}
+	if got, want := idx.DDL(), "CREATE INDEX ON orders (status, created_at DESC);"; got != want {
+		t.Fatalf("unexpected ddl: got %q want %q", got, want)
+	}
+}
+
+func TestEqualityColumns(t *testing.T) {
+	cases := map[string][]string{
+		"(bid = 1)":                          {"bid"},
+		"((status)::text = 'shipped'::text)": {"status"},
+		"((a.region = 'eu'::text) AND (a.tenant_id = $1))": {"region", "tenant_id"},
+		"((bid = 1) OR (bid = 2))":                         nil,
+		"(a.bid = b.bid)":                                  nil,
+	}
+	for filter, want := range cases {
+		if got := advisor.EqualityColumns(filter); !reflect.DeepEqual(got, want) {
+			t.Errorf("EqualityColumns(%q) = %v, want %v", filter, got, want)
+		}
+	}
+}
+
+func TestServedBy(t *testing.T) {
+	want := advisor.Index{
+		Table:   "orders",
+		Columns: []advisor.Column{{Expr: "status"}, {Expr: "created_at", Direction: "DESC"}},
+		Prefix:  1,
+	}
+	cases := []struct {
+		name     string
+		existing model.Index
+		served   bool
+	}{
+		{
+			name:     "exact match",
+			existing: btree("orders", model.IndexColumn{Expr: "status"}, model.IndexColumn{Expr: "created_at", Desc: true, NullsFirst: true}),
+			served:   true,
+		},
+		{
+			name:     "backward scan",
+			existing: btree("orders", model.IndexColumn{Expr: "status"}, model.IndexColumn{Expr: "created_at"}),
+			served:   true,
+		},
+		{
+			name:     "mismatched nulls ordering",
+			existing: btree("orders", model.IndexColumn{Expr: "status"}, model.IndexColumn{Expr: "created_at", Desc: true}),
+			served:   false,
+		},
+		{
+			name:     "ordering column first",
+			existing: btree("orders", model.IndexColumn{Expr: "created_at", Desc: true}, model.IndexColumn{Expr: "status"}),
+			served:   false,
+		},
+		{
+			name:     "other table",
+			existing: btree("customers", model.IndexColumn{Expr: "status"}, model.IndexColumn{Expr: "created_at", Desc: true, NullsFirst: true}),
+			served:   false,
+		},
+	}
+	for _, tc := range cases {
+		if got := want.ServedBy(tc.existing); got != tc.served {
+			t.Errorf("%s: ServedBy = %v, want %v", tc.name, got, tc.served)
+		}
+	}
+}
+
+func btree(table string, cols ...model.IndexColumn) model.Index {
+	return model.Index{Table: table, Name: table + "_idx", Method: "btree", Valid: true, Columns: cols}
+}
+
+func TestRedundant(t *testing.T) {
+	cat := &model.Catalog{Indexes: []model.Index{
+		ordersIndex("orders_pkey", model.IndexColumn{Expr: "id"}),
+		ordersIndex("orders_id_idx", model.IndexColumn{Expr: "id"}),
+		ordersIndex("orders_status_idx", model.IndexColumn{Expr: "status"}),
+		ordersIndex("orders_status_created_idx", model.IndexColumn{Expr: "status"}, model.IndexColumn{Expr: "created_at"}),
+		ordersIndex("orders_created_idx", model.IndexColumn{Expr: "created_at", Desc: true, NullsFirst: true}),
+	}}
+	cat.Indexes[0].Primary = true
+	cat.Indexes[0].Unique = true
+
+	got := advisor.Redundant(cat)
+	if len(got) != 2 {
+		t.Fatalf("expected 2 redundant indexes, got %+v", got)
+	}
+	if got[0].Index.Name != "orders_id_idx" || got[0].CoveredBy.Name != "orders_pkey" || !got[0].Duplicate {
+		t.Fatalf("expected orders_id_idx to duplicate the primary key, got %+v", got[0])
+	}
+	if got[1].Index.Name != "orders_status_idx" || got[1].CoveredBy.Name != "orders_status_created_idx" || got[1].Duplicate {
+		t.Fatalf("expected orders_status_idx to be a prefix of the composite index, got %+v", got[1])
+	}
+	if ddl := got[1].DropDDL(); ddl != "DROP INDEX orders_status_idx;" {
+		t.Fatalf("unexpected drop statement %q", ddl)
+	}
+}
+
+func TestRedundantReversed(t *testing.T) {
+	desc := func(expr string) model.IndexColumn {
+		return model.IndexColumn{Expr: expr, Desc: true, NullsFirst: true}
+	}
+	cat := &model.Catalog{Indexes: []model.Index{
+		ordersIndex("orders_customer_created_idx", model.IndexColumn{Expr: "customer_id"}, model.IndexColumn{Expr: "created_at"}),
+		ordersIndex("orders_customer_created_desc_idx", desc("customer_id"), desc("created_at")),
+		ordersIndex("orders_customer_recent_idx", model.IndexColumn{Expr: "customer_id"}, desc("created_at")),
+	}}
+
+	// A backward scan serves the fully reversed index, but not one that flips only some keys.
+	got := advisor.Redundant(cat)
+	if len(got) != 1 || got[0].Index.Name != "orders_customer_created_desc_idx" ||
+		got[0].CoveredBy.Name != "orders_customer_created_idx" || !got[0].Duplicate {
+		t.Fatalf("expected only the reversed index to duplicate the ascending one, got %+v", got)
+	}
+}
+
+func ordersIndex(name string, cols ...model.IndexColumn) model.Index {
+	idx := btree("orders", cols...)
+	idx.Name = name
+	return idx
+}
+
+func TestConsolidate(t *testing.T) {
+	status := advisor.Index{Table: "orders", Columns: []advisor.Column{{Expr: "status"}}, Prefix: 1}
+	statusCreated := advisor.Index{Table: "orders", Columns: []advisor.Column{{Expr: "status"}, {Expr: "created_at", Direction: "DESC"}}, Prefix: 1}
+	customerStatus := advisor.Index{Table: "orders", Columns: []advisor.Column{{Expr: "customer_id"}, {Expr: "status"}, {Expr: "created_at", Direction: "DESC"}}, Prefix: 2}
+	customer := advisor.Index{Table: "orders", Columns: []advisor.Column{{Expr: "customer_id"}}, Prefix: 1}
+	email := advisor.Index{Table: "users", Columns: []advisor.Column{{Expr: "lower((email)::text)"}}, Prefix: 1}
+
+	got := advisor.Consolidate([]advisor.Index{status, email, customer, statusCreated, customerStatus})
+	var ddl []string
+	serves := map[string]int{}
+	for _, c := range got {
+		ddl = append(ddl, c.Index.DDL())
+		serves[c.Index.DDL()] = len(c.Serves)
+	}
+	want := []string{
+		"CREATE INDEX ON orders (customer_id, status, created_at DESC);",
+		"CREATE INDEX ON orders (status, created_at DESC);",
+		"CREATE INDEX ON users (lower((email)::text));",
+	}
+	if !reflect.DeepEqual(ddl, want) {
+		t.Fatalf("unexpected covering set:\n got %q\nwant %q", ddl, want)
+	}
+	if serves[want[0]] != 2 || serves[want[1]] != 2 {
+		t.Fatalf("expected each orders index to serve two proposals, got %v", serves)
+	}
+
+	// A proposal that only some prefix order serves reorders the chosen index's equality columns.
+	got = advisor.Consolidate([]advisor.Index{customerStatus, status})
+	if len(got) != 1 || got[0].Index.DDL() != "CREATE INDEX ON orders (status, customer_id, created_at DESC);" {
+		t.Fatalf("expected the status proposal to be folded in by reordering the prefix, got %+v", got)
+	}
+}
@@ -0,0 +1,95 @@
+package advisor
+
+import (
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/mickamy/xplain/internal/model"
+)
+
+// Consolidated is one index of a minimal covering set together with the proposals it serves.
+type Consolidated struct {
+	Index  Index
+	Serves []Index
+}
+
+// Consolidate merges overlapping proposals into a smaller set of indexes that still serves every
+// one of them. Wider proposals are placed first; a narrower proposal is absorbed by a chosen index
+// that already serves it or, failing that, by one whose equality prefix can be reordered to lead
+// with the narrower proposal's columns while still serving every earlier member.
+func Consolidate(proposals []Index) []Consolidated {
+	ordered := slices.Clone(proposals)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		if len(ordered[i].Columns) != len(ordered[j].Columns) {
+			return len(ordered[i].Columns) > len(ordered[j].Columns)
+		}
+		return ordered[i].DDL() < ordered[j].DDL()
+	})
+
+	var out []Consolidated
+	for _, p := range ordered {
+		if k := slices.IndexFunc(out, func(c Consolidated) bool { return p.ServedBy(c.Index.asModel()) }); k >= 0 {
+			out[k].Serves = append(out[k].Serves, p)
+			continue
+		}
+		absorbed := false
+		for k := range out {
+			if merged, ok := leadWith(out[k].Index, p); ok && servesAll(merged, out[k].Serves) {
+				out[k].Index = merged
+				out[k].Serves = append(out[k].Serves, p)
+				absorbed = true
+				break
+			}
+		}
+		if !absorbed {
+			out = append(out, Consolidated{Index: p, Serves: []Index{p}})
+		}
+	}
+	return out
+}
+
+// leadWith reorders the equality prefix of idx so the prefix columns of p come first, which lets
+// idx serve p when p's own prefix is a subset of it.
+func leadWith(idx, p Index) (Index, bool) {
+	if !strings.EqualFold(idx.Table, p.Table) || !strings.EqualFold(idx.Schema, p.Schema) || p.Prefix > idx.Prefix {
+		return Index{}, false
+	}
+	lead := make([]Column, 0, len(idx.Columns))
+	rest := make([]Column, 0, idx.Prefix)
+	for _, col := range idx.Columns[:idx.Prefix] {
+		if slices.ContainsFunc(p.Columns[:p.Prefix], func(want Column) bool { return normalizeExpr(want.Expr) == normalizeExpr(col.Expr) }) {
+			lead = append(lead, col)
+		} else {
+			rest = append(rest, col)
+		}
+	}
+	if len(lead) != p.Prefix {
+		return Index{}, false
+	}
+	merged := Index{Schema: idx.Schema, Table: idx.Table, Prefix: idx.Prefix}
+	merged.Columns = append(append(append(merged.Columns, lead...), rest...), idx.Columns[idx.Prefix:]...)
+	if !p.ServedBy(merged.asModel()) {
+		return Index{}, false
+	}
+	return merged, true
+}
+
+func servesAll(idx Index, proposals []Index) bool {
+	existing := idx.asModel()
+	for _, p := range proposals {
+		if !p.ServedBy(existing) {
+			return false
+		}
+	}
+	return true
+}
+
+// asModel describes the proposal as if it had been created, so ServedBy can compare proposals.
+func (i Index) asModel() model.Index {
+	cols := make([]model.IndexColumn, 0, len(i.Columns))
+	for _, c := range i.Columns {
+		cols = append(cols, model.IndexColumn{Expr: c.Expr, Desc: c.desc(), NullsFirst: c.nullsFirst()})
+	}
+	return model.Index{Schema: i.Schema, Table: i.Table, Method: "btree", Columns: cols, Valid: true}
+}
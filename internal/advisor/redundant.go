@@ -0,0 +1,96 @@
+package advisor
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mickamy/xplain/internal/model"
+)
+
+// Redundancy describes an index whose keys are already covered by another index on the same table.
+type Redundancy struct {
+	Index     model.Index
+	CoveredBy model.Index
+	// Duplicate is true when both indexes have identical keys; otherwise Index is a leading prefix of CoveredBy.
+	Duplicate bool
+}
+
+// DropDDL renders the clean-up statement for the redundant index.
+func (r Redundancy) DropDDL() string {
+	name := r.Index.Name
+	if r.Index.Schema != "" {
+		name = r.Index.Schema + "." + name
+	}
+	return fmt.Sprintf("DROP INDEX %s;", name)
+}
+
+// Redundant lists indexes that another index on the same table makes unnecessary. Partial and
+// invalid indexes are skipped because their predicates are not compared, and indexes enforcing
+// a unique or primary key constraint are only reported when an exact duplicate exists.
+func Redundant(cat *model.Catalog) []Redundancy {
+	if cat == nil {
+		return nil
+	}
+	var out []Redundancy
+	reported := map[int]bool{}
+	for i, idx := range cat.Indexes {
+		for j, other := range cat.Indexes {
+			if i == j || reported[i] || reported[j] || !comparable(idx, other) {
+				continue
+			}
+			switch {
+			case sameKeys(idx.Columns, other.Columns):
+				// Keep the constraint-backed index, or the first one listed when both are equivalent.
+				if constraint(idx) && !constraint(other) || (constraint(idx) == constraint(other) && i < j) {
+					continue
+				}
+				out = append(out, Redundancy{Index: idx, CoveredBy: other, Duplicate: true})
+				reported[i] = true
+			case idx.Method == "btree" && !constraint(idx) && len(idx.Columns) < len(other.Columns) && sameKeys(idx.Columns, other.Columns[:len(idx.Columns)]):
+				out = append(out, Redundancy{Index: idx, CoveredBy: other})
+				reported[i] = true
+			}
+		}
+	}
+	return out
+}
+
+// Keys renders the key columns of an index for display.
+func Keys(idx model.Index) string {
+	cols := make([]string, 0, len(idx.Columns))
+	for _, col := range idx.Columns {
+		text := col.Expr
+		if col.Desc {
+			text += " DESC"
+		}
+		cols = append(cols, text)
+	}
+	return strings.Join(cols, ", ")
+}
+
+func comparable(a, b model.Index) bool {
+	return strings.EqualFold(a.Schema, b.Schema) && strings.EqualFold(a.Table, b.Table) &&
+		a.Method == b.Method && a.Valid && b.Valid && !a.Partial && !b.Partial && len(a.Columns) > 0
+}
+
+func constraint(idx model.Index) bool {
+	return idx.Unique || idx.Primary
+}
+
+// sameKeys reports whether a and b index the same expressions in the same order, with either the same
+// directions or every direction and nulls placement inverted: a backward scan of one serves the other.
+func sameKeys(a, b []model.IndexColumn) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	forward, backward := true, true
+	for k := range a {
+		if normalizeExpr(a[k].Expr) != normalizeExpr(b[k].Expr) {
+			return false
+		}
+		sameOrder := a[k].Desc == b[k].Desc && a[k].NullsFirst == b[k].NullsFirst
+		forward = forward && sameOrder
+		backward = backward && a[k].Desc != b[k].Desc && a[k].NullsFirst != b[k].NullsFirst
+	}
+	return forward || backward
+}
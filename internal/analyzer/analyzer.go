@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"math"
 	"sort"
+	"strconv"
+	"strings"
 
 	"github.com/mickamy/xplain/internal/model"
 )
@@ -16,27 +18,273 @@ type PlanAnalysis struct {
 	TotalTimeMs     float64
 	NodeCount       int
 	HotNodes        []*NodeStats
-	DivergentNodes  []*NodeStats
-	BufferHeavy     []*NodeStats
-	TotalBuffers    int64
+	// HotSelection is how HotNodes were picked, with the defaults filled in.
+	HotSelection   HotSelection
+	DivergentNodes []*NodeStats
+	// BufferHeavy lists up to five nodes that accessed the most blocks themselves (SelfBuffers), most
+	// first, so the operators doing the I/O are named rather than the root every block passes through.
+	BufferHeavy []*NodeStats
+	// TotalBuffers counts the buffers of every node plus those touched while planning (PlanningBuffers)
+	// and serializing the output.
+	TotalBuffers int64
+	// PlanningBuffers counts buffers the planner touched, separate from execution.
+	PlanningBuffers BufferTotals
+	// HitRatio is the fraction of the shared blocks execution accessed that were found in shared buffers;
+	// 1 when it accessed none.
+	HitRatio float64
+	// IOTimeMs totals the nodes' IOTimeMs: the time execution spent waiting on block reads and writes,
+	// measured with track_io_timing.
+	IOTimeMs  float64
+	Limit     *LimitAnalysis
+	QueryText string
+	// Name is the statement's name in the query registry or a manifest, when it was run by name.
+	Name string
+	// Settings are the non-default planner settings EXPLAIN (SETTINGS) reported.
+	Settings      map[string]string
+	Triggers      []model.Trigger
+	TriggerTimeMs float64
+	// JIT carries the statement's JIT compilation counters and timings, when JIT was used.
+	JIT *model.JIT
+	// Serialization is the output conversion EXPLAIN (ANALYZE, SERIALIZE) measured (PostgreSQL 17+).
+	Serialization *model.Serialization
+	// MemoryKB estimates the combined peak memory of sorts, hashes and other memory-bound nodes.
+	MemoryKB float64
+	// TempFiles lists the temp files found in the server log for this query, when one was supplied.
+	TempFiles []model.TempFile
+	// CostModel compares planner cost units with measured time; nil when either is missing.
+	CostModel *CostModel
+	// Metadata describes where and when the plan was captured, when known.
+	Metadata *model.Metadata
+	// Acknowledgements suppress insights that were reviewed and accepted, e.g. from .xplain-ignore.
+	Acknowledgements []model.Acknowledgement
+	// Catalog holds live index metadata when the plan was analyzed with a database connection.
+	Catalog *model.Catalog
+	// Time is the attribution the node times were computed with.
+	Time TimeAttribution
+}
+
+// Nodes returns every node of the plan in depth-first order.
+func (a *PlanAnalysis) Nodes() []*NodeStats {
+	if a == nil || a.Root == nil {
+		return nil
+	}
+	return flatten(a.Root)
+}
+
+// NodeByPath returns the node at a Path such as "0.2.1", or nil when the plan has none there.
+func (a *PlanAnalysis) NodeByPath(path string) *NodeStats {
+	for _, n := range a.Nodes() {
+		if n.Path == path {
+			return n
+		}
+	}
+	return nil
+}
+
+// Ancestors returns the nodes above n, its parent first and the root last.
+func (n *NodeStats) Ancestors() []*NodeStats {
+	var out []*NodeStats
+	for p := n.Parent; p != nil; p = p.Parent {
+		out = append(out, p)
+	}
+	return out
+}
+
+// Relations lists the distinct relation names scanned by the plan.
+func (a *PlanAnalysis) Relations() []string {
+	if a == nil || a.Root == nil {
+		return nil
+	}
+	seen := map[string]struct{}{}
+	var out []string
+	for _, n := range flatten(a.Root) {
+		name := n.Node.RelationName
+		if name == "" {
+			continue
+		}
+		if _, ok := seen[name]; ok {
+			continue
+		}
+		seen[name] = struct{}{}
+		out = append(out, name)
+	}
+	return out
 }
 
 // NodeStats augments a plan node with computed statistics.
 type NodeStats struct {
-	Node              *model.PlanNode
-	Depth             int
-	Parent            *NodeStats
-	ActualLoops       float64
-	InclusiveTimeMs   float64
-	ExclusiveTimeMs   float64
-	PercentExclusive  float64
-	PercentInclusive  float64
-	ActualTotalRows   float64
-	EstimatedRows     float64
-	RowEstimateFactor float64
-	Buffers           BufferTotals
-	Warnings          []string
-	Children          []*NodeStats
+	Node            *model.PlanNode
+	Depth           int
+	Parent          *NodeStats
+	ActualLoops     float64
+	InclusiveTimeMs float64
+	ExclusiveTimeMs float64
+	// PercentExclusive and PercentInclusive are the node's self and inclusive time over the root's
+	// inclusive time, the same as PercentOfRoot.
+	PercentExclusive float64
+	PercentInclusive float64
+	// PercentOfRoot and PercentOfExecution express the node's time against the root's inclusive time and
+	// against the Execution Time the server reported, which also covers triggers and sending the result.
+	// Without a reported Execution Time, PercentOfExecution falls back to the root's. Under cpu
+	// attribution nodes below a Gather sum every process, so either share can exceed 100%.
+	PercentOfRoot      Share
+	PercentOfExecution Share
+	ActualTotalRows    float64
+	EstimatedRows      float64
+	RowEstimateFactor  float64
+	// EstimateImpact weighs the node's misestimate by the time it cost: EstimateError times
+	// ExclusiveTimeMs. Divergent nodes are ranked by it, so a large error on a node that took no time
+	// does not outrank a smaller one on the operator the query waited on.
+	EstimateImpact float64
+	// MsPerRow is the node's self time divided by the rows it returned across loops; zero when it
+	// returned none.
+	MsPerRow float64
+	// RowsRemovedByFilter and RowsRemovedByJoinFilter total the rows the node's filter and join filter
+	// discarded across loops.
+	RowsRemovedByFilter     float64
+	RowsRemovedByJoinFilter float64
+	// RowsRemovedByIndexRecheck totals the rows a Bitmap Heap Scan's recheck of the index condition rejected.
+	RowsRemovedByIndexRecheck float64
+	// HeapFetches is the number of heap visits an Index Only Scan made, as EXPLAIN reports it.
+	// HeapFetchRatio is their share of the index entries the scan returned, its rows plus those its filter
+	// removed; near 1 the visibility map is stale and the scan reads the heap like a plain Index Scan.
+	HeapFetches    float64
+	HeapFetchRatio float64
+	Buffers        BufferTotals
+	// SelfSharedHit and SelfSharedRead are the shared blocks the node accessed itself: its buffers minus
+	// those its children report, since EXPLAIN counts a node's buffers including its children's.
+	SelfSharedHit  int64
+	SelfSharedRead int64
+	// HitRatio is SelfSharedHit over the node's own shared block accesses; 1 when it accessed none.
+	HitRatio float64
+	// IOTimeMs is the time the node itself spent reading and writing blocks: its I/O timings minus its
+	// children's, divided across the processes below a Gather like ExclusiveTimeMs. PercentIO is its
+	// share of the plan's IOTimeMs.
+	IOTimeMs  float64
+	PercentIO float64
+	// SelfBuffers counts the blocks the node accessed net of its children's; PercentBuffers is its share
+	// of the blocks the plan accessed.
+	SelfBuffers    int64
+	PercentBuffers float64
+	// FilterFunctions are the calls in the node's filter, ConditionFunctions those in its join filter and
+	// hash or merge condition, and OutputFunctions those its VERBOSE output list computes rather than
+	// passes up from a child. All of them run once per row.
+	FilterFunctions    []FunctionCall
+	ConditionFunctions []FunctionCall
+	OutputFunctions    []FunctionCall
+	// MemoryKB is the node's estimated peak memory, multiplied across parallel workers.
+	MemoryKB float64
+	// TempFiles and TempFileBytes are the log-reported temp files attributed to the node.
+	TempFiles     int
+	TempFileBytes int64
+	// CostSkew is the node's cost-per-ms relative to the plan's; below 1 the node ran slower than
+	// its cost suggests. Zero when the node has too little self time to judge.
+	CostSkew float64
+	// UnusedOutput lists output columns the parent never references (EXPLAIN VERBOSE only).
+	UnusedOutput []string
+	// Workers breaks the node down per parallel worker, when EXPLAIN reported them.
+	Workers  []WorkerStats
+	Warnings []Warning
+	Children []*NodeStats
+	// References lists the CTE, InitPlan or SubPlan roots elsewhere in the tree whose time this node's
+	// inclusive time contains, a CTE Scan reading a shared CTE for instance. That time is attributed to
+	// the subplan once and left out of ExclusiveTimeMs.
+	References []*NodeStats
+	// WorkerSkew compares the processes of a Gather or Gather Merge, nil for other nodes and when
+	// EXPLAIN reported no per-worker statistics below it.
+	WorkerSkew *WorkerSkew
+	// Processes is how many processes shared the node's loops: the launched workers plus the leader
+	// below a Gather, 1 elsewhere.
+	Processes float64
+	// Path addresses the node by the child indexes from the root joined by dots, "0" for the root and
+	// "0.2.1" for the second child of the root's third child. It depends only on the plan's shape, so it
+	// stays the same across runs of a plan and between renderers.
+	Path string
+}
+
+// TimeAttribution selects how the time of nodes below a Gather is counted.
+type TimeAttribution string
+
+const (
+	// WallClock divides a parallel node's time across the processes that ran it side by side, so times
+	// add up to the elapsed execution time. It is the default.
+	WallClock TimeAttribution = "wall"
+	// CPUTime sums the time of every process, the work a node cost across all cores; the nodes below a
+	// Gather then add up to more than the Gather itself.
+	CPUTime TimeAttribution = "cpu"
+)
+
+// ParseTimeAttribution validates a --parallel-time value; empty selects WallClock.
+func ParseTimeAttribution(s string) (TimeAttribution, error) {
+	switch t := TimeAttribution(strings.ToLower(strings.TrimSpace(s))); t {
+	case "":
+		return WallClock, nil
+	case WallClock, CPUTime:
+		return t, nil
+	default:
+		return "", fmt.Errorf("analyze: unknown time attribution %q (expected wall or cpu)", s)
+	}
+}
+
+// Share is a node's self and inclusive time as fractions of one total.
+type Share struct {
+	Exclusive float64
+	Inclusive float64
+}
+
+// PercentBase selects the total renderers express node shares against.
+type PercentBase string
+
+const (
+	// PercentRoot divides by the root node's inclusive time. It is the default.
+	PercentRoot PercentBase = "root"
+	// PercentExecution divides by the reported Execution Time.
+	PercentExecution PercentBase = "execution"
+)
+
+// ParsePercentBase validates a --percent-of value; empty selects PercentRoot.
+func ParsePercentBase(s string) (PercentBase, error) {
+	switch b := PercentBase(strings.ToLower(strings.TrimSpace(s))); b {
+	case "":
+		return PercentRoot, nil
+	case PercentRoot, PercentExecution:
+		return b, nil
+	default:
+		return "", fmt.Errorf("analyze: unknown percent base %q (expected root or execution)", s)
+	}
+}
+
+// Percent returns the node's share of the total base names; anything but PercentExecution is PercentOfRoot.
+func (n *NodeStats) Percent(base PercentBase) Share {
+	if base == PercentExecution {
+		return n.PercentOfExecution
+	}
+	return n.PercentOfRoot
+}
+
+// Options tunes how Analyze derives node statistics.
+type Options struct {
+	// Time picks wall-clock or CPU-time attribution for parallel nodes; empty means WallClock.
+	Time TimeAttribution
+	// Processes is the number of processes the plan root ran in, for a subtree cut out below a Gather;
+	// zero means a single one.
+	Processes float64
+	// Hot picks the hot nodes; the zero value keeps the top 5 by self time at 10% or more.
+	Hot HotSelection
+	// CostSkew is how many times off the plan's cost-per-ms a node's own ratio may be before it is flagged;
+	// zero means 10.
+	CostSkew float64
+}
+
+// WorkerStats is one parallel worker's share of a node.
+type WorkerStats struct {
+	Worker *model.Worker
+	// TimeMs and Rows are totals across the worker's loops.
+	TimeMs float64
+	Rows   float64
+	// RowShare is the worker's fraction of the node's rows; the leader produced whatever the workers did not.
+	RowShare float64
+	Buffers  BufferTotals
 }
 
 // BufferTotals mirrors the buffer counters for easier reporting.
@@ -51,6 +299,31 @@ type BufferTotals struct {
 	LocalWritten  int64
 	TempRead      int64
 	TempWritten   int64
+	// Read and write times in milliseconds, populated with track_io_timing.
+	SharedReadTimeMs  float64
+	SharedWriteTimeMs float64
+	LocalReadTimeMs   float64
+	LocalWriteTimeMs  float64
+	TempReadTimeMs    float64
+	TempWriteTimeMs   float64
+}
+
+// IOTimeMs returns the total time spent reading and writing blocks.
+func (b BufferTotals) IOTimeMs() float64 {
+	return b.SharedReadTimeMs + b.SharedWriteTimeMs + b.LocalReadTimeMs + b.LocalWriteTimeMs + b.TempReadTimeMs + b.TempWriteTimeMs
+}
+
+// HitRatio is the fraction of shared block accesses served from shared buffers, hit / (hit + read);
+// 1 when no shared blocks were accessed.
+func (b BufferTotals) HitRatio() float64 {
+	return hitRatio(b.SharedHit, b.SharedRead)
+}
+
+func hitRatio(hit, read int64) float64 {
+	if hit+read <= 0 {
+		return 1
+	}
+	return float64(hit) / float64(hit+read)
 }
 
 // Total returns the sum of all buffer counters.
@@ -61,20 +334,80 @@ func (b BufferTotals) Total() int64 {
 
 // Analyze derives metrics for the provided plan.
 func Analyze(explain *model.Explain) (*PlanAnalysis, error) {
+	return AnalyzeWith(explain, Options{})
+}
+
+// AnalyzeWith is Analyze with explicit options.
+func AnalyzeWith(explain *model.Explain, opts Options) (*PlanAnalysis, error) {
 	if explain == nil || explain.Plan == nil {
 		return nil, fmt.Errorf("analyze: missing plan")
 	}
+	if opts.Time == "" {
+		opts.Time = WallClock
+	}
 
-	root := buildStats(explain.Plan, 0, nil)
+	root := buildStats(explain.Plan, "0", 0, nil, opts)
+	attributeSubplans(root)
 	totalTime := root.InclusiveTimeMs
 
-	annotateRatios(root, totalTime)
+	executionTime := explain.ExecutionTime
+	if executionTime <= 0 {
+		executionTime = totalTime
+	}
+	annotateRatios(root, totalTime, executionTime)
 
 	allNodes := flatten(root)
 
-	hot := selectHotNodes(allNodes)
+	var ioTime float64
+	for _, n := range allNodes {
+		ioTime += n.IOTimeMs
+	}
+	// Plans whose upper nodes report no buffers are measured against the blocks the nodes accessed themselves.
+	blocks := root.Buffers.Total()
+	var selfBlocks int64
+	for _, n := range allNodes {
+		if ioTime > 0 {
+			n.PercentIO = n.IOTimeMs / ioTime
+		}
+		own := n.Buffers.Total()
+		for _, child := range n.Children {
+			own -= child.Buffers.Total()
+		}
+		n.SelfBuffers = max(own, 0)
+		selfBlocks += n.SelfBuffers
+	}
+	blocks = max(blocks, selfBlocks)
+	for _, n := range allNodes {
+		if blocks > 0 {
+			n.PercentBuffers = float64(n.SelfBuffers) / float64(blocks)
+		}
+	}
+	// Warnings need the shares, which are only known once subplans are attributed and the ratios annotated.
+	for _, n := range allNodes {
+		n.Warnings = append(n.Warnings, deriveWarnings(n)...)
+	}
+
+	opts.Hot = opts.Hot.WithDefaults()
+	hot := selectHotNodes(allNodes, opts.Hot)
 	divergent := selectDivergentNodes(allNodes)
 	bufferHeavy, totalBuffers := selectBufferHeavyNodes(allNodes)
+	planningBuffers := bufferTotals(explain.PlanningBuffers)
+	totalBuffers += planningBuffers.Total()
+	if explain.Serialization != nil {
+		totalBuffers += bufferTotals(explain.Serialization.Buffers).Total()
+	}
+
+	var triggerTime float64
+	for _, trig := range explain.Triggers {
+		triggerTime += trig.TimeMs
+	}
+
+	var memoryKB float64
+	for _, n := range allNodes {
+		n.MemoryKB = nodeMemoryKB(n)
+		memoryKB += n.MemoryKB
+		n.WorkerSkew = workerSkew(n)
+	}
 
 	return &PlanAnalysis{
 		Root:            root,
@@ -83,19 +416,45 @@ func Analyze(explain *model.Explain) (*PlanAnalysis, error) {
 		TotalTimeMs:     totalTime,
 		NodeCount:       len(allNodes),
 		HotNodes:        hot,
+		HotSelection:    opts.Hot,
 		DivergentNodes:  divergent,
 		BufferHeavy:     bufferHeavy,
 		TotalBuffers:    totalBuffers,
+		PlanningBuffers: planningBuffers,
+		HitRatio:        root.Buffers.HitRatio(),
+		IOTimeMs:        ioTime,
+		Limit:           analyzeLimit(root),
+		QueryText:       explain.QueryText,
+		Settings:        explain.Settings,
+		Triggers:        explain.Triggers,
+		Metadata:        explain.Metadata,
+		TriggerTimeMs:   triggerTime,
+		JIT:             explain.JIT,
+		Serialization:   explain.Serialization,
+		MemoryKB:        memoryKB,
+		CostModel:       analyzeCostModel(allNodes, opts.CostSkew),
+		Time:            opts.Time,
 	}, nil
 }
 
-func buildStats(node *model.PlanNode, depth int, parent *NodeStats) *NodeStats {
+func buildStats(node *model.PlanNode, path string, depth int, parent *NodeStats, opts Options) *NodeStats {
 	loops := node.ActualLoops
 	if loops <= 0 {
 		loops = 1
 	}
+	// Below a Gather, Actual Loops counts every process's loops and Actual Total Time is the average per
+	// loop, so time × loops is the time of all processes together. The processes ran at the same time;
+	// dividing by their number gives the wall-clock time the node took.
+	processes := opts.Processes
+	if parent != nil {
+		processes = parentProcesses(parent)
+	}
+	processes = math.Max(1, math.Min(processes, loops))
 
 	inclusive := node.ActualTotalTime * loops
+	if opts.Time == WallClock {
+		inclusive /= processes
+	}
 
 	stats := &NodeStats{
 		Node:            node,
@@ -105,26 +464,39 @@ func buildStats(node *model.PlanNode, depth int, parent *NodeStats) *NodeStats {
 		InclusiveTimeMs: inclusive,
 		ActualTotalRows: node.ActualRows * loops,
 		EstimatedRows:   node.PlanRows * loops,
-		Buffers: BufferTotals{
-			SharedHit:     node.Buffers.SharedHit,
-			SharedRead:    node.Buffers.SharedRead,
-			SharedDirtied: node.Buffers.SharedDirtied,
-			SharedWritten: node.Buffers.SharedWritten,
-			LocalHit:      node.Buffers.LocalHit,
-			LocalRead:     node.Buffers.LocalRead,
-			LocalDirtied:  node.Buffers.LocalDirtied,
-			LocalWritten:  node.Buffers.LocalWritten,
-			TempRead:      node.Buffers.TempRead,
-			TempWritten:   node.Buffers.TempWritten,
-		},
+		Buffers:         bufferTotals(node.Buffers),
+
+		RowsRemovedByFilter:       node.RowsRemovedFilter * loops,
+		RowsRemovedByJoinFilter:   node.RowsRemovedJoinFilter * loops,
+		RowsRemovedByIndexRecheck: node.RowsRemovedIndexRecheck * loops,
+		HeapFetches:               node.HeapFetches,
+		Processes:                 processes,
+		Path:                      path,
+	}
+	stats.Workers = workerStats(node, stats.ActualTotalRows)
+	if entries := stats.ActualTotalRows + stats.RowsRemovedByFilter; node.NodeType == "Index Only Scan" && entries > 0 {
+		stats.HeapFetchRatio = math.Min(node.HeapFetches/entries, 1)
 	}
 
 	var childTime float64
-	for _, childNode := range node.Children {
-		child := buildStats(childNode, depth+1, stats)
+	stats.SelfSharedHit, stats.SelfSharedRead = stats.Buffers.SharedHit, stats.Buffers.SharedRead
+	selfIO := stats.Buffers.IOTimeMs()
+	for i, childNode := range node.Children {
+		child := buildStats(childNode, path+"."+strconv.Itoa(i), depth+1, stats, opts)
 		stats.Children = append(stats.Children, child)
 		childTime += child.InclusiveTimeMs
+		stats.SelfSharedHit -= child.Buffers.SharedHit
+		stats.SelfSharedRead -= child.Buffers.SharedRead
+		selfIO -= child.Buffers.IOTimeMs()
+	}
+	stats.SelfSharedHit = max(stats.SelfSharedHit, 0)
+	stats.SelfSharedRead = max(stats.SelfSharedRead, 0)
+	// Like buffers, the I/O timings of a node below a Gather add up every process's.
+	stats.IOTimeMs = max(selfIO, 0)
+	if opts.Time == WallClock {
+		stats.IOTimeMs /= processes
 	}
+	stats.HitRatio = hitRatio(stats.SelfSharedHit, stats.SelfSharedRead)
 
 	stats.ExclusiveTimeMs = inclusive - childTime
 	if stats.ExclusiveTimeMs < 0 {
@@ -136,18 +508,82 @@ func buildStats(node *model.PlanNode, depth int, parent *NodeStats) *NodeStats {
 	}
 
 	stats.RowEstimateFactor = computeEstimateFactor(stats.EstimatedRows, stats.ActualTotalRows)
-	stats.Warnings = append(stats.Warnings, deriveWarnings(stats)...)
+	if stats.ActualTotalRows > 0 {
+		stats.MsPerRow = stats.ExclusiveTimeMs / stats.ActualTotalRows
+	}
+	stats.FilterFunctions = FunctionCalls(node.Filter)
+	for _, cond := range []string{node.JoinFilter, node.HashCond, node.MergeCond} {
+		stats.ConditionFunctions = append(stats.ConditionFunctions, FunctionCalls(cond)...)
+	}
+	stats.OutputFunctions = outputFunctions(node)
+	stats.UnusedOutput = unusedOutputs(stats)
 
 	return stats
 }
 
-func annotateRatios(node *NodeStats, total float64) {
-	if total > 0 {
-		node.PercentExclusive = node.ExclusiveTimeMs / total
-		node.PercentInclusive = node.InclusiveTimeMs / total
+// parentProcesses is the number of processes the children of parent run in: a Gather's launched workers
+// plus the leader, or the parent's own count.
+func parentProcesses(parent *NodeStats) float64 {
+	if t := parent.Node.NodeType; t == "Gather" || t == "Gather Merge" {
+		return parent.Node.WorkersLaunched + 1
+	}
+	return parent.Processes
+}
+
+func workerStats(node *model.PlanNode, nodeRows float64) []WorkerStats {
+	var out []WorkerStats
+	for i := range node.Workers {
+		w := &node.Workers[i]
+		loops := w.ActualLoops
+		if loops <= 0 {
+			loops = 1
+		}
+		ws := WorkerStats{
+			Worker:  w,
+			TimeMs:  w.ActualTotalTime * loops,
+			Rows:    w.ActualRows * loops,
+			Buffers: bufferTotals(w.Buffers),
+		}
+		if nodeRows > 0 {
+			ws.RowShare = ws.Rows / nodeRows
+		}
+		out = append(out, ws)
+	}
+	return out
+}
+
+func bufferTotals(b model.Buffers) BufferTotals {
+	return BufferTotals{
+		SharedHit:     b.SharedHit,
+		SharedRead:    b.SharedRead,
+		SharedDirtied: b.SharedDirtied,
+		SharedWritten: b.SharedWritten,
+		LocalHit:      b.LocalHit,
+		LocalRead:     b.LocalRead,
+		LocalDirtied:  b.LocalDirtied,
+		LocalWritten:  b.LocalWritten,
+		TempRead:      b.TempRead,
+		TempWritten:   b.TempWritten,
+
+		SharedReadTimeMs:  b.SharedReadTimeMs,
+		SharedWriteTimeMs: b.SharedWriteTimeMs,
+		LocalReadTimeMs:   b.LocalReadTimeMs,
+		LocalWriteTimeMs:  b.LocalWriteTimeMs,
+		TempReadTimeMs:    b.TempReadTimeMs,
+		TempWriteTimeMs:   b.TempWriteTimeMs,
+	}
+}
+
+func annotateRatios(node *NodeStats, root, execution float64) {
+	if root > 0 {
+		node.PercentOfRoot = Share{Exclusive: node.ExclusiveTimeMs / root, Inclusive: node.InclusiveTimeMs / root}
+		node.PercentExclusive, node.PercentInclusive = node.PercentOfRoot.Exclusive, node.PercentOfRoot.Inclusive
+	}
+	if execution > 0 {
+		node.PercentOfExecution = Share{Exclusive: node.ExclusiveTimeMs / execution, Inclusive: node.InclusiveTimeMs / execution}
 	}
 	for _, child := range node.Children {
-		annotateRatios(child, total)
+		annotateRatios(child, root, execution)
 	}
 }
 
@@ -164,46 +600,18 @@ func flatten(root *NodeStats) []*NodeStats {
 	return out
 }
 
-func selectHotNodes(nodes []*NodeStats) []*NodeStats {
-	if len(nodes) == 0 {
-		return nil
-	}
-
-	candidates := make([]*NodeStats, 0, len(nodes))
-	for _, n := range nodes {
-		if n.PercentExclusive > 0 {
-			candidates = append(candidates, n)
-		}
-	}
-
-	sort.Slice(candidates, func(i, j int) bool {
-		return candidates[i].PercentExclusive > candidates[j].PercentExclusive
-	})
-
-	limit := 5
-	if len(candidates) < limit {
-		limit = len(candidates)
-	}
-	cutoff := 0.10
-
-	var out []*NodeStats
-	for _, candidate := range candidates[:limit] {
-		if candidate.PercentExclusive < cutoff {
-			break
-		}
-		out = append(out, candidate)
-	}
-
-	if len(out) == 0 && len(candidates) > 0 {
-		out = candidates[:limit]
-	}
-
-	return out
+// EstimateError is how many times the row estimate was off in either direction, at least 1. Both counts
+// are taken as one row at the least, as the planner never estimates fewer, so an estimate of rows that
+// never came stays finite.
+func (n *NodeStats) EstimateError() float64 {
+	actual, estimated := max(n.ActualTotalRows, 1), max(n.EstimatedRows, 1)
+	return max(actual/estimated, estimated/actual)
 }
 
 func selectDivergentNodes(nodes []*NodeStats) []*NodeStats {
 	var out []*NodeStats
 	for _, n := range nodes {
+		n.EstimateImpact = n.EstimateError() * n.ExclusiveTimeMs
 		if math.IsInf(n.RowEstimateFactor, 1) || math.IsInf(n.RowEstimateFactor, -1) {
 			out = append(out, n)
 			continue
@@ -214,8 +622,11 @@ func selectDivergentNodes(nodes []*NodeStats) []*NodeStats {
 			}
 		}
 	}
-	sort.Slice(out, func(i, j int) bool {
-		return math.Abs(out[i].RowEstimateFactor-1) > math.Abs(out[j].RowEstimateFactor-1)
+	sort.SliceStable(out, func(i, j int) bool {
+		if out[i].EstimateImpact != out[j].EstimateImpact {
+			return out[i].EstimateImpact > out[j].EstimateImpact
+		}
+		return out[i].EstimateError() > out[j].EstimateError()
 	})
 	limit := 5
 	if len(out) < limit {
@@ -224,23 +635,23 @@ func selectDivergentNodes(nodes []*NodeStats) []*NodeStats {
 	return out[:limit]
 }
 
+// selectBufferHeavyNodes ranks the nodes by SelfBuffers for BufferHeavy and sums their inclusive buffers.
 func selectBufferHeavyNodes(nodes []*NodeStats) ([]*NodeStats, int64) {
 	var total int64
 	candidates := make([]*NodeStats, 0, len(nodes))
 	for _, n := range nodes {
-		b := n.Buffers.Total()
-		total += b
-		if b > 0 {
+		total += n.Buffers.Total()
+		if n.SelfBuffers > 0 {
 			candidates = append(candidates, n)
 		}
 	}
 	if len(candidates) == 0 {
 		return nil, total
 	}
-	sort.Slice(candidates, func(i, j int) bool {
-		return candidates[i].Buffers.Total() > candidates[j].Buffers.Total()
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].SelfBuffers > candidates[j].SelfBuffers
 	})
-	limit := 3
+	limit := 5
 	if len(candidates) < limit {
 		limit = len(candidates)
 	}
@@ -258,18 +669,146 @@ func computeEstimateFactor(estimated, actual float64) float64 {
 	return actual / estimated
 }
 
-func deriveWarnings(stats *NodeStats) []string {
-	var warnings []string
+func deriveWarnings(stats *NodeStats) []Warning {
+	var warnings []Warning
 	if stats.PercentExclusive >= 0.20 {
-		warnings = append(warnings, fmt.Sprintf("self time %.1f%% of plan", stats.PercentExclusive*100))
+		warnings = append(warnings, Warning{
+			Code:     WarnSelfTime,
+			Severity: SeverityWarning,
+			Metrics:  map[string]float64{"share": stats.PercentExclusive},
+			Text:     fmt.Sprintf("self time %.1f%% of plan", stats.PercentExclusive*100),
+		})
 	}
 	if stats.RowEstimateFactor >= 2.0 {
-		warnings = append(warnings, fmt.Sprintf("rows %.1fx higher than estimate", stats.RowEstimateFactor))
+		warnings = append(warnings, Warning{
+			Code:     WarnEstimateHigh,
+			Severity: SeverityWarning,
+			Metrics:  map[string]float64{"factor": stats.RowEstimateFactor, "actual": stats.ActualTotalRows, "estimated": stats.EstimatedRows},
+			Text:     fmt.Sprintf("rows %.1fx higher than estimate", stats.RowEstimateFactor),
+		})
 	} else if stats.RowEstimateFactor <= 0.5 {
-		warnings = append(warnings, fmt.Sprintf("rows %.1fx lower than estimate", stats.RowEstimateFactor))
+		warnings = append(warnings, Warning{
+			Code:     WarnEstimateLow,
+			Severity: SeverityWarning,
+			Metrics:  map[string]float64{"factor": stats.RowEstimateFactor, "actual": stats.ActualTotalRows, "estimated": stats.EstimatedRows},
+			Text:     fmt.Sprintf("rows %.1fx lower than estimate", stats.RowEstimateFactor),
+		})
 	}
 	if stats.Buffers.Total() > 0 && stats.PercentExclusive >= 0.05 {
-		warnings = append(warnings, "heavy buffer usage")
+		warnings = append(warnings, Warning{
+			Code:     WarnBufferHeavy,
+			Severity: SeverityInfo,
+			Metrics:  map[string]float64{"buffers": float64(stats.Buffers.Total())},
+			Text:     "heavy buffer usage",
+		})
+	}
+	if onDisk, _ := stats.SortOnDisk(); onDisk {
+		warnings = append(warnings, sortSpillWarning(stats))
+	}
+	if node := stats.Node; node.NodeType == "Hash" && node.HashBatches > 1 {
+		warnings = append(warnings, hashBatchWarning(stats))
+	}
+	if node := stats.Node; node.LossyHeapBlocks > 0 {
+		warnings = append(warnings, lossyBitmapWarning(stats))
+	}
+	if stats.HeapFetches >= 1000 && stats.HeapFetchRatio >= 0.5 {
+		warnings = append(warnings, Warning{
+			Code:     WarnHeapFetches,
+			Severity: SeverityWarning,
+			Metrics:  map[string]float64{"heap_fetches": stats.HeapFetches, "ratio": stats.HeapFetchRatio},
+			Text:     fmt.Sprintf("index-only scan fetched %.1f%% of its rows from the heap (%.0f heap fetches)", stats.HeapFetchRatio*100, stats.HeapFetches),
+		})
+	}
+	if w, ok := memoizeWarning(stats); ok {
+		warnings = append(warnings, w)
+	}
+	if share, ok := wastefulFilter(stats.RowsRemovedByFilter, stats.ActualTotalRows); ok {
+		warnings = append(warnings, Warning{
+			Code:     WarnFilterWaste,
+			Severity: SeverityWarning,
+			Metrics:  map[string]float64{"removed": stats.RowsRemovedByFilter, "share": share},
+			Text:     fmt.Sprintf("filter removed %.1f%% of %.0f rows", share*100, stats.RowsRemovedByFilter+stats.ActualTotalRows),
+		})
+	}
+	if share, ok := wastefulFilter(stats.RowsRemovedByJoinFilter, stats.ActualTotalRows); ok {
+		warnings = append(warnings, Warning{
+			Code:     WarnJoinFilterWaste,
+			Severity: SeverityWarning,
+			Metrics:  map[string]float64{"removed": stats.RowsRemovedByJoinFilter, "share": share},
+			Text:     fmt.Sprintf("join filter removed %.1f%% of %.0f row pairs", share*100, stats.RowsRemovedByJoinFilter+stats.ActualTotalRows),
+		})
 	}
 	return warnings
 }
+
+// sortSpillWarning describes a sort that went to disk, naming its method and disk usage when the node
+// itself (rather than only its workers) reported them. Its needed_kb metric is SortMemoryNeededKB.
+func sortSpillWarning(stats *NodeStats) Warning {
+	node := stats.Node
+	w := Warning{
+		Code:     WarnSortSpill,
+		Severity: SeverityWarning,
+		Metrics:  map[string]float64{"needed_kb": stats.SortMemoryNeededKB()},
+		Text:     "sort spilled to disk",
+	}
+	if node.SortMethod != "" {
+		w.Text += " (" + node.SortMethod
+		if node.SortSpaceType == "Disk" && node.SortSpaceUsedKB > 0 {
+			w.Text += fmt.Sprintf(", %.0f kB", node.SortSpaceUsedKB)
+			w.Metrics["disk_kb"] = node.SortSpaceUsedKB
+		}
+		w.Text += ")"
+	}
+	return w
+}
+
+// hashBatchWarning describes a hash table split into batches, noting when the planner expected fewer and
+// how much memory a single batch would have taken.
+func hashBatchWarning(stats *NodeStats) Warning {
+	node := stats.Node
+	needed := stats.HashMemoryNeededKB()
+	w := Warning{
+		Code:     WarnHashBatches,
+		Severity: SeverityWarning,
+		Metrics: map[string]float64{
+			"batches": node.HashBatches, "planned_batches": node.OriginalHashBatches, "needed_kb": needed,
+		},
+		Text: fmt.Sprintf("hash spilled to disk in %.0f batches", node.HashBatches),
+	}
+	if node.OriginalHashBatches > 0 && node.OriginalHashBatches < node.HashBatches {
+		w.Text += fmt.Sprintf(" (planned %.0f)", node.OriginalHashBatches)
+	}
+	if needed > 0 {
+		w.Text += fmt.Sprintf(", ~%.0f kB to fit in one", needed)
+	}
+	return w
+}
+
+// lossyBitmapWarning describes a Bitmap Heap Scan whose bitmap outgrew work_mem and fell back to whole
+// pages, making it recheck every tuple on them.
+func lossyBitmapWarning(stats *NodeStats) Warning {
+	node := stats.Node
+	pages := node.ExactHeapBlocks + node.LossyHeapBlocks
+	w := Warning{
+		Code:     WarnLossyBitmap,
+		Severity: SeverityWarning,
+		Metrics: map[string]float64{
+			"lossy_blocks": node.LossyHeapBlocks, "exact_blocks": node.ExactHeapBlocks,
+			"recheck_removed": stats.RowsRemovedByIndexRecheck,
+		},
+		Text: fmt.Sprintf("bitmap lossy on %.0f of %.0f heap blocks", node.LossyHeapBlocks, pages),
+	}
+	if stats.RowsRemovedByIndexRecheck > 0 {
+		w.Text += fmt.Sprintf(", recheck removed %.0f rows", stats.RowsRemovedByIndexRecheck)
+	}
+	return w
+}
+
+// wastefulFilter reports the share of rows a filter discarded when it threw away most of a sizeable input.
+func wastefulFilter(removed, kept float64) (float64, bool) {
+	if removed < 1000 {
+		return 0, false
+	}
+	share := removed / (removed + kept)
+	return share, share >= 0.9
+}
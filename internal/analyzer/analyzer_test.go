@@ -0,0 +1,586 @@
+package analyzer_test
+
+import (
+	"math"
+	"slices"
+	"testing"
+
+	"github.com/mickamy/xplain/internal/analyzer"
+	"github.com/mickamy/xplain/internal/model"
+	"github.com/mickamy/xplain/test"
+)
+
+func TestAnalyzeLimit(t *testing.T) {
+	analysis := test.LoadSampleAnalysis(t, "pgbench_hot.json")
+
+	limit := analysis.Limit
+	if limit == nil {
+		t.Fatalf("expected limit analysis for LIMIT plan")
+	}
+	if limit.RowsReturned != 20 {
+		t.Fatalf("expected 20 rows returned, got %v", limit.RowsReturned)
+	}
+	if limit.Sort == nil {
+		t.Fatalf("expected sort feeding the limit to be detected")
+	}
+	if limit.WastedNode == nil || limit.WastedNode.Node.NodeType != "Seq Scan" {
+		t.Fatalf("expected seq scan to be reported as wasted work, got %+v", limit.WastedNode)
+	}
+	if limit.WasteRatio() < 1000 {
+		t.Fatalf("expected large waste ratio, got %v", limit.WasteRatio())
+	}
+}
+
+func TestAnalyzeWithoutLimit(t *testing.T) {
+	analysis := test.LoadSampleAnalysis(t, "hash_spill.json")
+	if analysis.Limit != nil {
+		t.Fatalf("expected no limit analysis, got %+v", analysis.Limit)
+	}
+}
+
+func TestFunctionCalls(t *testing.T) {
+	calls := analyzer.FunctionCalls("((lower((email)::text) = 'x(y)'::text) AND (id = ANY ('{1,2}'::integer[])) AND (extract(year FROM created_at) = 2024))")
+	if len(calls) != 2 {
+		t.Fatalf("expected 2 calls, got %+v", calls)
+	}
+	if calls[0].Name != "lower" || calls[0].Expr != "lower((email)::text)" {
+		t.Fatalf("unexpected first call: %+v", calls[0])
+	}
+	if calls[1].Name != "extract" {
+		t.Fatalf("unexpected second call: %+v", calls[1])
+	}
+}
+
+func TestAnalyzeRowFunctions(t *testing.T) {
+	analysis, err := analyzer.Analyze(&model.Explain{Plan: &model.PlanNode{
+		NodeType: "Hash Join", HashCond: "(lower(o.email) = lower(u.email))", ActualRows: 10, ActualLoops: 1,
+		Output: []string{"u.id", "md5(o.note)"},
+		Children: []*model.PlanNode{
+			{NodeType: "Seq Scan", RelationName: "orders", Alias: "o", Output: []string{"o.email", "o.note"}, ActualRows: 10, ActualLoops: 1},
+			{NodeType: "Hash", Output: []string{"u.id", "u.email"}, ActualRows: 10, ActualLoops: 1},
+		},
+	}})
+	if err != nil {
+		t.Fatalf("analyze: %v", err)
+	}
+	root := analysis.Root
+	if len(root.ConditionFunctions) != 2 || root.ConditionFunctions[0].Expr != "lower(o.email)" {
+		t.Fatalf("expected both hash condition calls, got %+v", root.ConditionFunctions)
+	}
+	if len(root.OutputFunctions) != 1 || root.OutputFunctions[0].Name != "md5" {
+		t.Fatalf("expected the join's own output call, got %+v", root.OutputFunctions)
+	}
+}
+
+func TestAnalyzeCostModel(t *testing.T) {
+	analysis := test.LoadSampleAnalysis(t, "cost_skew.json")
+
+	model := analysis.CostModel
+	if model == nil {
+		t.Fatalf("expected cost model")
+	}
+	if model.CostPerMs != 100 {
+		t.Fatalf("expected plan ratio of 100 cost/ms, got %v", model.CostPerMs)
+	}
+	if model.Nodes != 4 || math.Abs(model.PlanCostPerMs-26500/4540.0) > 1e-9 {
+		t.Fatalf("expected 4 nodes and the root's 5.8 cost/ms, got %d, %v", model.Nodes, model.PlanCostPerMs)
+	}
+	if len(model.Outliers) != 1 || model.Outliers[0].Node.NodeType != "Index Scan" {
+		t.Fatalf("expected the index scan as only outlier, got %+v", model.Outliers)
+	}
+	if skew := model.Outliers[0].CostSkew; skew != 0.05 {
+		t.Fatalf("expected skew 0.05, got %v", skew)
+	}
+
+	// The index scan is 20x off, inside a wider allowance.
+	wide := test.LoadSampleAnalysisWith(t, "cost_skew.json", analyzer.Options{CostSkew: 25})
+	if len(wide.CostModel.Outliers) != 0 {
+		t.Fatalf("expected no outliers at a 25x skew, got %+v", wide.CostModel.Outliers)
+	}
+}
+
+func TestAnalyzeCostModelSkipsLimitedNodes(t *testing.T) {
+	analysis := test.LoadSampleAnalysis(t, "pgbench_branches.json")
+	if analysis.CostModel != nil && len(analysis.CostModel.Outliers) > 0 {
+		t.Fatalf("expected nodes below a LIMIT to be ignored, got %+v", analysis.CostModel.Outliers)
+	}
+}
+
+func TestAttachTempFiles(t *testing.T) {
+	analysis := test.LoadSampleAnalysis(t, "sort_orders.json")
+
+	analysis.AttachTempFiles([]model.TempFile{{Path: "a", SizeBytes: 18063360}, {Path: "b", SizeBytes: 18063360}})
+	sort := analysis.Root
+	if sort.TempFiles != 2 || sort.TempFileBytes != 36126720 {
+		t.Fatalf("expected both files on the sort, got %d files / %d bytes", sort.TempFiles, sort.TempFileBytes)
+	}
+	if scan := sort.Children[0]; scan.TempFiles != 0 {
+		t.Fatalf("expected no temp files on the scan, got %d", scan.TempFiles)
+	}
+}
+
+func TestAnalyzeHitRatio(t *testing.T) {
+	analysis := test.LoadSampleAnalysis(t, "pgbench_hot.json")
+
+	if want := 112.0 / (112 + 163935); math.Abs(analysis.HitRatio-want) > 1e-12 {
+		t.Fatalf("expected plan hit ratio %v, got %v", want, analysis.HitRatio)
+	}
+	scan := analysis.HotNodes[0]
+	if scan.SelfSharedHit != 0 || scan.SelfSharedRead != 163935 || scan.HitRatio != 0 {
+		t.Fatalf("expected the scan to read all its blocks, got hit %d read %d ratio %v",
+			scan.SelfSharedHit, scan.SelfSharedRead, scan.HitRatio)
+	}
+	// The Sort above the scan accessed no shared blocks of its own.
+	if sort := scan.Parent; sort.SelfSharedHit+sort.SelfSharedRead != 112 || sort.Buffers.SharedRead != 163935 {
+		t.Fatalf("expected the sort's own blocks net of the scan, got hit %d read %d",
+			sort.SelfSharedHit, sort.SelfSharedRead)
+	}
+}
+
+func TestAnalyzeIOTime(t *testing.T) {
+	analysis := test.LoadSampleAnalysis(t, "sort_orders.json")
+
+	if want := 31.21 + 9.84 + 14.32; math.Abs(analysis.IOTimeMs-want) > 0.01 {
+		t.Fatalf("expected plan I/O time %v, got %v", want, analysis.IOTimeMs)
+	}
+	// The Sort only owns its temp I/O; the shared reads belong to the scan below it.
+	sort := analysis.Root
+	scan := sort.Children[0]
+	if math.Abs(sort.IOTimeMs-(9.84+14.32)) > 0.01 || math.Abs(scan.IOTimeMs-31.21) > 0.01 {
+		t.Fatalf("expected self I/O split between sort and scan, got %v and %v", sort.IOTimeMs, scan.IOTimeMs)
+	}
+	if math.Abs(sort.PercentIO+scan.PercentIO-1) > 1e-9 || scan.PercentIO < sort.PercentIO {
+		t.Fatalf("unexpected I/O shares %v and %v", sort.PercentIO, scan.PercentIO)
+	}
+}
+
+func TestAnalyzeDivergenceImpact(t *testing.T) {
+	analysis, err := analyzer.Analyze(&model.Explain{Plan: &model.PlanNode{
+		NodeType: "Hash Join", PlanRows: 1000, ActualTotalTime: 900, ActualRows: 3000, ActualLoops: 1,
+		Children: []*model.PlanNode{
+			{NodeType: "Seq Scan", RelationName: "orders", PlanRows: 3000, ActualTotalTime: 99.99, ActualRows: 3000, ActualLoops: 1},
+			{NodeType: "Hash", PlanRows: 100, ActualTotalTime: 0.01, ActualRows: 1, ActualLoops: 1,
+				Children: []*model.PlanNode{{NodeType: "Index Scan", RelationName: "customers", PlanRows: 100,
+					ActualTotalTime: 0.005, ActualRows: 1, ActualLoops: 1}}},
+		},
+	}})
+	if err != nil {
+		t.Fatalf("analyze: %v", err)
+	}
+	// The 100x misses on the customers lookup took no time; the 3x miss on the 800 ms join did.
+	join := analysis.Root
+	if len(analysis.DivergentNodes) != 3 || analysis.DivergentNodes[0] != join {
+		t.Fatalf("expected the join to lead the divergent nodes, got %v", analysis.DivergentNodes)
+	}
+	if join.EstimateError() != 3 || math.Abs(join.EstimateImpact-3*800) > 1e-9 {
+		t.Fatalf("unexpected join impact %v (error %v)", join.EstimateImpact, join.EstimateError())
+	}
+	if lookup := analysis.DivergentNodes[2]; lookup.EstimateError() != 100 {
+		t.Fatalf("expected the lookup last despite its error, got %v", lookup.EstimateError())
+	}
+}
+
+func TestAnalyzeHotSelection(t *testing.T) {
+	analysis := test.LoadSampleAnalysis(t, "sort_orders.json")
+	if sel := analysis.HotSelection; sel.By != analyzer.HotBySelf || sel.Limit != 5 || *sel.MinPercent != 0.10 {
+		t.Fatalf("expected the default selection, got %+v", sel)
+	}
+	if len(analysis.HotNodes) != 2 || analysis.HotNodes[0].Node.NodeType != "Sort" {
+		t.Fatalf("expected the sort to lead by self time, got %d hot nodes", len(analysis.HotNodes))
+	}
+
+	// The scan waited on more I/O than the sort, which spent its time sorting.
+	byIO := test.LoadSampleAnalysisWith(t, "sort_orders.json", analyzer.Options{
+		Hot: analyzer.HotSelection{By: analyzer.HotByIO, Limit: 1},
+	})
+	if len(byIO.HotNodes) != 1 || byIO.HotNodes[0].Node.NodeType != "Seq Scan" {
+		t.Fatalf("expected the scan to lead by I/O time, got %v", byIO.HotNodes)
+	}
+	// The sort's own 8808 temp blocks outweigh the scan's 5874.
+	half := 0.5
+	byBuffers := test.LoadSampleAnalysisWith(t, "sort_orders.json", analyzer.Options{
+		Hot: analyzer.HotSelection{By: analyzer.HotByBuffers, MinPercent: &half},
+	})
+	sort := byBuffers.Root
+	if len(byBuffers.HotNodes) != 1 || byBuffers.HotNodes[0] != sort || math.Abs(sort.PercentBuffers-8808.0/14682) > 1e-9 {
+		t.Fatalf("expected only the sort above half the buffers, got %v (%v)", byBuffers.HotNodes, sort.PercentBuffers)
+	}
+	// A zero threshold is kept rather than defaulted, so the long tail below 10% shows up.
+	zero := 0.0
+	tail := test.LoadSampleAnalysisWith(t, "pgbench_hot.json", analyzer.Options{
+		Hot: analyzer.HotSelection{MinPercent: &zero},
+	})
+	if len(tail.HotNodes) != 4 || len(test.LoadSampleAnalysis(t, "pgbench_hot.json").HotNodes) != 1 {
+		t.Fatalf("expected every node with a share without a threshold, got %v", tail.HotNodes)
+	}
+	if _, err := analyzer.ParseHotRanking("rows"); err == nil {
+		t.Fatalf("expected unknown rankings to fail")
+	}
+}
+
+func TestAnalyzeShareWarnings(t *testing.T) {
+	// The warnings that depend on a node's share of the runtime see the shares computed for the whole plan.
+	analysis := test.LoadSampleAnalysis(t, "memory_heavy.json")
+	orders := analysis.HotNodes[0]
+	if orders.Node.RelationName != "orders" || !orders.HasWarning(analyzer.WarnSelfTime) {
+		t.Fatalf("expected a self-time warning on the hottest node, got %v", orders.WarningTexts())
+	}
+	if !orders.HasWarning(analyzer.WarnBufferHeavy) {
+		t.Fatalf("expected a buffer-heavy warning on the orders scan, got %v", orders.WarningTexts())
+	}
+	if hash := orders.Parent.Children[1]; hash.HasWarning(analyzer.WarnSelfTime) {
+		t.Fatalf("expected no self-time warning below 20%% of the plan, got %v", hash.WarningTexts())
+	}
+}
+
+func TestAnalyzeBufferHeavy(t *testing.T) {
+	// Only the scans report buffers, so each is measured against the blocks both scans accessed.
+	analysis := test.LoadSampleAnalysis(t, "memory_heavy.json")
+	if len(analysis.BufferHeavy) != 2 {
+		t.Fatalf("expected the two scans, got %v", analysis.BufferHeavy)
+	}
+	orders := analysis.BufferHeavy[0]
+	if orders.Node.RelationName != "orders" || orders.SelfBuffers != 31300 || math.Abs(orders.PercentBuffers-31300.0/38800) > 1e-9 {
+		t.Fatalf("expected the orders scan to lead with 31300 own blocks, got %+v", orders)
+	}
+}
+
+func TestAnalyzePercentBases(t *testing.T) {
+	analysis := test.LoadSampleAnalysis(t, "pg17_serialize.json")
+
+	root := analysis.Root
+	if root.PercentOfRoot.Exclusive != 1 || root.Percent(analyzer.PercentRoot) != root.PercentOfRoot {
+		t.Fatalf("expected the lone scan to be the whole tree, got %+v", root.PercentOfRoot)
+	}
+	// Sending 50000 wide rows makes up most of the reported Execution Time.
+	want := root.InclusiveTimeMs / analysis.ExecutionTimeMs
+	if got := root.Percent(analyzer.PercentExecution); got.Exclusive != want || got.Inclusive != want {
+		t.Fatalf("expected %v of the execution time, got %+v", want, got)
+	}
+	if _, err := analyzer.ParsePercentBase("wall"); err == nil {
+		t.Fatalf("expected unknown percent bases to fail")
+	}
+}
+
+func TestAnalyzeWorkers(t *testing.T) {
+	analysis, err := analyzer.Analyze(&model.Explain{Plan: &model.PlanNode{
+		NodeType: "Gather", ActualTotalTime: 40, ActualRows: 300, ActualLoops: 1,
+		Children: []*model.PlanNode{{
+			NodeType: "Parallel Seq Scan", ActualTotalTime: 35, ActualRows: 100, ActualLoops: 3,
+			Workers: []model.Worker{
+				{Number: 0, ActualTotalTime: 34.5, ActualRows: 140, ActualLoops: 1, Buffers: model.Buffers{SharedRead: 300}},
+				{Number: 1, ActualTotalTime: 33.9, ActualRows: 90},
+			},
+		}},
+	}})
+	if err != nil {
+		t.Fatalf("analyze: %v", err)
+	}
+	workers := analysis.Root.Children[0].Workers
+	if len(workers) != 2 {
+		t.Fatalf("expected 2 worker stats, got %+v", workers)
+	}
+	if workers[0].TimeMs != 34.5 || workers[0].Rows != 140 || workers[0].Buffers.SharedRead != 300 {
+		t.Fatalf("unexpected first worker %+v", workers[0])
+	}
+	if share := workers[1].RowShare; share != 0.3 {
+		t.Fatalf("expected the second worker to produce 30%% of the rows, got %v", share)
+	}
+	// The leader produced the remaining 70 rows and counts as a third process.
+	skew := analysis.Root.WorkerSkew
+	if skew == nil || skew.Node != analysis.Root.Children[0] || skew.Processes != 3 || skew.Busiest.Number != 0 {
+		t.Fatalf("unexpected worker skew %+v", skew)
+	}
+	if skew.MaxRows != 140 || skew.MeanRows != 100 || skew.RowRatio() != 1.4 || skew.MaxTimeMs != 34.5 {
+		t.Fatalf("unexpected worker skew figures %+v", skew)
+	}
+	if analysis.Root.Children[0].WorkerSkew != nil {
+		t.Fatalf("expected skew only on the Gather")
+	}
+}
+
+func TestAnalyzeParallelTime(t *testing.T) {
+	// The leader and two workers each scanned for about 30 ms per loop, side by side.
+	plan := &model.Explain{Plan: &model.PlanNode{
+		NodeType: "Gather", ActualTotalTime: 40, ActualRows: 300, ActualLoops: 1, WorkersLaunched: 2,
+		Children: []*model.PlanNode{{
+			NodeType: "Hash Join", ActualTotalTime: 35, ActualRows: 100, ActualLoops: 3,
+			Children: []*model.PlanNode{
+				{NodeType: "Parallel Seq Scan", ActualTotalTime: 20, ActualRows: 1000, ActualLoops: 3},
+				{NodeType: "Hash", ActualTotalTime: 0.5, ActualRows: 10, ActualLoops: 6},
+			},
+		}},
+	}}
+	wall, err := analyzer.Analyze(plan)
+	if err != nil {
+		t.Fatalf("analyze: %v", err)
+	}
+	join := wall.Root.Children[0]
+	if wall.Time != analyzer.WallClock || join.Processes != 3 || join.InclusiveTimeMs != 35 || wall.Root.ExclusiveTimeMs != 5 {
+		t.Fatalf("unexpected wall-clock times: processes %v, join %v, gather self %v", join.Processes, join.InclusiveTimeMs, wall.Root.ExclusiveTimeMs)
+	}
+	// The Hash ran twice in each process.
+	if hash := join.Children[1]; hash.InclusiveTimeMs != 1 || join.ExclusiveTimeMs != 14 {
+		t.Fatalf("unexpected hash %v and join self %v", hash.InclusiveTimeMs, join.ExclusiveTimeMs)
+	}
+	if join.ActualTotalRows != 300 {
+		t.Fatalf("expected rows to stay summed over processes, got %v", join.ActualTotalRows)
+	}
+
+	cpu, err := analyzer.AnalyzeWith(plan, analyzer.Options{Time: analyzer.CPUTime})
+	if err != nil {
+		t.Fatalf("analyze: %v", err)
+	}
+	if join := cpu.Root.Children[0]; join.InclusiveTimeMs != 105 || join.ExclusiveTimeMs != 42 || cpu.Root.ExclusiveTimeMs != 0 {
+		t.Fatalf("unexpected CPU times: join %v self %v, gather self %v", join.InclusiveTimeMs, join.ExclusiveTimeMs, cpu.Root.ExclusiveTimeMs)
+	}
+	if _, err := analyzer.ParseTimeAttribution("elapsed"); err == nil {
+		t.Fatalf("expected unknown attributions to fail")
+	}
+}
+
+func TestNodePaths(t *testing.T) {
+	analysis := test.LoadSampleAnalysis(t, "nloop_index.json")
+
+	scan := analysis.NodeByPath("0.1.0.0.0.0.0")
+	if scan == nil || scan.Node.Alias != "inner_accounts" {
+		t.Fatalf("unexpected node at 0.1.0.0.0.0.0: %+v", scan)
+	}
+	if scan.Path != scan.Node.ID {
+		t.Fatalf("expected the path to match the parsed node ID %q, got %q", scan.Node.ID, scan.Path)
+	}
+	ancestors := scan.Ancestors()
+	if len(ancestors) != 6 || ancestors[0] != scan.Parent || ancestors[5] != analysis.Root {
+		t.Fatalf("unexpected ancestors %v", ancestors)
+	}
+	if analysis.NodeByPath("0.9") != nil {
+		t.Fatalf("expected no node at 0.9")
+	}
+}
+
+func TestWorkerSkewNeedsVerbose(t *testing.T) {
+	// Without VERBOSE the workers only report their sort method, which says nothing about the split.
+	analysis := test.LoadSampleAnalysis(t, "hash_spill.json")
+	for _, n := range analysis.Nodes() {
+		if n.WorkerSkew != nil {
+			t.Fatalf("expected no worker skew for %s, got %+v", n.Node.NodeType, n.WorkerSkew)
+		}
+	}
+}
+
+func TestAnalyzeRemovedRows(t *testing.T) {
+	analysis, err := analyzer.Analyze(&model.Explain{Plan: &model.PlanNode{
+		NodeType: "Nested Loop", ActualTotalTime: 50, ActualRows: 20, ActualLoops: 1,
+		JoinFilter: "(o.total > c.credit_limit)", RowsRemovedJoinFilter: 4980,
+		Children: []*model.PlanNode{
+			{NodeType: "Seq Scan", RelationName: "customers", ActualTotalTime: 5, ActualRows: 50, ActualLoops: 1, RowsRemovedFilter: 10},
+			{NodeType: "Seq Scan", RelationName: "orders", ActualTotalTime: 0.5, ActualRows: 100, ActualLoops: 50, RowsRemovedFilter: 400},
+		},
+	}})
+	if err != nil {
+		t.Fatalf("analyze: %v", err)
+	}
+	join := analysis.Root
+	if join.RowsRemovedByJoinFilter != 4980 {
+		t.Fatalf("expected 4980 rows removed by the join filter, got %v", join.RowsRemovedByJoinFilter)
+	}
+	if !slices.Contains(join.WarningTexts(), "join filter removed 99.6% of 5000 row pairs") || !join.HasWarning(analyzer.WarnJoinFilterWaste) {
+		t.Fatalf("expected a join filter warning, got %v", join.Warnings)
+	}
+	customers, orders := join.Children[0], join.Children[1]
+	// 80% discarded is below the warning threshold.
+	if orders.RowsRemovedByFilter != 20000 || orders.HasWarning(analyzer.WarnFilterWaste) {
+		t.Fatalf("unexpected orders filter stats %v %v", orders.RowsRemovedByFilter, orders.Warnings)
+	}
+	if customers.RowsRemovedByFilter != 10 {
+		t.Fatalf("expected 10 rows removed from customers, got %v", customers.RowsRemovedByFilter)
+	}
+}
+
+func TestAnalyzeSortOnDisk(t *testing.T) {
+	analysis := test.LoadSampleAnalysis(t, "envelope_orders.json")
+	var sort *analyzer.NodeStats
+	for _, n := range analysis.Nodes() {
+		if n.Node.NodeType == "Sort" {
+			sort = n
+		}
+	}
+	if sort == nil || sort.Node.SortMethod != "external merge" || sort.Node.SortSpaceType != "Disk" {
+		t.Fatalf("expected an external merge sort, got %+v", sort)
+	}
+	if onDisk, known := sort.SortOnDisk(); !onDisk || !known {
+		t.Fatalf("expected the sort on disk, got %v %v", onDisk, known)
+	}
+	if !slices.Contains(sort.WarningTexts(), "sort spilled to disk (external merge, 35184 kB)") {
+		t.Fatalf("expected a sort spill warning, got %v", sort.Warnings)
+	}
+
+	// An in-memory sort above a spilling hash join still sees the join's temp blocks in its buffers.
+	memory, err := analyzer.Analyze(&model.Explain{Plan: &model.PlanNode{
+		NodeType: "Sort", SortMethod: "quicksort", SortSpaceUsedKB: 120, SortSpaceType: "Memory",
+		ActualTotalTime: 80, ActualRows: 500, ActualLoops: 1, Buffers: model.Buffers{TempRead: 4000, TempWritten: 4000},
+		Children: []*model.PlanNode{{NodeType: "Hash Join", ActualTotalTime: 75, ActualRows: 500, ActualLoops: 1,
+			Buffers: model.Buffers{TempRead: 4000, TempWritten: 4000}}},
+	}})
+	if err != nil {
+		t.Fatalf("analyze: %v", err)
+	}
+	if onDisk, known := memory.Root.SortOnDisk(); onDisk || !known {
+		t.Fatalf("expected an in-memory sort, got %v %v", onDisk, known)
+	}
+	if onDisk, known := memory.Root.Children[0].SortOnDisk(); onDisk || known {
+		t.Fatalf("expected no sort details on the join, got %v %v", onDisk, known)
+	}
+	if memory.Root.SortClass() != analyzer.SortInMemory || memory.Root.SortMemoryNeededKB() != 0 {
+		t.Fatalf("expected the quicksort classed in memory, got %q", memory.Root.SortClass())
+	}
+
+	// 150000 rows of 20 bytes take less than the 35184 kB the sort wrote, so the disk space is the floor.
+	if sort.SortClass() != analyzer.SortDisk || sort.SortMemoryNeededKB() != 35184 {
+		t.Fatalf("expected a disk sort needing 35184 kB, got %q %v", sort.SortClass(), sort.SortMemoryNeededKB())
+	}
+	for _, n := range test.LoadSampleAnalysis(t, "pgbench_hot.json").Nodes() {
+		if n.Node.NodeType == "Sort" && n.SortClass() != analyzer.SortTopN {
+			t.Fatalf("expected the LIMIT's sort to be a top-N heapsort, got %q", n.SortClass())
+		}
+	}
+}
+
+func TestAnalyzeHashBatches(t *testing.T) {
+	analysis, err := analyzer.Analyze(&model.Explain{Plan: &model.PlanNode{
+		NodeType: "Hash Join", ActualTotalTime: 900, ActualRows: 1e6, ActualLoops: 1,
+		Buffers: model.Buffers{TempRead: 30000, TempWritten: 30000},
+		Children: []*model.PlanNode{
+			{NodeType: "Seq Scan", RelationName: "orders", ActualTotalTime: 200, ActualRows: 1e6, ActualLoops: 1},
+			{NodeType: "Hash", ActualTotalTime: 300, ActualRows: 5e5, ActualLoops: 1,
+				HashBuckets: 262144, OriginalHashBuckets: 262144, HashBatches: 8, OriginalHashBatches: 1, PeakMemoryKB: 4100,
+				Buffers: model.Buffers{TempWritten: 12000}},
+		},
+	}})
+	if err != nil {
+		t.Fatalf("analyze: %v", err)
+	}
+	join, hash := analysis.Root, analysis.Root.Children[1]
+	if join.HashNode() != hash || hash.HashNode() != hash || join.Children[0].HashNode() != nil {
+		t.Fatal("expected the Hash child to build the join's hash table")
+	}
+	if batched, known := join.HashBatched(); !batched || !known {
+		t.Fatalf("expected the join to report batching, got %v %v", batched, known)
+	}
+	if !slices.Contains(hash.WarningTexts(), "hash spilled to disk in 8 batches (planned 1), ~32800 kB to fit in one") {
+		t.Fatalf("expected a batch warning, got %v", hash.Warnings)
+	}
+	w := hash.Warnings[len(hash.Warnings)-1]
+	if w.Code != analyzer.WarnHashBatches || w.Severity != analyzer.SeverityWarning || w.Metrics["batches"] != 8 || w.Metrics["planned_batches"] != 1 {
+		t.Fatalf("expected a typed batch warning, got %+v", w)
+	}
+	// One batch peaked at 4100 kB, so all eight would have needed eight times that.
+	if join.HashMemoryNeededKB() != 32800 || w.Metrics["needed_kb"] != 32800 {
+		t.Fatalf("expected 32800 kB for a single batch, got %v", join.HashMemoryNeededKB())
+	}
+	// Without a reported peak the estimate falls back to the rows and their width.
+	hash.Node.PeakMemoryKB, hash.Node.PlanWidth = 0, 16
+	if got := hash.HashMemoryNeededKB(); got != 5e5*(16+48)/1024 {
+		t.Fatalf("unexpected width-based estimate %v", got)
+	}
+
+	single := test.LoadSampleAnalysis(t, "hash_spill.json")
+	for _, n := range single.Nodes() {
+		if n.Node.NodeType == "Hash" {
+			if n.Node.HashBuckets != 1024 || n.Node.HashBatches != 1 || n.Node.PeakMemoryKB != 9 {
+				t.Fatalf("unexpected hash layout %+v", n.Node)
+			}
+			if batched, known := n.HashBatched(); batched || !known || n.HashMemoryNeededKB() != 0 {
+				t.Fatalf("expected a single in-memory batch, got %v %v", batched, known)
+			}
+		}
+	}
+}
+
+func TestAnalyzePruning(t *testing.T) {
+	startup := test.LoadSampleAnalysis(t, "planning_heavy.json").Root
+	if p, ok := startup.Pruning(); !ok || p.Removed != 1199 || p.Scanned() != 1 || p.Pruned() != 1199 {
+		t.Fatalf("unexpected startup pruning %+v", p)
+	}
+
+	analysis, err := analyzer.Analyze(&model.Explain{Plan: &model.PlanNode{
+		NodeType: "Append", ActualTotalTime: 4, ActualRows: 10, ActualLoops: 1, SubplansRemoved: 2,
+		Children: []*model.PlanNode{
+			{NodeType: "Seq Scan", RelationName: "events_2026_09", ActualTotalTime: 1, ActualRows: 4, ActualLoops: 1},
+			{NodeType: "Seq Scan", RelationName: "events_2026_10", ActualTotalTime: 2, ActualRows: 6, ActualLoops: 1},
+			{NodeType: "Seq Scan", RelationName: "events_2026_11"},
+		},
+	}})
+	if err != nil {
+		t.Fatalf("analyze: %v", err)
+	}
+	p, ok := analysis.Root.Pruning()
+	if !ok || p.Planned != 3 || p.NeverExecuted != 1 || p.Scanned() != 2 || p.Pruned() != 3 {
+		t.Fatalf("unexpected runtime pruning %+v", p)
+	}
+	if _, ok := analysis.Root.Children[0].Pruning(); ok {
+		t.Fatal("expected no pruning details for a scan")
+	}
+}
+
+func TestTotalBuffersIncludePlanning(t *testing.T) {
+	analysis := test.LoadSampleAnalysis(t, "planning_heavy.json")
+
+	var nodes int64
+	for _, n := range analysis.Nodes() {
+		nodes += n.Buffers.Total()
+	}
+	if want := nodes + analysis.PlanningBuffers.Total(); analysis.TotalBuffers != want {
+		t.Fatalf("expected %d total buffers (nodes plus planning), got %d", want, analysis.TotalBuffers)
+	}
+}
+
+func TestAttributeSharedCTE(t *testing.T) {
+	analysis := test.LoadSampleAnalysis(t, "cte_shared.json")
+
+	join := analysis.Root
+	cte, first, hash := join.Children[0], join.Children[1], join.Children[2]
+	second := hash.Children[0]
+	for _, tc := range []struct {
+		node *analyzer.NodeStats
+		want float64
+	}{
+		{join, 7.004}, {cte, 15.004}, {first, 5.004}, {second, 7.502},
+	} {
+		if math.Abs(tc.node.ExclusiveTimeMs-tc.want) > 1e-6 {
+			t.Fatalf("%s: expected self time %.3f ms, got %.3f ms", tc.node.Node.NodeType, tc.want, tc.node.ExclusiveTimeMs)
+		}
+	}
+	if len(first.References) != 1 || first.References[0] != cte || len(second.References) != 1 {
+		t.Fatalf("expected both CTE Scans to reference the CTE, got %v and %v", first.References, second.References)
+	}
+
+	var self float64
+	for _, n := range analysis.Nodes() {
+		self += n.ExclusiveTimeMs
+	}
+	if math.Abs(self-analysis.TotalTimeMs) > 1e-6 {
+		t.Fatalf("expected self times to add up to %.3f ms, got %.3f ms", analysis.TotalTimeMs, self)
+	}
+}
+
+func TestAttributeInitPlanToReference(t *testing.T) {
+	plan := &model.PlanNode{
+		NodeType: "Nested Loop", ActualTotalTime: 35, ActualLoops: 1,
+		Children: []*model.PlanNode{
+			{NodeType: "Result", ParentRelationship: "InitPlan", SubplanName: "InitPlan 1 (returns $0)", ActualTotalTime: 10, ActualLoops: 1},
+			{NodeType: "Seq Scan", ParentRelationship: "Outer", RelationName: "accounts", Filter: "(balance > $0)", ActualTotalTime: 20, ActualLoops: 1},
+			{NodeType: "Index Scan", ParentRelationship: "Inner", RelationName: "branches", ActualTotalTime: 3, ActualLoops: 1},
+		},
+	}
+	analysis, err := analyzer.Analyze(&model.Explain{Plan: plan})
+	if err != nil {
+		t.Fatalf("analyze: %v", err)
+	}
+	if got := analysis.Root.ExclusiveTimeMs; got != 12 {
+		t.Fatalf("expected the join to keep 12 ms of self time, got %.2f", got)
+	}
+	if scan := analysis.Root.Children[1]; scan.ExclusiveTimeMs != 10 || len(scan.References) != 1 {
+		t.Fatalf("expected the scan evaluating $0 to hand the InitPlan time back, got %.2f ms, %d references", scan.ExclusiveTimeMs, len(scan.References))
+	}
+}
@@ -0,0 +1,117 @@
+package analyzer
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+const (
+	// defaultCostSkew is the cost skew a node is flagged at when Options.CostSkew is unset.
+	defaultCostSkew = 10.0
+	// costSkewMinPercent and costSkewMinMs keep nodes with negligible self time from being flagged;
+	// sub-millisecond timings are dominated by fixed overhead rather than the cost model.
+	costSkewMinPercent = 0.05
+	costSkewMinMs      = 1.0
+)
+
+// CostModel relates the planner's cost units to the time the plan actually took.
+type CostModel struct {
+	// CostPerMs is the plan's effective ratio of estimated cost to actual milliseconds: the median of
+	// the node ratios, so one badly costed node that dominates the runtime cannot set the baseline.
+	CostPerMs float64
+	// Nodes counts the nodes CostPerMs was taken from: those with both a self cost and self time.
+	Nodes int
+	// PlanCostPerMs is the root's total cost over the plan's runtime, the calibration of the plan as a
+	// whole. Far below CostPerMs, the time went to nodes the planner thought cheap.
+	PlanCostPerMs float64
+	// Outliers are nodes whose own ratio is at least Options.CostSkew away from CostPerMs, worst first.
+	Outliers []*NodeStats
+}
+
+// SelfCost estimates the planner cost attributed to the node itself across all loops: its total
+// cost minus the total cost of its children. Rescan costs make this approximate for inner loops.
+func (n *NodeStats) SelfCost() float64 {
+	self := n.Node.TotalCost * n.ActualLoops
+	for _, child := range n.Children {
+		self -= child.Node.TotalCost * child.ActualLoops
+	}
+	return math.Max(self, 0)
+}
+
+// CostPerMs reports the node's estimated self cost per actual millisecond of self time.
+func (n *NodeStats) CostPerMs() float64 {
+	if n.ExclusiveTimeMs <= 0 {
+		return 0
+	}
+	return n.SelfCost() / n.ExclusiveTimeMs
+}
+
+func analyzeCostModel(nodes []*NodeStats, skew float64) *CostModel {
+	if skew <= 0 {
+		skew = defaultCostSkew
+	}
+	var candidates []*NodeStats
+	var ratios []float64
+	for _, n := range nodes {
+		if n.CostPerMs() <= 0 || belowLimit(n) {
+			continue
+		}
+		candidates = append(candidates, n)
+		ratios = append(ratios, n.CostPerMs())
+	}
+	if len(ratios) == 0 {
+		return nil
+	}
+	sort.Float64s(ratios)
+	median := ratios[len(ratios)/2]
+	if len(ratios)%2 == 0 {
+		median = (ratios[len(ratios)/2-1] + median) / 2
+	}
+
+	model := &CostModel{CostPerMs: median, Nodes: len(ratios)}
+	if root := nodes[0]; root.InclusiveTimeMs > 0 {
+		model.PlanCostPerMs = root.Node.TotalCost * root.ActualLoops / root.InclusiveTimeMs
+	}
+	for _, n := range candidates {
+		if n.PercentExclusive < costSkewMinPercent || n.ExclusiveTimeMs < costSkewMinMs {
+			continue
+		}
+		n.CostSkew = n.CostPerMs() / model.CostPerMs
+		metrics := map[string]float64{"cost_per_ms": n.CostPerMs(), "plan_cost_per_ms": model.CostPerMs, "skew": n.CostSkew}
+		switch {
+		case n.CostSkew <= 1/skew:
+			n.Warnings = append(n.Warnings, Warning{
+				Code:     WarnCostSlower,
+				Severity: SeverityInfo,
+				Metrics:  metrics,
+				Text:     fmt.Sprintf("%.0fx slower than its cost suggests", 1/n.CostSkew),
+			})
+		case n.CostSkew >= skew:
+			n.Warnings = append(n.Warnings, Warning{
+				Code:     WarnCostFaster,
+				Severity: SeverityInfo,
+				Metrics:  metrics,
+				Text:     fmt.Sprintf("%.0fx faster than its cost suggests", n.CostSkew),
+			})
+		default:
+			continue
+		}
+		model.Outliers = append(model.Outliers, n)
+	}
+	sort.SliceStable(model.Outliers, func(i, j int) bool {
+		return math.Abs(math.Log(model.Outliers[i].CostSkew)) > math.Abs(math.Log(model.Outliers[j].CostSkew))
+	})
+	return model
+}
+
+// belowLimit reports whether a LIMIT above the node may stop it early, in which case its total
+// cost describes work that never ran.
+func belowLimit(n *NodeStats) bool {
+	for p := n.Parent; p != nil; p = p.Parent {
+		if p.Node.NodeType == "Limit" {
+			return true
+		}
+	}
+	return false
+}
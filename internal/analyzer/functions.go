@@ -0,0 +1,107 @@
+package analyzer
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/mickamy/xplain/internal/model"
+)
+
+// FunctionCall is a function invocation found in a plan expression.
+type FunctionCall struct {
+	Name string
+	// Expr is the full call text including arguments, e.g. lower((email)::text).
+	Expr string
+}
+
+var functionStart = regexp.MustCompile(`([A-Za-z_][\w$]*(?:\.[A-Za-z_][\w$]*)?)\(`)
+
+// keywords that deparsed expressions place directly before a parenthesis.
+var nonFunctionWords = map[string]struct{}{
+	"any": {}, "all": {}, "array": {}, "row": {}, "in": {}, "exists": {}, "and": {}, "or": {}, "not": {},
+}
+
+// FunctionCalls lists the function calls that appear in a deparsed plan expression.
+func FunctionCalls(expr string) []FunctionCall {
+	if !strings.Contains(expr, "(") {
+		return nil
+	}
+	masked := maskLiterals(expr)
+	var out []FunctionCall
+	for _, loc := range functionStart.FindAllStringSubmatchIndex(masked, -1) {
+		name := masked[loc[2]:loc[3]]
+		if loc[2] > 0 && isIdentChar(masked[loc[2]-1]) {
+			continue
+		}
+		if _, skip := nonFunctionWords[strings.ToLower(name)]; skip {
+			continue
+		}
+		end := matchingParen(masked, loc[1]-1)
+		if end < 0 {
+			continue
+		}
+		out = append(out, FunctionCall{Name: name, Expr: expr[loc[2] : end+1]})
+	}
+	return out
+}
+
+// outputFunctions lists the calls in node's output list that none of its children already output, since a
+// parent repeats the expressions it receives from below.
+func outputFunctions(node *model.PlanNode) []FunctionCall {
+	var out []FunctionCall
+	for _, expr := range node.Output {
+		for _, call := range FunctionCalls(expr) {
+			if !childOutputs(node, call.Expr) {
+				out = append(out, call)
+			}
+		}
+	}
+	return out
+}
+
+func childOutputs(node *model.PlanNode, expr string) bool {
+	for _, child := range node.Children {
+		for _, out := range child.Output {
+			if strings.Contains(out, expr) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// maskLiterals blanks out quoted strings so their contents are not mistaken for calls.
+func maskLiterals(expr string) string {
+	b := []byte(expr)
+	inQuote := false
+	for i := 0; i < len(b); i++ {
+		if b[i] == '\'' {
+			inQuote = !inQuote
+			continue
+		}
+		if inQuote {
+			b[i] = ' '
+		}
+	}
+	return string(b)
+}
+
+func matchingParen(s string, open int) int {
+	depth := 0
+	for i := open; i < len(s); i++ {
+		switch s[i] {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+func isIdentChar(c byte) bool {
+	return c == '_' || c == '$' || c == '.' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+}
@@ -0,0 +1,130 @@
+package analyzer
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// HotRanking selects the measure hot nodes are ranked by.
+type HotRanking string
+
+const (
+	// HotBySelf ranks nodes by their share of the runtime spent in the node itself. It is the default.
+	HotBySelf HotRanking = "exclusive"
+	// HotByInclusive ranks nodes by their share of the runtime including their children, pointing at the
+	// subtrees that cost the most rather than at single operators.
+	HotByInclusive HotRanking = "inclusive"
+	// HotByBuffers ranks nodes by their share of the blocks the plan accessed, net of their children's.
+	HotByBuffers HotRanking = "buffers"
+	// HotByIO ranks nodes by their share of the plan's I/O time (PercentIO), which needs track_io_timing.
+	HotByIO HotRanking = "io"
+)
+
+// ParseHotRanking validates a --hot-by value; empty selects HotBySelf.
+func ParseHotRanking(s string) (HotRanking, error) {
+	switch r := HotRanking(strings.ToLower(strings.TrimSpace(s))); r {
+	case "":
+		return HotBySelf, nil
+	case HotBySelf, HotByInclusive, HotByBuffers, HotByIO:
+		return r, nil
+	default:
+		return "", fmt.Errorf("analyze: unknown hot node ranking %q (expected exclusive, inclusive, buffers or io)", s)
+	}
+}
+
+// Measure names what r ranks by, e.g. "self time".
+func (r HotRanking) Measure() string {
+	switch r {
+	case HotByInclusive:
+		return "inclusive time"
+	case HotByBuffers:
+		return "own buffers"
+	case HotByIO:
+		return "I/O time"
+	default:
+		return "self time"
+	}
+}
+
+// HotSelection picks the hot nodes: up to Limit nodes, ranked By their share of a measure, that take at
+// least MinPercent of it. When no node reaches MinPercent the top Limit are kept, so a plan whose time is
+// spread thin still names where it goes. Zero By and Limit and a nil MinPercent select the defaults, the
+// top 5 by self time at 10%; a MinPercent of 0 drops the threshold to show the long tail.
+type HotSelection struct {
+	By         HotRanking
+	Limit      int
+	MinPercent *float64
+}
+
+// defaultHotMinPercent is the share a node needs to be hot when HotSelection.MinPercent is unset.
+const defaultHotMinPercent = 0.10
+
+// WithDefaults returns s with its unset fields set to the defaults.
+func (s HotSelection) WithDefaults() HotSelection {
+	if s.By == "" {
+		s.By = HotBySelf
+	}
+	if s.Limit <= 0 {
+		s.Limit = 5
+	}
+	if s.MinPercent == nil {
+		minPercent := defaultHotMinPercent
+		s.MinPercent = &minPercent
+	}
+	return s
+}
+
+// Threshold returns the share a node needs to be hot, the default when MinPercent is unset.
+func (s HotSelection) Threshold() float64 {
+	if s.MinPercent == nil {
+		return defaultHotMinPercent
+	}
+	return *s.MinPercent
+}
+
+// Share returns the fraction of the measure s ranks by that the node accounts for.
+func (s HotSelection) Share(n *NodeStats) float64 {
+	switch s.By {
+	case HotByInclusive:
+		return n.PercentInclusive
+	case HotByBuffers:
+		return n.PercentBuffers
+	case HotByIO:
+		return n.PercentIO
+	default:
+		return n.PercentExclusive
+	}
+}
+
+func selectHotNodes(nodes []*NodeStats, sel HotSelection) []*NodeStats {
+	if len(nodes) == 0 {
+		return nil
+	}
+
+	candidates := make([]*NodeStats, 0, len(nodes))
+	for _, n := range nodes {
+		if sel.Share(n) > 0 {
+			candidates = append(candidates, n)
+		}
+	}
+
+	sort.Slice(candidates, func(i, j int) bool {
+		return sel.Share(candidates[i]) > sel.Share(candidates[j])
+	})
+
+	limit := min(sel.Limit, len(candidates))
+	var out []*NodeStats
+	for _, candidate := range candidates[:limit] {
+		if sel.Share(candidate) < sel.Threshold() {
+			break
+		}
+		out = append(out, candidate)
+	}
+
+	if len(out) == 0 && len(candidates) > 0 {
+		out = candidates[:limit]
+	}
+
+	return out
+}
@@ -0,0 +1,68 @@
+package analyzer
+
+// LimitAnalysis describes how the topmost LIMIT interacted with the work beneath it.
+type LimitAnalysis struct {
+	Node          *NodeStats
+	RowsReturned  float64
+	CostPerRowMs  float64
+	RowsProcessed float64
+	// WastedNode is the node beneath the LIMIT that produced the most rows.
+	WastedNode *NodeStats
+	// Sort is set when an explicit Sort feeds the LIMIT, forcing its input to be consumed in full.
+	Sort *NodeStats
+	// RowsSkipped counts rows the LIMIT's input produced but the LIMIT discarded, i.e. OFFSET.
+	RowsSkipped float64
+}
+
+// WasteRatio reports how many rows were processed per row the LIMIT returned.
+func (l *LimitAnalysis) WasteRatio() float64 {
+	if l == nil || l.RowsProcessed <= 0 {
+		return 0
+	}
+	returned := l.RowsReturned
+	if returned < 1 {
+		returned = 1
+	}
+	return l.RowsProcessed / returned
+}
+
+func analyzeLimit(root *NodeStats) *LimitAnalysis {
+	var limit *NodeStats
+	for _, n := range flatten(root) {
+		if n.Node.NodeType == "Limit" {
+			limit = n
+			break
+		}
+	}
+	if limit == nil {
+		return nil
+	}
+
+	returned := limit.ActualTotalRows
+	perRow := limit.InclusiveTimeMs
+	if returned >= 1 {
+		perRow = limit.InclusiveTimeMs / returned
+	}
+	out := &LimitAnalysis{
+		Node:         limit,
+		RowsReturned: returned,
+		CostPerRowMs: perRow,
+	}
+
+	if len(limit.Children) > 0 {
+		if skipped := limit.Children[0].ActualTotalRows - returned; skipped > 0 {
+			out.RowsSkipped = skipped
+		}
+	}
+
+	for _, n := range flatten(limit)[1:] {
+		if out.Sort == nil && (n.Node.NodeType == "Sort" || n.Node.NodeType == "Incremental Sort") {
+			out.Sort = n
+		}
+		if n.ActualTotalRows > out.RowsProcessed {
+			out.RowsProcessed = n.ActualTotalRows
+			out.WastedNode = n
+		}
+	}
+	return out
+}
@@ -0,0 +1,70 @@
+package analyzer
+
+import "fmt"
+
+const (
+	// memoizeMinLookups is how many lookups a Memoize needs before its hit ratio says anything.
+	memoizeMinLookups = 100
+	// memoizeUselessHitRatio is the hit ratio below which a cache saves next to no inner executions.
+	memoizeUselessHitRatio = 0.05
+)
+
+// CacheLookups counts the lookups a Memoize node served, hits and misses together.
+func (n *NodeStats) CacheLookups() float64 {
+	return n.Node.CacheHits + n.Node.CacheMisses
+}
+
+// CacheHitRatio returns the share of a Memoize node's lookups answered from its cache, and false when it
+// reported no lookups.
+func (n *NodeStats) CacheHitRatio() (float64, bool) {
+	lookups := n.CacheLookups()
+	if lookups <= 0 {
+		return 0, false
+	}
+	return n.Node.CacheHits / lookups, true
+}
+
+// WastedInnerExecutions estimates how often a Memoize reran its inner side for a key it had already seen:
+// every evicted entry and every result too large to keep can cost a later miss, so their sum, capped at
+// the misses, bounds the executions a large enough cache would have saved.
+func (n *NodeStats) WastedInnerExecutions() float64 {
+	return min(n.Node.CacheEvictions+n.Node.CacheOverflows, n.Node.CacheMisses)
+}
+
+// memoizeWarning grades a Memoize cache with enough lookups to judge: thrashing when evictions and
+// overflows make up half its misses or more, since then it keeps throwing away entries it needs again, or
+// useless when it hardly ever hits because the keys do not repeat.
+func memoizeWarning(stats *NodeStats) (Warning, bool) {
+	node := stats.Node
+	ratio, ok := stats.CacheHitRatio()
+	if !ok || stats.CacheLookups() < memoizeMinLookups {
+		return Warning{}, false
+	}
+	metrics := map[string]float64{
+		"hit_ratio": ratio, "hits": node.CacheHits, "misses": node.CacheMisses,
+		"evictions": node.CacheEvictions, "overflows": node.CacheOverflows,
+	}
+	if wasted := stats.WastedInnerExecutions(); node.CacheMisses > 0 && wasted >= node.CacheMisses/2 {
+		metrics["wasted_executions"] = wasted
+		dropped := fmt.Sprintf("%.0f evictions", node.CacheEvictions)
+		if node.CacheOverflows > 0 {
+			dropped += fmt.Sprintf(" and %.0f overflows", node.CacheOverflows)
+		}
+		return Warning{
+			Code:     WarnMemoizeThrashing,
+			Severity: SeverityWarning,
+			Metrics:  metrics,
+			Text: fmt.Sprintf("memoize thrashing: hit ratio %.1f%%, %s for %.0f misses",
+				ratio*100, dropped, node.CacheMisses),
+		}, true
+	}
+	if ratio < memoizeUselessHitRatio {
+		return Warning{
+			Code:     WarnMemoizeUseless,
+			Severity: SeverityWarning,
+			Metrics:  metrics,
+			Text:     fmt.Sprintf("memoize hit ratio %.1f%% over %.0f lookups", ratio*100, stats.CacheLookups()),
+		}, true
+	}
+	return Warning{}, false
+}
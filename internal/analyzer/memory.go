@@ -0,0 +1,171 @@
+package analyzer
+
+import (
+	"sort"
+	"strings"
+)
+
+// nodeMemoryKB estimates the memory a node held at its peak. In-memory sorts report their space
+// directly; Hash, Memoize, hashed Aggregate and Incremental Sort report a peak. Nodes running
+// below a Gather are counted once per launched worker plus the leader.
+func nodeMemoryKB(n *NodeStats) float64 {
+	node := n.Node
+	var kb float64
+	switch {
+	case node.SortSpaceType == "Memory":
+		kb = node.SortSpaceUsedKB
+	case node.PeakMemoryKB > 0:
+		kb = node.PeakMemoryKB
+	default:
+		return 0
+	}
+	return kb * parallelCopies(n)
+}
+
+// SortOnDisk reports whether a sort ran out of work_mem, going by the sort details EXPLAIN ANALYZE prints:
+// the node or one of its workers used disk space or an external sort method. known is false when EXPLAIN
+// gave no sort details, e.g. for nodes that are not sorts or plans without ANALYZE.
+func (n *NodeStats) SortOnDisk() (onDisk, known bool) {
+	node := n.Node
+	if node.SortMethod != "" || node.SortSpaceType != "" {
+		known = true
+		onDisk = sortOnDisk(node.SortMethod, node.SortSpaceType)
+	}
+	for _, w := range node.Workers {
+		if w.SortMethod != "" || w.SortSpaceType != "" {
+			known = true
+			onDisk = onDisk || sortOnDisk(w.SortMethod, w.SortSpaceType)
+		}
+	}
+	return onDisk, known
+}
+
+// SortClass is how a sort ran, judged from its method and space rather than from the temp blocks in its
+// buffers, which include whatever spilled below it.
+type SortClass string
+
+const (
+	// SortUnknown is a node EXPLAIN gave no sort details for.
+	SortUnknown SortClass = ""
+	// SortInMemory is a quicksort that fit in work_mem.
+	SortInMemory SortClass = "memory"
+	// SortTopN is a top-N heapsort, which only keeps the rows a LIMIT asks for and so never spills.
+	SortTopN SortClass = "top-n"
+	// SortDisk is a sort that ran out of work_mem, an external merge or sort, in the node or a worker.
+	SortDisk SortClass = "disk"
+)
+
+// SortClass classifies the node's sort; see SortOnDisk.
+func (n *NodeStats) SortClass() SortClass {
+	onDisk, known := n.SortOnDisk()
+	switch {
+	case !known:
+		return SortUnknown
+	case onDisk:
+		return SortDisk
+	case strings.HasPrefix(n.Node.SortMethod, "top-N"):
+		return SortTopN
+	default:
+		return SortInMemory
+	}
+}
+
+// SortDiskKB returns the disk space the sort used, the most any one process used when it ran in parallel
+// workers, or 0 when it sorted in memory.
+func (n *NodeStats) SortDiskKB() float64 {
+	var kb float64
+	if n.Node.SortSpaceType == "Disk" {
+		kb = n.Node.SortSpaceUsedKB
+	}
+	for _, w := range n.Node.Workers {
+		if w.SortSpaceType == "Disk" {
+			kb = max(kb, w.SortSpaceUsedKB)
+		}
+	}
+	return kb
+}
+
+// sortTupleBytes approximates what an in-memory sort spends per row beyond its width: the SortTuple slot
+// and the tuple's header and allocation overhead.
+const sortTupleBytes = 48
+
+// SortMemoryNeededKB estimates the work_mem a disk sort would have needed to run in memory, per process: the
+// larger of the space it used on disk and the rows each process sorted times their width. Tuples take more
+// room in memory than in the temp files, so it is a lower bound. It is 0 for sorts that did not spill.
+func (n *NodeStats) SortMemoryNeededKB() float64 {
+	if n.SortClass() != SortDisk {
+		return 0
+	}
+	rows := n.ActualTotalRows / max(n.Processes, 1)
+	return max(n.SortDiskKB(), rows*float64(n.Node.PlanWidth+sortTupleBytes)/1024)
+}
+
+// HashNode returns the Hash node that builds the hash table: the node itself, or the inner Hash child of a
+// Hash Join. It returns nil for other nodes.
+func (n *NodeStats) HashNode() *NodeStats {
+	switch n.Node.NodeType {
+	case "Hash":
+		return n
+	case "Hash Join":
+		for _, child := range n.Children {
+			if child.Node.NodeType == "Hash" {
+				return child
+			}
+		}
+	}
+	return nil
+}
+
+// HashBatched reports whether the hash table of a Hash node or Hash Join was split into several batches,
+// its overflow written to temp files, because it did not fit in work_mem. known is false when EXPLAIN did
+// not report batches, e.g. without ANALYZE.
+func (n *NodeStats) HashBatched() (batched, known bool) {
+	hash := n.HashNode()
+	if hash == nil || hash.Node.HashBatches <= 0 {
+		return false, false
+	}
+	return hash.Node.HashBatches > 1, true
+}
+
+// hashTupleBytes approximates what a hash table spends per row beyond its width: the tuple headers and
+// its share of the bucket array.
+const hashTupleBytes = 48
+
+// HashMemoryNeededKB estimates the memory the hash table of a batched Hash node or Hash Join would have
+// needed to fit in a single batch: the peak of one batch times the number of batches, or the rows times
+// their width when EXPLAIN reported no peak. It is 0 when the table was not split.
+func (n *NodeStats) HashMemoryNeededKB() float64 {
+	if batched, _ := n.HashBatched(); !batched {
+		return 0
+	}
+	hash := n.HashNode()
+	if hash.Node.PeakMemoryKB > 0 {
+		return hash.Node.PeakMemoryKB * hash.Node.HashBatches
+	}
+	return hash.ActualTotalRows * float64(hash.Node.PlanWidth+hashTupleBytes) / 1024
+}
+
+func sortOnDisk(method, spaceType string) bool {
+	return spaceType == "Disk" || strings.HasPrefix(method, "external")
+}
+
+func parallelCopies(n *NodeStats) float64 {
+	for p := n.Parent; p != nil; p = p.Parent {
+		if p.Node.NodeType == "Gather" || p.Node.NodeType == "Gather Merge" {
+			return p.Node.WorkersLaunched + 1
+		}
+	}
+	return 1
+}
+
+// MemoryConsumers returns the nodes with a memory estimate, largest first.
+func (a *PlanAnalysis) MemoryConsumers() []*NodeStats {
+	var out []*NodeStats
+	for _, n := range a.Nodes() {
+		if n.MemoryKB > 0 {
+			out = append(out, n)
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].MemoryKB > out[j].MemoryKB })
+	return out
+}
@@ -0,0 +1,43 @@
+package analyzer
+
+// Pruning counts the partitions an Append or Merge Append kept and pruned. Partitions the planner excluded
+// up front never show up in the plan; these are the ones pruned while executing.
+type Pruning struct {
+	// Planned is the number of children in the plan, pruned at run time or not.
+	Planned int
+	// Removed is the number of children pruned when the executor started ("Subplans Removed").
+	Removed int
+	// NeverExecuted is the number of planned children that never ran, pruned per execution by a
+	// parameter only known at run time such as the outer side of a nested loop.
+	NeverExecuted int
+}
+
+// Scanned is the number of partitions that were actually read.
+func (p Pruning) Scanned() int {
+	return p.Planned - p.NeverExecuted
+}
+
+// Pruned is the number of partitions runtime pruning skipped, at startup or during execution.
+func (p Pruning) Pruned() int {
+	return p.Removed + p.NeverExecuted
+}
+
+// Pruning reports the runtime partition pruning of an Append or Merge Append node. ok is false for other
+// nodes. Children only count as never executed when the plan was analyzed and the node itself ran.
+func (n *NodeStats) Pruning() (pruning Pruning, ok bool) {
+	if n.Node == nil || (n.Node.NodeType != "Append" && n.Node.NodeType != "Merge Append") {
+		return Pruning{}, false
+	}
+	pruning.Removed = int(n.Node.SubplansRemoved)
+	for _, child := range n.Children {
+		switch child.Node.ParentRelationship {
+		case "InitPlan", "SubPlan":
+			continue
+		}
+		pruning.Planned++
+		if n.Node.ActualLoops > 0 && child.Node.ActualLoops == 0 {
+			pruning.NeverExecuted++
+		}
+	}
+	return pruning, true
+}
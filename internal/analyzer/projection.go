@@ -0,0 +1,43 @@
+package analyzer
+
+import "strings"
+
+// referenceKeys lists the node properties whose expressions can consume a child's output columns.
+var referenceKeys = []string{"Index Cond", "Recheck Cond", "One-Time Filter", "Presorted Key", "Cache Key"}
+
+// unusedOutputs returns the output columns of n that its parent never references, e.g. columns a
+// Sort carries for a Hash Join that only reads two of them. It needs EXPLAIN VERBOSE output lists
+// on both nodes and returns nil otherwise.
+func unusedOutputs(n *NodeStats) []string {
+	if n.Parent == nil || len(n.Node.Output) == 0 || len(n.Parent.Node.Output) == 0 {
+		return nil
+	}
+	parent := n.Parent.Node
+
+	var refs []string
+	refs = append(refs, parent.Output...)
+	refs = append(refs, parent.Filter, parent.JoinFilter, parent.HashCond, parent.MergeCond)
+	refs = append(refs, parent.SortKey...)
+	refs = append(refs, parent.GroupKey...)
+	for _, key := range referenceKeys {
+		switch v := parent.Extra[key].(type) {
+		case string:
+			refs = append(refs, v)
+		case []any:
+			for _, item := range v {
+				if s, ok := item.(string); ok {
+					refs = append(refs, s)
+				}
+			}
+		}
+	}
+	used := strings.Join(refs, "\n")
+
+	var unused []string
+	for _, col := range n.Node.Output {
+		if !strings.Contains(used, col) {
+			unused = append(unused, col)
+		}
+	}
+	return unused
+}
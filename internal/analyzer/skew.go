@@ -0,0 +1,109 @@
+package analyzer
+
+import "github.com/mickamy/xplain/internal/model"
+
+// WorkerSkew measures how evenly the processes under a Gather or Gather Merge shared its work, from
+// the per-worker statistics EXPLAIN (ANALYZE, VERBOSE) prints.
+type WorkerSkew struct {
+	// Node is the topmost node below the Gather with per-worker statistics, the one the figures describe.
+	Node *NodeStats
+	// Processes counts the workers, plus the leader when it produced rows itself.
+	Processes int
+	// MaxTimeMs and MeanTimeMs compare the slowest worker with the workers' mean. EXPLAIN does not
+	// report the leader's own time, so it is left out of both.
+	MaxTimeMs  float64
+	MeanTimeMs float64
+	// MaxRows is the most rows one process produced and MeanRows the node's rows spread evenly.
+	MaxRows  float64
+	MeanRows float64
+	// Busiest is the worker that produced MaxRows; nil when it was the leader.
+	Busiest *model.Worker
+}
+
+// TimeRatio is the slowest worker's time over the mean; 1 when the workers took equally long.
+func (s *WorkerSkew) TimeRatio() float64 {
+	if s.MeanTimeMs <= 0 {
+		return 1
+	}
+	return s.MaxTimeMs / s.MeanTimeMs
+}
+
+// RowRatio is the busiest process's rows over the mean; it reaches Processes when one did all the work.
+func (s *WorkerSkew) RowRatio() float64 {
+	if s.MeanRows <= 0 {
+		return 1
+	}
+	return s.MaxRows / s.MeanRows
+}
+
+// RowShare is the busiest process's fraction of the rows.
+func (s *WorkerSkew) RowShare() float64 {
+	if s.MeanRows <= 0 || s.Processes == 0 {
+		return 0
+	}
+	return s.MaxRows / (s.MeanRows * float64(s.Processes))
+}
+
+// Ratio is the larger of TimeRatio and RowRatio, the skew the insight judges.
+func (s *WorkerSkew) Ratio() float64 {
+	return max(s.TimeRatio(), s.RowRatio())
+}
+
+// workerSkew computes the skew of a Gather or Gather Merge node. It returns nil for other nodes and
+// when fewer than two processes reported statistics.
+func workerSkew(gather *NodeStats) *WorkerSkew {
+	if t := gather.Node.NodeType; t != "Gather" && t != "Gather Merge" {
+		return nil
+	}
+	n := firstWithWorkers(gather.Children)
+	if n == nil {
+		return nil
+	}
+	skew := &WorkerSkew{Node: n, Processes: len(n.Workers)}
+	var workerRows, workerTime float64
+	for i := range n.Workers {
+		ws := &n.Workers[i]
+		workerRows += ws.Rows
+		workerTime += ws.TimeMs
+		skew.MaxTimeMs = max(skew.MaxTimeMs, ws.TimeMs)
+		if ws.Rows > skew.MaxRows || skew.Busiest == nil {
+			skew.MaxRows, skew.Busiest = ws.Rows, ws.Worker
+		}
+	}
+	skew.MeanTimeMs = workerTime / float64(len(n.Workers))
+	// The leader produced whatever the workers did not; it takes part unless parallel_leader_participation
+	// is off or the workers kept it busy gathering.
+	if leader := n.ActualTotalRows - workerRows; leader >= 1 {
+		skew.Processes++
+		if leader > skew.MaxRows {
+			skew.MaxRows, skew.Busiest = leader, nil
+		}
+	}
+	if skew.Processes < 2 {
+		return nil
+	}
+	skew.MeanRows = n.ActualTotalRows / float64(skew.Processes)
+	return skew
+}
+
+// firstWithWorkers finds the topmost node in a subtree with per-worker times and rows, stopping at
+// nested Gathers, whose workers are their own. Without VERBOSE, workers list only their sort or memory
+// details, which say nothing about how the work was split.
+func firstWithWorkers(nodes []*NodeStats) *NodeStats {
+	for _, n := range nodes {
+		for _, ws := range n.Workers {
+			if ws.TimeMs > 0 || ws.Rows > 0 {
+				return n
+			}
+		}
+	}
+	for _, n := range nodes {
+		if t := n.Node.NodeType; t == "Gather" || t == "Gather Merge" {
+			continue
+		}
+		if found := firstWithWorkers(n.Children); found != nil {
+			return found
+		}
+	}
+	return nil
+}
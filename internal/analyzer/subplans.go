@@ -0,0 +1,149 @@
+package analyzer
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/mickamy/xplain/internal/model"
+)
+
+var (
+	// initPlanParams finds the parameters an InitPlan sets in "InitPlan 1 (returns $0,$1)" (before PostgreSQL 16).
+	initPlanParams = regexp.MustCompile(`\$\d+`)
+	subplanNumber  = regexp.MustCompile(`^(InitPlan|SubPlan) (\d+)\b`)
+)
+
+// attributeSubplans fixes the self time of nodes around CTEs, InitPlans and SubPlans. EXPLAIN lists such a
+// subplan under the node it is attached to, but its time is spent inside whichever node pulls its rows or
+// evaluates its parameter: every CTE Scan reading a CTE, or the descendant whose condition uses an
+// InitPlan's $0. Subtracting the subplan from the node it hangs off as well as counting it in the
+// referencing nodes counts it twice, which shows up as a zero self time above and a hot CTE Scan below.
+//
+// When a subplan is referenced only below the node it is attached to, its time is instead taken out of
+// the referencing nodes once, largest first, since the first reader is the one that ran it: the scan that
+// materialized a CTE rather than the ones that reread the stored rows. References on the attaching node
+// itself, the common single-use case, keep the plain subtraction.
+func attributeSubplans(root *NodeStats) {
+	deferred := map[*NodeStats]bool{}
+	charged := map[*NodeStats]float64{}
+	for _, parent := range flatten(root) {
+		for _, sub := range parent.Children {
+			refs := subplanReferences(parent, sub)
+			if len(refs) == 0 {
+				continue
+			}
+			deferred[sub] = true
+			sort.SliceStable(refs, func(i, j int) bool {
+				return refs[i].InclusiveTimeMs > refs[j].InclusiveTimeMs
+			})
+			remaining := sub.InclusiveTimeMs
+			for _, ref := range refs {
+				ref.References = append(ref.References, sub)
+				share := math.Min(remaining, ref.InclusiveTimeMs-charged[ref])
+				if share <= 0 {
+					continue
+				}
+				charged[ref] += share
+				remaining -= share
+			}
+		}
+	}
+	if len(deferred) == 0 {
+		return
+	}
+	for _, n := range flatten(root) {
+		exclusive := n.InclusiveTimeMs - charged[n]
+		for _, child := range n.Children {
+			if !deferred[child] {
+				exclusive -= child.InclusiveTimeMs
+			}
+		}
+		n.ExclusiveTimeMs = math.Max(exclusive, 0)
+		n.MsPerRow = 0
+		if n.ActualTotalRows > 0 {
+			n.MsPerRow = n.ExclusiveTimeMs / n.ActualTotalRows
+		}
+	}
+}
+
+// subplanReferences returns the nodes below parent, outside sub, whose time includes running sub. It is
+// empty when sub is an ordinary child, when parent references sub itself, or when no reference is found.
+func subplanReferences(parent, sub *NodeStats) []*NodeStats {
+	if rel := sub.Node.ParentRelationship; rel != "InitPlan" && rel != "SubPlan" {
+		return nil
+	}
+	refers := subplanMatcher(sub.Node.SubplanName)
+	if refers == nil || refers(parent.Node) {
+		return nil
+	}
+	var refs []*NodeStats
+	var walk func(*NodeStats)
+	walk = func(n *NodeStats) {
+		for _, child := range n.Children {
+			if child == sub {
+				continue
+			}
+			if refers(child.Node) {
+				refs = append(refs, child)
+			}
+			walk(child)
+		}
+	}
+	walk(parent)
+	return refs
+}
+
+// subplanMatcher reports whether a node reads the subplan named name: a CTE Scan of "CTE recent_orders",
+// or an expression using "InitPlan 1 (returns $0)" as $0, "(InitPlan 1).col1" or "(SubPlan 2)".
+func subplanMatcher(name string) func(*model.PlanNode) bool {
+	if cte, ok := strings.CutPrefix(name, "CTE "); ok {
+		return func(n *model.PlanNode) bool {
+			return n.NodeType == "CTE Scan" && n.CTEName == cte
+		}
+	}
+	m := subplanNumber.FindStringSubmatch(name)
+	if m == nil {
+		return nil
+	}
+	patterns := []*regexp.Regexp{regexp.MustCompile(fmt.Sprintf(`\b%s %s\b`, m[1], m[2]))}
+	if _, returns, ok := strings.Cut(name, "returns "); ok {
+		for _, param := range initPlanParams.FindAllString(returns, -1) {
+			patterns = append(patterns, regexp.MustCompile(regexp.QuoteMeta(param)+`\b`))
+		}
+	}
+	return func(n *model.PlanNode) bool {
+		for _, expr := range expressions(n) {
+			for _, p := range patterns {
+				if p.MatchString(expr) {
+					return true
+				}
+			}
+		}
+		return false
+	}
+}
+
+// expressions lists the conditions and output expressions of a node, modelled or kept in Extra.
+func expressions(n *model.PlanNode) []string {
+	exprs := []string{n.Filter, n.JoinFilter, n.HashCond, n.MergeCond}
+	exprs = append(exprs, n.Output...)
+	for key, v := range n.Extra {
+		if key == "Subplan Name" {
+			continue
+		}
+		switch v := v.(type) {
+		case string:
+			exprs = append(exprs, v)
+		case []any:
+			for _, item := range v {
+				if s, ok := item.(string); ok {
+					exprs = append(exprs, s)
+				}
+			}
+		}
+	}
+	return exprs
+}
@@ -0,0 +1,55 @@
+package analyzer
+
+import (
+	"sort"
+
+	"github.com/mickamy/xplain/internal/model"
+)
+
+const blockSize = 8192
+
+// AttachTempFiles distributes temp files taken from the server log over the nodes that wrote
+// temp blocks. The log does not name the node, so each file, largest first, goes to the node with
+// the most written bytes still unaccounted for.
+func (a *PlanAnalysis) AttachTempFiles(files []model.TempFile) {
+	if a == nil || a.Root == nil || len(files) == 0 {
+		return
+	}
+	a.TempFiles = files
+
+	var spills []*NodeStats
+	remaining := map[*NodeStats]int64{}
+	for _, n := range a.Nodes() {
+		if written := selfTempWritten(n); written > 0 {
+			spills = append(spills, n)
+			remaining[n] = written * blockSize
+		}
+	}
+	if len(spills) == 0 {
+		return
+	}
+
+	sorted := append([]model.TempFile(nil), files...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].SizeBytes > sorted[j].SizeBytes })
+	for _, file := range sorted {
+		target := spills[0]
+		for _, n := range spills[1:] {
+			if remaining[n] > remaining[target] {
+				target = n
+			}
+		}
+		target.TempFiles++
+		target.TempFileBytes += file.SizeBytes
+		remaining[target] -= file.SizeBytes
+	}
+}
+
+// selfTempWritten returns the temp blocks written by the node itself; EXPLAIN reports buffers
+// including those of the children.
+func selfTempWritten(n *NodeStats) int64 {
+	written := n.Buffers.TempWritten
+	for _, child := range n.Children {
+		written -= child.Buffers.TempWritten
+	}
+	return max(written, 0)
+}
@@ -0,0 +1,71 @@
+package analyzer
+
+// Severity grades a node Warning, using the values of insight severities.
+type Severity string
+
+const (
+	SeverityInfo     Severity = "info"
+	SeverityWarning  Severity = "warning"
+	SeverityCritical Severity = "critical"
+)
+
+// Warning codes name the kind of a node Warning. They are stable, so assertions, ignore files and other
+// tools can match on them rather than on the wording.
+const (
+	WarnSelfTime         = "self-time"
+	WarnEstimateHigh     = "estimate-high"
+	WarnEstimateLow      = "estimate-low"
+	WarnBufferHeavy      = "buffer-heavy"
+	WarnSortSpill        = "sort-spill"
+	WarnHashBatches      = "hash-batches"
+	WarnFilterWaste      = "filter-waste"
+	WarnJoinFilterWaste  = "join-filter-waste"
+	WarnLossyBitmap      = "lossy-bitmap"
+	WarnHeapFetches      = "heap-fetches"
+	WarnMemoizeThrashing = "memoize-thrashing"
+	WarnMemoizeUseless   = "memoize-useless"
+	WarnCostSlower       = "cost-slower"
+	WarnCostFaster       = "cost-faster"
+)
+
+// WarningCodes lists every warning code, in the order they are declared.
+func WarningCodes() []string {
+	return []string{
+		WarnSelfTime, WarnEstimateHigh, WarnEstimateLow, WarnBufferHeavy, WarnSortSpill, WarnHashBatches,
+		WarnFilterWaste, WarnJoinFilterWaste, WarnLossyBitmap, WarnHeapFetches, WarnMemoizeThrashing,
+		WarnMemoizeUseless, WarnCostSlower, WarnCostFaster,
+	}
+}
+
+// Warning is a finding about one node, such as a sort that spilled or a filter that discarded most rows.
+type Warning struct {
+	Code     string   `json:"code"`
+	Severity Severity `json:"severity"`
+	// Metrics are the measurements behind the finding, e.g. "removed" and "share" for a wasteful filter.
+	Metrics map[string]float64 `json:"metrics,omitempty"`
+	// Text describes the finding for people, e.g. "filter removed 92.4% of 66000 rows".
+	Text string `json:"text"`
+}
+
+func (w Warning) String() string {
+	return w.Text
+}
+
+// HasWarning reports whether the node carries a warning with the given code.
+func (n *NodeStats) HasWarning(code string) bool {
+	for _, w := range n.Warnings {
+		if w.Code == code {
+			return true
+		}
+	}
+	return false
+}
+
+// WarningTexts returns the text of each of the node's warnings, in order.
+func (n *NodeStats) WarningTexts() []string {
+	texts := make([]string, 0, len(n.Warnings))
+	for _, w := range n.Warnings {
+		texts = append(texts, w.Text)
+	}
+	return texts
+}
@@ -0,0 +1,283 @@
+package assert
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mickamy/xplain/internal/analyzer"
+)
+
+// Assertion is a performance expectation checked against an analyzed plan.
+type Assertion interface {
+	// String returns the assertion as written by the user.
+	String() string
+	// Check reports whether the analysis satisfies the assertion, with a short explanation.
+	Check(analysis *analyzer.PlanAnalysis) (bool, string)
+}
+
+// Result is the outcome of one assertion.
+type Result struct {
+	Assertion string `json:"assertion"`
+	Passed    bool   `json:"passed"`
+	Detail    string `json:"detail"`
+}
+
+// List collects assertions from repeated --assert flags; it implements flag.Value.
+type List []Assertion
+
+// String renders the assertions as given.
+func (l *List) String() string {
+	specs := make([]string, 0, len(*l))
+	for _, a := range *l {
+		specs = append(specs, a.String())
+	}
+	return strings.Join(specs, "; ")
+}
+
+// Set parses and appends one assertion; "@file.json" loads a file of assertions (see LoadFile).
+func (l *List) Set(spec string) error {
+	if path, ok := strings.CutPrefix(strings.TrimSpace(spec), "@"); ok {
+		loaded, err := LoadFile(path)
+		if err != nil {
+			return err
+		}
+		*l = append(*l, loaded...)
+		return nil
+	}
+	a, err := Parse(spec)
+	if err != nil {
+		return err
+	}
+	*l = append(*l, a)
+	return nil
+}
+
+var comparison = regexp.MustCompile(`^([a-z_]+)\s*(<=|>=|==|!=|<|>)\s*(-?[0-9.]+)$`)
+
+// Parse builds an assertion from its textual form:
+//
+//	execution_time_ms < 100          compare a plan metric (see metrics) with a number
+//	no-seq-scan / no-seq-scan:orders no sequential scan at all, or none on the named table
+//	no-warning:sort-spill            no node carries a warning with the code (see analyzer.Warning)
+//	expect-index orders_status_idx   the plan must use the named index
+//	max-time 200ms                   execution time must not exceed the duration
+//
+// Plan shape assertions such as "Index Scan using idx must appear" are described on parseShape.
+func Parse(spec string) (Assertion, error) {
+	spec = strings.TrimSpace(spec)
+	if a, ok, err := parseShape(spec); ok {
+		return a, err
+	}
+	if m := comparison.FindStringSubmatch(spec); m != nil {
+		metric, ok := metrics[m[1]]
+		if !ok {
+			return nil, fmt.Errorf("assert: unknown metric %q (known: %s)", m[1], strings.Join(metricNames(), ", "))
+		}
+		value, err := strconv.ParseFloat(m[3], 64)
+		if err != nil {
+			return nil, fmt.Errorf("assert: %q: %w", spec, err)
+		}
+		return compare{spec: spec, name: m[1], metric: metric, op: m[2], value: value}, nil
+	}
+	if rest, ok := strings.CutPrefix(spec, "no-seq-scan"); ok && (rest == "" || rest[0] == ':') {
+		return noSeqScan{spec: spec, table: strings.TrimSpace(strings.TrimPrefix(rest, ":"))}, nil
+	}
+	if code, ok := strings.CutPrefix(spec, "no-warning:"); ok {
+		if code = strings.TrimSpace(code); code == "" {
+			return nil, fmt.Errorf("assert: no-warning needs a warning code, e.g. no-warning:sort-spill")
+		}
+		if codes := analyzer.WarningCodes(); !slices.Contains(codes, code) {
+			return nil, fmt.Errorf("assert: unknown warning code %q (known: %s)", code, strings.Join(codes, ", "))
+		}
+		return noWarning{spec: spec, code: code}, nil
+	}
+
+	name, arg, _ := strings.Cut(spec, " ")
+	if n, a, ok := strings.Cut(spec, "="); ok && !strings.ContainsAny(n, " ") {
+		name, arg = n, a
+	}
+	arg = strings.TrimSpace(arg)
+
+	switch name {
+	case "expect-index":
+		if arg == "" {
+			return nil, fmt.Errorf("assert: expect-index needs an index name")
+		}
+		return expectIndex{spec: spec, index: arg}, nil
+	case "max-time":
+		limit, err := time.ParseDuration(arg)
+		if err != nil {
+			return nil, fmt.Errorf("assert: max-time %q: %w", arg, err)
+		}
+		return maxTime{spec: spec, limit: limit}, nil
+	default:
+		return nil, fmt.Errorf("assert: unknown assertion %q", spec)
+	}
+}
+
+// Evaluate checks every assertion against the analysis.
+func Evaluate(analysis *analyzer.PlanAnalysis, assertions []Assertion) []Result {
+	results := make([]Result, 0, len(assertions))
+	for _, a := range assertions {
+		passed, detail := a.Check(analysis)
+		results = append(results, Result{Assertion: a.String(), Passed: passed, Detail: detail})
+	}
+	return results
+}
+
+// Failed counts the results that did not pass.
+func Failed(results []Result) int {
+	n := 0
+	for _, r := range results {
+		if !r.Passed {
+			n++
+		}
+	}
+	return n
+}
+
+type expectIndex struct {
+	spec  string
+	index string
+}
+
+func (a expectIndex) String() string { return a.spec }
+
+func (a expectIndex) Check(analysis *analyzer.PlanAnalysis) (bool, string) {
+	for _, n := range analysis.Nodes() {
+		if strings.EqualFold(n.Node.IndexName, a.index) {
+			return true, fmt.Sprintf("%s uses %s", n.Node.NodeType, a.index)
+		}
+	}
+	var used []string
+	for _, n := range analysis.Nodes() {
+		if n.Node.IndexName != "" {
+			used = append(used, n.Node.IndexName)
+		}
+	}
+	if len(used) == 0 {
+		return false, fmt.Sprintf("%s is not used; the plan uses no index", a.index)
+	}
+	return false, fmt.Sprintf("%s is not used; the plan uses %s", a.index, strings.Join(used, ", "))
+}
+
+type maxTime struct {
+	spec  string
+	limit time.Duration
+}
+
+func (a maxTime) String() string { return a.spec }
+
+func (a maxTime) Check(analysis *analyzer.PlanAnalysis) (bool, string) {
+	limitMs := float64(a.limit) / float64(time.Millisecond)
+	detail := fmt.Sprintf("execution took %.2f ms (limit %.2f ms)", analysis.TotalTimeMs, limitMs)
+	return analysis.TotalTimeMs <= limitMs, detail
+}
+
+// metrics are the plan-wide values available to comparison assertions.
+var metrics = map[string]func(*analyzer.PlanAnalysis) float64{
+	"execution_time_ms": func(a *analyzer.PlanAnalysis) float64 { return a.TotalTimeMs },
+	"planning_time_ms":  func(a *analyzer.PlanAnalysis) float64 { return a.PlanningTimeMs },
+	"total_buffers":     func(a *analyzer.PlanAnalysis) float64 { return float64(a.TotalBuffers) },
+	"node_count":        func(a *analyzer.PlanAnalysis) float64 { return float64(a.NodeCount) },
+	"rows":              func(a *analyzer.PlanAnalysis) float64 { return a.Root.ActualTotalRows },
+	"temp_blocks": func(a *analyzer.PlanAnalysis) float64 {
+		var total float64
+		for _, n := range a.Nodes() {
+			total += float64(n.Node.Buffers.TempRead + n.Node.Buffers.TempWritten)
+		}
+		return total
+	},
+}
+
+func metricNames() []string {
+	names := make([]string, 0, len(metrics))
+	for name := range metrics {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+type compare struct {
+	spec   string
+	name   string
+	metric func(*analyzer.PlanAnalysis) float64
+	op     string
+	value  float64
+}
+
+func (a compare) String() string { return a.spec }
+
+func (a compare) Check(analysis *analyzer.PlanAnalysis) (bool, string) {
+	got := a.metric(analysis)
+	var passed bool
+	switch a.op {
+	case "<":
+		passed = got < a.value
+	case "<=":
+		passed = got <= a.value
+	case ">":
+		passed = got > a.value
+	case ">=":
+		passed = got >= a.value
+	case "==":
+		passed = got == a.value
+	case "!=":
+		passed = got != a.value
+	}
+	return passed, fmt.Sprintf("%s is %s", a.name, strconv.FormatFloat(got, 'f', -1, 64))
+}
+
+type noWarning struct {
+	spec string
+	code string
+}
+
+func (a noWarning) String() string { return a.spec }
+
+func (a noWarning) Check(analysis *analyzer.PlanAnalysis) (bool, string) {
+	var hits []string
+	for _, n := range analysis.Nodes() {
+		for _, w := range n.Warnings {
+			if w.Code == a.code {
+				hits = append(hits, fmt.Sprintf("%s: %s", n.Node.NodeType, w.Text))
+			}
+		}
+	}
+	if len(hits) > 0 {
+		return false, strings.Join(hits, ", ")
+	}
+	return true, "no " + a.code + " warnings"
+}
+
+type noSeqScan struct {
+	spec  string
+	table string
+}
+
+func (a noSeqScan) String() string { return a.spec }
+
+func (a noSeqScan) Check(analysis *analyzer.PlanAnalysis) (bool, string) {
+	var hits []string
+	for _, n := range analysis.Nodes() {
+		if !strings.HasSuffix(n.Node.NodeType, "Seq Scan") {
+			continue
+		}
+		if a.table != "" && !strings.EqualFold(n.Node.RelationName, a.table) {
+			continue
+		}
+		hits = append(hits, fmt.Sprintf("%s on %s (%.0f rows)", n.Node.NodeType, n.Node.RelationName, n.ActualTotalRows))
+	}
+	if len(hits) > 0 {
+		return false, strings.Join(hits, ", ")
+	}
+	if a.table != "" {
+		return true, "no sequential scan on " + a.table
+	}
+	return true, "no sequential scans"
+}
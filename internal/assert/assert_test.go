@@ -0,0 +1,130 @@
+package assert_test
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mickamy/xplain/internal/assert"
+	"github.com/mickamy/xplain/test"
+)
+
+func TestDirectiveAssertions(t *testing.T) {
+	analysis := test.LoadSampleAnalysis(t, "pgbench_branches.json")
+
+	specs := []struct {
+		spec string
+		pass bool
+	}{
+		{"expect-index pgbench_branches_pkey", true},
+		{"expect-index missing_idx", false},
+		{"max-time 1h", true},
+		{"max-time=1us", false},
+	}
+	for _, s := range specs {
+		a, err := assert.Parse(s.spec)
+		if err != nil {
+			t.Fatalf("parse %q: %v", s.spec, err)
+		}
+		results := assert.Evaluate(analysis, []assert.Assertion{a})
+		if results[0].Passed != s.pass {
+			t.Fatalf("%q: expected passed=%v, got %+v", s.spec, s.pass, results[0])
+		}
+	}
+
+	if _, err := assert.Parse("expect-magic"); err == nil {
+		t.Fatalf("expected unknown assertion to fail")
+	}
+}
+
+func TestComparisonAndSeqScanAssertions(t *testing.T) {
+	analysis := test.LoadSampleAnalysis(t, "pgbench_hot.json")
+
+	var list assert.List
+	for _, spec := range []string{"execution_time_ms < 10000", "node_count == 4", "total_buffers > 0", "no-seq-scan:pgbench_branches", "no-seq-scan"} {
+		if err := list.Set(spec); err != nil {
+			t.Fatalf("set %q: %v", spec, err)
+		}
+	}
+
+	results := assert.Evaluate(analysis, list)
+	if failed := assert.Failed(results); failed != 1 {
+		t.Fatalf("expected only the global no-seq-scan to fail, got %+v", results)
+	}
+	if last := results[len(results)-1]; last.Passed || last.Detail != "Seq Scan on pgbench_accounts (99999 rows)" {
+		t.Fatalf("unexpected seq scan result %+v", last)
+	}
+
+	if err := list.Set("latency_ms < 5"); err == nil {
+		t.Fatalf("expected unknown metric to fail")
+	}
+}
+
+func TestNoWarningAssertion(t *testing.T) {
+	analysis := test.LoadSampleAnalysis(t, "envelope_orders.json")
+
+	var list assert.List
+	for _, spec := range []string{"no-warning:sort-spill", "no-warning:hash-batches"} {
+		if err := list.Set(spec); err != nil {
+			t.Fatalf("set %q: %v", spec, err)
+		}
+	}
+	results := assert.Evaluate(analysis, list)
+	if results[0].Passed || results[0].Detail != "Sort: sort spilled to disk (external merge, 35184 kB)" {
+		t.Fatalf("expected the spilling sort to fail the assertion, got %+v", results[0])
+	}
+	if !results[1].Passed {
+		t.Fatalf("expected no hash batch warnings, got %+v", results[1])
+	}
+	if err := list.Set("no-warning:"); err == nil {
+		t.Fatalf("expected a missing code to fail")
+	}
+	// A typo would otherwise always pass and silently disable the gate.
+	if err := list.Set("no-warning:sort-spil"); err == nil || !strings.Contains(err.Error(), "sort-spill") {
+		t.Fatalf("expected an unknown code to fail listing the known ones, got %v", err)
+	}
+}
+
+func TestShapeAssertions(t *testing.T) {
+	analysis := test.LoadSampleAnalysis(t, "pgbench_hot.json")
+
+	specs := []struct {
+		spec string
+		pass bool
+	}{
+		{"Seq Scan on pgbench_accounts must appear", true},
+		{"Seq Scan on pgbench_accounts must not appear", false},
+		{"Index Scan using pgbench_accounts_pkey must appear", false},
+		{"no Seq Scan above 1000 rows", false},
+		{"no Seq Scan above 1000000 rows", true},
+		{"no any node above 1000 loops", true},
+	}
+	for _, s := range specs {
+		a, err := assert.Parse(s.spec)
+		if err != nil {
+			t.Fatalf("parse %q: %v", s.spec, err)
+		}
+		if passed, detail := a.Check(analysis); passed != s.pass {
+			t.Fatalf("%q: expected passed=%v, got %v (%s)", s.spec, s.pass, passed, detail)
+		}
+	}
+}
+
+func TestLoadFile(t *testing.T) {
+	root := test.RootPath(t)
+	var list assert.List
+	if err := list.Set("@" + filepath.Join(root, "samples", "assertions.example.json")); err != nil {
+		t.Fatalf("load assertions: %v", err)
+	}
+	if len(list) != 4 {
+		t.Fatalf("expected 4 assertions, got %d", len(list))
+	}
+	if got := list[3].String(); got != "no Seq Scan on pgbench_accounts above 100000 rows" {
+		t.Fatalf("unexpected description for JSON shape %q", got)
+	}
+
+	results := assert.Evaluate(test.LoadSampleAnalysis(t, "pgbench_branches.json"), list)
+	if failed := assert.Failed(results); failed != 0 {
+		t.Fatalf("expected sample assertions to pass, got %+v", results)
+	}
+}
@@ -0,0 +1,221 @@
+package assert
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/mickamy/xplain/internal/analyzer"
+)
+
+// Pattern selects plan nodes by type, relation and index; empty fields match anything.
+type Pattern struct {
+	Node     string `json:"node"`
+	Relation string `json:"relation"`
+	Index    string `json:"index"`
+}
+
+// Shape asserts the presence or absence of matching nodes, optionally only those above a limit.
+type Shape struct {
+	Pattern
+	// Expect is "present" (the default) or "absent".
+	Expect   string  `json:"expect"`
+	MaxLoops float64 `json:"max_loops"`
+	MaxRows  float64 `json:"max_rows"`
+	MaxMs    float64 `json:"max_ms"`
+
+	spec string
+}
+
+var (
+	shapeAppear = regexp.MustCompile(`(?i)^(.+?)\s+must\s+(not\s+)?appear$`)
+	shapeNo     = regexp.MustCompile(`(?i)^no\s+(.+?)(?:\s+above\s+([0-9.]+)\s*(loops|rows|ms))?$`)
+	patternPart = regexp.MustCompile(`(?i)^(.*?)(?:\s+on\s+(\S+))?(?:\s+using\s+(\S+))?$`)
+)
+
+// parseShape understands the plan shape DSL:
+//
+//	Index Scan using idx_orders_customer must appear
+//	Seq Scan on orders must not appear
+//	no Nested Loop above 1000 loops
+//	no Sort above 50 ms
+func parseShape(spec string) (Assertion, bool, error) {
+	var s Shape
+	switch {
+	case shapeAppear.MatchString(spec):
+		m := shapeAppear.FindStringSubmatch(spec)
+		s.Pattern = ParsePattern(m[1])
+		if m[2] != "" {
+			s.Expect = "absent"
+		}
+	case shapeNo.MatchString(spec):
+		m := shapeNo.FindStringSubmatch(spec)
+		s.Pattern = ParsePattern(m[1])
+		s.Expect = "absent"
+		if m[2] != "" {
+			limit, err := strconv.ParseFloat(m[2], 64)
+			if err != nil {
+				return nil, true, fmt.Errorf("assert: %q: %w", spec, err)
+			}
+			switch strings.ToLower(m[3]) {
+			case "loops":
+				s.MaxLoops = limit
+			case "rows":
+				s.MaxRows = limit
+			case "ms":
+				s.MaxMs = limit
+			}
+		}
+	default:
+		return nil, false, nil
+	}
+	s.spec = spec
+	return s, true, nil
+}
+
+// ParsePattern reads a node pattern such as "Seq Scan on orders" or "Index Scan using idx";
+// "*", "any" and "any node" match every node type.
+func ParsePattern(text string) Pattern {
+	m := patternPart.FindStringSubmatch(strings.TrimSpace(text))
+	node := strings.TrimSpace(m[1])
+	if node == "*" || strings.EqualFold(node, "any") || strings.EqualFold(node, "any node") {
+		node = ""
+	}
+	return Pattern{Node: node, Relation: m[2], Index: m[3]}
+}
+
+// LoadFile reads assertions from a JSON file holding an array of DSL strings and/or Shape objects.
+func LoadFile(path string) ([]Assertion, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("assert: read %s: %w", path, err)
+	}
+	var raw []json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("assert: parse %s: %w", path, err)
+	}
+	out := make([]Assertion, 0, len(raw))
+	for i, item := range raw {
+		var spec string
+		if err := json.Unmarshal(item, &spec); err == nil {
+			a, err := Parse(spec)
+			if err != nil {
+				return nil, fmt.Errorf("%s[%d]: %w", path, i, err)
+			}
+			out = append(out, a)
+			continue
+		}
+		var s Shape
+		if err := json.Unmarshal(item, &s); err != nil {
+			return nil, fmt.Errorf("assert: %s[%d]: %w", path, i, err)
+		}
+		if s.Expect != "" && s.Expect != "present" && s.Expect != "absent" {
+			return nil, fmt.Errorf("assert: %s[%d]: expect must be present or absent, got %q", path, i, s.Expect)
+		}
+		out = append(out, s)
+	}
+	return out, nil
+}
+
+func (s Shape) String() string {
+	if s.spec != "" {
+		return s.spec
+	}
+	text := s.describe()
+	if s.Expect == "absent" {
+		text = "no " + text
+		for _, limit := range []struct {
+			value float64
+			unit  string
+		}{{s.MaxLoops, "loops"}, {s.MaxRows, "rows"}, {s.MaxMs, "ms"}} {
+			if limit.value > 0 {
+				text += fmt.Sprintf(" above %s %s", strconv.FormatFloat(limit.value, 'f', -1, 64), limit.unit)
+			}
+		}
+		return text
+	}
+	return text + " must appear"
+}
+
+func (s Shape) Check(analysis *analyzer.PlanAnalysis) (bool, string) {
+	var matches []*analyzer.NodeStats
+	for _, n := range analysis.Nodes() {
+		if s.matches(n) {
+			matches = append(matches, n)
+		}
+	}
+	if s.Expect != "absent" {
+		if len(matches) == 0 {
+			return false, "no node matches " + s.describe()
+		}
+		return true, fmt.Sprintf("%d matching node(s), e.g. %s", len(matches), nodeText(matches[0]))
+	}
+	if len(matches) == 0 {
+		return true, "no matching node"
+	}
+	parts := make([]string, 0, len(matches))
+	for _, n := range matches {
+		parts = append(parts, nodeText(n))
+	}
+	return false, strings.Join(parts, ", ")
+}
+
+func (s Shape) describe() string {
+	text := s.Node
+	if text == "" {
+		text = "any node"
+	}
+	if s.Relation != "" {
+		text += " on " + s.Relation
+	}
+	if s.Index != "" {
+		text += " using " + s.Index
+	}
+	return text
+}
+
+// Matches reports whether the node has the pattern's type, relation (or alias) and index.
+func (p Pattern) Matches(n *analyzer.NodeStats) bool {
+	node := n.Node
+	if p.Node != "" && !strings.EqualFold(node.NodeType, p.Node) {
+		return false
+	}
+	if p.Relation != "" && !strings.EqualFold(node.RelationName, p.Relation) && !strings.EqualFold(node.Alias, p.Relation) {
+		return false
+	}
+	if p.Index != "" && !strings.EqualFold(node.IndexName, p.Index) {
+		return false
+	}
+	return true
+}
+
+func (s Shape) matches(n *analyzer.NodeStats) bool {
+	if !s.Pattern.Matches(n) {
+		return false
+	}
+	node := n.Node
+	if s.MaxLoops > 0 && node.ActualLoops <= s.MaxLoops {
+		return false
+	}
+	if s.MaxRows > 0 && n.ActualTotalRows <= s.MaxRows {
+		return false
+	}
+	if s.MaxMs > 0 && n.ExclusiveTimeMs <= s.MaxMs {
+		return false
+	}
+	return true
+}
+
+func nodeText(n *analyzer.NodeStats) string {
+	text := n.Node.NodeType
+	if n.Node.RelationName != "" {
+		text += " on " + n.Node.RelationName
+	}
+	if n.Node.IndexName != "" {
+		text += " using " + n.Node.IndexName
+	}
+	return fmt.Sprintf("%s (%.0f loops, %.0f rows, %.2f ms)", text, n.Node.ActualLoops, n.ActualTotalRows, n.ExclusiveTimeMs)
+}
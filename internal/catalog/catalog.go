@@ -0,0 +1,109 @@
+package catalog
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/mickamy/xplain/internal/model"
+	"github.com/mickamy/xplain/internal/redact"
+)
+
+const indexQuery = `
+SELECT n.nspname,
+       t.relname,
+       i.relname,
+       am.amname,
+       ix.indisunique,
+       ix.indisprimary,
+       ix.indisvalid,
+       ix.indpred IS NOT NULL,
+       pg_get_indexdef(ix.indexrelid),
+       ARRAY(SELECT pg_get_indexdef(ix.indexrelid, k + 1, true)
+             FROM generate_series(0, ix.indnkeyatts - 1) AS k ORDER BY k),
+       ARRAY(SELECT (ix.indoption[k] & 1) = 1
+             FROM generate_series(0, ix.indnkeyatts - 1) AS k ORDER BY k),
+       ARRAY(SELECT (ix.indoption[k] & 2) = 2
+             FROM generate_series(0, ix.indnkeyatts - 1) AS k ORDER BY k)
+FROM pg_index ix
+JOIN pg_class i ON i.oid = ix.indexrelid
+JOIN pg_class t ON t.oid = ix.indrelid
+JOIN pg_namespace n ON n.oid = t.relnamespace
+JOIN pg_am am ON am.oid = i.relam
+WHERE t.relname = ANY($1)
+ORDER BY n.nspname, t.relname, i.relname`
+
+// Load connects to the database and collects index metadata for the given relations.
+func Load(ctx context.Context, dsn string, relations []string) (*model.Catalog, error) {
+	redact.Register(dsn)
+	cat, err := load(ctx, dsn, relations)
+	return cat, redact.Error(err)
+}
+
+func load(ctx context.Context, dsn string, relations []string) (*model.Catalog, error) {
+	if strings.TrimSpace(dsn) == "" {
+		return nil, errors.New("catalog: empty DSN")
+	}
+	cfg, err := pgx.ParseConfig(dsn)
+	if err != nil {
+		return nil, fmt.Errorf("catalog: connect: %w", err)
+	}
+	// Parsing resolves the password from the service file or ~/.pgpass when the DSN has none.
+	redact.RegisterPassword(cfg.Password)
+	conn, err := pgx.ConnectConfig(ctx, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("catalog: connect: %w", err)
+	}
+	defer func(conn *pgx.Conn, ctx context.Context) {
+		_ = conn.Close(ctx)
+	}(conn, ctx)
+
+	indexes, err := loadIndexes(ctx, conn, relations)
+	if err != nil {
+		return nil, err
+	}
+	return &model.Catalog{Indexes: indexes}, nil
+}
+
+func loadIndexes(ctx context.Context, conn *pgx.Conn, relations []string) ([]model.Index, error) {
+	if len(relations) == 0 {
+		return nil, nil
+	}
+	rows, err := conn.Query(ctx, indexQuery, relations)
+	if err != nil {
+		return nil, fmt.Errorf("catalog: query indexes: %w", err)
+	}
+	defer rows.Close()
+
+	var out []model.Index
+	for rows.Next() {
+		var (
+			idx        model.Index
+			exprs      []string
+			desc       []bool
+			nullsFirst []bool
+		)
+		if err := rows.Scan(&idx.Schema, &idx.Table, &idx.Name, &idx.Method, &idx.Unique, &idx.Primary,
+			&idx.Valid, &idx.Partial, &idx.Definition, &exprs, &desc, &nullsFirst); err != nil {
+			return nil, fmt.Errorf("catalog: scan index: %w", err)
+		}
+		for i, expr := range exprs {
+			col := model.IndexColumn{Expr: expr}
+			if i < len(desc) {
+				col.Desc = desc[i]
+			}
+			if i < len(nullsFirst) {
+				col.NullsFirst = nullsFirst[i]
+			}
+			idx.Columns = append(idx.Columns, col)
+		}
+		out = append(out, idx)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("catalog: read indexes: %w", err)
+	}
+	return out, nil
+}
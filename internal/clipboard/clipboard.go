@@ -0,0 +1,131 @@
+// Package clipboard reads and writes the system clipboard through the platform's clipboard tools,
+// so plans can be analyzed straight from a copy without temp files.
+package clipboard
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// Name is the --input / --out value that selects the clipboard instead of a file.
+const Name = "clipboard"
+
+type tool struct {
+	name string
+	args []string
+}
+
+// pasteTools and copyTools list the commands tried in order; the first one on PATH is used.
+var (
+	pasteTools = map[string][]tool{
+		"darwin": {{"pbpaste", nil}},
+		"windows": {{"powershell.exe", []string{
+			"-NoProfile", "-Command", "[Console]::OutputEncoding = [Text.Encoding]::UTF8; Get-Clipboard -Raw",
+		}}},
+		"linux": {
+			{"wl-paste", []string{"--no-newline"}},
+			{"xclip", []string{"-selection", "clipboard", "-out"}},
+			{"xsel", []string{"--clipboard", "--output"}},
+		},
+	}
+	copyTools = map[string][]tool{
+		"darwin": {{"pbcopy", nil}},
+		"windows": {{"powershell.exe", []string{
+			"-NoProfile", "-Command", "[Console]::InputEncoding = [Text.Encoding]::UTF8; Set-Clipboard -Value ([Console]::In.ReadToEnd())",
+		}}},
+		"linux": {
+			{"wl-copy", nil},
+			{"xclip", []string{"-selection", "clipboard", "-in"}},
+			{"xsel", []string{"--clipboard", "--input"}},
+		},
+	}
+)
+
+// Read returns the clipboard's text content.
+func Read() ([]byte, error) {
+	t, err := find(pasteTools)
+	if err != nil {
+		return nil, err
+	}
+	var stderr bytes.Buffer
+	cmd := exec.Command(t.name, t.args...)
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("clipboard: %s: %w%s", t.name, err, detail(stderr.String()))
+	}
+	if len(bytes.TrimSpace(out)) == 0 {
+		return nil, errors.New("clipboard: clipboard is empty")
+	}
+	return out, nil
+}
+
+// Write replaces the clipboard's content with data.
+func Write(data []byte) error {
+	t, err := find(copyTools)
+	if err != nil {
+		return err
+	}
+	var stderr bytes.Buffer
+	cmd := exec.Command(t.name, t.args...)
+	cmd.Stdin = bytes.NewReader(data)
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("clipboard: %s: %w%s", t.name, err, detail(stderr.String()))
+	}
+	return nil
+}
+
+// Writer collects output and copies it to the clipboard on Close.
+type Writer struct {
+	buf    bytes.Buffer
+	closed bool
+}
+
+// NewWriter returns a Writer, failing early when no clipboard tool is available.
+func NewWriter() (*Writer, error) {
+	if _, err := find(copyTools); err != nil {
+		return nil, err
+	}
+	return &Writer{}, nil
+}
+
+func (w *Writer) Write(p []byte) (int, error) {
+	return w.buf.Write(p)
+}
+
+// Close copies everything written so far to the clipboard. Later calls do nothing.
+func (w *Writer) Close() error {
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+	return Write(w.buf.Bytes())
+}
+
+func find(tools map[string][]tool) (tool, error) {
+	candidates, ok := tools[runtime.GOOS]
+	if !ok {
+		// Other Unix systems ship the same X11/Wayland tools as Linux.
+		candidates = tools["linux"]
+	}
+	names := make([]string, 0, len(candidates))
+	for _, t := range candidates {
+		if _, err := exec.LookPath(t.name); err == nil {
+			return t, nil
+		}
+		names = append(names, t.name)
+	}
+	return tool{}, fmt.Errorf("clipboard: no clipboard tool found on PATH (tried %s)", strings.Join(names, ", "))
+}
+
+func detail(stderr string) string {
+	if stderr = strings.TrimSpace(stderr); stderr != "" {
+		return ": " + stderr
+	}
+	return ""
+}
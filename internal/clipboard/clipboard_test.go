@@ -0,0 +1,74 @@
+package clipboard_test
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/mickamy/xplain/internal/clipboard"
+)
+
+// fakeTools puts wl-copy/wl-paste stand-ins backed by a file on PATH.
+func fakeTools(t *testing.T) string {
+	t.Helper()
+	if runtime.GOOS != "linux" {
+		t.Skip("fake clipboard tools are shell scripts for Linux")
+	}
+	dir := t.TempDir()
+	store := filepath.Join(dir, "store")
+	scripts := map[string]string{
+		"wl-copy":  "#!/bin/sh\ncat > '" + store + "'\n",
+		"wl-paste": "#!/bin/sh\ncat '" + store + "'\n",
+	}
+	for name, body := range scripts {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(body), 0o755); err != nil {
+			t.Fatalf("write %s: %v", name, err)
+		}
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	return store
+}
+
+func TestWriterRoundTrip(t *testing.T) {
+	fakeTools(t)
+
+	w, err := clipboard.NewWriter()
+	if err != nil {
+		t.Fatalf("new writer: %v", err)
+	}
+	_, _ = w.Write([]byte("Seq Scan on orders  "))
+	_, _ = w.Write([]byte("(cost=0.00..1.00 rows=1 width=4)\n"))
+	if err := w.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("second close: %v", err)
+	}
+
+	got, err := clipboard.Read()
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	if !strings.HasPrefix(string(got), "Seq Scan on orders  (cost=") {
+		t.Fatalf("unexpected clipboard content %q", got)
+	}
+}
+
+func TestReadEmpty(t *testing.T) {
+	store := fakeTools(t)
+	if err := os.WriteFile(store, []byte("\n"), 0o644); err != nil {
+		t.Fatalf("write store: %v", err)
+	}
+	if _, err := clipboard.Read(); err == nil || !strings.Contains(err.Error(), "empty") {
+		t.Fatalf("expected an empty clipboard error, got %v", err)
+	}
+}
+
+func TestMissingTool(t *testing.T) {
+	t.Setenv("PATH", t.TempDir())
+	if _, err := clipboard.NewWriter(); err == nil || !strings.Contains(err.Error(), "no clipboard tool") {
+		t.Fatalf("expected a missing tool error, got %v", err)
+	}
+}
@@ -4,13 +4,19 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"reflect"
 	"sync"
 )
 
 // Config holds tunable thresholds for insight scoring and diff reporting.
 type Config struct {
-	Insights InsightConfig `json:"insights"`
-	Diff     DiffConfig    `json:"diff"`
+	Insights InsightConfig  `json:"insights"`
+	Diff     DiffConfig     `json:"diff"`
+	Output   OutputConfig   `json:"output"`
+	Analysis AnalysisConfig `json:"analysis"`
+	// Queries overrides insight and diff thresholds for individual statements, keyed by fingerprint or by
+	// the statement's name in the query registry or a manifest.
+	Queries map[string]QueryConfig `json:"queries,omitempty"`
 }
 
 // InsightConfig defines thresholds for insight generation.
@@ -26,6 +32,32 @@ type InsightConfig struct {
 	RowEstimateCriticalLow  float64 `json:"row_estimate_critical_low"`
 	SpillNewBlocks          float64 `json:"spill_new_blocks"`
 	ParallelLimitKeepRatio  float64 `json:"parallel_limit_keep_ratio"`
+	LimitWasteRatio         float64 `json:"limit_waste_ratio"`
+	PaginationOffsetRows    float64 `json:"pagination_offset_rows"`
+	FullAggregateRows       float64 `json:"full_aggregate_rows"`
+	DistinctFanoutRatio     float64 `json:"distinct_fanout_ratio"`
+	FunctionScanRows        float64 `json:"function_scan_rows"`
+	TriggerDominantPercent  float64 `json:"trigger_dominant_percent"`
+	TriggerSectionPercent   float64 `json:"trigger_section_percent"`
+	WideRowBytes            float64 `json:"wide_row_bytes"`
+	WideRowMinPercent       float64 `json:"wide_row_min_percent"`
+	SortAdvicePercent       float64 `json:"sort_advice_percent"`
+	UnusedOutputColumns     float64 `json:"unused_output_columns"`
+	UnusedOutputRows        float64 `json:"unused_output_rows"`
+	MemoryBudgetKB          float64 `json:"memory_budget_kb"`
+	PlanningBufferBlocks    int64   `json:"planning_buffer_blocks"`
+	PerRowWarningMs         float64 `json:"per_row_warning_ms"`
+	PerRowCriticalMs        float64 `json:"per_row_critical_ms"`
+	PerRowMinSelfMs         float64 `json:"per_row_min_self_ms"`
+	TransferWarningPercent  float64 `json:"transfer_warning_percent"`
+	TransferMinMs           float64 `json:"transfer_min_ms"`
+	ResultSizeWarningKB     float64 `json:"result_size_warning_kb"`
+	WorkerSkewRatio         float64 `json:"worker_skew_ratio"`
+	WorkerSkewMinPercent    float64 `json:"worker_skew_min_percent"`
+	CacheHitWarningPercent  float64 `json:"cache_hit_warning_percent"`
+	CacheHitMinBlocks       int64   `json:"cache_hit_min_blocks"`
+	CostSkewFactor          float64 `json:"cost_skew_factor"`
+	CostModelWarningPercent float64 `json:"cost_model_warning_percent"`
 }
 
 // DiffConfig defines thresholds for diff summaries.
@@ -35,6 +67,93 @@ type DiffConfig struct {
 	MaxItems         int     `json:"max_items"`
 	CriticalDeltaMs  float64 `json:"critical_delta_ms"`
 	WarningDeltaMs   float64 `json:"warning_delta_ms"`
+	// Queries overrides the thresholds for individual statements, keyed by fingerprint; xplain thresholds
+	// learns them from the run history.
+	Queries map[string]QueryDiffConfig `json:"queries,omitempty"`
+}
+
+// QueryDiffConfig holds one statement's diff thresholds. Zero fields keep the global value.
+type QueryDiffConfig struct {
+	// Query is the normalized statement, recorded only to make the file readable.
+	Query            string  `json:"query,omitempty"`
+	MinSelfDeltaMs   float64 `json:"min_self_delta_ms,omitempty"`
+	MinPercentChange float64 `json:"min_percent_change,omitempty"`
+	CriticalDeltaMs  float64 `json:"critical_delta_ms,omitempty"`
+	WarningDeltaMs   float64 `json:"warning_delta_ms,omitempty"`
+}
+
+// ForQuery returns the thresholds that apply to the statement with the given fingerprint.
+func (d DiffConfig) ForQuery(fingerprint string) DiffConfig {
+	q, ok := d.Queries[fingerprint]
+	if !ok {
+		return d
+	}
+	return d.with(q)
+}
+
+func (d DiffConfig) with(q QueryDiffConfig) DiffConfig {
+	if q.MinSelfDeltaMs > 0 {
+		d.MinSelfDeltaMs = q.MinSelfDeltaMs
+	}
+	if q.MinPercentChange > 0 {
+		d.MinPercentChange = q.MinPercentChange
+	}
+	if q.CriticalDeltaMs > 0 {
+		d.CriticalDeltaMs = q.CriticalDeltaMs
+	}
+	if q.WarningDeltaMs > 0 {
+		d.WarningDeltaMs = q.WarningDeltaMs
+	}
+	return d
+}
+
+// QueryConfig overrides the thresholds for one statement, so analytical and transactional queries in one
+// repository can be judged by different standards. Zero fields keep the global value.
+type QueryConfig struct {
+	Insights InsightConfig   `json:"insights"`
+	Diff     QueryDiffConfig `json:"diff"`
+}
+
+// ForQuery returns the configuration for one statement, identified by its fingerprint and, when it has
+// one, its name: the learned diff.queries thresholds for the fingerprint first, then the queries entries
+// for the fingerprint and for the name, the name winning where both set a threshold.
+func (c Config) ForQuery(fingerprint, name string) Config {
+	c.Diff = c.Diff.ForQuery(fingerprint)
+	for _, key := range []string{fingerprint, name} {
+		q, ok := c.Queries[key]
+		if key == "" || !ok {
+			continue
+		}
+		dst, src := reflect.ValueOf(&c.Insights).Elem(), reflect.ValueOf(q.Insights)
+		for i := 0; i < src.NumField(); i++ {
+			if !src.Field(i).IsZero() {
+				dst.Field(i).Set(src.Field(i))
+			}
+		}
+		c.Diff = c.Diff.with(q.Diff)
+	}
+	return c
+}
+
+// OutputConfig defines where saved plans, reports and history are written.
+type OutputConfig struct {
+	Dir         string `json:"dir"`
+	BaselineDir string `json:"baseline_dir"`
+	// RedactHosts keeps database host names out of saved envelopes, reports and error messages.
+	RedactHosts bool `json:"redact_hosts"`
+}
+
+// AnalysisConfig defines how plans are turned into node statistics.
+type AnalysisConfig struct {
+	// ParallelTime is "wall" to count the time of nodes below a Gather as elapsed time, or "cpu" to sum
+	// it across the processes that ran them.
+	ParallelTime string `json:"parallel_time"`
+	// HotBy ranks hot nodes by "exclusive" (self time), "inclusive" time, own "buffers" or "io" time;
+	// HotLimit caps how many are kept and HotMinPercent is the share of that measure a node needs, 0 for
+	// no threshold.
+	HotBy         string  `json:"hot_by"`
+	HotLimit      int     `json:"hot_limit"`
+	HotMinPercent float64 `json:"hot_min_percent"`
 }
 
 var (
@@ -57,6 +176,32 @@ func Default() Config {
 			RowEstimateCriticalLow:  0.2,
 			SpillNewBlocks:          100,
 			ParallelLimitKeepRatio:  0.10,
+			LimitWasteRatio:         50,
+			PaginationOffsetRows:    1000,
+			FullAggregateRows:       100000,
+			DistinctFanoutRatio:     5,
+			FunctionScanRows:        10000,
+			TriggerDominantPercent:  0.30,
+			TriggerSectionPercent:   0.05,
+			WideRowBytes:            1024,
+			WideRowMinPercent:       0.10,
+			SortAdvicePercent:       0.10,
+			UnusedOutputColumns:     3,
+			UnusedOutputRows:        10000,
+			MemoryBudgetKB:          65536,
+			PlanningBufferBlocks:    1000,
+			PerRowWarningMs:         0.05,
+			PerRowCriticalMs:        0.5,
+			PerRowMinSelfMs:         1,
+			TransferWarningPercent:  0.5,
+			TransferMinMs:           20,
+			ResultSizeWarningKB:     102400,
+			WorkerSkewRatio:         1.5,
+			WorkerSkewMinPercent:    0.10,
+			CacheHitWarningPercent:  0.50,
+			CacheHitMinBlocks:       1000,
+			CostSkewFactor:          10,
+			CostModelWarningPercent: 0.20,
 		},
 		Diff: DiffConfig{
 			MinSelfDeltaMs:   2.0,
@@ -65,6 +210,16 @@ func Default() Config {
 			CriticalDeltaMs:  10.0,
 			WarningDeltaMs:   5.0,
 		},
+		Output: OutputConfig{
+			Dir:         ".xplain",
+			BaselineDir: ".xplain/baselines",
+		},
+		Analysis: AnalysisConfig{
+			ParallelTime:  "wall",
+			HotBy:         "exclusive",
+			HotLimit:      5,
+			HotMinPercent: 0.10,
+		},
 	}
 }
 
@@ -82,7 +237,8 @@ func Use(cfg Config) {
 	mu.Unlock()
 }
 
-// Apply loads configuration from the provided path (JSON). Empty path resets to default.
+// Apply loads configuration from the provided path (JSON, with // comments allowed). Empty path resets
+// to default.
 func Apply(path string) error {
 	if path == "" {
 		Use(Default())
@@ -93,7 +249,7 @@ func Apply(path string) error {
 		return fmt.Errorf("read config: %w", err)
 	}
 	cfg := Default()
-	if err := json.Unmarshal(data, &cfg); err != nil {
+	if err := json.Unmarshal(stripComments(data), &cfg); err != nil {
 		return fmt.Errorf("parse config: %w", err)
 	}
 	Use(cfg)
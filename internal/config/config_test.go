@@ -3,6 +3,8 @@ package config
 import (
 	"os"
 	"path/filepath"
+	"regexp"
+	"strings"
 	"testing"
 
 	"github.com/mickamy/xplain/test"
@@ -43,3 +45,183 @@ func TestApplyMissingFile(t *testing.T) {
 		t.Fatalf("expected error for missing config file")
 	}
 }
+
+func TestValidate(t *testing.T) {
+	cfg, problems, err := Validate([]byte(`{
+		"insights": {"hotspot_warning_percent": 0.5, "hotspot_critical": 0.9, "sort_advice_percent": 10},
+		"diff": {"queries": {"abc": {"warning_delta_ms": 20, "typo": 1}}},
+		"outptu": {"dir": "x"}
+	}`))
+	if err != nil {
+		t.Fatalf("validate: %v", err)
+	}
+	if cfg.Insights.HotspotWarningPercent != 0.5 || cfg.Insights.HotspotCriticalPercent != Default().Insights.HotspotCriticalPercent {
+		t.Fatalf("expected the file merged over the defaults, got %+v", cfg.Insights)
+	}
+	var keys []string
+	for _, p := range problems {
+		keys = append(keys, p.Key)
+	}
+	want := []string{
+		"diff.queries.abc.typo",
+		"insights.hotspot_critical",
+		"outptu",
+		"insights.hotspot_warning_percent",
+		"insights.sort_advice_percent",
+		"diff.queries.abc.warning_delta_ms",
+	}
+	if strings.Join(keys, " ") != strings.Join(want, " ") {
+		t.Fatalf("expected problems %v, got %v", want, problems)
+	}
+
+	_, problems, err = Validate([]byte(`{"analysis": {"hot_by": "rows", "hot_min_percent": 5}}`))
+	if err != nil || len(problems) != 2 || problems[0].Key != "analysis.hot_by" || problems[1].Key != "analysis.hot_min_percent" {
+		t.Fatalf("expected the hot node settings to be checked, got %v, %v", problems, err)
+	}
+
+	_, problems, err = Validate([]byte(`{"insights": {"cost_skew_factor": 0.5, "cost_model_warning_percent": 20}}`))
+	if err != nil || len(problems) != 2 || problems[0].Key != "insights.cost_skew_factor" || problems[1].Key != "insights.cost_model_warning_percent" {
+		t.Fatalf("expected the cost model settings to be checked, got %v, %v", problems, err)
+	}
+
+	if _, problems, err := Validate([]byte(`{}`)); err != nil || len(problems) != 0 {
+		t.Fatalf("expected the defaults to validate, got %v, %v", problems, err)
+	}
+	if _, _, err := Validate([]byte(`{"insights": `)); err == nil {
+		t.Fatalf("expected invalid JSON to fail")
+	}
+}
+
+func TestEncode(t *testing.T) {
+	cfg := Default()
+	if err := cfg.Set("insights.hotspot_warning_percent", "0.25"); err != nil {
+		t.Fatalf("set: %v", err)
+	}
+	if err := cfg.Set("analysis.parallel_time", "cpu"); err != nil {
+		t.Fatalf("set: %v", err)
+	}
+	if err := cfg.Set("insights.hotspot", "1"); err == nil {
+		t.Fatalf("expected unknown keys to fail")
+	}
+	if err := cfg.Set("diff.max_items", "many"); err == nil {
+		t.Fatalf("expected invalid numbers to fail")
+	}
+
+	out, err := Encode(cfg, "jsonc")
+	if err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+	if !strings.Contains(string(out), "    // "+Describe("insights.hotspot_warning_percent")+"\n") {
+		t.Fatalf("expected a comment above each setting, got:\n%s", out)
+	}
+	got, problems, err := Validate(out)
+	if err != nil || len(problems) != 0 {
+		t.Fatalf("expected the commented file to validate, got %v, %v", problems, err)
+	}
+	if got.Insights.HotspotWarningPercent != 0.25 || got.Analysis.ParallelTime != "cpu" {
+		t.Fatalf("expected the set values to round-trip, got %+v", got)
+	}
+
+	path := filepath.Join(t.TempDir(), "xplain.json")
+	if err := os.WriteFile(path, out, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { Use(Default()) })
+	if err := Apply(path); err != nil {
+		t.Fatalf("apply commented config: %v", err)
+	}
+	if Active().Insights.HotspotWarningPercent != 0.25 {
+		t.Fatalf("expected Apply to read the commented file")
+	}
+	if _, err := Encode(cfg, "yaml"); err == nil {
+		t.Fatalf("expected unknown formats to fail")
+	}
+}
+
+func TestMergeDiffQueries(t *testing.T) {
+	// The commented file config init writes takes learned thresholds and keeps its comments and key order.
+	data, err := Encode(Default(), "jsonc")
+	if err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+	learned := map[string]QueryDiffConfig{"abc123": {Query: "select 1", MinSelfDeltaMs: 2.5}}
+	out, dropped, err := MergeDiffQueries(data, learned)
+	if err != nil || dropped {
+		t.Fatalf("expected the commented config to merge, got %v (dropped %v)", err, dropped)
+	}
+	start, end := strings.Index(string(out), ",\n    \"queries\": {"), strings.Index(string(out), "\n  },\n  \"output\"")
+	if start < 0 || end < start || string(out[:start])+string(out[end:]) != string(data) {
+		t.Fatalf("expected only diff.queries to be added:\n%s", out)
+	}
+	cfg, problems, err := Validate(out)
+	if err != nil || len(problems) != 0 {
+		t.Fatalf("expected a valid config, got %v %v", err, problems)
+	}
+	if q := cfg.Diff.Queries["abc123"]; q.MinSelfDeltaMs != 2.5 || cfg.Insights != Default().Insights {
+		t.Fatalf("expected the learned threshold next to the existing settings, got %+v", cfg.Diff.Queries)
+	}
+
+	// Existing entries keep their place; updated ones are rewritten where they are.
+	out, _, err = MergeDiffQueries([]byte(`{"diff": {"queries": {"zz": {"min_self_delta_ms": 1}, "abc123": {"query": "old"}}}, "output": {"dir": "x"}}`), learned)
+	if err != nil || !regexp.MustCompile(`(?s)"zz".*"abc123": \{\s*"query": "select 1".*\}\s*\},\s*"output"`).Match(out) {
+		t.Fatalf("expected the entries in place and output after diff, got %s (%v)", out, err)
+	}
+
+	fresh, dropped, err := MergeDiffQueries(nil, learned)
+	if err != nil || dropped || !strings.Contains(string(fresh), `"abc123"`) {
+		t.Fatalf("expected a new config holding the thresholds, got %s (%v)", fresh, err)
+	}
+}
+
+func TestDiffForQuery(t *testing.T) {
+	cfg := Default().Diff
+	cfg.Queries = map[string]QueryDiffConfig{"abc": {MinSelfDeltaMs: 0.5, CriticalDeltaMs: 1.5}}
+
+	tuned := cfg.ForQuery("abc")
+	if tuned.MinSelfDeltaMs != 0.5 || tuned.CriticalDeltaMs != 1.5 {
+		t.Fatalf("expected per-query overrides, got %+v", tuned)
+	}
+	if tuned.MinPercentChange != cfg.MinPercentChange || tuned.WarningDeltaMs != cfg.WarningDeltaMs {
+		t.Fatalf("expected unset fields to keep the global values, got %+v", tuned)
+	}
+	if other := cfg.ForQuery("other"); other.MinSelfDeltaMs != cfg.MinSelfDeltaMs {
+		t.Fatalf("expected global thresholds for other queries, got %+v", other)
+	}
+}
+
+func TestConfigForQuery(t *testing.T) {
+	cfg, problems, err := Validate([]byte(`{
+		"diff": {"queries": {"fp1": {"critical_delta_ms": 50}}},
+		"queries": {
+			"fp1": {"insights": {"hotspot_warning_percent": 0.3, "hotspot_critical_percent": 0.6}, "diff": {"critical_delta_ms": 80}},
+			"nightly_report": {"insights": {"hotspot_critical_percent": 0.9, "hostpot": 1}, "diff": {"min_self_delta_ms": 100}},
+			"broken": {"insights": {"hotspot_warning_percent": 0.5}}
+		}
+	}`))
+	if err != nil {
+		t.Fatalf("validate: %v", err)
+	}
+	var keys []string
+	for _, p := range problems {
+		keys = append(keys, p.Key)
+	}
+	want := []string{"queries.nightly_report.insights.hostpot", "queries.broken.insights.hotspot_warning_percent"}
+	if strings.Join(keys, " ") != strings.Join(want, " ") {
+		t.Fatalf("expected problems %v, got %v", want, problems)
+	}
+
+	tuned := cfg.ForQuery("fp1", "nightly_report")
+	in := tuned.Insights
+	if in.HotspotWarningPercent != 0.3 || in.HotspotCriticalPercent != 0.9 || in.BufferWarningBlocks != Default().Insights.BufferWarningBlocks {
+		t.Fatalf("expected the name to win over the fingerprint and unset fields to keep the global values, got %+v", in)
+	}
+	if tuned.Diff.CriticalDeltaMs != 80 || tuned.Diff.MinSelfDeltaMs != 100 {
+		t.Fatalf("expected queries entries to override the learned diff thresholds, got %+v", tuned.Diff)
+	}
+	if other := cfg.ForQuery("fp2", ""); other.Insights != Default().Insights || other.Diff.CriticalDeltaMs != Default().Diff.CriticalDeltaMs {
+		t.Fatalf("expected global thresholds for other queries, got %+v", other)
+	}
+	if cfg.Insights.HotspotCriticalPercent != Default().Insights.HotspotCriticalPercent {
+		t.Fatalf("expected ForQuery to leave the configuration untouched")
+	}
+}
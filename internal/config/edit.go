@@ -0,0 +1,194 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"slices"
+)
+
+// MergeDiffQueries adds queries to diff.queries of the configuration file data, replacing the entries
+// with the same fingerprint, and returns the updated file. Empty data starts a new file. Only the
+// diff.queries value is rewritten, or inserted when missing, so the rest of the file keeps its key order,
+// its formatting and its // comments; dropped reports whether there were comments inside diff.queries,
+// which are not kept.
+func MergeDiffQueries(data []byte, queries map[string]QueryDiffConfig) (out []byte, dropped bool, err error) {
+	blank := stripComments(data)
+	open := bytes.IndexFunc(blank, func(r rune) bool { return r != ' ' && r != '\t' && r != '\r' && r != '\n' })
+	if open < 0 {
+		doc := map[string]map[string]map[string]QueryDiffConfig{"diff": {"queries": queries}}
+		if out, err = json.MarshalIndent(doc, "", "  "); err != nil {
+			return nil, false, fmt.Errorf("encode config: %w", err)
+		}
+		return append(out, '\n'), false, nil
+	}
+	if blank[open] != '{' {
+		return nil, false, fmt.Errorf("parse config: expected a JSON object")
+	}
+
+	root, err := members(blank, open)
+	if err != nil {
+		return nil, false, err
+	}
+	diffSection, ok := root.find("diff")
+	if !ok || blank[diffSection.start] != '{' {
+		section, err := queriesObject(nil, queries, "    ")
+		if err != nil {
+			return nil, false, err
+		}
+		section = append(append([]byte("{\n    \"queries\": "), section...), "\n  }"...)
+		if ok {
+			return splice(data, diffSection.start, diffSection.end, section), false, nil
+		}
+		return root.insert(data, "diff", section, ""), false, nil
+	}
+
+	diffMembers, err := members(blank, diffSection.start)
+	if err != nil {
+		return nil, false, err
+	}
+	existing, ok := diffMembers.find("queries")
+	if !ok {
+		section, err := queriesObject(nil, queries, "    ")
+		if err != nil {
+			return nil, false, err
+		}
+		return diffMembers.insert(data, "queries", section, "  "), false, nil
+	}
+	section, err := queriesObject(blank[existing.start:existing.end], queries, "    ")
+	if err != nil {
+		return nil, false, err
+	}
+	dropped = !bytes.Equal(data[existing.start:existing.end], blank[existing.start:existing.end])
+	return splice(data, existing.start, existing.end, section), dropped, nil
+}
+
+// member is one key of a JSON object and the span of its value in the document.
+type member struct {
+	key        string
+	start, end int
+}
+
+// object lists the members of a JSON object in order, with the offsets of its braces.
+type object struct {
+	open, close int
+	members     []member
+}
+
+// members reads the object whose '{' is at data[open].
+func members(data []byte, open int) (object, error) {
+	obj := object{open: open}
+	dec := json.NewDecoder(bytes.NewReader(data[open:]))
+	if _, err := dec.Token(); err != nil {
+		return obj, fmt.Errorf("parse config: %w", err)
+	}
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return obj, fmt.Errorf("parse config: %w", err)
+		}
+		key, _ := tok.(string)
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			return obj, fmt.Errorf("parse config: %w", err)
+		}
+		end := open + int(dec.InputOffset())
+		obj.members = append(obj.members, member{key: key, start: end - len(raw), end: end})
+	}
+	if _, err := dec.Token(); err != nil {
+		return obj, fmt.Errorf("parse config: %w", err)
+	}
+	obj.close = open + int(dec.InputOffset()) - 1
+	return obj, nil
+}
+
+func (o object) find(key string) (member, bool) {
+	for _, m := range o.members {
+		if m.key == key {
+			return m, true
+		}
+	}
+	return member{}, false
+}
+
+// insert adds key with value as the last member of o, the object's own members indented by indent
+// plus two spaces.
+func (o object) insert(data []byte, key string, value []byte, indent string) []byte {
+	text := "\n" + indent + `  "` + key + `": ` + string(value)
+	at := o.open + 1
+	if n := len(o.members); n > 0 {
+		text = "," + text
+		at = o.members[n-1].end
+	} else {
+		text += "\n" + indent
+	}
+	return splice(data, at, at, []byte(text))
+}
+
+// queriesObject returns the diff.queries object: the entries of existing in their order, those found in
+// queries replaced, then the new fingerprints of queries sorted, indented to sit at indent.
+func queriesObject(existing []byte, queries map[string]QueryDiffConfig, indent string) ([]byte, error) {
+	var entries []member
+	if len(existing) > 0 && existing[0] == '{' {
+		obj, err := members(existing, 0)
+		if err != nil {
+			return nil, err
+		}
+		entries = obj.members
+	}
+	var compact bytes.Buffer
+	compact.WriteByte('{')
+	write := func(key string, value []byte) error {
+		if compact.Len() > 1 {
+			compact.WriteByte(',')
+		}
+		name, _ := json.Marshal(key)
+		compact.Write(name)
+		compact.WriteByte(':')
+		return json.Compact(&compact, value)
+	}
+	seen := map[string]bool{}
+	for _, e := range entries {
+		value := existing[e.start:e.end]
+		if q, ok := queries[e.key]; ok {
+			var err error
+			if value, err = json.Marshal(q); err != nil {
+				return nil, fmt.Errorf("encode config: %w", err)
+			}
+		}
+		seen[e.key] = true
+		if err := write(e.key, value); err != nil {
+			return nil, fmt.Errorf("encode config: %w", err)
+		}
+	}
+	var added []string
+	for fingerprint := range queries {
+		if !seen[fingerprint] {
+			added = append(added, fingerprint)
+		}
+	}
+	slices.Sort(added)
+	for _, fingerprint := range added {
+		value, err := json.Marshal(queries[fingerprint])
+		if err == nil {
+			err = write(fingerprint, value)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("encode config: %w", err)
+		}
+	}
+	compact.WriteByte('}')
+
+	var out bytes.Buffer
+	if err := json.Indent(&out, compact.Bytes(), indent, "  "); err != nil {
+		return nil, fmt.Errorf("encode config: %w", err)
+	}
+	return out.Bytes(), nil
+}
+
+func splice(data []byte, start, end int, text []byte) []byte {
+	out := make([]byte, 0, len(data)-(end-start)+len(text))
+	out = append(out, data[:start]...)
+	out = append(out, text...)
+	return append(out, data[end:]...)
+}
@@ -0,0 +1,224 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// descriptions explain each setting in the commented starter file config init writes.
+var descriptions = map[string]string{
+	"insights.hotspot_critical_percent":   "Share of the runtime (0-1) a node's self time reaches to be a critical hot spot.",
+	"insights.hotspot_warning_percent":    "Share of the runtime (0-1) a node's self time reaches to be a hot spot warning.",
+	"insights.seq_scan_buffer_hint":       "Buffers a hot Seq Scan touches before the hot spot suggests an index.",
+	"insights.buffer_warning_blocks":      "Buffers one node touches before buffer churn is a warning.",
+	"insights.buffer_critical_blocks":     "Buffers one node touches before buffer churn is critical.",
+	"insights.nested_loop_warn_loops":     "Inner-side loops of a Nested Loop before it is flagged.",
+	"insights.nested_loop_critical_loops": "Inner-side loops of a Nested Loop before it is critical.",
+	"insights.row_estimate_critical_high": "Actual/estimated row ratio above which estimate drift is critical.",
+	"insights.row_estimate_critical_low":  "Actual/estimated row ratio below which estimate drift is critical.",
+	"insights.spill_new_blocks":           "Temp blocks a node writes before its spill is reported.",
+	"insights.parallel_limit_keep_ratio":  "Fraction (0-1) of a Gather's rows a LIMIT must keep to not be flagged.",
+	"insights.limit_waste_ratio":          "Rows read per row a LIMIT returns before the work is called waste.",
+	"insights.pagination_offset_rows":     "OFFSET rows skipped before keyset pagination is suggested.",
+	"insights.full_aggregate_rows":        "Rows an aggregate reads through a Seq Scan before it is flagged.",
+	"insights.distinct_fanout_ratio":      "Input rows per output row of a DISTINCT or GROUP BY before it is flagged.",
+	"insights.function_scan_rows":         "Rows a filter calling a function examines before it is flagged.",
+	"insights.trigger_dominant_percent":   "Share of execution (0-1) foreign key triggers take before they are flagged.",
+	"insights.trigger_section_percent":    "Share of execution (0-1) triggers take before the Triggers section is shown.",
+	"insights.wide_row_bytes":             "Average row width in bytes before rows are reported as wide.",
+	"insights.wide_row_min_percent":       "Share of the runtime (0-1) a scan takes before its row width is judged.",
+	"insights.sort_advice_percent":        "Share of the runtime (0-1) a Sort takes before an index is suggested.",
+	"insights.unused_output_columns":      "Unused output columns a node carries before it is flagged (VERBOSE plans).",
+	"insights.unused_output_rows":         "Rows a node with unused output columns returns before it is flagged.",
+	"insights.memory_budget_kb":           "Estimated peak memory in KiB above which the query is flagged.",
+	"insights.planning_buffer_blocks":     "Buffers planning touches before it is flagged as heavy I/O.",
+	"insights.per_row_warning_ms":         "Self time per returned row in ms before a node is a warning.",
+	"insights.per_row_critical_ms":        "Self time per returned row in ms before a node is critical.",
+	"insights.per_row_min_self_ms":        "Self time in ms a node needs before its per-row cost is judged.",
+	"insights.transfer_warning_percent":   "Share of the client round trip (0-1) moving the result takes before it is flagged.",
+	"insights.transfer_min_ms":            "Transfer time in ms below which it is never flagged.",
+	"insights.result_size_warning_kb":     "Estimated result size in KiB before it is flagged.",
+	"insights.worker_skew_ratio":          "Busiest parallel process's work over an even share before skew is flagged.",
+	"insights.worker_skew_min_percent":    "Share of the runtime (0-1) a Gather needs before its skew is judged.",
+	"insights.cache_hit_warning_percent":  "Cache hit ratio (0-1) below which a hot node's block reads are called cold.",
+	"insights.cache_hit_min_blocks":       "Shared blocks a hot node accesses itself before its hit ratio is judged.",
+	"insights.cost_skew_factor":           "How many times off the plan's cost per ms a node's own ratio is before it is flagged.",
+	"insights.cost_model_warning_percent": "Share of the runtime (0-1) a mis-costed node's self time reaches to be a warning.",
+	"diff.min_self_delta_ms":              "Self-time change in ms a node needs to appear in a diff.",
+	"diff.min_percent_change":             "Self-time change in percent (0-100) a node needs to appear in a diff.",
+	"diff.max_items":                      "Regressions and improvements listed per diff.",
+	"diff.critical_delta_ms":              "Self-time regression in ms that makes a diff critical.",
+	"diff.warning_delta_ms":               "Self-time regression in ms that makes a diff a warning.",
+	"diff.queries":                        "Per-query overrides keyed by fingerprint; xplain thresholds fills these in.",
+	"queries":                             "Per-query overrides of the insights and diff settings, keyed by fingerprint or query name.",
+	"output.dir":                          "Directory for saved plans, reports and history.",
+	"output.baseline_dir":                 "Directory for the baselines xplain ci compares against.",
+	"output.redact_hosts":                 "Keep database host names out of saved envelopes, reports and errors.",
+	"analysis.parallel_time":              "Time of nodes below a Gather: wall for elapsed time, cpu to sum every process.",
+	"analysis.hot_by":                     "Rank hot nodes by exclusive (self) time, inclusive time, own buffers or io time.",
+	"analysis.hot_limit":                  "How many hot nodes to keep at most.",
+	"analysis.hot_min_percent":            "Share of the ranking measure a node needs to count as hot; 0 keeps every node up to the limit.",
+}
+
+// Describe returns the one-line explanation of a setting such as "insights.hotspot_warning_percent".
+func Describe(key string) string {
+	return descriptions[key]
+}
+
+// Encode writes cfg as a configuration file: "json" is plain JSON, "jsonc" adds a // comment above every
+// setting explaining it. Apply and Validate read both.
+func Encode(cfg Config, format string) ([]byte, error) {
+	out, err := json.MarshalIndent(cfg, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("encode config: %w", err)
+	}
+	switch format {
+	case "json":
+		return append(out, '\n'), nil
+	case "jsonc":
+	default:
+		return nil, fmt.Errorf("config: unknown format %q (expected jsonc or json)", format)
+	}
+	var b bytes.Buffer
+	b.WriteString("// xplain configuration. Remove the settings you do not change; omitted ones keep their default.\n")
+	var path []string
+	for _, line := range strings.Split(string(out), "\n") {
+		text := strings.TrimSpace(line)
+		if strings.HasPrefix(text, "}") {
+			if len(path) > 0 {
+				path = path[:len(path)-1]
+			}
+		}
+		if key, _, ok := strings.Cut(text, `": `); ok && strings.HasPrefix(key, `"`) {
+			full := strings.Join(append(path, strings.TrimPrefix(key, `"`)), ".")
+			if doc := descriptions[full]; doc != "" {
+				indent := line[:len(line)-len(strings.TrimLeft(line, " "))]
+				b.WriteString(indent + "// " + doc + "\n")
+			}
+			if strings.HasSuffix(text, "{") {
+				path = append(path, strings.TrimPrefix(key, `"`))
+			}
+		}
+		b.WriteString(line + "\n")
+	}
+	return b.Bytes(), nil
+}
+
+// Set assigns the setting at key, e.g. "insights.hotspot_warning_percent", from its text form.
+func (c *Config) Set(key, value string) error {
+	v := reflect.ValueOf(c).Elem()
+	parts := strings.Split(key, ".")
+	for i, part := range parts {
+		field, ok := fieldByTag(v, part)
+		if !ok {
+			return fmt.Errorf("config: unknown key %q", key)
+		}
+		if i < len(parts)-1 {
+			if field.Kind() != reflect.Struct {
+				return fmt.Errorf("config: unknown key %q", key)
+			}
+			v = field
+			continue
+		}
+		value = strings.TrimSpace(value)
+		var err error
+		switch field.Kind() {
+		case reflect.Float64:
+			var f float64
+			if f, err = strconv.ParseFloat(value, 64); err == nil {
+				field.SetFloat(f)
+			}
+		case reflect.Int, reflect.Int64:
+			var n int64
+			if n, err = strconv.ParseInt(value, 10, 64); err == nil {
+				field.SetInt(n)
+			}
+		case reflect.Bool:
+			var flag bool
+			if flag, err = strconv.ParseBool(value); err == nil {
+				field.SetBool(flag)
+			}
+		case reflect.String:
+			field.SetString(value)
+		default:
+			return fmt.Errorf("config: %s cannot be set from the command line", key)
+		}
+		if err != nil {
+			return fmt.Errorf("config: %s: invalid value %q", key, value)
+		}
+	}
+	return nil
+}
+
+// Get returns the text form of the setting at key, as Set accepts it.
+func (c Config) Get(key string) (string, bool) {
+	v := reflect.ValueOf(c)
+	for _, part := range strings.Split(key, ".") {
+		if v.Kind() != reflect.Struct {
+			return "", false
+		}
+		field, ok := fieldByTag(v, part)
+		if !ok {
+			return "", false
+		}
+		v = field
+	}
+	switch v.Kind() {
+	case reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'g', -1, 64), true
+	case reflect.Int, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10), true
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool()), true
+	case reflect.String:
+		return v.String(), true
+	}
+	return "", false
+}
+
+func fieldByTag(v reflect.Value, name string) (reflect.Value, bool) {
+	for i := 0; i < v.NumField(); i++ {
+		tag, _, _ := strings.Cut(v.Type().Field(i).Tag.Get("json"), ",")
+		if tag == name {
+			return v.Field(i), true
+		}
+	}
+	return reflect.Value{}, false
+}
+
+// stripComments blanks the // line comments of a jsonc file with spaces, leaving string contents alone,
+// so the result decodes as JSON with the same line numbers and byte offsets.
+func stripComments(data []byte) []byte {
+	out := make([]byte, 0, len(data))
+	inString, escaped := false, false
+	for i := 0; i < len(data); i++ {
+		c := data[i]
+		switch {
+		case inString:
+			switch {
+			case escaped:
+				escaped = false
+			case c == '\\':
+				escaped = true
+			case c == '"':
+				inString = false
+			}
+		case c == '"':
+			inString = true
+		case c == '/' && i+1 < len(data) && data[i+1] == '/':
+			for ; i < len(data) && data[i] != '\n'; i++ {
+				out = append(out, ' ')
+			}
+			if i < len(data) {
+				out = append(out, '\n')
+			}
+			continue
+		}
+		out = append(out, c)
+	}
+	return out
+}
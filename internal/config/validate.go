@@ -0,0 +1,181 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Problem is one issue Validate found in a configuration file.
+type Problem struct {
+	// Key is the setting's path, e.g. "insights.hotspot_warning_percent".
+	Key     string
+	Message string
+}
+
+func (p Problem) String() string {
+	return p.Key + ": " + p.Message
+}
+
+// Validate parses a configuration file as Apply does and checks it: keys Apply would silently ignore,
+// warning thresholds at or above their critical counterpart, and fractions outside 0–1. It returns the
+// effective configuration, the file merged over Default, along with the problems; the error is for
+// input that is not valid JSON.
+func Validate(data []byte) (Config, []Problem, error) {
+	data = stripComments(data)
+	cfg := Default()
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return cfg, nil, fmt.Errorf("parse config: %w", err)
+	}
+	var raw map[string]any
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return cfg, nil, fmt.Errorf("parse config: %w", err)
+	}
+	problems := unknownKeys("", raw, reflect.TypeOf(cfg))
+	problems = append(problems, cfg.check()...)
+	return cfg, problems, nil
+}
+
+// unknownKeys lists the keys of raw that the struct type t has no JSON field for.
+func unknownKeys(prefix string, raw map[string]any, t reflect.Type) []Problem {
+	fields := map[string]reflect.Type{}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name != "" && name != "-" {
+			fields[name] = f.Type
+		}
+	}
+	keys := make([]string, 0, len(raw))
+	for key := range raw {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var problems []Problem
+	for _, key := range keys {
+		path := prefix + key
+		ft, ok := fields[key]
+		if !ok {
+			problems = append(problems, Problem{Key: path, Message: "unknown key, ignored"})
+			continue
+		}
+		switch {
+		case ft.Kind() == reflect.Struct:
+			if nested, ok := raw[key].(map[string]any); ok {
+				problems = append(problems, unknownKeys(path+".", nested, ft)...)
+			}
+		case ft.Kind() == reflect.Map && ft.Elem().Kind() == reflect.Struct:
+			entries, _ := raw[key].(map[string]any)
+			names := make([]string, 0, len(entries))
+			for name := range entries {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			for _, name := range names {
+				if nested, ok := entries[name].(map[string]any); ok {
+					problems = append(problems, unknownKeys(path+"."+name+".", nested, ft.Elem())...)
+				}
+			}
+		}
+	}
+	return problems
+}
+
+// check reports thresholds that contradict each other or fall outside their range, globally and for each
+// queries entry merged over the global settings. Problems of an entry that the global settings already
+// have are reported once, for the global key.
+func (c Config) check() []Problem {
+	problems := c.checkThresholds("")
+	global := map[string]bool{}
+	for _, p := range problems {
+		global[p.Key] = true
+	}
+	keys := make([]string, 0, len(c.Queries))
+	for key := range c.Queries {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		prefix := "queries." + key + "."
+		for _, p := range c.ForQuery(key, "").checkThresholds(prefix) {
+			if !global[strings.TrimPrefix(p.Key, prefix)] {
+				problems = append(problems, p)
+			}
+		}
+	}
+
+	fingerprints := make([]string, 0, len(c.Diff.Queries))
+	for fp := range c.Diff.Queries {
+		fingerprints = append(fingerprints, fp)
+	}
+	sort.Strings(fingerprints)
+	for _, fp := range fingerprints {
+		q := c.Diff.ForQuery(fp)
+		prefix := "diff.queries." + fp + "."
+		if q.WarningDeltaMs >= q.CriticalDeltaMs {
+			problems = append(problems, Problem{
+				Key:     prefix + "warning_delta_ms",
+				Message: fmt.Sprintf("%g must be below %scritical_delta_ms (%g)", q.WarningDeltaMs, prefix, q.CriticalDeltaMs),
+			})
+		}
+	}
+	if t := c.Analysis.ParallelTime; t != "" && t != "wall" && t != "cpu" {
+		problems = append(problems, Problem{Key: "analysis.parallel_time", Message: fmt.Sprintf("%q must be wall or cpu", t)})
+	}
+	switch by := c.Analysis.HotBy; by {
+	case "", "exclusive", "inclusive", "buffers", "io":
+	default:
+		problems = append(problems, Problem{Key: "analysis.hot_by", Message: fmt.Sprintf("%q must be exclusive, inclusive, buffers or io", by)})
+	}
+	if n := c.Analysis.HotLimit; n < 0 {
+		problems = append(problems, Problem{Key: "analysis.hot_limit", Message: fmt.Sprintf("%d must not be negative", n)})
+	}
+	if f := c.Analysis.HotMinPercent; f < 0 || f > 1 {
+		problems = append(problems, Problem{Key: "analysis.hot_min_percent", Message: fmt.Sprintf("%g must be a fraction between 0 and 1", f)})
+	}
+	return problems
+}
+
+// checkThresholds reports the insight and diff thresholds of c that contradict each other or fall outside
+// their range, with prefix ahead of each key.
+func (c Config) checkThresholds(prefix string) []Problem {
+	var problems []Problem
+	ordered := func(warnKey string, warn float64, critKey string, crit float64) {
+		if warn >= crit {
+			problems = append(problems, Problem{
+				Key:     prefix + warnKey,
+				Message: fmt.Sprintf("%g must be below %s%s (%g)", warn, prefix, critKey, crit),
+			})
+		}
+	}
+	in := c.Insights
+	ordered("insights.hotspot_warning_percent", in.HotspotWarningPercent, "insights.hotspot_critical_percent", in.HotspotCriticalPercent)
+	ordered("insights.buffer_warning_blocks", float64(in.BufferWarningBlocks), "insights.buffer_critical_blocks", float64(in.BufferCriticalBlocks))
+	ordered("insights.nested_loop_warn_loops", in.NestedLoopWarnLoops, "insights.nested_loop_critical_loops", in.NestedLoopCriticalLoops)
+	ordered("insights.per_row_warning_ms", in.PerRowWarningMs, "insights.per_row_critical_ms", in.PerRowCriticalMs)
+	ordered("diff.warning_delta_ms", c.Diff.WarningDeltaMs, "diff.critical_delta_ms", c.Diff.CriticalDeltaMs)
+	if low := in.RowEstimateCriticalLow; low <= 0 || low >= 1 {
+		problems = append(problems, Problem{Key: prefix + "insights.row_estimate_critical_low", Message: fmt.Sprintf("%g must be between 0 and 1", low)})
+	}
+	if high := in.RowEstimateCriticalHigh; high <= 1 {
+		problems = append(problems, Problem{Key: prefix + "insights.row_estimate_critical_high", Message: fmt.Sprintf("%g must be above 1", high)})
+	}
+	if skew := in.CostSkewFactor; skew <= 1 {
+		problems = append(problems, Problem{Key: prefix + "insights.cost_skew_factor", Message: fmt.Sprintf("%g must be above 1", skew)})
+	}
+
+	// Insight percentages and keep ratios are fractions: 0.2 is 20%.
+	v := reflect.ValueOf(in)
+	for i := 0; i < v.NumField(); i++ {
+		name, _, _ := strings.Cut(v.Type().Field(i).Tag.Get("json"), ",")
+		if !strings.HasSuffix(name, "_percent") && !strings.HasSuffix(name, "_keep_ratio") {
+			continue
+		}
+		if f := v.Field(i).Float(); f < 0 || f > 1 {
+			problems = append(problems, Problem{Key: prefix + "insights." + name, Message: fmt.Sprintf("%g must be a fraction between 0 and 1", f)})
+		}
+	}
+	return problems
+}
@@ -4,11 +4,14 @@ import (
 	"encoding/json"
 	"fmt"
 	"math"
+	"path"
 	"sort"
 	"strings"
 
 	"github.com/mickamy/xplain/internal/analyzer"
 	"github.com/mickamy/xplain/internal/config"
+	"github.com/mickamy/xplain/internal/fingerprint"
+	"github.com/mickamy/xplain/internal/model"
 )
 
 // Options configures the diff sensitivity.
@@ -16,6 +19,16 @@ type Options struct {
 	MinSelfTimeDeltaMs float64
 	MinPercentChange   float64
 	MaxItems           int
+	// Serial compares parallel and serial plans on the work each operator did: Gather nodes are folded
+	// into the node below them and partial aggregates into their finalize step, so gaining or losing
+	// workers does not show up as operators appearing and disappearing. Both plans should be analyzed
+	// with analyzer.CPUTime, so a parallel operator counts the work of all its processes.
+	Serial bool
+	// BaseEnvironment and TargetEnvironment are snapshots from xplain env taken with each plan. They add
+	// extension and statistics changes to the environment section, and fill in settings and server
+	// versions the plans did not record.
+	BaseEnvironment   *model.Environment
+	TargetEnvironment *model.Environment
 }
 
 // Report summarises the delta between two plan analyses.
@@ -25,6 +38,27 @@ type Report struct {
 	Improvements []Entry          `json:"improvements"`
 	Insights     []insightMessage `json:"insights"`
 	Options      Options          `json:"-"`
+	// thresholds are the diff settings for the compared statement, including per-query overrides, and
+	// insightThresholds the insight settings it is judged by.
+	thresholds        config.DiffConfig
+	insightThresholds config.InsightConfig
+	// environment lists server version and setting differences between the two captures.
+	environment []string
+	Base        *model.Metadata `json:"base_metadata,omitempty"`
+	Target      *model.Metadata `json:"target_metadata,omitempty"`
+	// Attachments are optional references to the full base and target plans for the Markdown output.
+	Attachments []Attachment `json:"-"`
+	// Tree is the target plan with each node's change since the base; Removed lists the operators only
+	// the base plan ran. The HTML view colours the tree by these deltas.
+	Tree    *NodeDelta `json:"-"`
+	Removed []Entry    `json:"-"`
+}
+
+// Attachment points reviewers at one of the compared plans: a relative link, an embedded plan tree, or both.
+type Attachment struct {
+	Label string
+	Link  string
+	Tree  string
 }
 
 // SummaryDiff covers high-level execution differences.
@@ -73,10 +107,13 @@ func Compare(base, target *analyzer.PlanAnalysis, opts Options) (*Report, error)
 		return nil, fmt.Errorf("diff: target analysis missing")
 	}
 
-	opts = applyDefaults(opts)
+	cfg := config.Active().ForQuery(fingerprint.Of(target), target.Name)
+	thresholds := cfg.Diff
+	opts = applyDefaults(opts, thresholds)
 
-	baseAgg := aggregate(base.Root)
-	targetAgg := aggregate(target.Root)
+	baseNames, targetNames := newRelationNamers(base, target)
+	baseAgg := aggregate(base.Root, baseNames, opts.Serial)
+	targetAgg := aggregate(target.Root, targetNames, opts.Serial)
 
 	signatures := unionKeys(baseAgg, targetAgg)
 	var regressions, improvements []Entry
@@ -116,6 +153,8 @@ func Compare(base, target *analyzer.PlanAnalysis, opts Options) (*Report, error)
 	planPct := percentChange(base.PlanningTimeMs, target.PlanningTimeMs)
 
 	report := &Report{
+		Base:   base.Metadata,
+		Target: target.Metadata,
 		Summary: SummaryDiff{
 			BaseExecutionMs:   base.TotalTimeMs,
 			TargetExecutionMs: target.TotalTimeMs,
@@ -126,10 +165,14 @@ func Compare(base, target *analyzer.PlanAnalysis, opts Options) (*Report, error)
 			DeltaPlanningMs:   planDelta,
 			PercentPlanning:   planPct,
 		},
-		Regressions:  regressions,
-		Improvements: improvements,
-		Options:      opts,
+		Regressions:       regressions,
+		Improvements:      improvements,
+		Options:           opts,
+		thresholds:        thresholds,
+		insightThresholds: cfg.Insights,
+		environment:       environmentChanges(base, target, opts),
 	}
+	report.Tree, report.Removed = buildTree(base, target, baseNames, targetNames, opts)
 	report.Insights = synthesizeInsights(report)
 	return report, nil
 }
@@ -139,6 +182,12 @@ func (r *Report) Markdown() string {
 	var b strings.Builder
 	b.WriteString("# xplain diff\n\n")
 	b.WriteString("## Summary\n")
+	if source := r.Base.Summary(); source != "" {
+		_, _ = fmt.Fprintf(&b, "- Base source: %s\n", source)
+	}
+	if source := r.Target.Summary(); source != "" {
+		_, _ = fmt.Fprintf(&b, "- Target source: %s\n", source)
+	}
 	_, _ = fmt.Fprintf(&b, "- Execution: %.3f ms → %.3f ms (%+.3f ms, %+.1f%%)\n",
 		r.Summary.BaseExecutionMs, r.Summary.TargetExecutionMs,
 		r.Summary.DeltaExecutionMs, r.Summary.PercentExecution)
@@ -188,9 +237,29 @@ func (r *Report) Markdown() string {
 				rowsSummary(entry))
 		}
 	}
+	writeAttachments(&b, r.Attachments)
 	return b.String()
 }
 
+func writeAttachments(b *strings.Builder, attachments []Attachment) {
+	if len(attachments) == 0 {
+		return
+	}
+	b.WriteString("\n### Plans\n")
+	for _, a := range attachments {
+		if a.Link != "" {
+			_, _ = fmt.Fprintf(b, "- %s: [%s](%s)\n", a.Label, path.Base(a.Link), a.Link)
+		}
+	}
+	for _, a := range attachments {
+		if a.Tree == "" {
+			continue
+		}
+		_, _ = fmt.Fprintf(b, "\n<details>\n<summary>%s plan</summary>\n\n```\n%s\n```\n\n</details>\n",
+			a.Label, strings.TrimRight(a.Tree, "\n"))
+	}
+}
+
 // JSON marshals the diff report into an indented JSON document.
 func (r *Report) JSON() ([]byte, error) {
 	if r == nil {
@@ -222,9 +291,17 @@ func synthesizeInsights(r *Report) []insightMessage {
 	}
 	var insights []insightMessage
 	maxItems := 3
-	cfg := config.Active()
-	diffCfg := cfg.Diff
-	insightCfg := cfg.Insights
+	diffCfg := r.thresholds
+	insightCfg := r.insightThresholds
+
+	// A different server or configuration changes plans by itself, so it leads the list.
+	for _, change := range r.environment {
+		text := change
+		if len(r.Regressions) > 0 || len(r.Improvements) > 0 {
+			text += " — a probable cause of the changes below"
+		}
+		insights = append(insights, insightMessage{Severity: "info", Icon: "ℹ️", Message: text})
+	}
 
 	for i, entry := range r.Regressions {
 		if i >= maxItems {
@@ -299,30 +376,44 @@ type aggregated struct {
 	TempBlocks    float64
 }
 
-func aggregate(root *analyzer.NodeStats) map[string]aggregated {
+func aggregate(root *analyzer.NodeStats, names relationNamer, serial bool) map[string]aggregated {
 	result := map[string]aggregated{}
-	var walk func(*analyzer.NodeStats)
-	walk = func(n *analyzer.NodeStats) {
-		sig := signature(n)
+	var walk func(n *analyzer.NodeStats, carriedMs float64)
+	walk = func(n *analyzer.NodeStats, carriedMs float64) {
+		if serial && isGather(n) {
+			// The Gather's own time is the coordination the child needed to run in parallel.
+			for _, child := range n.Children {
+				walk(child, carriedMs+n.ExclusiveTimeMs)
+			}
+			return
+		}
+		sig := signature(n, names)
 		entry := result[sig]
-		entry.SelfMs += n.ExclusiveTimeMs
-		entry.ActualRows += n.ActualTotalRows
-		entry.EstimatedRows += n.EstimatedRows
+		entry.SelfMs += n.ExclusiveTimeMs + carriedMs
+		// A partial aggregate's rows are per-worker groups that the finalize step above combines.
+		if !serial || n.Node.PartialMode != "Partial" {
+			entry.ActualRows += n.ActualTotalRows
+			entry.EstimatedRows += n.EstimatedRows
+		}
 		entry.Buffers += float64(n.Buffers.Total())
 		entry.TempBlocks += float64(n.Buffers.TempRead + n.Buffers.TempWritten)
 		result[sig] = entry
 		for _, child := range n.Children {
-			walk(child)
+			walk(child, 0)
 		}
 	}
-	walk(root)
+	walk(root, 0)
 	return result
 }
 
-func signature(node *analyzer.NodeStats) string {
+func isGather(n *analyzer.NodeStats) bool {
+	return (n.Node.NodeType == "Gather" || n.Node.NodeType == "Gather Merge") && len(n.Children) == 1
+}
+
+func signature(node *analyzer.NodeStats, names relationNamer) string {
 	parts := []string{node.Node.NodeType}
 	if node.Node.RelationName != "" {
-		parts = append(parts, node.Node.RelationName)
+		parts = append(parts, names.label(node.Node))
 	}
 	if node.Node.IndexName != "" {
 		parts = append(parts, node.Node.IndexName)
@@ -333,6 +424,61 @@ func signature(node *analyzer.NodeStats) string {
 	return strings.Join(parts, " · ")
 }
 
+// relationNamer labels relations in signatures. A bare table name is used unless the plans
+// reference it from several schemas or the plan references it under several aliases (self-joins),
+// in which case the schema or alias is added so those references are not merged into one entry.
+type relationNamer struct {
+	schemas map[string]map[string]struct{}
+	aliases map[string]map[string]struct{}
+}
+
+// newRelationNamers returns the namers for base and target. Schemas are collected across both plans,
+// aliases from each plan on its own, so renaming the only alias of a table in the target keeps its
+// nodes matched with the base.
+func newRelationNamers(base, target *analyzer.PlanAnalysis) (relationNamer, relationNamer) {
+	schemas := map[string]map[string]struct{}{}
+	for _, plan := range []*analyzer.PlanAnalysis{base, target} {
+		for _, n := range plan.Nodes() {
+			if n.Node.RelationName != "" {
+				addName(schemas, n.Node.RelationName, n.Node.Schema)
+			}
+		}
+	}
+	namer := func(plan *analyzer.PlanAnalysis) relationNamer {
+		names := relationNamer{schemas: schemas, aliases: map[string]map[string]struct{}{}}
+		// Aliases are grouped per qualified name, which needs every schema seen first.
+		for _, n := range plan.Nodes() {
+			if n.Node.RelationName != "" {
+				addName(names.aliases, names.qualified(n.Node), n.Node.Alias)
+			}
+		}
+		return names
+	}
+	return namer(base), namer(target)
+}
+
+func addName(m map[string]map[string]struct{}, key, value string) {
+	if m[key] == nil {
+		m[key] = map[string]struct{}{}
+	}
+	m[key][value] = struct{}{}
+}
+
+func (r relationNamer) qualified(node *model.PlanNode) string {
+	if len(r.schemas[node.RelationName]) > 1 && node.Schema != "" {
+		return node.Schema + "." + node.RelationName
+	}
+	return node.RelationName
+}
+
+func (r relationNamer) label(node *model.PlanNode) string {
+	name := r.qualified(node)
+	if len(r.aliases[name]) > 1 && node.Alias != "" && node.Alias != node.RelationName {
+		return name + " AS " + node.Alias
+	}
+	return name
+}
+
 func unionKeys(base, target map[string]aggregated) []string {
 	seen := map[string]struct{}{}
 	for k := range base {
@@ -404,8 +550,7 @@ func percentChange(base, target float64) float64 {
 	return (target - base) / base * 100
 }
 
-func applyDefaults(opts Options) Options {
-	cfg := config.Active().Diff
+func applyDefaults(opts Options, cfg config.DiffConfig) Options {
 	if opts.MinSelfTimeDeltaMs <= 0 {
 		opts.MinSelfTimeDeltaMs = cfg.MinSelfDeltaMs
 	}
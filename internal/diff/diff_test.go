@@ -1,9 +1,14 @@
 package diff_test
 
 import (
+	"slices"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/mickamy/xplain/internal/analyzer"
 	"github.com/mickamy/xplain/internal/diff"
+	"github.com/mickamy/xplain/internal/model"
 	"github.com/mickamy/xplain/test"
 )
 
@@ -27,3 +32,186 @@ func TestCompareSamplesAndJSON(t *testing.T) {
 		t.Fatalf("expected json payload")
 	}
 }
+
+func TestMarkdownAttachments(t *testing.T) {
+	base := test.LoadSampleAnalysis(t, "nloop_base.json")
+	target := test.LoadSampleAnalysis(t, "nloop_index.json")
+
+	report, err := diff.Compare(base, target, diff.Options{})
+	if err != nil {
+		t.Fatalf("compare: %v", err)
+	}
+	report.Attachments = []diff.Attachment{
+		{Label: "Base", Link: "plans/nloop_base.json", Tree: "Nested Loop\n"},
+		{Label: "Target", Link: "plans/nloop_index.json"},
+	}
+
+	md := report.Markdown()
+	for _, want := range []string{
+		"- Base: [nloop_base.json](plans/nloop_base.json)",
+		"- Target: [nloop_index.json](plans/nloop_index.json)",
+		"<summary>Base plan</summary>\n\n```\nNested Loop\n```",
+	} {
+		if !strings.Contains(md, want) {
+			t.Fatalf("expected %q in markdown:\n%s", want, md)
+		}
+	}
+	if strings.Contains(md, "Target plan</summary>") {
+		t.Fatalf("unexpected details block for attachment without tree")
+	}
+}
+
+func TestCompareSeparatesSelfJoinAliases(t *testing.T) {
+	base := test.LoadSampleAnalysis(t, "self_join.json")
+	target := test.LoadSampleAnalysis(t, "self_join.json")
+	for _, n := range target.Nodes() {
+		if n.Node.Alias == "m" {
+			n.ExclusiveTimeMs += 50
+		}
+	}
+
+	report, err := diff.Compare(base, target, diff.Options{})
+	if err != nil {
+		t.Fatalf("compare: %v", err)
+	}
+	if len(report.Regressions) != 1 || report.Regressions[0].Signature != "Seq Scan · employees AS m" {
+		t.Fatalf("expected only the manager scan to regress, got %+v", report.Regressions)
+	}
+
+	// Renaming the only alias of a table keeps its nodes matched.
+	renamed := test.LoadSampleAnalysis(t, "memory_heavy.json")
+	for _, n := range renamed.Nodes() {
+		if n.Node.Alias == "o" {
+			n.Node.Alias = "ord"
+		}
+	}
+	aliased, err := diff.Compare(test.LoadSampleAnalysis(t, "memory_heavy.json"), renamed, diff.Options{})
+	if err != nil {
+		t.Fatalf("compare: %v", err)
+	}
+	if len(aliased.Removed) != 0 {
+		t.Fatalf("expected the renamed alias to keep matching, got removed %+v", aliased.Removed)
+	}
+
+	// The outer reference keeps the bare name because its alias is the table name itself.
+	nested, err := diff.Compare(test.LoadSampleAnalysis(t, "nloop_base.json"), test.LoadSampleAnalysis(t, "nloop_index.json"), diff.Options{MinSelfTimeDeltaMs: 0.5, MinPercentChange: 1})
+	if err != nil {
+		t.Fatalf("compare: %v", err)
+	}
+	var signatures []string
+	for _, e := range nested.Improvements {
+		signatures = append(signatures, e.Signature)
+	}
+	if !slices.Contains(signatures, "Seq Scan · pgbench_accounts AS inner_accounts") || !slices.Contains(signatures, "Seq Scan · pgbench_accounts") {
+		t.Fatalf("expected both pgbench_accounts references listed separately, got %v", signatures)
+	}
+}
+
+func TestCompareCallsOutEnvironment(t *testing.T) {
+	base := test.LoadSampleAnalysis(t, "nloop_base.json")
+	target := test.LoadSampleAnalysis(t, "nloop_index.json")
+	base.Metadata = &model.Metadata{ServerVersion: "14.11", Settings: map[string]string{"work_mem": "4MB", "jit": "on"}}
+	target.Metadata = &model.Metadata{ServerVersion: "16.2 (Debian 16.2-1.pgdg120+2)", Settings: map[string]string{"work_mem": "64MB", "jit": "on"}}
+	target.Settings = map[string]string{"random_page_cost": "1.1"}
+
+	report, err := diff.Compare(base, target, diff.Options{})
+	if err != nil {
+		t.Fatalf("compare: %v", err)
+	}
+	md := report.Markdown()
+	for _, want := range []string{
+		"- ℹ️ Target ran on PG16 vs base PG14 — a probable cause of the changes below\n",
+		"- ℹ️ work_mem differs: 4MB → 64MB — a probable cause of the changes below\n",
+	} {
+		if !strings.Contains(md, want) {
+			t.Fatalf("expected %q in markdown:\n%s", want, md)
+		}
+	}
+	// jit is unchanged and random_page_cost is unknown for the base.
+	if strings.Contains(md, "jit differs") || strings.Contains(md, "random_page_cost") {
+		t.Fatalf("unexpected setting call-out:\n%s", md)
+	}
+}
+
+func TestCompareUsesEnvironmentSnapshots(t *testing.T) {
+	base := test.LoadSampleAnalysis(t, "nloop_base.json")
+	target := test.LoadSampleAnalysis(t, "nloop_index.json")
+	analyzed := time.Date(2026, 10, 1, 3, 0, 0, 0, time.UTC)
+	baseEnv := &model.Environment{
+		ServerVersion: "16.4",
+		Settings:      map[string]string{"random_page_cost": "4"},
+		Extensions:    map[string]string{"pg_stat_statements": "1.10", "pg_trgm": "1.6"},
+		Tables:        []model.TableStats{{Schema: "public", Name: "pgbench_accounts", LastAnalyzed: analyzed, LiveRows: 100000}},
+	}
+	targetEnv := &model.Environment{
+		ServerVersion: "16.4",
+		Settings:      map[string]string{"random_page_cost": "1.1"},
+		Extensions:    map[string]string{"pg_stat_statements": "1.11", "pg_hint_plan": "1.6.1"},
+		Tables: []model.TableStats{
+			{Schema: "public", Name: "pgbench_accounts", LastAnalyzed: analyzed.Add(48 * time.Hour), LiveRows: 100000, ModifiedSinceAnalyze: 25000},
+		},
+	}
+
+	report, err := diff.Compare(base, target, diff.Options{BaseEnvironment: baseEnv, TargetEnvironment: targetEnv})
+	if err != nil {
+		t.Fatalf("compare: %v", err)
+	}
+	md := report.Markdown()
+	for _, want := range []string{
+		"random_page_cost differs: 4 → 1.1",
+		"Extension pg_hint_plan 1.6.1 is installed on target only",
+		"Extension pg_stat_statements differs: 1.10 → 1.11",
+		"Extension pg_trgm 1.6 is installed on base only",
+		"pgbench_accounts was re-analyzed between the captures (last ANALYZE 2026-10-01 03:00 UTC → 2026-10-03 03:00 UTC)",
+		"pgbench_accounts had 25% of its rows modified since its last ANALYZE on target",
+	} {
+		if !strings.Contains(md, want) {
+			t.Fatalf("expected %q in markdown:\n%s", want, md)
+		}
+	}
+	if strings.Contains(md, "Target ran on") {
+		t.Fatalf("unexpected version call-out:\n%s", md)
+	}
+}
+
+func TestCompareSerialNormalizesParallelPlans(t *testing.T) {
+	scan := func(perLoopMs, loops float64) *model.PlanNode {
+		return &model.PlanNode{NodeType: "Seq Scan", RelationName: "orders", ActualTotalTime: perLoopMs, ActualRows: 1000 / loops, ActualLoops: loops, PlanRows: 1000 / loops}
+	}
+	// Serial diffs compare the work of every process, so both plans are analyzed with CPU time.
+	cpu := analyzer.Options{Time: analyzer.CPUTime}
+	base, err := analyzer.AnalyzeWith(&model.Explain{Plan: &model.PlanNode{
+		NodeType: "Sort", ActualTotalTime: 200, ActualRows: 1000, ActualLoops: 1, PlanRows: 1000,
+		Children: []*model.PlanNode{scan(100, 1)},
+	}}, cpu)
+	if err != nil {
+		t.Fatalf("analyze base: %v", err)
+	}
+	// The same work split over the leader and one worker, plus 20 ms merging their output.
+	target, err := analyzer.AnalyzeWith(&model.Explain{Plan: &model.PlanNode{
+		NodeType: "Gather Merge", ActualTotalTime: 200, ActualRows: 1000, ActualLoops: 1, PlanRows: 1000, WorkersLaunched: 1,
+		Children: []*model.PlanNode{{
+			NodeType: "Sort", ActualTotalTime: 90, ActualRows: 500, ActualLoops: 2, PlanRows: 500,
+			Children: []*model.PlanNode{scan(50, 2)},
+		}},
+	}}, cpu)
+	if err != nil {
+		t.Fatalf("analyze target: %v", err)
+	}
+
+	plain, err := diff.Compare(base, target, diff.Options{})
+	if err != nil {
+		t.Fatalf("compare: %v", err)
+	}
+	if len(plain.Regressions) != 1 || plain.Regressions[0].Signature != "Gather Merge" || len(plain.Improvements) != 1 {
+		t.Fatalf("expected the Gather Merge to show up without normalization, got %+v / %+v", plain.Regressions, plain.Improvements)
+	}
+
+	serial, err := diff.Compare(base, target, diff.Options{Serial: true})
+	if err != nil {
+		t.Fatalf("compare serial: %v", err)
+	}
+	if len(serial.Regressions) != 0 || len(serial.Improvements) != 0 {
+		t.Fatalf("expected no changes after normalization, got %+v / %+v", serial.Regressions, serial.Improvements)
+	}
+}
@@ -0,0 +1,161 @@
+package diff
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mickamy/xplain/internal/analyzer"
+	"github.com/mickamy/xplain/internal/model"
+)
+
+// staleModifiedRatio is the share of a table's rows modified since its last ANALYZE from which the
+// statistics count as stale; autovacuum re-analyzes at the same 10% by default.
+const staleModifiedRatio = 0.1
+
+// environmentChanges describes how the servers the two plans ran on differed: the PostgreSQL major version
+// and planner settings recorded in the envelope, reported by EXPLAIN (SETTINGS) or snapshotted by xplain
+// env, plus extension versions and statistics of the target's tables when snapshots were supplied.
+// Settings only known for one side are skipped, since their value on the other side is unknown.
+func environmentChanges(base, target *analyzer.PlanAnalysis, opts Options) []string {
+	var changes []string
+	if b, t := majorVersion(base, opts.BaseEnvironment), majorVersion(target, opts.TargetEnvironment); b != "" && t != "" && b != t {
+		changes = append(changes, fmt.Sprintf("Target ran on PG%s vs base PG%s", t, b))
+	}
+	baseSettings, targetSettings := settings(base, opts.BaseEnvironment), settings(target, opts.TargetEnvironment)
+	for _, name := range sortedKeys(targetSettings) {
+		before, ok := baseSettings[name]
+		if after := targetSettings[name]; ok && before != after {
+			changes = append(changes, fmt.Sprintf("%s differs: %s → %s", name, before, after))
+		}
+	}
+	changes = append(changes, extensionChanges(opts.BaseEnvironment, opts.TargetEnvironment)...)
+	changes = append(changes, statisticsChanges(target, opts.BaseEnvironment, opts.TargetEnvironment)...)
+	return changes
+}
+
+// extensionChanges lists extensions installed, removed or upgraded between two snapshots.
+func extensionChanges(base, target *model.Environment) []string {
+	if base == nil || target == nil {
+		return nil
+	}
+	var changes []string
+	for _, name := range sortedKeys(target.Extensions) {
+		before, ok := base.Extensions[name]
+		switch after := target.Extensions[name]; {
+		case !ok:
+			changes = append(changes, fmt.Sprintf("Extension %s %s is installed on target only", name, after))
+		case before != after:
+			changes = append(changes, fmt.Sprintf("Extension %s differs: %s → %s", name, before, after))
+		}
+	}
+	for _, name := range sortedKeys(base.Extensions) {
+		if _, ok := target.Extensions[name]; !ok {
+			changes = append(changes, fmt.Sprintf("Extension %s %s is installed on base only", name, base.Extensions[name]))
+		}
+	}
+	return changes
+}
+
+// statisticsChanges reports, for each table the target plan reads, statistics that were refreshed between
+// the two snapshots and statistics that were stale or missing when the target was captured.
+func statisticsChanges(target *analyzer.PlanAnalysis, baseEnv, targetEnv *model.Environment) []string {
+	if targetEnv == nil {
+		return nil
+	}
+	var changes []string
+	for _, table := range scannedTables(target) {
+		t := targetEnv.Table(table.schema, table.name)
+		if t == nil {
+			continue
+		}
+		if b := baseEnv.Table(table.schema, table.name); b != nil && !t.LastAnalyzed.IsZero() && !b.LastAnalyzed.Equal(t.LastAnalyzed) {
+			changes = append(changes, fmt.Sprintf("%s was re-analyzed between the captures (last ANALYZE %s → %s)",
+				t.Name, formatAnalyzed(b.LastAnalyzed), formatAnalyzed(t.LastAnalyzed)))
+		}
+		switch {
+		case t.LastAnalyzed.IsZero():
+			changes = append(changes, fmt.Sprintf("%s had never been analyzed on target, so the planner guessed its statistics (run ANALYZE %s)", t.Name, t.Name))
+		case t.LiveRows > 0 && float64(t.ModifiedSinceAnalyze) >= float64(t.LiveRows)*staleModifiedRatio:
+			changes = append(changes, fmt.Sprintf("%s had %.0f%% of its rows modified since its last ANALYZE on target, so its statistics were stale (run ANALYZE %s)",
+				t.Name, float64(t.ModifiedSinceAnalyze)/float64(t.LiveRows)*100, t.Name))
+		}
+	}
+	return changes
+}
+
+type tableRef struct {
+	schema, name string
+}
+
+func scannedTables(analysis *analyzer.PlanAnalysis) []tableRef {
+	seen := map[tableRef]bool{}
+	var tables []tableRef
+	for _, n := range analysis.Nodes() {
+		ref := tableRef{schema: n.Node.Schema, name: n.Node.RelationName}
+		if ref.name == "" || seen[ref] {
+			continue
+		}
+		seen[ref] = true
+		tables = append(tables, ref)
+	}
+	return tables
+}
+
+func formatAnalyzed(t time.Time) string {
+	if t.IsZero() {
+		return "never"
+	}
+	return t.UTC().Format("2006-01-02 15:04 UTC")
+}
+
+// settings merges EXPLAIN (SETTINGS) output, the environment snapshot and the settings the envelope
+// recorded, later sources winning: the envelope was captured with the plan itself.
+func settings(analysis *analyzer.PlanAnalysis, env *model.Environment) map[string]string {
+	out := map[string]string{}
+	for name, value := range analysis.Settings {
+		out[name] = value
+	}
+	if env != nil {
+		for name, value := range env.Settings {
+			out[name] = value
+		}
+	}
+	if analysis.Metadata != nil {
+		for name, value := range analysis.Metadata.Settings {
+			out[name] = value
+		}
+	}
+	return out
+}
+
+// majorVersion extracts the major version from server_version, e.g. "16" from "16.2 (Debian 16.2-1)" and
+// "9.6" from "9.6.24". The envelope's version wins over the snapshot's.
+func majorVersion(analysis *analyzer.PlanAnalysis, env *model.Environment) string {
+	var version string
+	switch {
+	case analysis.Metadata != nil && analysis.Metadata.ServerVersion != "":
+		version = analysis.Metadata.ServerVersion
+	case env != nil:
+		version = env.ServerVersion
+	}
+	version, _, _ = strings.Cut(strings.TrimSpace(version), " ")
+	if version == "" {
+		return ""
+	}
+	parts := strings.Split(version, ".")
+	if parts[0] == "9" && len(parts) > 1 {
+		return parts[0] + "." + parts[1]
+	}
+	return parts[0]
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,83 @@
+package diff
+
+import (
+	"math"
+	"sort"
+
+	"github.com/mickamy/xplain/internal/analyzer"
+)
+
+// NodeDelta is a node of the target plan with the change in self time attributed to it.
+type NodeDelta struct {
+	Node      *analyzer.NodeStats
+	Signature string
+	// BaseSelfMs is the base self time of the node's signature, split across the target nodes that share it
+	// in proportion to their own self time, so the deltas of a signature add up to its Entry's.
+	BaseSelfMs  float64
+	DeltaSelfMs float64
+	// New is set when no base node has the signature.
+	New bool
+	// Significant is set when the delta passes the report's regression or improvement thresholds.
+	Significant bool
+	Children    []*NodeDelta
+}
+
+// PercentChange is the node's change relative to its base self time.
+func (d *NodeDelta) PercentChange() float64 {
+	return percentChange(d.BaseSelfMs, d.BaseSelfMs+d.DeltaSelfMs)
+}
+
+// Severity is the node's delta as a share of the slower plan's execution time, in [0, 1], so a change
+// stands out by how much it moved the statement rather than by how hot the node is.
+func (r *Report) Severity(d *NodeDelta) float64 {
+	total := math.Max(r.Summary.BaseExecutionMs, r.Summary.TargetExecutionMs)
+	if total <= 0 || !d.Significant {
+		return 0
+	}
+	return math.Min(1, math.Abs(d.DeltaSelfMs)/total)
+}
+
+// buildTree pairs every target node with the base self time of its signature. Gather nodes folded away
+// by Options.Serial have no signature of their own and keep a zero delta.
+func buildTree(base, target *analyzer.PlanAnalysis, baseNames, names relationNamer, opts Options) (*NodeDelta, []Entry) {
+	baseAgg := aggregate(base.Root, baseNames, opts.Serial)
+	targetAgg := aggregate(target.Root, names, opts.Serial)
+	counts := map[string]int{}
+	for _, n := range target.Nodes() {
+		counts[signature(n, names)]++
+	}
+
+	var walk func(n *analyzer.NodeStats) *NodeDelta
+	walk = func(n *analyzer.NodeStats) *NodeDelta {
+		sig := signature(n, names)
+		d := &NodeDelta{Node: n, Signature: sig}
+		if theirs, ok := targetAgg[sig]; ok && !(opts.Serial && isGather(n)) {
+			share := 1 / float64(counts[sig])
+			if theirs.SelfMs > 0 {
+				share = n.ExclusiveTimeMs / theirs.SelfMs
+			}
+			before, seen := baseAgg[sig]
+			d.New = !seen
+			d.BaseSelfMs = before.SelfMs * share
+			d.DeltaSelfMs = n.ExclusiveTimeMs - d.BaseSelfMs
+			entry := buildEntry(sig, before, theirs)
+			d.Significant = passesRegression(entry, opts) || passesImprovement(entry, opts)
+		}
+		for _, child := range n.Children {
+			d.Children = append(d.Children, walk(child))
+		}
+		return d
+	}
+	root := walk(target.Root)
+
+	var removed []Entry
+	for sig, before := range baseAgg {
+		if _, ok := targetAgg[sig]; !ok {
+			removed = append(removed, buildEntry(sig, before, aggregated{}))
+		}
+	}
+	sort.Slice(removed, func(i, j int) bool {
+		return removed[i].BaseSelfMs > removed[j].BaseSelfMs
+	})
+	return root, removed
+}
@@ -0,0 +1,82 @@
+package fingerprint
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"regexp"
+	"strings"
+
+	"github.com/mickamy/xplain/internal/analyzer"
+	"github.com/mickamy/xplain/internal/model"
+)
+
+var (
+	lineComment  = regexp.MustCompile(`--[^\n]*`)
+	blockComment = regexp.MustCompile(`(?s)/\*.*?\*/`)
+	stringLit    = regexp.MustCompile(`'(?:[^']|'')*'`)
+	numberLit    = regexp.MustCompile(`\b\d+(?:\.\d+)?\b`)
+	paramRef     = regexp.MustCompile(`\$\d+`)
+	valueList    = regexp.MustCompile(`\(\s*\?(?:\s*,\s*\?)*\s*\)`)
+	whitespace   = regexp.MustCompile(`\s+`)
+)
+
+// Of returns the fingerprint of the analyzed statement: the normalized query text when it is
+// known, otherwise the shape of the plan tree.
+func Of(analysis *analyzer.PlanAnalysis) string {
+	if analysis == nil {
+		return ""
+	}
+	if strings.TrimSpace(analysis.QueryText) != "" {
+		return Query(analysis.QueryText)
+	}
+	if analysis.Root != nil {
+		return Plan(analysis.Root.Node)
+	}
+	return ""
+}
+
+// Query hashes a statement after stripping comments and literals, so executions that differ only
+// in parameter values share a fingerprint.
+func Query(sql string) string {
+	return digest(Normalize(sql))
+}
+
+// Normalize reduces a statement to its structure: comments removed, literals and parameters
+// replaced by ?, IN lists collapsed, keywords lower-cased and whitespace squeezed.
+func Normalize(sql string) string {
+	text := blockComment.ReplaceAllString(sql, " ")
+	text = lineComment.ReplaceAllString(text, " ")
+	text = stringLit.ReplaceAllString(text, "?")
+	text = paramRef.ReplaceAllString(text, "?")
+	text = numberLit.ReplaceAllString(text, "?")
+	text = valueList.ReplaceAllString(text, "(?)")
+	text = whitespace.ReplaceAllString(strings.ToLower(text), " ")
+	return strings.TrimSuffix(strings.TrimSpace(text), ";")
+}
+
+// Plan hashes the node types and relations of a plan tree, ignoring costs and timings.
+func Plan(root *model.PlanNode) string {
+	var b strings.Builder
+	writeShape(&b, root)
+	return digest(b.String())
+}
+
+func writeShape(b *strings.Builder, node *model.PlanNode) {
+	if node == nil {
+		return
+	}
+	b.WriteString(node.NodeType)
+	if node.RelationName != "" {
+		b.WriteString(" " + node.RelationName)
+	}
+	b.WriteString("(")
+	for _, child := range node.Children {
+		writeShape(b, child)
+	}
+	b.WriteString(")")
+}
+
+func digest(text string) string {
+	sum := sha256.Sum256([]byte(text))
+	return hex.EncodeToString(sum[:8])
+}
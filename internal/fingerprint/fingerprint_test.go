@@ -0,0 +1,32 @@
+package fingerprint_test
+
+import (
+	"testing"
+
+	"github.com/mickamy/xplain/internal/fingerprint"
+	"github.com/mickamy/xplain/test"
+)
+
+func TestQueryIgnoresLiteralsAndFormatting(t *testing.T) {
+	a := fingerprint.Query("SELECT * FROM orders WHERE id IN (1, 2, 3) AND status = 'paid'; -- hot path")
+	b := fingerprint.Query("select *\n  from orders\n where id in ($1) and status = 'open'")
+	if a != b {
+		t.Fatalf("expected equal fingerprints, got %s and %s", a, b)
+	}
+	if c := fingerprint.Query("SELECT * FROM customers WHERE id = 1"); c == a {
+		t.Fatalf("expected different statements to have different fingerprints")
+	}
+}
+
+func TestOfFallsBackToPlanShape(t *testing.T) {
+	analysis := test.LoadSampleAnalysis(t, "pgbench_hot.json")
+	analysis.QueryText = ""
+
+	got := fingerprint.Of(analysis)
+	if len(got) != 16 {
+		t.Fatalf("expected 16 hex characters, got %q", got)
+	}
+	if got != fingerprint.Plan(analysis.Root.Node) {
+		t.Fatalf("expected plan-shape fingerprint")
+	}
+}
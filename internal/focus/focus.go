@@ -0,0 +1,93 @@
+package focus
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/mickamy/xplain/internal/analyzer"
+	"github.com/mickamy/xplain/internal/assert"
+	"github.com/mickamy/xplain/internal/model"
+)
+
+var nodePath = regexp.MustCompile(`^\d+(\.\d+)*$`)
+
+// Selector picks one node of a plan, either by its path such as "0.1.2" or by attributes.
+type Selector struct {
+	// Path is the node ID, the child indexes from the root joined by dots.
+	Path    string
+	Pattern assert.Pattern
+	spec    string
+}
+
+// Parse reads a selector: a node path such as "0.1.2", or comma-separated key=value pairs with the keys
+// type, relation and index, e.g. "relation=orders,type=Seq Scan".
+func Parse(spec string) (Selector, error) {
+	spec = strings.TrimSpace(spec)
+	s := Selector{spec: spec}
+	if nodePath.MatchString(spec) {
+		s.Path = spec
+		return s, nil
+	}
+	for _, part := range strings.Split(spec, ",") {
+		key, value, ok := strings.Cut(part, "=")
+		key, value = strings.ToLower(strings.TrimSpace(key)), strings.TrimSpace(value)
+		if !ok || value == "" {
+			return Selector{}, fmt.Errorf("focus: %q: expected a node path like 0.1.2 or key=value pairs", spec)
+		}
+		switch key {
+		case "type", "node":
+			s.Pattern.Node = value
+		case "relation", "table":
+			s.Pattern.Relation = value
+		case "index":
+			s.Pattern.Index = value
+		default:
+			return Selector{}, fmt.Errorf("focus: %q: unknown key %q (expected type, relation or index)", spec, key)
+		}
+	}
+	return s, nil
+}
+
+func (s Selector) String() string {
+	return s.spec
+}
+
+// Matches reports whether the selector picks the node.
+func (s Selector) Matches(n *analyzer.NodeStats) bool {
+	if s.Path != "" {
+		return n.Node.ID == s.Path
+	}
+	return s.Pattern.Matches(n)
+}
+
+// Find returns the first node, in depth-first order, that the selector picks.
+func (s Selector) Find(analysis *analyzer.PlanAnalysis) (*analyzer.NodeStats, error) {
+	for _, n := range analysis.Nodes() {
+		if s.Matches(n) {
+			return n, nil
+		}
+	}
+	return nil, fmt.Errorf("focus: no node matches %q", s.spec)
+}
+
+// Apply re-analyzes the subtree under the node Find picks, so times and shares are relative to that
+// fragment. Statement-level data such as triggers and JIT is left out; the query text and capture
+// metadata are kept.
+func (s Selector) Apply(analysis *analyzer.PlanAnalysis) (*analyzer.PlanAnalysis, error) {
+	n, err := s.Find(analysis)
+	if err != nil {
+		return nil, err
+	}
+	focused, err := analyzer.AnalyzeWith(&model.Explain{
+		Plan:      n.Node,
+		QueryText: analysis.QueryText,
+		Metadata:  analysis.Metadata,
+	}, analyzer.Options{Time: analysis.Time, Processes: n.Processes, Hot: analysis.HotSelection})
+	if err != nil {
+		return nil, err
+	}
+	focused.Acknowledgements = analysis.Acknowledgements
+	focused.Catalog = analysis.Catalog
+	return focused, nil
+}
@@ -0,0 +1,41 @@
+package focus_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mickamy/xplain/internal/focus"
+	"github.com/mickamy/xplain/test"
+)
+
+func TestApply(t *testing.T) {
+	analysis := test.LoadSampleAnalysis(t, "pgbench_hot.json")
+
+	for _, spec := range []string{"0.0.0", "type=Sort", "Relation=pgbench_accounts, type=seq scan"} {
+		selector, err := focus.Parse(spec)
+		if err != nil {
+			t.Fatalf("parse %q: %v", spec, err)
+		}
+		focused, err := selector.Apply(analysis)
+		if err != nil {
+			t.Fatalf("apply %q: %v", spec, err)
+		}
+		if !selector.Matches(focused.Root) || focused.Root.Depth != 0 {
+			t.Fatalf("%q: expected the matched node as root, got %s", spec, focused.Root.Node.NodeType)
+		}
+		if focused.NodeCount >= analysis.NodeCount || focused.Root.PercentInclusive != 1 {
+			t.Fatalf("%q: expected shares relative to the subtree, got %d nodes and %.2f", spec, focused.NodeCount, focused.Root.PercentInclusive)
+		}
+	}
+
+	selector, err := focus.Parse("relation=missing")
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if _, err := selector.Apply(analysis); err == nil || !strings.Contains(err.Error(), "no node matches") {
+		t.Fatalf("expected no match, got %v", err)
+	}
+	if _, err := focus.Parse("owner=me"); err == nil {
+		t.Fatal("expected an unknown key to be rejected")
+	}
+}
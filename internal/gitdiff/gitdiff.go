@@ -0,0 +1,53 @@
+package gitdiff
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// ChangedFiles lists files under dir that were added, copied, modified or renamed between the merge
+// base of since and until (HEAD when empty), filtered to the given extension (e.g. ".sql"). Deleted
+// files are omitted because there is nothing left to analyze. Paths are relative to dir.
+func ChangedFiles(ctx context.Context, dir, since, until, ext string) ([]string, error) {
+	if strings.TrimSpace(since) == "" {
+		return nil, fmt.Errorf("gitdiff: empty ref")
+	}
+	if strings.TrimSpace(until) == "" {
+		until = "HEAD"
+	}
+	for _, ref := range []string{since, until} {
+		if _, err := git(ctx, dir, "rev-parse", "--verify", "--quiet", ref+"^{commit}"); err != nil {
+			return nil, fmt.Errorf("gitdiff: unknown ref %q (shallow clones need the base ref fetched, e.g. fetch-depth: 0)", ref)
+		}
+	}
+	out, err := git(ctx, dir, "diff", "--name-only", "--relative", "--diff-filter=ACMR", since+"..."+until)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []string
+	for line := range strings.SplitSeq(string(out), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || (ext != "" && !strings.EqualFold(filepath.Ext(line), ext)) {
+			continue
+		}
+		files = append(files, filepath.FromSlash(line))
+	}
+	return files, nil
+}
+
+func git(ctx context.Context, dir string, args ...string) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = dir
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("gitdiff: git %s: %w: %s", strings.Join(args, " "), err, strings.TrimSpace(stderr.String()))
+	}
+	return out, nil
+}
@@ -0,0 +1,79 @@
+package gitdiff_test
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/mickamy/xplain/internal/gitdiff"
+)
+
+func TestChangedFiles(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+	dir := t.TempDir()
+	git := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		cmd.Env = append(os.Environ(), "GIT_AUTHOR_NAME=t", "GIT_AUTHOR_EMAIL=t@example.com", "GIT_COMMITTER_NAME=t", "GIT_COMMITTER_EMAIL=t@example.com")
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+	write := func(name, content string) {
+		t.Helper()
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	git("init", "-q", "-b", "main")
+	write("queries/a.sql", "SELECT 1;")
+	write("queries/b.sql", "SELECT 2;")
+	git("add", ".")
+	git("commit", "-q", "-m", "base")
+	git("checkout", "-q", "-b", "feature")
+	write("queries/b.sql", "SELECT 3;")
+	write("queries/c.sql", "SELECT 4;")
+	write("README.md", "docs")
+	git("add", ".")
+	git("commit", "-q", "-m", "change")
+
+	git("tag", "first")
+	write("queries/d.sql", "SELECT 5;")
+	git("rm", "-q", "queries/a.sql")
+	git("add", ".")
+	git("commit", "-q", "-m", "more")
+
+	ctx := context.Background()
+	got, err := gitdiff.ChangedFiles(ctx, dir, "main", "first", ".sql")
+	if err != nil {
+		t.Fatalf("changed files: %v", err)
+	}
+	want := []string{filepath.Join("queries", "b.sql"), filepath.Join("queries", "c.sql")}
+	if !slices.Equal(got, want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+
+	got, err = gitdiff.ChangedFiles(ctx, dir, "main", "", ".sql")
+	if err != nil {
+		t.Fatalf("changed files: %v", err)
+	}
+	want = append(want, filepath.Join("queries", "d.sql"))
+	if !slices.Equal(got, want) {
+		t.Fatalf("expected deleted files to be skipped, got %v", got)
+	}
+
+	if _, err := gitdiff.ChangedFiles(ctx, dir, "origin/missing", "", ".sql"); err == nil {
+		t.Fatalf("expected unknown ref to fail")
+	}
+}
@@ -0,0 +1,123 @@
+package history
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/mickamy/xplain/internal/analyzer"
+	"github.com/mickamy/xplain/internal/fingerprint"
+)
+
+// FileName is the history log kept at the root of the output directory.
+const FileName = "history.jsonl"
+
+// Entry records one saved execution of a statement.
+type Entry struct {
+	Fingerprint  string    `json:"fingerprint"`
+	CapturedAt   time.Time `json:"captured_at"`
+	ExecutionMs  float64   `json:"execution_ms"`
+	PlanningMs   float64   `json:"planning_ms"`
+	TotalBuffers int64     `json:"total_buffers"`
+	Database     string    `json:"database,omitempty"`
+	Query        string    `json:"query,omitempty"`
+	// Plan and Report are slash-separated paths relative to the output directory, so a history log
+	// written on Windows reads the same elsewhere.
+	Plan   string `json:"plan"`
+	Report string `json:"report,omitempty"`
+	// Name is the registered query name the statement was run under, if any.
+	Name string `json:"name,omitempty"`
+}
+
+// PlanPath resolves the entry's plan file inside the output directory dir.
+func (e Entry) PlanPath(dir string) string {
+	return filepath.Join(dir, filepath.FromSlash(e.Plan))
+}
+
+// ReportPath resolves the entry's HTML report inside the output directory dir, or returns "" when none was saved.
+func (e Entry) ReportPath(dir string) string {
+	if e.Report == "" {
+		return ""
+	}
+	return filepath.Join(dir, filepath.FromSlash(e.Report))
+}
+
+// FromAnalysis builds an entry describing the analyzed plan; file paths are filled in by the caller.
+func FromAnalysis(analysis *analyzer.PlanAnalysis) Entry {
+	entry := Entry{
+		Fingerprint:  fingerprint.Of(analysis),
+		CapturedAt:   time.Now().UTC(),
+		ExecutionMs:  analysis.TotalTimeMs,
+		PlanningMs:   analysis.PlanningTimeMs,
+		TotalBuffers: analysis.TotalBuffers,
+	}
+	if analysis.QueryText != "" {
+		entry.Query = fingerprint.Normalize(analysis.QueryText)
+	}
+	if meta := analysis.Metadata; meta != nil {
+		entry.Database = meta.Database
+		if !meta.CapturedAt.IsZero() {
+			entry.CapturedAt = meta.CapturedAt.UTC()
+		}
+	}
+	return entry
+}
+
+// Append adds an entry to the history log in dir, creating the directory when needed.
+func Append(dir string, entry Entry) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("history: create dir: %w", err)
+	}
+	file, err := os.OpenFile(filepath.Join(dir, FileName), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("history: open: %w", err)
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("history: encode: %w", err)
+	}
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("history: write: %w", err)
+	}
+	return nil
+}
+
+// Load reads every entry from the history log in dir. A missing log yields no entries.
+func Load(dir string) ([]Entry, error) {
+	file, err := os.Open(filepath.Join(dir, FileName))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("history: open: %w", err)
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+
+	var entries []Entry
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var entry Entry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, fmt.Errorf("history: line %d: %w", line, err)
+		}
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("history: read: %w", err)
+	}
+	return entries, nil
+}
@@ -0,0 +1,108 @@
+package history_test
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mickamy/xplain/internal/history"
+	"github.com/mickamy/xplain/test"
+)
+
+func TestAppendAndLoad(t *testing.T) {
+	dir := t.TempDir()
+	analysis := test.LoadSampleAnalysis(t, "envelope_orders.json")
+
+	entry := history.FromAnalysis(analysis)
+	entry.Plan = "plan.json"
+	if entry.Database != "shop" || entry.CapturedAt.Year() != 2026 {
+		t.Fatalf("expected envelope metadata in entry, got %+v", entry)
+	}
+	for range 2 {
+		if err := history.Append(dir, entry); err != nil {
+			t.Fatalf("append: %v", err)
+		}
+	}
+
+	entries, err := history.Load(dir)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if len(entries) != 2 || entries[1].Fingerprint != entry.Fingerprint || entries[1].Plan != "plan.json" {
+		t.Fatalf("unexpected entries %+v", entries)
+	}
+}
+
+func TestLoadMissing(t *testing.T) {
+	entries, err := history.Load(t.TempDir())
+	if err != nil || entries != nil {
+		t.Fatalf("expected empty history, got %v, %v", entries, err)
+	}
+}
+
+func TestEntryPaths(t *testing.T) {
+	entry := history.Entry{Plan: "abc123/20261014T101500Z.json"}
+	if got, want := entry.PlanPath("out"), filepath.Join("out", "abc123", "20261014T101500Z.json"); got != want {
+		t.Fatalf("expected plan path %q, got %q", want, got)
+	}
+	if got := entry.ReportPath("out"); got != "" {
+		t.Fatalf("expected no report path, got %q", got)
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2026, 10, d, 0, 0, 0, 0, time.UTC) }
+	entries := []history.Entry{
+		{Fingerprint: "a", CapturedAt: day(3), ExecutionMs: 40, Database: "shop"},
+		{Fingerprint: "a", CapturedAt: day(1), ExecutionMs: 100, Database: "shop", Query: "select 1"},
+		{Fingerprint: "b", CapturedAt: day(2), ExecutionMs: 10, Database: "crm"},
+		{Fingerprint: "b", CapturedAt: day(4), ExecutionMs: 30, Database: "crm"},
+		{Fingerprint: "a", CapturedAt: day(5), ExecutionMs: 50, Database: "shop"},
+		{Fingerprint: "c", CapturedAt: day(6), ExecutionMs: 5},
+	}
+
+	stats := history.Summarize(entries, history.StatsOptions{MinDeltaMs: 2, MinPercent: 5, Top: 1})
+	if stats.Runs != 6 || stats.Queries != 3 || !stats.First.Equal(day(1)) || !stats.Last.Equal(day(6)) {
+		t.Fatalf("unexpected totals %+v", stats)
+	}
+	if len(stats.Databases) != 2 || stats.Databases[0] != "crm" {
+		t.Fatalf("unexpected databases %v", stats.Databases)
+	}
+	// a: 100 -> 40 -> 50 regresses once; b: 10 -> 30 regresses once.
+	if stats.Regressions != 2 || stats.RegressedQueries != 2 {
+		t.Fatalf("expected 2 regressions in 2 statements, got %d in %d", stats.Regressions, stats.RegressedQueries)
+	}
+	if stats.Improved != 1 || stats.AverageImprovement != 50 || stats.SavedMs != 50 {
+		t.Fatalf("unexpected improvement %+v", stats)
+	}
+	if len(stats.MostImproved) != 1 || stats.MostImproved[0].Fingerprint != "a" || stats.MostImproved[0].Runs != 3 {
+		t.Fatalf("unexpected most improved %+v", stats.MostImproved)
+	}
+}
+
+func TestLearnThresholds(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2026, 10, d, 0, 0, 0, 0, time.UTC) }
+	var entries []history.Entry
+	// An old slow run of "a" falls outside the window and does not inflate its noise.
+	entries = append(entries, history.Entry{Fingerprint: "a", CapturedAt: day(1), ExecutionMs: 500})
+	for i, ms := range []float64{98, 102, 100, 100} {
+		entries = append(entries, history.Entry{Fingerprint: "a", CapturedAt: day(i + 2), ExecutionMs: ms, Query: "select 1"})
+	}
+	for i, ms := range []float64{1, 1, 1, 1} {
+		entries = append(entries, history.Entry{Fingerprint: "b", CapturedAt: day(i + 2), ExecutionMs: ms})
+	}
+	entries = append(entries, history.Entry{Fingerprint: "c", CapturedAt: day(3), ExecutionMs: 7})
+
+	learned := history.LearnThresholds(entries, history.ThresholdOptions{MinRuns: 3, Window: 4, Sigma: 3})
+	if len(learned) != 2 || learned[0].Fingerprint != "a" || learned[1].Fingerprint != "b" {
+		t.Fatalf("expected thresholds for a and b, got %+v", learned)
+	}
+	a := learned[0]
+	// Sample stddev of 98, 102, 100, 100 is sqrt(8/3) ≈ 1.633; three of them ≈ 4.9 ms, 4.9% of the 100 ms mean.
+	if a.Runs != 4 || a.MeanMs != 100 || a.MinSelfDeltaMs != 4.9 || a.MinPercentChange != 4.9 || a.CriticalDeltaMs != 9.8 || a.Query != "select 1" {
+		t.Fatalf("unexpected thresholds for a %+v", a)
+	}
+	if b := learned[1]; b.MinSelfDeltaMs != 0.1 || b.MinPercentChange != 10 {
+		t.Fatalf("expected floors for a perfectly stable query, got %+v", b)
+	}
+}
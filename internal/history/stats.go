@@ -0,0 +1,127 @@
+package history
+
+import (
+	"sort"
+	"time"
+)
+
+// StatsOptions decide when a run counts as a regression of the run before it.
+type StatsOptions struct {
+	// MinDeltaMs and MinPercent must both be exceeded by the slowdown.
+	MinDeltaMs float64
+	MinPercent float64
+	// Top bounds the statements listed as most improved.
+	Top int
+}
+
+// Stats summarizes the history log: how much was analyzed, how many slowdowns were caught between
+// consecutive runs of a statement and how much faster tuned statements became.
+type Stats struct {
+	Runs      int       `json:"runs"`
+	Queries   int       `json:"queries"`
+	Databases []string  `json:"databases,omitempty"`
+	First     time.Time `json:"first,omitzero"`
+	Last      time.Time `json:"last,omitzero"`
+	// Regressions counts runs that were slower than the previous run of the same statement.
+	Regressions      int `json:"regressions"`
+	RegressedQueries int `json:"regressed_queries"`
+	// Improved counts statements whose latest run is faster than their first one.
+	Improved int `json:"improved"`
+	// AverageImprovement is the mean speed-up of the improved statements, in percent.
+	AverageImprovement float64 `json:"average_improvement_percent"`
+	SavedMs            float64 `json:"saved_ms"`
+	MostImproved       []Query `json:"most_improved,omitempty"`
+}
+
+// Query is the first and latest run of one statement.
+type Query struct {
+	Fingerprint string  `json:"fingerprint"`
+	Query       string  `json:"query,omitempty"`
+	Runs        int     `json:"runs"`
+	FirstMs     float64 `json:"first_ms"`
+	LatestMs    float64 `json:"latest_ms"`
+	// Change is the latest run's time relative to the first, in percent; negative is faster.
+	Change float64 `json:"change_percent"`
+}
+
+// Summarize computes Stats over entries, which need not be sorted.
+func Summarize(entries []Entry, opts StatsOptions) Stats {
+	stats := Stats{Runs: len(entries)}
+	if len(entries) == 0 {
+		return stats
+	}
+
+	sorted := append([]Entry(nil), entries...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].CapturedAt.Before(sorted[j].CapturedAt) })
+	stats.First = sorted[0].CapturedAt
+	stats.Last = sorted[len(sorted)-1].CapturedAt
+
+	var order []string
+	byQuery := map[string][]Entry{}
+	databases := map[string]bool{}
+	for _, entry := range sorted {
+		if _, ok := byQuery[entry.Fingerprint]; !ok {
+			order = append(order, entry.Fingerprint)
+		}
+		byQuery[entry.Fingerprint] = append(byQuery[entry.Fingerprint], entry)
+		if entry.Database != "" && !databases[entry.Database] {
+			databases[entry.Database] = true
+			stats.Databases = append(stats.Databases, entry.Database)
+		}
+	}
+	sort.Strings(stats.Databases)
+	stats.Queries = len(order)
+
+	var improved []Query
+	for _, fp := range order {
+		runs := byQuery[fp]
+		regressed := false
+		for i := 1; i < len(runs); i++ {
+			delta := runs[i].ExecutionMs - runs[i-1].ExecutionMs
+			if delta > opts.MinDeltaMs && runs[i-1].ExecutionMs > 0 && delta/runs[i-1].ExecutionMs*100 > opts.MinPercent {
+				stats.Regressions++
+				regressed = true
+			}
+		}
+		if regressed {
+			stats.RegressedQueries++
+		}
+
+		first, latest := runs[0], runs[len(runs)-1]
+		if len(runs) < 2 || first.ExecutionMs <= 0 || latest.ExecutionMs >= first.ExecutionMs {
+			continue
+		}
+		q := Query{
+			Fingerprint: fp,
+			Query:       queryText(runs),
+			Runs:        len(runs),
+			FirstMs:     first.ExecutionMs,
+			LatestMs:    latest.ExecutionMs,
+			Change:      (latest.ExecutionMs - first.ExecutionMs) / first.ExecutionMs * 100,
+		}
+		improved = append(improved, q)
+		stats.SavedMs += first.ExecutionMs - latest.ExecutionMs
+		stats.AverageImprovement -= q.Change
+	}
+
+	stats.Improved = len(improved)
+	if stats.Improved > 0 {
+		stats.AverageImprovement /= float64(stats.Improved)
+	}
+	sort.SliceStable(improved, func(i, j int) bool { return improved[i].Change < improved[j].Change })
+	if opts.Top > 0 && len(improved) > opts.Top {
+		improved = improved[:opts.Top]
+	}
+	stats.MostImproved = improved
+	return stats
+}
+
+// queryText returns the most recent normalized query recorded for a statement's runs.
+func queryText(runs []Entry) string {
+	for i := len(runs) - 1; i >= 0; i-- {
+		if runs[i].Query != "" {
+			return runs[i].Query
+		}
+	}
+	return ""
+}
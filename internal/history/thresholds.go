@@ -0,0 +1,99 @@
+package history
+
+import (
+	"math"
+	"sort"
+)
+
+// ThresholdOptions control how diff thresholds are learned from the run-to-run variance of a statement.
+type ThresholdOptions struct {
+	// MinRuns is the number of runs a statement needs before its variance is trusted.
+	MinRuns int
+	// Window limits the variance to the most recent runs, so an old tuning step does not count as noise.
+	Window int
+	// Sigma is how many standard deviations a change must exceed to be reported.
+	Sigma float64
+}
+
+// Threshold is the diff sensitivity learned for one statement.
+type Threshold struct {
+	Fingerprint string  `json:"fingerprint"`
+	Query       string  `json:"query,omitempty"`
+	Runs        int     `json:"runs"`
+	MeanMs      float64 `json:"mean_ms"`
+	StdDevMs    float64 `json:"stddev_ms"`
+	// MinSelfDeltaMs and MinPercentChange are Sigma standard deviations, absolute and relative to the mean.
+	MinSelfDeltaMs   float64 `json:"min_self_delta_ms"`
+	MinPercentChange float64 `json:"min_percent_change"`
+	// WarningDeltaMs and CriticalDeltaMs grade regressions at one and two times that noise.
+	WarningDeltaMs  float64 `json:"warning_delta_ms"`
+	CriticalDeltaMs float64 `json:"critical_delta_ms"`
+}
+
+// Floors keep learned thresholds for perfectly stable statements from flagging measurement jitter.
+const (
+	minLearnedDeltaMs = 0.1
+	minLearnedPercent = 1.0
+)
+
+// LearnThresholds derives per-statement diff thresholds from the spread of each statement's execution times,
+// ordered by fingerprint. Statements with fewer than MinRuns runs are skipped.
+func LearnThresholds(entries []Entry, opts ThresholdOptions) []Threshold {
+	if opts.MinRuns < 2 {
+		opts.MinRuns = 2
+	}
+	if opts.Sigma <= 0 {
+		opts.Sigma = 3
+	}
+
+	sorted := append([]Entry(nil), entries...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].CapturedAt.Before(sorted[j].CapturedAt) })
+	byQuery := map[string][]Entry{}
+	for _, entry := range sorted {
+		byQuery[entry.Fingerprint] = append(byQuery[entry.Fingerprint], entry)
+	}
+
+	var out []Threshold
+	for fp, runs := range byQuery {
+		if opts.Window > 0 && len(runs) > opts.Window {
+			runs = runs[len(runs)-opts.Window:]
+		}
+		if len(runs) < opts.MinRuns {
+			continue
+		}
+		var sum float64
+		for _, run := range runs {
+			sum += run.ExecutionMs
+		}
+		mean := sum / float64(len(runs))
+		var squares float64
+		for _, run := range runs {
+			squares += (run.ExecutionMs - mean) * (run.ExecutionMs - mean)
+		}
+		stddev := math.Sqrt(squares / float64(len(runs)-1))
+
+		noise := math.Max(opts.Sigma*stddev, minLearnedDeltaMs)
+		percent := minLearnedPercent
+		if mean > 0 {
+			percent = math.Max(noise/mean*100, minLearnedPercent)
+		}
+		out = append(out, Threshold{
+			Fingerprint:      fp,
+			Query:            queryText(runs),
+			Runs:             len(runs),
+			MeanMs:           round(mean, 3),
+			StdDevMs:         round(stddev, 3),
+			MinSelfDeltaMs:   round(noise, 2),
+			MinPercentChange: round(percent, 1),
+			WarningDeltaMs:   round(noise, 2),
+			CriticalDeltaMs:  round(2*noise, 2),
+		})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Fingerprint < out[j].Fingerprint })
+	return out
+}
+
+func round(value float64, places int) float64 {
+	scale := math.Pow(10, float64(places))
+	return math.Round(value*scale) / scale
+}
@@ -0,0 +1,76 @@
+// Package ignore reads .xplain-ignore files that acknowledge insights, similar to linter suppressions.
+package ignore
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/mickamy/xplain/internal/model"
+)
+
+// DefaultPath is the ignore file read from the working directory when no other path is given.
+const DefaultPath = ".xplain-ignore"
+
+// Load reads an ignore file. A missing file yields no entries only when optional is set.
+func Load(path string, optional bool) ([]model.Acknowledgement, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		if optional && os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("open %s: %w", path, err)
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+
+	entries, err := Parse(file)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return entries, nil
+}
+
+// Parse reads one acknowledgement per line: an insight rule, then an optional node selector.
+// The justification is a trailing "# comment", or else the comment lines directly above:
+//
+//	# Nightly export reads the whole table by design.
+//	hot-spot Seq Scan on orders
+//	planning-io  # partition count is fixed until the archive job ships
+func Parse(r io.Reader) ([]model.Acknowledgement, error) {
+	var (
+		entries []model.Acknowledgement
+		comment []string
+	)
+	scanner := bufio.NewScanner(r)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			comment = nil
+			continue
+		}
+		if strings.HasPrefix(line, "#") {
+			comment = append(comment, strings.TrimSpace(strings.TrimPrefix(line, "#")))
+			continue
+		}
+
+		reason := strings.Join(comment, " ")
+		comment = nil
+		if body, trailing, found := strings.Cut(line, "#"); found {
+			line = strings.TrimSpace(body)
+			reason = strings.TrimSpace(trailing)
+		}
+		rule, selector, _ := strings.Cut(line, " ")
+		if rule == "" {
+			return nil, fmt.Errorf("line %d: missing rule", lineNo)
+		}
+		entries = append(entries, model.Acknowledgement{Rule: rule, Selector: strings.TrimSpace(selector), Reason: reason})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
@@ -0,0 +1,32 @@
+package ignore_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/mickamy/xplain/internal/ignore"
+	"github.com/mickamy/xplain/test"
+)
+
+func TestLoad(t *testing.T) {
+	entries, err := ignore.Load(filepath.Join(test.RootPath(t), "samples", "xplain-ignore.example"), false)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if len(entries) != 3 {
+		t.Fatalf("expected 3 entries, got %+v", entries)
+	}
+	if e := entries[0]; e.Rule != "hot-spot" || e.Selector != "Seq Scan on orders" || e.Reason != "The nightly export reads every order by design." {
+		t.Fatalf("unexpected first entry %+v", e)
+	}
+	if e := entries[2]; e.Rule != "planning-io" || e.Selector != "" || e.Reason != "partition count is fixed until the archive job ships" {
+		t.Fatalf("unexpected last entry %+v", e)
+	}
+
+	if _, err := ignore.Load(filepath.Join(t.TempDir(), ignore.DefaultPath), true); err != nil {
+		t.Fatalf("expected a missing optional file to be skipped, got %v", err)
+	}
+	if _, err := ignore.Load(filepath.Join(t.TempDir(), "missing"), false); err == nil {
+		t.Fatalf("expected a missing explicit file to fail")
+	}
+}
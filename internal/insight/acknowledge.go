@@ -0,0 +1,47 @@
+package insight
+
+import (
+	"strings"
+
+	"github.com/mickamy/xplain/internal/analyzer"
+	"github.com/mickamy/xplain/internal/assert"
+)
+
+// Finding returns the observation part of the message, without the remediation after " — ".
+func (m Message) Finding() string {
+	finding, _, _ := strings.Cut(m.Text, " — ")
+	return finding
+}
+
+// acknowledge marks messages matched by the analysis' acknowledgements. A rule of "*" matches
+// every rule; a selector matches the node the message is anchored to.
+func acknowledge(analysis *analyzer.PlanAnalysis, messages []Message) {
+	if len(analysis.Acknowledgements) == 0 {
+		return
+	}
+	nodes := map[string]*analyzer.NodeStats{}
+	for _, n := range analysis.Nodes() {
+		if _, ok := nodes[AnchorID(n)]; !ok {
+			nodes[AnchorID(n)] = n
+		}
+	}
+	for i := range messages {
+		msg := &messages[i]
+		for _, ack := range analysis.Acknowledgements {
+			if ack.Rule != "*" && !strings.EqualFold(ack.Rule, msg.Rule) {
+				continue
+			}
+			if ack.Selector != "" {
+				node := nodes[msg.Anchor]
+				if node == nil || !assert.ParsePattern(ack.Selector).Matches(node) {
+					continue
+				}
+			}
+			msg.Acknowledged = ack.Reason
+			if msg.Acknowledged == "" {
+				msg.Acknowledged = "acknowledged"
+			}
+			break
+		}
+	}
+}
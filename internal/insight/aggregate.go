@@ -0,0 +1,79 @@
+package insight
+
+import (
+	"fmt"
+
+	"github.com/mickamy/xplain/internal/analyzer"
+	"github.com/mickamy/xplain/internal/config"
+)
+
+func fullAggregateMessages(analysis *analyzer.PlanAnalysis) []Message {
+	if analysis == nil || analysis.Root == nil {
+		return nil
+	}
+	cfg := settings(analysis).Insights
+	var msgs []Message
+	var walk func(*analyzer.NodeStats)
+	walk = func(n *analyzer.NodeStats) {
+		if n == nil || n.Node == nil {
+			return
+		}
+		if n.Node.NodeType == "Aggregate" && len(n.Node.GroupKey) == 0 {
+			if scan := seqScanBelow(n); scan != nil && scan.ActualTotalRows >= cfg.FullAggregateRows {
+				msgs = append(msgs, fullAggregateMessage(cfg, n, scan))
+				return
+			}
+		}
+		for _, child := range n.Children {
+			walk(child)
+		}
+	}
+	walk(analysis.Root)
+	return msgs
+}
+
+func fullAggregateMessage(cfg config.InsightConfig, agg, scan *analyzer.NodeStats) Message {
+	var text string
+	kept := keptRatio(scan)
+	if kept >= 0.9 {
+		text = fmt.Sprintf("Full-table aggregate: %s reads all %.0f rows of %s", CompactLabel(agg), scan.ActualTotalRows, scan.Node.RelationName)
+		text += " — if an estimate is enough use pg_class.reltuples or pg_stat_user_tables.n_live_tup, or maintain a counter table"
+	} else {
+		text = fmt.Sprintf("Filtered aggregate: %s scans %s and keeps %.0f rows (%.1f%%)", CompactLabel(agg), scan.Node.RelationName, scan.ActualTotalRows, kept*100)
+		text += fmt.Sprintf(" — a partial index WHERE %s enables an index-only scan", NormalizeWhitespace(scan.Node.Filter))
+	}
+	severity := SeverityInfo
+	if agg.PercentInclusive >= cfg.HotspotWarningPercent {
+		severity = SeverityWarning
+	}
+	return Message{Rule: "full-aggregate", Severity: severity, Text: text, Anchor: AnchorID(agg)}
+}
+
+// seqScanBelow returns the sequential scan feeding an aggregate when only
+// pass-through nodes (partial aggregates, Gather) sit between them.
+func seqScanBelow(agg *analyzer.NodeStats) *analyzer.NodeStats {
+	node := agg
+	for {
+		if len(node.Children) != 1 {
+			return nil
+		}
+		node = node.Children[0]
+		switch node.Node.NodeType {
+		case "Gather", "Gather Merge", "Aggregate":
+			continue
+		case "Seq Scan":
+			return node
+		default:
+			return nil
+		}
+	}
+}
+
+func keptRatio(scan *analyzer.NodeStats) float64 {
+	removed := scan.RowsRemovedByFilter
+	examined := scan.ActualTotalRows + removed
+	if examined <= 0 {
+		return 1
+	}
+	return scan.ActualTotalRows / examined
+}
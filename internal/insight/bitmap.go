@@ -0,0 +1,33 @@
+package insight
+
+import (
+	"fmt"
+
+	"github.com/mickamy/xplain/internal/analyzer"
+)
+
+// bitmapPageBytes approximates the memory a TID bitmap spends per exact heap page: a PagetableEntry and its
+// hash table slot, as tbm_calculate_entries budgets them.
+const bitmapPageBytes = 72
+
+// lossyBitmapMessages flags Bitmap Heap Scans whose bitmap outgrew work_mem. Past that point PostgreSQL keeps
+// only the page for part of the bitmap and has to recheck the condition against every tuple on those pages,
+// which shows up as Rows Removed by Index Recheck.
+func lossyBitmapMessages(analysis *analyzer.PlanAnalysis) []Message {
+	var msgs []Message
+	walkNodes(analysis.Root, func(n *analyzer.NodeStats) {
+		if !n.HasWarning(analyzer.WarnLossyBitmap) {
+			return
+		}
+		pages := n.Node.ExactHeapBlocks + n.Node.LossyHeapBlocks
+		text := fmt.Sprintf("Lossy bitmap: %s kept only the page for %.0f of %.0f heap blocks (%.1f%%)",
+			CompactLabel(n), n.Node.LossyHeapBlocks, pages, n.Node.LossyHeapBlocks/pages*100)
+		if n.RowsRemovedByIndexRecheck > 0 {
+			text += fmt.Sprintf(", so rechecking them removed %.0f rows for %.0f kept", n.RowsRemovedByIndexRecheck, n.ActualTotalRows)
+		}
+		text += fmt.Sprintf(" — work_mem is too small for the bitmap; about %s would keep it exact",
+			HumanizeBytes(pages*bitmapPageBytes))
+		msgs = append(msgs, Message{Rule: "lossy-bitmap", Severity: warningSeverity(analysis, n), Text: text, Anchor: AnchorID(n)})
+	})
+	return msgs
+}
@@ -0,0 +1,63 @@
+package insight
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mickamy/xplain/internal/analyzer"
+)
+
+// SummarizeCacheHit describes the plan's shared buffer hit ratio, e.g. "61.2% (hit 1204, read 763)", or ""
+// when execution accessed no shared blocks or BUFFERS was off.
+func SummarizeCacheHit(analysis *analyzer.PlanAnalysis) string {
+	if analysis == nil || analysis.Root == nil {
+		return ""
+	}
+	b := analysis.Root.Buffers
+	if b.SharedHit+b.SharedRead == 0 {
+		return ""
+	}
+	return fmt.Sprintf("%.1f%% (hit %d, read %d)", analysis.HitRatio*100, b.SharedHit, b.SharedRead)
+}
+
+// FormatHitRatio describes the hit ratio of the shared blocks a node accessed itself, e.g. "hit ratio
+// 4.2%", or "" when it accessed none.
+func FormatHitRatio(n *analyzer.NodeStats) string {
+	if n.SelfSharedHit+n.SelfSharedRead == 0 {
+		return ""
+	}
+	return fmt.Sprintf("hit ratio %.1f%%", n.HitRatio*100)
+}
+
+// coldReadMessages flags hot nodes that found most of the blocks they accessed outside shared buffers, where
+// the time likely went to reading them from the OS cache or disk rather than to the operator itself.
+func coldReadMessages(analysis *analyzer.PlanAnalysis) []Message {
+	cfg := settings(analysis).Insights
+	var msgs []Message
+	for _, n := range analysis.HotNodes {
+		accessed := n.SelfSharedHit + n.SelfSharedRead
+		if accessed < cfg.CacheHitMinBlocks || n.HitRatio >= cfg.CacheHitWarningPercent {
+			continue
+		}
+		text := fmt.Sprintf("Cold reads: %s found only %.1f%% of its %d blocks (~%s) in shared buffers, reading %d from the OS cache or disk",
+			CompactLabel(n), n.HitRatio*100, accessed, HumanizeBuffers(accessed), n.SelfSharedRead)
+		if len(n.Children) == 0 && n.Buffers.SharedReadTimeMs > 0 {
+			text += fmt.Sprintf(" (%.2f ms of its %.2f ms self time)", n.Buffers.SharedReadTimeMs, n.ExclusiveTimeMs)
+		}
+		text += " — if the query runs often, its working set does not fit shared_buffers: raise it, warm the table with pg_prewarm, or read fewer pages with an index or a narrower filter"
+		msgs = append(msgs, Message{Rule: "cold-reads", Severity: severityForHotspot(cfg, n), Text: text, Anchor: AnchorID(n)})
+	}
+	return msgs
+}
+
+// FormatBufferDetail combines the hit ratio and I/O share of a buffer-heavy node, e.g. "hit ratio 4.2%,
+// self 24.16 ms, 43.6% of I/O", leaving out whichever the plan did not record.
+func FormatBufferDetail(n *analyzer.NodeStats) string {
+	var parts []string
+	for _, part := range []string{FormatHitRatio(n), FormatIOShare(n)} {
+		if part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return strings.Join(parts, ", ")
+}
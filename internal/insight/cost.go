@@ -0,0 +1,80 @@
+package insight
+
+import (
+	"fmt"
+
+	"github.com/mickamy/xplain/internal/analyzer"
+)
+
+// SummarizeCostModel describes how planner cost units translated into time for the plan, e.g.
+// "100.0 cost/ms median over 3 nodes (whole plan 5.8); 1 node 10x or more off", or "" without a cost model.
+func SummarizeCostModel(analysis *analyzer.PlanAnalysis) string {
+	if analysis == nil || analysis.CostModel == nil {
+		return ""
+	}
+	model := analysis.CostModel
+	text := fmt.Sprintf("%.1f cost/ms median over %d nodes", model.CostPerMs, model.Nodes)
+	if model.PlanCostPerMs > 0 {
+		text += fmt.Sprintf(" (whole plan %.1f)", model.PlanCostPerMs)
+	}
+	switch len(model.Outliers) {
+	case 0:
+		return text + "; estimates consistent"
+	case 1:
+		return text + "; 1 node 10x or more off"
+	default:
+		return text + fmt.Sprintf("; %d nodes 10x or more off", len(model.Outliers))
+	}
+}
+
+// FormatCost describes a node's self cost against its self time, e.g. "cost 1500 (5.0/ms, 0.05x the
+// plan)"; the comparison is left out when the node had too little self time to judge.
+func FormatCost(n *analyzer.NodeStats) string {
+	cost := n.SelfCost()
+	if cost <= 0 || n.CostPerMs() <= 0 {
+		return ""
+	}
+	if n.CostSkew <= 0 {
+		return fmt.Sprintf("cost %.0f (%.1f/ms)", cost, n.CostPerMs())
+	}
+	return fmt.Sprintf("cost %.0f (%.1f/ms, %.2gx the plan)", cost, n.CostPerMs(), n.CostSkew)
+}
+
+func costModelMessages(analysis *analyzer.PlanAnalysis) []Message {
+	if analysis == nil || analysis.CostModel == nil {
+		return nil
+	}
+	model := analysis.CostModel
+	cfg := settings(analysis).Insights
+	var msgs []Message
+	for _, n := range model.Outliers[:min(3, len(model.Outliers))] {
+		direction, factor := "slower", 1/n.CostSkew
+		if n.CostSkew > 1 {
+			direction, factor = "faster", n.CostSkew
+		}
+		text := fmt.Sprintf("Cost model: %s ran %.0fx %s than its cost suggests (%.1f cost/ms vs %.1f for the plan) — %s",
+			CompactLabel(n), factor, direction, n.CostPerMs(), model.CostPerMs, costHint(n))
+		severity := SeverityInfo
+		if n.PercentExclusive >= cfg.CostModelWarningPercent {
+			severity = SeverityWarning
+		}
+		msgs = append(msgs, Message{Rule: "cost-model", Severity: severity, Text: text, Anchor: AnchorID(n)})
+	}
+	return msgs
+}
+
+// costHint names the planner settings most likely behind the node's mis-costing.
+func costHint(n *analyzer.NodeStats) string {
+	slower := n.CostSkew < 1
+	diskBound := n.Buffers.SharedRead+n.Buffers.LocalRead+n.Buffers.TempRead > n.Buffers.SharedHit+n.Buffers.LocalHit
+	switch {
+	case diskBound && slower:
+		return "most of its pages came from disk, so random_page_cost/seq_page_cost may be too low for this storage or effective_cache_size too high"
+	case diskBound:
+		return "its page reads were cheaper than costed, so random_page_cost may be too high for this storage (e.g. SSDs)"
+	case slower:
+		return "it was CPU-bound, so cpu_tuple_cost/cpu_operator_cost or the COST of functions it calls may be too low"
+	default:
+		return "it was CPU-bound, so cpu_tuple_cost/cpu_operator_cost or the COST of functions it calls may be too high"
+	}
+}
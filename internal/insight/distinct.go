@@ -0,0 +1,96 @@
+package insight
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mickamy/xplain/internal/analyzer"
+)
+
+func distinctFanoutMessages(analysis *analyzer.PlanAnalysis) []Message {
+	if analysis == nil || analysis.Root == nil {
+		return nil
+	}
+	cfg := settings(analysis).Insights
+	var msgs []Message
+	walkNodes(analysis.Root, func(n *analyzer.NodeStats) {
+		if n.Node == nil || !isDedupNode(n) || len(n.Children) == 0 {
+			return
+		}
+		input := n.Children[0]
+		out := n.ActualTotalRows
+		if out < 1 {
+			out = 1
+		}
+		collapse := input.ActualTotalRows / out
+		if collapse < cfg.DistinctFanoutRatio {
+			return
+		}
+		join, fanout := fanoutJoin(input)
+		if join == nil {
+			return
+		}
+
+		cost := n.ExclusiveTimeMs
+		if input.Node.NodeType == "Sort" || input.Node.NodeType == "Incremental Sort" {
+			cost += input.ExclusiveTimeMs
+		}
+		text := fmt.Sprintf("DISTINCT after join fanout: %s collapses %.0f rows to %.0f (x%.1f) multiplied by %s (x%.1f fanout), costing %.2f ms",
+			CompactLabel(n), input.ActualTotalRows, n.ActualTotalRows, collapse, CompactLabel(join), fanout, cost)
+		text += " — rewrite the join as EXISTS / semi-join or aggregate before joining instead of deduplicating at the end"
+		severity := SeverityInfo
+		if analysis.TotalTimeMs > 0 && cost/analysis.TotalTimeMs >= cfg.HotspotWarningPercent {
+			severity = SeverityWarning
+		}
+		msgs = append(msgs, Message{Rule: "distinct-fanout", Severity: severity, Text: text, Anchor: AnchorID(n)})
+	})
+	return msgs
+}
+
+// isDedupNode reports whether the node only removes duplicates: a Unique node, or a grouping
+// aggregate whose VERBOSE output list is exactly its group key.
+func isDedupNode(n *analyzer.NodeStats) bool {
+	if n.Node.NodeType == "Unique" {
+		return true
+	}
+	if n.Node.NodeType != "Aggregate" || len(n.Node.GroupKey) == 0 || len(n.Node.Output) != len(n.Node.GroupKey) {
+		return false
+	}
+	keys := map[string]struct{}{}
+	for _, k := range n.Node.GroupKey {
+		keys[k] = struct{}{}
+	}
+	for _, col := range n.Node.Output {
+		if _, ok := keys[col]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// fanoutJoin finds the join beneath a node that multiplies its smallest input the most.
+func fanoutJoin(root *analyzer.NodeStats) (*analyzer.NodeStats, float64) {
+	var best *analyzer.NodeStats
+	var bestFanout float64
+	walkNodes(root, func(n *analyzer.NodeStats) {
+		if n.Node == nil || len(n.Children) < 2 {
+			return
+		}
+		if !strings.Contains(n.Node.NodeType, "Join") && n.Node.NodeType != "Nested Loop" {
+			return
+		}
+		smallest := -1.0
+		for _, child := range n.Children {
+			if child.ActualTotalRows > 0 && (smallest < 0 || child.ActualTotalRows < smallest) {
+				smallest = child.ActualTotalRows
+			}
+		}
+		if smallest <= 0 {
+			return
+		}
+		if fanout := n.ActualTotalRows / smallest; fanout >= 2 && fanout > bestFanout {
+			best, bestFanout = n, fanout
+		}
+	})
+	return best, bestFanout
+}
@@ -0,0 +1,22 @@
+package insight
+
+import (
+	"fmt"
+
+	"github.com/mickamy/xplain/internal/advisor"
+	"github.com/mickamy/xplain/internal/analyzer"
+)
+
+// ignoredIndexNote reports a catalog index that already serves idx, so advice never proposes a duplicate.
+// An existing but unused index points at statistics or costing rather than a missing index.
+func ignoredIndexNote(analysis *analyzer.PlanAnalysis, idx advisor.Index, what string) (string, bool) {
+	if analysis == nil {
+		return "", false
+	}
+	existing, ok := advisor.FindExisting(analysis.Catalog, idx)
+	if !ok {
+		return "", false
+	}
+	return fmt.Sprintf("index %s already provides %s but the planner ignored it — check statistics (ANALYZE %s), cost settings such as random_page_cost, and type or collation mismatches",
+		existing.Name, what, idx.QualifiedTable()), true
+}
@@ -0,0 +1,129 @@
+package insight
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mickamy/xplain/internal/advisor"
+	"github.com/mickamy/xplain/internal/analyzer"
+	"github.com/mickamy/xplain/internal/config"
+)
+
+// volatileFunctions are re-evaluated for every row and can never be indexed.
+var volatileFunctions = map[string]struct{}{
+	"random": {}, "clock_timestamp": {}, "timeofday": {}, "nextval": {}, "setseed": {},
+	"gen_random_uuid": {}, "uuid_generate_v4": {}, "txid_current": {},
+}
+
+// stableFunctions are cheap or evaluated once per statement, so they are not worth flagging.
+var stableFunctions = map[string]struct{}{
+	"now": {}, "current_date": {}, "current_timestamp": {}, "statement_timestamp": {},
+	"transaction_timestamp": {}, "current_setting": {}, "current_user": {}, "current_schema": {},
+}
+
+// aggregateFunctions compute a plan's aggregates and window functions, which an output list is expected to call
+// for every row.
+var aggregateFunctions = map[string]struct{}{
+	"count": {}, "sum": {}, "avg": {}, "min": {}, "max": {}, "array_agg": {}, "string_agg": {},
+	"json_agg": {}, "jsonb_agg": {}, "json_object_agg": {}, "jsonb_object_agg": {}, "bool_and": {}, "bool_or": {},
+	"row_number": {}, "rank": {}, "dense_rank": {}, "ntile": {}, "lag": {}, "lead": {},
+	"first_value": {}, "last_value": {},
+}
+
+func filterFunctionMessages(analysis *analyzer.PlanAnalysis) []Message {
+	if analysis == nil || analysis.Root == nil {
+		return nil
+	}
+	cfg := settings(analysis).Insights
+	var msgs []Message
+	walkNodes(analysis.Root, func(n *analyzer.NodeStats) {
+		if n.Node == nil {
+			return
+		}
+		if msg, ok := filterFunctionMessage(analysis, n); ok {
+			msgs = append(msgs, msg)
+			return
+		}
+		// Calls in join conditions and output lists cannot use an index on the scanned table, so they are
+		// only worth naming when the node runs them often enough.
+		for _, site := range []struct {
+			where string
+			calls []analyzer.FunctionCall
+			rows  float64
+		}{
+			{"join condition", n.ConditionFunctions, n.ActualTotalRows + n.RowsRemovedByJoinFilter},
+			{"output list", n.OutputFunctions, n.ActualTotalRows},
+		} {
+			if site.rows < cfg.FunctionScanRows {
+				continue
+			}
+			_, other := splitFunctions(site.calls)
+			if len(other) == 0 {
+				continue
+			}
+			text := fmt.Sprintf("Per-row function call: %s evaluates %s() in its %s for %.0f rows — make the function cheaper, mark user-defined functions IMMUTABLE/STABLE so they can be inlined, or compute it once before the rows fan out",
+				CompactLabel(n), other[0].Name, site.where, site.rows)
+			msgs = append(msgs, Message{Rule: "per-row-function", Severity: functionSeverity(n, cfg), Text: text, Anchor: AnchorID(n)})
+			return
+		}
+	})
+	return msgs
+}
+
+// filterFunctionMessage reports a call in a scan's filter, which an expression index can evaluate ahead of time.
+func filterFunctionMessage(analysis *analyzer.PlanAnalysis, n *analyzer.NodeStats) (Message, bool) {
+	cfg := settings(analysis).Insights
+	if n.Node.RelationName == "" || len(n.FilterFunctions) == 0 {
+		return Message{}, false
+	}
+	examined := n.ActualTotalRows + n.RowsRemovedByFilter
+	if examined < cfg.FunctionScanRows {
+		return Message{}, false
+	}
+	volatile, other := splitFunctions(n.FilterFunctions)
+	switch {
+	case len(volatile) > 0:
+		text := fmt.Sprintf("Per-row function call: %s re-evaluates volatile %s() for %.0f rows and cannot use an index — compute the value once (parameter or CTE) before filtering",
+			CompactLabel(n), volatile[0].Name, examined)
+		return Message{Rule: "per-row-function", Severity: SeverityWarning, Text: text, Anchor: AnchorID(n)}, true
+	case len(other) > 0:
+		call := other[0]
+		idx := advisor.Index{Schema: n.Node.Schema, Table: n.Node.RelationName, Columns: []advisor.Column{{Expr: call.Expr}}}
+		var proposal *advisor.Index
+		text := fmt.Sprintf("Per-row function call: %s evaluates %s() in its filter for %.0f rows", CompactLabel(n), call.Name, examined)
+		if note, found := ignoredIndexNote(analysis, idx, call.Expr); found {
+			text += " although " + note
+		} else {
+			text += fmt.Sprintf(" — add an expression index (%s) or mark user-defined functions IMMUTABLE/STABLE so they can be indexed and inlined", idx.DDL())
+			proposal = &idx
+		}
+		return Message{Rule: "per-row-function", Severity: functionSeverity(n, cfg), Text: text, Anchor: AnchorID(n), Index: proposal}, true
+	}
+	return Message{}, false
+}
+
+// splitFunctions drops the cheap stable and aggregate calls and separates the volatile ones from the rest.
+func splitFunctions(calls []analyzer.FunctionCall) (volatile, other []analyzer.FunctionCall) {
+	for _, call := range calls {
+		name := strings.ToLower(call.Name)
+		if _, ok := stableFunctions[name]; ok {
+			continue
+		}
+		if _, ok := aggregateFunctions[name]; ok {
+			continue
+		}
+		if _, ok := volatileFunctions[name]; ok {
+			volatile = append(volatile, call)
+			continue
+		}
+		other = append(other, call)
+	}
+	return volatile, other
+}
+
+func functionSeverity(n *analyzer.NodeStats, cfg config.InsightConfig) Severity {
+	if n.PercentExclusive >= cfg.HotspotWarningPercent {
+		return SeverityWarning
+	}
+	return SeverityInfo
+}
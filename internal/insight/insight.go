@@ -6,8 +6,10 @@ import (
 	"sort"
 	"strings"
 
+	"github.com/mickamy/xplain/internal/advisor"
 	"github.com/mickamy/xplain/internal/analyzer"
 	"github.com/mickamy/xplain/internal/config"
+	"github.com/mickamy/xplain/internal/fingerprint"
 )
 
 // Severity expresses the urgency of an insight message.
@@ -19,11 +21,58 @@ const (
 	SeverityCritical Severity = "critical"
 )
 
+// ParseSeverity validates a --min-severity value; empty keeps every message.
+func ParseSeverity(s string) (Severity, error) {
+	switch sev := Severity(strings.ToLower(strings.TrimSpace(s))); sev {
+	case "", SeverityInfo, SeverityWarning, SeverityCritical:
+		return sev, nil
+	default:
+		return "", fmt.Errorf("insight: unknown severity %q (expected info, warning or critical)", s)
+	}
+}
+
+// rank orders severities from info to critical.
+func (s Severity) rank() int {
+	switch s {
+	case SeverityCritical:
+		return 2
+	case SeverityWarning:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// AtLeast reports whether s is as urgent as min or more.
+func (s Severity) AtLeast(min Severity) bool {
+	return s.rank() >= min.rank()
+}
+
+// FilterSeverity keeps the messages at least as urgent as min, acknowledged ones included.
+func FilterSeverity(messages []Message, min Severity) []Message {
+	if min == "" || min == SeverityInfo {
+		return messages
+	}
+	var out []Message
+	for _, msg := range messages {
+		if msg.Severity.AtLeast(min) {
+			out = append(out, msg)
+		}
+	}
+	return out
+}
+
 // Message represents an actionable observation about a plan.
 type Message struct {
+	// Rule identifies the check that produced the message, e.g. "hot-spot".
+	Rule     string
 	Severity Severity
 	Text     string
 	Anchor   string
+	// Index is the CREATE INDEX proposal the message makes, when it makes one.
+	Index *advisor.Index
+	// Acknowledged holds the justification from an ignore file that suppresses the message.
+	Acknowledged string
 }
 
 // BuildMessages derives human-readable insight messages for a plan.
@@ -39,26 +88,66 @@ func BuildMessages(analysis *analyzer.PlanAnalysis) []Message {
 
 	out = append(out, driftMessages(analysis)...)
 	out = append(out, workerImbalanceMessages(analysis)...)
+	out = append(out, workerSkewMessages(analysis)...)
 	out = append(out, workerShortfallMessages(analysis)...)
 	if msg := bufferMessage(analysis); msg != nil {
 		out = append(out, *msg)
 	}
+	out = append(out, coldReadMessages(analysis)...)
+	if msg := planningBufferMessage(analysis); msg != nil {
+		out = append(out, *msg)
+	}
 
 	if msg := parallelLimitMessage(analysis); msg != nil {
 		out = append(out, *msg)
 	}
+	if msg := limitWasteMessage(analysis); msg != nil {
+		out = append(out, *msg)
+	}
+	if msg := paginationMessage(analysis); msg != nil {
+		out = append(out, *msg)
+	}
 
+	out = append(out, fullAggregateMessages(analysis)...)
+	out = append(out, distinctFanoutMessages(analysis)...)
+	out = append(out, filterFunctionMessages(analysis)...)
+	out = append(out, perRowCostMessages(analysis)...)
+	out = append(out, foreignKeyTriggerMessages(analysis)...)
+	out = append(out, wideRowMessages(analysis)...)
+	if msg := resultSizeMessage(analysis); msg != nil {
+		out = append(out, *msg)
+	}
+	out = append(out, sortIndexMessages(analysis)...)
+	out = append(out, redundantIndexMessages(analysis)...)
+	out = append(out, unusedOutputMessages(analysis)...)
 	out = append(out, spillMessages(analysis)...)
+	out = append(out, lossyBitmapMessages(analysis)...)
+	out = append(out, memoizeMessages(analysis)...)
+	out = append(out, heapFetchMessages(analysis)...)
+	if msg := memoryBudgetMessage(analysis); msg != nil {
+		out = append(out, *msg)
+	}
 	out = append(out, nestedLoopMessages(analysis)...)
+	out = append(out, costModelMessages(analysis)...)
+	if msg := transferMessage(analysis); msg != nil {
+		out = append(out, *msg)
+	}
 
+	acknowledge(analysis, out)
 	return out
 }
 
+// settings returns the configuration the analyzed statement is judged by: the active one with the queries
+// overrides for its fingerprint and name applied.
+func settings(analysis *analyzer.PlanAnalysis) config.Config {
+	return config.Active().ForQuery(fingerprint.Of(analysis), analysis.Name)
+}
+
 func hotspotMessage(analysis *analyzer.PlanAnalysis) *Message {
 	if len(analysis.HotNodes) == 0 {
 		return nil
 	}
-	cfg := config.Active().Insights
+	cfg := settings(analysis).Insights
 	hot := analysis.HotNodes[0]
 	text := fmt.Sprintf("Hot spot: %s self %.2f ms (%.1f%%)", CompactLabel(hot), hot.ExclusiveTimeMs, hot.PercentExclusive*100)
 	if buf := hot.Buffers.Total(); buf > 0 {
@@ -67,15 +156,14 @@ func hotspotMessage(analysis *analyzer.PlanAnalysis) *Message {
 	if strings.Contains(hot.Node.NodeType, "Seq Scan") && int64(hot.Buffers.Total()) > cfg.SeqScanBufferHint {
 		text += " — consider adding an index or tightening the filter"
 	}
-	severity := severityForHotspot(hot)
-	return &Message{Severity: severity, Text: text, Anchor: AnchorID(hot)}
+	severity := severityForHotspot(cfg, hot)
+	return &Message{Rule: "hot-spot", Severity: severity, Text: text, Anchor: AnchorID(hot)}
 }
 
-func severityForHotspot(node *analyzer.NodeStats) Severity {
+func severityForHotspot(cfg config.InsightConfig, node *analyzer.NodeStats) Severity {
 	if node == nil {
 		return SeverityInfo
 	}
-	cfg := config.Active().Insights
 	switch {
 	case node.PercentExclusive >= cfg.HotspotCriticalPercent:
 		return SeverityCritical
@@ -86,11 +174,20 @@ func severityForHotspot(node *analyzer.NodeStats) Severity {
 	}
 }
 
+// warningSeverity is the severity of an insight that is a warning wherever it occurs, and critical when
+// the node is a critical hot spot.
+func warningSeverity(analysis *analyzer.PlanAnalysis, n *analyzer.NodeStats) Severity {
+	if severityForHotspot(settings(analysis).Insights, n) == SeverityCritical {
+		return SeverityCritical
+	}
+	return SeverityWarning
+}
+
 func driftMessages(analysis *analyzer.PlanAnalysis) []Message {
 	if len(analysis.DivergentNodes) == 0 {
 		return nil
 	}
-	cfg := config.Active().Insights
+	cfg := settings(analysis).Insights
 	max := 2
 	var msgs []Message
 	for i, node := range analysis.DivergentNodes {
@@ -109,7 +206,7 @@ func driftMessages(analysis *analyzer.PlanAnalysis) []Message {
 		if ratio >= cfg.RowEstimateCriticalHigh || ratio <= cfg.RowEstimateCriticalLow {
 			severity = SeverityCritical
 		}
-		msgs = append(msgs, Message{Severity: severity, Text: text, Anchor: AnchorID(node)})
+		msgs = append(msgs, Message{Rule: "estimate-drift", Severity: severity, Text: text, Anchor: AnchorID(node)})
 	}
 	return msgs
 }
@@ -119,7 +216,7 @@ func bufferMessage(analysis *analyzer.PlanAnalysis) *Message {
 	if candidate == nil {
 		return nil
 	}
-	cfg := config.Active().Insights
+	cfg := settings(analysis).Insights
 	buf := candidate.Buffers.Total()
 	text := fmt.Sprintf("Buffer churn: %s touched %d buffers (~%s)", CompactLabel(candidate), buf, HumanizeBuffers(buf))
 	severity := SeverityInfo
@@ -129,7 +226,7 @@ func bufferMessage(analysis *analyzer.PlanAnalysis) *Message {
 	case buf >= cfg.BufferWarningBlocks:
 		severity = SeverityWarning
 	}
-	return &Message{Severity: severity, Text: text, Anchor: AnchorID(candidate)}
+	return &Message{Rule: "buffer-churn", Severity: severity, Text: text, Anchor: AnchorID(candidate)}
 }
 
 func selectBufferCandidate(analysis *analyzer.PlanAnalysis) *analyzer.NodeStats {
@@ -169,7 +266,7 @@ func parallelLimitMessage(analysis *analyzer.PlanAnalysis) *Message {
 	if analysis == nil || analysis.Root == nil {
 		return nil
 	}
-	cfg := config.Active().Insights
+	cfg := settings(analysis).Insights
 	var candidate *analyzer.NodeStats
 	walkNodes(analysis.Root, func(node *analyzer.NodeStats) {
 		if candidate != nil {
@@ -196,25 +293,75 @@ func parallelLimitMessage(analysis *analyzer.PlanAnalysis) *Message {
 		return nil
 	}
 	text := fmt.Sprintf("Parallel gather reads %.0f rows but LIMIT keeps %.0f — consider adding an index or reducing parallelism", candidate.EstimatedRows, candidate.ActualTotalRows)
-	return &Message{Severity: SeverityWarning, Text: text, Anchor: AnchorID(candidate)}
+	return &Message{Rule: "parallel-limit", Severity: SeverityWarning, Text: text, Anchor: AnchorID(candidate)}
+}
+
+func limitWasteMessage(analysis *analyzer.PlanAnalysis) *Message {
+	if analysis == nil || analysis.Limit == nil || analysis.Limit.WastedNode == nil {
+		return nil
+	}
+	cfg := settings(analysis).Insights
+	limit := analysis.Limit
+	ratio := limit.WasteRatio()
+	if ratio < cfg.LimitWasteRatio {
+		return nil
+	}
+	var proposal *advisor.Index
+	text := fmt.Sprintf("LIMIT returned %.0f rows (%.2f ms per row) but %s produced %.0f rows (x%.0f)",
+		limit.RowsReturned, limit.CostPerRowMs, CompactLabel(limit.WastedNode), limit.RowsProcessed, ratio)
+	if limit.Sort != nil {
+		text += fmt.Sprintf(" for %s to discard", CompactLabel(limit.Sort))
+		if idx, ok := advisor.ForSort(limit.Sort); ok {
+			if note, found := ignoredIndexNote(analysis, idx, "that order"); found {
+				text += " — " + note
+			} else {
+				text += fmt.Sprintf(" — %s lets the scan stop after the first rows", idx.DDL())
+				proposal = &idx
+			}
+		} else {
+			text += " — an index matching the ORDER BY lets the scan stop after the first rows"
+		}
+	} else {
+		text += " — the subtree does work the LIMIT never needs; tighten the filter or index it"
+	}
+	severity := SeverityWarning
+	if ratio >= cfg.LimitWasteRatio*100 {
+		severity = SeverityCritical
+	}
+	return &Message{Rule: "limit-waste", Severity: severity, Text: text, Anchor: AnchorID(limit.WastedNode), Index: proposal}
 }
 
 func spillMessages(analysis *analyzer.PlanAnalysis) []Message {
 	if analysis == nil || analysis.Root == nil {
 		return nil
 	}
-	cfg := config.Active().Insights
+	cfg := settings(analysis).Insights
 	var candidates []*analyzer.NodeStats
 	walkNodes(analysis.Root, func(node *analyzer.NodeStats) {
 		if node == nil || node.Node == nil {
 			return
 		}
 		tempBlocks := node.Buffers.TempRead + node.Buffers.TempWritten
-		if float64(tempBlocks) < cfg.SpillNewBlocks {
-			return
-		}
 		switch node.Node.NodeType {
-		case "Sort", "Incremental Sort", "Hash", "Hash Join":
+		case "Sort", "Incremental Sort":
+			// Buffers include the children's, so temp blocks under an in-memory or top-N sort came from
+			// below it, while a sort that reports an external method spilled however few blocks BUFFERS saw.
+			switch node.SortClass() {
+			case analyzer.SortDisk:
+				candidates = append(candidates, node)
+			case analyzer.SortUnknown:
+				if float64(tempBlocks) >= cfg.SpillNewBlocks {
+					candidates = append(candidates, node)
+				}
+			}
+		case "Hash", "Hash Join":
+			if float64(tempBlocks) < cfg.SpillNewBlocks {
+				return
+			}
+			// A single batch means the hash table fit in memory; the temp blocks came from another child.
+			if batched, known := node.HashBatched(); known && !batched {
+				return
+			}
 			candidates = append(candidates, node)
 		}
 	})
@@ -222,9 +369,7 @@ func spillMessages(analysis *analyzer.PlanAnalysis) []Message {
 		return nil
 	}
 	sort.Slice(candidates, func(i, j int) bool {
-		ti := candidates[i].Buffers.TempRead + candidates[i].Buffers.TempWritten
-		tj := candidates[j].Buffers.TempRead + candidates[j].Buffers.TempWritten
-		return ti > tj
+		return spillBlocks(candidates[i]) > spillBlocks(candidates[j])
 	})
 	limit := 2
 	if len(candidates) < limit {
@@ -234,7 +379,30 @@ func spillMessages(analysis *analyzer.PlanAnalysis) []Message {
 	for _, node := range candidates[:limit] {
 		tempBlocks := node.Buffers.TempRead + node.Buffers.TempWritten
 		label := CompactLabel(node)
-		text := fmt.Sprintf("%s spilled to disk: %s used %d temp buffers (~%s)", node.Node.NodeType, label, tempBlocks, HumanizeBuffers(tempBlocks))
+		var text string
+		if tempBlocks > 0 {
+			text = fmt.Sprintf("%s spilled to disk: %s used %d temp buffers (~%s)", node.Node.NodeType, label, tempBlocks, HumanizeBuffers(tempBlocks))
+		} else {
+			text = fmt.Sprintf("%s spilled to disk: %s wrote %s to disk", node.Node.NodeType, label, HumanizeBytes(node.SortDiskKB()*1024))
+		}
+		if method := node.Node.SortMethod; method != "" {
+			text += " with " + method
+		}
+		if needed := node.SortMemoryNeededKB(); needed > 0 {
+			text += fmt.Sprintf("; it needs work_mem of at least ~%s to sort in memory", HumanizeBytes(needed*1024))
+		}
+		if hash := node.HashNode(); hash != nil && hash.Node.HashBatches > 1 {
+			text += fmt.Sprintf(" in %.0f hash batches", hash.Node.HashBatches)
+			if hash.Node.OriginalHashBatches > 0 && hash.Node.OriginalHashBatches < hash.Node.HashBatches {
+				text += fmt.Sprintf(" (planned %.0f)", hash.Node.OriginalHashBatches)
+			}
+			if needed := node.HashMemoryNeededKB(); needed > 0 {
+				text += fmt.Sprintf("; a single batch would need about %s of work_mem × hash_mem_multiplier", HumanizeBytes(needed*1024))
+			}
+		}
+		if node.TempFiles > 0 {
+			text += fmt.Sprintf(", %d temp file(s) totalling %s on disk per the server log", node.TempFiles, HumanizeBytes(float64(node.TempFileBytes)))
+		}
 		switch node.Node.NodeType {
 		case "Sort", "Incremental Sort":
 			text += " — consider increasing work_mem or adding a supporting index"
@@ -242,21 +410,30 @@ func spillMessages(analysis *analyzer.PlanAnalysis) []Message {
 			text += " — consider increasing work_mem or rewriting the join"
 		}
 		severity := SeverityWarning
-		if tempBlocks >= 20000 {
+		if blocks := spillBlocks(node); blocks >= 20000 {
 			severity = SeverityCritical
-		} else if tempBlocks < 2000 {
+		} else if blocks < 2000 {
 			severity = SeverityInfo
 		}
-		msgs = append(msgs, Message{Severity: severity, Text: text, Anchor: AnchorID(node)})
+		msgs = append(msgs, Message{Rule: "spill", Severity: severity, Text: text, Anchor: AnchorID(node)})
 	}
 	return msgs
 }
 
+// spillBlocks sizes a spill in blocks: the temp blocks BUFFERS counted, or the disk space a sort reported
+// when BUFFERS was off.
+func spillBlocks(n *analyzer.NodeStats) int64 {
+	if blocks := n.Buffers.TempRead + n.Buffers.TempWritten; blocks > 0 {
+		return blocks
+	}
+	return int64(n.SortDiskKB() / 8)
+}
+
 func nestedLoopMessages(analysis *analyzer.PlanAnalysis) []Message {
 	if analysis == nil || analysis.Root == nil {
 		return nil
 	}
-	cfg := config.Active().Insights
+	cfg := settings(analysis).Insights
 	var msgs []Message
 	walkNodes(analysis.Root, func(node *analyzer.NodeStats) {
 		if node == nil || node.Node == nil || node.Node.NodeType != "Nested Loop" {
@@ -280,7 +457,7 @@ func nestedLoopMessages(analysis *analyzer.PlanAnalysis) []Message {
 			} else if child.ActualLoops < cfg.NestedLoopWarnLoops*2 {
 				severity = SeverityInfo
 			}
-			msgs = append(msgs, Message{Severity: severity, Text: text, Anchor: AnchorID(node)})
+			msgs = append(msgs, Message{Rule: "nested-loop", Severity: severity, Text: text, Anchor: AnchorID(node)})
 			break
 		}
 	})
@@ -306,17 +483,32 @@ func NodeLabel(node *analyzer.NodeStats) string {
 		return ""
 	}
 	label := node.Node.NodeType
-	if node.Node.RelationName != "" {
-		label = fmt.Sprintf("%s %s", label, node.Node.RelationName)
-		if node.Node.Alias != "" && node.Node.Alias != node.Node.RelationName {
+	target := node.Node.RelationName
+	if target == "" {
+		target = node.Node.CTEName
+	}
+	if target != "" {
+		label = fmt.Sprintf("%s %s", label, target)
+		if node.Node.Alias != "" && node.Node.Alias != target {
 			label = fmt.Sprintf("%s (%s)", label, node.Node.Alias)
 		}
 	} else if node.Node.Alias != "" {
 		label = fmt.Sprintf("%s (%s)", label, node.Node.Alias)
 	}
+	if name := subplanName(node.Node.SubplanName); name != "" {
+		label = fmt.Sprintf("%s: %s", name, label)
+	}
 	return label
 }
 
+// subplanName shortens "InitPlan 1 (returns $0)" to "InitPlan 1"; the returned parameters add nothing to a label.
+func subplanName(name string) string {
+	if i := strings.Index(name, " ("); i >= 0 {
+		name = name[:i]
+	}
+	return name
+}
+
 // CompactLabel shortens long labels for inline summaries.
 func CompactLabel(node *analyzer.NodeStats) string {
 	label := NodeLabel(node)
@@ -332,7 +524,11 @@ func HumanizeBuffers(blocks int64) string {
 		return "0"
 	}
 	const blockSize = 8192
-	bytes := float64(blocks * blockSize)
+	return HumanizeBytes(float64(blocks * blockSize))
+}
+
+// HumanizeBytes converts a byte count into a readable size.
+func HumanizeBytes(bytes float64) string {
 	switch {
 	case bytes >= 1<<30:
 		return fmt.Sprintf("%.2f GiB", bytes/(1<<30))
@@ -345,6 +541,28 @@ func HumanizeBuffers(blocks int64) string {
 	}
 }
 
+// SummarizeIOTime lists the non-zero I/O timings, e.g. "shared read 12.40 ms, temp write 3.10 ms".
+func SummarizeIOTime(b analyzer.BufferTotals) string {
+	timings := []struct {
+		label string
+		ms    float64
+	}{
+		{"shared read", b.SharedReadTimeMs},
+		{"shared write", b.SharedWriteTimeMs},
+		{"local read", b.LocalReadTimeMs},
+		{"local write", b.LocalWriteTimeMs},
+		{"temp read", b.TempReadTimeMs},
+		{"temp write", b.TempWriteTimeMs},
+	}
+	var parts []string
+	for _, t := range timings {
+		if t.ms > 0 {
+			parts = append(parts, fmt.Sprintf("%s %.2f ms", t.label, t.ms))
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
 // SummarizeTotalBuffers builds a human readable total buffer summary.
 func SummarizeTotalBuffers(total int64) string {
 	if total <= 0 {
@@ -357,10 +575,17 @@ func SummarizeTotalBuffers(total int64) string {
 func NormalizeWhitespace(s string) string {
 	return strings.Join(strings.Fields(s), " ")
 }
+
+// AnchorID returns the HTML id that links insights to a node's card, "node-0-2-1" for the node at Path
+// "0.2.1", so two nodes with the same label stay distinct. Nodes built without a path fall back to their
+// label.
 func AnchorID(node *analyzer.NodeStats) string {
 	if node == nil {
 		return ""
 	}
+	if node.Path != "" {
+		return "node-" + strings.ReplaceAll(node.Path, ".", "-")
+	}
 	label := NodeLabel(node)
 	label = strings.ToLower(label)
 	label = strings.ReplaceAll(label, " ", "-")
@@ -369,6 +594,7 @@ func AnchorID(node *analyzer.NodeStats) string {
 	label = strings.ReplaceAll(label, "(", "")
 	label = strings.ReplaceAll(label, ")", "")
 	label = strings.ReplaceAll(label, ",", "")
+	label = strings.ReplaceAll(label, ":", "")
 	label = strings.ReplaceAll(label, "--", "-")
 	return label
 }
@@ -386,13 +612,13 @@ func workerImbalanceMessages(analysis *analyzer.PlanAnalysis) []Message {
 		limit = len(candidates)
 	}
 	var msgs []Message
-	cfg := config.Active().Insights
+	cfg := settings(analysis).Insights
 	for _, item := range candidates[:limit] {
 		n := item.node
 		ratio := n.ActualTotalRows / (n.EstimatedRows + 1e-9)
 		if ratio >= cfg.RowEstimateCriticalHigh || ratio <= cfg.RowEstimateCriticalLow {
 			text := fmt.Sprintf("Parallel imbalance: %s returned %.0f rows vs %.0f expected (x%.2f) — check work_mem or join strategy", CompactLabel(n), n.ActualTotalRows, n.EstimatedRows, n.RowEstimateFactor)
-			msgs = append(msgs, Message{Severity: SeverityWarning, Text: text, Anchor: AnchorID(n)})
+			msgs = append(msgs, Message{Rule: "worker-imbalance", Severity: SeverityWarning, Text: text, Anchor: AnchorID(n)})
 		}
 	}
 	return msgs
@@ -439,7 +665,7 @@ func workerShortfallMessages(analysis *analyzer.PlanAnalysis) []Message {
 			if launched == 0 {
 				severity = SeverityCritical
 			}
-			msgs = append(msgs, Message{Severity: severity, Text: text, Anchor: AnchorID(n)})
+			msgs = append(msgs, Message{Rule: "worker-shortfall", Severity: severity, Text: text, Anchor: AnchorID(n)})
 		}
 		for _, child := range n.Children {
 			walk(child)
@@ -0,0 +1,693 @@
+package insight_test
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/mickamy/xplain/internal/analyzer"
+	"github.com/mickamy/xplain/internal/config"
+	"github.com/mickamy/xplain/internal/insight"
+	"github.com/mickamy/xplain/internal/model"
+	"github.com/mickamy/xplain/test"
+)
+
+func findMessage(messages []insight.Message, prefix string) *insight.Message {
+	for i := range messages {
+		if strings.HasPrefix(messages[i].Text, prefix) {
+			return &messages[i]
+		}
+	}
+	return nil
+}
+
+func TestPaginationMessage(t *testing.T) {
+	analysis := test.LoadSampleAnalysis(t, "pagination_offset.json")
+
+	msg := findMessage(insight.BuildMessages(analysis), "Pagination:")
+	if msg == nil {
+		t.Fatalf("expected pagination insight")
+	}
+	if !strings.Contains(msg.Text, "CREATE INDEX ON orders (status, created_at DESC)") {
+		t.Fatalf("expected keyset index suggestion, got %q", msg.Text)
+	}
+
+	// A row comparison sorts every column one way, so mixed directions need the expanded predicate.
+	keys := analysis.Limit.Sort.Node.SortKey
+	analysis.Limit.Sort.Node.SortKey = []string{"created_at DESC", "id"}
+	msg = findMessage(insight.BuildMessages(analysis), "Pagination:")
+	if msg == nil || !strings.Contains(msg.Text, "WHERE created_at < :last_created_at OR "+
+		"(created_at = :last_created_at AND id > :last_id) ORDER BY created_at DESC, id") {
+		t.Fatalf("expected an expanded keyset predicate for mixed directions, got %+v", msg)
+	}
+	analysis.Limit.Sort.Node.SortKey = keys
+
+	// An OFFSET in a comment, a literal or a subquery says nothing about the rows the outer query skips.
+	analysis.Limit.RowsSkipped = 0
+	analysis.QueryText = "SELECT * FROM orders WHERE note <> 'offset 99999' /* OFFSET 5000 */" +
+		" AND id IN (SELECT id FROM old OFFSET 90000) ORDER BY created_at LIMIT 20"
+	if msg := findMessage(insight.BuildMessages(analysis), "Pagination:"); msg != nil {
+		t.Fatalf("expected no pagination insight without a top-level OFFSET, got %q", msg.Text)
+	}
+	analysis.QueryText = "SELECT * FROM orders ORDER BY created_at LIMIT 20 OFFSET 90000"
+	if msg := findMessage(insight.BuildMessages(analysis), "Pagination:"); msg == nil || !strings.Contains(msg.Text, "skips 90000 rows") {
+		t.Fatalf("expected the query's OFFSET to stand in for the plan, got %+v", msg)
+	}
+}
+
+func TestIndexAdviceWithExistingIndex(t *testing.T) {
+	analysis := test.LoadSampleAnalysis(t, "pagination_offset.json")
+	analysis.Catalog = &model.Catalog{Indexes: []model.Index{{
+		Table:   "orders",
+		Name:    "orders_status_created_idx",
+		Method:  "btree",
+		Valid:   true,
+		Columns: []model.IndexColumn{{Expr: "status"}, {Expr: "created_at"}},
+	}}}
+	messages := insight.BuildMessages(analysis)
+
+	msg := findMessage(messages, "Pagination:")
+	if msg == nil || !strings.Contains(msg.Text, "backed by existing index orders_status_created_idx") {
+		t.Fatalf("expected pagination to reuse the existing index, got %+v", msg)
+	}
+	msg = findMessage(messages, "LIMIT returned")
+	if msg == nil || strings.Contains(msg.Text, "CREATE INDEX") || !strings.Contains(msg.Text, "planner ignored it") {
+		t.Fatalf("expected LIMIT insight to flag the ignored index, got %+v", msg)
+	}
+
+	analysis = test.LoadSampleAnalysis(t, "function_filter.json")
+	analysis.Catalog = &model.Catalog{Indexes: []model.Index{{
+		Table:   "users",
+		Name:    "users_lower_email_idx",
+		Method:  "btree",
+		Valid:   true,
+		Columns: []model.IndexColumn{{Expr: "lower(email::text)"}},
+	}}}
+	msg = findMessage(insight.BuildMessages(analysis), "Per-row function call:")
+	if msg == nil || strings.Contains(msg.Text, "CREATE INDEX") || !strings.Contains(msg.Text, "users_lower_email_idx") {
+		t.Fatalf("expected function insight to flag the ignored expression index, got %+v", msg)
+	}
+}
+
+func TestPaginationMessageSkipsPlainLimit(t *testing.T) {
+	analysis := test.LoadSampleAnalysis(t, "pgbench_hot.json")
+	if msg := findMessage(insight.BuildMessages(analysis), "Pagination:"); msg != nil {
+		t.Fatalf("unexpected pagination insight: %q", msg.Text)
+	}
+}
+
+func TestFullAggregateMessage(t *testing.T) {
+	analysis := test.LoadSampleAnalysis(t, "count_all.json")
+
+	msg := findMessage(insight.BuildMessages(analysis), "Full-table aggregate:")
+	if msg == nil {
+		t.Fatalf("expected full-table aggregate insight")
+	}
+	if !strings.Contains(msg.Text, "reltuples") {
+		t.Fatalf("expected approximate count hint, got %q", msg.Text)
+	}
+}
+
+func TestDistinctFanoutMessage(t *testing.T) {
+	analysis := test.LoadSampleAnalysis(t, "distinct_fanout.json")
+
+	msg := findMessage(insight.BuildMessages(analysis), "DISTINCT after join fanout:")
+	if msg == nil {
+		t.Fatalf("expected distinct fanout insight")
+	}
+	if !strings.Contains(msg.Text, "Hash Join") {
+		t.Fatalf("expected fanout join to be named, got %q", msg.Text)
+	}
+}
+
+func TestFilterFunctionMessage(t *testing.T) {
+	analysis := test.LoadSampleAnalysis(t, "function_filter.json")
+
+	msg := findMessage(insight.BuildMessages(analysis), "Per-row function call:")
+	if msg == nil {
+		t.Fatalf("expected per-row function insight")
+	}
+	if !strings.Contains(msg.Text, "CREATE INDEX ON users (lower((email)::text))") {
+		t.Fatalf("expected expression index suggestion, got %q", msg.Text)
+	}
+}
+
+func TestOutputFunctionMessage(t *testing.T) {
+	// The Sort repeats the scan's output, so only the scan that computes the call is named.
+	analysis, err := analyzer.Analyze(&model.Explain{Plan: &model.PlanNode{
+		NodeType: "Sort", ActualTotalTime: 900, ActualRows: 1e6, ActualLoops: 1,
+		Output: []string{"id", "(slugify(title))", "(count(*) OVER (?))"},
+		Children: []*model.PlanNode{{
+			NodeType: "Seq Scan", RelationName: "posts", ActualTotalTime: 800, ActualRows: 1e6, ActualLoops: 1,
+			Output: []string{"id", "slugify(title)", "now()"},
+		}},
+	}})
+	if err != nil {
+		t.Fatalf("analyze: %v", err)
+	}
+	var found []insight.Message
+	for _, msg := range insight.BuildMessages(analysis) {
+		if strings.HasPrefix(msg.Text, "Per-row function call:") {
+			found = append(found, msg)
+		}
+	}
+	if len(found) != 1 || !strings.Contains(found[0].Text, "Seq Scan posts evaluates slugify() in its output list for 1000000 rows") || found[0].Index != nil {
+		t.Fatalf("expected one output list insight on the scan without an index, got %+v", found)
+	}
+}
+
+func TestForeignKeyTriggerMessage(t *testing.T) {
+	analysis := test.LoadSampleAnalysis(t, "fk_delete.json")
+
+	msg := findMessage(insight.BuildMessages(analysis), "Foreign key checks:")
+	if msg == nil {
+		t.Fatalf("expected foreign key trigger insight")
+	}
+	if !strings.Contains(msg.Text, "orders_customer_id_fkey") || msg.Severity != insight.SeverityCritical {
+		t.Fatalf("unexpected foreign key insight: %+v", msg)
+	}
+}
+
+func TestWideRowMessage(t *testing.T) {
+	analysis := test.LoadSampleAnalysis(t, "wide_rows.json")
+
+	msg := findMessage(insight.BuildMessages(analysis), "Probable TOAST reads:")
+	if msg == nil {
+		t.Fatalf("expected TOAST insight")
+	}
+	if !strings.Contains(msg.Text, "MiB payload") {
+		t.Fatalf("expected payload estimate, got %q", msg.Text)
+	}
+
+	analysis.Root.PercentExclusive = 0.05
+	if msg := findMessage(insight.BuildMessages(analysis), "Probable TOAST reads:"); msg != nil {
+		t.Fatalf("expected scans below insights.wide_row_min_percent to be left out, got %q", msg.Text)
+	}
+	cfg := config.Default()
+	cfg.Insights.WideRowMinPercent = 0.01
+	config.Use(cfg)
+	t.Cleanup(func() { config.Use(config.Default()) })
+	if msg := findMessage(insight.BuildMessages(analysis), "Probable TOAST reads:"); msg == nil {
+		t.Fatalf("expected a lower insights.wide_row_min_percent to include the scan")
+	}
+}
+
+func TestResultSizeMessage(t *testing.T) {
+	analysis := test.LoadSampleAnalysis(t, "wide_rows.json")
+
+	msg := findMessage(insight.BuildMessages(analysis), "Large result:")
+	if msg == nil {
+		t.Fatalf("expected result size insight")
+	}
+	if msg.Rule != "result-size" || !strings.Contains(msg.Text, "returns 100000 rows of ~2140 bytes (~204.09 MiB)") {
+		t.Fatalf("unexpected result size insight %+v", msg)
+	}
+	if msg := findMessage(insight.BuildMessages(test.LoadSampleAnalysis(t, "pgbench_hot.json")), "Large result:"); msg != nil {
+		t.Fatalf("expected no result size insight for pgbench_hot, got %q", msg.Text)
+	}
+}
+
+func TestSortIndexMessage(t *testing.T) {
+	analysis := test.LoadSampleAnalysis(t, "sort_orders.json")
+
+	msg := findMessage(insight.BuildMessages(analysis), "Sort:")
+	if msg == nil {
+		t.Fatalf("expected sort index insight")
+	}
+	if !strings.Contains(msg.Text, "CREATE INDEX ON orders (status, customer_id, created_at DESC)") {
+		t.Fatalf("expected composite ordering index, got %q", msg.Text)
+	}
+
+	analysis.Catalog = &model.Catalog{Indexes: []model.Index{{
+		Table:  "orders",
+		Name:   "orders_status_customer_created_idx",
+		Method: "btree",
+		Valid:  true,
+		Columns: []model.IndexColumn{
+			{Expr: "status"},
+			{Expr: "customer_id"},
+			{Expr: "created_at", Desc: true, NullsFirst: true},
+		},
+	}}}
+	msg = findMessage(insight.BuildMessages(analysis), "Sort:")
+	if msg == nil || !strings.Contains(msg.Text, "orders_status_customer_created_idx already provides that order but the planner ignored it") {
+		t.Fatalf("expected existing index to be reported, got %+v", msg)
+	}
+}
+
+func TestRedundantIndexMessage(t *testing.T) {
+	analysis := test.LoadSampleAnalysis(t, "sort_orders.json")
+	analysis.Catalog = &model.Catalog{Indexes: []model.Index{
+		{Table: "orders", Name: "orders_status_idx", Method: "btree", Valid: true, Columns: []model.IndexColumn{{Expr: "status"}}},
+		{Table: "orders", Name: "orders_status_customer_idx", Method: "btree", Valid: true, Columns: []model.IndexColumn{{Expr: "status"}, {Expr: "customer_id"}}},
+	}}
+
+	msg := findMessage(insight.BuildMessages(analysis), "Redundant index:")
+	if msg == nil {
+		t.Fatalf("expected redundant index insight")
+	}
+	if !strings.Contains(msg.Text, "orders_status_idx (status) is a leading prefix of orders_status_customer_idx") ||
+		!strings.Contains(msg.Text, "DROP INDEX orders_status_idx;") {
+		t.Fatalf("unexpected redundant index text %q", msg.Text)
+	}
+	if msg.Anchor == "" {
+		t.Fatalf("expected the insight to anchor to the orders scan")
+	}
+}
+
+func TestUnusedOutputMessage(t *testing.T) {
+	analysis := test.LoadSampleAnalysis(t, "wide_projection.json")
+
+	msg := findMessage(insight.BuildMessages(analysis), "Unused columns:")
+	if msg == nil {
+		t.Fatalf("expected unused output insight")
+	}
+	if !strings.Contains(msg.Text, "Hash carries 7 columns") || !strings.Contains(msg.Text, "reads only 2") || strings.Contains(msg.Text, "c.name,") {
+		t.Fatalf("unexpected unused output text %q", msg.Text)
+	}
+}
+
+func TestMemoryBudgetMessage(t *testing.T) {
+	analysis := test.LoadSampleAnalysis(t, "memory_heavy.json")
+
+	if got := insight.SummarizeMemory(analysis); got != "~207.89 MiB across 2 sort/hash nodes" {
+		t.Fatalf("unexpected memory summary %q", got)
+	}
+	msg := findMessage(insight.BuildMessages(analysis), "Memory:")
+	if msg == nil {
+		t.Fatalf("expected memory budget insight")
+	}
+	if msg.Severity != insight.SeverityWarning || !strings.Contains(msg.Text, "Hash 120.00 MiB, Sort 87.89 MiB") {
+		t.Fatalf("unexpected memory insight %+v", msg)
+	}
+}
+
+func TestCostModelMessage(t *testing.T) {
+	analysis := test.LoadSampleAnalysis(t, "cost_skew.json")
+
+	msg := findMessage(insight.BuildMessages(analysis), "Cost model:")
+	if msg == nil {
+		t.Fatalf("expected cost model insight")
+	}
+	if !strings.Contains(msg.Text, "20x slower") || !strings.Contains(msg.Text, "random_page_cost") {
+		t.Fatalf("unexpected cost model text %q", msg.Text)
+	}
+	want := "100.0 cost/ms median over 4 nodes (whole plan 5.8); 1 node 10x or more off"
+	if got := insight.SummarizeCostModel(analysis); got != want {
+		t.Fatalf("expected summary %q, got %q", want, got)
+	}
+	if got := insight.FormatCost(analysis.CostModel.Outliers[0]); got != "cost 22500 (5.0/ms, 0.05x the plan)" {
+		t.Fatalf("unexpected node cost %q", got)
+	}
+}
+
+func TestPlanningBufferMessage(t *testing.T) {
+	analysis := test.LoadSampleAnalysis(t, "planning_heavy.json")
+
+	if got := analysis.PlanningBuffers.Total(); got != 20460 {
+		t.Fatalf("expected 20460 planning buffers, got %d", got)
+	}
+	msg := findMessage(insight.BuildMessages(analysis), "Planning I/O:")
+	if msg == nil {
+		t.Fatalf("expected planning I/O insight")
+	}
+	if msg.Severity != insight.SeverityCritical || !strings.Contains(msg.Text, "2210 read from disk") {
+		t.Fatalf("unexpected planning I/O insight %+v", msg)
+	}
+}
+
+func TestAcknowledgedMessages(t *testing.T) {
+	analysis := test.LoadSampleAnalysis(t, "memory_heavy.json")
+	analysis.Acknowledgements = []model.Acknowledgement{
+		{Rule: "hot-spot", Selector: "Seq Scan on orders", Reason: "nightly export"},
+		{Rule: "buffer-churn", Selector: "Seq Scan on customers"},
+	}
+
+	messages := insight.BuildMessages(analysis)
+	hot := findMessage(messages, "Hot spot:")
+	if hot == nil || hot.Rule != "hot-spot" || hot.Acknowledged != "nightly export" {
+		t.Fatalf("expected acknowledged hot spot, got %+v", hot)
+	}
+	if churn := findMessage(messages, "Buffer churn:"); churn == nil || churn.Acknowledged != "" {
+		t.Fatalf("expected buffer churn on orders to stay open, got %+v", churn)
+	}
+}
+
+func TestScorePlan(t *testing.T) {
+	clean := insight.ScorePlan(test.LoadSampleAnalysis(t, "planning_heavy.json"))
+	if clean.Value != 100 || len(clean.Penalties) != 0 {
+		t.Fatalf("expected a clean score, got %+v", clean)
+	}
+
+	analysis := test.LoadSampleAnalysis(t, "sort_orders.json")
+	score := insight.ScorePlan(analysis)
+	if score.Value >= 100 || score.Value < 0 {
+		t.Fatalf("expected a deduction, got %+v", score)
+	}
+	categories := map[string]int{}
+	total := 0
+	for _, p := range score.Penalties {
+		categories[p.Category] = p.Points
+		total += p.Points
+	}
+	if categories["spills"] == 0 || categories["index usage"] == 0 || 100-total != score.Value {
+		t.Fatalf("expected spill and index penalties adding up, got %+v", score)
+	}
+
+	analysis.Acknowledgements = []model.Acknowledgement{{Rule: "spill"}}
+	if acked := insight.ScorePlan(analysis); acked.Value <= score.Value {
+		t.Fatalf("expected acknowledging the spill to raise the score, got %d vs %d", acked.Value, score.Value)
+	}
+
+	// Both scans of the count take over 20% of the runtime; each is a hot spot finding.
+	if hot := findPenalty(insight.ScorePlan(test.LoadSampleAnalysis(t, "count_all.json")), "hotspots"); hot.Findings != 2 {
+		t.Fatalf("expected every hot node to count, got %+v", hot)
+	}
+	// The large scan behind the LIMIT already carries an index proposal and is not penalized twice.
+	if index := findPenalty(insight.ScorePlan(test.LoadSampleAnalysis(t, "pgbench_hot.json")), "index usage"); index.Findings != 1 {
+		t.Fatalf("expected the proposed scan to count once, got %+v", index)
+	}
+}
+
+func findPenalty(score insight.Score, category string) insight.Penalty {
+	for _, p := range score.Penalties {
+		if p.Category == category {
+			return p
+		}
+	}
+	return insight.Penalty{}
+}
+
+func TestSummarizeJIT(t *testing.T) {
+	analysis := test.LoadSampleAnalysis(t, "pgbench_hot.json")
+
+	want := "48.71 ms for 13 functions, 5.0% of execution (emission 40.93 ms, optimization 6.64 ms, generation 1.14 ms)"
+	if got := insight.SummarizeJIT(analysis); got != want {
+		t.Fatalf("unexpected JIT summary %q", got)
+	}
+	if got := insight.SummarizeJIT(test.LoadSampleAnalysis(t, "sort_orders.json")); got != "" {
+		t.Fatalf("expected no JIT summary without JIT, got %q", got)
+	}
+}
+
+func TestSignificantTriggers(t *testing.T) {
+	analysis := test.LoadSampleAnalysis(t, "fk_delete.json")
+
+	triggers := insight.SignificantTriggers(analysis)
+	if len(triggers) != 2 || triggers[0].Trigger.ConstraintName != "orders_customer_id_fkey" || triggers[0].Share < 0.99 {
+		t.Fatalf("expected the foreign key trigger first, got %+v", triggers)
+	}
+	if got := insight.TriggerLabel(triggers[1].Trigger); got != "customers_audit" {
+		t.Fatalf("unexpected trigger label %q", got)
+	}
+	if got := insight.SignificantTriggers(test.LoadSampleAnalysis(t, "pgbench_hot.json")); got != nil {
+		t.Fatalf("expected no trigger section without triggers, got %+v", got)
+	}
+}
+
+func TestPerRowCostMessage(t *testing.T) {
+	analysis := test.LoadSampleAnalysis(t, "function_filter.json")
+
+	scan := analysis.Root
+	if scan.MsPerRow != scan.ExclusiveTimeMs/scan.ActualTotalRows {
+		t.Fatalf("unexpected ms per row %v", scan.MsPerRow)
+	}
+	msg := findMessage(insight.BuildMessages(analysis), "Per-row cost:")
+	if msg == nil {
+		t.Fatalf("expected per-row cost insight")
+	}
+	if msg.Severity != insight.SeverityCritical || !strings.Contains(msg.Text, "212.64 ms/row") || !strings.Contains(msg.Text, "its filter calls lower()") {
+		t.Fatalf("unexpected per-row cost insight %+v", msg)
+	}
+	if got := insight.FormatPerRow(0.0123); got != "12.3 µs/row" {
+		t.Fatalf("unexpected per-row format %q", got)
+	}
+	if msg := findMessage(insight.BuildMessages(test.LoadSampleAnalysis(t, "pgbench_hot.json")), "Per-row cost:"); msg != nil {
+		t.Fatalf("expected no per-row cost insight for pgbench_hot, got %q", msg.Text)
+	}
+}
+
+func TestFilterSeverity(t *testing.T) {
+	messages := insight.BuildMessages(test.LoadSampleAnalysis(t, "pgbench_hot.json"))
+	critical := insight.FilterSeverity(messages, insight.SeverityCritical)
+	if len(critical) == 0 || len(critical) == len(messages) {
+		t.Fatalf("expected the critical filter to keep some of %d messages, got %d", len(messages), len(critical))
+	}
+	for _, msg := range critical {
+		if msg.Severity != insight.SeverityCritical {
+			t.Fatalf("unexpected %s message %q", msg.Severity, msg.Text)
+		}
+	}
+	if got := insight.FilterSeverity(messages, ""); len(got) != len(messages) {
+		t.Fatalf("expected no filter to keep every message, got %d of %d", len(got), len(messages))
+	}
+	if sev, err := insight.ParseSeverity("Warning"); err != nil || sev != insight.SeverityWarning {
+		t.Fatalf("unexpected parse result %q, %v", sev, err)
+	}
+	if _, err := insight.ParseSeverity("urgent"); err == nil {
+		t.Fatalf("expected unknown severities to fail")
+	}
+}
+
+func TestWorkerSkewMessage(t *testing.T) {
+	analysis := test.LoadSampleAnalysis(t, "parallel_skew.json")
+
+	msg := findMessage(insight.BuildMessages(analysis), "Parallel skew:")
+	if msg == nil {
+		t.Fatalf("expected worker skew insight")
+	}
+	if msg.Rule != "worker-skew" || msg.Severity != insight.SeverityCritical ||
+		!strings.Contains(msg.Text, "worker 0 of Parallel Seq Scan events produced 86.7% of the rows (2.6x an even share across 3 processes)") {
+		t.Fatalf("unexpected worker skew insight %+v", msg)
+	}
+	if msg := findMessage(insight.BuildMessages(test.LoadSampleAnalysis(t, "pgbench_hot.json")), "Parallel skew:"); msg != nil {
+		t.Fatalf("expected no worker skew insight for pgbench_hot, got %q", msg.Text)
+	}
+
+	// Workers that split the rows evenly but not the time are reported by their times.
+	analysis, err := analyzer.Analyze(&model.Explain{Plan: &model.PlanNode{
+		NodeType: "Gather", WorkersPlanned: 2, WorkersLaunched: 2, ActualTotalTime: 1000, ActualRows: 2e6, ActualLoops: 1,
+		Children: []*model.PlanNode{{
+			NodeType: "Parallel Seq Scan", RelationName: "events", ActualTotalTime: 900, ActualRows: 1e6, ActualLoops: 2,
+			Workers: []model.Worker{
+				{Number: 0, ActualTotalTime: 900, ActualRows: 1e6, ActualLoops: 1},
+				{Number: 1, ActualTotalTime: 300, ActualRows: 1e6, ActualLoops: 1},
+			},
+		}},
+	}})
+	if err != nil {
+		t.Fatalf("analyze: %v", err)
+	}
+	msg = findMessage(insight.BuildMessages(analysis), "Parallel skew:")
+	if msg == nil || !strings.Contains(msg.Text, "slowest worker of Parallel Seq Scan events took 900.00 ms vs 600.00 ms mean (1.5x) for an even share of the rows") {
+		t.Fatalf("expected a time skew insight, got %+v", msg)
+	}
+}
+
+func TestColdReadMessage(t *testing.T) {
+	analysis := test.LoadSampleAnalysis(t, "pgbench_hot.json")
+
+	msg := findMessage(insight.BuildMessages(analysis), "Cold reads:")
+	if msg == nil {
+		t.Fatalf("expected cold read insight")
+	}
+	if msg.Rule != "cold-reads" || msg.Severity != insight.SeverityCritical ||
+		!strings.Contains(msg.Text, "Seq Scan pgbench_accounts found only 0.0% of its 163935 blocks") {
+		t.Fatalf("unexpected cold read insight %+v", msg)
+	}
+	if got := insight.SummarizeCacheHit(analysis); got != "0.1% (hit 112, read 163935)" {
+		t.Fatalf("unexpected cache hit summary %q", got)
+	}
+	if msg := findMessage(insight.BuildMessages(test.LoadSampleAnalysis(t, "nested_loop_noindex.json")), "Cold reads:"); msg != nil {
+		t.Fatalf("expected no cold read insight for a fully cached plan, got %q", msg.Text)
+	}
+}
+
+func TestLossyBitmapMessage(t *testing.T) {
+	analysis := test.LoadSampleAnalysis(t, "bitmap_lossy.json")
+
+	scan := analysis.Root.Children[0]
+	if !scan.HasWarning(analyzer.WarnLossyBitmap) || scan.RowsRemovedByIndexRecheck != 760984 {
+		t.Fatalf("expected a lossy bitmap warning on %s, got %v", scan.Node.NodeType, scan.WarningTexts())
+	}
+	msg := findMessage(insight.BuildMessages(analysis), "Lossy bitmap:")
+	if msg == nil {
+		t.Fatalf("expected lossy bitmap insight")
+	}
+	if msg.Rule != "lossy-bitmap" || !strings.Contains(msg.Text, "14184 of 16394 heap blocks (86.5%)") ||
+		!strings.Contains(msg.Text, "removed 760984 rows") || !strings.Contains(msg.Text, "work_mem") {
+		t.Fatalf("unexpected lossy bitmap insight %+v", msg)
+	}
+	if msg := findMessage(insight.BuildMessages(test.LoadSampleAnalysis(t, "pgbench_hot.json")), "Lossy bitmap:"); msg != nil {
+		t.Fatalf("expected no lossy bitmap insight without a bitmap scan, got %q", msg.Text)
+	}
+}
+
+func TestHeapFetchMessage(t *testing.T) {
+	analysis := test.LoadSampleAnalysis(t, "index_only_stale.json")
+
+	scan := analysis.Root.Children[0]
+	if !scan.HasWarning(analyzer.WarnHeapFetches) || scan.HeapFetchRatio != 46920.0/48210 {
+		t.Fatalf("expected a heap fetch warning on %s, got %v (ratio %v)", scan.Node.NodeType, scan.WarningTexts(), scan.HeapFetchRatio)
+	}
+	msg := findMessage(insight.BuildMessages(analysis), "Stale visibility map:")
+	if msg == nil || msg.Rule != "heap-fetches" || !strings.Contains(msg.Text, "97.3% of its rows") ||
+		!strings.Contains(msg.Text, "VACUUM orders") {
+		t.Fatalf("unexpected heap fetch insight %+v", msg)
+	}
+	// The CTE sample's index-only scan found every page all-visible.
+	if msg := findMessage(insight.BuildMessages(test.LoadSampleAnalysis(t, "cte_subplan.json")), "Stale visibility map:"); msg != nil {
+		t.Fatalf("expected no heap fetch insight without heap fetches, got %q", msg.Text)
+	}
+}
+
+func TestMemoizeMessages(t *testing.T) {
+	analysis := test.LoadSampleAnalysis(t, "memoize_thrashing.json")
+
+	memoize := analysis.Root.Children[0].Children[1]
+	if ratio, ok := memoize.CacheHitRatio(); !ok || ratio != 0.312 || memoize.WastedInnerExecutions() != 58800 {
+		t.Fatalf("unexpected memoize ratio %v, wasted %v", ratio, memoize.WastedInnerExecutions())
+	}
+	msg := findMessage(insight.BuildMessages(analysis), "Memoize thrashing:")
+	if msg == nil || msg.Rule != "memoize-thrashing" || !strings.Contains(msg.Text, "31.2% of 100000 lookups") ||
+		!strings.Contains(msg.Text, "up to 58800 times") {
+		t.Fatalf("unexpected memoize thrashing insight %+v", msg)
+	}
+
+	// Keys that never repeat leave the cache nothing to answer, whatever its size.
+	distinct, err := analyzer.Analyze(&model.Explain{Plan: &model.PlanNode{
+		NodeType: "Nested Loop", ActualTotalTime: 300, ActualRows: 5000, ActualLoops: 1,
+		Children: []*model.PlanNode{
+			{NodeType: "Seq Scan", RelationName: "events", ActualTotalTime: 20, ActualRows: 5000, ActualLoops: 1},
+			{
+				NodeType: "Memoize", ActualTotalTime: 0.05, ActualRows: 1, ActualLoops: 5000,
+				CacheHits: 12, CacheMisses: 4988,
+				Children: []*model.PlanNode{{NodeType: "Index Scan", RelationName: "users", ActualTotalTime: 0.05, ActualRows: 1, ActualLoops: 4988}},
+			},
+		},
+	}})
+	if err != nil {
+		t.Fatalf("analyze: %v", err)
+	}
+	msg = findMessage(insight.BuildMessages(distinct), "Useless memoize:")
+	if msg == nil || msg.Rule != "memoize-useless" || !strings.Contains(msg.Text, "ran 4988 times anyway") {
+		t.Fatalf("unexpected useless memoize insight %+v", msg)
+	}
+	if got := insight.FormatMemoize(distinct.Root.Children[1]); got != "cache hit 0.2% of 5000 lookups" {
+		t.Fatalf("unexpected memoize summary %q", got)
+	}
+}
+
+func TestSortSpillMessage(t *testing.T) {
+	// Without BUFFERS the sort's own report of an external merge is what tells the spill apart.
+	analysis, err := analyzer.Analyze(&model.Explain{Plan: &model.PlanNode{
+		NodeType: "Sort", SortMethod: "external merge", SortSpaceUsedKB: 20480, SortSpaceType: "Disk",
+		PlanWidth: 40, ActualTotalTime: 400, ActualRows: 1e6, ActualLoops: 1,
+		Children: []*model.PlanNode{{NodeType: "Seq Scan", RelationName: "events", ActualTotalTime: 90, ActualRows: 1e6, ActualLoops: 1}},
+	}})
+	if err != nil {
+		t.Fatalf("analyze: %v", err)
+	}
+	msg := findMessage(insight.BuildMessages(analysis), "Sort spilled to disk:")
+	if msg == nil {
+		t.Fatalf("expected a sort spill insight")
+	}
+	// A million 88-byte tuples need more than the 20 MiB the temp files took.
+	if !strings.Contains(msg.Text, "wrote 20.00 MiB to disk with external merge; it needs work_mem of at least ~83.92 MiB") {
+		t.Fatalf("unexpected sort spill insight %+v", msg)
+	}
+	if got := insight.FormatSort(analysis.Root); got != "sort external merge 20.00 MiB on disk" {
+		t.Fatalf("unexpected sort description %q", got)
+	}
+	if msg := findMessage(insight.BuildMessages(test.LoadSampleAnalysis(t, "pgbench_hot.json")), "Sort spilled"); msg != nil {
+		t.Fatalf("expected no spill insight for a top-N heapsort, got %q", msg.Text)
+	}
+}
+
+func TestHashBatchSpillMessage(t *testing.T) {
+	analysis, err := analyzer.Analyze(&model.Explain{Plan: &model.PlanNode{
+		NodeType: "Hash Join", ActualTotalTime: 900, ActualRows: 1e6, ActualLoops: 1,
+		Buffers: model.Buffers{TempRead: 30000, TempWritten: 30000},
+		Children: []*model.PlanNode{
+			{NodeType: "Seq Scan", RelationName: "orders", ActualTotalTime: 200, ActualRows: 1e6, ActualLoops: 1},
+			{NodeType: "Hash", ActualTotalTime: 300, ActualRows: 5e5, ActualLoops: 1,
+				HashBatches: 8, OriginalHashBatches: 1, PeakMemoryKB: 4096, Buffers: model.Buffers{TempWritten: 12000}},
+		},
+	}})
+	if err != nil {
+		t.Fatalf("analyze: %v", err)
+	}
+	msg := findMessage(insight.BuildMessages(analysis), "Hash Join spilled to disk:")
+	if msg == nil {
+		t.Fatalf("expected a hash spill insight")
+	}
+	if msg.Rule != "spill" || !strings.Contains(msg.Text, "in 8 hash batches (planned 1); a single batch would need about 32.00 MiB") {
+		t.Fatalf("unexpected hash spill insight %+v", msg)
+	}
+}
+
+func TestQueryOverrides(t *testing.T) {
+	analysis := test.LoadSampleAnalysis(t, "pgbench_hot.json")
+	analysis.Name = "top_accounts"
+
+	cfg := config.Default()
+	cfg.Queries = map[string]config.QueryConfig{
+		"top_accounts": {Insights: config.InsightConfig{HotspotCriticalPercent: 0.95}},
+	}
+	config.Use(cfg)
+	t.Cleanup(func() { config.Use(config.Default()) })
+
+	msg := findMessage(insight.BuildMessages(analysis), "Hot spot:")
+	if msg == nil || msg.Severity != insight.SeverityWarning {
+		t.Fatalf("expected the per-query threshold to make the hot spot a warning, got %+v", msg)
+	}
+	analysis.Name = ""
+	if msg := findMessage(insight.BuildMessages(analysis), "Hot spot:"); msg == nil || msg.Severity != insight.SeverityCritical {
+		t.Fatalf("expected other queries to keep the global threshold, got %+v", msg)
+	}
+}
+
+func TestTimeBudget(t *testing.T) {
+	analysis := test.LoadSampleAnalysis(t, "sort_orders.json")
+	if insight.BuildTimeBudget(analysis) != nil || insight.SummarizeTimeBudget(analysis) != "" {
+		t.Fatal("expected no time budget without a measured round trip")
+	}
+
+	analysis.Metadata = &model.Metadata{Client: &model.ClientTiming{WallMs: 1259.775, Rows: 150000, Bytes: 48 << 20}}
+	budget := insight.BuildTimeBudget(analysis)
+	if budget == nil || math.Abs(budget.TransferMs-1000) > 1e-6 {
+		t.Fatalf("expected 1000 ms of transfer time, got %+v", budget)
+	}
+	want := "1259.78 ms round trip: planning 0.14 ms, execution 259.63 ms, network/transfer 1000.00 ms (79.4%) for 150000 rows (~48.00 MiB)"
+	if got := insight.SummarizeTimeBudget(analysis); got != want {
+		t.Fatalf("unexpected time budget %q", got)
+	}
+	msg := findMessage(insight.BuildMessages(analysis), "Transfer time:")
+	if msg == nil || msg.Severity != insight.SeverityWarning || !strings.Contains(msg.Text, "sending 150000 rows (~48.00 MiB)") {
+		t.Fatalf("unexpected transfer insight %+v", msg)
+	}
+
+	// A round trip close to the server's own time leaves nothing worth flagging.
+	analysis.Metadata.Client.WallMs = 265
+	if msg := findMessage(insight.BuildMessages(analysis), "Transfer time:"); msg != nil {
+		t.Fatalf("expected no transfer insight, got %q", msg.Text)
+	}
+}
+
+func TestNodeLabelNamesCTEsAndSubPlans(t *testing.T) {
+	analysis := test.LoadSampleAnalysis(t, "cte_subplan.json")
+
+	root := analysis.Root
+	if got := insight.NodeLabel(root); got != "CTE Scan recent_orders (r)" {
+		t.Fatalf("unexpected CTE scan label %q", got)
+	}
+	if got := insight.NodeLabel(root.Children[0]); got != "CTE recent_orders: Seq Scan orders" {
+		t.Fatalf("unexpected CTE label %q", got)
+	}
+	if got := insight.NodeLabel(root.Children[1]); got != "SubPlan 1: Aggregate" {
+		t.Fatalf("unexpected SubPlan label %q", got)
+	}
+	if got := insight.AnchorID(root.Children[1]); got != "node-0-1" {
+		t.Fatalf("unexpected SubPlan anchor %q", got)
+	}
+}
@@ -0,0 +1,31 @@
+package insight
+
+import (
+	"fmt"
+
+	"github.com/mickamy/xplain/internal/analyzer"
+)
+
+// SummarizeIOWait describes how much of the execution time went to waiting on block I/O, e.g. "55.37 ms,
+// 22.0% of execution: mostly CPU", or "" when track_io_timing recorded nothing. Plans spending half their
+// time or more on I/O are called I/O-bound.
+func SummarizeIOWait(analysis *analyzer.PlanAnalysis) string {
+	if analysis == nil || analysis.IOTimeMs <= 0 || analysis.TotalTimeMs <= 0 {
+		return ""
+	}
+	share := analysis.IOTimeMs / analysis.TotalTimeMs
+	bound := "mostly CPU"
+	if share >= 0.5 {
+		bound = "I/O-bound"
+	}
+	return fmt.Sprintf("%.2f ms, %.1f%% of execution: %s", analysis.IOTimeMs, share*100, bound)
+}
+
+// FormatIOShare describes the I/O time a node spent itself, e.g. "self 24.16 ms, 43.6% of I/O", or ""
+// when it waited on none.
+func FormatIOShare(n *analyzer.NodeStats) string {
+	if n.IOTimeMs <= 0 {
+		return ""
+	}
+	return fmt.Sprintf("self %.2f ms, %.1f%% of I/O", n.IOTimeMs, n.PercentIO*100)
+}
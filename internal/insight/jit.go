@@ -0,0 +1,43 @@
+package insight
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/mickamy/xplain/internal/analyzer"
+)
+
+// SummarizeJIT renders the JIT compilation time for summary headers, its share of execution and the stages
+// it went to, or "" when the plan did not use JIT.
+func SummarizeJIT(analysis *analyzer.PlanAnalysis) string {
+	if analysis == nil || analysis.JIT == nil {
+		return ""
+	}
+	jit := analysis.JIT
+	text := fmt.Sprintf("%.2f ms for %d functions", jit.TotalMs, jit.Functions)
+	if analysis.ExecutionTimeMs > 0 {
+		text += fmt.Sprintf(", %.1f%% of execution", jit.TotalMs/analysis.ExecutionTimeMs*100)
+	}
+
+	stages := []struct {
+		label string
+		ms    float64
+	}{
+		{"emission", jit.EmissionMs},
+		{"optimization", jit.OptimizationMs},
+		{"inlining", jit.InliningMs},
+		{"generation", jit.GenerationMs},
+	}
+	sort.SliceStable(stages, func(i, j int) bool { return stages[i].ms > stages[j].ms })
+	var parts []string
+	for _, s := range stages {
+		if s.ms > 0 {
+			parts = append(parts, fmt.Sprintf("%s %.2f ms", s.label, s.ms))
+		}
+	}
+	if len(parts) > 0 {
+		text += " (" + strings.Join(parts, ", ") + ")"
+	}
+	return text
+}
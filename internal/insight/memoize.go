@@ -0,0 +1,61 @@
+package insight
+
+import (
+	"fmt"
+
+	"github.com/mickamy/xplain/internal/analyzer"
+)
+
+// FormatMemoize describes how well a Memoize cache worked, e.g. "cache hit 90.0% of 10000 lookups, 120
+// evictions", or "" for nodes that reported no lookups.
+func FormatMemoize(n *analyzer.NodeStats) string {
+	ratio, ok := n.CacheHitRatio()
+	if !ok {
+		return ""
+	}
+	text := fmt.Sprintf("cache hit %.1f%% of %.0f lookups", ratio*100, n.CacheLookups())
+	if evictions := n.Node.CacheEvictions; evictions > 0 {
+		text += fmt.Sprintf(", %.0f evictions", evictions)
+	}
+	if overflows := n.Node.CacheOverflows; overflows > 0 {
+		text += fmt.Sprintf(", %.0f overflows", overflows)
+	}
+	return text
+}
+
+// memoizeMessages explains the Memoize caches the analyzer found ineffective. A thrashing cache is too small
+// for the keys in use, so it drops entries and reruns the inner side when they come back; a useless one sees
+// keys that hardly repeat, paying for the cache on every lookup while the inner side runs anyway.
+func memoizeMessages(analysis *analyzer.PlanAnalysis) []Message {
+	var msgs []Message
+	walkNodes(analysis.Root, func(n *analyzer.NodeStats) {
+		ratio, _ := n.CacheHitRatio()
+		var rule, text string
+		switch {
+		case n.HasWarning(analyzer.WarnMemoizeThrashing):
+			rule = "memoize-thrashing"
+			text = fmt.Sprintf("Memoize thrashing: %s answered only %.1f%% of %.0f lookups from its cache and "+
+				"evicted %.0f entries", CompactLabel(n), ratio*100, n.CacheLookups(), n.Node.CacheEvictions)
+			if n.Node.CacheOverflows > 0 {
+				text += fmt.Sprintf(" (%.0f results too large to cache)", n.Node.CacheOverflows)
+			}
+			text += fmt.Sprintf(", rerunning its inner side up to %.0f times for keys it had seen", n.WastedInnerExecutions())
+			if n.Node.PeakMemoryKB > 0 {
+				text += fmt.Sprintf(" — the %s cache is smaller than the keys in use", HumanizeBytes(n.Node.PeakMemoryKB*1024))
+			} else {
+				text += " — the cache is smaller than the keys in use"
+			}
+			text += "; raise work_mem or hash_mem_multiplier, or sort the outer side by the cache key so repeats arrive together"
+		case n.HasWarning(analyzer.WarnMemoizeUseless):
+			rule = "memoize-useless"
+			text = fmt.Sprintf("Useless memoize: %s hit its cache on only %.1f%% of %.0f lookups, so its inner side "+
+				"ran %.0f times anyway — the cache key barely repeats although the planner expected it to; "+
+				"ANALYZE the outer table or SET enable_memoize = off for this query",
+				CompactLabel(n), ratio*100, n.CacheLookups(), n.Node.CacheMisses)
+		default:
+			return
+		}
+		msgs = append(msgs, Message{Rule: rule, Severity: warningSeverity(analysis, n), Text: text, Anchor: AnchorID(n)})
+	})
+	return msgs
+}
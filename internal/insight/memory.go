@@ -0,0 +1,62 @@
+package insight
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mickamy/xplain/internal/analyzer"
+)
+
+// SummarizeMemory renders the plan's estimated peak memory for summary headers, or "" when unknown.
+func SummarizeMemory(analysis *analyzer.PlanAnalysis) string {
+	if analysis == nil || analysis.MemoryKB <= 0 {
+		return ""
+	}
+	return fmt.Sprintf("~%s across %d sort/hash nodes", HumanizeBytes(analysis.MemoryKB*1024), len(analysis.MemoryConsumers()))
+}
+
+func memoryBudgetMessage(analysis *analyzer.PlanAnalysis) *Message {
+	if analysis == nil || analysis.Root == nil {
+		return nil
+	}
+	budget := settings(analysis).Insights.MemoryBudgetKB
+	if budget <= 0 || analysis.MemoryKB <= budget {
+		return nil
+	}
+
+	consumers := analysis.MemoryConsumers()
+	top := make([]string, 0, 3)
+	for _, n := range consumers[:min(3, len(consumers))] {
+		top = append(top, fmt.Sprintf("%s %s", CompactLabel(n), HumanizeBytes(n.MemoryKB*1024)))
+	}
+	text := fmt.Sprintf("Memory: sorts and hashes peaked at ~%s combined (%s), above the %s budget — every concurrent execution needs this much, so lower work_mem/hash_mem_multiplier for this workload or shrink the sorted and hashed inputs",
+		HumanizeBytes(analysis.MemoryKB*1024), strings.Join(top, ", "), HumanizeBytes(budget*1024))
+	severity := SeverityWarning
+	if analysis.MemoryKB >= budget*4 {
+		severity = SeverityCritical
+	}
+	return &Message{Rule: "memory-budget", Severity: severity, Text: text, Anchor: AnchorID(consumers[0])}
+}
+
+// FormatSort describes how a sort ran, e.g. "sort top-N heapsort 26.00 KiB in memory" or "sort external merge
+// 34.36 MiB on disk", or "" for nodes EXPLAIN gave no sort details for.
+func FormatSort(n *analyzer.NodeStats) string {
+	class := n.SortClass()
+	if class == analyzer.SortUnknown {
+		return ""
+	}
+	text := "sort"
+	if method := n.Node.SortMethod; method != "" {
+		text += " " + method
+	}
+	if class == analyzer.SortDisk {
+		if kb := n.SortDiskKB(); kb > 0 {
+			text += " " + HumanizeBytes(kb*1024)
+		}
+		return text + " on disk"
+	}
+	if kb := n.Node.SortSpaceUsedKB; kb > 0 {
+		text += " " + HumanizeBytes(kb*1024)
+	}
+	return text + " in memory"
+}
@@ -0,0 +1,140 @@
+package insight
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/mickamy/xplain/internal/advisor"
+	"github.com/mickamy/xplain/internal/analyzer"
+)
+
+var (
+	offsetClause = regexp.MustCompile(`(?i)\boffset\s+(\d+)`)
+	// sqlNoise matches comments, string literals and quoted identifiers, whose words are not clauses.
+	sqlNoise = regexp.MustCompile(`--[^\n]*|(?s:/\*.*?\*/)|'(?:[^']|'')*'|"(?:[^"]|"")*"`)
+)
+
+// queryOffset reads the OFFSET of the statement's outermost query, ignoring comments, literals and anything
+// in parentheses such as subqueries.
+func queryOffset(sql string) (float64, bool) {
+	text := []byte(sqlNoise.ReplaceAllString(sql, " "))
+	depth := 0
+	for i, c := range text {
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		}
+		if depth > 0 || c == ')' {
+			text[i] = ' '
+		}
+	}
+	m := offsetClause.FindSubmatch(text)
+	if m == nil {
+		return 0, false
+	}
+	offset, err := strconv.ParseFloat(string(m[1]), 64)
+	return offset, err == nil
+}
+
+func paginationMessage(analysis *analyzer.PlanAnalysis) *Message {
+	if analysis == nil || analysis.Limit == nil {
+		return nil
+	}
+	cfg := settings(analysis).Insights
+	limit := analysis.Limit
+
+	// The rows the Limit discarded are what the OFFSET cost; the query text only stands in when the plan
+	// measured none, as without ANALYZE.
+	skipped := limit.RowsSkipped
+	if offset, ok := queryOffset(analysis.QueryText); ok && skipped == 0 {
+		skipped = offset
+	}
+	if skipped < cfg.PaginationOffsetRows || limit.Sort == nil {
+		return nil
+	}
+
+	var proposal *advisor.Index
+	text := fmt.Sprintf("Pagination: OFFSET skips %.0f rows to return %.0f", skipped, limit.RowsReturned)
+	if idx, ok := advisor.ForSort(limit.Sort); ok {
+		backing := idx.DDL()
+		if existing, found := advisor.FindExisting(analysis.Catalog, idx); found {
+			backing = "existing index " + existing.Name
+		} else {
+			proposal = &idx
+		}
+		text += fmt.Sprintf(" — switch to keyset pagination (%s) backed by %s", keysetHint(limit.Sort.Node.SortKey, limit.RowsReturned), backing)
+	} else {
+		text += " — switch to keyset pagination (WHERE sort_key > last_seen) with an index on the ORDER BY columns"
+	}
+	severity := SeverityWarning
+	if skipped >= cfg.PaginationOffsetRows*100 {
+		severity = SeverityCritical
+	}
+	return &Message{Rule: "pagination", Severity: severity, Text: text, Anchor: AnchorID(limit.Sort), Index: proposal}
+}
+
+// keysetHint writes the WHERE clause that continues after the last row seen. Keys sorted in one direction
+// compare as a row, (a, b) > :last_seen; a row comparison orders every column the same way, so keys mixing
+// ASC and DESC get the expanded a < :last_a OR (a = :last_a AND b > :last_b) instead.
+func keysetHint(sortKeys []string, limit float64) string {
+	var cols, ops []string
+	for _, key := range sortKeys {
+		fields := strings.Fields(key)
+		if len(fields) == 0 {
+			continue
+		}
+		op := ">"
+		if len(fields) > 1 && strings.EqualFold(fields[1], "DESC") {
+			op = "<"
+		}
+		cols = append(cols, fields[0])
+		ops = append(ops, op)
+	}
+	order := strings.Join(sortKeys, ", ")
+	mixed := false
+	for _, op := range ops {
+		mixed = mixed || op != ops[0]
+	}
+	if !mixed {
+		lhs := strings.Join(cols, ", ")
+		if len(cols) > 1 {
+			lhs = "(" + lhs + ")"
+		}
+		op := ">"
+		if len(ops) > 0 {
+			op = ops[0]
+		}
+		return fmt.Sprintf("WHERE %s %s :last_seen ORDER BY %s LIMIT %.0f", lhs, op, order, limit)
+	}
+	terms := make([]string, 0, len(cols))
+	for i := range cols {
+		var parts []string
+		for j := range i {
+			parts = append(parts, fmt.Sprintf("%s = %s", cols[j], lastSeen(cols[j])))
+		}
+		parts = append(parts, fmt.Sprintf("%s %s %s", cols[i], ops[i], lastSeen(cols[i])))
+		term := strings.Join(parts, " AND ")
+		if len(parts) > 1 {
+			term = "(" + term + ")"
+		}
+		terms = append(terms, term)
+	}
+	return fmt.Sprintf("WHERE %s ORDER BY %s LIMIT %.0f", strings.Join(terms, " OR "), order, limit)
+}
+
+// lastSeen names the placeholder for a sort column's value in the last row seen, e.g. :last_created_at
+// for o.created_at.
+func lastSeen(col string) string {
+	name := col[strings.LastIndex(col, ".")+1:]
+	name = strings.Trim(strings.Map(func(r rune) rune {
+		if r == '_' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' {
+			return r
+		}
+		return '_'
+	}, name), "_")
+	return ":last_" + name
+}
@@ -0,0 +1,28 @@
+package insight
+
+import (
+	"fmt"
+
+	"github.com/mickamy/xplain/internal/analyzer"
+)
+
+// planningBufferMessage flags plans whose planning phase alone touched many buffers, which usually
+// means loading catalog metadata for many partitions or indexes.
+func planningBufferMessage(analysis *analyzer.PlanAnalysis) *Message {
+	if analysis == nil {
+		return nil
+	}
+	threshold := settings(analysis).Insights.PlanningBufferBlocks
+	b := analysis.PlanningBuffers
+	if threshold <= 0 || b.Total() < threshold {
+		return nil
+	}
+
+	text := fmt.Sprintf("Planning I/O: planning touched %d buffers (~%s, %d read from disk) in %.2f ms — catalog metadata for many partitions or indexes is being loaded; prune partitions or drop unused indexes, keep connections long-lived so the catalog cache stays warm, or use prepared statements to plan once",
+		b.Total(), HumanizeBuffers(b.Total()), b.SharedRead, analysis.PlanningTimeMs)
+	severity := SeverityWarning
+	if analysis.PlanningTimeMs > analysis.ExecutionTimeMs {
+		severity = SeverityCritical
+	}
+	return &Message{Rule: "planning-io", Severity: severity, Text: text}
+}
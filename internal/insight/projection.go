@@ -0,0 +1,47 @@
+package insight
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mickamy/xplain/internal/analyzer"
+)
+
+// bufferingNodes materialise their input, so every carried column costs memory or temp space.
+var bufferingNodes = map[string]struct{}{
+	"Sort":             {},
+	"Incremental Sort": {},
+	"Hash":             {},
+	"Materialize":      {},
+	"Memoize":          {},
+}
+
+func unusedOutputMessages(analysis *analyzer.PlanAnalysis) []Message {
+	if analysis == nil || analysis.Root == nil {
+		return nil
+	}
+	cfg := settings(analysis).Insights
+	var msgs []Message
+	walkNodes(analysis.Root, func(n *analyzer.NodeStats) {
+		if _, ok := bufferingNodes[n.Node.NodeType]; !ok {
+			return
+		}
+		unused := n.UnusedOutput
+		if float64(len(unused)) < cfg.UnusedOutputColumns || n.ActualTotalRows < cfg.UnusedOutputRows {
+			return
+		}
+		shown := unused
+		if len(shown) > 4 {
+			shown = append(shown[:4:4], fmt.Sprintf("%d more", len(unused)-4))
+		}
+		text := fmt.Sprintf("Unused columns: %s carries %d columns (~%.0f bytes per row) for %.0f rows but %s reads only %d — drop %s from the select list or subquery to shrink it",
+			CompactLabel(n), len(n.Node.Output), n.Node.PlanWidth, n.ActualTotalRows, CompactLabel(n.Parent),
+			len(n.Node.Output)-len(unused), strings.Join(shown, ", "))
+		severity := SeverityInfo
+		if n.PercentInclusive >= cfg.HotspotWarningPercent {
+			severity = SeverityWarning
+		}
+		msgs = append(msgs, Message{Rule: "unused-columns", Severity: severity, Text: text, Anchor: AnchorID(n)})
+	})
+	return msgs
+}
@@ -0,0 +1,32 @@
+package insight
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mickamy/xplain/internal/advisor"
+	"github.com/mickamy/xplain/internal/analyzer"
+)
+
+func redundantIndexMessages(analysis *analyzer.PlanAnalysis) []Message {
+	if analysis == nil || analysis.Root == nil || analysis.Catalog == nil {
+		return nil
+	}
+	var msgs []Message
+	for _, r := range advisor.Redundant(analysis.Catalog) {
+		relation := "is a leading prefix of"
+		if r.Duplicate {
+			relation = "duplicates"
+		}
+		text := fmt.Sprintf("Redundant index: %s (%s) %s %s (%s) — %s saves write and vacuum overhead",
+			r.Index.Name, advisor.Keys(r.Index), relation, r.CoveredBy.Name, advisor.Keys(r.CoveredBy), r.DropDDL())
+		var anchor string
+		walkNodes(analysis.Root, func(n *analyzer.NodeStats) {
+			if anchor == "" && n.Node != nil && strings.EqualFold(n.Node.RelationName, r.Index.Table) {
+				anchor = AnchorID(n)
+			}
+		})
+		msgs = append(msgs, Message{Rule: "redundant-index", Severity: SeverityInfo, Text: text, Anchor: anchor})
+	}
+	return msgs
+}
@@ -0,0 +1,103 @@
+package insight
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/mickamy/xplain/internal/analyzer"
+)
+
+// FormatPerRow renders a per-row time in µs below one millisecond and in ms above.
+func FormatPerRow(ms float64) string {
+	if ms < 1 {
+		return fmt.Sprintf("%.1f µs/row", ms*1000)
+	}
+	return fmt.Sprintf("%.2f ms/row", ms)
+}
+
+// FormatRemoved renders the rows a node's filter and join filter discarded, or "" when they discarded none.
+func FormatRemoved(n *analyzer.NodeStats) string {
+	var parts []string
+	if n.RowsRemovedByFilter > 0 {
+		parts = append(parts, fmt.Sprintf("%.0f by filter", n.RowsRemovedByFilter))
+	}
+	if n.RowsRemovedByJoinFilter > 0 {
+		parts = append(parts, fmt.Sprintf("%.0f by join filter", n.RowsRemovedByJoinFilter))
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return "removed " + strings.Join(parts, ", ")
+}
+
+// FormatPruning renders how many partitions an Append or Merge Append scanned and how many runtime pruning
+// skipped, or "" when it pruned none.
+func FormatPruning(n *analyzer.NodeStats) string {
+	p, ok := n.Pruning()
+	if !ok || p.Pruned() == 0 {
+		return ""
+	}
+	var parts []string
+	if p.Removed > 0 {
+		parts = append(parts, fmt.Sprintf("%d at startup", p.Removed))
+	}
+	if p.NeverExecuted > 0 {
+		parts = append(parts, fmt.Sprintf("%d never executed", p.NeverExecuted))
+	}
+	return fmt.Sprintf("partitions %d of %d scanned, pruned %s", p.Scanned(), p.Planned+p.Removed, strings.Join(parts, ", "))
+}
+
+// perRowCostMessages flags scans and filtering nodes that spend an extreme amount of self time on each row
+// they return. Other nodes are skipped: an aggregate or LIMIT returns few rows by design, so its time per
+// output row says little.
+func perRowCostMessages(analysis *analyzer.PlanAnalysis) []Message {
+	if analysis == nil || analysis.Root == nil {
+		return nil
+	}
+	cfg := settings(analysis).Insights
+	if cfg.PerRowWarningMs <= 0 {
+		return nil
+	}
+	var costly []*analyzer.NodeStats
+	walkNodes(analysis.Root, func(n *analyzer.NodeStats) {
+		if n.Node == nil || (n.Node.RelationName == "" && n.Node.Filter == "") {
+			return
+		}
+		if n.ExclusiveTimeMs >= cfg.PerRowMinSelfMs && n.MsPerRow >= cfg.PerRowWarningMs {
+			costly = append(costly, n)
+		}
+	})
+	sort.SliceStable(costly, func(i, j int) bool { return costly[i].MsPerRow > costly[j].MsPerRow })
+
+	var msgs []Message
+	for _, n := range costly {
+		text := fmt.Sprintf("Per-row cost: %s spends %s (self %.2f ms for %.0f rows)",
+			CompactLabel(n), FormatPerRow(n.MsPerRow), n.ExclusiveTimeMs, n.ActualTotalRows)
+		switch {
+		case len(n.FilterFunctions) > 0:
+			names := make([]string, 0, len(n.FilterFunctions))
+			for _, call := range n.FilterFunctions {
+				names = append(names, call.Name+"()")
+			}
+			text += fmt.Sprintf(" — its filter calls %s for every row; make the functions cheaper, index their result or filter on plain columns first", strings.Join(names, ", "))
+		case len(n.ConditionFunctions)+len(n.OutputFunctions) > 0:
+			names := make([]string, 0, len(n.ConditionFunctions)+len(n.OutputFunctions))
+			for _, call := range append(slices.Clone(n.ConditionFunctions), n.OutputFunctions...) {
+				names = append(names, call.Name+"()")
+			}
+			text += fmt.Sprintf(" — it calls %s for every row; make the functions cheaper or compute them once before the rows fan out", strings.Join(names, ", "))
+		case n.RowsRemovedByFilter+n.RowsRemovedByJoinFilter > 0:
+			text += fmt.Sprintf(" — it discards %.0f rows to find them; a more selective index or join condition would skip that work", n.RowsRemovedByFilter+n.RowsRemovedByJoinFilter)
+		default:
+			text += " — look for expensive expressions, SubPlans or detoasting of large columns in this node"
+		}
+		severity := SeverityWarning
+		if n.MsPerRow >= cfg.PerRowCriticalMs {
+			severity = SeverityCritical
+		}
+		msgs = append(msgs, Message{Rule: "per-row-cost", Severity: severity, Text: text, Anchor: AnchorID(n)})
+	}
+	return msgs
+}
@@ -0,0 +1,105 @@
+package insight
+
+import (
+	"strings"
+
+	"github.com/mickamy/xplain/internal/analyzer"
+)
+
+// Score grades a plan's health from 0 to 100, where 100 means none of the scored problems were found.
+type Score struct {
+	Value     int       `json:"score"`
+	Penalties []Penalty `json:"penalties,omitempty"`
+}
+
+// Penalty is the deduction one category of findings made from a Score.
+type Penalty struct {
+	Category string `json:"category"`
+	Points   int    `json:"points"`
+	// Findings counts the messages (or nodes) behind the deduction.
+	Findings int `json:"findings"`
+}
+
+// scoreCategory deducts points per finding by severity, up to a cap. Info-level findings are free.
+type scoreCategory struct {
+	name              string
+	warning, critical int
+	limit             int
+}
+
+var (
+	scoreHotspots   = scoreCategory{name: "hotspots", warning: 5, critical: 10, limit: 15}
+	scoreDrift      = scoreCategory{name: "estimate drift", warning: 5, critical: 10, limit: 20}
+	scoreSpills     = scoreCategory{name: "spills", warning: 10, critical: 20, limit: 30}
+	scoreIndexUsage = scoreCategory{name: "index usage", warning: 10, critical: 15, limit: 35}
+)
+
+// scoreMinHotspotMs keeps sub-millisecond plans from losing points merely because one node dominates them.
+const scoreMinHotspotMs = 1.0
+
+// ScorePlan rates a plan from its hot spots (every hot node taking at least a millisecond), estimate
+// drift, spills and index usage (index proposals, and large sequential scans that carry none). Acknowledged
+// insights do not count against it.
+func ScorePlan(analysis *analyzer.PlanAnalysis) Score {
+	if analysis == nil {
+		return Score{}
+	}
+	cfg := settings(analysis).Insights
+	findings := map[string][]Severity{}
+	proposed := map[string]bool{}
+	for _, msg := range BuildMessages(analysis) {
+		if msg.Index != nil {
+			proposed[msg.Anchor] = true
+		}
+		if msg.Acknowledged != "" {
+			continue
+		}
+		switch {
+		case msg.Index != nil:
+			findings[scoreIndexUsage.name] = append(findings[scoreIndexUsage.name], msg.Severity)
+		case msg.Rule == "estimate-drift":
+			findings[scoreDrift.name] = append(findings[scoreDrift.name], msg.Severity)
+		case msg.Rule == "spill":
+			findings[scoreSpills.name] = append(findings[scoreSpills.name], msg.Severity)
+		}
+	}
+	// The hot spot insight names the hottest node only; the score weighs every hot node.
+	var hot []Message
+	for _, n := range analysis.HotNodes {
+		if severity := severityForHotspot(cfg, n); severity != SeverityInfo && n.ExclusiveTimeMs >= scoreMinHotspotMs {
+			hot = append(hot, Message{Rule: "hot-spot", Severity: severity, Anchor: AnchorID(n)})
+		}
+	}
+	acknowledge(analysis, hot)
+	for _, msg := range hot {
+		if msg.Acknowledged == "" {
+			findings[scoreHotspots.name] = append(findings[scoreHotspots.name], msg.Severity)
+		}
+	}
+	for _, n := range analysis.Nodes() {
+		if strings.Contains(n.Node.NodeType, "Seq Scan") && n.Buffers.Total() > cfg.SeqScanBufferHint && !proposed[AnchorID(n)] {
+			findings[scoreIndexUsage.name] = append(findings[scoreIndexUsage.name], SeverityWarning)
+		}
+	}
+
+	score := Score{Value: 100}
+	for _, category := range []scoreCategory{scoreHotspots, scoreDrift, scoreSpills, scoreIndexUsage} {
+		points := 0
+		for _, severity := range findings[category.name] {
+			switch severity {
+			case SeverityCritical:
+				points += category.critical
+			case SeverityWarning:
+				points += category.warning
+			}
+		}
+		points = min(points, category.limit)
+		if points == 0 {
+			continue
+		}
+		score.Value -= points
+		score.Penalties = append(score.Penalties, Penalty{Category: category.name, Points: points, Findings: len(findings[category.name])})
+	}
+	score.Value = max(score.Value, 0)
+	return score
+}
@@ -0,0 +1,28 @@
+package insight
+
+import (
+	"fmt"
+
+	"github.com/mickamy/xplain/internal/analyzer"
+)
+
+// SummarizeSerialization describes the EXPLAIN (SERIALIZE) block of PostgreSQL 17+, e.g.
+// "12.40 ms, 5.2 MiB as text, 8.1% of execution".
+func SummarizeSerialization(analysis *analyzer.PlanAnalysis) string {
+	if analysis == nil || analysis.Serialization == nil {
+		return ""
+	}
+	s := analysis.Serialization
+	var text string
+	if s.TimeMs > 0 {
+		text = fmt.Sprintf("%.2f ms, ", s.TimeMs)
+	}
+	text += HumanizeBytes(s.OutputKB * 1024)
+	if s.Format != "" {
+		text += " as " + s.Format
+	}
+	if s.TimeMs > 0 && analysis.ExecutionTimeMs > 0 {
+		text += fmt.Sprintf(", %.1f%% of execution", s.TimeMs/analysis.ExecutionTimeMs*100)
+	}
+	return text
+}
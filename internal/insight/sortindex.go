@@ -0,0 +1,63 @@
+package insight
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/mickamy/xplain/internal/advisor"
+	"github.com/mickamy/xplain/internal/analyzer"
+)
+
+func sortIndexMessages(analysis *analyzer.PlanAnalysis) []Message {
+	if analysis == nil || analysis.Root == nil {
+		return nil
+	}
+	cfg := settings(analysis).Insights
+	var sorts []*analyzer.NodeStats
+	walkNodes(analysis.Root, func(n *analyzer.NodeStats) {
+		if n.Node == nil || (n.Node.NodeType != "Sort" && n.Node.NodeType != "Incremental Sort") {
+			return
+		}
+		// Sorts feeding a LIMIT are covered by the LIMIT and pagination insights.
+		if analysis.Limit != nil && analysis.Limit.Sort == n {
+			return
+		}
+		if n.PercentExclusive >= cfg.SortAdvicePercent {
+			sorts = append(sorts, n)
+		}
+	})
+	sort.Slice(sorts, func(i, j int) bool { return sorts[i].ExclusiveTimeMs > sorts[j].ExclusiveTimeMs })
+	if len(sorts) > 2 {
+		sorts = sorts[:2]
+	}
+
+	var msgs []Message
+	for _, n := range sorts {
+		idx, ok := advisor.ForSort(n)
+		if !ok {
+			continue
+		}
+		keys := strings.Join(n.Node.SortKey, ", ")
+		text := fmt.Sprintf("Sort: %s spent %.2f ms ordering %.0f rows by (%s)", CompactLabel(n), n.ExclusiveTimeMs, n.ActualTotalRows, keys)
+		var proposal *advisor.Index
+		note, found := ignoredIndexNote(analysis, idx, "that order")
+		if !found {
+			proposal = &idx
+		}
+		switch {
+		case found:
+			text += " although " + note
+		case analysis.Catalog != nil:
+			text += fmt.Sprintf(" and no index on %s provides it — %s", idx.QualifiedTable(), idx.DDL())
+		default:
+			text += fmt.Sprintf(" — %s would deliver rows pre-sorted", idx.DDL())
+		}
+		severity := SeverityInfo
+		if n.PercentExclusive >= cfg.HotspotWarningPercent {
+			severity = SeverityWarning
+		}
+		msgs = append(msgs, Message{Rule: "sort-index", Severity: severity, Text: text, Anchor: AnchorID(n), Index: proposal})
+	}
+	return msgs
+}
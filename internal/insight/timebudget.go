@@ -0,0 +1,79 @@
+package insight
+
+import (
+	"fmt"
+
+	"github.com/mickamy/xplain/internal/analyzer"
+)
+
+// TimeBudget splits the latency a client observed into planning, execution and the remainder spent sending
+// the result over the network and (de)serializing it.
+type TimeBudget struct {
+	WallMs      float64
+	PlanningMs  float64
+	ExecutionMs float64
+	TransferMs  float64
+	Rows        int64
+	Bytes       int64
+}
+
+// TransferShare is the part of the round trip spent outside planning and execution.
+func (b *TimeBudget) TransferShare() float64 {
+	if b.WallMs <= 0 {
+		return 0
+	}
+	return b.TransferMs / b.WallMs
+}
+
+// BuildTimeBudget compares the client-observed round trip recorded by xplain run with the server's planning
+// and execution times; nil when the round trip was not measured. EXPLAIN ANALYZE timing adds overhead of
+// its own, so the transfer time is a lower bound.
+func BuildTimeBudget(analysis *analyzer.PlanAnalysis) *TimeBudget {
+	if analysis == nil || analysis.Metadata == nil || analysis.Metadata.Client == nil {
+		return nil
+	}
+	client := analysis.Metadata.Client
+	execution := analysis.ExecutionTimeMs
+	if execution <= 0 {
+		execution = analysis.TotalTimeMs
+	}
+	return &TimeBudget{
+		WallMs:      client.WallMs,
+		PlanningMs:  analysis.PlanningTimeMs,
+		ExecutionMs: execution,
+		TransferMs:  max(client.WallMs-analysis.PlanningTimeMs-execution, 0),
+		Rows:        client.Rows,
+		Bytes:       client.Bytes,
+	}
+}
+
+// SummarizeTimeBudget renders the time budget for summary headers, or "" when it was not measured.
+func SummarizeTimeBudget(analysis *analyzer.PlanAnalysis) string {
+	b := BuildTimeBudget(analysis)
+	if b == nil {
+		return ""
+	}
+	return fmt.Sprintf("%.2f ms round trip: planning %.2f ms, execution %.2f ms, network/transfer %.2f ms (%.1f%%) for %d rows (~%s)",
+		b.WallMs, b.PlanningMs, b.ExecutionMs, b.TransferMs, b.TransferShare()*100, b.Rows, HumanizeBytes(float64(b.Bytes)))
+}
+
+// transferMessage flags statements whose latency is mostly spent moving the result to the client rather
+// than executing the plan.
+func transferMessage(analysis *analyzer.PlanAnalysis) *Message {
+	b := BuildTimeBudget(analysis)
+	if b == nil {
+		return nil
+	}
+	cfg := settings(analysis).Insights
+	share := b.TransferShare()
+	if cfg.TransferWarningPercent <= 0 || share < cfg.TransferWarningPercent || b.TransferMs < cfg.TransferMinMs {
+		return nil
+	}
+	text := fmt.Sprintf("Transfer time: %.2f ms of the %.2f ms round trip (%.1f%%) went to sending %d rows (~%s) to the client, not to executing the plan — fetch fewer rows or columns, paginate, or aggregate in the database",
+		b.TransferMs, b.WallMs, share*100, b.Rows, HumanizeBytes(float64(b.Bytes)))
+	severity := SeverityWarning
+	if share >= 0.8 {
+		severity = SeverityCritical
+	}
+	return &Message{Rule: "transfer-time", Severity: severity, Text: text}
+}
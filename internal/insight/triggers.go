@@ -0,0 +1,91 @@
+package insight
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/mickamy/xplain/internal/analyzer"
+	"github.com/mickamy/xplain/internal/model"
+)
+
+// TriggerTime is one trigger's time together with its share of execution time.
+type TriggerTime struct {
+	Trigger model.Trigger
+	Share   float64
+}
+
+// SignificantTriggers lists the plan's triggers, slowest first, when together they take at least
+// insights.trigger_section_percent of execution time; otherwise it returns nil.
+func SignificantTriggers(analysis *analyzer.PlanAnalysis) []TriggerTime {
+	if analysis == nil || len(analysis.Triggers) == 0 {
+		return nil
+	}
+	total := triggerBaseMs(analysis)
+	if total <= 0 || analysis.TriggerTimeMs/total < settings(analysis).Insights.TriggerSectionPercent {
+		return nil
+	}
+	out := make([]TriggerTime, 0, len(analysis.Triggers))
+	for _, trig := range analysis.Triggers {
+		out = append(out, TriggerTime{Trigger: trig, Share: trig.TimeMs / total})
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Trigger.TimeMs > out[j].Trigger.TimeMs })
+	return out
+}
+
+// TriggerLabel names a trigger, with the constraint it implements when it is a constraint trigger.
+func TriggerLabel(trig model.Trigger) string {
+	if trig.ConstraintName != "" {
+		return fmt.Sprintf("%s (%s)", trig.Name, trig.ConstraintName)
+	}
+	return trig.Name
+}
+
+// triggerBaseMs is the execution time trigger shares are relative to. Trigger time is part of the
+// reported execution time but not of the plan tree's.
+func triggerBaseMs(analysis *analyzer.PlanAnalysis) float64 {
+	if analysis.ExecutionTimeMs > 0 {
+		return analysis.ExecutionTimeMs
+	}
+	return analysis.TotalTimeMs + analysis.TriggerTimeMs
+}
+
+func foreignKeyTriggerMessages(analysis *analyzer.PlanAnalysis) []Message {
+	if analysis == nil || analysis.Root == nil || len(analysis.Triggers) == 0 {
+		return nil
+	}
+	op := analysis.Root.Node.Operation
+	if op != "Update" && op != "Delete" {
+		return nil
+	}
+	cfg := settings(analysis).Insights
+	total := triggerBaseMs(analysis)
+	if total <= 0 {
+		return nil
+	}
+
+	var fks []model.Trigger
+	for _, trig := range analysis.Triggers {
+		if trig.IsForeignKey() && trig.TimeMs/total >= cfg.TriggerDominantPercent {
+			fks = append(fks, trig)
+		}
+	}
+	sort.Slice(fks, func(i, j int) bool { return fks[i].TimeMs > fks[j].TimeMs })
+
+	var msgs []Message
+	for _, trig := range fks {
+		constraint := trig.ConstraintName
+		if constraint == "" {
+			constraint = trig.Name
+		}
+		share := trig.TimeMs / total
+		text := fmt.Sprintf("Foreign key checks: %s on %s spent %.2f ms (%.1f%% of execution) across %.0f calls during %s",
+			constraint, trig.Relation, trig.TimeMs, share*100, trig.Calls, op)
+		text += fmt.Sprintf(" — index the referencing columns of %s so each check is an index lookup instead of a scan", constraint)
+		severity := SeverityWarning
+		if share >= cfg.HotspotCriticalPercent {
+			severity = SeverityCritical
+		}
+		msgs = append(msgs, Message{Rule: "foreign-key-checks", Severity: severity, Text: text, Anchor: AnchorID(analysis.Root)})
+	}
+	return msgs
+}
@@ -0,0 +1,31 @@
+package insight
+
+import (
+	"fmt"
+
+	"github.com/mickamy/xplain/internal/analyzer"
+)
+
+// heapFetchMessages flags Index Only Scans that went to the heap for most of their rows. An index-only scan
+// skips the heap only for pages the visibility map marks all-visible, which VACUUM sets and any write to the
+// page clears; on a table that changed since its last vacuum the scan checks the heap row by row and costs
+// as much as a plain Index Scan.
+func heapFetchMessages(analysis *analyzer.PlanAnalysis) []Message {
+	var msgs []Message
+	walkNodes(analysis.Root, func(n *analyzer.NodeStats) {
+		if !n.HasWarning(analyzer.WarnHeapFetches) {
+			return
+		}
+		table := n.Node.RelationName
+		if table == "" {
+			table = "the table"
+		} else if n.Node.Schema != "" {
+			table = n.Node.Schema + "." + table
+		}
+		text := fmt.Sprintf("Stale visibility map: %s fetched %.1f%% of its rows from the heap (%.0f heap fetches), "+
+			"so it works like a plain index scan — VACUUM %s to mark its pages all-visible, and make autovacuum "+
+			"visit it more often if it is written to steadily", CompactLabel(n), n.HeapFetchRatio*100, n.HeapFetches, table)
+		msgs = append(msgs, Message{Rule: "heap-fetches", Severity: warningSeverity(analysis, n), Text: text, Anchor: AnchorID(n)})
+	})
+	return msgs
+}
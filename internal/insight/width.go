@@ -0,0 +1,71 @@
+package insight
+
+import (
+	"fmt"
+
+	"github.com/mickamy/xplain/internal/analyzer"
+)
+
+const pageSize = 8192
+
+// toastReadFactor is how many times more pages than the rows' width explains a scan must
+// touch before the extra reads are attributed to out-of-line (TOAST) values.
+const toastReadFactor = 2.0
+
+// resultSizeMessage estimates what the query sends to the client from the rows and width of the root node
+// and warns when it crosses insights.result_size_warning_kb. Actual rows are used when the plan was
+// analyzed, the planner's estimate otherwise.
+func resultSizeMessage(analysis *analyzer.PlanAnalysis) *Message {
+	if analysis == nil || analysis.Root == nil || analysis.Root.Node == nil {
+		return nil
+	}
+	cfg := settings(analysis).Insights
+	if cfg.ResultSizeWarningKB <= 0 {
+		return nil
+	}
+	root := analysis.Root
+	rows, kind := root.ActualTotalRows, "returns"
+	if root.Node.ActualLoops == 0 {
+		rows, kind = root.Node.PlanRows, "is estimated to return"
+	}
+	size := rows * root.Node.PlanWidth
+	if size < cfg.ResultSizeWarningKB*1024 {
+		return nil
+	}
+	text := fmt.Sprintf("Large result: the query %s %.0f rows of ~%.0f bytes (~%s) to the client — paginate with a keyset LIMIT, aggregate in SQL or stream it with a cursor instead of fetching it all at once",
+		kind, rows, root.Node.PlanWidth, HumanizeBytes(size))
+	return &Message{Rule: "result-size", Severity: SeverityWarning, Text: text, Anchor: AnchorID(root)}
+}
+
+func wideRowMessages(analysis *analyzer.PlanAnalysis) []Message {
+	if analysis == nil || analysis.Root == nil {
+		return nil
+	}
+	cfg := settings(analysis).Insights
+	var msgs []Message
+	walkNodes(analysis.Root, func(n *analyzer.NodeStats) {
+		if n.Node == nil || n.Node.RelationName == "" || n.PercentExclusive < cfg.WideRowMinPercent {
+			return
+		}
+		width := n.Node.PlanWidth
+		if width <= 0 || n.ActualTotalRows <= 0 {
+			return
+		}
+		payload := n.ActualTotalRows * width
+		examined := n.ActualTotalRows + n.RowsRemovedByFilter
+		heapPages := examined * width / pageSize
+		pages := float64(n.Buffers.SharedHit + n.Buffers.SharedRead)
+
+		switch {
+		case width >= cfg.WideRowBytes && heapPages > 0 && pages >= heapPages*toastReadFactor:
+			text := fmt.Sprintf("Probable TOAST reads: %s touched %.0f pages for %.0f rows of ~%.0f bytes (x%.1f what the row width explains, ~%s payload) — large columns are being detoasted; select only the columns you need instead of SELECT *",
+				CompactLabel(n), pages, examined, width, pages/heapPages, HumanizeBytes(payload))
+			msgs = append(msgs, Message{Rule: "wide-rows", Severity: SeverityWarning, Text: text, Anchor: AnchorID(n)})
+		case width >= cfg.WideRowBytes:
+			text := fmt.Sprintf("Wide rows: %s returns ~%.0f-byte rows (~%s across %.0f rows) — prune the column list instead of SELECT *",
+				CompactLabel(n), width, HumanizeBytes(payload), n.ActualTotalRows)
+			msgs = append(msgs, Message{Rule: "wide-rows", Severity: SeverityInfo, Text: text, Anchor: AnchorID(n)})
+		}
+	})
+	return msgs
+}
@@ -0,0 +1,84 @@
+package insight
+
+import (
+	"fmt"
+
+	"github.com/mickamy/xplain/internal/analyzer"
+)
+
+// WorkerDetails describes one parallel worker's share of a node as short report fragments: time, rows,
+// buffers, I/O time and sort or memory usage, whichever EXPLAIN reported.
+func WorkerDetails(ws analyzer.WorkerStats) []string {
+	var parts []string
+	if ws.TimeMs > 0 {
+		parts = append(parts, fmt.Sprintf("time %.2f ms", ws.TimeMs))
+	}
+	if ws.Rows > 0 || ws.TimeMs > 0 {
+		rows := fmt.Sprintf("rows %.0f", ws.Rows)
+		if ws.RowShare > 0 {
+			rows += fmt.Sprintf(" (%.1f%%)", ws.RowShare*100)
+		}
+		parts = append(parts, rows)
+	}
+	if total := ws.Buffers.Total(); total > 0 {
+		parts = append(parts, fmt.Sprintf("buf %d (~%s)", total, HumanizeBuffers(total)))
+	}
+	if io := SummarizeIOTime(ws.Buffers); io != "" {
+		parts = append(parts, "io "+io)
+	}
+	if w := ws.Worker; w != nil && w.SortMethod != "" {
+		sort := "sort " + w.SortMethod
+		if w.SortSpaceUsedKB > 0 {
+			sort += fmt.Sprintf(" %s %s", HumanizeBytes(w.SortSpaceUsedKB*1024), w.SortSpaceType)
+		}
+		parts = append(parts, sort)
+	} else if w != nil && w.PeakMemoryKB > 0 {
+		parts = append(parts, "memory "+HumanizeBytes(w.PeakMemoryKB*1024))
+	}
+	return parts
+}
+
+// workerSkewMessages flags Gather and Gather Merge nodes whose busiest process took or produced
+// insights.worker_skew_ratio times its fair share, when the Gather holds at least
+// insights.worker_skew_min_percent of the runtime. It is critical when one process did most of the work
+// of three or more, since the query then runs about as fast as it would serially.
+func workerSkewMessages(analysis *analyzer.PlanAnalysis) []Message {
+	if analysis == nil || analysis.Root == nil {
+		return nil
+	}
+	cfg := settings(analysis).Insights
+	if cfg.WorkerSkewRatio <= 0 {
+		return nil
+	}
+	var msgs []Message
+	for _, n := range analysis.Nodes() {
+		skew := n.WorkerSkew
+		if skew == nil || skew.Ratio() < cfg.WorkerSkewRatio || n.PercentInclusive < cfg.WorkerSkewMinPercent {
+			continue
+		}
+		// Name the measure that tripped the ratio: rows split unevenly, or an even split that took some
+		// workers far longer.
+		var text string
+		severity := SeverityWarning
+		if skew.RowRatio() >= cfg.WorkerSkewRatio {
+			busiest := "the leader"
+			if skew.Busiest != nil {
+				busiest = fmt.Sprintf("worker %d", skew.Busiest.Number)
+			}
+			text = fmt.Sprintf("Parallel skew: %s of %s produced %.1f%% of the rows (%.1fx an even share across %d processes)",
+				busiest, CompactLabel(skew.Node), skew.RowShare()*100, skew.RowRatio(), skew.Processes)
+			if skew.TimeRatio() >= cfg.WorkerSkewRatio {
+				text += fmt.Sprintf("; slowest worker %.2f ms vs %.2f ms mean", skew.MaxTimeMs, skew.MeanTimeMs)
+			}
+			text += " — the matching rows likely sit in a few blocks or partitions, so adding workers will not help"
+			if skew.Processes >= 3 && skew.RowShare() > 0.5 {
+				severity = SeverityCritical
+			}
+		} else {
+			text = fmt.Sprintf("Parallel skew: the slowest worker of %s took %.2f ms vs %.2f ms mean (%.1fx) for an even share of the rows — some workers' rows cost more to produce, e.g. pages read from disk, detoasting or lock waits, so adding workers will not help",
+				CompactLabel(skew.Node), skew.MaxTimeMs, skew.MeanTimeMs, skew.TimeRatio())
+		}
+		msgs = append(msgs, Message{Rule: "worker-skew", Severity: severity, Text: text, Anchor: AnchorID(n)})
+	}
+	return msgs
+}
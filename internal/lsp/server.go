@@ -0,0 +1,403 @@
+// Package lsp serves plan feedback to editors over the Language Server Protocol: statements in an
+// open SQL file are analyzed against a development database and their insights are published as
+// diagnostics on the statement.
+package lsp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/mickamy/xplain/internal/analyzer"
+	"github.com/mickamy/xplain/internal/assert"
+	"github.com/mickamy/xplain/internal/insight"
+	"github.com/mickamy/xplain/internal/sqlfile"
+)
+
+// CommandAnalyze is the workspace command that analyzes the statement at a position. Its
+// arguments are the document URI and the zero-based line.
+const CommandAnalyze = "xplain.analyze"
+
+// Report is the outcome of analyzing one statement.
+type Report struct {
+	Analysis   *analyzer.PlanAnalysis
+	Assertions []assert.Result
+}
+
+// AnalyzeFunc runs EXPLAIN ANALYZE for a statement and analyzes the plan.
+type AnalyzeFunc func(ctx context.Context, sql string) (*Report, error)
+
+// Options configure the server.
+type Options struct {
+	Analyze AnalyzeFunc
+	// OnSave analyzes every statement of a document when it is saved, not only on request.
+	OnSave bool
+	// AllowWrites lets data-modifying statements run; EXPLAIN ANALYZE executes them for real.
+	AllowWrites bool
+	Version     string
+}
+
+// Server holds the open documents and their diagnostics.
+type Server struct {
+	opts Options
+
+	writeMu sync.Mutex
+	out     io.Writer
+
+	mu   sync.Mutex
+	docs map[string]*document
+	wg   sync.WaitGroup
+}
+
+type document struct {
+	text string
+	// version increases with every edit so analyses of stale text are discarded.
+	version int
+	// diagnostics are kept per statement start offset, so analyzing one statement keeps the rest.
+	diagnostics map[int][]diagnostic
+}
+
+// Serve reads requests from in and writes responses to out until the client sends "exit" or in
+// is closed.
+func Serve(ctx context.Context, in io.Reader, out io.Writer, opts Options) error {
+	s := &Server{opts: opts, out: out, docs: map[string]*document{}}
+	ctx, cancel := context.WithCancel(ctx)
+	defer func() {
+		cancel()
+		s.wg.Wait()
+	}()
+
+	reader := bufio.NewReader(in)
+	for {
+		msg, err := readMessage(reader)
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if msg.invalid != nil {
+			s.replyError(nil, codeParseError, msg.invalid.Error())
+			continue
+		}
+		if msg.Method == "exit" {
+			return nil
+		}
+		s.handle(ctx, msg)
+	}
+}
+
+func (s *Server) handle(ctx context.Context, msg *message) {
+	switch msg.Method {
+	case "initialize":
+		s.reply(msg.ID, map[string]any{
+			"capabilities": map[string]any{
+				"textDocumentSync": map[string]any{
+					"openClose": true,
+					"change":    1, // full document text on every change
+					"save":      map[string]any{"includeText": false},
+				},
+				"codeActionProvider":     true,
+				"executeCommandProvider": map[string]any{"commands": []string{CommandAnalyze}},
+			},
+			"serverInfo": map[string]any{"name": "xplain", "version": s.opts.Version},
+		})
+	case "initialized", "$/cancelRequest", "$/setTrace", "workspace/didChangeConfiguration":
+	case "shutdown":
+		s.reply(msg.ID, nil)
+	case "textDocument/didOpen":
+		if params, ok := s.params(msg); ok {
+			s.setText(params.TextDocument.URI, params.TextDocument.Text)
+		}
+	case "textDocument/didChange":
+		if params, ok := s.params(msg); ok && len(params.ContentChanges) > 0 {
+			s.setText(params.TextDocument.URI, params.ContentChanges[len(params.ContentChanges)-1].Text)
+		}
+	case "textDocument/didSave":
+		if params, ok := s.params(msg); ok && s.opts.OnSave {
+			s.analyzeDocument(ctx, params.TextDocument.URI, -1)
+		}
+	case "textDocument/didClose":
+		if params, ok := s.params(msg); ok {
+			s.mu.Lock()
+			delete(s.docs, params.TextDocument.URI)
+			s.mu.Unlock()
+			s.publish(params.TextDocument.URI, []diagnostic{})
+		}
+	case "textDocument/codeAction":
+		params, ok := s.params(msg)
+		if !ok {
+			return
+		}
+		s.reply(msg.ID, s.codeActions(params.TextDocument.URI, params.Range.Start))
+	case "workspace/executeCommand":
+		var params executeCommandParams
+		if err := json.Unmarshal(msg.Params, &params); err != nil || params.Command != CommandAnalyze || len(params.Arguments) < 2 {
+			s.replyError(msg.ID, codeInvalidParams, fmt.Sprintf("expected %s with a document URI and line", CommandAnalyze))
+			return
+		}
+		var (
+			uri  string
+			line int
+		)
+		if json.Unmarshal(params.Arguments[0], &uri) != nil || json.Unmarshal(params.Arguments[1], &line) != nil {
+			s.replyError(msg.ID, codeInvalidParams, "invalid command arguments")
+			return
+		}
+		if !s.analyzeDocument(ctx, uri, line) {
+			s.replyError(msg.ID, codeInvalidParams, "no statement at that line")
+			return
+		}
+		s.reply(msg.ID, nil)
+	default:
+		if msg.ID != nil {
+			s.replyError(msg.ID, codeMethodNotFound, "method not supported: "+msg.Method)
+		}
+	}
+}
+
+func (s *Server) params(msg *message) (documentParams, bool) {
+	var params documentParams
+	if err := json.Unmarshal(msg.Params, &params); err != nil {
+		if msg.ID != nil {
+			s.replyError(msg.ID, codeInvalidParams, err.Error())
+		}
+		return params, false
+	}
+	return params, true
+}
+
+func (s *Server) setText(uri, text string) {
+	s.mu.Lock()
+	doc, ok := s.docs[uri]
+	if !ok {
+		doc = &document{diagnostics: map[int][]diagnostic{}}
+		s.docs[uri] = doc
+	}
+	doc.text = text
+	doc.version++
+	// Offsets no longer line up with the edited text.
+	stale := len(doc.diagnostics) > 0
+	doc.diagnostics = map[int][]diagnostic{}
+	s.mu.Unlock()
+	if stale {
+		s.publish(uri, []diagnostic{})
+	}
+}
+
+func (s *Server) codeActions(uri string, pos position) []codeAction {
+	s.mu.Lock()
+	doc, ok := s.docs[uri]
+	var text string
+	if ok {
+		text = doc.text
+	}
+	s.mu.Unlock()
+	actions := []codeAction{}
+	if !ok || statementAt(text, pos.Line) == nil {
+		return actions
+	}
+	return append(actions, codeAction{
+		Title: "Analyze statement with xplain (runs EXPLAIN ANALYZE)",
+		Kind:  "source",
+		Command: &command{
+			Title:     "Analyze statement with xplain",
+			Command:   CommandAnalyze,
+			Arguments: []any{uri, pos.Line},
+		},
+	})
+}
+
+// analyzeDocument analyzes the statement covering line, or every statement when line is negative,
+// in the background. It reports whether there was anything to analyze.
+func (s *Server) analyzeDocument(ctx context.Context, uri string, line int) bool {
+	s.mu.Lock()
+	doc, ok := s.docs[uri]
+	if !ok {
+		s.mu.Unlock()
+		return false
+	}
+	text, version := doc.text, doc.version
+	s.mu.Unlock()
+
+	var statements []sqlfile.Statement
+	if line < 0 {
+		statements = sqlfile.Split(text)
+	} else if stmt := statementAt(text, line); stmt != nil {
+		statements = []sqlfile.Statement{*stmt}
+	}
+	if len(statements) == 0 {
+		return false
+	}
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		for _, stmt := range statements {
+			diags := s.diagnose(ctx, text, stmt)
+			s.mu.Lock()
+			current, open := s.docs[uri]
+			if !open || current.version != version {
+				s.mu.Unlock()
+				return
+			}
+			current.diagnostics[stmt.Start] = diags
+			all := current.all()
+			s.mu.Unlock()
+			s.publish(uri, all)
+		}
+	}()
+	return true
+}
+
+// diagnose analyzes one statement and describes the result as diagnostics on its first line.
+func (s *Server) diagnose(ctx context.Context, text string, stmt sqlfile.Statement) []diagnostic {
+	target := statementRange(text, stmt)
+	if !s.opts.AllowWrites && !ReadOnly(stmt.Text) {
+		return []diagnostic{{
+			Range: target, Severity: severityInformation, Source: "xplain",
+			Message: "xplain: not analyzed because EXPLAIN ANALYZE would execute this statement; start the server with --allow-writes to include it",
+		}}
+	}
+	report, err := s.opts.Analyze(ctx, stmt.Text)
+	if err != nil {
+		return []diagnostic{{Range: target, Severity: severityError, Source: "xplain", Message: err.Error()}}
+	}
+
+	analysis := report.Analysis
+	summary := fmt.Sprintf("xplain: executed in %.2f ms (planning %.2f ms), %d nodes, %s",
+		analysis.TotalTimeMs, analysis.PlanningTimeMs, analysis.NodeCount, insight.SummarizeTotalBuffers(analysis.TotalBuffers))
+	diags := []diagnostic{{Range: target, Severity: severityHint, Source: "xplain", Message: summary}}
+	for _, msg := range insight.BuildMessages(analysis) {
+		if msg.Acknowledged != "" {
+			continue
+		}
+		severity := severityWarning
+		if msg.Severity == insight.SeverityInfo {
+			severity = severityInformation
+		}
+		diags = append(diags, diagnostic{Range: target, Severity: severity, Code: msg.Rule, Source: "xplain", Message: msg.Text})
+	}
+	for _, result := range report.Assertions {
+		if !result.Passed {
+			diags = append(diags, diagnostic{
+				Range: target, Severity: severityError, Code: "assertion", Source: "xplain",
+				Message: fmt.Sprintf("%s: %s", result.Assertion, result.Detail),
+			})
+		}
+	}
+	return diags
+}
+
+var (
+	leadingComments = regexp.MustCompile(`^(?:\s+|--[^\n]*(?:\n|$)|/\*(?s:.*?)\*/)*`)
+	firstKeyword    = regexp.MustCompile(`^(?i)[a-z]+`)
+	modifyKeyword   = regexp.MustCompile(`(?i)\b(?:insert|update|delete|merge)\b`)
+	intoKeyword     = regexp.MustCompile(`(?i)\binto\b`)
+	lockingClause   = regexp.MustCompile(`(?i)\bfor\s+(?:no\s+key\s+update|key\s+share|update|share)\b`)
+	// opaqueText matches string literals, quoted identifiers and comments, whose words are not keywords.
+	opaqueText = regexp.MustCompile(`'(?:[^']|'')*'|"(?:[^"]|"")*"|--[^\n]*|/\*(?s:.*?)\*/`)
+)
+
+// ReadOnly reports whether sql is a query: a SELECT, VALUES or TABLE statement, or a WITH query
+// without data-modifying CTEs, that neither creates a table with SELECT INTO nor locks rows with
+// FOR UPDATE or FOR SHARE. Functions with side effects called from a query are not detected, which is
+// why the server also runs EXPLAIN in a read-only transaction unless writes are allowed.
+func ReadOnly(sql string) bool {
+	body := sql[len(leadingComments.FindString(sql)):]
+	code := opaqueText.ReplaceAllString(body, "''")
+	switch strings.ToLower(firstKeyword.FindString(body)) {
+	case "with":
+		if modifyKeyword.MatchString(code) {
+			return false
+		}
+	case "select", "values", "table":
+	default:
+		return false
+	}
+	return !intoKeyword.MatchString(code) && !lockingClause.MatchString(code)
+}
+
+func (d *document) all() []diagnostic {
+	starts := make([]int, 0, len(d.diagnostics))
+	for start := range d.diagnostics {
+		starts = append(starts, start)
+	}
+	sort.Ints(starts)
+	out := []diagnostic{}
+	for _, start := range starts {
+		out = append(out, d.diagnostics[start]...)
+	}
+	return out
+}
+
+// statementAt finds the statement whose span covers line, ignoring the blank lines between them.
+func statementAt(text string, line int) *sqlfile.Statement {
+	offset := offsetAt(text, position{Line: line})
+	lineEnd := len(text)
+	if nl := strings.IndexByte(text[offset:], '\n'); nl >= 0 {
+		lineEnd = offset + nl
+	}
+	for _, stmt := range sqlfile.Split(text) {
+		if stmt.Start <= lineEnd && offset <= stmt.End {
+			return &stmt
+		}
+	}
+	return nil
+}
+
+// statementRange marks the first line of the statement's code, skipping leading comments, so
+// diagnostics do not cover a whole multi-line query.
+func statementRange(text string, stmt sqlfile.Statement) textRange {
+	start := stmt.Start
+	for offset := stmt.Start; offset < stmt.End; {
+		nl := strings.IndexByte(text[offset:stmt.End], '\n')
+		line := text[offset:stmt.End]
+		if nl >= 0 {
+			line = text[offset : offset+nl]
+		}
+		if trimmed := strings.TrimSpace(line); trimmed != "" && !strings.HasPrefix(trimmed, "--") {
+			start = offset + len(line) - len(strings.TrimLeft(line, " \t"))
+			break
+		}
+		if nl < 0 {
+			break
+		}
+		offset += nl + 1
+	}
+	end := stmt.End
+	if nl := strings.IndexByte(text[start:stmt.End], '\n'); nl >= 0 {
+		end = start + nl
+	}
+	end = len(strings.TrimRight(text[:end], " \t\r"))
+	return textRange{Start: positionAt(text, start), End: positionAt(text, end)}
+}
+
+func (s *Server) reply(id *json.RawMessage, result any) {
+	s.send(response{JSONRPC: "2.0", ID: id, Result: result})
+}
+
+func (s *Server) replyError(id *json.RawMessage, code int, msg string) {
+	s.send(errorResponse{JSONRPC: "2.0", ID: id, Error: rpcError{Code: code, Message: msg}})
+}
+
+func (s *Server) publish(uri string, diags []diagnostic) {
+	s.send(notification{JSONRPC: "2.0", Method: "textDocument/publishDiagnostics", Params: map[string]any{
+		"uri":         uri,
+		"diagnostics": diags,
+	}})
+}
+
+func (s *Server) send(msg any) {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	// A client that went away is noticed when reading the next message.
+	_ = writeMessage(s.out, msg)
+}
@@ -0,0 +1,197 @@
+package lsp_test
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/mickamy/xplain/internal/lsp"
+	"github.com/mickamy/xplain/test"
+)
+
+const uri = "file:///work/queries.sql"
+
+const script = `-- xplain: timeout=5s
+SELECT aid, abalance
+FROM pgbench_accounts
+WHERE bid = 1;
+
+DELETE FROM pgbench_history;
+
+SELECT broken;
+`
+
+func frame(t *testing.T, messages ...map[string]any) io.Reader {
+	t.Helper()
+	var buf bytes.Buffer
+	for _, msg := range messages {
+		msg["jsonrpc"] = "2.0"
+		body, err := json.Marshal(msg)
+		if err != nil {
+			t.Fatalf("encode: %v", err)
+		}
+		_, _ = fmt.Fprintf(&buf, "Content-Length: %d\r\n\r\n%s", len(body), body)
+	}
+	return &buf
+}
+
+func decode(t *testing.T, out []byte) []map[string]any {
+	t.Helper()
+	var messages []map[string]any
+	r := bufio.NewReader(bytes.NewReader(out))
+	for {
+		header, err := textproto.NewReader(r).ReadMIMEHeader()
+		if errors.Is(err, io.EOF) {
+			return messages
+		}
+		if err != nil {
+			t.Fatalf("read header: %v", err)
+		}
+		length, _ := strconv.Atoi(header.Get("Content-Length"))
+		body := make([]byte, length)
+		if _, err := io.ReadFull(r, body); err != nil {
+			t.Fatalf("read body: %v", err)
+		}
+		var msg map[string]any
+		if err := json.Unmarshal(body, &msg); err != nil {
+			t.Fatalf("decode %s: %v", body, err)
+		}
+		messages = append(messages, msg)
+	}
+}
+
+func TestServeAnalyzesStatementOnCommand(t *testing.T) {
+	var analyzed []string
+	analyze := func(_ context.Context, sql string) (*lsp.Report, error) {
+		analyzed = append(analyzed, sql)
+		if strings.Contains(sql, "broken") {
+			return nil, errors.New(`column "broken" does not exist`)
+		}
+		return &lsp.Report{Analysis: test.LoadSampleAnalysis(t, "pgbench_hot.json")}, nil
+	}
+
+	in := frame(t,
+		map[string]any{"id": 1, "method": "initialize", "params": map[string]any{}},
+		map[string]any{"method": "initialized", "params": map[string]any{}},
+		map[string]any{"method": "textDocument/didOpen", "params": map[string]any{
+			"textDocument": map[string]any{"uri": uri, "languageId": "sql", "version": 1, "text": script},
+		}},
+		map[string]any{"id": 2, "method": "textDocument/codeAction", "params": map[string]any{
+			"textDocument": map[string]any{"uri": uri},
+			"range":        map[string]any{"start": map[string]any{"line": 2, "character": 0}, "end": map[string]any{"line": 2, "character": 0}},
+		}},
+		map[string]any{"id": 3, "method": "workspace/executeCommand", "params": map[string]any{
+			"command": lsp.CommandAnalyze, "arguments": []any{uri, 2},
+		}},
+		map[string]any{"id": 4, "method": "textDocument/hover", "params": map[string]any{}},
+		map[string]any{"id": 5, "method": "shutdown"},
+		map[string]any{"method": "exit"},
+	)
+	var out bytes.Buffer
+	if err := lsp.Serve(context.Background(), in, &out, lsp.Options{Analyze: analyze, Version: "test"}); err != nil {
+		t.Fatalf("serve: %v", err)
+	}
+
+	if len(analyzed) != 1 || !strings.HasPrefix(analyzed[0], "-- xplain: timeout=5s\nSELECT aid") || strings.HasSuffix(analyzed[0], ";") {
+		t.Fatalf("unexpected analyzed statements %q", analyzed)
+	}
+
+	var (
+		actions     []any
+		diagnostics []any
+		hoverError  map[string]any
+	)
+	for _, msg := range decode(t, out.Bytes()) {
+		switch {
+		case msg["id"] == 2.0:
+			actions, _ = msg["result"].([]any)
+		case msg["id"] == 4.0:
+			hoverError, _ = msg["error"].(map[string]any)
+		case msg["method"] == "textDocument/publishDiagnostics":
+			diagnostics = msg["params"].(map[string]any)["diagnostics"].([]any)
+		}
+	}
+	if len(actions) != 1 {
+		t.Fatalf("expected one code action, got %v", actions)
+	}
+	if hoverError == nil || hoverError["code"] != -32601.0 {
+		t.Fatalf("expected method not found for hover, got %v", hoverError)
+	}
+	if len(diagnostics) < 2 {
+		t.Fatalf("expected summary and insight diagnostics, got %v", diagnostics)
+	}
+	first := diagnostics[0].(map[string]any)
+	start := first["range"].(map[string]any)["start"].(map[string]any)
+	if start["line"] != 1.0 || first["severity"] != 4.0 || !strings.Contains(first["message"].(string), "676.50 ms") {
+		t.Fatalf("unexpected summary diagnostic %v", first)
+	}
+	if second := diagnostics[1].(map[string]any); second["severity"] != 2.0 || second["code"] == "" {
+		t.Fatalf("expected an insight warning, got %v", second)
+	}
+}
+
+func TestServeOnSaveSkipsWrites(t *testing.T) {
+	analyze := func(_ context.Context, sql string) (*lsp.Report, error) {
+		return nil, errors.New("boom: " + strings.Fields(sql)[0])
+	}
+	in := frame(t,
+		map[string]any{"method": "textDocument/didOpen", "params": map[string]any{
+			"textDocument": map[string]any{"uri": uri, "text": script},
+		}},
+		map[string]any{"method": "textDocument/didSave", "params": map[string]any{"textDocument": map[string]any{"uri": uri}}},
+	)
+	var out bytes.Buffer
+	if err := lsp.Serve(context.Background(), in, &out, lsp.Options{Analyze: analyze, OnSave: true}); err != nil {
+		t.Fatalf("serve: %v", err)
+	}
+
+	messages := decode(t, out.Bytes())
+	last := messages[len(messages)-1]["params"].(map[string]any)["diagnostics"].([]any)
+	if len(last) != 3 {
+		t.Fatalf("expected one diagnostic per statement, got %v", last)
+	}
+	var lines []float64
+	for _, d := range last {
+		lines = append(lines, d.(map[string]any)["range"].(map[string]any)["start"].(map[string]any)["line"].(float64))
+	}
+	if lines[0] != 1 || lines[1] != 5 || lines[2] != 7 {
+		t.Fatalf("unexpected diagnostic lines %v", lines)
+	}
+	if msg := last[1].(map[string]any)["message"].(string); !strings.Contains(msg, "--allow-writes") {
+		t.Fatalf("expected the DELETE to be skipped, got %q", msg)
+	}
+	if msg := last[2].(map[string]any)["message"].(string); msg != "boom: SELECT" {
+		t.Fatalf("expected the analysis error, got %q", msg)
+	}
+}
+
+func TestReadOnly(t *testing.T) {
+	tests := map[string]bool{
+		"SELECT 1":                                              true,
+		"-- note\n/* more */ select * from t":                   true,
+		"WITH x AS (SELECT 1) SELECT * FROM x":                  true,
+		"WITH x AS (SELECT 'delete') SELECT * FROM x":           true,
+		"WITH d AS (DELETE FROM t RETURNING *) SELECT * FROM d": false,
+		"UPDATE t SET a = 1":                                    false,
+		"CREATE TABLE t (a int)":                                false,
+		"SELECT * INTO new_table FROM orders":                   false,
+		"WITH x AS (SELECT 1) SELECT * INTO t2 FROM x":          false,
+		"SELECT * FROM orders FOR UPDATE":                       false,
+		"select id from orders for no key update":               false,
+		"SELECT * FROM orders FOR SHARE SKIP LOCKED":            false,
+		"SELECT 'into' AS \"for update\" -- into":               true,
+	}
+	for sql, want := range tests {
+		if got := lsp.ReadOnly(sql); got != want {
+			t.Fatalf("ReadOnly(%q) = %v, want %v", sql, got, want)
+		}
+	}
+}
@@ -0,0 +1,150 @@
+package manifest
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/mickamy/xplain/internal/runner"
+)
+
+// Manifest maps named queries to SQL files and the database profile each one runs against.
+type Manifest struct {
+	Profiles map[string]Profile `json:"profiles"`
+	Queries  []Query            `json:"queries"`
+	// Dir is the directory holding the manifest; relative SQL paths resolve against it.
+	Dir string `json:"-"`
+}
+
+// Profile describes how to connect to one target database.
+type Profile struct {
+	URL string `json:"url"`
+	// URLEnv names an environment variable holding the connection string, so secrets stay out of the manifest.
+	URLEnv string `json:"url_env"`
+	// Service names an entry of the connection service file (~/.pg_service.conf or $PGSERVICEFILE), used
+	// when the environment variable is unset; passwords then come from the service file or ~/.pgpass.
+	Service string `json:"service"`
+}
+
+// Query is one canned statement tracked by the manifest.
+type Query struct {
+	Name    string `json:"name"`
+	SQL     string `json:"sql"`
+	Profile string `json:"profile"`
+	// Vars are SQL template variables for this query.
+	Vars map[string]string `json:"vars"`
+}
+
+// validName keeps query names usable as file names under the output and baseline directories: no path
+// separators, and no leading dot that could make "..".
+var validName = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// Load reads and validates a manifest file.
+func Load(path string) (*Manifest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read manifest: %w", err)
+	}
+	var m Manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("parse manifest: %w", err)
+	}
+	m.Dir = filepath.Dir(path)
+	if err := m.validate(); err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+func (m *Manifest) validate() error {
+	if len(m.Queries) == 0 {
+		return errors.New("manifest: no queries defined")
+	}
+	seen := map[string]struct{}{}
+	for i, q := range m.Queries {
+		if strings.TrimSpace(q.Name) == "" {
+			return fmt.Errorf("manifest: query %d has no name", i+1)
+		}
+		if !validName.MatchString(q.Name) {
+			return fmt.Errorf("manifest: invalid query name %q: use letters, digits, '.', '_' and '-'", q.Name)
+		}
+		if _, dup := seen[q.Name]; dup {
+			return fmt.Errorf("manifest: duplicate query name %q", q.Name)
+		}
+		seen[q.Name] = struct{}{}
+		if strings.TrimSpace(q.SQL) == "" {
+			return fmt.Errorf("manifest: query %q has no sql path", q.Name)
+		}
+		if q.Profile != "" {
+			if _, ok := m.Profiles[q.Profile]; !ok {
+				return fmt.Errorf("manifest: query %q references unknown profile %q", q.Name, q.Profile)
+			}
+		}
+	}
+	return nil
+}
+
+// SQLPath resolves the query's SQL file relative to the manifest.
+func (m *Manifest) SQLPath(q Query) string {
+	if filepath.IsAbs(q.SQL) {
+		return q.SQL
+	}
+	return filepath.Join(m.Dir, q.SQL)
+}
+
+// DSN returns the connection string for the query's profile, falling back to the given default
+// (e.g. --url or $DATABASE_URL) when the query has no profile.
+func (m *Manifest) DSN(q Query, fallback string) (string, error) {
+	if q.Profile == "" {
+		if fallback == "" {
+			return "", fmt.Errorf("manifest: query %q has no profile and no default connection", q.Name)
+		}
+		return fallback, nil
+	}
+	p := m.Profiles[q.Profile]
+	if p.URLEnv != "" {
+		if v := strings.TrimSpace(os.Getenv(p.URLEnv)); v != "" {
+			return v, nil
+		}
+	}
+	if p.Service != "" {
+		return runner.ServiceDSN(p.Service), nil
+	}
+	if p.URL != "" {
+		return p.URL, nil
+	}
+	return "", fmt.Errorf("manifest: profile %q has no url (set $%s)", q.Profile, p.URLEnv)
+}
+
+// Vars merges the query's template variables with overrides from the command line.
+func (m *Manifest) Vars(q Query, overrides map[string]string) map[string]string {
+	vars := make(map[string]string, len(q.Vars)+len(overrides))
+	maps.Copy(vars, q.Vars)
+	maps.Copy(vars, overrides)
+	return vars
+}
+
+// Select returns the queries whose names are listed, or every query when names is empty.
+func (m *Manifest) Select(names []string) ([]Query, error) {
+	if len(names) == 0 {
+		return m.Queries, nil
+	}
+	byName := map[string]Query{}
+	for _, q := range m.Queries {
+		byName[q.Name] = q
+	}
+	out := make([]Query, 0, len(names))
+	for _, name := range names {
+		q, ok := byName[name]
+		if !ok {
+			return nil, fmt.Errorf("manifest: unknown query %q", name)
+		}
+		out = append(out, q)
+	}
+	return out, nil
+}
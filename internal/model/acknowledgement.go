@@ -0,0 +1,9 @@
+package model
+
+// Acknowledgement marks insights of one rule as reviewed, optionally only on matching nodes.
+type Acknowledgement struct {
+	Rule string
+	// Selector is a node pattern such as "Seq Scan on orders"; empty matches every node.
+	Selector string
+	Reason   string
+}
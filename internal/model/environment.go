@@ -0,0 +1,49 @@
+package model
+
+import "time"
+
+// Environment is a snapshot of the server state that shapes plans, written by xplain env: planner settings,
+// installed extensions and how fresh each table's statistics are.
+type Environment struct {
+	Host          string    `json:"host,omitempty"`
+	Port          uint16    `json:"port,omitempty"`
+	Database      string    `json:"database,omitempty"`
+	ServerVersion string    `json:"server_version,omitempty"`
+	CapturedAt    time.Time `json:"captured_at,omitzero"`
+	// Settings holds every query-tuning setting plus the memory and cost settings xplain run records.
+	Settings map[string]string `json:"settings"`
+	// Extensions maps installed extensions to their versions.
+	Extensions map[string]string `json:"extensions"`
+	Tables     []TableStats      `json:"tables"`
+}
+
+// TableStats describes how current a table's planner statistics are.
+type TableStats struct {
+	Schema string `json:"schema"`
+	Name   string `json:"name"`
+	// LastAnalyzed is the later of the last manual and automatic ANALYZE; zero when never analyzed.
+	LastAnalyzed time.Time `json:"last_analyzed,omitzero"`
+	LiveRows     int64     `json:"live_rows"`
+	// ModifiedSinceAnalyze counts rows inserted, updated or deleted since the last ANALYZE.
+	ModifiedSinceAnalyze int64 `json:"modified_since_analyze"`
+}
+
+// Table finds a table by schema and name. Without a schema, as in plans captured without VERBOSE, a table in
+// public wins over same-named tables elsewhere.
+func (e *Environment) Table(schema, name string) *TableStats {
+	if e == nil {
+		return nil
+	}
+	var found *TableStats
+	for i := range e.Tables {
+		t := &e.Tables[i]
+		switch {
+		case t.Name != name:
+		case t.Schema == schema, schema == "" && t.Schema == "public":
+			return t
+		case schema == "" && found == nil:
+			found = t
+		}
+	}
+	return found
+}
@@ -0,0 +1,75 @@
+package model
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Metadata describes where and when a plan was captured.
+type Metadata struct {
+	Host          string    `json:"host,omitempty"`
+	Port          uint16    `json:"port,omitempty"`
+	Database      string    `json:"database,omitempty"`
+	User          string    `json:"user,omitempty"`
+	ServerVersion string    `json:"server_version,omitempty"`
+	CapturedAt    time.Time `json:"captured_at,omitzero"`
+	Query         string    `json:"query,omitempty"`
+	// Settings records the planner-relevant settings in effect for the session, e.g. work_mem.
+	Settings map[string]string `json:"settings,omitempty"`
+	// Client is the round trip measured by running the statement itself, when it was measured.
+	Client *ClientTiming `json:"client,omitempty"`
+}
+
+// ClientTiming is what the client observed running the statement once more without EXPLAIN and fetching
+// every row of the result.
+type ClientTiming struct {
+	WallMs float64 `json:"wall_ms"`
+	Rows   int64   `json:"rows"`
+	Bytes  int64   `json:"bytes"`
+}
+
+// Envelope is the document written by xplain run: the raw EXPLAIN output wrapped with capture metadata.
+type Envelope struct {
+	Xplain *Metadata       `json:"xplain"`
+	Plan   json.RawMessage `json:"plan"`
+}
+
+// Summary renders the metadata as a single header line, e.g.
+// "db.internal:5432/app as report · PostgreSQL 16.2 · captured 2026-01-02 15:04:05 UTC · work_mem=64MB".
+func (m *Metadata) Summary() string {
+	if m == nil {
+		return ""
+	}
+	var parts []string
+	target := m.Host
+	if target != "" && m.Port != 0 {
+		target = fmt.Sprintf("%s:%d", target, m.Port)
+	}
+	if m.Database != "" {
+		target += "/" + m.Database
+	}
+	if m.User != "" {
+		target += " as " + m.User
+	}
+	if target = strings.TrimPrefix(target, "/"); target != "" {
+		parts = append(parts, target)
+	}
+	if m.ServerVersion != "" {
+		parts = append(parts, "PostgreSQL "+m.ServerVersion)
+	}
+	if !m.CapturedAt.IsZero() {
+		parts = append(parts, "captured "+m.CapturedAt.UTC().Format("2006-01-02 15:04:05 UTC"))
+	}
+	if len(m.Settings) > 0 {
+		settings := make([]string, 0, len(m.Settings))
+		for name, value := range m.Settings {
+			settings = append(settings, name+"="+value)
+		}
+		slices.Sort(settings)
+		parts = append(parts, strings.Join(settings, ", "))
+	}
+	return strings.Join(parts, " · ")
+}
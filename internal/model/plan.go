@@ -1,20 +1,83 @@
 package model
 
+import "strings"
+
 // Explain represents the root of a PostgreSQL execution plan.
 type Explain struct {
-	Plan          *PlanNode
-	PlanningTime  float64
-	ExecutionTime float64
-	Settings      map[string]string
+	Plan         *PlanNode
+	PlanningTime float64
+	// PlanningBuffers counts buffers touched while planning (PostgreSQL 13+ with BUFFERS).
+	PlanningBuffers Buffers
+	ExecutionTime   float64
+	Settings        map[string]string
+	// QueryText holds the statement text when it is known (auto_explain output or xplain analyze).
+	QueryText string
+	Triggers  []Trigger
+	// JIT reports just-in-time compilation of the statement; nil when the server did not use JIT.
+	JIT *JIT
+	// Serialization reports converting the result rows to the wire format; nil unless the plan was
+	// captured with EXPLAIN (ANALYZE, SERIALIZE) on PostgreSQL 17+.
+	Serialization *Serialization
+	// Metadata describes the capture environment when the plan came from an xplain envelope.
+	Metadata *Metadata
 	// Extra carries additional top-level fields that we do not interpret yet.
 	Extra map[string]any
 }
 
+// Trigger reports time spent in one trigger (including foreign key checks) during execution.
+type Trigger struct {
+	Name           string
+	ConstraintName string
+	Relation       string
+	TimeMs         float64
+	Calls          float64
+}
+
+// IsForeignKey reports whether the trigger implements a foreign key constraint.
+func (t Trigger) IsForeignKey() bool {
+	return strings.HasPrefix(t.Name, "RI_ConstraintTrigger")
+}
+
+// JIT summarizes the functions PostgreSQL compiled with LLVM and the time the compilation stages took.
+type JIT struct {
+	Functions    int
+	Inlining     bool
+	Optimization bool
+	Expressions  bool
+	Deforming    bool
+	GenerationMs float64
+	// DeformMs is the part of generation spent on tuple deforming (PostgreSQL 17+).
+	DeformMs       float64
+	InliningMs     float64
+	OptimizationMs float64
+	EmissionMs     float64
+	TotalMs        float64
+}
+
+// Serialization is the time and volume of converting the result rows to text or binary output, the
+// detoasting cost EXPLAIN ANALYZE otherwise skips.
+type Serialization struct {
+	TimeMs   float64
+	OutputKB float64
+	// Format is "text" or "binary".
+	Format  string
+	Buffers Buffers
+}
+
+// TempFile is a temporary file reported by the server log when log_temp_files is enabled.
+type TempFile struct {
+	Path      string
+	SizeBytes int64
+	// Statement is the query the log attributed the file to, when it was logged alongside.
+	Statement string
+}
+
 // PlanNode captures one node in the execution plan tree.
 type PlanNode struct {
 	ID                 string
 	NodeType           string
 	RelationName       string
+	Operation          string
 	Schema             string
 	Alias              string
 	ParentRelationship string
@@ -30,30 +93,125 @@ type PlanNode struct {
 	WorkersLaunched    float64
 	Output             []string
 	Filter             string
-	JoinType           string
-	IndexName          string
-	HashCond           string
-	MergeCond          string
-	SortKey            []string
-	GroupKey           []string
-	Buffers            Buffers
-	Extra              map[string]any
-	Children           []*PlanNode
+	RowsRemovedFilter  float64
+	// JoinFilter is the join qualifier a join checks against each row pair after matching them, and
+	// RowsRemovedJoinFilter the pairs it rejected per loop.
+	JoinFilter            string
+	RowsRemovedJoinFilter float64
+	JoinType              string
+	IndexName             string
+	// HeapFetches counts the rows an Index Only Scan had to read from the heap because the visibility
+	// map did not mark their page all-visible. EXPLAIN reports it as a total over all loops, unlike the
+	// per-loop rows removed by filters.
+	HeapFetches float64
+	// ExactHeapBlocks and LossyHeapBlocks count the pages a Bitmap Heap Scan visited, split by whether its
+	// bitmap still held their matching tuples or, having outgrown work_mem, only the page; they are totals
+	// over all loops too. RowsRemovedIndexRecheck is the per-loop rows a recheck of the index condition
+	// rejected, which lossy pages force for every tuple on them.
+	ExactHeapBlocks         float64
+	LossyHeapBlocks         float64
+	RowsRemovedIndexRecheck float64
+	// CTEName is the common table expression a CTE Scan reads; SubplanName labels the root of an InitPlan
+	// or SubPlan ("SubPlan 1", "InitPlan 1 (returns $0)", "CTE recent_orders").
+	CTEName     string
+	SubplanName string
+	HashCond    string
+	MergeCond   string
+	SortKey     []string
+	GroupKey    []string
+	Strategy    string
+	PartialMode string
+	// SortMethod is the algorithm a Sort used ("quicksort", "top-N heapsort", "external merge", ...);
+	// SortSpaceUsedKB and SortSpaceType report where it ran ("Memory" or "Disk") and how much it used.
+	SortMethod      string
+	SortSpaceUsedKB float64
+	SortSpaceType   string
+	// HashBuckets and HashBatches are the hash table layout a Hash node ended with; the Original values
+	// are what the planner started with. More than one batch means the hash table spilled to disk.
+	HashBuckets         float64
+	OriginalHashBuckets float64
+	HashBatches         float64
+	OriginalHashBatches float64
+	// SubplansRemoved counts the children an Append or Merge Append pruned when the executor started,
+	// typically partitions excluded by a parameter or stable expression (PostgreSQL 12+).
+	SubplansRemoved float64
+	// PeakMemoryKB is the peak memory of Hash, Memoize, hashed Aggregate and Incremental Sort nodes.
+	PeakMemoryKB float64
+	// CacheHits and CacheMisses count the lookups a Memoize answered from its cache or had to run its inner
+	// side for; CacheEvictions counts the entries it dropped to make room and CacheOverflows the results
+	// too large to cache at all. All four are totals over the loops (PostgreSQL 14+).
+	CacheHits      float64
+	CacheMisses    float64
+	CacheEvictions float64
+	CacheOverflows float64
+	Buffers        Buffers
+	// Workers breaks the node down per parallel worker (EXPLAIN ANALYZE, VERBOSE; sort details without VERBOSE).
+	Workers  []Worker
+	Extra    map[string]any
+	Children []*PlanNode
+}
+
+// Worker is one parallel worker's share of a node. The leader's share is what remains of the node's
+// totals after subtracting every worker.
+type Worker struct {
+	Number            int
+	ActualStartupTime float64
+	ActualTotalTime   float64
+	ActualRows        float64
+	ActualLoops       float64
+	SortMethod        string
+	SortSpaceUsedKB   float64
+	SortSpaceType     string
+	PeakMemoryKB      float64
+	Buffers           Buffers
+	// Extra carries per-worker fields that we do not interpret yet, such as JIT timings.
+	Extra map[string]any
 }
 
 // Buffers holds buffer usage statistics for a node.
 type Buffers struct {
-	SharedHit       int64
-	SharedRead      int64
-	SharedDirtied   int64
-	SharedWritten   int64
-	LocalHit        int64
-	LocalRead       int64
-	LocalDirtied    int64
-	LocalWritten    int64
-	TempRead        int64
-	TempWritten     int64
-	IOReadTimeMs    float64
-	IOWriteTimeMs   float64
-	BlockReadTimeMs float64
+	SharedHit     int64
+	SharedRead    int64
+	SharedDirtied int64
+	SharedWritten int64
+	LocalHit      int64
+	LocalRead     int64
+	LocalDirtied  int64
+	LocalWritten  int64
+	TempRead      int64
+	TempWritten   int64
+	// I/O timings are only present with track_io_timing (temp timings since PostgreSQL 15,
+	// the shared/local split since 17; older servers report shared and local together).
+	SharedReadTimeMs  float64
+	SharedWriteTimeMs float64
+	LocalReadTimeMs   float64
+	LocalWriteTimeMs  float64
+	TempReadTimeMs    float64
+	TempWriteTimeMs   float64
+}
+
+// Catalog carries live database metadata gathered alongside a plan.
+type Catalog struct {
+	Indexes []Index
+}
+
+// Index describes an existing index as reported by the system catalog.
+type Index struct {
+	Schema     string
+	Table      string
+	Name       string
+	Method     string
+	Columns    []IndexColumn
+	Unique     bool
+	Primary    bool
+	Valid      bool
+	Partial    bool
+	Definition string
+}
+
+// IndexColumn is one key column (or expression) of an index.
+type IndexColumn struct {
+	Expr       string
+	Desc       bool
+	NullsFirst bool
 }
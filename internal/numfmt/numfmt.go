@@ -0,0 +1,197 @@
+// Package numfmt formats the durations, counts and sizes reports show, with locale-aware separators.
+package numfmt
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Units selects how durations are shown.
+type Units string
+
+const (
+	// UnitsMillis always prints milliseconds, as EXPLAIN does.
+	UnitsMillis Units = "ms"
+	// UnitsSeconds always prints seconds.
+	UnitsSeconds Units = "s"
+	// UnitsAuto prints milliseconds below one second, seconds below one minute and minutes above.
+	UnitsAuto Units = "auto"
+)
+
+// ParseUnits validates a --units value. Empty leaves the choice to the renderer: the TUI picks auto,
+// the other renderers milliseconds.
+func ParseUnits(s string) (Units, error) {
+	switch u := Units(strings.ToLower(strings.TrimSpace(s))); u {
+	case "", UnitsMillis, UnitsSeconds, UnitsAuto:
+		return u, nil
+	default:
+		return "", fmt.Errorf("numfmt: unknown units %q (expected ms, s or auto)", s)
+	}
+}
+
+// Locale holds the separators a language writes numbers with. The zero value groups nothing and uses
+// a dot, matching the raw EXPLAIN output.
+type Locale struct {
+	Group   string
+	Decimal string
+}
+
+// narrowSpace is the narrow no-break space French and other languages group digits with.
+const narrowSpace = "\u202f"
+
+var locales = map[string]Locale{
+	"en": {Group: ",", Decimal: "."},
+	"ja": {Group: ",", Decimal: "."},
+	"ko": {Group: ",", Decimal: "."},
+	"zh": {Group: ",", Decimal: "."},
+	"hi": {Group: ",", Decimal: "."},
+	"de": {Group: ".", Decimal: ","},
+	"es": {Group: ".", Decimal: ","},
+	"it": {Group: ".", Decimal: ","},
+	"nl": {Group: ".", Decimal: ","},
+	"pt": {Group: ".", Decimal: ","},
+	"da": {Group: ".", Decimal: ","},
+	"id": {Group: ".", Decimal: ","},
+	"tr": {Group: ".", Decimal: ","},
+	"fr": {Group: narrowSpace, Decimal: ","},
+	"cs": {Group: narrowSpace, Decimal: ","},
+	"fi": {Group: narrowSpace, Decimal: ","},
+	"nb": {Group: narrowSpace, Decimal: ","},
+	"pl": {Group: narrowSpace, Decimal: ","},
+	"ru": {Group: narrowSpace, Decimal: ","},
+	"sv": {Group: narrowSpace, Decimal: ","},
+	"uk": {Group: narrowSpace, Decimal: ","},
+}
+
+// regional holds the regions whose separators differ from their language's.
+var regional = map[string]Locale{
+	"de_ch": {Group: "’", Decimal: "."},
+	"pt_br": {Group: ".", Decimal: ","},
+	"pt_pt": {Group: narrowSpace, Decimal: ","},
+	"es_mx": {Group: ",", Decimal: "."},
+}
+
+// ParseLocale resolves a --locale value such as "de", "fr_FR.UTF-8" or "en-US". "auto" reads LC_ALL,
+// LC_NUMERIC and LANG in that order; empty, "C" and "POSIX" keep the raw EXPLAIN formatting.
+func ParseLocale(name string) (Locale, error) {
+	name = strings.TrimSpace(name)
+	if strings.EqualFold(name, "auto") {
+		name = ""
+		for _, key := range []string{"LC_ALL", "LC_NUMERIC", "LANG"} {
+			if v := os.Getenv(key); v != "" {
+				name = v
+				break
+			}
+		}
+		if l, err := ParseLocale(name); err == nil {
+			return l, nil
+		}
+		// An environment locale xplain does not know falls back to the raw formatting.
+		return Locale{}, nil
+	}
+	tag, _, _ := strings.Cut(strings.ToLower(name), ".")
+	tag, _, _ = strings.Cut(tag, "@")
+	tag = strings.ReplaceAll(tag, "-", "_")
+	if tag == "" || tag == "c" || tag == "posix" {
+		return Locale{}, nil
+	}
+	if l, ok := regional[tag]; ok {
+		return l, nil
+	}
+	lang, _, _ := strings.Cut(tag, "_")
+	if l, ok := locales[lang]; ok {
+		return l, nil
+	}
+	return Locale{}, fmt.Errorf("numfmt: unknown locale %q", name)
+}
+
+// Format prints durations, counts and sizes. The zero value reproduces the raw EXPLAIN formatting:
+// milliseconds, no digit grouping and a dot as the decimal separator.
+type Format struct {
+	Locale Locale
+	Units  Units
+}
+
+// Number prints v with the given number of decimals and the locale's separators.
+func (f Format) Number(v float64, decimals int) string {
+	if math.IsInf(v, 0) || math.IsNaN(v) {
+		return strconv.FormatFloat(v, 'f', decimals, 64)
+	}
+	text := strconv.FormatFloat(v, 'f', decimals, 64)
+	sign := ""
+	if strings.HasPrefix(text, "-") {
+		sign, text = "-", text[1:]
+	}
+	whole, frac, _ := strings.Cut(text, ".")
+	if f.Locale.Group != "" && len(whole) > 3 {
+		var b strings.Builder
+		lead := len(whole) % 3
+		if lead > 0 {
+			b.WriteString(whole[:lead])
+		}
+		for i := lead; i < len(whole); i += 3 {
+			if b.Len() > 0 {
+				b.WriteString(f.Locale.Group)
+			}
+			b.WriteString(whole[i : i+3])
+		}
+		whole = b.String()
+	}
+	if frac == "" {
+		return sign + whole
+	}
+	decimal := f.Locale.Decimal
+	if decimal == "" {
+		decimal = "."
+	}
+	return sign + whole + decimal + frac
+}
+
+// Count prints a row, loop or block count without decimals, e.g. "184,232".
+func (f Format) Count(v float64) string {
+	return f.Number(v, 0)
+}
+
+// Duration prints a time given in milliseconds, switching units as asked: "184232.123 ms" becomes
+// "184.232 s" with seconds and "3.071 min" with auto, keeping the number of decimals.
+func (f Format) Duration(ms float64, decimals int) string {
+	number, unit := f.DurationParts(ms, decimals)
+	return number + " " + unit
+}
+
+// DurationParts is Duration split into the number and its unit, so columns of times can be aligned.
+func (f Format) DurationParts(ms float64, decimals int) (string, string) {
+	switch {
+	case f.Units == UnitsAuto && math.Abs(ms) >= 60000:
+		return f.Number(ms/60000, decimals), "min"
+	case f.Units == UnitsSeconds, f.Units == UnitsAuto && math.Abs(ms) >= 1000:
+		return f.Number(ms/1000, decimals), "s"
+	default:
+		return f.Number(ms, decimals), "ms"
+	}
+}
+
+// Bytes prints a size in binary units, e.g. "1.50 GiB".
+func (f Format) Bytes(bytes float64) string {
+	switch {
+	case bytes >= 1<<30:
+		return f.Number(bytes/(1<<30), 2) + " GiB"
+	case bytes >= 1<<20:
+		return f.Number(bytes/(1<<20), 2) + " MiB"
+	case bytes >= 1<<10:
+		return f.Number(bytes/(1<<10), 2) + " KiB"
+	default:
+		return f.Number(bytes, 0) + " B"
+	}
+}
+
+// Blocks prints a count of 8 KiB buffers as a size.
+func (f Format) Blocks(blocks int64) string {
+	if blocks <= 0 {
+		return "0"
+	}
+	return f.Bytes(float64(blocks) * 8192)
+}
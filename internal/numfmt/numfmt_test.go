@@ -0,0 +1,60 @@
+package numfmt_test
+
+import (
+	"testing"
+
+	"github.com/mickamy/xplain/internal/numfmt"
+)
+
+func TestFormat(t *testing.T) {
+	de, err := numfmt.ParseLocale("de_DE.UTF-8")
+	if err != nil {
+		t.Fatalf("parse locale: %v", err)
+	}
+	en, err := numfmt.ParseLocale("en-US")
+	if err != nil {
+		t.Fatalf("parse locale: %v", err)
+	}
+	cases := []struct {
+		name string
+		got  string
+		want string
+	}{
+		{"raw", numfmt.Format{}.Duration(184232.123, 3), "184232.123 ms"},
+		{"raw count", numfmt.Format{}.Count(1234567), "1234567"},
+		{"en", numfmt.Format{Locale: en}.Duration(184232.123, 3), "184,232.123 ms"},
+		{"de", numfmt.Format{Locale: de}.Duration(184232.123, 3), "184.232,123 ms"},
+		{"de count", numfmt.Format{Locale: de}.Count(-1234567), "-1.234.567"},
+		{"seconds", numfmt.Format{Locale: en, Units: numfmt.UnitsSeconds}.Duration(12.5, 3), "0.013 s"},
+		{"auto long", numfmt.Format{Locale: en, Units: numfmt.UnitsAuto}.Duration(18423.212, 3), "18.423 s"},
+		{"auto minutes", numfmt.Format{Units: numfmt.UnitsAuto}.Duration(184232.123, 3), "3.071 min"},
+		{"auto short", numfmt.Format{Locale: en, Units: numfmt.UnitsAuto}.Duration(999.5, 2), "999.50 ms"},
+		{"bytes", numfmt.Format{Locale: de}.Bytes(1.5 * (1 << 30)), "1,50 GiB"},
+	}
+	for _, tc := range cases {
+		if tc.got != tc.want {
+			t.Errorf("%s: expected %q, got %q", tc.name, tc.want, tc.got)
+		}
+	}
+}
+
+func TestParseLocale(t *testing.T) {
+	if l, err := numfmt.ParseLocale("C"); err != nil || l != (numfmt.Locale{}) {
+		t.Fatalf("expected C to keep the raw formatting, got %+v, %v", l, err)
+	}
+	if _, err := numfmt.ParseLocale("xx_YY"); err == nil {
+		t.Fatalf("expected unknown locales to fail")
+	}
+	t.Setenv("LC_ALL", "")
+	t.Setenv("LC_NUMERIC", "fr_FR.UTF-8")
+	l, err := numfmt.ParseLocale("auto")
+	if err != nil {
+		t.Fatalf("parse auto: %v", err)
+	}
+	if got := (numfmt.Format{Locale: l}).Number(1234.5, 1); got != "1\u202f234,5" {
+		t.Fatalf("expected the French separators from LC_NUMERIC, got %q", got)
+	}
+	if _, err := numfmt.ParseUnits("minutes"); err == nil {
+		t.Fatalf("expected unknown units to fail")
+	}
+}
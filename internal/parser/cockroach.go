@@ -0,0 +1,314 @@
+package parser
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/mickamy/xplain/internal/model"
+)
+
+var (
+	// cockroachQualifier splits "hash join (left outer)" into the operator and its qualifier.
+	cockroachQualifier = regexp.MustCompile(`^(.*?)\s*\(([^)]*)\)$`)
+	cockroachDetail    = regexp.MustCompile(`^([a-zA-Z][\w ./-]*?):\s*(.*)$`)
+)
+
+// cockroachOperators maps CockroachDB operators to the PostgreSQL node types whose insights apply.
+// Scans are resolved from their spans; operators missing here keep their CockroachDB name.
+var cockroachOperators = map[string]string{
+	"filter": "Result", "render": "Result", "limit": "Limit", "sort": "Sort", "top-k": "Sort",
+	"hash join": "Hash Join", "merge join": "Merge Join", "lookup join": "Nested Loop",
+	"index join": "Nested Loop", "cross join": "Nested Loop", "apply join": "Nested Loop",
+	"inverted join": "Nested Loop", "zigzag join": "Nested Loop", "group": "Aggregate",
+	"distinct": "Unique", "window": "WindowAgg", "union all": "Append", "union": "SetOp",
+	"intersect": "SetOp", "except": "SetOp", "values": "Values Scan", "virtual table": "Function Scan",
+	"insert": "ModifyTable", "insert fast path": "ModifyTable", "update": "ModifyTable",
+	"upsert": "ModifyTable", "delete": "ModifyTable", "delete range": "ModifyTable",
+}
+
+var cockroachJoinTypes = map[string]string{
+	"inner": "Inner", "left outer": "Left", "right outer": "Right", "full outer": "Full",
+	"semi": "Semi", "left semi": "Semi", "anti": "Anti", "left anti": "Anti",
+}
+
+var cockroachStrategies = map[string]string{"hash": "Hashed", "streaming": "Sorted", "scalar": "Plain"}
+
+// ParseCockroach reads CockroachDB's EXPLAIN ANALYZE output, the "•" tree printed by the SQL shell,
+// or a statement bundle from EXPLAIN ANALYZE (DEBUG), whose plan.txt holds that tree next to
+// statement.sql. Operators are mapped to their PostgreSQL counterparts, so reports and diffs treat
+// the plan like any other; the CockroachDB name is kept in the node's "CockroachDB Operator".
+func ParseCockroach(r io.Reader) (*model.Explain, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("explain cockroach: read: %w", err)
+	}
+	var query string
+	if isZip(data) {
+		if data, query, err = statementBundle(data); err != nil {
+			return nil, err
+		}
+	}
+	lines, err := textLines(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	entry := map[string]any{}
+	if query != "" {
+		entry["Query Text"] = query
+	}
+	type crdbNode struct {
+		node   *textNode
+		column int
+	}
+	var (
+		root    *textNode
+		stack   []crdbNode
+		current *textNode
+	)
+	for _, line := range lines {
+		text, column := trimTree(line.text)
+		column += line.column
+		// The SQL shell frames the output with an "info" header and a "Time: ..." footer.
+		if text == "" || text == "info" || strings.HasPrefix(text, "Time: ") {
+			continue
+		}
+		if name, ok := strings.CutPrefix(text, "• "); ok {
+			node := &textNode{data: cockroachNode(strings.TrimSpace(name))}
+			for len(stack) > 0 && stack[len(stack)-1].column >= column {
+				stack = stack[:len(stack)-1]
+			}
+			if len(stack) == 0 {
+				if root != nil {
+					return nil, errors.New("explain cockroach: more than one root operator")
+				}
+				root = node
+			} else {
+				parent := stack[len(stack)-1].node
+				parent.children = append(parent.children, node)
+			}
+			stack = append(stack, crdbNode{node: node, column: column})
+			current = node
+			continue
+		}
+		m := cockroachDetail.FindStringSubmatch(text)
+		if m == nil {
+			continue
+		}
+		if current == nil {
+			cockroachStatement(entry, m[1], m[2])
+		} else {
+			cockroachAttribute(current.data, m[1], m[2])
+		}
+	}
+	if root == nil {
+		return nil, errors.New("explain cockroach: no plan operators found")
+	}
+	entry["Plan"] = root.finish()
+	return buildExplain(entry, nil, "explain cockroach")
+}
+
+// isCockroach reports whether a text plan is CockroachDB's "•" tree rather than PostgreSQL's.
+func isCockroach(lines []textLine) bool {
+	for _, line := range lines {
+		if text, _ := trimTree(line.text); strings.HasPrefix(text, "• ") {
+			return true
+		}
+	}
+	return false
+}
+
+func isZip(data []byte) bool {
+	return bytes.HasPrefix(data, []byte("PK\x03\x04"))
+}
+
+// statementBundle returns plan.txt and the statement of a CockroachDB statement bundle zip.
+func statementBundle(data []byte) ([]byte, string, error) {
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, "", fmt.Errorf("explain cockroach: open statement bundle: %w", err)
+	}
+	var (
+		plan  []byte
+		query string
+	)
+	for _, file := range archive.File {
+		name := path.Base(file.Name)
+		if name != "plan.txt" && name != "statement.sql" && name != "statement.txt" {
+			continue
+		}
+		content, err := readZipFile(file)
+		if err != nil {
+			return nil, "", fmt.Errorf("explain cockroach: read %s: %w", file.Name, err)
+		}
+		if name == "plan.txt" {
+			plan = content
+		} else {
+			query = strings.TrimSpace(string(content))
+		}
+	}
+	if plan == nil {
+		return nil, "", errors.New("explain cockroach: statement bundle has no plan.txt")
+	}
+	return plan, query, nil
+}
+
+func readZipFile(file *zip.File) ([]byte, error) {
+	rc, err := file.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = rc.Close()
+	}()
+	return io.ReadAll(rc)
+}
+
+// trimTree strips the "│", "├──" and "└──" drawing ahead of a line, returning the rest and the
+// column, in characters, it starts at.
+func trimTree(text string) (string, int) {
+	column := 0
+	for _, r := range text {
+		if r != ' ' && r != '│' && r != '├' && r != '└' && r != '─' {
+			break
+		}
+		column++
+	}
+	return strings.TrimSpace(string([]rune(text)[column:])), column
+}
+
+func cockroachNode(name string) map[string]any {
+	operator, qualifier := name, ""
+	if m := cockroachQualifier.FindStringSubmatch(name); m != nil {
+		operator, qualifier = m[1], m[2]
+	}
+	data := map[string]any{"CockroachDB Operator": name}
+	nodeType, ok := cockroachOperators[operator]
+	if !ok {
+		nodeType = operator
+	}
+	data["Node Type"] = nodeType
+	switch {
+	case operator == "scan":
+		data["Node Type"] = "Index Scan"
+	case strings.HasSuffix(nodeType, "Join") || nodeType == "Nested Loop":
+		if joinType, ok := cockroachJoinTypes[qualifier]; ok {
+			data["Join Type"] = joinType
+		} else {
+			data["Join Type"] = "Inner"
+		}
+	case nodeType == "Aggregate":
+		if strategy, ok := cockroachStrategies[qualifier]; ok {
+			data["Strategy"] = strategy
+		}
+	case nodeType == "ModifyTable":
+		verb := strings.Fields(operator)[0]
+		data["Operation"] = strings.ToUpper(verb[:1]) + verb[1:]
+	}
+	return data
+}
+
+// cockroachStatement stores a statement-level line such as "planning time: 10µs".
+func cockroachStatement(entry map[string]any, key, value string) {
+	switch key {
+	case "planning time":
+		entry["Planning Time"] = cockroachMillis(value)
+	case "execution time":
+		entry["Execution Time"] = cockroachMillis(value)
+	default:
+		entry["CockroachDB "+key] = value
+	}
+}
+
+// cockroachAttribute stores one "key: value" line of an operator.
+func cockroachAttribute(data map[string]any, key, value string) {
+	switch key {
+	case "actual row count":
+		data["Actual Rows"] = cockroachCount(value)
+		data["Actual Loops"] = 1.0
+	case "estimated row count":
+		data["Plan Rows"] = cockroachCount(value)
+	case "execution time":
+		data["Actual Total Time"] = cockroachMillis(value)
+	case "KV time":
+		// Scans report the time spent reading from the storage layer rather than an execution time.
+		if _, ok := data["Actual Total Time"]; !ok {
+			data["Actual Total Time"] = cockroachMillis(value)
+		}
+		data["CockroachDB KV time"] = value
+	case "table":
+		table, index, _ := strings.Cut(value, "@")
+		data["Relation Name"] = table
+		if index != "" {
+			data["Index Name"] = index
+		}
+	case "spans":
+		if strings.HasPrefix(value, "FULL SCAN") && data["Node Type"] == "Index Scan" {
+			data["Node Type"] = "Seq Scan"
+		}
+		data["CockroachDB spans"] = value
+	case "filter":
+		data["Filter"] = value
+	case "pred":
+		data["Join Filter"] = value
+	case "equality":
+		if data["Node Type"] == "Merge Join" {
+			data["Merge Cond"] = value
+		} else {
+			data["Hash Cond"] = value
+		}
+	case "order":
+		data["Sort Key"] = cockroachOrdering(value)
+	case "group by":
+		data["Group Key"] = cockroachList(value)
+	default:
+		data["CockroachDB "+key] = value
+	}
+}
+
+// cockroachOrdering converts "+a,-b" to the Sort Key list PostgreSQL reports: "a", "b DESC".
+func cockroachOrdering(value string) []any {
+	var keys []any
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		switch {
+		case strings.HasPrefix(item, "-"):
+			keys = append(keys, strings.TrimPrefix(item, "-")+" DESC")
+		case item != "":
+			keys = append(keys, strings.TrimPrefix(item, "+"))
+		}
+	}
+	return keys
+}
+
+func cockroachList(value string) []any {
+	var items []any
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// cockroachCount reads counts such as "1,000" or "1,000 (100% of the table; stats collected 2 minutes ago)".
+func cockroachCount(value string) float64 {
+	first, _, _ := strings.Cut(strings.TrimSpace(value), " ")
+	return number(strings.ReplaceAll(first, ",", ""))
+}
+
+// cockroachMillis converts durations such as "850µs" or "1.2s" to milliseconds.
+func cockroachMillis(value string) float64 {
+	d, err := time.ParseDuration(strings.TrimSpace(value))
+	if err != nil {
+		return 0
+	}
+	return float64(d) / float64(time.Millisecond)
+}
@@ -0,0 +1,75 @@
+package parser_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mickamy/xplain/internal/parser"
+	"github.com/mickamy/xplain/test"
+)
+
+func TestParseCockroach(t *testing.T) {
+	analysis := test.LoadSampleAnalysis(t, "cockroach_analyze.txt")
+	if analysis.ExecutionTimeMs != 412 || analysis.PlanningTimeMs != 1 {
+		t.Fatalf("unexpected statement times %.3f / %.3f", analysis.ExecutionTimeMs, analysis.PlanningTimeMs)
+	}
+	root := analysis.Root.Node
+	if root.NodeType != "Sort" || root.ActualRows != 20 || root.PlanRows != 19 || strings.Join(root.SortKey, ",") != "total DESC" {
+		t.Fatalf("unexpected root %+v", root)
+	}
+	group := analysis.Root.Children[0].Node
+	if group.NodeType != "Aggregate" || group.Strategy != "Hashed" || strings.Join(group.GroupKey, ",") != "customer_id" {
+		t.Fatalf("unexpected group %+v", group)
+	}
+	join := analysis.Root.Children[0].Children[0]
+	if join.Node.NodeType != "Hash Join" || join.Node.JoinType != "Inner" || join.Node.HashCond != "(customer_id) = (id)" {
+		t.Fatalf("unexpected join %+v", join.Node)
+	}
+	orders, customers := join.Children[0].Node, join.Children[1].Node
+	if orders.NodeType != "Seq Scan" || orders.RelationName != "orders" || orders.ActualTotalTime != 352 || orders.ActualRows != 1_000_000 {
+		t.Fatalf("unexpected full scan %+v", orders)
+	}
+	if customers.NodeType != "Index Scan" || customers.IndexName != "customers_region_idx" || customers.ParentRelationship != "Inner" {
+		t.Fatalf("unexpected index scan %+v", customers)
+	}
+	if orders.Extra["CockroachDB Operator"] != "scan" {
+		t.Fatalf("expected the CockroachDB operator to be kept, got %v", orders.Extra)
+	}
+}
+
+func TestParseCockroachStatementBundle(t *testing.T) {
+	plan, err := os.ReadFile(filepath.Join(test.RootPath(t), "samples", "cockroach_analyze.txt"))
+	if err != nil {
+		t.Fatalf("read sample: %v", err)
+	}
+	var bundle bytes.Buffer
+	w := zip.NewWriter(&bundle)
+	for name, content := range map[string][]byte{
+		"statement.sql": []byte("SELECT customer_id, sum(total) FROM orders JOIN customers ON ...;\n"),
+		"plan.txt":      plan,
+		"env.sql":       []byte("SET CLUSTER SETTING ...;\n"),
+	} {
+		f, err := w.Create(name)
+		if err != nil {
+			t.Fatalf("create %s: %v", name, err)
+		}
+		if _, err := f.Write(content); err != nil {
+			t.Fatalf("write %s: %v", name, err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("close bundle: %v", err)
+	}
+
+	explain, err := parser.Parse(&bundle)
+	if err != nil {
+		t.Fatalf("parse bundle: %v", err)
+	}
+	if !strings.HasPrefix(explain.QueryText, "SELECT customer_id") || explain.Plan.NodeType != "Sort" {
+		t.Fatalf("unexpected plan %+v", explain)
+	}
+}
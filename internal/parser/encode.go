@@ -0,0 +1,191 @@
+package parser
+
+import (
+	"bytes"
+	"encoding/json"
+	"sort"
+
+	"github.com/mickamy/xplain/internal/model"
+)
+
+// field is one property of an encoded plan object.
+type field struct {
+	key   string
+	value any
+}
+
+// object is a JSON object that keeps its properties in the order EXPLAIN prints them.
+type object []field
+
+func (o object) MarshalJSON() ([]byte, error) {
+	var b bytes.Buffer
+	b.WriteByte('{')
+	for i, f := range o {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		key, err := json.Marshal(f.key)
+		if err != nil {
+			return nil, err
+		}
+		value, err := json.Marshal(f.value)
+		if err != nil {
+			return nil, err
+		}
+		b.Write(key)
+		b.WriteByte(':')
+		b.Write(value)
+	}
+	b.WriteByte('}')
+	return b.Bytes(), nil
+}
+
+// set appends the property unless it holds the zero value, which the decoder treats as absent anyway.
+func (o *object) set(key string, value any) {
+	switch v := value.(type) {
+	case string:
+		if v == "" {
+			return
+		}
+	case float64:
+		if v == 0 {
+			return
+		}
+	case int64:
+		if v == 0 {
+			return
+		}
+	case []string:
+		if len(v) == 0 {
+			return
+		}
+	}
+	*o = append(*o, field{key, value})
+}
+
+// extra appends the properties the decoder kept aside, sorted so the output is stable.
+func (o *object) extra(values map[string]any) {
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		*o = append(*o, field{k, values[k]})
+	}
+}
+
+// EncodePlan renders a plan node and its children as a standalone EXPLAIN (FORMAT JSON) document that
+// this package parses back into an equivalent tree. The node's parent relationship is dropped, since it
+// becomes the root.
+func EncodePlan(node *model.PlanNode) ([]byte, error) {
+	root := encodeNode(node)
+	for i, f := range root {
+		if f.key == "Parent Relationship" {
+			root = append(root[:i], root[i+1:]...)
+			break
+		}
+	}
+	return json.MarshalIndent([]object{{{"Plan", root}}}, "", "  ")
+}
+
+func encodeNode(node *model.PlanNode) object {
+	var o object
+	o.set("Node Type", node.NodeType)
+	o.set("Operation", node.Operation)
+	o.set("Parent Relationship", node.ParentRelationship)
+	o.set("Subplan Name", node.SubplanName)
+	o.set("Strategy", node.Strategy)
+	o.set("Partial Mode", node.PartialMode)
+	o.set("Join Type", node.JoinType)
+	o.set("Relation Name", node.RelationName)
+	o.set("Schema", node.Schema)
+	o.set("CTE Name", node.CTEName)
+	o.set("Alias", node.Alias)
+	o.set("Index Name", node.IndexName)
+	o.set("Startup Cost", node.StartupCost)
+	o.set("Total Cost", node.TotalCost)
+	o.set("Plan Rows", node.PlanRows)
+	o.set("Plan Width", node.PlanWidth)
+	o.set("Actual Startup Time", node.ActualStartupTime)
+	o.set("Actual Total Time", node.ActualTotalTime)
+	o.set("Actual Rows", node.ActualRows)
+	o.set("Actual Loops", node.ActualLoops)
+	o.set("Output", node.Output)
+	o.set("Workers Planned", node.WorkersPlanned)
+	o.set("Workers Launched", node.WorkersLaunched)
+	o.set("Sort Key", node.SortKey)
+	o.set("Group Key", node.GroupKey)
+	o.set("Sort Method", node.SortMethod)
+	o.set("Sort Space Used", node.SortSpaceUsedKB)
+	o.set("Sort Space Type", node.SortSpaceType)
+	o.set("Hash Buckets", node.HashBuckets)
+	o.set("Original Hash Buckets", node.OriginalHashBuckets)
+	o.set("Hash Batches", node.HashBatches)
+	o.set("Original Hash Batches", node.OriginalHashBatches)
+	o.set("Peak Memory Usage", node.PeakMemoryKB)
+	o.set("Cache Hits", node.CacheHits)
+	o.set("Cache Misses", node.CacheMisses)
+	o.set("Cache Evictions", node.CacheEvictions)
+	o.set("Cache Overflows", node.CacheOverflows)
+	o.set("Hash Cond", node.HashCond)
+	o.set("Merge Cond", node.MergeCond)
+	o.set("Join Filter", node.JoinFilter)
+	o.set("Rows Removed by Join Filter", node.RowsRemovedJoinFilter)
+	o.set("Filter", node.Filter)
+	o.set("Rows Removed by Filter", node.RowsRemovedFilter)
+	o.set("Rows Removed by Index Recheck", node.RowsRemovedIndexRecheck)
+	o.set("Heap Fetches", node.HeapFetches)
+	o.set("Exact Heap Blocks", node.ExactHeapBlocks)
+	o.set("Lossy Heap Blocks", node.LossyHeapBlocks)
+	o.set("Subplans Removed", node.SubplansRemoved)
+	encodeBuffers(&o, node.Buffers)
+	o.extra(node.Extra)
+	if len(node.Workers) > 0 {
+		workers := make([]object, 0, len(node.Workers))
+		for _, w := range node.Workers {
+			var wo object
+			wo = append(wo, field{"Worker Number", w.Number})
+			wo.set("Actual Startup Time", w.ActualStartupTime)
+			wo.set("Actual Total Time", w.ActualTotalTime)
+			wo.set("Actual Rows", w.ActualRows)
+			wo.set("Actual Loops", w.ActualLoops)
+			wo.set("Sort Method", w.SortMethod)
+			wo.set("Sort Space Used", w.SortSpaceUsedKB)
+			wo.set("Sort Space Type", w.SortSpaceType)
+			wo.set("Peak Memory Usage", w.PeakMemoryKB)
+			encodeBuffers(&wo, w.Buffers)
+			wo.extra(w.Extra)
+			workers = append(workers, wo)
+		}
+		o = append(o, field{"Workers", workers})
+	}
+	if len(node.Children) > 0 {
+		children := make([]object, 0, len(node.Children))
+		for _, child := range node.Children {
+			children = append(children, encodeNode(child))
+		}
+		o = append(o, field{"Plans", children})
+	}
+	return o
+}
+
+// encodeBuffers writes buffer counters and I/O timings under their PostgreSQL 17 names.
+func encodeBuffers(o *object, b model.Buffers) {
+	o.set("Shared Hit Blocks", b.SharedHit)
+	o.set("Shared Read Blocks", b.SharedRead)
+	o.set("Shared Dirtied Blocks", b.SharedDirtied)
+	o.set("Shared Written Blocks", b.SharedWritten)
+	o.set("Local Hit Blocks", b.LocalHit)
+	o.set("Local Read Blocks", b.LocalRead)
+	o.set("Local Dirtied Blocks", b.LocalDirtied)
+	o.set("Local Written Blocks", b.LocalWritten)
+	o.set("Temp Read Blocks", b.TempRead)
+	o.set("Temp Written Blocks", b.TempWritten)
+	o.set("Shared I/O Read Time", b.SharedReadTimeMs)
+	o.set("Shared I/O Write Time", b.SharedWriteTimeMs)
+	o.set("Local I/O Read Time", b.LocalReadTimeMs)
+	o.set("Local I/O Write Time", b.LocalWriteTimeMs)
+	o.set("Temp I/O Read Time", b.TempReadTimeMs)
+	o.set("Temp I/O Write Time", b.TempWriteTimeMs)
+}
@@ -0,0 +1,43 @@
+package parser_test
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/mickamy/xplain/internal/parser"
+	"github.com/mickamy/xplain/test"
+)
+
+func TestEncodePlanRoundTrip(t *testing.T) {
+	for _, name := range []string{"pgbench_hot.json", "pgbench_hot.txt", "pgbench_hot.yaml", "envelope_orders.json", "cte_subplan.json"} {
+		original := test.LoadSampleAnalysis(t, name).Root.Node
+		encoded, err := parser.EncodePlan(original)
+		if err != nil {
+			t.Fatalf("%s: encode: %v", name, err)
+		}
+		decoded, err := parser.ParseJSON(bytes.NewReader(encoded))
+		if err != nil {
+			t.Fatalf("%s: parse encoded plan: %v", name, err)
+		}
+		if !reflect.DeepEqual(decoded.Plan, original) {
+			t.Fatalf("%s: round trip changed the plan:\n%s", name, encoded)
+		}
+	}
+
+	subtree := test.LoadSampleAnalysis(t, "pgbench_hot.json").Root.Children[0].Children[0].Node
+	encoded, err := parser.EncodePlan(subtree)
+	if err != nil {
+		t.Fatalf("encode subtree: %v", err)
+	}
+	decoded, err := parser.ParseJSON(bytes.NewReader(encoded))
+	if err != nil {
+		t.Fatalf("parse subtree: %v", err)
+	}
+	if decoded.Plan.ID != "0" || decoded.Plan.NodeType != "Sort" || decoded.Plan.ParentRelationship != "" {
+		t.Fatalf("expected the Sort as a standalone root, got %+v", decoded.Plan)
+	}
+	if len(decoded.Plan.Children) != 1 || decoded.Plan.Children[0].ID != "0.0" {
+		t.Fatalf("expected the scan below the new root, got %+v", decoded.Plan.Children)
+	}
+}
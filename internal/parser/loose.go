@@ -0,0 +1,80 @@
+package parser
+
+import (
+	"bytes"
+	"encoding/json"
+	"regexp"
+	"strings"
+)
+
+var (
+	// recordHeader is the "-[ RECORD 1 ]---" line psql's expanded mode (\x) prints ahead of each row.
+	recordHeader = regexp.MustCompile(`^-\[ RECORD \d+ \]-*\+?-*$`)
+	// expandedCell is the "QUERY PLAN | " column label of expanded mode, or the bare "| " of its continuation lines.
+	expandedCell = regexp.MustCompile(`^\s*(?:QUERY PLAN\s*)?\|\s?`)
+)
+
+// cleanJSON strips the decorations a JSON plan picks up when copied out of psql or a log: the column
+// header, its underline and the row count footer, " +" continuation markers, expanded-mode labels, and
+// quotes wrapped around the whole document with the quotes inside escaped (\" or CSV-style "", and
+// doubled single quotes inside SQL single quotes). Input that does not turn into a JSON document is
+// returned unchanged, so text and YAML plans pass through.
+func cleanJSON(data []byte) []byte {
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) > 0 && (trimmed[0] == '[' || trimmed[0] == '{') && json.Valid(trimmed) {
+		return data
+	}
+
+	lines := strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n")
+	kept := make([]string, 0, len(lines))
+	for _, line := range lines {
+		line = strings.TrimRight(line, " ")
+		if psqlHeader.MatchString(line) || recordHeader.MatchString(strings.TrimSpace(line)) {
+			continue
+		}
+		line = expandedCell.ReplaceAllString(line, "")
+		if trimmedLine := strings.TrimRight(line, " "); strings.HasSuffix(trimmedLine, "+") {
+			line = strings.TrimSuffix(trimmedLine, "+")
+		}
+		kept = append(kept, line)
+	}
+	text := strings.TrimSpace(strings.Join(kept, "\n"))
+	text = unquoteDocument(text)
+	if text == "" || (text[0] != '[' && text[0] != '{') {
+		return data
+	}
+	return []byte(text)
+}
+
+// unquoteDocument removes quotes around a whole document and the escaping they forced on the quotes
+// inside. A document whose quotes are escaped without surrounding quotes, as some loggers write it, is
+// unescaped too.
+func unquoteDocument(text string) string {
+	quoted := len(text) >= 2 && text[0] == '"' && text[len(text)-1] == '"'
+	switch {
+	case quoted && strings.Contains(text, `""`) && !strings.Contains(text, `\"`):
+		return strings.ReplaceAll(text[1:len(text)-1], `""`, `"`)
+	case quoted:
+		if s, ok := decodeString(text[1 : len(text)-1]); ok {
+			return strings.TrimSpace(s)
+		}
+	case len(text) >= 2 && text[0] == '\'' && text[len(text)-1] == '\'':
+		return strings.TrimSpace(strings.ReplaceAll(text[1:len(text)-1], "''", "'"))
+	case strings.HasPrefix(text, `{\"`) || strings.HasPrefix(text, `[{\"`) || strings.Contains(text, `\n`) && !strings.Contains(text, "\n"):
+		if s, ok := decodeString(text); ok {
+			return strings.TrimSpace(s)
+		}
+	}
+	return text
+}
+
+// decodeString decodes s as the body of a JSON string, tolerating the raw newlines and tabs copying
+// leaves in it.
+func decodeString(s string) (string, bool) {
+	s = strings.NewReplacer("\n", `\n`, "\t", `\t`, "\r", "").Replace(s)
+	var out string
+	if err := json.Unmarshal([]byte(`"`+s+`"`), &out); err != nil {
+		return "", false
+	}
+	return out, true
+}
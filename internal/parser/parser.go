@@ -1,6 +1,7 @@
 package parser
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -12,6 +13,80 @@ import (
 	"github.com/mickamy/xplain/internal/model"
 )
 
+// Parse reads an EXPLAIN document in FORMAT JSON, FORMAT YAML or the default text format, or
+// CockroachDB's EXPLAIN ANALYZE output, telling them apart by how the document starts. JSON copied
+// out of psql or a log is cleaned up first (see cleanJSON).
+func Parse(r io.Reader) (*model.Explain, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("explain: read: %w", err)
+	}
+	data = cleanJSON(data)
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) == 0 {
+		return nil, errors.New("explain: empty input")
+	}
+	if trimmed[0] == '[' || trimmed[0] == '{' {
+		return ParseJSON(bytes.NewReader(data))
+	}
+	if isZip(data) {
+		return ParseCockroach(bytes.NewReader(data))
+	}
+	return ParseFormat(bytes.NewReader(data), detectFormat(data))
+}
+
+// ParseAll is Parse for input that may hold several plans. Only JSON carries more than one (see
+// ParseJSONAll); the other formats yield a single plan.
+func ParseAll(r io.Reader) ([]*model.Explain, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("explain: read: %w", err)
+	}
+	data = cleanJSON(data)
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) > 0 && (trimmed[0] == '[' || trimmed[0] == '{') {
+		return ParseJSONAll(bytes.NewReader(data))
+	}
+	explain, err := Parse(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	return []*model.Explain{explain}, nil
+}
+
+// ParseFormat reads an EXPLAIN document in the named format: "json", "yaml", "text" or "cockroach".
+func ParseFormat(r io.Reader, format string) (*model.Explain, error) {
+	switch format {
+	case "json":
+		data, err := io.ReadAll(r)
+		if err != nil {
+			return nil, fmt.Errorf("explain: read: %w", err)
+		}
+		return ParseJSON(bytes.NewReader(cleanJSON(data)))
+	case "yaml":
+		return ParseYAML(r)
+	case "text":
+		return ParseText(r)
+	case "cockroach":
+		return ParseCockroach(r)
+	default:
+		return nil, fmt.Errorf("explain: unsupported format %q (expected json, yaml, text or cockroach)", format)
+	}
+}
+
+// detectFormat tells YAML from text by the first line after any psql header, and CockroachDB
+// plans by their "•" operators.
+func detectFormat(data []byte) string {
+	lines, err := textLines(bytes.NewReader(data))
+	switch {
+	case err == nil && len(lines) > 0 && isYAML(lines[0].text):
+		return "yaml"
+	case err == nil && isCockroach(lines):
+		return "cockroach"
+	}
+	return "text"
+}
+
 // ParseJSON reads a PostgreSQL EXPLAIN (FORMAT JSON) document and produces an Explain structure.
 func ParseJSON(r io.Reader) (*model.Explain, error) {
 	decoder := json.NewDecoder(r)
@@ -22,19 +97,76 @@ func ParseJSON(r io.Reader) (*model.Explain, error) {
 		return nil, fmt.Errorf("decode explain json: %w", err)
 	}
 
+	payload, meta, err := unwrapEnvelope(payload)
+	if err != nil {
+		return nil, err
+	}
+
 	entry, err := pickFirstEntry(payload)
 	if err != nil {
 		return nil, err
 	}
+	return buildExplain(entry, meta, "explain json")
+}
+
+// ParseJSONAll reads every plan of an EXPLAIN (FORMAT JSON) document: each entry of the top-level
+// array, and the entries of further documents concatenated after it, in order.
+func ParseJSONAll(r io.Reader) ([]*model.Explain, error) {
+	decoder := json.NewDecoder(r)
+	decoder.UseNumber()
+
+	var plans []*model.Explain
+	for {
+		var payload any
+		if err := decoder.Decode(&payload); err != nil {
+			if errors.Is(err, io.EOF) && len(plans) > 0 {
+				return plans, nil
+			}
+			return nil, fmt.Errorf("decode explain json: %w", err)
+		}
 
+		payload, meta, err := unwrapEnvelope(payload)
+		if err != nil {
+			return nil, err
+		}
+		entries, ok := payload.([]any)
+		if !ok {
+			entries = []any{payload}
+		}
+		if len(entries) == 0 {
+			return nil, errors.New("explain json: empty payload")
+		}
+		for _, raw := range entries {
+			entry, err := asObject(raw)
+			if err != nil {
+				return nil, fmt.Errorf("explain json: invalid entry: %w", err)
+			}
+			// Each plan gets its own copy of the envelope metadata so adjusting one leaves the others alone.
+			var entryMeta *model.Metadata
+			if meta != nil {
+				copied := *meta
+				entryMeta = &copied
+			}
+			explain, err := buildExplain(entry, entryMeta, fmt.Sprintf("explain json: entry %d", len(plans)+1))
+			if err != nil {
+				return nil, err
+			}
+			plans = append(plans, explain)
+		}
+	}
+}
+
+// buildExplain converts one EXPLAIN entry, keyed by the FORMAT JSON property names, into an Explain.
+// Every input format is normalised to this shape so they share a single decoder.
+func buildExplain(entry map[string]any, meta *model.Metadata, format string) (*model.Explain, error) {
 	planMapVal, ok := entry["Plan"]
 	if !ok {
-		return nil, errors.New("explain json: missing Plan root")
+		return nil, fmt.Errorf("%s: missing Plan root", format)
 	}
 
 	planMap, err := asObject(planMapVal)
 	if err != nil {
-		return nil, fmt.Errorf("explain json: invalid Plan node: %w", err)
+		return nil, fmt.Errorf("%s: invalid Plan node: %w", format, err)
 	}
 
 	root, err := parsePlanNode(planMap, "0")
@@ -43,15 +175,24 @@ func ParseJSON(r io.Reader) (*model.Explain, error) {
 	}
 
 	explain := &model.Explain{
-		Plan:          root,
-		PlanningTime:  asFloat(entry["Planning Time"]),
-		ExecutionTime: asFloat(entry["Execution Time"]),
-		Settings:      parseSettings(entry["Settings"]),
-		Extra:         map[string]any{},
+		Plan:            root,
+		PlanningTime:    asFloat(entry["Planning Time"]),
+		PlanningBuffers: parsePlanningBuffers(entry["Planning"]),
+		ExecutionTime:   asFloat(entry["Execution Time"]),
+		Settings:        parseSettings(entry["Settings"]),
+		QueryText:       asString(entry["Query Text"]),
+		Triggers:        parseTriggers(entry["Triggers"]),
+		JIT:             parseJIT(entry["JIT"]),
+		Serialization:   parseSerialization(entry["Serialization"]),
+		Metadata:        meta,
+		Extra:           map[string]any{},
+	}
+	if explain.QueryText == "" && meta != nil {
+		explain.QueryText = meta.Query
 	}
 
 	for k, v := range entry {
-		if k == "Plan" || k == "Planning Time" || k == "Execution Time" || k == "Settings" {
+		if k == "Plan" || k == "Planning" || k == "Planning Time" || k == "Execution Time" || k == "Settings" || k == "Query Text" || k == "Triggers" || k == "JIT" || k == "Serialization" {
 			continue
 		}
 		explain.Extra[k] = v
@@ -60,6 +201,29 @@ func ParseJSON(r io.Reader) (*model.Explain, error) {
 	return explain, nil
 }
 
+// unwrapEnvelope extracts the plan and capture metadata from an xplain run envelope;
+// plain EXPLAIN documents pass through unchanged.
+func unwrapEnvelope(payload any) (any, *model.Metadata, error) {
+	obj, ok := payload.(map[string]any)
+	if !ok {
+		return payload, nil, nil
+	}
+	rawMeta, hasMeta := obj["xplain"]
+	plan, hasPlan := obj["plan"]
+	if !hasMeta || !hasPlan {
+		return payload, nil, nil
+	}
+	encoded, err := json.Marshal(rawMeta)
+	if err != nil {
+		return nil, nil, fmt.Errorf("explain json: invalid xplain metadata: %w", err)
+	}
+	var meta model.Metadata
+	if err := json.Unmarshal(encoded, &meta); err != nil {
+		return nil, nil, fmt.Errorf("explain json: invalid xplain metadata: %w", err)
+	}
+	return plan, &meta, nil
+}
+
 func pickFirstEntry(payload any) (map[string]any, error) {
 	switch v := payload.(type) {
 	case []any:
@@ -80,34 +244,60 @@ func pickFirstEntry(payload any) (map[string]any, error) {
 
 func parsePlanNode(data map[string]any, path string) (*model.PlanNode, error) {
 	node := &model.PlanNode{
-		ID:                 path,
-		NodeType:           asString(data["Node Type"]),
-		RelationName:       asString(data["Relation Name"]),
-		Schema:             asString(data["Schema"]),
-		Alias:              asString(data["Alias"]),
-		ParentRelationship: asString(data["Parent Relationship"]),
-		StartupCost:        asFloat(data["Startup Cost"]),
-		TotalCost:          asFloat(data["Total Cost"]),
-		PlanRows:           asFloat(data["Plan Rows"]),
-		PlanWidth:          asFloat(data["Plan Width"]),
-		ActualStartupTime:  asFloat(data["Actual Startup Time"]),
-		ActualTotalTime:    asFloat(data["Actual Total Time"]),
-		ActualRows:         asFloat(data["Actual Rows"]),
-		ActualLoops:        asFloat(data["Actual Loops"]),
-		WorkersPlanned:     asFloat(data["Workers Planned"]),
-		WorkersLaunched:    asFloat(data["Workers Launched"]),
-		Output:             asStringSlice(data["Output"]),
-		Filter:             asString(data["Filter"]),
-		JoinType:           asString(data["Join Type"]),
-		IndexName:          asString(data["Index Name"]),
-		HashCond:           asString(data["Hash Cond"]),
-		MergeCond:          asString(data["Merge Cond"]),
-		SortKey:            asStringSlice(data["Sort Key"]),
-		GroupKey:           asStringSlice(data["Group Key"]),
-		Extra:              map[string]any{},
+		ID:                      path,
+		NodeType:                asString(data["Node Type"]),
+		RelationName:            asString(data["Relation Name"]),
+		Operation:               asString(data["Operation"]),
+		Schema:                  asString(data["Schema"]),
+		Alias:                   asString(data["Alias"]),
+		ParentRelationship:      asString(data["Parent Relationship"]),
+		CTEName:                 asString(data["CTE Name"]),
+		SubplanName:             asString(data["Subplan Name"]),
+		StartupCost:             asFloat(data["Startup Cost"]),
+		TotalCost:               asFloat(data["Total Cost"]),
+		PlanRows:                asFloat(data["Plan Rows"]),
+		PlanWidth:               asFloat(data["Plan Width"]),
+		ActualStartupTime:       asFloat(data["Actual Startup Time"]),
+		ActualTotalTime:         asFloat(data["Actual Total Time"]),
+		ActualRows:              asFloat(data["Actual Rows"]),
+		ActualLoops:             asFloat(data["Actual Loops"]),
+		WorkersPlanned:          asFloat(data["Workers Planned"]),
+		WorkersLaunched:         asFloat(data["Workers Launched"]),
+		Output:                  asStringSlice(data["Output"]),
+		Filter:                  asString(data["Filter"]),
+		RowsRemovedFilter:       asFloat(data["Rows Removed by Filter"]),
+		JoinFilter:              asString(data["Join Filter"]),
+		RowsRemovedJoinFilter:   asFloat(data["Rows Removed by Join Filter"]),
+		JoinType:                asString(data["Join Type"]),
+		IndexName:               asString(data["Index Name"]),
+		HeapFetches:             asFloat(data["Heap Fetches"]),
+		ExactHeapBlocks:         asFloat(data["Exact Heap Blocks"]),
+		LossyHeapBlocks:         asFloat(data["Lossy Heap Blocks"]),
+		RowsRemovedIndexRecheck: asFloat(data["Rows Removed by Index Recheck"]),
+		CacheHits:               asFloat(data["Cache Hits"]),
+		CacheMisses:             asFloat(data["Cache Misses"]),
+		CacheEvictions:          asFloat(data["Cache Evictions"]),
+		CacheOverflows:          asFloat(data["Cache Overflows"]),
+		SubplansRemoved:         asFloat(data["Subplans Removed"]),
+		HashCond:                asString(data["Hash Cond"]),
+		MergeCond:               asString(data["Merge Cond"]),
+		SortKey:                 asStringSlice(data["Sort Key"]),
+		GroupKey:                asStringSlice(data["Group Key"]),
+		Strategy:                asString(data["Strategy"]),
+		PartialMode:             asString(data["Partial Mode"]),
+		SortMethod:              asString(data["Sort Method"]),
+		SortSpaceUsedKB:         asFloat(data["Sort Space Used"]),
+		SortSpaceType:           asString(data["Sort Space Type"]),
+		HashBuckets:             asFloat(data["Hash Buckets"]),
+		OriginalHashBuckets:     asFloat(data["Original Hash Buckets"]),
+		HashBatches:             asFloat(data["Hash Batches"]),
+		OriginalHashBatches:     asFloat(data["Original Hash Batches"]),
+		PeakMemoryKB:            peakMemory(data),
+		Extra:                   map[string]any{},
 	}
 
 	node.Buffers = parseBuffers(data)
+	node.Workers = parseWorkers(data["Workers"])
 
 	childrenSlice := asSlice(data["Plans"])
 
@@ -125,42 +315,76 @@ func parsePlanNode(data map[string]any, path string) (*model.PlanNode, error) {
 	}
 
 	known := map[string]struct{}{
-		"Node Type":             {},
-		"Relation Name":         {},
-		"Schema":                {},
-		"Alias":                 {},
-		"Parent Relationship":   {},
-		"Startup Cost":          {},
-		"Total Cost":            {},
-		"Plan Rows":             {},
-		"Plan Width":            {},
-		"Actual Startup Time":   {},
-		"Actual Total Time":     {},
-		"Actual Rows":           {},
-		"Actual Loops":          {},
-		"Workers Planned":       {},
-		"Workers Launched":      {},
-		"Output":                {},
-		"Filter":                {},
-		"Join Type":             {},
-		"Index Name":            {},
-		"Hash Cond":             {},
-		"Merge Cond":            {},
-		"Sort Key":              {},
-		"Group Key":             {},
-		"Plans":                 {},
-		"Shared Hit Blocks":     {},
-		"Shared Read Blocks":    {},
-		"Shared Dirtied Blocks": {},
-		"Shared Written Blocks": {},
-		"Local Hit Blocks":      {},
-		"Local Read Blocks":     {},
-		"Local Dirtied Blocks":  {},
-		"Local Written Blocks":  {},
-		"Temp Read Blocks":      {},
-		"Temp Written Blocks":   {},
-		"I/O Read Time":         {},
-		"I/O Write Time":        {},
+		"Node Type":                     {},
+		"Relation Name":                 {},
+		"Operation":                     {},
+		"Schema":                        {},
+		"Alias":                         {},
+		"Parent Relationship":           {},
+		"CTE Name":                      {},
+		"Subplan Name":                  {},
+		"Startup Cost":                  {},
+		"Total Cost":                    {},
+		"Plan Rows":                     {},
+		"Plan Width":                    {},
+		"Actual Startup Time":           {},
+		"Actual Total Time":             {},
+		"Actual Rows":                   {},
+		"Actual Loops":                  {},
+		"Workers Planned":               {},
+		"Workers Launched":              {},
+		"Output":                        {},
+		"Filter":                        {},
+		"Rows Removed by Filter":        {},
+		"Join Filter":                   {},
+		"Rows Removed by Join Filter":   {},
+		"Join Type":                     {},
+		"Index Name":                    {},
+		"Heap Fetches":                  {},
+		"Exact Heap Blocks":             {},
+		"Lossy Heap Blocks":             {},
+		"Rows Removed by Index Recheck": {},
+		"Cache Hits":                    {},
+		"Cache Misses":                  {},
+		"Cache Evictions":               {},
+		"Cache Overflows":               {},
+		"Subplans Removed":              {},
+		"Hash Cond":                     {},
+		"Merge Cond":                    {},
+		"Sort Key":                      {},
+		"Group Key":                     {},
+		"Strategy":                      {},
+		"Partial Mode":                  {},
+		"Sort Method":                   {},
+		"Sort Space Used":               {},
+		"Sort Space Type":               {},
+		"Hash Buckets":                  {},
+		"Original Hash Buckets":         {},
+		"Hash Batches":                  {},
+		"Original Hash Batches":         {},
+		"Peak Memory Usage":             {},
+		"Plans":                         {},
+		"Workers":                       {},
+		"Shared Hit Blocks":             {},
+		"Shared Read Blocks":            {},
+		"Shared Dirtied Blocks":         {},
+		"Shared Written Blocks":         {},
+		"Local Hit Blocks":              {},
+		"Local Read Blocks":             {},
+		"Local Dirtied Blocks":          {},
+		"Local Written Blocks":          {},
+		"Temp Read Blocks":              {},
+		"Temp Written Blocks":           {},
+		"I/O Read Time":                 {},
+		"I/O Write Time":                {},
+		"Block Read Time":               {},
+		"Block Write Time":              {},
+		"Shared I/O Read Time":          {},
+		"Shared I/O Write Time":         {},
+		"Local I/O Read Time":           {},
+		"Local I/O Write Time":          {},
+		"Temp I/O Read Time":            {},
+		"Temp I/O Write Time":           {},
 	}
 
 	for k, v := range data {
@@ -173,22 +397,60 @@ func parsePlanNode(data map[string]any, path string) (*model.PlanNode, error) {
 	return node, nil
 }
 
+// peakMemory reads "Peak Memory Usage", or for Incremental Sort the largest in-memory group peak.
+func peakMemory(data map[string]any) float64 {
+	peak := asFloat(data["Peak Memory Usage"])
+	for _, key := range []string{"Full-sort Groups", "Pre-sorted Groups"} {
+		groups, ok := data[key].(map[string]any)
+		if !ok {
+			continue
+		}
+		if space, ok := groups["Sort Space Memory"].(map[string]any); ok {
+			peak = math.Max(peak, asFloat(space["Peak Sort Space Used"]))
+		}
+	}
+	return peak
+}
+
 func parseBuffers(data map[string]any) model.Buffers {
 	return model.Buffers{
-		SharedHit:       asInt64(data["Shared Hit Blocks"]),
-		SharedRead:      asInt64(data["Shared Read Blocks"]),
-		SharedDirtied:   asInt64(data["Shared Dirtied Blocks"]),
-		SharedWritten:   asInt64(data["Shared Written Blocks"]),
-		LocalHit:        asInt64(data["Local Hit Blocks"]),
-		LocalRead:       asInt64(data["Local Read Blocks"]),
-		LocalDirtied:    asInt64(data["Local Dirtied Blocks"]),
-		LocalWritten:    asInt64(data["Local Written Blocks"]),
-		TempRead:        asInt64(data["Temp Read Blocks"]),
-		TempWritten:     asInt64(data["Temp Written Blocks"]),
-		IOReadTimeMs:    asFloat(data["I/O Read Time"]),
-		IOWriteTimeMs:   asFloat(data["I/O Write Time"]),
-		BlockReadTimeMs: asFloat(data["Block Read Time"]),
+		SharedHit:     asInt64(data["Shared Hit Blocks"]),
+		SharedRead:    asInt64(data["Shared Read Blocks"]),
+		SharedDirtied: asInt64(data["Shared Dirtied Blocks"]),
+		SharedWritten: asInt64(data["Shared Written Blocks"]),
+		LocalHit:      asInt64(data["Local Hit Blocks"]),
+		LocalRead:     asInt64(data["Local Read Blocks"]),
+		LocalDirtied:  asInt64(data["Local Dirtied Blocks"]),
+		LocalWritten:  asInt64(data["Local Written Blocks"]),
+		TempRead:      asInt64(data["Temp Read Blocks"]),
+		TempWritten:   asInt64(data["Temp Written Blocks"]),
+		// PostgreSQL 17 split "I/O Read Time" into shared and local timings.
+		SharedReadTimeMs:  firstFloat(data, "Shared I/O Read Time", "I/O Read Time", "Block Read Time"),
+		SharedWriteTimeMs: firstFloat(data, "Shared I/O Write Time", "I/O Write Time", "Block Write Time"),
+		LocalReadTimeMs:   asFloat(data["Local I/O Read Time"]),
+		LocalWriteTimeMs:  asFloat(data["Local I/O Write Time"]),
+		TempReadTimeMs:    asFloat(data["Temp I/O Read Time"]),
+		TempWriteTimeMs:   asFloat(data["Temp I/O Write Time"]),
+	}
+}
+
+// parsePlanningBuffers reads the top-level "Planning" block EXPLAIN emits alongside "Planning Time".
+func parsePlanningBuffers(val any) model.Buffers {
+	data, err := asObject(val)
+	if err != nil {
+		return model.Buffers{}
 	}
+	return parseBuffers(data)
+}
+
+// firstFloat returns the value of the first key present, so renamed fields parse across server versions.
+func firstFloat(data map[string]any, keys ...string) float64 {
+	for _, key := range keys {
+		if val, ok := data[key]; ok {
+			return asFloat(val)
+		}
+	}
+	return 0
 }
 
 func parseSettings(val any) map[string]string {
@@ -227,6 +489,110 @@ func parseSettings(val any) map[string]string {
 	return result
 }
 
+func parseTriggers(val any) []model.Trigger {
+	var out []model.Trigger
+	for _, entry := range asSlice(val) {
+		item, err := asObject(entry)
+		if err != nil {
+			continue
+		}
+		out = append(out, model.Trigger{
+			Name:           asString(item["Trigger Name"]),
+			ConstraintName: asString(item["Constraint Name"]),
+			Relation:       asString(item["Relation"]),
+			TimeMs:         asFloat(item["Time"]),
+			Calls:          asFloat(item["Calls"]),
+		})
+	}
+	return out
+}
+
+func parseJIT(val any) *model.JIT {
+	data, err := asObject(val)
+	if err != nil {
+		return nil
+	}
+	options, _ := asObject(data["Options"])
+	timing, _ := asObject(data["Timing"])
+	jit := &model.JIT{
+		Functions:      int(asInt64(data["Functions"])),
+		Inlining:       asBool(options["Inlining"]),
+		Optimization:   asBool(options["Optimization"]),
+		Expressions:    asBool(options["Expressions"]),
+		Deforming:      asBool(options["Deforming"]),
+		InliningMs:     asFloat(timing["Inlining"]),
+		OptimizationMs: asFloat(timing["Optimization"]),
+		EmissionMs:     asFloat(timing["Emission"]),
+		TotalMs:        asFloat(timing["Total"]),
+	}
+	// PostgreSQL 17 splits generation into deforming and the rest: {"Deform": 0.3, "Total": 1.2}.
+	if generation, err := asObject(timing["Generation"]); err == nil {
+		jit.GenerationMs = asFloat(generation["Total"])
+		jit.DeformMs = asFloat(generation["Deform"])
+	} else {
+		jit.GenerationMs = asFloat(timing["Generation"])
+	}
+	return jit
+}
+
+// parseSerialization reads the PostgreSQL 17 "Serialization" block of EXPLAIN (ANALYZE, SERIALIZE):
+// {"Time": 0.06, "Output Volume": 5, "Format": "text"}, plus buffer counters with BUFFERS.
+func parseSerialization(val any) *model.Serialization {
+	data, err := asObject(val)
+	if err != nil {
+		return nil
+	}
+	return &model.Serialization{
+		TimeMs:   asFloat(data["Time"]),
+		OutputKB: asFloat(data["Output Volume"]),
+		Format:   asString(data["Format"]),
+		Buffers:  parseBuffers(data),
+	}
+}
+
+// workerFields are the per-worker keys modelled by model.Worker; the rest land in its Extra.
+var workerFields = map[string]struct{}{
+	"Worker Number": {}, "Actual Startup Time": {}, "Actual Total Time": {}, "Actual Rows": {}, "Actual Loops": {},
+	"Sort Method": {}, "Sort Space Used": {}, "Sort Space Type": {}, "Peak Memory Usage": {},
+	"Shared Hit Blocks": {}, "Shared Read Blocks": {}, "Shared Dirtied Blocks": {}, "Shared Written Blocks": {},
+	"Local Hit Blocks": {}, "Local Read Blocks": {}, "Local Dirtied Blocks": {}, "Local Written Blocks": {},
+	"Temp Read Blocks": {}, "Temp Written Blocks": {},
+	"I/O Read Time": {}, "I/O Write Time": {}, "Block Read Time": {}, "Block Write Time": {},
+	"Shared I/O Read Time": {}, "Shared I/O Write Time": {}, "Local I/O Read Time": {}, "Local I/O Write Time": {},
+	"Temp I/O Read Time": {}, "Temp I/O Write Time": {},
+}
+
+// parseWorkers reads a node's "Workers" array.
+func parseWorkers(val any) []model.Worker {
+	var out []model.Worker
+	for _, entry := range asSlice(val) {
+		item, err := asObject(entry)
+		if err != nil {
+			continue
+		}
+		worker := model.Worker{
+			Number:            int(asFloat(item["Worker Number"])),
+			ActualStartupTime: asFloat(item["Actual Startup Time"]),
+			ActualTotalTime:   asFloat(item["Actual Total Time"]),
+			ActualRows:        asFloat(item["Actual Rows"]),
+			ActualLoops:       asFloat(item["Actual Loops"]),
+			SortMethod:        asString(item["Sort Method"]),
+			SortSpaceUsedKB:   asFloat(item["Sort Space Used"]),
+			SortSpaceType:     asString(item["Sort Space Type"]),
+			PeakMemoryKB:      peakMemory(item),
+			Buffers:           parseBuffers(item),
+			Extra:             map[string]any{},
+		}
+		for k, v := range item {
+			if _, ok := workerFields[k]; !ok {
+				worker.Extra[k] = v
+			}
+		}
+		out = append(out, worker)
+	}
+	return out
+}
+
 func asObject(val any) (map[string]any, error) {
 	if val == nil {
 		return nil, errors.New("nil object")
@@ -325,6 +691,17 @@ func asFloat(val any) float64 {
 	}
 }
 
+func asBool(val any) bool {
+	switch v := val.(type) {
+	case bool:
+		return v
+	case string:
+		return v == "true"
+	default:
+		return false
+	}
+}
+
 func asInt64(val any) int64 {
 	if val == nil {
 		return 0
@@ -0,0 +1,283 @@
+package parser_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mickamy/xplain/internal/analyzer"
+	"github.com/mickamy/xplain/internal/parser"
+	"github.com/mickamy/xplain/test"
+)
+
+func TestParseJSONAll(t *testing.T) {
+	input := `[
+  {"Plan": {"Node Type": "Result", "Actual Total Time": 0.01}, "Execution Time": 0.02},
+  {"Plan": {"Node Type": "Seq Scan", "Relation Name": "orders"}, "Execution Time": 1.5}
+]
+[{"Plan": {"Node Type": "Index Scan", "Relation Name": "customers"}}]
+`
+	plans, err := parser.ParseJSONAll(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if len(plans) != 3 {
+		t.Fatalf("expected 3 plans, got %d", len(plans))
+	}
+	if plans[1].Plan.RelationName != "orders" || plans[1].ExecutionTime != 1.5 || plans[2].Plan.NodeType != "Index Scan" {
+		t.Fatalf("unexpected plans %+v %+v", plans[1].Plan, plans[2].Plan)
+	}
+
+	// ParseJSON keeps reading only the first entry.
+	first, err := parser.ParseJSON(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("parse first: %v", err)
+	}
+	if first.Plan.NodeType != "Result" {
+		t.Fatalf("expected the first plan, got %s", first.Plan.NodeType)
+	}
+
+	if _, err := parser.ParseJSONAll(strings.NewReader(`[{"Plan": {"Node Type": "Result"}}, 1]`)); err == nil {
+		t.Fatal("expected an error for a non-object entry")
+	}
+}
+
+func TestParseAll(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join(test.RootPath(t), "samples", "envelope_orders.json"))
+	if err != nil {
+		t.Fatalf("read sample: %v", err)
+	}
+	plans, err := parser.ParseAll(strings.NewReader(string(data) + string(data)))
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if len(plans) != 2 || plans[0].Metadata == nil || plans[0].Metadata == plans[1].Metadata {
+		t.Fatalf("expected two plans with their own metadata, got %+v", plans)
+	}
+
+	text, err := os.ReadFile(filepath.Join(test.RootPath(t), "samples", "pgbench_hot.txt"))
+	if err != nil {
+		t.Fatalf("read sample: %v", err)
+	}
+	if plans, err := parser.ParseAll(strings.NewReader(string(text))); err != nil || len(plans) != 1 {
+		t.Fatalf("expected one text plan, got %d (%v)", len(plans), err)
+	}
+}
+
+func TestParseWorkers(t *testing.T) {
+	input := `[{"Plan": {
+  "Node Type": "Gather", "Actual Total Time": 40.0, "Actual Rows": 300, "Actual Loops": 1, "Workers Launched": 2,
+  "Plans": [{
+    "Node Type": "Seq Scan", "Parallel Aware": true, "Relation Name": "orders",
+    "Actual Total Time": 35.0, "Actual Rows": 100, "Actual Loops": 3,
+    "Workers": [
+      {"Worker Number": 0, "Actual Startup Time": 0.1, "Actual Total Time": 34.5, "Actual Rows": 140, "Actual Loops": 1,
+       "Shared Hit Blocks": 12, "Shared Read Blocks": 300, "JIT": {"Functions": 2}},
+      {"Worker Number": 1, "Actual Startup Time": 0.2, "Actual Total Time": 33.9, "Actual Rows": 90, "Actual Loops": 1}
+    ]
+  }]
+}}]`
+	explain, err := parser.ParseJSON(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	scan := explain.Plan.Children[0]
+	if _, ok := scan.Extra["Workers"]; ok {
+		t.Fatal("expected Workers to be modelled instead of kept in Extra")
+	}
+	if len(scan.Workers) != 2 {
+		t.Fatalf("expected 2 workers, got %+v", scan.Workers)
+	}
+	first := scan.Workers[0]
+	if first.Number != 0 || first.ActualTotalTime != 34.5 || first.ActualRows != 140 || first.Buffers.SharedRead != 300 {
+		t.Fatalf("unexpected first worker %+v", first)
+	}
+	if _, ok := first.Extra["JIT"]; !ok || len(first.Extra) != 1 {
+		t.Fatalf("expected only JIT in the worker extras, got %v", first.Extra)
+	}
+	if scan.Workers[1].Number != 1 {
+		t.Fatalf("unexpected second worker %+v", scan.Workers[1])
+	}
+}
+
+func TestParseJIT(t *testing.T) {
+	for _, name := range []string{"pgbench_hot.json", "pgbench_hot.txt", "pgbench_hot.yaml"} {
+		f, err := os.Open(filepath.Join(test.RootPath(t), "samples", name))
+		if err != nil {
+			t.Fatalf("open %s: %v", name, err)
+		}
+		explain, err := parser.Parse(f)
+		_ = f.Close()
+		if err != nil {
+			t.Fatalf("parse %s: %v", name, err)
+		}
+		jit := explain.JIT
+		if jit == nil || jit.Functions != 13 || jit.TotalMs != 48.708 || jit.EmissionMs != 40.930 || jit.GenerationMs != 1.139 {
+			t.Fatalf("%s: unexpected JIT %+v", name, jit)
+		}
+		if jit.Inlining || jit.Optimization || !jit.Expressions || !jit.Deforming {
+			t.Fatalf("%s: unexpected JIT options %+v", name, jit)
+		}
+		if _, ok := explain.Extra["JIT"]; ok {
+			t.Fatalf("%s: expected JIT to be modelled instead of kept in Extra", name)
+		}
+	}
+
+	// PostgreSQL 17 reports the deforming share of generation separately.
+	text := `Seq Scan on orders  (cost=0.00..10.00 rows=100 width=8) (actual time=0.010..5.000 rows=100 loops=1)
+Planning Time: 0.100 ms
+JIT:
+  Functions: 2
+  Options: Inlining true, Optimization true, Expressions true, Deforming true
+  Timing: Generation 0.716 ms (Deform 0.250 ms), Inlining 3.000 ms, Optimization 10.500 ms, Emission 8.000 ms, Total 22.216 ms
+Execution Time: 30.000 ms
+`
+	explain, err := parser.Parse(strings.NewReader(text))
+	if err != nil {
+		t.Fatalf("parse text: %v", err)
+	}
+	if jit := explain.JIT; jit == nil || jit.GenerationMs != 0.716 || jit.DeformMs != 0.25 || jit.InliningMs != 3 || !jit.Optimization {
+		t.Fatalf("unexpected PostgreSQL 17 JIT %+v", explain.JIT)
+	}
+}
+
+func TestParseHeapFetches(t *testing.T) {
+	input := `[{"Plan": {
+  "Node Type": "Index Only Scan", "Relation Name": "orders", "Index Name": "orders_customer_idx",
+  "Actual Total Time": 12.5, "Actual Rows": 400, "Actual Loops": 5, "Heap Fetches": 1800
+}}]`
+	explain, err := parser.ParseJSON(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if explain.Plan.HeapFetches != 1800 {
+		t.Fatalf("expected 1800 heap fetches, got %v", explain.Plan.HeapFetches)
+	}
+	if _, ok := explain.Plan.Extra["Heap Fetches"]; ok {
+		t.Fatal("expected Heap Fetches to be modelled instead of kept in Extra")
+	}
+
+	analysis, err := analyzer.Analyze(explain)
+	if err != nil {
+		t.Fatalf("analyze: %v", err)
+	}
+	// Already a total, so it is not multiplied by the loops like the row counts are.
+	if analysis.Root.HeapFetches != 1800 || analysis.Root.ActualTotalRows != 2000 {
+		t.Fatalf("unexpected node stats: %v heap fetches for %v rows", analysis.Root.HeapFetches, analysis.Root.ActualTotalRows)
+	}
+
+	text, err := parser.Parse(strings.NewReader(`Index Only Scan using orders_customer_idx on orders  (cost=0.29..4.31 rows=1 width=4) (actual time=0.011..0.012 rows=1 loops=1)
+  Index Cond: (customer_id = 42)
+  Heap Fetches: 3
+`))
+	if err != nil {
+		t.Fatalf("parse text: %v", err)
+	}
+	if text.Plan.HeapFetches != 3 {
+		t.Fatalf("expected 3 heap fetches from text, got %v", text.Plan.HeapFetches)
+	}
+}
+
+func TestParseLossyBitmap(t *testing.T) {
+	text, err := parser.Parse(strings.NewReader(`Bitmap Heap Scan on pgbench_accounts  (cost=2290.98..19843.21 rows=118200 width=4) (actual time=19.873..412.508 rows=120480 loops=1)
+  Recheck Cond: ((abalance >= '-500'::integer) AND (abalance <= 500))
+  Rows Removed by Index Recheck: 760984
+  Heap Blocks: exact=2210 lossy=14184
+  ->  Bitmap Index Scan on pgbench_accounts_abalance_idx  (cost=0.00..2261.43 rows=118200 width=0) (actual time=18.214..18.215 rows=120480 loops=1)
+        Index Cond: ((abalance >= '-500'::integer) AND (abalance <= 500))
+`))
+	if err != nil {
+		t.Fatalf("parse text: %v", err)
+	}
+	scan := text.Plan
+	if scan.ExactHeapBlocks != 2210 || scan.LossyHeapBlocks != 14184 || scan.RowsRemovedIndexRecheck != 760984 {
+		t.Fatalf("unexpected bitmap details: exact %v lossy %v recheck %v",
+			scan.ExactHeapBlocks, scan.LossyHeapBlocks, scan.RowsRemovedIndexRecheck)
+	}
+	if _, ok := scan.Extra["Lossy Heap Blocks"]; ok {
+		t.Fatal("expected Lossy Heap Blocks to be modelled instead of kept in Extra")
+	}
+}
+
+func TestParseAllWithStrict(t *testing.T) {
+	doc := `[{"Plan": {"Node Type": "Seq Scan", "Actual Rows": "12", "Actual Loops": 1,
+	  "Plans": [{"Relation Name": "orders"}]}, "Planning Time": 0.1, "Producer": "pgmustard"}]`
+
+	plans, _, err := parser.ParseAllWith(strings.NewReader(doc), parser.Options{})
+	if err != nil || len(plans) != 1 || plans[0].Plan.ActualRows != 12 {
+		t.Fatalf("expected the lenient parse to coerce the row count, got %v (%v)", plans, err)
+	}
+
+	_, warnings, err := parser.ParseAllWith(strings.NewReader(doc), parser.Options{Strict: true})
+	var schemaErr *parser.SchemaError
+	if !errors.As(err, &schemaErr) {
+		t.Fatalf("expected a schema error, got %v", err)
+	}
+	got := make([]string, 0, len(warnings)+len(schemaErr.Issues))
+	for _, issue := range append(warnings, schemaErr.Issues...) {
+		got = append(got, issue.Severity+" "+issue.String())
+	}
+	want := []string{
+		`warning $: unknown top-level key "Producer"`,
+		`error Plan: "Actual Rows" should be a number, got the string "12"`,
+		`error Plan.Plans[0]: missing required key "Node Type"`,
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("unexpected issues:\n%s", strings.Join(got, "\n"))
+	}
+
+	valid := `{"Plan": {"Node Type": "Result", "Actual Rows": 1, "Actual Loops": 1}, "Query Identifier": 42, "Source": "x"}`
+	plans, warnings, err = parser.ParseAllWith(strings.NewReader(valid), parser.Options{Strict: true})
+	if err != nil || len(plans) != 1 || len(warnings) != 1 {
+		t.Fatalf("expected a plan with one warning, got %v %v (%v)", plans, warnings, err)
+	}
+
+	if _, _, err := parser.ParseAllWith(strings.NewReader("Result  (cost=0.00..0.01 rows=1 width=4)"), parser.Options{Strict: true}); err == nil {
+		t.Fatal("expected strict mode to reject text plans")
+	}
+}
+
+func TestParseMangledJSON(t *testing.T) {
+	cases := map[string]string{
+		"psql aligned": `                 QUERY PLAN
+---------------------------------------------
+ [                                          +
+   {                                        +
+     "Plan": {                              +
+       "Node Type": "Seq Scan",             +
+       "Actual Rows": 10                    +
+     },                                     +
+     "Query Text": "SELECT 'a +'"           +
+   }                                        +
+ ]
+(1 row)
+`,
+		"psql expanded": `-[ RECORD 1 ]----------------------------
+QUERY PLAN | [
+           |   {
+           |     "Plan": {"Node Type": "Seq Scan", "Actual Rows": 10},
+           |     "Query Text": "SELECT 'a +'"
+           |   }
+           | ]
+`,
+		"quoted with escapes": `"[{\"Plan\": {\"Node Type\": \"Seq Scan\", \"Actual Rows\": 10},
+  \"Query Text\": \"SELECT 'a +'\"}]"`,
+		"csv quoted":    `"[{""Plan"": {""Node Type"": ""Seq Scan"", ""Actual Rows"": 10}, ""Query Text"": ""SELECT 'a +'""}]"`,
+		"log escaped":   `{\n  \"Plan\": {\"Node Type\": \"Seq Scan\", \"Actual Rows\": 10},\n  \"Query Text\": \"SELECT 'a +'\"\n}`,
+		"single quoted": `'[{"Plan": {"Node Type": "Seq Scan", "Actual Rows": 10}, "Query Text": "SELECT ''a +''"}]'`,
+	}
+	for name, input := range cases {
+		explain, err := parser.Parse(strings.NewReader(input))
+		if err != nil {
+			t.Fatalf("%s: parse: %v", name, err)
+		}
+		if explain.Plan.NodeType != "Seq Scan" || explain.Plan.ActualRows != 10 {
+			t.Fatalf("%s: unexpected plan %+v", name, explain.Plan)
+		}
+		if explain.QueryText != "SELECT 'a +'" {
+			t.Fatalf("%s: unexpected query text %q", name, explain.QueryText)
+		}
+	}
+}
@@ -0,0 +1,355 @@
+package parser
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/mickamy/xplain/internal/model"
+)
+
+// Options tune ParseAllWith.
+type Options struct {
+	// Format is auto (when empty), json, yaml, text or cockroach.
+	Format string
+	// Strict validates FORMAT JSON and YAML documents against the EXPLAIN schema before decoding them,
+	// rather than coercing every value the way the lenient parser does. See Validate.
+	Strict bool
+}
+
+// Severity of a schema Issue: warnings are reported, errors abort a strict parse.
+const (
+	SeverityWarning = "warning"
+	SeverityError   = "error"
+)
+
+// Issue is one deviation from the EXPLAIN schema.
+type Issue struct {
+	Severity string
+	// Plan is the 1-based entry the issue was found in when the document holds several plans, else 0.
+	Plan int
+	// Path locates the object holding the key, e.g. "Plan.Plans[1].Workers[0]".
+	Path    string
+	Message string
+}
+
+func (i Issue) String() string {
+	if i.Plan > 0 {
+		return fmt.Sprintf("plan %d: %s: %s", i.Plan, i.Path, i.Message)
+	}
+	return fmt.Sprintf("%s: %s", i.Path, i.Message)
+}
+
+// SchemaError is returned by a strict parse when the document has error-level issues.
+type SchemaError struct {
+	Issues []Issue
+}
+
+func (e *SchemaError) Error() string {
+	parts := make([]string, 0, len(e.Issues))
+	for _, issue := range e.Issues {
+		parts = append(parts, issue.String())
+	}
+	return fmt.Sprintf("explain: %d schema error(s): %s", len(e.Issues), strings.Join(parts, "; "))
+}
+
+// ParseAllWith is ParseAll for the format and strictness in opts. In strict mode the warnings are
+// returned with the plans, and error-level issues fail the parse with a *SchemaError.
+func ParseAllWith(r io.Reader, opts Options) ([]*model.Explain, []Issue, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, nil, fmt.Errorf("explain: read: %w", err)
+	}
+	format := opts.Format
+	if format == "" {
+		format = "auto"
+	}
+	if format == "auto" || format == "json" {
+		data = cleanJSON(data)
+	}
+
+	var warnings []Issue
+	if opts.Strict {
+		issues, err := validate(data, format)
+		if err != nil {
+			return nil, nil, err
+		}
+		var failures []Issue
+		for _, issue := range issues {
+			if issue.Severity == SeverityError {
+				failures = append(failures, issue)
+			} else {
+				warnings = append(warnings, issue)
+			}
+		}
+		if len(failures) > 0 {
+			return nil, warnings, &SchemaError{Issues: failures}
+		}
+	}
+
+	switch format {
+	case "auto":
+		plans, err := ParseAll(bytes.NewReader(data))
+		return plans, warnings, err
+	case "json":
+		plans, err := ParseJSONAll(bytes.NewReader(data))
+		return plans, warnings, err
+	default:
+		plan, err := ParseFormat(bytes.NewReader(data), format)
+		if err != nil {
+			return nil, warnings, err
+		}
+		return []*model.Explain{plan}, warnings, nil
+	}
+}
+
+// validate checks every plan of a FORMAT JSON or YAML document, or of an xplain envelope, against the
+// EXPLAIN schema: unknown top-level keys are warnings; values of the wrong type and missing required
+// fields ("Plan", and "Node Type" on every node) are errors. Node keys the parser does not model are
+// kept in Extra and not checked. Text plans are built by xplain itself and cannot be validated.
+func validate(data []byte, format string) ([]Issue, error) {
+	trimmed := bytes.TrimSpace(data)
+	if format == "auto" {
+		switch {
+		case len(trimmed) > 0 && (trimmed[0] == '[' || trimmed[0] == '{'):
+			format = "json"
+		case len(trimmed) > 0:
+			format = detectFormat(data)
+		}
+	}
+
+	var entries []any
+	switch format {
+	case "json":
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.UseNumber()
+		for {
+			var payload any
+			if err := decoder.Decode(&payload); err != nil {
+				if errors.Is(err, io.EOF) && len(entries) > 0 {
+					break
+				}
+				return nil, fmt.Errorf("decode explain json: %w", err)
+			}
+			payload, _, err := unwrapEnvelope(payload)
+			if err != nil {
+				return nil, err
+			}
+			if list, ok := payload.([]any); ok {
+				entries = append(entries, list...)
+			} else {
+				entries = append(entries, payload)
+			}
+		}
+	case "yaml":
+		payload, err := yamlPayload(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		if list, ok := payload.([]any); ok && len(list) > 0 {
+			entries = list[:1]
+		} else {
+			entries = []any{payload}
+		}
+	default:
+		return nil, errors.New("explain: strict mode needs FORMAT JSON or YAML input")
+	}
+
+	var v validator
+	for i, raw := range entries {
+		if len(entries) > 1 {
+			v.plan = i + 1
+		}
+		v.entry(raw)
+	}
+	return v.issues, nil
+}
+
+type kind int
+
+const (
+	kindString kind = iota
+	kindNumber
+	kindArray
+	kindObject
+)
+
+func (k kind) String() string {
+	switch k {
+	case kindString:
+		return "a string"
+	case kindNumber:
+		return "a number"
+	case kindArray:
+		return "an array"
+	default:
+		return "an object"
+	}
+}
+
+// topLevelKinds lists the keys EXPLAIN writes next to "Plan".
+var topLevelKinds = map[string]kind{
+	"Plan": kindObject, "Planning": kindObject, "Planning Time": kindNumber, "Execution Time": kindNumber,
+	"Settings": kindObject, "Query Text": kindString, "Query Identifier": kindNumber, "Triggers": kindArray,
+	"JIT": kindObject, "Serialization": kindObject,
+}
+
+// fieldKinds are the types of the node, worker and planning keys the parser models.
+var fieldKinds = map[string]kind{
+	"Node Type": kindString, "Relation Name": kindString, "Operation": kindString, "Schema": kindString,
+	"Alias": kindString, "Parent Relationship": kindString, "CTE Name": kindString, "Subplan Name": kindString,
+	"Filter": kindString, "Join Filter": kindString, "Join Type": kindString, "Index Name": kindString,
+	"Hash Cond": kindString, "Merge Cond": kindString, "Strategy": kindString, "Partial Mode": kindString,
+	"Sort Method": kindString, "Sort Space Type": kindString,
+	"Output": kindArray, "Sort Key": kindArray, "Group Key": kindArray, "Plans": kindArray, "Workers": kindArray,
+	"Startup Cost": kindNumber, "Total Cost": kindNumber, "Plan Rows": kindNumber, "Plan Width": kindNumber,
+	"Actual Startup Time": kindNumber, "Actual Total Time": kindNumber, "Actual Rows": kindNumber,
+	"Actual Loops": kindNumber, "Workers Planned": kindNumber, "Workers Launched": kindNumber,
+	"Worker Number": kindNumber, "Rows Removed by Filter": kindNumber, "Rows Removed by Join Filter": kindNumber,
+	"Heap Fetches": kindNumber, "Exact Heap Blocks": kindNumber, "Lossy Heap Blocks": kindNumber,
+	"Rows Removed by Index Recheck": kindNumber, "Subplans Removed": kindNumber, "Sort Space Used": kindNumber,
+	"Hash Buckets": kindNumber, "Original Hash Buckets": kindNumber, "Hash Batches": kindNumber,
+	"Original Hash Batches": kindNumber, "Peak Memory Usage": kindNumber, "Cache Hits": kindNumber,
+	"Cache Misses": kindNumber, "Cache Evictions": kindNumber, "Cache Overflows": kindNumber,
+	"Shared Hit Blocks": kindNumber, "Shared Read Blocks": kindNumber, "Shared Dirtied Blocks": kindNumber,
+	"Shared Written Blocks": kindNumber, "Local Hit Blocks": kindNumber, "Local Read Blocks": kindNumber,
+	"Local Dirtied Blocks": kindNumber, "Local Written Blocks": kindNumber, "Temp Read Blocks": kindNumber,
+	"Temp Written Blocks": kindNumber, "I/O Read Time": kindNumber, "I/O Write Time": kindNumber,
+	"Block Read Time": kindNumber, "Block Write Time": kindNumber, "Shared I/O Read Time": kindNumber,
+	"Shared I/O Write Time": kindNumber, "Local I/O Read Time": kindNumber, "Local I/O Write Time": kindNumber,
+	"Temp I/O Read Time": kindNumber, "Temp I/O Write Time": kindNumber,
+}
+
+type validator struct {
+	plan   int
+	issues []Issue
+}
+
+func (v *validator) report(severity, path, format string, args ...any) {
+	v.issues = append(v.issues, Issue{Severity: severity, Plan: v.plan, Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+func (v *validator) entry(raw any) {
+	entry, ok := raw.(map[string]any)
+	if !ok {
+		v.report(SeverityError, "$", "expected an object, got %s", describe(raw))
+		return
+	}
+	for _, key := range sortedKeys(entry) {
+		want, known := topLevelKinds[key]
+		if !known {
+			v.report(SeverityWarning, "$", "unknown top-level key %q", key)
+			continue
+		}
+		v.check("$", key, entry[key], want)
+	}
+	plan, ok := entry["Plan"].(map[string]any)
+	if !ok {
+		if _, present := entry["Plan"]; !present {
+			v.report(SeverityError, "$", `missing required key "Plan"`)
+		}
+		return
+	}
+	v.node(plan, "Plan")
+	if planning, ok := entry["Planning"].(map[string]any); ok {
+		v.fields(planning, "Planning")
+	}
+	if serialization, ok := entry["Serialization"].(map[string]any); ok {
+		v.fields(serialization, "Serialization")
+		for _, key := range []string{"Time", "Output Volume"} {
+			if val, present := serialization[key]; present {
+				v.check("Serialization", key, val, kindNumber)
+			}
+		}
+	}
+}
+
+func (v *validator) node(node map[string]any, path string) {
+	if _, ok := node["Node Type"]; !ok {
+		v.report(SeverityError, path, `missing required key "Node Type"`)
+	}
+	// TIMING OFF drops the times but never the row counts, so loops without rows is a truncated node.
+	if _, hasLoops := node["Actual Loops"]; hasLoops {
+		if _, hasRows := node["Actual Rows"]; !hasRows {
+			v.report(SeverityError, path, `"Actual Loops" without "Actual Rows"`)
+		}
+	}
+	v.fields(node, path)
+	for i, child := range asSlice(node["Plans"]) {
+		childPath := fmt.Sprintf("%s.Plans[%d]", path, i)
+		if obj, ok := child.(map[string]any); ok {
+			v.node(obj, childPath)
+		} else {
+			v.report(SeverityError, childPath, "expected a plan node object, got %s", describe(child))
+		}
+	}
+	for i, worker := range asSlice(node["Workers"]) {
+		workerPath := fmt.Sprintf("%s.Workers[%d]", path, i)
+		if obj, ok := worker.(map[string]any); ok {
+			v.fields(obj, workerPath)
+		} else {
+			v.report(SeverityError, workerPath, "expected a worker object, got %s", describe(worker))
+		}
+	}
+}
+
+func (v *validator) fields(obj map[string]any, path string) {
+	for _, key := range sortedKeys(obj) {
+		if want, ok := fieldKinds[key]; ok {
+			v.check(path, key, obj[key], want)
+		}
+	}
+}
+
+func (v *validator) check(path, key string, val any, want kind) {
+	var ok bool
+	switch want {
+	case kindString:
+		_, ok = val.(string)
+	case kindNumber:
+		_, ok = val.(json.Number)
+	case kindArray:
+		_, ok = val.([]any)
+	case kindObject:
+		_, ok = val.(map[string]any)
+	}
+	if !ok {
+		v.report(SeverityError, path, "%q should be %s, got %s", key, want, describe(val))
+	}
+}
+
+func sortedKeys(m map[string]any) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// describe names a decoded value's type for an issue message, quoting short scalars.
+func describe(val any) string {
+	switch v := val.(type) {
+	case nil:
+		return "null"
+	case string:
+		if len(v) > 20 {
+			v = v[:20] + "…"
+		}
+		return fmt.Sprintf("the string %q", v)
+	case json.Number:
+		return "the number " + v.String()
+	case bool:
+		return fmt.Sprintf("the boolean %t", v)
+	case []any:
+		return "an array"
+	case map[string]any:
+		return "an object"
+	default:
+		return fmt.Sprintf("%T", val)
+	}
+}
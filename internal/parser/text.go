@@ -0,0 +1,655 @@
+package parser
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/mickamy/xplain/internal/model"
+)
+
+var (
+	// planDetail finds the "(cost=...)", "(actual ...)" or "(never executed)" suffix of a node line.
+	planDetail = regexp.MustCompile(`\s+\((?:cost=|actual |never executed\))`)
+	costPart   = regexp.MustCompile(`\(cost=([\d.]+)\.\.([\d.]+) rows=(\d+) width=(\d+)\)`)
+	actualPart = regexp.MustCompile(`\(actual(?: time=([\d.]+)\.\.([\d.]+))? rows=([\d.]+) loops=(\d+)\)`)
+	workerPart = regexp.MustCompile(`^actual(?: time=([\d.]+)\.\.([\d.]+))? rows=([\d.]+) loops=(\d+)`)
+	separator  = regexp.MustCompile(`\s{2,}`)
+	detailLine = regexp.MustCompile(`^([A-Z][\w /\-]*?):(?:\s+(.*))?$`)
+	labelLine  = regexp.MustCompile(`^(InitPlan|SubPlan|CTE)\b(.*)$`)
+	// psql decorations around the plan: the column header, its underline and the row count footer.
+	psqlHeader = regexp.MustCompile(`^\s*(?:QUERY PLAN|-+\+?-*|\(\d+ rows?\))\s*$`)
+
+	scanUsing = regexp.MustCompile(`^(.*Scan)( Backward)? using (\S+) on (\S+)(?: (\S+))?$`)
+	scanOn    = regexp.MustCompile(`^(.*Scan) on (\S+)(?: (\S+))?$`)
+	modify    = regexp.MustCompile(`^(Insert|Update|Delete|Merge) on (\S+)(?: (\S+))?$`)
+	hashJoin  = regexp.MustCompile(`^(Hash|Merge)(?: (Left|Right|Full|Semi|Anti|Right Semi|Right Anti))? Join$`)
+	loopJoin  = regexp.MustCompile(`^Nested Loop(?: (Left|Right|Full|Semi|Anti|Right Semi|Right Anti) Join)?$`)
+	setOp     = regexp.MustCompile(`^(Hash)?SetOp (\w+(?: All)?)$`)
+	trigger   = regexp.MustCompile(`^Trigger (\S+)(?: for constraint (\S+))?(?: on (\S+))?: time=([\d.]+) calls=(\d+)$`)
+)
+
+// relationScans read a table, so their target is reported as "Relation Name" rather than an alias.
+var relationScans = map[string]bool{
+	"Seq Scan": true, "Index Scan": true, "Index Only Scan": true, "Bitmap Heap Scan": true,
+	"Tid Scan": true, "Tid Range Scan": true, "Sample Scan": true, "Foreign Scan": true,
+}
+
+// textNode is a plan node being assembled from EXPLAIN text output.
+type textNode struct {
+	data map[string]any
+	// column is where the node name starts; its details are indented further.
+	column   int
+	children []*textNode
+}
+
+// ParseText reads the classic text output of EXPLAIN (ANALYZE), as printed by psql or written to
+// the server log by auto_explain, and produces the same Explain structure as ParseJSON.
+func ParseText(r io.Reader) (*model.Explain, error) {
+	lines, err := textLines(r)
+	if err != nil {
+		return nil, err
+	}
+
+	start := -1
+	for i, line := range lines {
+		if planDetail.MatchString(line.text) {
+			start = i
+			break
+		}
+	}
+	// COSTS OFF without ANALYZE leaves no parenthesised details; the plan is then the first line.
+	if start < 0 {
+		for i, line := range lines {
+			if !detailLine.MatchString(line.text) {
+				start = i
+				break
+			}
+		}
+	}
+	if start < 0 {
+		return nil, errors.New("explain text: no plan nodes found")
+	}
+	entry := map[string]any{}
+	if query := queryText(lines[:start]); query != "" {
+		entry["Query Text"] = query
+	}
+
+	root := newTextNode(lines[start].text, lines[start].column)
+	stack := []*textNode{root}
+	var (
+		label    map[string]any
+		section  map[string]any
+		sectionC int
+		worker   map[string]any
+	)
+	for _, line := range lines[start+1:] {
+		text, col := line.text, line.column
+
+		if section != nil && col > sectionC {
+			applyDetail(section, text, "")
+			continue
+		}
+		section = nil
+
+		if col <= root.column {
+			section, sectionC = topLevel(entry, text, col)
+			continue
+		}
+
+		if strings.HasPrefix(text, "->") {
+			name := strings.TrimSpace(strings.TrimPrefix(text, "->"))
+			node := newTextNode(name, col+len(text)-len(name))
+			for len(stack) > 1 && stack[len(stack)-1].column >= col {
+				stack = stack[:len(stack)-1]
+			}
+			parent := stack[len(stack)-1]
+			if label != nil {
+				for k, v := range label {
+					node.data[k] = v
+				}
+				label = nil
+			}
+			parent.children = append(parent.children, node)
+			stack = append(stack, node)
+			worker = nil
+			continue
+		}
+
+		for len(stack) > 1 && stack[len(stack)-1].column >= col {
+			stack = stack[:len(stack)-1]
+		}
+		current := stack[len(stack)-1]
+		if m := labelLine.FindStringSubmatch(text); m != nil && !strings.Contains(text, ":") {
+			relationship := m[1]
+			if relationship == "CTE" {
+				relationship = "InitPlan"
+			}
+			label = map[string]any{"Parent Relationship": relationship, "Subplan Name": strings.TrimSpace(text)}
+			continue
+		}
+		if strings.HasPrefix(text, "Worker ") {
+			worker = workerDetail(current, text)
+			continue
+		}
+		if worker != nil && strings.HasPrefix(text, "Buffers:") {
+			applyDetail(worker, text, "")
+			continue
+		}
+		worker = nil
+		applyDetail(current.data, text, asString(current.data["Node Type"]))
+	}
+
+	entry["Plan"] = root.finish()
+	return buildExplain(entry, nil, "explain text")
+}
+
+type textLine struct {
+	text   string
+	column int
+}
+
+// textLines splits the input into trimmed lines with their indentation, dropping blank lines, psql
+// decorations and the " +" continuation markers of psql's aligned format.
+func textLines(r io.Reader) ([]textLine, error) {
+	var out []textLine
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		raw := strings.ReplaceAll(strings.TrimRight(scanner.Text(), " \r"), "\t", "    ")
+		if strings.HasSuffix(raw, " +") {
+			raw = strings.TrimRight(strings.TrimSuffix(raw, "+"), " ")
+		}
+		text := strings.TrimLeft(raw, " ")
+		if text == "" || psqlHeader.MatchString(raw) {
+			continue
+		}
+		out = append(out, textLine{text: text, column: len(raw) - len(text)})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("explain text: read: %w", err)
+	}
+	return out, nil
+}
+
+// queryText extracts the "Query Text:" auto_explain logs ahead of the plan, which may span lines.
+func queryText(lines []textLine) string {
+	for i, line := range lines {
+		if rest, ok := strings.CutPrefix(line.text, "Query Text:"); ok {
+			parts := []string{strings.TrimSpace(rest)}
+			for _, more := range lines[i+1:] {
+				parts = append(parts, strings.Repeat(" ", max(more.column-line.column, 0))+more.text)
+			}
+			return strings.TrimSpace(strings.Join(parts, "\n"))
+		}
+	}
+	return ""
+}
+
+func newTextNode(line string, column int) *textNode {
+	name := line
+	if loc := planDetail.FindStringIndex(line); loc != nil {
+		name = line[:loc[0]]
+	}
+	data := nodeIdentity(strings.TrimSpace(name))
+	if m := costPart.FindStringSubmatch(line); m != nil {
+		data["Startup Cost"] = number(m[1])
+		data["Total Cost"] = number(m[2])
+		data["Plan Rows"] = number(m[3])
+		data["Plan Width"] = number(m[4])
+	}
+	if m := actualPart.FindStringSubmatch(line); m != nil {
+		if m[1] != "" {
+			data["Actual Startup Time"] = number(m[1])
+			data["Actual Total Time"] = number(m[2])
+		}
+		data["Actual Rows"] = number(m[3])
+		data["Actual Loops"] = number(m[4])
+	} else if strings.Contains(line, "(never executed)") {
+		data["Actual Rows"] = 0.0
+		data["Actual Loops"] = 0.0
+	}
+	return &textNode{data: data, column: column}
+}
+
+// nodeIdentity maps a node heading such as "Parallel Index Scan Backward using idx on public.t a"
+// to the Node Type, relation and strategy properties FORMAT JSON reports separately.
+func nodeIdentity(name string) map[string]any {
+	data := map[string]any{}
+	if rest, ok := strings.CutPrefix(name, "Parallel "); ok {
+		data["Parallel Aware"] = true
+		name = rest
+	}
+	if rest, ok := strings.CutPrefix(name, "Async "); ok {
+		data["Async Capable"] = true
+		name = rest
+	}
+	for _, mode := range []string{"Partial", "Finalize"} {
+		if rest, ok := strings.CutPrefix(name, mode+" "); ok && strings.HasSuffix(rest, "Aggregate") {
+			data["Partial Mode"] = mode
+			name = rest
+		}
+	}
+
+	switch {
+	case strings.HasSuffix(name, "Aggregate") && !strings.Contains(name, " "):
+		data["Node Type"] = "Aggregate"
+		data["Strategy"] = map[string]string{
+			"Aggregate": "Plain", "HashAggregate": "Hashed", "GroupAggregate": "Sorted", "MixedAggregate": "Mixed",
+		}[name]
+		if _, ok := data["Partial Mode"]; !ok {
+			data["Partial Mode"] = "Simple"
+		}
+	case setOp.MatchString(name):
+		m := setOp.FindStringSubmatch(name)
+		data["Node Type"] = "SetOp"
+		data["Strategy"] = "Sorted"
+		if m[1] != "" {
+			data["Strategy"] = "Hashed"
+		}
+		data["Command"] = m[2]
+	case hashJoin.MatchString(name):
+		m := hashJoin.FindStringSubmatch(name)
+		data["Node Type"] = m[1] + " Join"
+		data["Join Type"] = joinType(m[2])
+	case loopJoin.MatchString(name):
+		data["Node Type"] = "Nested Loop"
+		data["Join Type"] = joinType(loopJoin.FindStringSubmatch(name)[1])
+	case modify.MatchString(name):
+		m := modify.FindStringSubmatch(name)
+		data["Node Type"] = "ModifyTable"
+		data["Operation"] = m[1]
+		setRelation(data, m[2], m[3])
+	case scanUsing.MatchString(name):
+		m := scanUsing.FindStringSubmatch(name)
+		data["Node Type"] = m[1]
+		data["Scan Direction"] = "Forward"
+		if m[2] != "" {
+			data["Scan Direction"] = "Backward"
+		}
+		data["Index Name"] = m[3]
+		setRelation(data, m[4], m[5])
+	case scanOn.MatchString(name):
+		m := scanOn.FindStringSubmatch(name)
+		data["Node Type"] = m[1]
+		target, alias := m[2], m[3]
+		switch {
+		case m[1] == "Bitmap Index Scan":
+			data["Index Name"] = target
+		case relationScans[m[1]]:
+			setRelation(data, target, alias)
+		case m[1] == "Function Scan":
+			data["Function Name"] = target
+			data["Alias"] = firstNonEmpty(alias, target)
+		case m[1] == "CTE Scan" || m[1] == "WorkTable Scan":
+			data["CTE Name"] = target
+			data["Alias"] = firstNonEmpty(alias, target)
+		default:
+			data["Alias"] = firstNonEmpty(alias, target)
+		}
+	case strings.HasPrefix(name, "Custom Scan (") && strings.HasSuffix(name, ")"):
+		data["Node Type"] = "Custom Scan"
+		data["Custom Plan Provider"] = strings.TrimSuffix(strings.TrimPrefix(name, "Custom Scan ("), ")")
+	default:
+		data["Node Type"] = name
+	}
+	return data
+}
+
+func setRelation(data map[string]any, target, alias string) {
+	relation := target
+	if schema, table, ok := strings.Cut(target, "."); ok && !strings.HasPrefix(target, `"`) {
+		data["Schema"] = schema
+		relation = table
+	}
+	data["Relation Name"] = relation
+	data["Alias"] = firstNonEmpty(alias, relation)
+}
+
+func joinType(kind string) string {
+	if kind == "" {
+		return "Inner"
+	}
+	return kind
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// finish converts the node and its children into the map shape parsePlanNode expects, filling in
+// the parent relationships text output leaves implicit.
+func (n *textNode) finish() map[string]any {
+	if len(n.children) == 0 {
+		return n.data
+	}
+	nodeType := asString(n.data["Node Type"])
+	plans := make([]any, 0, len(n.children))
+	// InitPlans and SubPlans are labelled; the remaining children are the node's own inputs.
+	inputs := 0
+	for _, child := range n.children {
+		if _, ok := child.data["Parent Relationship"]; !ok {
+			child.data["Parent Relationship"] = relationship(nodeType, inputs)
+			inputs++
+		}
+		plans = append(plans, child.finish())
+	}
+	n.data["Plans"] = plans
+	return n.data
+}
+
+func relationship(parentType string, index int) string {
+	switch parentType {
+	case "Append", "Merge Append", "BitmapAnd", "BitmapOr":
+		return "Member"
+	case "Subquery Scan":
+		return "Subquery"
+	}
+	if index == 1 {
+		return "Inner"
+	}
+	return "Outer"
+}
+
+// topLevel handles the statement-level lines that follow the plan tree. Lines that open a block,
+// such as "Planning:" or "JIT:", return the map their indented lines belong to.
+func topLevel(entry map[string]any, text string, column int) (map[string]any, int) {
+	if m := trigger.FindStringSubmatch(text); m != nil {
+		t := map[string]any{"Trigger Name": m[1], "Time": number(m[4]), "Calls": number(m[5])}
+		if m[2] != "" {
+			t["Constraint Name"] = m[2]
+		}
+		if m[3] != "" {
+			t["Relation"] = m[3]
+		}
+		entry["Triggers"] = append(asSlice(entry["Triggers"]), t)
+		return nil, 0
+	}
+	m := detailLine.FindStringSubmatch(text)
+	if m == nil {
+		return nil, 0
+	}
+	key, value := m[1], m[2]
+	switch key {
+	case "Planning Time", "Execution Time":
+		entry[key] = number(strings.TrimSuffix(value, " ms"))
+	case "Settings":
+		entry[key] = settings(value)
+	case "Planning", "JIT":
+		block := map[string]any{}
+		entry[key] = block
+		return block, column
+	case "Serialization":
+		block := serialization(value)
+		entry[key] = block
+		return block, column
+	default:
+		entry[key] = value
+	}
+	return nil, 0
+}
+
+// serialization parses the PostgreSQL 17 "time=0.058 ms  output=5kB  format=text" line into the
+// "Serialization" object FORMAT JSON reports. The block's "Buffers:" line follows indented.
+func serialization(value string) map[string]any {
+	out := map[string]any{}
+	for _, item := range separator.Split(value, -1) {
+		name, v, ok := strings.Cut(strings.TrimSpace(item), "=")
+		if !ok {
+			continue
+		}
+		switch name {
+		case "time":
+			out["Time"] = number(strings.TrimSuffix(v, " ms"))
+		case "output":
+			out["Output Volume"] = number(strings.TrimSuffix(v, "kB"))
+		case "format":
+			out["Format"] = v
+		}
+	}
+	return out
+}
+
+func settings(value string) map[string]any {
+	out := map[string]any{}
+	for _, item := range splitTopLevel(value) {
+		name, setting, ok := strings.Cut(item, " = ")
+		if ok {
+			out[strings.TrimSpace(name)] = strings.Trim(strings.TrimSpace(setting), "'")
+		}
+	}
+	return out
+}
+
+// listKeys are properties FORMAT JSON reports as arrays.
+var listKeys = map[string]bool{"Output": true, "Sort Key": true, "Group Key": true, "Presorted Key": true}
+
+// applyDetail stores one "Key: value" line of a node. Several properties can share a line,
+// separated by two spaces, as in "Buckets: 1024  Batches: 1  Memory Usage: 9kB".
+func applyDetail(data map[string]any, text, nodeType string) {
+	m := detailLine.FindStringSubmatch(text)
+	if m == nil {
+		return
+	}
+	key, value := m[1], m[2]
+	switch {
+	case key == "Buffers":
+		buffers(data, value)
+		return
+	case key == "I/O Timings":
+		ioTimings(data, value)
+		return
+	case key == "Heap Blocks":
+		for _, kv := range strings.Fields(value) {
+			if k, v, ok := strings.Cut(kv, "="); ok {
+				data[title(k)+" Heap Blocks"] = number(v)
+			}
+		}
+		return
+	case listKeys[key]:
+		data[key] = toAny(splitTopLevel(value))
+		return
+	case key == "Full-sort Groups" || key == "Pre-sorted Groups":
+		data[key] = value
+		return
+	case nodeType == "" && (key == "Options" || key == "Timing"):
+		data[key] = jitDetail(value)
+		return
+	}
+	if !strings.Contains(value, "  ") {
+		setProperty(data, key, value, nodeType)
+		return
+	}
+	segments := separator.Split(value, -1)
+	setProperty(data, key, segments[0], nodeType)
+	for _, segment := range segments[1:] {
+		if k, v, ok := strings.Cut(segment, ": "); ok {
+			setProperty(data, strings.TrimSpace(k), strings.TrimSpace(v), nodeType)
+		}
+	}
+}
+
+// jitDetail parses the JIT "Options: Inlining false, ..." and "Timing: Generation 1.1 ms (Deform 0.3 ms), ..."
+// lines into the objects FORMAT JSON reports.
+func jitDetail(value string) map[string]any {
+	out := map[string]any{}
+	for _, item := range splitTopLevel(value) {
+		name, setting, ok := strings.Cut(item, " ")
+		if !ok {
+			continue
+		}
+		switch setting {
+		case "true", "false":
+			out[name] = setting == "true"
+			continue
+		}
+		total, inner, nested := strings.Cut(setting, " (")
+		ms := number(strings.TrimSuffix(total, " ms"))
+		if !nested {
+			out[name] = ms
+			continue
+		}
+		part := map[string]any{"Total": ms}
+		if k, v, ok := strings.Cut(strings.TrimSuffix(inner, ")"), " "); ok {
+			part[k] = number(strings.TrimSuffix(v, " ms"))
+		}
+		out[name] = part
+	}
+	return out
+}
+
+func setProperty(data map[string]any, key, value, nodeType string) {
+	switch key {
+	case "Memory", "Disk":
+		data["Sort Space Used"] = number(strings.TrimSuffix(value, "kB"))
+		data["Sort Space Type"] = key
+	case "Memory Usage":
+		data["Peak Memory Usage"] = number(strings.TrimSuffix(value, "kB"))
+	case "Disk Usage":
+		data["Disk Usage"] = number(strings.TrimSuffix(value, "kB"))
+	case "Buckets", "Batches":
+		current, original, _ := strings.Cut(value, " (originally ")
+		name := "Hash " + key
+		if key == "Batches" && nodeType == "Aggregate" {
+			name = "HashAgg Batches"
+		}
+		data[name] = number(current)
+		if original != "" {
+			data["Original "+name] = number(strings.TrimSuffix(original, ")"))
+		}
+	case "Hits", "Misses", "Evictions", "Overflows":
+		data["Cache "+key] = number(value)
+	default:
+		if f, err := strconv.ParseFloat(value, 64); err == nil {
+			data[key] = f
+		} else {
+			data[key] = value
+		}
+	}
+}
+
+// bufferKinds maps the BUFFERS text counters to their FORMAT JSON names.
+var bufferKinds = map[string]string{"hit": "Hit", "read": "Read", "dirtied": "Dirtied", "written": "Written"}
+
+// buffers parses "shared hit=3 read=2, local hit=1, temp read=4 written=4".
+func buffers(data map[string]any, value string) {
+	for _, group := range strings.Split(value, ",") {
+		fields := strings.Fields(group)
+		if len(fields) < 2 {
+			continue
+		}
+		scope := title(fields[0])
+		for _, kv := range fields[1:] {
+			k, v, ok := strings.Cut(kv, "=")
+			if kind, known := bufferKinds[k]; ok && known {
+				data[scope+" "+kind+" Blocks"] = number(v)
+			}
+		}
+	}
+}
+
+// ioTimings parses "shared read=1.2 write=0.3, temp read=0.1" (PostgreSQL 17), "shared/local
+// read=1.2" (15-16) and "read=1.2 write=0.3" (older servers).
+func ioTimings(data map[string]any, value string) {
+	for _, group := range strings.Split(value, ",") {
+		fields := strings.Fields(group)
+		prefix := "I/O"
+		if len(fields) > 0 && !strings.Contains(fields[0], "=") {
+			if scope := fields[0]; scope != "shared/local" {
+				prefix = title(scope) + " I/O"
+			}
+			fields = fields[1:]
+		}
+		for _, kv := range fields {
+			if k, v, ok := strings.Cut(kv, "="); ok {
+				data[prefix+" "+title(k)+" Time"] = number(v)
+			}
+		}
+	}
+}
+
+// workerDetail records a "Worker N:" line in the node's Workers list and returns that worker.
+func workerDetail(node *textNode, text string) map[string]any {
+	label, rest, _ := strings.Cut(text, ":")
+	id := number(strings.TrimPrefix(label, "Worker "))
+	workers := asSlice(node.data["Workers"])
+	var w map[string]any
+	for _, existing := range workers {
+		if m, ok := existing.(map[string]any); ok && m["Worker Number"] == id {
+			w = m
+		}
+	}
+	if w == nil {
+		w = map[string]any{"Worker Number": id}
+		node.data["Workers"] = append(workers, w)
+	}
+	rest = strings.TrimSpace(rest)
+	if m := workerPart.FindStringSubmatch(rest); m != nil {
+		if m[1] != "" {
+			w["Actual Startup Time"] = number(m[1])
+			w["Actual Total Time"] = number(m[2])
+		}
+		w["Actual Rows"] = number(m[3])
+		w["Actual Loops"] = number(m[4])
+		return w
+	}
+	applyDetail(w, rest, asString(node.data["Node Type"]))
+	return w
+}
+
+// splitTopLevel splits a comma-separated list, ignoring commas nested in parentheses or quotes.
+func splitTopLevel(value string) []string {
+	var (
+		out    []string
+		depth  int
+		quoted bool
+		start  int
+	)
+	for i, r := range value {
+		switch {
+		case r == '\'':
+			quoted = !quoted
+		case quoted:
+		case r == '(' || r == '[':
+			depth++
+		case r == ')' || r == ']':
+			depth--
+		case r == ',' && depth == 0:
+			out = append(out, strings.TrimSpace(value[start:i]))
+			start = i + 1
+		}
+	}
+	if last := strings.TrimSpace(value[start:]); last != "" {
+		out = append(out, last)
+	}
+	return out
+}
+
+func toAny(values []string) []any {
+	out := make([]any, 0, len(values))
+	for _, v := range values {
+		out = append(out, v)
+	}
+	return out
+}
+
+func title(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToUpper(s[:1]) + s[1:]
+}
+
+func number(s string) float64 {
+	f, _ := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	return f
+}
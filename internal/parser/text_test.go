@@ -0,0 +1,152 @@
+package parser_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mickamy/xplain/internal/analyzer"
+	"github.com/mickamy/xplain/internal/parser"
+	"github.com/mickamy/xplain/test"
+)
+
+func TestParseTextMatchesJSON(t *testing.T) {
+	assertSameAnalysis(t, test.LoadSampleAnalysis(t, "pgbench_hot.json"), test.LoadSampleAnalysis(t, "pgbench_hot.txt"))
+}
+
+// assertSameAnalysis compares the statement totals and the leftmost path of two analyses of one plan.
+func assertSameAnalysis(t *testing.T, fromJSON, fromText *analyzer.PlanAnalysis) {
+	t.Helper()
+
+	if fromText.NodeCount != fromJSON.NodeCount {
+		t.Fatalf("expected %d nodes, got %d", fromJSON.NodeCount, fromText.NodeCount)
+	}
+	if fromText.ExecutionTimeMs != fromJSON.ExecutionTimeMs || fromText.PlanningTimeMs != fromJSON.PlanningTimeMs {
+		t.Fatalf("expected timings %v/%v, got %v/%v", fromJSON.PlanningTimeMs, fromJSON.ExecutionTimeMs, fromText.PlanningTimeMs, fromText.ExecutionTimeMs)
+	}
+	if fromText.PlanningBuffers != fromJSON.PlanningBuffers {
+		t.Fatalf("expected planning buffers %+v, got %+v", fromJSON.PlanningBuffers, fromText.PlanningBuffers)
+	}
+
+	want, got := fromJSON.Root, fromText.Root
+	for want != nil {
+		if got == nil {
+			t.Fatalf("text plan is missing %s", want.Node.NodeType)
+		}
+		w, g := want.Node, got.Node
+		if g.NodeType != w.NodeType || g.RelationName != w.RelationName || g.ParentRelationship != w.ParentRelationship {
+			t.Fatalf("node %s: expected %s on %q (%s), got %s on %q (%s)", w.ID, w.NodeType, w.RelationName, w.ParentRelationship, g.NodeType, g.RelationName, g.ParentRelationship)
+		}
+		if g.ActualTotalTime != w.ActualTotalTime || g.ActualRows != w.ActualRows || g.TotalCost != w.TotalCost {
+			t.Fatalf("node %s: expected %v ms / %v rows, got %v ms / %v rows", w.ID, w.ActualTotalTime, w.ActualRows, g.ActualTotalTime, g.ActualRows)
+		}
+		if g.Buffers != w.Buffers || g.SortMethod != w.SortMethod || g.SortSpaceUsedKB != w.SortSpaceUsedKB || g.SortSpaceType != w.SortSpaceType || g.HashBatches != w.HashBatches || g.Filter != w.Filter {
+			t.Fatalf("node %s: expected details %+v, got %+v", w.ID, w, g)
+		}
+		if len(want.Children) == 0 {
+			break
+		}
+		if len(got.Children) != len(want.Children) {
+			t.Fatalf("node %s: expected %d children, got %d", w.ID, len(want.Children), len(got.Children))
+		}
+		want, got = want.Children[0], got.Children[0]
+	}
+}
+
+func TestParseTextDetails(t *testing.T) {
+	input := `
+Query Text: SELECT *
+  FROM orders o JOIN customers c ON c.id = o.customer_id
+Nested Loop Left Join  (cost=0.29..16.34 rows=1 width=72) (actual time=0.020..0.031 rows=3 loops=1)
+  Join Filter: (c.vip OR (o.total > '100'::numeric))
+  Rows Removed by Join Filter: 4
+  Buffers: shared hit=9
+  InitPlan 1
+    ->  Result  (cost=0.00..0.01 rows=1 width=4) (actual time=0.001..0.001 rows=1 loops=1)
+  ->  Seq Scan on public.orders o  (cost=0.00..8.00 rows=1 width=40) (actual time=0.010..0.012 rows=3 loops=1)
+        Output: o.id, o.customer_id, round(o.total, 2)
+        Filter: (o.status = 'paid'::text)
+        Rows Removed by Filter: 97
+  ->  Memoize  (cost=0.29..8.31 rows=1 width=32) (actual time=0.004..0.004 rows=1 loops=3)
+        Cache Key: o.customer_id
+        Hits: 2  Misses: 1  Evictions: 0  Overflows: 0  Memory Usage: 1kB
+        ->  Index Scan Backward using customers_pkey on customers c  (cost=0.28..8.30 rows=1 width=32) (never executed)
+              Index Cond: (id = o.customer_id)
+              I/O Timings: shared read=0.125
+Settings: work_mem = '64MB', random_page_cost = '1.1'
+Trigger RI_ConstraintTrigger_c_1 for constraint orders_customer_fk on orders: time=0.412 calls=3
+Planning Time: 0.210 ms
+Execution Time: 0.950 ms
+`
+	plan, err := parser.Parse(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+
+	if !strings.HasPrefix(plan.QueryText, "SELECT *\n  FROM orders") {
+		t.Fatalf("unexpected query text %q", plan.QueryText)
+	}
+	if plan.ExecutionTime != 0.95 || plan.Settings["work_mem"] != "64MB" {
+		t.Fatalf("unexpected statement details: %v ms, %v", plan.ExecutionTime, plan.Settings)
+	}
+	if len(plan.Triggers) != 1 || plan.Triggers[0].ConstraintName != "orders_customer_fk" || plan.Triggers[0].Calls != 3 {
+		t.Fatalf("unexpected triggers %+v", plan.Triggers)
+	}
+
+	root := plan.Plan
+	if root.NodeType != "Nested Loop" || root.JoinType != "Left" || root.Buffers.SharedHit != 9 {
+		t.Fatalf("unexpected root %+v", root)
+	}
+	if root.JoinFilter != "(c.vip OR (o.total > '100'::numeric))" || root.RowsRemovedJoinFilter != 4 {
+		t.Fatalf("unexpected join filter %q removing %v rows", root.JoinFilter, root.RowsRemovedJoinFilter)
+	}
+	if len(root.Children) != 3 {
+		t.Fatalf("expected 3 children, got %d", len(root.Children))
+	}
+	initPlan, outer, inner := root.Children[0], root.Children[1], root.Children[2]
+	if initPlan.ParentRelationship != "InitPlan" || initPlan.SubplanName != "InitPlan 1" {
+		t.Fatalf("unexpected init plan %+v", initPlan)
+	}
+	if outer.ParentRelationship != "Outer" || outer.Schema != "public" || outer.RelationName != "orders" || outer.Alias != "o" {
+		t.Fatalf("unexpected outer scan %+v", outer)
+	}
+	if len(outer.Output) != 3 || outer.Output[2] != "round(o.total, 2)" || outer.RowsRemovedFilter != 97 {
+		t.Fatalf("unexpected outer details %+v", outer)
+	}
+	if inner.ParentRelationship != "Inner" || inner.PeakMemoryKB != 1 || inner.CacheHits != 2 {
+		t.Fatalf("unexpected memoize %+v", inner)
+	}
+
+	scan := inner.Children[0]
+	if scan.NodeType != "Index Scan" || scan.IndexName != "customers_pkey" || scan.ActualLoops != 0 {
+		t.Fatalf("unexpected index scan %+v", scan)
+	}
+	if scan.Extra["Scan Direction"] != "Backward" || scan.Buffers.SharedReadTimeMs != 0.125 {
+		t.Fatalf("unexpected index scan details %+v", scan)
+	}
+}
+
+func TestParseRejectsEmptyInput(t *testing.T) {
+	if _, err := parser.Parse(strings.NewReader("  \n")); err == nil {
+		t.Fatalf("expected an error for empty input")
+	}
+	if _, err := parser.ParseText(strings.NewReader("QUERY PLAN\n----------\n(0 rows)\n")); err == nil {
+		t.Fatalf("expected an error for a plan without nodes")
+	}
+}
+
+func TestParseSerialization(t *testing.T) {
+	fromJSON := test.LoadSampleAnalysis(t, "pg17_serialize.json")
+	fromText := test.LoadSampleAnalysis(t, "pg17_serialize.txt")
+	assertSameAnalysis(t, fromJSON, fromText)
+
+	s := fromJSON.Serialization
+	if s == nil {
+		t.Fatalf("expected the Serialization block to be parsed")
+	}
+	if s.TimeMs != 142.337 || s.OutputKB != 61532 || s.Format != "text" || s.Buffers.SharedRead != 2210 || s.Buffers.SharedReadTimeMs != 21.604 {
+		t.Fatalf("unexpected serialization %+v", s)
+	}
+	if fromText.Serialization == nil || *fromText.Serialization != *s {
+		t.Fatalf("expected text serialization %+v, got %+v", s, fromText.Serialization)
+	}
+}
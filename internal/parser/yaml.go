@@ -0,0 +1,165 @@
+package parser
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/mickamy/xplain/internal/model"
+)
+
+// ParseYAML reads a PostgreSQL EXPLAIN (FORMAT YAML) document. The server emits a small, regular
+// subset of YAML (block mappings and sequences, JSON-quoted strings), which is decoded into the
+// same shape as FORMAT JSON rather than pulling in a general YAML library.
+func ParseYAML(r io.Reader) (*model.Explain, error) {
+	payload, err := yamlPayload(r)
+	if err != nil {
+		return nil, err
+	}
+	entry, err := pickFirstEntry(payload)
+	if err != nil {
+		return nil, fmt.Errorf("explain yaml: %w", err)
+	}
+	return buildExplain(entry, nil, "explain yaml")
+}
+
+// yamlPayload decodes a FORMAT YAML document into the values ParseJSON would decode.
+func yamlPayload(r io.Reader) (any, error) {
+	lines, err := textLines(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(lines) == 0 {
+		return nil, errors.New("explain yaml: empty document")
+	}
+	payload, next, err := yamlValue(lines, 0, lines[0].column)
+	if err != nil {
+		return nil, err
+	}
+	if next < len(lines) {
+		return nil, fmt.Errorf("explain yaml: unexpected indentation at %q", lines[next].text)
+	}
+	return payload, nil
+}
+
+// isYAML reports whether the first plan line looks like FORMAT YAML output.
+func isYAML(line string) bool {
+	return strings.HasPrefix(line, "- Plan:") || strings.HasPrefix(line, "Plan:")
+}
+
+// yamlValue decodes the block starting at lines[i], indented by column, and returns the index of
+// the first line after it.
+func yamlValue(lines []textLine, i, column int) (any, int, error) {
+	if isSequenceItem(lines[i].text) {
+		return yamlSequence(lines, i, column)
+	}
+	return yamlMapping(lines, i, column)
+}
+
+func yamlSequence(lines []textLine, i, column int) (any, int, error) {
+	items := []any{}
+	for i < len(lines) && lines[i].column == column && isSequenceItem(lines[i].text) {
+		rest := strings.TrimPrefix(lines[i].text, "-")
+		content := strings.TrimLeft(rest, " ")
+		if content == "" {
+			// The item's value is the indented block on the following lines.
+			if i+1 >= len(lines) || lines[i+1].column <= column {
+				items = append(items, nil)
+				i++
+				continue
+			}
+			value, next, err := yamlValue(lines, i+1, lines[i+1].column)
+			if err != nil {
+				return nil, 0, err
+			}
+			items = append(items, value)
+			i = next
+			continue
+		}
+		if _, _, isKey := yamlKey(content); !isKey {
+			items = append(items, yamlScalar(content))
+			i++
+			continue
+		}
+		// "- Key: value" opens a mapping whose other keys line up with the first one.
+		inner := column + 1 + len(rest) - len(content)
+		lines[i] = textLine{text: content, column: inner}
+		value, next, err := yamlMapping(lines, i, inner)
+		if err != nil {
+			return nil, 0, err
+		}
+		items = append(items, value)
+		i = next
+	}
+	return items, i, nil
+}
+
+func yamlMapping(lines []textLine, i, column int) (any, int, error) {
+	obj := map[string]any{}
+	for i < len(lines) && lines[i].column == column && !isSequenceItem(lines[i].text) {
+		key, value, ok := yamlKey(lines[i].text)
+		if !ok {
+			return nil, 0, fmt.Errorf("explain yaml: expected \"key: value\", got %q", lines[i].text)
+		}
+		i++
+		if value != "" {
+			obj[key] = yamlScalar(value)
+			continue
+		}
+		// An empty value is followed by a nested block, or is an empty group such as "Triggers:".
+		// Sequences may sit at the key's own indentation.
+		if i < len(lines) && (lines[i].column > column || (lines[i].column == column && isSequenceItem(lines[i].text))) {
+			nested, next, err := yamlValue(lines, i, lines[i].column)
+			if err != nil {
+				return nil, 0, err
+			}
+			obj[key] = nested
+			i = next
+			continue
+		}
+		obj[key] = nil
+	}
+	return obj, i, nil
+}
+
+func isSequenceItem(text string) bool {
+	return text == "-" || strings.HasPrefix(text, "- ")
+}
+
+// yamlKey splits "Key: value". Plan keys are never quoted, so the first ": " ends the key.
+func yamlKey(text string) (string, string, bool) {
+	if key, ok := strings.CutSuffix(text, ":"); ok && !strings.HasPrefix(key, `"`) {
+		return key, "", true
+	}
+	key, value, ok := strings.Cut(text, ": ")
+	if !ok || strings.HasPrefix(key, `"`) {
+		return "", "", false
+	}
+	return key, strings.TrimSpace(value), true
+}
+
+// yamlScalar decodes a value the way ParseJSON would: quoted strings use JSON escaping, numbers
+// stay json.Number and true/false become booleans.
+func yamlScalar(value string) any {
+	switch {
+	case strings.HasPrefix(value, `"`):
+		var s string
+		if err := json.Unmarshal([]byte(value), &s); err == nil {
+			return s
+		}
+		return strings.Trim(value, `"`)
+	case strings.HasPrefix(value, "'"):
+		return strings.ReplaceAll(strings.Trim(value, "'"), "''", "'")
+	case value == "true" || value == "false":
+		return value == "true"
+	case value == "null" || value == "~":
+		return nil
+	}
+	if _, err := strconv.ParseFloat(value, 64); err == nil {
+		return json.Number(value)
+	}
+	return value
+}
@@ -0,0 +1,57 @@
+package parser_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mickamy/xplain/internal/parser"
+	"github.com/mickamy/xplain/test"
+)
+
+func TestParseYAMLMatchesJSON(t *testing.T) {
+	assertSameAnalysis(t, test.LoadSampleAnalysis(t, "pgbench_hot.json"), test.LoadSampleAnalysis(t, "pgbench_hot.yaml"))
+}
+
+func TestParseYAMLFromPsql(t *testing.T) {
+	input := `                QUERY PLAN
+-----------------------------------------
+ - Plan:                                +
+     Node Type: "Sort"                  +
+     Startup Cost: 12.5                 +
+     Actual Rows: 3                     +
+     Actual Loops: 1                    +
+     Sort Key:                          +
+       - "(lower(\"name\"))"              +
+       - "id DESC"                      +
+     Plans:                             +
+       - Node Type: "Seq Scan"          +
+         Parent Relationship: "Outer"   +
+         Relation Name: "users"         +
+         Filter: "(note = 'a: b')"      +
+   Triggers:                            +
+   Settings:                            +
+     work_mem: "64MB"                   +
+   Execution Time: 0.412
+(1 row)
+`
+	plan, err := parser.Parse(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	root := plan.Plan
+	if root.NodeType != "Sort" || root.StartupCost != 12.5 || len(root.SortKey) != 2 || root.SortKey[0] != `(lower("name"))` {
+		t.Fatalf("unexpected root %+v", root)
+	}
+	if len(root.Children) != 1 || root.Children[0].RelationName != "users" || root.Children[0].Filter != "(note = 'a: b')" {
+		t.Fatalf("unexpected children %+v", root.Children)
+	}
+	if plan.ExecutionTime != 0.412 || plan.Settings["work_mem"] != "64MB" || plan.Triggers != nil {
+		t.Fatalf("unexpected statement details %+v", plan)
+	}
+}
+
+func TestParseFormatRejectsUnknown(t *testing.T) {
+	if _, err := parser.ParseFormat(strings.NewReader("[]"), "xml"); err == nil {
+		t.Fatalf("expected an error for an unsupported format")
+	}
+}
@@ -0,0 +1,94 @@
+package pglog
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var planLine = regexp.MustCompile(`duration: ([\d.]+) ms\s+plan:\s*(.*)$`)
+
+// Plan is one plan logged by auto_explain.
+type Plan struct {
+	// Line is the 1-based log line the entry starts on.
+	Line       int
+	DurationMs float64
+	// Text is the plan as logged, in whichever auto_explain.log_format was configured.
+	Text string
+}
+
+// AutoExplain extracts the plans auto_explain wrote to a server log. Both the stderr format, whose
+// continuation lines are tab-indented after a log_line_prefix line, and jsonlog are understood.
+func AutoExplain(r io.Reader) ([]Plan, error) {
+	var (
+		plans   []Plan
+		current *Plan
+		body    []string
+	)
+	flush := func() {
+		if current != nil {
+			current.Text = strings.TrimSpace(strings.Join(body, "\n"))
+			if current.Text != "" {
+				plans = append(plans, *current)
+			}
+		}
+		current, body = nil, nil
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimRight(scanner.Text(), "\r")
+		if current != nil && strings.HasPrefix(text, "\t") {
+			body = append(body, strings.TrimPrefix(text, "\t"))
+			continue
+		}
+		flush()
+
+		if strings.HasPrefix(text, "{") {
+			var entry struct {
+				Message string `json:"message"`
+			}
+			if json.Unmarshal([]byte(text), &entry) == nil && entry.Message != "" {
+				if plan, ok := messagePlan(entry.Message, line); ok {
+					plans = append(plans, plan)
+				}
+				continue
+			}
+		}
+		if plan, inline, ok := startPlan(text, line); ok {
+			current, body = &plan, []string{inline}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("pglog: read log: %w", err)
+	}
+	flush()
+	return plans, nil
+}
+
+// messagePlan reads the plan from a whole log message, as jsonlog and csvlog store it.
+func messagePlan(message string, line int) (Plan, bool) {
+	first, rest, _ := strings.Cut(message, "\n")
+	plan, inline, ok := startPlan(first, line)
+	if !ok {
+		return Plan{}, false
+	}
+	plan.Text = strings.TrimSpace(inline + "\n" + rest)
+	return plan, plan.Text != ""
+}
+
+// startPlan recognises the "duration: ... plan:" line opening an entry and returns any plan text
+// that follows on the same line.
+func startPlan(text string, line int) (Plan, string, bool) {
+	m := planLine.FindStringSubmatch(text)
+	if m == nil {
+		return Plan{}, "", false
+	}
+	duration, _ := strconv.ParseFloat(m[1], 64)
+	return Plan{Line: line, DurationMs: duration}, m[2], true
+}
@@ -0,0 +1,114 @@
+package pglog_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mickamy/xplain/internal/parser"
+	"github.com/mickamy/xplain/internal/pglog"
+	"github.com/mickamy/xplain/test"
+)
+
+func TestAutoExplain(t *testing.T) {
+	file, err := os.Open(filepath.Join(test.RootPath(t), "samples", "auto_explain.log"))
+	if err != nil {
+		t.Fatalf("open log: %v", err)
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+
+	plans, err := pglog.AutoExplain(file)
+	if err != nil {
+		t.Fatalf("parse log: %v", err)
+	}
+	if len(plans) != 2 {
+		t.Fatalf("expected 2 plans, got %+v", plans)
+	}
+	if plans[0].Line != 2 || plans[0].DurationMs != 0.986 || !strings.HasPrefix(plans[0].Text, "{") {
+		t.Fatalf("unexpected first plan %+v", plans[0])
+	}
+	for _, plan := range plans {
+		explain, err := parser.Parse(strings.NewReader(plan.Text))
+		if err != nil {
+			t.Fatalf("parse plan at line %d: %v", plan.Line, err)
+		}
+		if explain.QueryText == "" || explain.Plan == nil {
+			t.Fatalf("expected query text and plan at line %d, got %+v", plan.Line, explain)
+		}
+	}
+	if !strings.HasPrefix(plans[1].Text, "Query Text: SELECT aid, abalance\n  FROM pgbench_accounts") {
+		t.Fatalf("expected continuation lines to keep their indentation, got %q", plans[1].Text)
+	}
+}
+
+func TestAutoExplainJSONLog(t *testing.T) {
+	log := `{"timestamp":"2026-10-14 10:02:11.408 UTC","pid":51877,"error_severity":"LOG","message":"duration: 1.250 ms  plan:\n{\n  \"Query Text\": \"SELECT 1\",\n  \"Plan\": {\"Node Type\": \"Result\", \"Actual Total Time\": 0.002, \"Actual Rows\": 1, \"Actual Loops\": 1}\n}"}
+{"timestamp":"2026-10-14 10:02:11.409 UTC","pid":51877,"error_severity":"LOG","message":"duration: 1.300 ms  statement: SELECT 1"}
+`
+	plans, err := pglog.AutoExplain(strings.NewReader(log))
+	if err != nil {
+		t.Fatalf("parse log: %v", err)
+	}
+	if len(plans) != 1 || plans[0].DurationMs != 1.25 {
+		t.Fatalf("expected one plan, got %+v", plans)
+	}
+	explain, err := parser.Parse(strings.NewReader(plans[0].Text))
+	if err != nil {
+		t.Fatalf("parse plan: %v", err)
+	}
+	if explain.QueryText != "SELECT 1" || explain.Plan.NodeType != "Result" {
+		t.Fatalf("unexpected plan %+v", explain)
+	}
+}
+
+func TestCSVLog(t *testing.T) {
+	file, err := os.Open(filepath.Join(test.RootPath(t), "samples", "auto_explain.csv"))
+	if err != nil {
+		t.Fatalf("open log: %v", err)
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+
+	plans, err := pglog.CSVLog(file)
+	if err != nil {
+		t.Fatalf("parse csvlog: %v", err)
+	}
+	if len(plans) != 2 || plans[0].Line != 2 || plans[1].DurationMs != 676.502 {
+		t.Fatalf("unexpected plans %+v", plans)
+	}
+	for _, plan := range plans {
+		if _, err := parser.Parse(strings.NewReader(plan.Text)); err != nil {
+			t.Fatalf("parse plan at line %d: %v", plan.Line, err)
+		}
+	}
+}
+
+func TestPgBadger(t *testing.T) {
+	export := `{
+  "top_slowest": [
+    {"duration": 12.5, "query": "SELECT 1", "plan": "Query Text: SELECT 1\nResult  (cost=0.00..0.01 rows=1 width=4) (actual time=0.001..0.001 rows=1 loops=1)"}
+  ],
+  "normalyzed_info": {
+    "select ?": {"samples": ["duration: 12.500 ms  plan:\nQuery Text: SELECT 1\nResult  (cost=0.00..0.01 rows=1 width=4) (actual time=0.001..0.001 rows=1 loops=1)"]}
+  },
+  "per_minute_info": {"10:02": {"count": 3, "query": "SELECT now()"}}
+}`
+	plans, err := pglog.PgBadger(strings.NewReader(export))
+	if err != nil {
+		t.Fatalf("parse export: %v", err)
+	}
+	if len(plans) != 1 || plans[0].Line != 0 {
+		t.Fatalf("expected the duplicated plan once, got %+v", plans)
+	}
+	explain, err := parser.Parse(strings.NewReader(plans[0].Text))
+	if err != nil {
+		t.Fatalf("parse plan: %v", err)
+	}
+	if explain.QueryText != "SELECT 1" || explain.Plan.NodeType != "Result" {
+		t.Fatalf("unexpected plan %+v", explain)
+	}
+}
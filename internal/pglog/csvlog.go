@@ -0,0 +1,38 @@
+package pglog
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// csvMessageField is the position of the message column in log_destination = 'csvlog' output, which
+// has kept its first fourteen columns since PostgreSQL 8.3; later releases only append columns.
+const csvMessageField = 13
+
+// CSVLog extracts the plans auto_explain wrote to a csvlog file. Plans span several physical lines
+// inside the quoted message field; Plan.Line is the line the record starts on.
+func CSVLog(r io.Reader) ([]Plan, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.ReuseRecord = true
+
+	var plans []Plan
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return plans, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("pglog: read csvlog: %w", err)
+		}
+		if len(record) <= csvMessageField {
+			continue
+		}
+		line, _ := reader.FieldPos(0)
+		if plan, ok := messagePlan(record[csvMessageField], line); ok {
+			plans = append(plans, plan)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package pglog
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var textPlanNode = regexp.MustCompile(`\(cost=[\d.]+\.\.[\d.]+ rows=\d+ width=\d+\)`)
+
+// PgBadger extracts the auto_explain plans kept in a pgBadger JSON export (pgbadger -x json). The
+// export dumps pgBadger's internal tables, whose layout changes between releases, so rather than
+// following a schema every string holding a plan is collected: a logged "duration: ... plan:" message,
+// a text plan or a FORMAT JSON plan. A "duration" next to the plan supplies DurationMs. pgBadger keeps
+// a slow query in several tables, so a plan seen again is skipped. Plans carry no log line.
+func PgBadger(r io.Reader) ([]Plan, error) {
+	decoder := json.NewDecoder(r)
+	decoder.UseNumber()
+	var payload any
+	if err := decoder.Decode(&payload); err != nil {
+		return nil, fmt.Errorf("pglog: read pgbadger json: %w", err)
+	}
+	c := collector{seen: map[string]bool{}}
+	c.walk(payload, 0)
+	return c.plans, nil
+}
+
+type collector struct {
+	plans []Plan
+	seen  map[string]bool
+}
+
+func (c *collector) walk(val any, duration float64) {
+	switch v := val.(type) {
+	case map[string]any:
+		if number, ok := v["duration"].(json.Number); ok {
+			duration, _ = number.Float64()
+		}
+		if plan, ok := v["Plan"].(map[string]any); ok && plan["Node Type"] != nil {
+			if data, err := json.MarshalIndent(v, "", "  "); err == nil {
+				c.add(Plan{DurationMs: duration, Text: string(data)})
+			}
+			return
+		}
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			c.walk(v[k], duration)
+		}
+	case []any:
+		for _, item := range v {
+			c.walk(item, duration)
+		}
+	case string:
+		if plan, ok := messagePlan(v, 0); ok {
+			c.add(plan)
+		} else if looksLikePlan(v) {
+			c.add(Plan{DurationMs: duration, Text: strings.TrimSpace(v)})
+		}
+	}
+}
+
+func (c *collector) add(plan Plan) {
+	if c.seen[plan.Text] {
+		return
+	}
+	c.seen[plan.Text] = true
+	c.plans = append(c.plans, plan)
+}
+
+// looksLikePlan reports whether s is an EXPLAIN plan in the text or JSON format.
+func looksLikePlan(s string) bool {
+	s = strings.TrimSpace(s)
+	switch {
+	case strings.HasPrefix(s, "Query Text:"):
+		return true
+	case strings.HasPrefix(s, "{") || strings.HasPrefix(s, "["):
+		return strings.Contains(s, `"Node Type"`)
+	default:
+		first, _, _ := strings.Cut(s, "\n")
+		return textPlanNode.MatchString(first)
+	}
+}
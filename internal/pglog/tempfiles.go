@@ -0,0 +1,68 @@
+// Package pglog reads PostgreSQL server log output.
+package pglog
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/mickamy/xplain/internal/fingerprint"
+	"github.com/mickamy/xplain/internal/model"
+)
+
+var (
+	tempFileLine  = regexp.MustCompile(`temporary file: path "([^"]+)", size (\d+)`)
+	statementLine = regexp.MustCompile(`STATEMENT:\s+(.*)$`)
+)
+
+// TempFiles extracts the "temporary file" entries written by log_temp_files from a stderr-format
+// log. The STATEMENT line PostgreSQL logs after them is recorded on each file it follows.
+func TempFiles(r io.Reader) ([]model.TempFile, error) {
+	var files []model.TempFile
+	pending := 0
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if m := tempFileLine.FindStringSubmatch(line); m != nil {
+			size, err := strconv.ParseInt(m[2], 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("pglog: temp file size %q: %w", m[2], err)
+			}
+			files = append(files, model.TempFile{Path: m[1], SizeBytes: size})
+			pending++
+			continue
+		}
+		if m := statementLine.FindStringSubmatch(line); m != nil && pending > 0 {
+			statement := strings.TrimSpace(m[1])
+			for i := len(files) - pending; i < len(files); i++ {
+				files[i].Statement = statement
+			}
+			pending = 0
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("pglog: read log: %w", err)
+	}
+	return files, nil
+}
+
+// ForStatement keeps the files logged for the given statement, comparing normalized fingerprints
+// so differing literals still match. Files without a statement, or any file when query is empty,
+// are kept.
+func ForStatement(files []model.TempFile, query string) []model.TempFile {
+	if strings.TrimSpace(query) == "" {
+		return files
+	}
+	want := fingerprint.Query(query)
+	var out []model.TempFile
+	for _, f := range files {
+		if f.Statement == "" || fingerprint.Query(f.Statement) == want {
+			out = append(out, f)
+		}
+	}
+	return out
+}
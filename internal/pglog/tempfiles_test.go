@@ -0,0 +1,36 @@
+package pglog_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mickamy/xplain/internal/pglog"
+	"github.com/mickamy/xplain/test"
+)
+
+func TestTempFiles(t *testing.T) {
+	file, err := os.Open(filepath.Join(test.RootPath(t), "samples", "temp_files.log"))
+	if err != nil {
+		t.Fatalf("open log: %v", err)
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+
+	files, err := pglog.TempFiles(file)
+	if err != nil {
+		t.Fatalf("parse log: %v", err)
+	}
+	if len(files) != 3 {
+		t.Fatalf("expected 3 temp files, got %+v", files)
+	}
+	if files[0].Path != "base/pgsql_tmp/pgsql_tmp48213.0" || files[0].SizeBytes != 18063360 || files[0].Statement == "" {
+		t.Fatalf("unexpected first file %+v", files[0])
+	}
+
+	matched := pglog.ForStatement(files, "SELECT customer_id, created_at, total FROM orders WHERE status = 'pending' ORDER BY customer_id, created_at DESC")
+	if len(matched) != 2 {
+		t.Fatalf("expected the two orders files, got %+v", matched)
+	}
+}
@@ -0,0 +1,226 @@
+package prepared
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/mickamy/xplain/internal/analyzer"
+	"github.com/mickamy/xplain/internal/fingerprint"
+	"github.com/mickamy/xplain/internal/model"
+	"github.com/mickamy/xplain/internal/sweep"
+)
+
+// Plan kinds: custom plans are made for the parameter values, generic plans for any value and show the
+// parameters as $n.
+const (
+	Custom  = "custom"
+	Generic = "generic"
+)
+
+// minSlowdown is how much slower the plan after the flip must be before the flip counts as a regression.
+const minSlowdown = 1.2
+
+var (
+	paramRef = regexp.MustCompile(`\$\d+\b`)
+	// initPlanReturns reads the parameters an InitPlan sets, "InitPlan 1 (returns $0,$1)" up to PostgreSQL 15.
+	initPlanReturns = regexp.MustCompile(`\(returns ([^)]*)\)`)
+)
+
+// Report describes how the plan of a prepared statement evolved over repeated executions.
+type Report struct {
+	Executions []Entry `json:"executions"`
+	// FlipAt is the first execution whose plan differs from the first one; 0 when the plan never changed.
+	FlipAt int `json:"flip_at,omitempty"`
+	// BeforeMs and AfterMs average the execution time before and from the flip.
+	BeforeMs float64 `json:"before_mean_ms"`
+	AfterMs  float64 `json:"after_mean_ms,omitempty"`
+	// Regressed reports whether executions got markedly slower after the flip.
+	Regressed bool   `json:"regressed"`
+	Verdict   string `json:"verdict"`
+}
+
+// Entry summarizes one execution of the prepared statement.
+type Entry struct {
+	Number      int     `json:"execution"`
+	Plan        string  `json:"plan"`
+	ExecutionMs float64 `json:"execution_ms"`
+	PlanningMs  float64 `json:"planning_ms"`
+	Shape       string  `json:"shape"`
+	// Changed reports whether this plan differs from the previous execution's.
+	Changed bool `json:"changed"`
+}
+
+// Detect compares the plans of consecutive executions, in order, of one prepared statement.
+func Detect(executions []*analyzer.PlanAnalysis) *Report {
+	report := &Report{}
+	var first, previous string
+	var before, after []float64
+	for i, analysis := range executions {
+		if analysis == nil || analysis.Root == nil {
+			continue
+		}
+		entry := Entry{
+			Number:      i + 1,
+			Plan:        Kind(analysis),
+			ExecutionMs: executionMs(analysis),
+			PlanningMs:  analysis.PlanningTimeMs,
+			Shape:       sweep.Shape(analysis.Root.Node),
+		}
+		key := entry.Plan + " " + fingerprint.Plan(analysis.Root.Node)
+		if first == "" {
+			first = key
+		}
+		entry.Changed = previous != "" && key != previous
+		previous = key
+		if report.FlipAt == 0 && key != first {
+			report.FlipAt = entry.Number
+		}
+		if report.FlipAt == 0 {
+			before = append(before, entry.ExecutionMs)
+		} else {
+			after = append(after, entry.ExecutionMs)
+		}
+		report.Executions = append(report.Executions, entry)
+	}
+	report.BeforeMs = mean(before)
+	report.AfterMs = mean(after)
+	report.Regressed = report.BeforeMs > 0 && report.AfterMs >= report.BeforeMs*minSlowdown
+	report.Verdict = report.verdict()
+	return report
+}
+
+func (r *Report) verdict() string {
+	if len(r.Executions) == 0 {
+		return ""
+	}
+	if r.FlipAt == 0 {
+		last := r.Executions[len(r.Executions)-1]
+		if last.Plan == Generic {
+			return fmt.Sprintf("The plan never changed over %d executions; it was generic throughout.", len(r.Executions))
+		}
+		return fmt.Sprintf("The plan never changed over %d executions; PostgreSQL kept planning for the parameter values.", len(r.Executions))
+	}
+	flipped := r.Executions[r.FlipAt-1]
+	text := fmt.Sprintf("The plan flipped at execution %d (%s plan", r.FlipAt, flipped.Plan)
+	if flipped.Shape != r.Executions[0].Shape {
+		text += " with a different shape"
+	}
+	text += ")"
+	switch {
+	case r.BeforeMs <= 0 || r.AfterMs <= 0:
+		return text + "."
+	case r.Regressed:
+		return text + fmt.Sprintf(" and got %.1fx slower: %.3f ms on average before, %.3f ms after. "+
+			"Consider plan_cache_mode = force_custom_plan for this statement or fixing the estimate behind the generic plan.",
+			r.AfterMs/r.BeforeMs, r.BeforeMs, r.AfterMs)
+	case r.BeforeMs >= r.AfterMs*minSlowdown:
+		return text + fmt.Sprintf(" and got %.1fx faster: %.3f ms on average before, %.3f ms after.", r.BeforeMs/r.AfterMs, r.BeforeMs, r.AfterMs)
+	}
+	return text + fmt.Sprintf("; latency stayed about the same (%.3f ms before, %.3f ms after).", r.BeforeMs, r.AfterMs)
+}
+
+// Kind reports whether the plan was made for specific parameter values or is a generic plan that still
+// references $n parameters. The $n an InitPlan returns, as PostgreSQL 15 and older print its output, are
+// not statement parameters and do not make a plan generic.
+func Kind(analysis *analyzer.PlanAnalysis) string {
+	internal := map[string]bool{}
+	for _, n := range analysis.Nodes() {
+		if m := initPlanReturns.FindStringSubmatch(n.Node.SubplanName); m != nil {
+			for _, param := range paramRef.FindAllString(m[1], -1) {
+				internal[param] = true
+			}
+		}
+	}
+	for _, n := range analysis.Nodes() {
+		if referencesParams(n.Node, internal) {
+			return Generic
+		}
+	}
+	return Custom
+}
+
+func referencesParams(node *model.PlanNode, internal map[string]bool) bool {
+	refers := func(text string) bool {
+		for _, param := range paramRef.FindAllString(text, -1) {
+			if !internal[param] {
+				return true
+			}
+		}
+		return false
+	}
+	for _, cond := range []string{node.Filter, node.JoinFilter, node.HashCond, node.MergeCond} {
+		if refers(cond) {
+			return true
+		}
+	}
+	for _, val := range node.Extra {
+		if s, ok := val.(string); ok && refers(s) {
+			return true
+		}
+	}
+	return false
+}
+
+func executionMs(analysis *analyzer.PlanAnalysis) float64 {
+	if analysis.ExecutionTimeMs > 0 {
+		return analysis.ExecutionTimeMs
+	}
+	return analysis.TotalTimeMs
+}
+
+func mean(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	var sum float64
+	for _, v := range values {
+		sum += v
+	}
+	return sum / float64(len(values))
+}
+
+// Markdown renders the report as a Markdown document.
+func (r *Report) Markdown() string {
+	var b strings.Builder
+	b.WriteString("# xplain prepared statement check\n\n")
+	b.WriteString("| Execution | Plan | Execution (ms) | Planning (ms) | Shape |\n")
+	b.WriteString("|---:|---|---:|---:|---|\n")
+	for _, e := range r.Executions {
+		plan := e.Plan
+		if e.Changed {
+			plan = "**" + plan + "** (changed)"
+		}
+		_, _ = fmt.Fprintf(&b, "| %d | %s | %.3f | %.3f | `%s` |\n", e.Number, plan, e.ExecutionMs, e.PlanningMs, e.Shape)
+	}
+	if r.Verdict != "" {
+		_, _ = fmt.Fprintf(&b, "\n%s\n", r.Verdict)
+	}
+	return b.String()
+}
+
+// Text renders the report as an aligned terminal table.
+func (r *Report) Text() string {
+	var b strings.Builder
+	_, _ = fmt.Fprintf(&b, "%9s %-8s %14s %13s  %s\n", "Execution", "Plan", "Execution ms", "Planning ms", "Shape")
+	for _, e := range r.Executions {
+		marker := ""
+		if e.Changed {
+			marker = "  <- changed"
+		}
+		_, _ = fmt.Fprintf(&b, "%9d %-8s %14.3f %13.3f  %s%s\n", e.Number, e.Plan, e.ExecutionMs, e.PlanningMs, e.Shape, marker)
+	}
+	if r.Verdict != "" {
+		_, _ = fmt.Fprintf(&b, "\n%s\n", r.Verdict)
+	}
+	return b.String()
+}
+
+// JSON marshals the report into an indented JSON document.
+func (r *Report) JSON() ([]byte, error) {
+	if r == nil {
+		return nil, fmt.Errorf("nil report")
+	}
+	return json.MarshalIndent(r, "", "  ")
+}
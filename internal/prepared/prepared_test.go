@@ -0,0 +1,77 @@
+package prepared_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mickamy/xplain/internal/analyzer"
+	"github.com/mickamy/xplain/internal/parser"
+	"github.com/mickamy/xplain/internal/prepared"
+)
+
+const (
+	customPlan = `[{"Plan": {"Node Type": "Index Scan", "Relation Name": "orders", "Index Name": "orders_customer_idx",
+  "Index Cond": "(customer_id = 42)", "Actual Total Time": 0.4, "Actual Rows": 3, "Actual Loops": 1},
+  "Planning Time": 0.15, "Execution Time": 0.5}]`
+	genericPlan = `[{"Plan": {"Node Type": "Seq Scan", "Relation Name": "orders", "Filter": "(customer_id = $1)",
+  "Actual Total Time": 40.0, "Actual Rows": 3, "Actual Loops": 1},
+  "Planning Time": 0.01, "Execution Time": 41.0}]`
+	// PostgreSQL 15 prints the value an InitPlan returns as $0.
+	initPlanCustom = `[{"Plan": {"Node Type": "Seq Scan", "Relation Name": "orders", "Filter": "((customer_id = 42) AND (total > $0))",
+  "Actual Total Time": 2.0, "Actual Rows": 1, "Actual Loops": 1,
+  "Plans": [{"Node Type": "Aggregate", "Parent Relationship": "InitPlan", "Subplan Name": "InitPlan 1 (returns $0)",
+    "Actual Total Time": 1.0, "Actual Rows": 1, "Actual Loops": 1}]},
+  "Planning Time": 0.1, "Execution Time": 2.1}]`
+)
+
+func analyze(t *testing.T, input string) *analyzer.PlanAnalysis {
+	t.Helper()
+	plan, err := parser.Parse(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	analysis, err := analyzer.Analyze(plan)
+	if err != nil {
+		t.Fatalf("analyze: %v", err)
+	}
+	return analysis
+}
+
+func TestDetect(t *testing.T) {
+	var runs []*analyzer.PlanAnalysis
+	for i := 0; i < 5; i++ {
+		runs = append(runs, analyze(t, customPlan))
+	}
+	for i := 0; i < 3; i++ {
+		runs = append(runs, analyze(t, genericPlan))
+	}
+
+	report := prepared.Detect(runs)
+	if report.FlipAt != 6 || len(report.Executions) != 8 {
+		t.Fatalf("expected the plan to flip at execution 6, got %+v", report)
+	}
+	if e := report.Executions[5]; e.Plan != prepared.Generic || !e.Changed || e.Shape != "Seq Scan on orders" {
+		t.Fatalf("unexpected flipped execution %+v", e)
+	}
+	if report.Executions[0].Plan != prepared.Custom || report.Executions[6].Changed {
+		t.Fatalf("expected custom plans first and no change after the flip, got %+v", report.Executions)
+	}
+	if report.BeforeMs != 0.5 || report.AfterMs != 41 || !report.Regressed {
+		t.Fatalf("unexpected averages %v and %v", report.BeforeMs, report.AfterMs)
+	}
+	if !strings.Contains(report.Verdict, "flipped at execution 6 (generic plan with a different shape) and got 82.0x slower") {
+		t.Fatalf("unexpected verdict %q", report.Verdict)
+	}
+
+	if kind := prepared.Kind(analyze(t, initPlanCustom)); kind != prepared.Custom {
+		t.Fatalf("expected the InitPlan output not to count as a parameter, got %s", kind)
+	}
+	if kind := prepared.Kind(analyze(t, strings.Replace(initPlanCustom, "customer_id = 42", "customer_id = $1", 1))); kind != prepared.Generic {
+		t.Fatalf("expected a statement parameter next to the InitPlan output to stay generic, got %s", kind)
+	}
+
+	stable := prepared.Detect(runs[:5])
+	if stable.FlipAt != 0 || !strings.Contains(stable.Verdict, "never changed over 5 executions") {
+		t.Fatalf("expected a stable plan, got %+v", stable)
+	}
+}
@@ -0,0 +1,265 @@
+// Package redact keeps connection secrets out of errors, logs, reports and saved envelopes.
+package redact
+
+import (
+	"net"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/mickamy/xplain/internal/config"
+)
+
+const (
+	// Password replaces connection passwords.
+	Password = "xxxxx"
+	// Host replaces host names when output.redact_hosts is enabled.
+	Host = "redacted"
+
+	// minBarePassword is the length from which a password is scrubbed wherever it appears as a whole
+	// word. Shorter ones, like the "password" of local setups, are too often ordinary words in driver
+	// errors, SQL or plan text, so they are only scrubbed where a connection string carries them.
+	minBarePassword = 9
+)
+
+var (
+	mu sync.RWMutex
+	// dsns maps registered connection strings to their masked form.
+	dsns = map[string]string{}
+	// secrets maps registered passwords to the patterns of the connection string forms carrying them.
+	secrets = map[string][]*regexp.Regexp{}
+	// hidden holds the registered hosts when output.redact_hosts is enabled.
+	hidden = map[string]bool{}
+
+	keywordPassword = regexp.MustCompile(`(password\s*=\s*)('(?:[^'\\]|\\.)*'|[^\s]+)`)
+	keywordHost     = regexp.MustCompile(`((?:host|hostaddr)\s*=\s*)('(?:[^'\\]|\\.)*'|[^\s]+)`)
+	// brokenURL catches the user:password@ part of URLs that net/url refuses to parse.
+	brokenURL = regexp.MustCompile(`^(postgres(?:ql)?://[^:@/]*):[^/]*@`)
+)
+
+// Register records the secrets of a connection string so String and Error scrub them from any
+// text produced afterwards, such as driver errors that quote the DSN or the host it dialled.
+func Register(dsn string) {
+	dsn = strings.TrimSpace(dsn)
+	if dsn == "" {
+		return
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	dsns[dsn] = DSN(dsn)
+	for _, pw := range passwords(dsn) {
+		secrets[pw] = passwordForms(pw)
+	}
+	if config.Active().Output.RedactHosts {
+		for _, host := range hosts(dsn) {
+			hidden[host] = true
+		}
+	}
+}
+
+// RegisterPassword records a password that reached a connection without its connection string carrying it,
+// such as one read from the connection service file or ~/.pgpass.
+func RegisterPassword(pw string) {
+	if pw == "" {
+		return
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	secrets[pw] = passwordForms(pw)
+}
+
+// passwordForms matches pw where connection strings carry it, as user:pw@ and password=pw, with the
+// text before and after it in the first and second group. The keyword form is only taken without
+// spaces around the "=", so a SQL comparison such as password = 'password' is left alone.
+func passwordForms(pw string) []*regexp.Regexp {
+	quoted := regexp.QuoteMeta(strings.ReplaceAll(pw, "'", `\'`))
+	pw = regexp.QuoteMeta(pw)
+	return []*regexp.Regexp{
+		regexp.MustCompile(`(:)` + pw + `(@)`),
+		regexp.MustCompile(`((?:^|[\s?&])(?i:password)=')` + quoted + `(')`),
+		regexp.MustCompile(`((?:^|[\s?&])(?i:password)=)` + pw + `(&|\s|$)`),
+	}
+}
+
+// DSN returns the connection string with its password masked, and its hosts too when
+// output.redact_hosts is enabled. Both URL and keyword/value forms are understood.
+func DSN(dsn string) string {
+	hideHosts := config.Active().Output.RedactHosts
+	if u, ok := parseURL(dsn); ok {
+		if u.User != nil {
+			if _, set := u.User.Password(); set {
+				u.User = url.UserPassword(u.User.Username(), Password)
+			}
+		}
+		if hideHosts && u.Host != "" {
+			u.Host = Host
+		}
+		query := u.Query()
+		if query.Has("password") {
+			query.Set("password", Password)
+		}
+		if hideHosts {
+			for _, key := range []string{"host", "hostaddr"} {
+				if query.Has(key) {
+					query.Set(key, Host)
+				}
+			}
+		}
+		u.RawQuery = query.Encode()
+		return u.String()
+	}
+	dsn = brokenURL.ReplaceAllString(dsn, "${1}:"+Password+"@")
+	dsn = keywordPassword.ReplaceAllString(dsn, "${1}"+Password)
+	if hideHosts {
+		dsn = keywordHost.ReplaceAllString(dsn, "${1}"+Host)
+	}
+	return dsn
+}
+
+// String scrubs every registered secret from s. Registered connection strings are masked as a whole
+// first, then passwords where a connection string form carries them and, when long enough not to be
+// an ordinary word, wherever they stand as a whole word, and finally hidden hosts as whole words.
+func String(s string) string {
+	mu.RLock()
+	defer mu.RUnlock()
+	for _, dsn := range longestFirst(dsns) {
+		s = strings.ReplaceAll(s, dsn, dsns[dsn])
+	}
+	for _, pw := range longestFirst(secrets) {
+		for _, form := range secrets[pw] {
+			s = form.ReplaceAllString(s, "${1}"+Password+"${2}")
+		}
+		if utf8.RuneCountInString(pw) >= minBarePassword {
+			s = replaceWord(s, pw, Password)
+		}
+	}
+	for _, host := range longestFirst(hidden) {
+		s = replaceWord(s, host, Host)
+	}
+	return s
+}
+
+func longestFirst[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool { return len(keys[i]) > len(keys[j]) })
+	return keys
+}
+
+// replaceWord replaces the occurrences of word in s that are not part of a longer word.
+func replaceWord(s, word, replacement string) string {
+	var b strings.Builder
+	for {
+		i := strings.Index(s, word)
+		if i < 0 {
+			b.WriteString(s)
+			return b.String()
+		}
+		end := i + len(word)
+		before, _ := utf8.DecodeLastRuneInString(s[:i])
+		after, _ := utf8.DecodeRuneInString(s[end:])
+		b.WriteString(s[:i])
+		if wordRune(before) || wordRune(after) {
+			b.WriteString(word)
+		} else {
+			b.WriteString(replacement)
+		}
+		s = s[end:]
+	}
+}
+
+func wordRune(r rune) bool {
+	return r != utf8.RuneError && (r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r))
+}
+
+// Error returns err with registered secrets scrubbed from its message. The original error stays
+// reachable through errors.Is and errors.As.
+func Error(err error) error {
+	if err == nil {
+		return nil
+	}
+	msg := String(err.Error())
+	if msg == err.Error() {
+		return err
+	}
+	return &redactedError{msg: msg, err: err}
+}
+
+type redactedError struct {
+	msg string
+	err error
+}
+
+func (e *redactedError) Error() string { return e.msg }
+
+func (e *redactedError) Unwrap() error { return e.err }
+
+func parseURL(dsn string) (*url.URL, bool) {
+	if !strings.HasPrefix(dsn, "postgres://") && !strings.HasPrefix(dsn, "postgresql://") {
+		return nil, false
+	}
+	u, err := url.Parse(dsn)
+	if err != nil {
+		return nil, false
+	}
+	return u, true
+}
+
+func passwords(dsn string) []string {
+	var out []string
+	if u, ok := parseURL(dsn); ok {
+		if u.User != nil {
+			if pw, set := u.User.Password(); set && pw != "" {
+				// The DSN itself carries the percent-encoded form.
+				out = append(out, pw, url.UserPassword("", pw).String()[1:])
+			}
+		}
+		if pw := u.Query().Get("password"); pw != "" {
+			out = append(out, pw)
+		}
+		return out
+	}
+	for _, m := range keywordPassword.FindAllStringSubmatch(dsn, -1) {
+		if pw := unquote(m[2]); pw != "" {
+			out = append(out, pw)
+		}
+	}
+	return out
+}
+
+func hosts(dsn string) []string {
+	var raw []string
+	if u, ok := parseURL(dsn); ok {
+		raw = append(raw, strings.Split(u.Host, ",")...)
+		raw = append(raw, strings.Split(u.Query().Get("host"), ",")...)
+		raw = append(raw, u.Query().Get("hostaddr"))
+	} else {
+		for _, m := range keywordHost.FindAllStringSubmatch(dsn, -1) {
+			raw = append(raw, strings.Split(unquote(m[2]), ",")...)
+		}
+	}
+	var out []string
+	for _, h := range raw {
+		if host, _, err := net.SplitHostPort(h); err == nil {
+			h = host
+		}
+		// Socket directories are paths, not host names worth hiding.
+		if h = strings.Trim(h, "[]"); h != "" && !strings.HasPrefix(h, "/") {
+			out = append(out, h)
+		}
+	}
+	return out
+}
+
+func unquote(v string) string {
+	if len(v) >= 2 && v[0] == '\'' && v[len(v)-1] == '\'' {
+		v = strings.ReplaceAll(v[1:len(v)-1], `\'`, `'`)
+	}
+	return v
+}
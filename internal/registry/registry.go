@@ -0,0 +1,116 @@
+package registry
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"time"
+
+	"github.com/mickamy/xplain/internal/fingerprint"
+)
+
+// FileName is the registry kept at the root of the output directory. It stores the SQL itself, so a
+// committed registry resolves the same names on every machine.
+const FileName = "queries.json"
+
+var validName = regexp.MustCompile(`^[a-z0-9][a-z0-9._-]*$`)
+
+// Query is one named statement.
+type Query struct {
+	// SQL is the statement as registered, template placeholders included, so --var still applies.
+	SQL string `json:"sql"`
+	// Source is the slash-separated file the statement was read from, for reference.
+	Source      string    `json:"source,omitempty"`
+	Fingerprint string    `json:"fingerprint"`
+	AddedAt     time.Time `json:"added_at"`
+}
+
+// Registry maps query names to statements.
+type Registry struct {
+	Queries map[string]Query `json:"queries"`
+	path    string
+}
+
+// Load reads the registry in dir; a missing file yields an empty registry.
+func Load(dir string) (*Registry, error) {
+	r := &Registry{Queries: map[string]Query{}, path: filepath.Join(dir, FileName)}
+	data, err := os.ReadFile(r.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return r, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("registry: read: %w", err)
+	}
+	if err := json.Unmarshal(data, r); err != nil {
+		return nil, fmt.Errorf("registry: parse %s: %w", r.path, err)
+	}
+	if r.Queries == nil {
+		r.Queries = map[string]Query{}
+	}
+	return r, nil
+}
+
+// Add registers sql under name. An existing name is only overwritten when replace is set.
+func (r *Registry) Add(name, sql, source string, replace bool) (Query, error) {
+	if !validName.MatchString(name) {
+		return Query{}, fmt.Errorf("registry: invalid name %q: use lower-case letters, digits, '.', '_' and '-'", name)
+	}
+	if _, ok := r.Queries[name]; ok && !replace {
+		return Query{}, fmt.Errorf("registry: query %q already exists (pass --replace to overwrite it)", name)
+	}
+	q := Query{
+		SQL:         sql,
+		Source:      filepath.ToSlash(source),
+		Fingerprint: fingerprint.Query(sql),
+		AddedAt:     time.Now().UTC(),
+	}
+	r.Queries[name] = q
+	return q, nil
+}
+
+// Get returns the query registered under name.
+func (r *Registry) Get(name string) (Query, error) {
+	q, ok := r.Queries[name]
+	if !ok {
+		return Query{}, fmt.Errorf("registry: no query named %q in %s (see xplain query list)", name, r.path)
+	}
+	return q, nil
+}
+
+// Remove drops the query registered under name.
+func (r *Registry) Remove(name string) error {
+	if _, ok := r.Queries[name]; !ok {
+		return fmt.Errorf("registry: no query named %q in %s", name, r.path)
+	}
+	delete(r.Queries, name)
+	return nil
+}
+
+// Names lists the registered names in order.
+func (r *Registry) Names() []string {
+	names := make([]string, 0, len(r.Queries))
+	for name := range r.Queries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Save writes the registry back to the file it was loaded from, creating the directory when needed.
+func (r *Registry) Save() error {
+	if err := os.MkdirAll(filepath.Dir(r.path), 0o755); err != nil {
+		return fmt.Errorf("registry: create dir: %w", err)
+	}
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return fmt.Errorf("registry: encode: %w", err)
+	}
+	if err := os.WriteFile(r.path, append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("registry: write: %w", err)
+	}
+	return nil
+}
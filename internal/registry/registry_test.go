@@ -0,0 +1,48 @@
+package registry_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mickamy/xplain/internal/registry"
+)
+
+func TestAddSaveAndLoad(t *testing.T) {
+	dir := t.TempDir()
+	reg, err := registry.Load(dir)
+	if err != nil || len(reg.Names()) != 0 {
+		t.Fatalf("expected an empty registry, got %v (%v)", reg.Names(), err)
+	}
+
+	sql := "SELECT * FROM orders WHERE customer_id = {{ .customer_id }};\n"
+	if _, err := reg.Add("checkout-search", sql, "queries/checkout_search.sql", false); err != nil {
+		t.Fatalf("add: %v", err)
+	}
+	if _, err := reg.Add("checkout-search", "SELECT 1", "", false); err == nil || !strings.Contains(err.Error(), "--replace") {
+		t.Fatalf("expected a duplicate name to be rejected, got %v", err)
+	}
+	if _, err := reg.Add("Checkout Search", sql, "", false); err == nil {
+		t.Fatal("expected an invalid name to be rejected")
+	}
+	if err := reg.Save(); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+
+	loaded, err := registry.Load(dir)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	q, err := loaded.Get("checkout-search")
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	if q.SQL != sql || q.Source != "queries/checkout_search.sql" || q.Fingerprint == "" || q.AddedAt.IsZero() {
+		t.Fatalf("unexpected query %+v", q)
+	}
+	if err := loaded.Remove("checkout-search"); err != nil {
+		t.Fatalf("remove: %v", err)
+	}
+	if _, err := loaded.Get("checkout-search"); err == nil {
+		t.Fatal("expected the removed query to be gone")
+	}
+}
@@ -0,0 +1,130 @@
+package badge
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+
+	"github.com/mickamy/xplain/internal/insight"
+)
+
+// DefaultLabel is the text on the left half of the badge.
+const DefaultLabel = "query score"
+
+// Options configures the badge renderers.
+type Options struct {
+	Label string
+}
+
+// endpoint is the shields.io endpoint schema (https://shields.io/badges/endpoint-badge).
+type endpoint struct {
+	SchemaVersion int    `json:"schemaVersion"`
+	Label         string `json:"label"`
+	Message       string `json:"message"`
+	Color         string `json:"color"`
+}
+
+// RenderJSON writes the score as a shields.io endpoint document, for badges served from a committed file.
+func RenderJSON(w io.Writer, score insight.Score, opts Options) error {
+	payload, err := json.MarshalIndent(endpoint{
+		SchemaVersion: 1,
+		Label:         label(opts),
+		Message:       message(score),
+		Color:         Color(score.Value),
+	}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("badge render: encode: %w", err)
+	}
+	if _, err := fmt.Fprintf(w, "%s\n", payload); err != nil {
+		return fmt.Errorf("badge render: %w", err)
+	}
+	return nil
+}
+
+// RenderSVG writes a flat badge in the shields.io style that needs no external service.
+func RenderSVG(w io.Writer, score insight.Score, opts Options) error {
+	tpl, err := template.New("badge").Parse(svgTemplate)
+	if err != nil {
+		return fmt.Errorf("badge render: compile template: %w", err)
+	}
+	left, right := label(opts), message(score)
+	leftWidth, rightWidth := textWidth(left), textWidth(right)
+	data := struct {
+		Label, Message, Color        string
+		Width, LeftWidth, RightWidth int
+		LabelX, MessageX             int
+		LabelLength, MessageLength   int
+	}{
+		Label:         left,
+		Message:       right,
+		Color:         colors[Color(score.Value)],
+		Width:         leftWidth + rightWidth,
+		LeftWidth:     leftWidth,
+		RightWidth:    rightWidth,
+		LabelX:        leftWidth * 5,
+		MessageX:      leftWidth*10 + rightWidth*5,
+		LabelLength:   (leftWidth - 10) * 10,
+		MessageLength: (rightWidth - 10) * 10,
+	}
+	if err := tpl.Execute(w, data); err != nil {
+		return fmt.Errorf("badge render: execute template: %w", err)
+	}
+	return nil
+}
+
+// Color maps a score to a shields.io named color.
+func Color(value int) string {
+	switch {
+	case value >= 90:
+		return "brightgreen"
+	case value >= 75:
+		return "green"
+	case value >= 60:
+		return "yellow"
+	case value >= 40:
+		return "orange"
+	default:
+		return "red"
+	}
+}
+
+var colors = map[string]string{
+	"brightgreen": "#4c1",
+	"green":       "#97ca00",
+	"yellow":      "#dfb317",
+	"orange":      "#fe7d37",
+	"red":         "#e05d44",
+}
+
+func label(opts Options) string {
+	if opts.Label == "" {
+		return DefaultLabel
+	}
+	return opts.Label
+}
+
+func message(score insight.Score) string {
+	return fmt.Sprintf("%d/100", score.Value)
+}
+
+// textWidth approximates the rendered width of 11px Verdana text plus padding.
+func textWidth(text string) int {
+	return len([]rune(text))*7 + 10
+}
+
+const svgTemplate = `<svg xmlns="http://www.w3.org/2000/svg" width="{{.Width}}" height="20" role="img" aria-label="{{.Label}}: {{.Message}}">
+  <title>{{.Label}}: {{.Message}}</title>
+  <linearGradient id="s" x2="0" y2="100%"><stop offset="0" stop-color="#bbb" stop-opacity=".1"/><stop offset="1" stop-opacity=".1"/></linearGradient>
+  <clipPath id="r"><rect width="{{.Width}}" height="20" rx="3" fill="#fff"/></clipPath>
+  <g clip-path="url(#r)">
+    <rect width="{{.LeftWidth}}" height="20" fill="#555"/>
+    <rect x="{{.LeftWidth}}" width="{{.RightWidth}}" height="20" fill="{{.Color}}"/>
+    <rect width="{{.Width}}" height="20" fill="url(#s)"/>
+  </g>
+  <g fill="#fff" text-anchor="middle" font-family="Verdana,Geneva,DejaVu Sans,sans-serif" font-size="110">
+    <text x="{{.LabelX}}" y="140" transform="scale(.1)" textLength="{{.LabelLength}}">{{.Label}}</text>
+    <text x="{{.MessageX}}" y="140" transform="scale(.1)" textLength="{{.MessageLength}}">{{.Message}}</text>
+  </g>
+</svg>
+`
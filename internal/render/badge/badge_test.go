@@ -0,0 +1,39 @@
+package badge_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/mickamy/xplain/internal/insight"
+	"github.com/mickamy/xplain/internal/render/badge"
+)
+
+func TestRenderJSON(t *testing.T) {
+	var buf bytes.Buffer
+	if err := badge.RenderJSON(&buf, insight.Score{Value: 72}, badge.Options{}); err != nil {
+		t.Fatalf("render: %v", err)
+	}
+	var payload map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &payload); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if payload["schemaVersion"] != 1.0 || payload["label"] != badge.DefaultLabel || payload["message"] != "72/100" || payload["color"] != "yellow" {
+		t.Fatalf("unexpected endpoint %v", payload)
+	}
+}
+
+func TestRenderSVG(t *testing.T) {
+	var buf bytes.Buffer
+	if err := badge.RenderSVG(&buf, insight.Score{Value: 95}, badge.Options{Label: "orders <list>"}); err != nil {
+		t.Fatalf("render: %v", err)
+	}
+	svg := buf.String()
+	if !strings.HasPrefix(svg, "<svg") || !strings.Contains(svg, "95/100") || !strings.Contains(svg, "#4c1") {
+		t.Fatalf("unexpected svg %s", svg)
+	}
+	if !strings.Contains(svg, "orders &lt;list&gt;") {
+		t.Fatalf("expected the label to be escaped, got %s", svg)
+	}
+}
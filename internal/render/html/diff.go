@@ -0,0 +1,224 @@
+package html
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+
+	"github.com/mickamy/xplain/internal/diff"
+	"github.com/mickamy/xplain/internal/insight"
+	"github.com/mickamy/xplain/internal/numfmt"
+)
+
+// RenderDiff writes an HTML view of a diff report: the target plan tree with every node coloured by
+// how much its self time moved since the base plan, rather than by its absolute share of the runtime.
+func RenderDiff(w io.Writer, report *diff.Report, opts Options) error {
+	if report == nil || report.Tree == nil {
+		return fmt.Errorf("html render: empty diff report")
+	}
+	if opts.Title == "" {
+		opts.Title = "xplain diff"
+	}
+	data := diffTemplateData{
+		Title:         opts.Title,
+		IncludeStyles: opts.IncludeStyles,
+		HighContrast:  opts.HighContrast,
+		Execution: fmt.Sprintf("%.3f ms → %.3f ms (%+.3f ms, %+.1f%%)", report.Summary.BaseExecutionMs,
+			report.Summary.TargetExecutionMs, report.Summary.DeltaExecutionMs, report.Summary.PercentExecution),
+		Planning: fmt.Sprintf("%.3f ms → %.3f ms (%+.3f ms, %+.1f%%)", report.Summary.BasePlanningMs,
+			report.Summary.TargetPlanningMs, report.Summary.DeltaPlanningMs, report.Summary.PercentPlanning),
+		Root: buildDiffNodeView(report, report.Tree, opts.Numbers),
+	}
+	for _, msg := range report.Insights {
+		data.Insights = append(data.Insights, msg.Icon+" "+msg.Message)
+	}
+	for _, entry := range report.Removed {
+		data.Removed = append(data.Removed, listView{
+			Label: entry.Signature,
+			Self:  fmt.Sprintf("%.2f ms", entry.BaseSelfMs),
+		})
+	}
+	tpl, err := template.New("diff").Parse(diffTemplate)
+	if err != nil {
+		return fmt.Errorf("html render: compile template: %w", err)
+	}
+	if err := tpl.Execute(w, data); err != nil {
+		return fmt.Errorf("html render: execute template: %w", err)
+	}
+	return nil
+}
+
+type diffTemplateData struct {
+	Title         string
+	IncludeStyles bool
+	HighContrast  bool
+	Execution     string
+	Planning      string
+	Insights      []string
+	Root          *diffNodeView
+	// Removed lists operators that only the base plan ran, which the target tree cannot show.
+	Removed []listView
+}
+
+type diffNodeView struct {
+	Label  string
+	Anchor string
+	// Kind is regression, improvement, new or unchanged; it picks the node colour.
+	Kind string
+	// KindLabel names the kind in words so the colour is never the only cue.
+	KindLabel string
+	Heat      float64
+	// HeatLabel spells out the change the tint shows, for screen readers and tooltips.
+	HeatLabel string
+	Times     string
+	Delta     string
+	Rows      string
+	Children  []*diffNodeView
+}
+
+func buildDiffNodeView(report *diff.Report, d *diff.NodeDelta, num numfmt.Format) *diffNodeView {
+	view := &diffNodeView{
+		Label:     insight.NodeLabel(d.Node),
+		Anchor:    insight.AnchorID(d.Node),
+		Kind:      "unchanged",
+		KindLabel: "unchanged",
+		// A change of 40% of the runtime is shown at full intensity, as hot nodes are in the report.
+		Heat:  clamp(report.Severity(d)*2.5, 0, 1),
+		Times: num.Duration(d.BaseSelfMs, 2) + " → " + num.Duration(d.Node.ExclusiveTimeMs, 2),
+		Rows:  formatRows(d.Node, num),
+	}
+	switch {
+	case d.New:
+		view.Kind, view.KindLabel = "new", "new"
+		view.Times = num.Duration(d.Node.ExclusiveTimeMs, 2)
+	case d.Significant && d.DeltaSelfMs > 0:
+		view.Kind, view.KindLabel = "regression", "slower"
+	case d.Significant && d.DeltaSelfMs < 0:
+		view.Kind, view.KindLabel = "improvement", "faster"
+	}
+	if !d.New {
+		sign := ""
+		if d.DeltaSelfMs >= 0 {
+			sign = "+"
+		}
+		view.Delta = fmt.Sprintf("%s%s (%+.1f%%)", sign, num.Duration(d.DeltaSelfMs, 2), d.PercentChange())
+	}
+	view.HeatLabel = fmt.Sprintf("%s: self time %s, a change of %.1f%% of the runtime",
+		view.KindLabel, view.Times, report.Severity(d)*100)
+	for _, child := range d.Children {
+		view.Children = append(view.Children, buildDiffNodeView(report, child, num))
+	}
+	return view
+}
+
+const diffTemplate = `<!DOCTYPE html>
+<html lang="en">
+<head>
+	<meta charset="utf-8">
+	<title>{{.Title}}</title>
+	{{- if .IncludeStyles }}
+	<style>
+		body { font-family: -apple-system, BlinkMacSystemFont, "Segoe UI", Helvetica, Arial, sans-serif; margin: 0; padding: 0; background: #f7f7f8; color: #202124; }
+		main { max-width: 960px; margin: 0 auto; padding: 32px 24px 48px; }
+		header { background: #212a3b; color: #f7f7f8; padding: 32px 24px; }
+		header h1 { margin: 0 0 8px; font-size: 28px; }
+		header p { margin: 4px 0; opacity: 0.8; }
+		section { margin-top: 32px; }
+		section h2 { margin-bottom: 12px; font-size: 20px; }
+		.legend { display: flex; flex-wrap: wrap; gap: 8px 18px; font-size: 13px; color: #364a63; margin-bottom: 16px; }
+		.legend span { display: inline-flex; align-items: center; gap: 6px; }
+		.legend i { display: inline-block; width: 14px; height: 14px; border-radius: 4px; }
+		.legend .scale { background: linear-gradient(90deg, rgba(244,71,71,0.1) 0%, rgba(244,71,71,1) 100%); width: 48px; }
+		.kind-regression { --delta: 244,71,71; }
+		.kind-improvement { --delta: 59,165,93; }
+		.kind-new { --delta: 124,92,255; }
+		.kind-unchanged { --delta: 33,42,59; }
+		.legend i.kind-regression, .legend i.kind-improvement, .legend i.kind-new, .legend i.kind-unchanged { background: rgb(var(--delta)); }
+		.legend i.kind-unchanged { opacity: 0.2; }
+		.plan-tree, .node-children, .flat-list { list-style: none; margin: 0; padding: 0; }
+		.node-card { background: #fff; border-radius: 12px; margin-bottom: 12px; position: relative; padding: 14px 18px; box-shadow: 0 8px 20px rgba(16,37,58,0.12); border-left: 6px solid rgba(var(--delta), 0.85); }
+		.node-card.kind-unchanged { border-left-color: rgba(33,42,59,0.1); }
+		.node-card::after { content: ""; position: absolute; inset: 0; border-radius: inherit; background: linear-gradient(90deg, rgba(var(--delta),var(--heat)) 0%, rgba(var(--delta),0) 72%); opacity: 0.45; pointer-events: none; }
+		.node-header { position: relative; z-index: 1; display: flex; justify-content: space-between; gap: 12px; align-items: baseline; }
+		.node-label { font-weight: 600; font-size: 15px; }
+		.node-metrics { font-size: 13px; color: #5b7083; }
+		.node-meta { position: relative; z-index: 1; margin-top: 8px; font-size: 13px; color: #364a63; display: flex; flex-wrap: wrap; gap: 12px 18px; }
+		.node-children { margin-left: 24px; border-left: 1px dashed rgba(33,42,59,0.15); padding-left: 20px; }
+		.kind-label { font-size: 12px; text-transform: uppercase; letter-spacing: 0.04em; margin-right: 6px; }
+		body.high-contrast { background: #fff; color: #000; }
+		body.high-contrast header { background: #000; }
+		body.high-contrast .kind-regression { --delta: 213,94,0; }
+		body.high-contrast .kind-improvement { --delta: 0,158,115; }
+		body.high-contrast .kind-new { --delta: 0,114,178; }
+		body.high-contrast .node-card, body.high-contrast .flat-list li { box-shadow: none; border: 2px solid #000; }
+		body.high-contrast .node-card::after { display: none; }
+		body.high-contrast .node-card { border-left: calc(4px + var(--heat) * 12px) solid rgb(var(--delta)); }
+		body.high-contrast .node-card.kind-improvement { border-left-style: dashed; }
+		body.high-contrast .node-card.kind-new { border-left-style: dotted; }
+		body.high-contrast .node-card.kind-unchanged { border-left: 2px solid #000; }
+		body.high-contrast .node-metrics, body.high-contrast .node-meta, body.high-contrast .legend { color: #000; }
+		.flat-list li { background: #fff; border-radius: 12px; padding: 12px 16px; margin-bottom: 8px; box-shadow: 0 4px 12px rgba(13,28,39,0.10); font-size: 14px; display: flex; justify-content: space-between; gap: 12px; }
+	</style>
+	{{- end }}
+</head>
+<body{{if .HighContrast}} class="high-contrast"{{end}}>
+	<header>
+		<h1>{{.Title}}</h1>
+		<p>Execution {{.Execution}}</p>
+		<p>Planning {{.Planning}}</p>
+	</header>
+	<main>
+		{{- if .Insights }}
+		<section>
+			<h2>Insights</h2>
+			<ul class="flat-list">
+				{{- range .Insights }}<li>{{.}}</li>{{- end }}
+			</ul>
+		</section>
+		{{- end }}
+		<section>
+			<h2>Target plan by change</h2>
+			<div class="legend">
+				<span><i class="kind-regression"></i>slower than base</span>
+				<span><i class="kind-improvement"></i>faster than base</span>
+				<span><i class="kind-new"></i>not in base</span>
+				<span><i class="kind-unchanged"></i>below the diff thresholds</span>
+				<span><i class="scale"></i>intensity: change as a share of the runtime</span>
+			</div>
+			<ul class="plan-tree">
+				{{ template "node" .Root }}
+			</ul>
+		</section>
+		{{- if .Removed }}
+		<section>
+			<h2>Only in base</h2>
+			<ul class="flat-list">
+				{{- range .Removed }}<li><span>{{.Label}}</span><span>{{.Self}}</span></li>{{- end }}
+			</ul>
+		</section>
+		{{- end }}
+	</main>
+
+	{{ define "node" }}
+	<li>
+		<div class="node-card kind-{{.Kind}}" id="{{.Anchor}}" style="--heat: {{printf "%.3f" .Heat}};" title="{{.HeatLabel}}">
+			<div class="node-header">
+				<span class="node-label">{{.Label}}</span>
+				<span class="node-metrics"><span class="kind-label">{{.KindLabel}}</span>{{.Times}}{{if .Delta}} · {{.Delta}}{{end}}</span>
+			</div>
+			{{- if .Rows }}
+			<div class="node-meta"><span>{{.Rows}}</span></div>
+			{{- end }}
+		</div>
+		{{- if .Children }}
+		<ul class="node-children">
+			{{- range .Children }}
+				{{ template "node" . }}
+			{{- end }}
+		</ul>
+		{{- end }}
+	</li>
+	{{ end }}
+</body>
+</html>
+`
@@ -5,16 +5,26 @@ import (
 	"html/template"
 	"io"
 	"math"
+	"sort"
 	"strings"
 
 	"github.com/mickamy/xplain/internal/analyzer"
 	"github.com/mickamy/xplain/internal/insight"
+	"github.com/mickamy/xplain/internal/numfmt"
 )
 
 // Options configures the HTML renderer.
 type Options struct {
 	Title         string
 	IncludeStyles bool
+	// HighContrast swaps the tinted heat gradients for solid borders and a colour-blind safe palette.
+	HighContrast bool
+	// Numbers formats times, row counts and sizes; the zero value prints them as EXPLAIN does.
+	Numbers numfmt.Format
+	// MinSeverity hides insights less urgent than it; empty shows them all.
+	MinSeverity insight.Severity
+	// PercentBase picks what node shares are a percentage of; empty means the root's inclusive time.
+	PercentBase analyzer.PercentBase
 }
 
 // Render writes an HTML report containing a plan summary and annotated tree.
@@ -25,6 +35,11 @@ func Render(w io.Writer, analysis *analyzer.PlanAnalysis, opts Options) error {
 	if opts.Title == "" {
 		opts.Title = "xplain report"
 	}
+	title, err := ExpandTitle(opts.Title, analysis)
+	if err != nil {
+		return fmt.Errorf("html render: %w", err)
+	}
+	opts.Title = title
 	data := buildTemplateData(analysis, opts)
 	tpl, err := template.New("report").Funcs(template.FuncMap{"join": strings.Join}).Parse(reportTemplate)
 	if err != nil {
@@ -39,20 +54,39 @@ func Render(w io.Writer, analysis *analyzer.PlanAnalysis, opts Options) error {
 type templateData struct {
 	Title         string
 	IncludeStyles bool
+	HighContrast  bool
 	Summary       summaryView
 	Root          *nodeView
 	HotNodes      []listView
-	Divergent     []listView
-	Insights      []insightView
+	// HotRule describes what the hot nodes are ranked by.
+	HotRule   string
+	Divergent []listView
+	// BufferHeavy lists the nodes that accessed the most blocks themselves.
+	BufferHeavy []listView
+	Insights    []insightView
+	// Triggers lists trigger times when they are a significant share of execution.
+	Triggers []listView
+	// Verbose is set when the plan carries EXPLAIN VERBOSE schemas or output lists.
+	Verbose bool
 }
 
 type summaryView struct {
-	ExecutionTime string
-	PlanningTime  string
-	NodeCount     int
-	HotCount      int
-	Divergent     int
-	Buffers       string
+	Source          string
+	ExecutionTime   string
+	PlanningTime    string
+	PlanningBuffers string
+	NodeCount       int
+	HotCount        int
+	Divergent       int
+	Buffers         string
+	Memory          string
+	IOTime          string
+	IOWait          string
+	CacheHit        string
+	CostModel       string
+	JIT             string
+	Serialization   string
+	TimeBudget      string
 }
 
 type listView struct {
@@ -67,42 +101,71 @@ type insightView struct {
 	Severity string
 	Text     string
 	Anchor   string
+	// Acknowledged carries the ignore file justification; the view then shows only the finding.
+	Acknowledged string
 }
 
 type nodeView struct {
-	Label      string
-	Anchor     string
-	Self       string
-	Share      string
-	BarWidth   float64
-	Heat       float64
-	Rows       string
-	Buffers    string
+	Label    string
+	Anchor   string
+	Self     string
+	Share    string
+	BarWidth float64
+	Heat     float64
+	Rows     string
+	Buffers  string
+	// Cost compares the node's planner cost with its time: see insight.FormatCost.
+	Cost string
+	// Sort says how a sort ran: see insight.FormatSort.
+	Sort string
+	// Memoize says how often a Memoize cache hit: see insight.FormatMemoize.
+	Memoize    string
+	Relation   string
+	Output     []string
+	Workers    []string
 	Warnings   []string
 	Children   []*nodeView
 	HasWarning bool
+	// HeatLabel spells out what the bar and heat tint show, for screen readers and tooltips.
+	HeatLabel string
 }
 
 func buildTemplateData(analysis *analyzer.PlanAnalysis, opts Options) templateData {
-	root := buildNodeView(analysis.Root)
-	messages := insight.BuildMessages(analysis)
+	num := opts.Numbers
+	root := buildNodeView(analysis.Root, num, opts.PercentBase)
+	messages := insight.FilterSeverity(insight.BuildMessages(analysis), opts.MinSeverity)
 	insights := make([]insightView, 0, len(messages))
 	for _, msg := range messages {
-		insights = append(insights, insightView{
+		view := insightView{
 			Icon:     severityIcon(msg.Severity),
 			Severity: string(msg.Severity),
 			Text:     msg.Text,
 			Anchor:   msg.Anchor,
-		})
+		}
+		if msg.Acknowledged != "" {
+			view.Icon = "✓"
+			view.Severity = "acknowledged"
+			view.Text = fmt.Sprintf("[%s] %s", msg.Rule, msg.Finding())
+			view.Acknowledged = msg.Acknowledged
+		}
+		insights = append(insights, view)
 	}
+	// Acknowledged insights go last so the open ones lead the list.
+	sort.SliceStable(insights, func(i, j int) bool {
+		return insights[i].Acknowledged == "" && insights[j].Acknowledged != ""
+	})
 
 	hot := make([]listView, 0, len(analysis.HotNodes))
 	for _, node := range analysis.HotNodes {
+		share := node.Percent(opts.PercentBase).Exclusive
+		if analysis.HotSelection.WithDefaults().By != analyzer.HotBySelf {
+			share = analysis.HotSelection.Share(node)
+		}
 		hot = append(hot, listView{
 			Label: insight.NodeLabel(node),
-			Self:  fmt.Sprintf("%.2f ms", node.ExclusiveTimeMs),
-			Share: fmt.Sprintf("%.1f%%", node.PercentExclusive*100),
-			Extra: formatRows(node),
+			Self:  num.Duration(node.ExclusiveTimeMs, 2),
+			Share: fmt.Sprintf("%.1f%%", share*100),
+			Extra: formatRows(node, num),
 		})
 	}
 
@@ -110,75 +173,183 @@ func buildTemplateData(analysis *analyzer.PlanAnalysis, opts Options) templateDa
 	for _, node := range analysis.DivergentNodes {
 		divergent = append(divergent, listView{
 			Label: insight.NodeLabel(node),
-			Self:  fmt.Sprintf("%.2f ms", node.ExclusiveTimeMs),
-			Share: fmt.Sprintf("x%.2f", node.RowEstimateFactor),
-			Extra: formatRows(node),
+			Self:  num.Duration(node.ExclusiveTimeMs, 2),
+			Share: "x" + num.Number(node.RowEstimateFactor, 2),
+			Extra: formatRows(node, num),
+		})
+	}
+
+	bufferHeavy := make([]listView, 0, len(analysis.BufferHeavy))
+	for _, node := range analysis.BufferHeavy {
+		bufferHeavy = append(bufferHeavy, listView{
+			Label: insight.NodeLabel(node),
+			Self:  num.Blocks(node.SelfBuffers),
+			Share: fmt.Sprintf("%.1f%%", node.PercentBuffers*100),
+			Extra: insight.FormatBufferDetail(node),
+		})
+	}
+
+	var triggers []listView
+	for _, t := range insight.SignificantTriggers(analysis) {
+		extra := num.Count(t.Trigger.Calls) + " calls"
+		if t.Trigger.Relation != "" {
+			extra += " on " + t.Trigger.Relation
+		}
+		triggers = append(triggers, listView{
+			Label: insight.TriggerLabel(t.Trigger),
+			Self:  num.Duration(t.Trigger.TimeMs, 2),
+			Share: fmt.Sprintf("%.1f%%", t.Share*100),
+			Extra: extra,
 		})
 	}
 
 	return templateData{
 		Title:         opts.Title,
 		IncludeStyles: opts.IncludeStyles,
+		HighContrast:  opts.HighContrast,
 		Summary: summaryView{
-			ExecutionTime: fmt.Sprintf("%.3f ms", analysis.TotalTimeMs),
-			PlanningTime:  fmt.Sprintf("%.3f ms", analysis.PlanningTimeMs),
-			NodeCount:     analysis.NodeCount,
-			HotCount:      len(analysis.HotNodes),
-			Divergent:     len(analysis.DivergentNodes),
-			Buffers:       insight.SummarizeTotalBuffers(analysis.TotalBuffers),
+			Source:          analysis.Metadata.Summary(),
+			ExecutionTime:   num.Duration(analysis.TotalTimeMs, 3),
+			PlanningTime:    num.Duration(analysis.PlanningTimeMs, 3),
+			PlanningBuffers: summarizePlanningBuffers(analysis.PlanningBuffers, num),
+			NodeCount:       analysis.NodeCount,
+			HotCount:        len(analysis.HotNodes),
+			Divergent:       len(analysis.DivergentNodes),
+			Buffers:         summarizeTotalBuffers(analysis.TotalBuffers, num),
+			Memory:          insight.SummarizeMemory(analysis),
+			IOTime:          insight.SummarizeIOTime(analysis.Root.Buffers),
+			IOWait:          insight.SummarizeIOWait(analysis),
+			CacheHit:        insight.SummarizeCacheHit(analysis),
+			CostModel:       insight.SummarizeCostModel(analysis),
+			JIT:             insight.SummarizeJIT(analysis),
+			Serialization:   insight.SummarizeSerialization(analysis),
+			TimeBudget:      insight.SummarizeTimeBudget(analysis),
 		},
-		Root:      root,
-		HotNodes:  hot,
-		Divergent: divergent,
-		Insights:  insights,
+		Root:        root,
+		HotNodes:    hot,
+		HotRule:     "Highest " + analysis.HotSelection.By.Measure() + " share",
+		Divergent:   divergent,
+		BufferHeavy: bufferHeavy,
+		Insights:    insights,
+		Triggers:    triggers,
+		Verbose:     hasVerbose(root),
+	}
+}
+
+func hasVerbose(view *nodeView) bool {
+	if view.Relation != "" || len(view.Output) > 0 {
+		return true
 	}
+	for _, child := range view.Children {
+		if hasVerbose(child) {
+			return true
+		}
+	}
+	return false
 }
 
-func buildNodeView(node *analyzer.NodeStats) *nodeView {
+func buildNodeView(node *analyzer.NodeStats, num numfmt.Format, base analyzer.PercentBase) *nodeView {
+	share := node.Percent(base).Exclusive
+	of := "the execution time"
+	if base == analyzer.PercentExecution {
+		of = "the reported Execution Time"
+	}
 	view := &nodeView{
 		Label:    insight.NodeLabel(node),
 		Anchor:   insight.AnchorID(node),
-		Self:     fmt.Sprintf("%.2f ms (workers)", node.ExclusiveTimeMs),
-		Share:    fmt.Sprintf("%.1f%%", node.PercentExclusive*100),
-		BarWidth: math.Min(100, math.Max(0, node.PercentExclusive*100)),
-		Heat:     clamp(node.PercentExclusive*2.5, 0, 1),
-		Rows:     formatRows(node),
-		Buffers:  formatBuffers(node),
-		Warnings: append([]string(nil), node.Warnings...),
+		Self:     num.Duration(node.ExclusiveTimeMs, 2) + " (workers)",
+		Share:    fmt.Sprintf("%.1f%%", share*100),
+		BarWidth: math.Min(100, math.Max(0, share*100)),
+		Heat:     clamp(share*2.5, 0, 1),
+		Rows:     formatRows(node, num),
+		Buffers:  formatBuffers(node, num),
+		Cost:     insight.FormatCost(node),
+		Sort:     insight.FormatSort(node),
+		Memoize:  insight.FormatMemoize(node),
+		Output:   node.Node.Output,
+		Warnings: node.WarningTexts(),
+		HeatLabel: fmt.Sprintf("%.1f%% of %s is spent in this node itself (%s)",
+			share*100, of, num.Duration(node.ExclusiveTimeMs, 2)),
+	}
+	for _, ws := range node.Workers {
+		if details := insight.WorkerDetails(ws); len(details) > 0 {
+			view.Workers = append(view.Workers, fmt.Sprintf("worker %d: %s", ws.Worker.Number, strings.Join(details, ", ")))
+		}
+	}
+	if node.Node.Schema != "" && node.Node.RelationName != "" {
+		view.Relation = node.Node.Schema + "." + node.Node.RelationName
 	}
 	if len(view.Warnings) > 0 {
 		view.HasWarning = true
 	}
 	for _, child := range node.Children {
-		view.Children = append(view.Children, buildNodeView(child))
+		view.Children = append(view.Children, buildNodeView(child, num, base))
 	}
 	return view
 }
 
-func formatRows(node *analyzer.NodeStats) string {
+func formatRows(node *analyzer.NodeStats, num numfmt.Format) string {
 	if node.EstimatedRows == 0 && node.ActualTotalRows == 0 {
 		return ""
 	}
+	text := fmt.Sprintf("rows %s / %s (x%s)", num.Count(node.ActualTotalRows), num.Count(node.EstimatedRows),
+		num.Number(node.RowEstimateFactor, 2))
 	if math.IsInf(node.RowEstimateFactor, 1) {
-		return fmt.Sprintf("rows %.0f / %.0f (∞)", node.ActualTotalRows, node.EstimatedRows)
+		text = fmt.Sprintf("rows %s / %s (∞)", num.Count(node.ActualTotalRows), num.Count(node.EstimatedRows))
 	}
-	return fmt.Sprintf("rows %.0f / %.0f (x%.2f)", node.ActualTotalRows, node.EstimatedRows, node.RowEstimateFactor)
+	if node.MsPerRow > 0 {
+		text += ", " + insight.FormatPerRow(node.MsPerRow)
+	}
+	if removed := insight.FormatRemoved(node); removed != "" {
+		text += ", " + removed
+	}
+	if pruning := insight.FormatPruning(node); pruning != "" {
+		text += ", " + pruning
+	}
+	return text
+}
+
+func summarizePlanningBuffers(b analyzer.BufferTotals, num numfmt.Format) string {
+	total := b.Total()
+	if total == 0 {
+		return ""
+	}
+	return fmt.Sprintf("%s (~%s), shared read %s", num.Count(float64(total)), num.Blocks(total), num.Count(float64(b.SharedRead)))
+}
+
+func summarizeTotalBuffers(total int64, num numfmt.Format) string {
+	if total <= 0 {
+		return ""
+	}
+	return fmt.Sprintf("%s blocks (~%s)", num.Count(float64(total)), num.Blocks(total))
 }
 
-func formatBuffers(node *analyzer.NodeStats) string {
+func formatBuffers(node *analyzer.NodeStats, num numfmt.Format) string {
 	total := node.Buffers.Total()
 	if total == 0 {
 		return ""
 	}
-	parts := []string{fmt.Sprintf("total %d (~%s)", total, insight.HumanizeBuffers(total))}
+	parts := []string{fmt.Sprintf("total %s (~%s)", num.Count(float64(total)), num.Blocks(total))}
 	if node.Buffers.SharedRead > 0 {
-		parts = append(parts, fmt.Sprintf("shared read %d", node.Buffers.SharedRead))
+		parts = append(parts, "shared read "+num.Count(float64(node.Buffers.SharedRead)))
 	}
 	if node.Buffers.SharedHit > 0 {
-		parts = append(parts, fmt.Sprintf("shared hit %d", node.Buffers.SharedHit))
+		parts = append(parts, "shared hit "+num.Count(float64(node.Buffers.SharedHit)))
 	}
 	if node.Buffers.TempRead > 0 || node.Buffers.TempWritten > 0 {
-		parts = append(parts, fmt.Sprintf("temp %d/%d", node.Buffers.TempRead, node.Buffers.TempWritten))
+		parts = append(parts, fmt.Sprintf("temp %s/%s", num.Count(float64(node.Buffers.TempRead)), num.Count(float64(node.Buffers.TempWritten))))
+	}
+	if hit := insight.FormatHitRatio(node); hit != "" {
+		parts = append(parts, hit)
+	}
+	if node.TempFiles > 0 {
+		parts = append(parts, fmt.Sprintf("temp files %d (%s)", node.TempFiles, num.Bytes(float64(node.TempFileBytes))))
+	}
+	if io := insight.SummarizeIOTime(node.Buffers); io != "" {
+		if share := insight.FormatIOShare(node); share != "" {
+			io += " (" + share + ")"
+		}
+		parts = append(parts, "I/O "+io)
 	}
 	return "buffers " + strings.Join(parts, ", ")
 }
@@ -241,6 +412,11 @@ const reportTemplate = `<!DOCTYPE html>
 		.node-bar span { display: block; height: 100%; border-radius: inherit; background: linear-gradient(90deg, #f44747 0%, #faae32 100%); width: calc(var(--width) * 1%); }
 		.node-meta { position: relative; z-index: 1; margin-top: 10px; font-size: 13px; color: #364a63; display: flex; flex-wrap: wrap; gap: 12px 18px; }
 		.node-warning { color: #b25600; font-weight: 600; }
+		.node-verbose { position: relative; z-index: 1; margin-top: 8px; font-size: 12px; color: #5b7083; display: flex; flex-direction: column; gap: 4px; }
+		.node-verbose code { color: #253043; word-break: break-word; }
+		.node-workers { position: relative; z-index: 1; margin: 8px 0 0; padding-left: 18px; font-size: 12px; color: #5b7083; }
+		.verbose-toggle { display: inline-block; margin-bottom: 12px; font-size: 13px; color: #5b7083; }
+		#verbose-toggle:not(:checked) ~ .plan-tree .node-verbose { display: none; }
 		.node-children { margin-left: 24px; border-left: 1px dashed rgba(33,42,59,0.15); padding-left: 20px; }
 .node-card.highlight { outline: 3px solid #f44747; box-shadow: 0 0 0 4px rgba(244,71,71,0.25); }
 .node-card.highlight::after { opacity: 0.55; }
@@ -254,6 +430,21 @@ const reportTemplate = `<!DOCTYPE html>
 		.insight-list li.severity-critical { border-left: 4px solid #f44747; }
 		.insight-list li.severity-warning { border-left: 4px solid #faae32; }
 		.insight-list li.severity-info { border-left: 4px solid rgba(33,42,59,0.15); }
+		.insight-list li.severity-acknowledged { border-left: 4px solid #3ba55d; opacity: 0.7; }
+		.insight-list li .acknowledged-reason { color: #5b7083; }
+		body.high-contrast { background: #fff; color: #000; }
+		body.high-contrast header { background: #000; }
+		body.high-contrast .summary-tile, body.high-contrast .list-card, body.high-contrast .node-card, body.high-contrast .insight-list li { box-shadow: none; border: 2px solid #000; }
+		body.high-contrast .summary-tile strong, body.high-contrast .node-metrics, body.high-contrast .node-meta, body.high-contrast .list-card header span { color: #000; }
+		body.high-contrast .node-card::after { display: none; }
+		body.high-contrast .node-card { border-left: calc(2px + var(--heat) * 14px) solid #0072b2; }
+		body.high-contrast .node-bar { background: #fff; border: 1px solid #000; }
+		body.high-contrast .node-bar span { background: repeating-linear-gradient(45deg, #000 0 4px, #0072b2 4px 8px); }
+		body.high-contrast .node-warning { color: #000; text-decoration: underline; }
+		body.high-contrast .insight-list li.severity-critical { border-left: 8px solid #d55e00; }
+		body.high-contrast .insight-list li.severity-warning { border-left: 8px dashed #e69f00; }
+		body.high-contrast .insight-list li.severity-info { border-left: 8px dotted #000; }
+		body.high-contrast .insight-list li.severity-acknowledged { border-left: 8px double #009e73; opacity: 1; }
 		@media (max-width: 640px) {
 			main { padding: 24px 16px 32px; }
 			.list-card li { grid-template-columns: 1fr auto; grid-template-areas: "label share" "extra extra"; }
@@ -263,7 +454,7 @@ const reportTemplate = `<!DOCTYPE html>
 	</style>
 	{{- end }}
 </head>
-<body>
+<body{{if .HighContrast}} class="high-contrast"{{end}}>
 	<script>
 	(function() {
 		function clearHighlight() {
@@ -310,8 +501,11 @@ const reportTemplate = `<!DOCTYPE html>
 	</script>
 	<header>
 		<h1>{{.Title}}</h1>
+		{{- if .Summary.Source }}
+		<p class="source">{{.Summary.Source}}</p>
+		{{- end }}
 		<p>Execution {{.Summary.ExecutionTime}} · Planning {{.Summary.PlanningTime}}</p>
-		<p>Nodes {{.Summary.NodeCount}} · Hot {{.Summary.HotCount}} · Divergent {{.Summary.Divergent}}{{if .Summary.Buffers}} · Buffers {{.Summary.Buffers}}{{end}}</p>
+		<p>Nodes {{.Summary.NodeCount}} · Hot {{.Summary.HotCount}} · Divergent {{.Summary.Divergent}}{{if .Summary.Buffers}} · Buffers {{.Summary.Buffers}}{{end}}{{if .Summary.Memory}} · Memory {{.Summary.Memory}}{{end}}</p>
 	</header>
 	<main>
 		<section>
@@ -325,6 +519,12 @@ const reportTemplate = `<!DOCTYPE html>
 					<strong>Planning time</strong>
 					<span>{{.Summary.PlanningTime}}</span>
 				</div>
+				{{- if .Summary.PlanningBuffers }}
+				<div class="summary-tile">
+					<strong>Planning buffers</strong>
+					<span>{{.Summary.PlanningBuffers}}</span>
+				</div>
+				{{- end }}
 				<div class="summary-tile">
 					<strong>Plan nodes</strong>
 					<span>{{.Summary.NodeCount}}</span>
@@ -339,6 +539,54 @@ const reportTemplate = `<!DOCTYPE html>
 					<span>{{.Summary.Buffers}}</span>
 				</div>
 				{{- end }}
+				{{- if .Summary.CacheHit }}
+				<div class="summary-tile">
+					<strong>Cache hit ratio</strong>
+					<span>{{.Summary.CacheHit}}</span>
+				</div>
+				{{- end }}
+				{{- if .Summary.IOTime }}
+				<div class="summary-tile">
+					<strong>I/O time</strong>
+					<span>{{.Summary.IOTime}}</span>
+				</div>
+				{{- end }}
+				{{- if .Summary.IOWait }}
+				<div class="summary-tile">
+					<strong>I/O wait</strong>
+					<span>{{.Summary.IOWait}}</span>
+				</div>
+				{{- end }}
+				{{- if .Summary.Memory }}
+				<div class="summary-tile">
+					<strong>Peak memory</strong>
+					<span>{{.Summary.Memory}}</span>
+				</div>
+				{{- end }}
+				{{- if .Summary.CostModel }}
+				<div class="summary-tile">
+					<strong>Cost model</strong>
+					<span>{{.Summary.CostModel}}</span>
+				</div>
+				{{- end }}
+				{{- if .Summary.JIT }}
+				<div class="summary-tile">
+					<strong>JIT</strong>
+					<span>{{.Summary.JIT}}</span>
+				</div>
+				{{- end }}
+				{{- if .Summary.Serialization }}
+				<div class="summary-tile">
+					<strong>Serialization</strong>
+					<span>{{.Summary.Serialization}}</span>
+				</div>
+				{{- end }}
+				{{- if .Summary.TimeBudget }}
+				<div class="summary-tile">
+					<strong>Client round trip</strong>
+					<span>{{.Summary.TimeBudget}}</span>
+				</div>
+				{{- end }}
 			</div>
 		</section>
 
@@ -353,6 +601,7 @@ const reportTemplate = `<!DOCTYPE html>
 					{{- else -}}
 						{{.Text}}
 					{{- end -}}
+					{{- if .Acknowledged }} <em class="acknowledged-reason">acknowledged: {{.Acknowledged}}</em>{{- end -}}
 				</span></li>
 				{{- end }}
 			</ul>
@@ -365,7 +614,7 @@ const reportTemplate = `<!DOCTYPE html>
 				<div class="list-card">
 					<header>
 						<h3>Hot nodes</h3>
-						<span>Highest self time share</span>
+						<span>{{.HotRule}}</span>
 					</header>
 					<ul>
 						{{- if .HotNodes }}
@@ -385,7 +634,7 @@ const reportTemplate = `<!DOCTYPE html>
 				<div class="list-card">
 					<header>
 						<h3>Estimate drift</h3>
-						<span>Actual vs expected rows</span>
+						<span>Actual vs expected rows, by error × self time</span>
 					</header>
 					<ul>
 						{{- if .Divergent }}
@@ -402,11 +651,53 @@ const reportTemplate = `<!DOCTYPE html>
 						{{- end }}
 					</ul>
 				</div>
+				<div class="list-card">
+					<header>
+						<h3>I/O heavy nodes</h3>
+						<span>Most blocks accessed, net of children</span>
+					</header>
+					<ul>
+						{{- if .BufferHeavy }}
+							{{- range .BufferHeavy }}
+							<li>
+								<span>{{.Label}}</span>
+								<span>{{.Self}}</span>
+								<span>{{.Share}}</span>
+								<span>{{.Extra}}</span>
+							</li>
+							{{- end }}
+						{{- else }}
+							<li><span>No buffer usage recorded</span></li>
+						{{- end }}
+					</ul>
+				</div>
+				{{- if .Triggers }}
+				<div class="list-card">
+					<header>
+						<h3>Triggers</h3>
+						<span>Share of execution time</span>
+					</header>
+					<ul>
+						{{- range .Triggers }}
+						<li>
+							<span>{{.Label}}</span>
+							<span>{{.Self}}</span>
+							<span>{{.Share}}</span>
+							<span>{{.Extra}}</span>
+						</li>
+						{{- end }}
+					</ul>
+				</div>
+				{{- end }}
 			</div>
 		</section>
 
 		<section>
 			<h2>Plan Tree</h2>
+			{{- if .Verbose }}
+			<input type="checkbox" id="verbose-toggle" checked>
+			<label class="verbose-toggle" for="verbose-toggle">Show schemas and output columns</label>
+			{{- end }}
 			<ul class="plan-tree">
 				{{ template "node" .Root }}
 			</ul>
@@ -415,17 +706,31 @@ const reportTemplate = `<!DOCTYPE html>
 
 	{{ define "node" }}
 	<li>
-		<div class="node-card" id="{{.Anchor}}" style="--heat: {{printf "%.3f" .Heat}};">
+		<div class="node-card" id="{{.Anchor}}" style="--heat: {{printf "%.3f" .Heat}};" title="{{.HeatLabel}}">
 		<div class="node-header">
 			<span class="node-label">{{.Label}}</span>
 			<span class="node-metrics">{{.Self}} · {{.Share}}</span>
 		</div>
-			<div class="node-bar"><span style="--width: {{printf "%.2f" .BarWidth}};"></span></div>
+			<div class="node-bar" role="img" aria-label="{{.HeatLabel}}"><span style="--width: {{printf "%.2f" .BarWidth}};"></span></div>
 			<div class="node-meta">
 				{{- if .Rows }}<span>{{.Rows}}</span>{{- end }}
 				{{- if .Buffers }}<span>{{.Buffers}}</span>{{- end }}
+				{{- if .Cost }}<span>{{.Cost}}</span>{{- end }}
+				{{- if .Sort }}<span>{{.Sort}}</span>{{- end }}
+				{{- if .Memoize }}<span>{{.Memoize}}</span>{{- end }}
 				{{- if .HasWarning }}<span class="node-warning">{{ join .Warnings "; " }}</span>{{- end }}
 			</div>
+			{{- if .Workers }}
+			<ul class="node-workers">
+				{{- range .Workers }}<li>{{.}}</li>{{- end }}
+			</ul>
+			{{- end }}
+			{{- if or .Relation .Output }}
+			<div class="node-verbose">
+				{{- if .Relation }}<span>relation <code>{{.Relation}}</code></span>{{- end }}
+				{{- if .Output }}<span>output <code>{{ join .Output ", " }}</code></span>{{- end }}
+			</div>
+			{{- end }}
 		</div>
 		{{- if .Children }}
 		<ul class="node-children">
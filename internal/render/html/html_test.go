@@ -2,8 +2,11 @@ package html_test
 
 import (
 	"bytes"
+	"strings"
 	"testing"
 
+	"github.com/mickamy/xplain/internal/diff"
+	"github.com/mickamy/xplain/internal/fingerprint"
 	"github.com/mickamy/xplain/internal/render/html"
 	"github.com/mickamy/xplain/test"
 )
@@ -21,4 +24,93 @@ func TestRenderSampleHTML(t *testing.T) {
 	if !bytes.Contains(buf.Bytes(), []byte("Insights")) {
 		t.Fatalf("expected insights section in html output")
 	}
+	if !bytes.Contains(buf.Bytes(), []byte("<h3>I/O heavy nodes</h3>")) {
+		t.Fatalf("expected the I/O heavy nodes card in html output")
+	}
+}
+
+func TestRenderDiffColoursByChange(t *testing.T) {
+	base := test.LoadSampleAnalysis(t, "nloop_base.json")
+	target := test.LoadSampleAnalysis(t, "nloop_index.json")
+	report, err := diff.Compare(base, target, diff.Options{})
+	if err != nil {
+		t.Fatalf("compare: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := html.RenderDiff(&buf, report, html.Options{IncludeStyles: true}); err != nil {
+		t.Fatalf("render diff: %v", err)
+	}
+	out := buf.String()
+	for _, want := range []string{
+		`<i class="kind-regression"></i>slower than base`,
+		`class="node-card kind-improvement" id="node-0-1-0-0-0-0-0"`,
+		`class="node-card kind-unchanged" id="node-0" style="--heat: 0.000;"`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("expected %q in diff html", want)
+		}
+	}
+}
+
+func TestExpandTitle(t *testing.T) {
+	analysis := test.LoadSampleAnalysis(t, "envelope_orders.json")
+
+	got, err := html.ExpandTitle("{{.Database}} {{.Date}} {{.Fingerprint}}", analysis)
+	if err != nil {
+		t.Fatalf("expand title: %v", err)
+	}
+	want := "shop 2026-10-14 " + fingerprint.Of(analysis)
+	if got != want {
+		t.Fatalf("expected %q, got %q", want, got)
+	}
+	if _, err := html.ExpandTitle("{{.Missing}}", analysis); err == nil {
+		t.Fatalf("expected unknown variables to fail")
+	}
+}
+
+func TestRenderVerboseDetails(t *testing.T) {
+	analysis := test.LoadSampleAnalysis(t, "multi_schema.json")
+
+	var buf bytes.Buffer
+	if err := html.Render(&buf, analysis, html.Options{Title: "test"}); err != nil {
+		t.Fatalf("render html: %v", err)
+	}
+	for _, want := range []string{`id="verbose-toggle"`, "<code>billing.accounts</code>", "<code>crm.accounts</code>", "<code>b.id, b.balance</code>"} {
+		if !bytes.Contains(buf.Bytes(), []byte(want)) {
+			t.Fatalf("expected %q in html output", want)
+		}
+	}
+
+	buf.Reset()
+	if err := html.Render(&buf, test.LoadSampleAnalysis(t, "pgbench_hot.json"), html.Options{Title: "test"}); err != nil {
+		t.Fatalf("render html: %v", err)
+	}
+	if bytes.Contains(buf.Bytes(), []byte(`id="verbose-toggle"`)) {
+		t.Fatalf("expected no verbose toggle without VERBOSE data")
+	}
+}
+
+func TestRenderHeatLabels(t *testing.T) {
+	analysis := test.LoadSampleAnalysis(t, "pgbench_hot.json")
+
+	var buf bytes.Buffer
+	if err := html.Render(&buf, analysis, html.Options{Title: "test", IncludeStyles: true}); err != nil {
+		t.Fatalf("render html: %v", err)
+	}
+	out := buf.String()
+	if !strings.Contains(out, `role="img" aria-label="`) || !strings.Contains(out, "of the execution time is spent in this node itself") {
+		t.Fatalf("expected heat bars to carry a textual label")
+	}
+	if strings.Contains(out, `class="high-contrast"`) {
+		t.Fatalf("expected the default palette without --high-contrast")
+	}
+
+	buf.Reset()
+	if err := html.Render(&buf, analysis, html.Options{Title: "test", IncludeStyles: true, HighContrast: true}); err != nil {
+		t.Fatalf("render html: %v", err)
+	}
+	if !strings.Contains(buf.String(), `<body class="high-contrast">`) {
+		t.Fatalf("expected the high-contrast body class")
+	}
 }
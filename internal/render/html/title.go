@@ -0,0 +1,54 @@
+package html
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/mickamy/xplain/internal/analyzer"
+	"github.com/mickamy/xplain/internal/fingerprint"
+)
+
+// TitleVars are the variables available to --title templates, e.g. "{{.Fingerprint}} {{.Date}} {{.Database}}".
+type TitleVars struct {
+	Fingerprint   string
+	Date          string
+	Time          string
+	Database      string
+	Host          string
+	User          string
+	ServerVersion string
+}
+
+// ExpandTitle renders a title template against the analysis. Date and Time use the capture
+// timestamp from the run envelope, falling back to the current time.
+func ExpandTitle(pattern string, analysis *analyzer.PlanAnalysis) (string, error) {
+	if !strings.Contains(pattern, "{{") {
+		return pattern, nil
+	}
+	tpl, err := template.New("title").Option("missingkey=error").Parse(pattern)
+	if err != nil {
+		return "", fmt.Errorf("title template: %w", err)
+	}
+
+	captured := time.Now()
+	vars := TitleVars{Fingerprint: fingerprint.Of(analysis)}
+	if meta := analysis.Metadata; meta != nil {
+		if !meta.CapturedAt.IsZero() {
+			captured = meta.CapturedAt
+		}
+		vars.Database = meta.Database
+		vars.Host = meta.Host
+		vars.User = meta.User
+		vars.ServerVersion = meta.ServerVersion
+	}
+	vars.Date = captured.UTC().Format("2006-01-02")
+	vars.Time = captured.UTC().Format("15:04:05")
+
+	var b strings.Builder
+	if err := tpl.Execute(&b, vars); err != nil {
+		return "", fmt.Errorf("title template: %w", err)
+	}
+	return strings.TrimSpace(b.String()), nil
+}
@@ -0,0 +1,21 @@
+package tui
+
+import (
+	"io"
+	"os"
+)
+
+// ColorSupported reports whether ANSI colors written to w will be rendered: w must be an
+// interactive terminal, NO_COLOR must be unset and TERM must not be "dumb". On Windows the
+// console is switched to virtual terminal processing first; consoles that refuse it (older
+// cmd.exe) get plain text instead of raw escape sequences.
+func ColorSupported(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok || f == nil {
+		return false
+	}
+	if _, set := os.LookupEnv("NO_COLOR"); set || os.Getenv("TERM") == "dumb" {
+		return false
+	}
+	return enableVirtualTerminal(f)
+}
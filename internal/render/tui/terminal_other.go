@@ -0,0 +1,11 @@
+//go:build !windows
+
+package tui
+
+import "os"
+
+// enableVirtualTerminal reports whether f is a character device; Unix terminals interpret ANSI natively.
+func enableVirtualTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
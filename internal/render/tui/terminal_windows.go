@@ -0,0 +1,29 @@
+package tui
+
+import (
+	"os"
+	"syscall"
+)
+
+// enableVirtualTerminalProcessing is ENABLE_VIRTUAL_TERMINAL_PROCESSING from wincon.h.
+const enableVirtualTerminalProcessing = 0x0004
+
+var setConsoleMode = syscall.NewLazyDLL("kernel32.dll").NewProc("SetConsoleMode")
+
+// enableVirtualTerminal turns on ANSI escape handling for the console behind f. It fails for
+// redirected output, which has no console mode, and for consoles predating Windows 10.
+func enableVirtualTerminal(f *os.File) bool {
+	handle := syscall.Handle(f.Fd())
+	var mode uint32
+	if err := syscall.GetConsoleMode(handle, &mode); err != nil {
+		return false
+	}
+	if mode&enableVirtualTerminalProcessing != 0 {
+		return true
+	}
+	if err := setConsoleMode.Find(); err != nil {
+		return false
+	}
+	ok, _, _ := setConsoleMode.Call(uintptr(handle), uintptr(mode|enableVirtualTerminalProcessing))
+	return ok != 0
+}
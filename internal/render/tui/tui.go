@@ -6,9 +6,11 @@ import (
 	"io"
 	"math"
 	"strings"
+	"unicode/utf8"
 
 	"github.com/mickamy/xplain/internal/analyzer"
 	"github.com/mickamy/xplain/internal/insight"
+	"github.com/mickamy/xplain/internal/numfmt"
 )
 
 // Options controls how the TUI renderer behaves.
@@ -17,6 +19,23 @@ type Options struct {
 	MaxDepth     int
 	ShowWarnings bool
 	BarWidth     int
+	// Numbers formats times, row counts and sizes. Without units, times switch to seconds and minutes
+	// as they grow.
+	Numbers numfmt.Format
+	// Precision is the number of decimals times are printed with; -1 keeps 3 in the header and 2 per node.
+	Precision int
+	// MinSeverity hides insights less urgent than it; empty shows them all.
+	MinSeverity insight.Severity
+	// PercentBase picks what node shares are a percentage of; empty means the root's inclusive time.
+	PercentBase analyzer.PercentBase
+}
+
+// layout holds the column widths that line the node times up: the widest tree prefix plus label, and
+// the widest self time number and unit.
+type layout struct {
+	label  int
+	number int
+	unit   int
 }
 
 // Render prints an ASCII tree that highlights hot nodes and row estimation issues.
@@ -31,26 +50,98 @@ func Render(w io.Writer, analysis *analyzer.PlanAnalysis, opts Options) error {
 	if opts.BarWidth <= 0 {
 		opts.BarWidth = 20
 	}
+	if opts.Numbers.Units == "" {
+		opts.Numbers.Units = numfmt.UnitsAuto
+	}
 
-	_, _ = fmt.Fprintf(w, "Execution time %.3f ms (planning %.3f ms)\n", analysis.TotalTimeMs, analysis.PlanningTimeMs)
-	_, _ = fmt.Fprintf(w, "Nodes %d | Hot nodes >=10%% runtime %d | Divergent estimates %d\n\n",
-		analysis.NodeCount, len(analysis.HotNodes), len(analysis.DivergentNodes))
+	if source := analysis.Metadata.Summary(); source != "" {
+		_, _ = fmt.Fprintf(w, "Source %s\n", source)
+	}
+	num := opts.Numbers
+	planning := "planning " + num.Duration(analysis.PlanningTimeMs, opts.precision(3))
+	if blocks := analysis.PlanningBuffers.Total(); blocks > 0 {
+		planning += fmt.Sprintf(", buf %s (~%s)", num.Count(float64(blocks)), num.Blocks(blocks))
+	}
+	_, _ = fmt.Fprintf(w, "Execution time %s (%s)\n", num.Duration(analysis.TotalTimeMs, opts.precision(3)), planning)
+	hot := analysis.HotSelection.WithDefaults()
+	ranked := "runtime"
+	if hot.By != analyzer.HotBySelf {
+		ranked = hot.By.Measure()
+	}
+	_, _ = fmt.Fprintf(w, "Nodes %d | Hot nodes >=%.0f%% %s %d | Divergent estimates %d\n",
+		analysis.NodeCount, hot.Threshold()*100, ranked, len(analysis.HotNodes), len(analysis.DivergentNodes))
+	if hit := insight.SummarizeCacheHit(analysis); hit != "" {
+		_, _ = fmt.Fprintf(w, "Cache hit ratio %s\n", hit)
+	}
+	if io := insight.SummarizeIOTime(analysis.Root.Buffers); io != "" {
+		if wait := insight.SummarizeIOWait(analysis); wait != "" {
+			io += " (" + wait + ")"
+		}
+		_, _ = fmt.Fprintf(w, "I/O time %s\n", io)
+	}
+	if memory := insight.SummarizeMemory(analysis); memory != "" {
+		_, _ = fmt.Fprintf(w, "Memory %s\n", memory)
+	}
+	if cost := insight.SummarizeCostModel(analysis); cost != "" {
+		_, _ = fmt.Fprintf(w, "Cost model %s\n", cost)
+	}
+	if jit := insight.SummarizeJIT(analysis); jit != "" {
+		_, _ = fmt.Fprintf(w, "JIT %s\n", jit)
+	}
+	if serialization := insight.SummarizeSerialization(analysis); serialization != "" {
+		_, _ = fmt.Fprintf(w, "Serialization %s\n", serialization)
+	}
+	if budget := insight.SummarizeTimeBudget(analysis); budget != "" {
+		_, _ = fmt.Fprintf(w, "Client %s\n", budget)
+	}
+	_, _ = fmt.Fprintln(w)
 
 	renderInsights(w, analysis, opts)
+	renderTriggers(w, analysis)
+	renderBufferHeavy(w, analysis, num)
 
-	_, _ = fmt.Fprintf(w, "%s\n", renderLine(analysis.Root, opts))
-	printChildren(w, analysis.Root, "", opts)
+	cols := measure(analysis.Root, 0, opts)
+	_, _ = fmt.Fprintf(w, "%s\n", renderLine(analysis.Root, opts, cols, 0))
+	renderWorkers(w, analysis.Root, "")
+	printChildren(w, analysis.Root, "", opts, cols)
 
 	return nil
 }
 
-func printChildren(w io.Writer, parent *analyzer.NodeStats, prefix string, opts Options) {
+func (o Options) precision(fallback int) int {
+	if o.Precision >= 0 {
+		return o.Precision
+	}
+	return fallback
+}
+
+// measure finds the column widths of the lines the tree will print, down to MaxDepth.
+func measure(node *analyzer.NodeStats, indent int, opts Options) layout {
+	number, unit := opts.Numbers.DurationParts(node.ExclusiveTimeMs, opts.precision(2))
+	cols := layout{
+		label:  indent + utf8.RuneCountInString(formatLabel(node)),
+		number: utf8.RuneCountInString(number),
+		unit:   len(unit),
+	}
+	if opts.MaxDepth > 0 && node.Depth >= opts.MaxDepth {
+		return cols
+	}
+	for _, child := range node.Children {
+		c := measure(child, indent+4, opts)
+		cols.label = max(cols.label, c.label)
+		cols.number = max(cols.number, c.number)
+		cols.unit = max(cols.unit, c.unit)
+	}
+	return cols
+}
+
+func printChildren(w io.Writer, parent *analyzer.NodeStats, prefix string, opts Options, cols layout) {
 	for i, child := range parent.Children {
-		renderBranch(w, child, prefix, i == len(parent.Children)-1, opts)
+		renderBranch(w, child, prefix, i == len(parent.Children)-1, opts, cols)
 	}
 }
 
-func renderBranch(w io.Writer, node *analyzer.NodeStats, prefix string, isLast bool, opts Options) {
+func renderBranch(w io.Writer, node *analyzer.NodeStats, prefix string, isLast bool, opts Options, cols layout) {
 	connector := "|-- "
 	childPrefix := prefix + "|   "
 	if isLast {
@@ -58,8 +149,9 @@ func renderBranch(w io.Writer, node *analyzer.NodeStats, prefix string, isLast b
 		childPrefix = prefix + "    "
 	}
 
-	line := renderLine(node, opts)
+	line := renderLine(node, opts, cols, len(prefix)+len(connector))
 	_, _ = fmt.Fprintf(w, "%s%s%s\n", prefix, connector, line)
+	renderWorkers(w, node, childPrefix)
 
 	if opts.MaxDepth > 0 && node.Depth >= opts.MaxDepth {
 		if len(node.Children) > 0 {
@@ -68,17 +160,34 @@ func renderBranch(w io.Writer, node *analyzer.NodeStats, prefix string, isLast b
 		return
 	}
 
-	printChildren(w, node, childPrefix, opts)
+	printChildren(w, node, childPrefix, opts, cols)
 }
 
-func renderLine(node *analyzer.NodeStats, opts Options) string {
+// renderWorkers lists each parallel worker's share of the node beneath its line.
+func renderWorkers(w io.Writer, node *analyzer.NodeStats, prefix string) {
+	for _, ws := range node.Workers {
+		if details := insight.WorkerDetails(ws); len(details) > 0 {
+			_, _ = fmt.Fprintf(w, "%s  ~ worker %d: %s\n", prefix, ws.Worker.Number, strings.Join(details, " | "))
+		}
+	}
+}
+
+// renderLine formats one node. indent is the width of the tree prefix ahead of it; the label is padded so
+// that the self times of all nodes start in one column, with their decimal points aligned.
+func renderLine(node *analyzer.NodeStats, opts Options, cols layout, indent int) string {
 	label := formatLabel(node)
+	if pad := cols.label - indent - utf8.RuneCountInString(label); pad > 0 {
+		label += strings.Repeat(" ", pad)
+	}
 
-	self := fmt.Sprintf("self %.2f ms (workers)", node.ExclusiveTimeMs)
-	share := fmt.Sprintf("%5.1f%%", node.PercentExclusive*100)
+	num := opts.Numbers
+	number, unit := num.DurationParts(node.ExclusiveTimeMs, opts.precision(2))
+	self := fmt.Sprintf("self %*s %-*s (workers)", cols.number, number, cols.unit, unit)
+	percent := node.Percent(opts.PercentBase).Exclusive
+	share := fmt.Sprintf("%5.1f%%", percent*100)
 
-	bar := drawBar(node.PercentExclusive, opts.BarWidth)
-	barColor := pickColor(node.PercentExclusive)
+	bar := drawBar(percent, opts.BarWidth)
+	barColor := pickColor(percent)
 	if !opts.EnableColor {
 		barColor = ""
 	}
@@ -88,37 +197,80 @@ func renderLine(node *analyzer.NodeStats, opts Options) string {
 
 	rowInfo := ""
 	if node.EstimatedRows > 0 || node.ActualTotalRows > 0 {
-		rowInfo = fmt.Sprintf("rows %.0f/%.0f", node.ActualTotalRows, node.EstimatedRows)
+		rowInfo = fmt.Sprintf("rows %s/%s", num.Count(node.ActualTotalRows), num.Count(node.EstimatedRows))
 		if node.RowEstimateFactor > 0 && !math.IsInf(node.RowEstimateFactor, 0) {
-			rowInfo += fmt.Sprintf(" (x%.2f)", node.RowEstimateFactor)
+			rowInfo += fmt.Sprintf(" (x%s)", num.Number(node.RowEstimateFactor, 2))
 		} else if math.IsInf(node.RowEstimateFactor, 1) {
 			rowInfo += " (∞)"
 		}
 	}
 
+	if node.MsPerRow > 0 {
+		rowInfo += ", " + insight.FormatPerRow(node.MsPerRow)
+	}
+	if removed := insight.FormatRemoved(node); removed != "" {
+		rowInfo += ", " + removed
+	}
+	if pruning := insight.FormatPruning(node); pruning != "" {
+		rowInfo += ", " + pruning
+	}
+
+	columnInfo := ""
+	if cols := len(node.Node.Output); cols > 0 {
+		columnInfo = fmt.Sprintf("cols %d (%.0f B)", cols, node.Node.PlanWidth)
+	}
+
 	bufferInfo := ""
 	if node.Buffers.Total() > 0 {
-		bufferInfo = fmt.Sprintf("buf %d (~%s)", node.Buffers.Total(), insight.HumanizeBuffers(node.Buffers.Total()))
+		bufferInfo = fmt.Sprintf("buf %s (~%s)", num.Count(float64(node.Buffers.Total())), num.Blocks(node.Buffers.Total()))
+	}
+
+	tempInfo := ""
+	if node.TempFiles > 0 {
+		tempInfo = fmt.Sprintf("temp files %d (%s)", node.TempFiles, num.Bytes(float64(node.TempFileBytes)))
+	}
+
+	ioInfo := ""
+	if io := insight.SummarizeIOTime(node.Buffers); io != "" {
+		ioInfo = "io " + io
+		if share := insight.FormatIOShare(node); share != "" {
+			ioInfo += " (" + share + ")"
+		}
 	}
 
 	warningText := ""
 	if opts.ShowWarnings && len(node.Warnings) > 0 {
-		warningText = strings.Join(node.Warnings, "; ")
+		warningText = strings.Join(node.WarningTexts(), "; ")
 		if opts.EnableColor {
 			warningText = applyColor(warningText, "yellow")
 		}
 		warningText = " [" + warningText + "]"
 	} else if len(node.Warnings) > 0 {
-		warningText = " [" + strings.Join(node.Warnings, "; ") + "]"
+		warningText = " [" + strings.Join(node.WarningTexts(), "; ") + "]"
 	}
 
 	parts := []string{label, self, share, bar}
 	if rowInfo != "" {
 		parts = append(parts, rowInfo)
 	}
+	if columnInfo != "" {
+		parts = append(parts, columnInfo)
+	}
 	if bufferInfo != "" {
 		parts = append(parts, bufferInfo)
 	}
+	if tempInfo != "" {
+		parts = append(parts, tempInfo)
+	}
+	if sort := insight.FormatSort(node); sort != "" {
+		parts = append(parts, sort)
+	}
+	if memoize := insight.FormatMemoize(node); memoize != "" {
+		parts = append(parts, memoize)
+	}
+	if ioInfo != "" {
+		parts = append(parts, ioInfo)
+	}
 
 	return strings.Join(parts, " | ") + warningText
 }
@@ -135,15 +287,60 @@ func formatLabel(node *analyzer.NodeStats) string {
 }
 
 func renderInsights(w io.Writer, analysis *analyzer.PlanAnalysis, opts Options) {
-	messages := insight.BuildMessages(analysis)
+	messages := insight.FilterSeverity(insight.BuildMessages(analysis), opts.MinSeverity)
 	if len(messages) == 0 {
 		return
 	}
+	var acknowledged []insight.Message
 	_, _ = fmt.Fprintln(w, "Insights:")
 	for _, msg := range messages {
+		if msg.Acknowledged != "" {
+			acknowledged = append(acknowledged, msg)
+			continue
+		}
 		icon := severityIcon(msg.Severity)
 		_, _ = fmt.Fprintf(w, "  - %s %s\n", icon, msg.Text)
 	}
+	if len(acknowledged) > 0 {
+		_, _ = fmt.Fprintln(w, "Acknowledged:")
+		for _, msg := range acknowledged {
+			_, _ = fmt.Fprintf(w, "  - ✓ [%s] %s — %s\n", msg.Rule, msg.Finding(), msg.Acknowledged)
+		}
+	}
+	_, _ = fmt.Fprintln(w)
+}
+
+func renderTriggers(w io.Writer, analysis *analyzer.PlanAnalysis) {
+	triggers := insight.SignificantTriggers(analysis)
+	if len(triggers) == 0 {
+		return
+	}
+	_, _ = fmt.Fprintf(w, "Triggers: %.3f ms\n", analysis.TriggerTimeMs)
+	for _, t := range triggers {
+		line := fmt.Sprintf("  - %s: %.3f ms (%.1f%% of execution), %.0f calls", insight.TriggerLabel(t.Trigger), t.Trigger.TimeMs, t.Share*100, t.Trigger.Calls)
+		if t.Trigger.Relation != "" {
+			line += " on " + t.Trigger.Relation
+		}
+		_, _ = fmt.Fprintln(w, line)
+	}
+	_, _ = fmt.Fprintln(w)
+}
+
+// renderBufferHeavy lists the nodes that accessed the most blocks themselves, which the tree only shows
+// one line at a time.
+func renderBufferHeavy(w io.Writer, analysis *analyzer.PlanAnalysis, num numfmt.Format) {
+	if len(analysis.BufferHeavy) == 0 {
+		return
+	}
+	_, _ = fmt.Fprintln(w, "I/O heavy nodes:")
+	for _, n := range analysis.BufferHeavy {
+		line := fmt.Sprintf("  - %s: %s blocks (~%s, %.1f%% of buffers)", insight.CompactLabel(n),
+			num.Count(float64(n.SelfBuffers)), num.Blocks(n.SelfBuffers), n.PercentBuffers*100)
+		if detail := insight.FormatBufferDetail(n); detail != "" {
+			line += ", " + detail
+		}
+		_, _ = fmt.Fprintln(w, line)
+	}
 	_, _ = fmt.Fprintln(w)
 }
 
@@ -2,8 +2,11 @@ package tui_test
 
 import (
 	"bytes"
+	"os"
+	"strings"
 	"testing"
 
+	"github.com/mickamy/xplain/internal/analyzer"
 	"github.com/mickamy/xplain/internal/render/tui"
 	"github.com/mickamy/xplain/test"
 )
@@ -12,7 +15,7 @@ func TestRenderSampleTUI(t *testing.T) {
 	analysis := test.LoadSampleAnalysis(t, "pgbench_branches.json")
 
 	var buf bytes.Buffer
-	err := tui.Render(&buf, analysis, tui.Options{EnableColor: false, MaxDepth: 2})
+	err := tui.Render(&buf, analysis, tui.Options{EnableColor: false, MaxDepth: 2, Precision: -1})
 	if err != nil {
 		t.Fatalf("render tui: %v", err)
 	}
@@ -23,4 +26,104 @@ func TestRenderSampleTUI(t *testing.T) {
 	if !bytes.Contains(buf.Bytes(), []byte("Execution time")) {
 		t.Fatalf("expected execution header in tui output")
 	}
+	if !bytes.Contains(buf.Bytes(), []byte("I/O heavy nodes:")) {
+		t.Fatalf("expected the I/O heavy nodes in tui output")
+	}
+}
+
+func TestRenderEnvelopeSource(t *testing.T) {
+	analysis := test.LoadSampleAnalysis(t, "envelope_orders.json")
+
+	var buf bytes.Buffer
+	if err := tui.Render(&buf, analysis, tui.Options{Precision: -1}); err != nil {
+		t.Fatalf("render tui: %v", err)
+	}
+	want := "Source db.internal:5432/shop as report · PostgreSQL 16.4 · captured 2026-10-14 09:30:00 UTC · random_page_cost=1.1, work_mem=64MB"
+	if !bytes.Contains(buf.Bytes(), []byte(want)) {
+		t.Fatalf("expected metadata header %q in tui output:\n%s", want, buf.String())
+	}
+	if analysis.QueryText == "" {
+		t.Fatalf("expected query text to be taken from the envelope")
+	}
+}
+
+func TestRenderIOTiming(t *testing.T) {
+	analysis := test.LoadSampleAnalysis(t, "sort_orders.json")
+
+	var buf bytes.Buffer
+	if err := tui.Render(&buf, analysis, tui.Options{Precision: -1}); err != nil {
+		t.Fatalf("render tui: %v", err)
+	}
+	want := "I/O time shared read 31.21 ms, temp read 9.84 ms, temp write 14.32 ms"
+	if !bytes.Contains(buf.Bytes(), []byte(want)) {
+		t.Fatalf("expected I/O timing header %q in tui output:\n%s", want, buf.String())
+	}
+	for _, want := range []string{"(55.37 ms, 22.0% of execution: mostly CPU)", "(self 31.21 ms, 56.4% of I/O)"} {
+		if !bytes.Contains(buf.Bytes(), []byte(want)) {
+			t.Fatalf("expected %q in tui output:\n%s", want, buf.String())
+		}
+	}
+}
+
+func TestColorSupported(t *testing.T) {
+	if tui.ColorSupported(&bytes.Buffer{}) {
+		t.Fatalf("expected no colors for in-memory writers")
+	}
+	file, err := os.CreateTemp(t.TempDir(), "out")
+	if err != nil {
+		t.Fatalf("create temp: %v", err)
+	}
+	defer func() { _ = file.Close() }()
+	if tui.ColorSupported(file) {
+		t.Fatalf("expected no colors for regular files")
+	}
+	t.Setenv("NO_COLOR", "")
+	if tui.ColorSupported(os.Stdout) {
+		t.Fatalf("expected NO_COLOR to disable colors even when empty")
+	}
+}
+
+func TestRenderAlignsTimes(t *testing.T) {
+	// CPU time sums the parallel scan's processes, which takes it past a second.
+	analysis := test.LoadSampleAnalysisWith(t, "pgbench_hot.json", analyzer.Options{Time: analyzer.CPUTime})
+
+	var buf bytes.Buffer
+	if err := tui.Render(&buf, analysis, tui.Options{Precision: -1}); err != nil {
+		t.Fatalf("render tui: %v", err)
+	}
+	column := -1
+	for _, line := range strings.Split(buf.String(), "\n") {
+		i := strings.Index(line, "| self ")
+		if i < 0 {
+			continue
+		}
+		// Decimal points line up: find the one in the self time field.
+		point := i + strings.Index(line[i:], ".")
+		if column >= 0 && point != column {
+			t.Fatalf("expected self times aligned at column %d, got %d in %q", column, point, line)
+		}
+		column = point
+	}
+	if column < 0 {
+		t.Fatalf("expected node lines in tui output")
+	}
+	if !strings.Contains(buf.String(), "self  1.82 s  (workers)") {
+		t.Fatalf("expected the long scan in seconds:\n%s", buf.String())
+	}
+
+	buf.Reset()
+	if err := tui.Render(&buf, analysis, tui.Options{Precision: 4}); err != nil {
+		t.Fatalf("render tui: %v", err)
+	}
+	if !strings.Contains(buf.String(), "Execution time 676.5020 ms") {
+		t.Fatalf("expected --precision to apply to the header:\n%s", buf.String())
+	}
+
+	buf.Reset()
+	if err := tui.Render(&buf, analysis, tui.Options{Precision: 0}); err != nil {
+		t.Fatalf("render tui: %v", err)
+	}
+	if !strings.Contains(buf.String(), "Execution time 677 ms") {
+		t.Fatalf("expected --precision 0 to drop the decimals:\n%s", buf.String())
+	}
 }
@@ -0,0 +1,106 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/mickamy/xplain/internal/model"
+	"github.com/mickamy/xplain/internal/redact"
+)
+
+// CaptureEnvironment snapshots the planner-relevant state of the server: query-tuning and memory settings,
+// installed extensions, and the statistics age of every user table.
+func CaptureEnvironment(ctx context.Context, dsn string, opts Options) (*model.Environment, error) {
+	redact.Register(dsn)
+	env, err := captureEnvironment(ctx, dsn, opts)
+	return env, redact.Error(err)
+}
+
+func captureEnvironment(ctx context.Context, dsn string, opts Options) (*model.Environment, error) {
+	if strings.TrimSpace(dsn) == "" {
+		return nil, errors.New("runner: empty DSN")
+	}
+	if opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.Timeout)
+		defer cancel()
+	}
+
+	conn, meta, err := open(ctx, dsn, "", opts)
+	if err != nil {
+		return nil, err
+	}
+	defer func(conn *pgx.Conn, ctx context.Context) {
+		_ = conn.Close(ctx)
+	}(conn, ctx)
+
+	env := &model.Environment{
+		Host:          meta.Host,
+		Port:          meta.Port,
+		Database:      meta.Database,
+		ServerVersion: meta.ServerVersion,
+		CapturedAt:    time.Now().UTC(),
+		Settings:      map[string]string{},
+		Extensions:    map[string]string{},
+	}
+
+	rows, err := conn.Query(ctx, `SELECT name, current_setting(name) FROM pg_settings
+WHERE category LIKE 'Query Tuning%' OR name = ANY($1)`, capturedSettings)
+	if err != nil {
+		return nil, fmt.Errorf("runner: settings: %w", err)
+	}
+	for rows.Next() {
+		var name, value string
+		if err := rows.Scan(&name, &value); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("runner: settings: %w", err)
+		}
+		env.Settings[name] = value
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("runner: settings: %w", err)
+	}
+
+	if rows, err = conn.Query(ctx, "SELECT extname, extversion FROM pg_extension"); err != nil {
+		return nil, fmt.Errorf("runner: extensions: %w", err)
+	}
+	for rows.Next() {
+		var name, version string
+		if err := rows.Scan(&name, &version); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("runner: extensions: %w", err)
+		}
+		env.Extensions[name] = version
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("runner: extensions: %w", err)
+	}
+
+	if rows, err = conn.Query(ctx, `SELECT schemaname, relname, greatest(last_analyze, last_autoanalyze), n_live_tup, n_mod_since_analyze
+FROM pg_stat_user_tables ORDER BY schemaname, relname`); err != nil {
+		return nil, fmt.Errorf("runner: table statistics: %w", err)
+	}
+	for rows.Next() {
+		var (
+			t        model.TableStats
+			analyzed *time.Time
+		)
+		if err := rows.Scan(&t.Schema, &t.Name, &analyzed, &t.LiveRows, &t.ModifiedSinceAnalyze); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("runner: table statistics: %w", err)
+		}
+		if analyzed != nil {
+			t.LastAnalyzed = analyzed.UTC()
+		}
+		env.Tables = append(env.Tables, t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("runner: table statistics: %w", err)
+	}
+	return env, nil
+}
@@ -0,0 +1,414 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+
+	"github.com/mickamy/xplain/internal/model"
+	"github.com/mickamy/xplain/internal/redact"
+)
+
+// SQLSTATE codes the preflight turns into friendly messages.
+const (
+	insufficientPrivilege = "42501"
+	undefinedTable        = "42P01"
+	invalidSchemaName     = "3F000"
+)
+
+const identPattern = `(?:"[^"]+"|[a-z_][\w$]*)`
+
+// relationRef finds the relations a statement names after FROM, JOIN, UPDATE, INTO or USING, with an
+// optional schema. CTE names match too; they are dropped when the catalog does not know them.
+var relationRef = regexp.MustCompile(`(?i)\b(from|join|update|into|using)\s+(?:only\s+)?(` + identPattern + `(?:\s*\.\s*` + identPattern + `)?)`)
+
+// rowLock finds a SELECT's locking clauses, with the tables listed after OF when the clause names them.
+var rowLock = regexp.MustCompile(`(?i)\bfor\s+(?:no\s+key\s+update|update|key\s+share|share)\b(?:\s+of\s+(` + identPattern + `(?:\s*,\s*` + identPattern + `)*))?`)
+
+// notAlias are the keywords that can follow a relation name where an alias would.
+var notAlias = map[string]bool{
+	"where": true, "join": true, "on": true, "using": true, "inner": true, "left": true, "right": true,
+	"full": true, "cross": true, "natural": true, "group": true, "order": true, "limit": true, "offset": true,
+	"for": true, "set": true, "returning": true, "union": true, "except": true, "intersect": true,
+	"having": true, "window": true, "select": true, "values": true, "default": true, "fetch": true,
+	"overriding": true, "tablesample": true, "with": true, "as": true, "lateral": true,
+}
+
+var plainIdent = regexp.MustCompile(`^[a-z_][a-z0-9_$]*$`)
+
+// Grant is a privilege the connected role lacks.
+type Grant struct {
+	// Privilege is SELECT, INSERT, UPDATE or DELETE on a table, or USAGE on a schema.
+	Privilege string
+	// Object is what the privilege applies to, e.g. "TABLE public.orders" or "SCHEMA billing".
+	Object string
+}
+
+// Statement renders the GRANT that would give role the privilege.
+func (g Grant) Statement(role string) string {
+	return fmt.Sprintf("GRANT %s ON %s TO %s;", g.Privilege, g.Object, quoteIdent(role))
+}
+
+// PreflightError explains why the connected role cannot EXPLAIN the statement.
+type PreflightError struct {
+	User string
+	// Missing lists the grants the role needs, when the failure was a permission error.
+	Missing []Grant
+	// Hint suggests what to check when the statement names a relation or schema that does not exist.
+	Hint string
+	Err  error
+}
+
+func (e *PreflightError) Error() string {
+	var b strings.Builder
+	_, _ = fmt.Fprintf(&b, "runner: role %s cannot EXPLAIN the statement: %s", e.User, pgMessage(e.Err))
+	if len(e.Missing) > 0 {
+		b.WriteString("\nmissing grants:")
+		for _, g := range e.Missing {
+			b.WriteString("\n  " + g.Statement(e.User))
+		}
+	}
+	if e.Hint != "" {
+		b.WriteString("\n" + e.Hint)
+	}
+	return b.String()
+}
+
+func (e *PreflightError) Unwrap() error {
+	return e.Err
+}
+
+// Check connects and verifies that the role can EXPLAIN the statement, without running it. The returned
+// metadata describes the connection that was checked.
+func Check(ctx context.Context, dsn, sqlStatement string, opts Options) (*model.Metadata, error) {
+	redact.Register(dsn)
+	meta, err := check(ctx, dsn, sqlStatement, opts)
+	return meta, redact.Error(err)
+}
+
+func check(ctx context.Context, dsn, sqlStatement string, opts Options) (*model.Metadata, error) {
+	query, err := checkInput(dsn, sqlStatement)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.Timeout)
+		defer cancel()
+	}
+
+	conn, meta, err := open(ctx, dsn, query, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer func(conn *pgx.Conn, ctx context.Context) {
+		_ = conn.Close(ctx)
+	}(conn, ctx)
+
+	if err := preflight(ctx, conn, meta, query); err != nil {
+		return nil, err
+	}
+	return &meta, nil
+}
+
+// preflight plans the statement with a plain EXPLAIN, which checks privileges and resolves every relation
+// without executing anything. Permission and missing-relation errors come back as a *PreflightError.
+func preflight(ctx context.Context, conn *pgx.Conn, meta model.Metadata, query string) error {
+	_, err := conn.Exec(ctx, "EXPLAIN "+query)
+	if err == nil {
+		return nil
+	}
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return fmt.Errorf("runner: preflight: %w", err)
+	}
+	switch pgErr.Code {
+	case insufficientPrivilege:
+		missing, lookupErr := missingGrants(ctx, conn, query)
+		if lookupErr != nil {
+			return fmt.Errorf("runner: preflight: %w", errors.Join(err, lookupErr))
+		}
+		return &PreflightError{User: meta.User, Missing: missing, Err: err}
+	case undefinedTable, invalidSchemaName:
+		var searchPath string
+		if err := conn.QueryRow(ctx, "SELECT current_setting('search_path')").Scan(&searchPath); err != nil {
+			return fmt.Errorf("runner: preflight: %w", err)
+		}
+		hint := fmt.Sprintf("check the name and schema, and that database %s is the right one; search_path is %q",
+			meta.Database, searchPath)
+		return &PreflightError{User: meta.User, Hint: hint, Err: err}
+	}
+	return fmt.Errorf("runner: preflight: %w", err)
+}
+
+// missingGrants checks the role's privileges on every relation the statement names and on their schemas.
+// Names that are not relations, such as CTEs, are skipped.
+func missingGrants(ctx context.Context, conn *pgx.Conn, query string) ([]Grant, error) {
+	const lookup = `SELECT n.nspname, c.relname, has_schema_privilege(n.oid, 'USAGE'), has_table_privilege(c.oid, $3)
+FROM pg_class c JOIN pg_namespace n ON n.oid = c.relnamespace
+WHERE c.relname = $2 AND (n.nspname = $1 OR ($1 = '' AND n.nspname = ANY (current_schemas(true))))
+ORDER BY array_position(current_schemas(true), n.nspname::text) NULLS LAST
+LIMIT 1`
+
+	var missing []Grant
+	seen := map[Grant]bool{}
+	add := func(g Grant) {
+		if !seen[g] {
+			seen[g] = true
+			missing = append(missing, g)
+		}
+	}
+	for _, ref := range relationRefs(query) {
+		var schema, relation string
+		var usage, allowed bool
+		err := conn.QueryRow(ctx, lookup, ref.schema, ref.relation, ref.privilege).Scan(&schema, &relation, &usage, &allowed)
+		if errors.Is(err, pgx.ErrNoRows) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if !usage {
+			add(Grant{Privilege: "USAGE", Object: "SCHEMA " + quoteIdent(schema)})
+		}
+		if !allowed {
+			add(Grant{Privilege: ref.privilege, Object: "TABLE " + quoteIdent(schema) + "." + quoteIdent(relation)})
+		}
+	}
+	return missing, nil
+}
+
+type relationReference struct {
+	schema, relation, privilege string
+}
+
+// relationRefs lists the relations the statement names with the privilege each needs: the target of
+// INSERT, UPDATE or DELETE needs that privilege, the tables a SELECT locks UPDATE as well, everything else
+// SELECT. The statements of a leading WITH clause are read on their own, so the verb is the one that follows
+// it and data-modifying CTEs count too.
+func relationRefs(query string) []relationReference {
+	body, ctes := splitWith(query)
+	var refs []relationReference
+	for _, cte := range ctes {
+		refs = append(refs, relationRefs(cte)...)
+	}
+	return append(refs, statementRefs(body)...)
+}
+
+func statementRefs(query string) []relationReference {
+	verb := strings.ToUpper(firstWord(query))
+	var refs []relationReference
+	var aliases []string
+	locks := rowLock.FindAllStringSubmatchIndex(query, -1)
+	for i, loc := range relationRef.FindAllStringSubmatchIndex(query, -1) {
+		if slices.ContainsFunc(locks, func(lock []int) bool { return loc[0] >= lock[0] && loc[0] < lock[1] }) {
+			continue
+		}
+		parts := strings.SplitN(query[loc[4]:loc[5]], ".", 2)
+		ref := relationReference{relation: identName(parts[0]), privilege: "SELECT"}
+		if len(parts) == 2 {
+			ref.schema, ref.relation = ref.relation, identName(parts[1])
+		}
+		keyword := strings.ToLower(query[loc[2]:loc[3]])
+		switch {
+		case i == 0 && verb == "INSERT" && keyword == "into",
+			i == 0 && verb == "UPDATE" && keyword == "update",
+			i == 0 && verb == "DELETE" && keyword == "from":
+			ref.privilege = verb
+		}
+		refs = append(refs, ref)
+		aliases = append(aliases, aliasAt(query, loc[1]))
+	}
+	if verb != "SELECT" {
+		return refs
+	}
+
+	// FOR UPDATE, NO KEY UPDATE, SHARE and KEY SHARE need UPDATE on the tables they lock: those listed
+	// after OF, by name or alias, or every table the query reads.
+	for _, lock := range locks {
+		var of map[string]bool
+		if lock[2] >= 0 {
+			of = map[string]bool{}
+			for _, name := range strings.Split(query[lock[2]:lock[3]], ",") {
+				of[identName(name)] = true
+			}
+		}
+		for i, ref := range refs[:len(aliases)] {
+			if of != nil && !of[ref.relation] && !of[aliases[i]] {
+				continue
+			}
+			ref.privilege = "UPDATE"
+			refs = append(refs, ref)
+		}
+	}
+	return refs
+}
+
+// aliasAt returns the alias given to the relation named just before pos, if any.
+func aliasAt(query string, pos int) string {
+	pos = skipSpace(query, pos)
+	if hasKeyword(query, pos, "as") {
+		pos = skipSpace(query, pos+len("as"))
+	}
+	alias := identName(query[pos : pos+identAt(query, pos)])
+	if notAlias[alias] {
+		return ""
+	}
+	return alias
+}
+
+// splitWith separates the statement that follows a leading WITH clause from the bodies of its common table
+// expressions. A query without one, or one it cannot follow, comes back whole.
+func splitWith(query string) (string, []string) {
+	masked := maskQuoted(query)
+	pos := skipSpace(masked, 0)
+	if !hasKeyword(masked, pos, "with") {
+		return query, nil
+	}
+	pos = skipSpace(masked, pos+len("with"))
+	if hasKeyword(masked, pos, "recursive") {
+		pos = skipSpace(masked, pos+len("recursive"))
+	}
+	var ctes []string
+	for {
+		// name [(columns)] AS [[NOT] MATERIALIZED] (body)
+		name := identAt(masked, pos)
+		if name == 0 {
+			return query, nil
+		}
+		pos = skipSpace(masked, pos+name)
+		if pos < len(masked) && masked[pos] == '(' {
+			end := closingParen(masked, pos)
+			if end < 0 {
+				return query, nil
+			}
+			pos = skipSpace(masked, end+1)
+		}
+		if !hasKeyword(masked, pos, "as") {
+			return query, nil
+		}
+		pos = skipSpace(masked, pos+len("as"))
+		for _, word := range []string{"not", "materialized"} {
+			if hasKeyword(masked, pos, word) {
+				pos = skipSpace(masked, pos+len(word))
+			}
+		}
+		if pos >= len(masked) || masked[pos] != '(' {
+			return query, nil
+		}
+		end := closingParen(masked, pos)
+		if end < 0 {
+			return query, nil
+		}
+		ctes = append(ctes, query[pos+1:end])
+		pos = skipSpace(masked, end+1)
+		if pos >= len(masked) || masked[pos] != ',' {
+			return query[pos:], ctes
+		}
+		pos = skipSpace(masked, pos+1)
+	}
+}
+
+// maskQuoted blanks the contents of string literals and quoted identifiers, keeping every offset, so
+// the parentheses and keywords inside them are not read as syntax.
+func maskQuoted(query string) string {
+	b := []byte(query)
+	var quote byte
+	for i, c := range b {
+		switch {
+		case quote == 0 && (c == '\'' || c == '"'):
+			quote = c
+		case quote != 0 && c == quote:
+			quote = 0
+		case quote != 0:
+			b[i] = ' '
+		}
+	}
+	return string(b)
+}
+
+func skipSpace(s string, pos int) int {
+	for pos < len(s) && unicode.IsSpace(rune(s[pos])) {
+		pos++
+	}
+	return pos
+}
+
+// hasKeyword reports whether the word at pos is keyword, in any case.
+func hasKeyword(s string, pos int, keyword string) bool {
+	end := pos + len(keyword)
+	return end <= len(s) && strings.EqualFold(s[pos:end], keyword) && identAt(s, pos) == len(keyword)
+}
+
+// identAt returns the length of the identifier at pos, quoted or not, or 0 when there is none.
+func identAt(s string, pos int) int {
+	if pos < len(s) && s[pos] == '"' {
+		if end := strings.IndexByte(s[pos+1:], '"'); end >= 0 {
+			return end + 2
+		}
+		return 0
+	}
+	n := 0
+	for pos+n < len(s) && (s[pos+n] == '_' || s[pos+n] == '$' || unicode.IsLetter(rune(s[pos+n])) || unicode.IsDigit(rune(s[pos+n]))) {
+		n++
+	}
+	return n
+}
+
+func closingParen(s string, open int) int {
+	depth := 0
+	for i := open; i < len(s); i++ {
+		switch s[i] {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+func firstWord(query string) string {
+	fields := strings.Fields(query)
+	if len(fields) == 0 {
+		return ""
+	}
+	return fields[0]
+}
+
+// identName folds an unquoted identifier to lower case and strips the quotes of a quoted one, as
+// PostgreSQL does.
+func identName(ident string) string {
+	ident = strings.TrimSpace(ident)
+	if strings.HasPrefix(ident, `"`) {
+		return strings.Trim(ident, `"`)
+	}
+	return strings.ToLower(ident)
+}
+
+// quoteIdent quotes an identifier only when it needs it.
+func quoteIdent(ident string) string {
+	if plainIdent.MatchString(ident) {
+		return ident
+	}
+	return `"` + strings.ReplaceAll(ident, `"`, `""`) + `"`
+}
+
+func pgMessage(err error) string {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return pgErr.Message
+	}
+	return err.Error()
+}
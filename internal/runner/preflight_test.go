@@ -0,0 +1,21 @@
+package runner
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestRelationRefs(t *testing.T) {
+	cases := map[string]string{
+		"SELECT * FROM orders o JOIN users u ON u.id = o.user_id":                                                                            "[{ orders SELECT} { users SELECT}]",
+		"WITH recent AS (SELECT * FROM orders WHERE note = 'a (b') UPDATE users SET seen = true FROM recent WHERE users.id = recent.user_id": "[{ orders SELECT} { users UPDATE} { recent SELECT}]",
+		"WITH gone AS (DELETE FROM billing.orders RETURNING id) INSERT INTO archive SELECT id FROM gone":                                     "[{billing orders DELETE} { archive INSERT} { gone SELECT}]",
+		"SELECT * FROM orders o JOIN users u ON u.id = o.user_id FOR UPDATE OF o":                                                            "[{ orders SELECT} { users SELECT} { orders UPDATE}]",
+		"SELECT * FROM jobs FOR NO KEY UPDATE SKIP LOCKED":                                                                                   "[{ jobs SELECT} { jobs UPDATE}]",
+	}
+	for query, want := range cases {
+		if got := fmt.Sprint(relationRefs(query)); got != want {
+			t.Errorf("relationRefs(%q) = %s, want %s", query, got, want)
+		}
+	}
+}
@@ -2,30 +2,150 @@ package runner
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"sort"
 	"strings"
 	"time"
 
 	"github.com/jackc/pgx/v5"
+
+	"github.com/mickamy/xplain/internal/config"
+	"github.com/mickamy/xplain/internal/model"
+	"github.com/mickamy/xplain/internal/redact"
 )
 
 // Options customises how EXPLAIN is executed.
 type Options struct {
 	Timeout time.Duration
+	// Verbose adds VERBOSE so nodes report their output column lists.
+	Verbose bool
+	// Settings are applied to the session with set_config before EXPLAIN runs, e.g.
+	// max_parallel_workers_per_gather.
+	Settings map[string]string
+	// ClientTiming also runs the statement without EXPLAIN in a read-only transaction, fetching every
+	// row, and records the round trip the client observed in the metadata.
+	ClientTiming bool
+	// ReadOnly runs EXPLAIN in a read-only transaction that is always rolled back, so a statement that
+	// would write fails instead of changing the database.
+	ReadOnly bool
 }
 
-// Run executes EXPLAIN (ANALYZE, BUFFERS, FORMAT JSON) for the provided SQL statement.
-func Run(ctx context.Context, dsn, sqlStatement string, opts Options) ([]byte, error) {
-	if strings.TrimSpace(dsn) == "" {
-		return nil, errors.New("runner: empty DSN")
+// Result carries the raw EXPLAIN output together with where and when it was captured.
+type Result struct {
+	Plan     []byte
+	Metadata model.Metadata
+}
+
+// Envelope renders the result as an indented xplain envelope document.
+func (r *Result) Envelope() ([]byte, error) {
+	payload, err := json.MarshalIndent(model.Envelope{Xplain: &r.Metadata, Plan: r.Plan}, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("runner: encode envelope: %w", err)
 	}
-	query := strings.TrimSpace(sqlStatement)
-	if query == "" {
-		return nil, errors.New("runner: empty sql statement")
+	return append(payload, '\n'), nil
+}
+
+// Run executes EXPLAIN (ANALYZE, BUFFERS, FORMAT JSON) for the provided SQL statement after the same
+// preflight as Check, so permission problems surface as a *PreflightError listing the missing grants.
+// Errors never carry the connection password, nor the host when output.redact_hosts is set.
+func Run(ctx context.Context, dsn, sqlStatement string, opts Options) (*Result, error) {
+	redact.Register(dsn)
+	result, err := run(ctx, dsn, sqlStatement, opts)
+	return result, redact.Error(err)
+}
+
+func run(ctx context.Context, dsn, sqlStatement string, opts Options) (*Result, error) {
+	query, err := checkInput(dsn, sqlStatement)
+	if err != nil {
+		return nil, err
 	}
 
-	explainSQL := fmt.Sprintf("EXPLAIN (ANALYZE, BUFFERS, FORMAT JSON) %s", query)
+	if opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.Timeout)
+		defer cancel()
+	}
+
+	conn, meta, err := open(ctx, dsn, query, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer func(conn *pgx.Conn, ctx context.Context) {
+		_ = conn.Close(ctx)
+	}(conn, ctx)
+
+	if err := preflight(ctx, conn, meta, query); err != nil {
+		return nil, err
+	}
+	var result *Result
+	if opts.ReadOnly {
+		result, err = explainReadOnly(ctx, conn, meta, explainSQL(query, opts))
+	} else {
+		result, err = explain(ctx, conn, meta, explainSQL(query, opts))
+	}
+	if err != nil {
+		return nil, err
+	}
+	if opts.ClientTiming {
+		if result.Metadata.Client, err = measureClient(ctx, conn, query); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// measureClient times the statement from the client's side: sending it, executing it and receiving every
+// row. It runs in a read-only transaction that is rolled back, so statements that write are rejected
+// rather than applied twice. The simple protocol keeps it to a single round trip.
+func measureClient(ctx context.Context, conn *pgx.Conn, query string) (*model.ClientTiming, error) {
+	tx, err := conn.BeginTx(ctx, pgx.TxOptions{AccessMode: pgx.ReadOnly})
+	if err != nil {
+		return nil, fmt.Errorf("runner: client timing: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
+	timing := &model.ClientTiming{}
+	start := time.Now()
+	rows, err := tx.Query(ctx, query, pgx.QueryExecModeSimpleProtocol)
+	if err != nil {
+		return nil, fmt.Errorf("runner: client timing: %w", err)
+	}
+	for rows.Next() {
+		timing.Rows++
+		for _, value := range rows.RawValues() {
+			timing.Bytes += int64(len(value))
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("runner: client timing: %w", err)
+	}
+	timing.WallMs = float64(time.Since(start).Microseconds()) / 1000
+	return timing, nil
+}
+
+// RunPrepared prepares the statement and runs EXPLAIN (ANALYZE, BUFFERS, FORMAT JSON) EXECUTE on it
+// executions times in one session, passing params as literals. PostgreSQL plans the first five executions
+// with the parameter values and may switch to a generic plan from the sixth, so the results show whether
+// and when that happens. The timeout covers the whole session.
+func RunPrepared(ctx context.Context, dsn, sqlStatement string, params []string, executions int, opts Options) ([]*Result, error) {
+	redact.Register(dsn)
+	results, err := runPrepared(ctx, dsn, sqlStatement, params, executions, opts)
+	return results, redact.Error(err)
+}
+
+func runPrepared(ctx context.Context, dsn, sqlStatement string, params []string, executions int, opts Options) ([]*Result, error) {
+	query, err := checkInput(dsn, sqlStatement)
+	if err != nil {
+		return nil, err
+	}
+	if executions < 1 {
+		return nil, errors.New("runner: executions must be at least 1")
+	}
 
 	if opts.Timeout > 0 {
 		var cancel context.CancelFunc
@@ -33,17 +153,157 @@ func Run(ctx context.Context, dsn, sqlStatement string, opts Options) ([]byte, e
 		defer cancel()
 	}
 
-	conn, err := pgx.Connect(ctx, dsn)
+	conn, meta, err := open(ctx, dsn, query, opts)
 	if err != nil {
-		return nil, fmt.Errorf("runner: connect: %w", err)
+		return nil, err
 	}
 	defer func(conn *pgx.Conn, ctx context.Context) {
 		_ = conn.Close(ctx)
 	}(conn, ctx)
 
-	var payload []byte
-	if err := conn.QueryRow(ctx, explainSQL).Scan(&payload); err != nil {
+	if _, err := conn.Exec(ctx, "PREPARE "+preparedName+" AS "+strings.TrimSuffix(query, ";")); err != nil {
+		return nil, fmt.Errorf("runner: prepare: %w", err)
+	}
+	execute := "EXECUTE " + preparedName
+	if len(params) > 0 {
+		literals := make([]string, 0, len(params))
+		for _, p := range params {
+			literals = append(literals, quoteLiteral(p))
+		}
+		execute += "(" + strings.Join(literals, ", ") + ")"
+	}
+
+	results := make([]*Result, 0, executions)
+	for range executions {
+		result, err := explain(ctx, conn, meta, explainSQL(execute, opts))
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// preparedName is the statement name RunPrepared prepares; the session is private, so it cannot clash.
+const preparedName = "xplain_stmt"
+
+// ServiceDSN returns a connection string selecting the named entry of the connection service file,
+// ~/.pg_service.conf or $PGSERVICEFILE, which pgx reads the way libpq does.
+func ServiceDSN(name string) string {
+	return "service='" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(name) + "'"
+}
+
+func checkInput(dsn, sqlStatement string) (string, error) {
+	if strings.TrimSpace(dsn) == "" {
+		return "", errors.New("runner: empty DSN")
+	}
+	query := strings.TrimSpace(sqlStatement)
+	if query == "" {
+		return "", errors.New("runner: empty sql statement")
+	}
+	return query, nil
+}
+
+func explainSQL(query string, opts Options) string {
+	options := "ANALYZE, BUFFERS"
+	if opts.Verbose {
+		options += ", VERBOSE"
+	}
+	return fmt.Sprintf("EXPLAIN (%s, FORMAT JSON) %s", options, query)
+}
+
+// open connects, records where the plan is captured and applies opts.Settings to the session.
+func open(ctx context.Context, dsn, query string, opts Options) (*pgx.Conn, model.Metadata, error) {
+	meta := model.Metadata{Query: query}
+	cfg, err := pgx.ParseConfig(dsn)
+	if err != nil {
+		return nil, meta, fmt.Errorf("runner: connect: %w", err)
+	}
+	// Parsing resolves the password from the service file or ~/.pgpass when the DSN has none.
+	redact.RegisterPassword(cfg.Password)
+	conn, err := pgx.ConnectConfig(ctx, cfg)
+	if err != nil {
+		return nil, meta, fmt.Errorf("runner: connect: %w", err)
+	}
+	fail := func(err error) (*pgx.Conn, model.Metadata, error) {
+		_ = conn.Close(ctx)
+		return nil, meta, err
+	}
+
+	if !config.Active().Output.RedactHosts {
+		meta.Host = conn.Config().Host
+		meta.Port = conn.Config().Port
+	}
+	if err := conn.QueryRow(ctx, "SELECT current_database(), current_user, current_setting('server_version')").
+		Scan(&meta.Database, &meta.User, &meta.ServerVersion); err != nil {
+		return fail(fmt.Errorf("runner: metadata: %w", err))
+	}
+
+	names := make([]string, 0, len(opts.Settings))
+	for name := range opts.Settings {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if _, err := conn.Exec(ctx, "SELECT set_config($1, $2, false)", name, opts.Settings[name]); err != nil {
+			return fail(fmt.Errorf("runner: set %s: %w", name, err))
+		}
+	}
+
+	rows, err := conn.Query(ctx, "SELECT name, current_setting(name) FROM pg_settings WHERE name = ANY($1)", capturedSettings)
+	if err != nil {
+		return fail(fmt.Errorf("runner: settings: %w", err))
+	}
+	meta.Settings = map[string]string{}
+	for rows.Next() {
+		var name, value string
+		if err := rows.Scan(&name, &value); err != nil {
+			rows.Close()
+			return fail(fmt.Errorf("runner: settings: %w", err))
+		}
+		meta.Settings[name] = value
+	}
+	if err := rows.Err(); err != nil {
+		return fail(fmt.Errorf("runner: settings: %w", err))
+	}
+	return conn, meta, nil
+}
+
+// capturedSettings are the settings recorded in the envelope because they commonly change plans.
+var capturedSettings = []string{
+	"work_mem", "hash_mem_multiplier", "shared_buffers", "effective_cache_size", "effective_io_concurrency",
+	"random_page_cost", "seq_page_cost", "cpu_tuple_cost", "default_statistics_target", "jit", "plan_cache_mode",
+	"max_parallel_workers_per_gather", "parallel_setup_cost", "parallel_tuple_cost",
+}
+
+// rowQuerier is what explain needs from a connection or a transaction.
+type rowQuerier interface {
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+func explain(ctx context.Context, conn rowQuerier, meta model.Metadata, statement string) (*Result, error) {
+	result := &Result{Metadata: meta}
+	result.Metadata.CapturedAt = time.Now().UTC()
+	if err := conn.QueryRow(ctx, statement).Scan(&result.Plan); err != nil {
 		return nil, fmt.Errorf("runner: query: %w", err)
 	}
-	return payload, nil
+	return result, nil
+}
+
+// explainReadOnly runs explain inside a read-only transaction and rolls it back, whatever the statement did.
+func explainReadOnly(ctx context.Context, conn *pgx.Conn, meta model.Metadata, statement string) (*Result, error) {
+	tx, err := conn.BeginTx(ctx, pgx.TxOptions{AccessMode: pgx.ReadOnly})
+	if err != nil {
+		return nil, fmt.Errorf("runner: begin read-only transaction: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+	return explain(ctx, tx, meta, statement)
+}
+
+// quoteLiteral renders a parameter value as a SQL string literal; PostgreSQL coerces it to the
+// parameter's type.
+func quoteLiteral(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", "''") + "'"
 }
@@ -0,0 +1,39 @@
+package runner_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgconn"
+
+	"github.com/mickamy/xplain/internal/runner"
+)
+
+func TestServiceDSN(t *testing.T) {
+	dir := t.TempDir()
+	serviceFile := filepath.Join(dir, "pg_service.conf")
+	passFile := filepath.Join(dir, "pgpass")
+	if err := os.WriteFile(serviceFile, []byte("[bench]\nhost=db.internal\nport=6432\ndbname=bench\nuser=analyst\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(passFile, []byte("db.internal:6432:bench:analyst:s3cret\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PGSERVICEFILE", serviceFile)
+	t.Setenv("PGPASSFILE", passFile)
+
+	cfg, err := pgconn.ParseConfig(runner.ServiceDSN("bench"))
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if cfg.Host != "db.internal" || cfg.Port != 6432 || cfg.Database != "bench" || cfg.User != "analyst" {
+		t.Fatalf("unexpected service settings %+v", cfg)
+	}
+	if cfg.Password != "s3cret" {
+		t.Fatalf("expected the password from the pgpass file, got %q", cfg.Password)
+	}
+	if got := runner.ServiceDSN(`it's`); got != `service='it\'s'` {
+		t.Fatalf("unexpected quoting %q", got)
+	}
+}
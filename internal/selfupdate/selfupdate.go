@@ -0,0 +1,279 @@
+// Package selfupdate replaces a standalone xplain binary with the latest GitHub release.
+package selfupdate
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	// DefaultAPI is the GitHub REST endpoint releases are looked up from.
+	DefaultAPI = "https://api.github.com"
+	// DefaultRepo is the repository publishing xplain releases.
+	DefaultRepo = "mickamy/xplain"
+
+	binaryName = "xplain"
+	// maxDownload bounds archive and checksum downloads.
+	maxDownload = 200 << 20
+)
+
+// Release is the subset of a GitHub release needed to update.
+type Release struct {
+	Tag    string  `json:"tag_name"`
+	Assets []Asset `json:"assets"`
+}
+
+// Asset is one file attached to a release.
+type Asset struct {
+	Name string `json:"name"`
+	URL  string `json:"browser_download_url"`
+}
+
+// Version returns the release tag without its leading "v", as used in archive names.
+func (r *Release) Version() string {
+	return strings.TrimPrefix(r.Tag, "v")
+}
+
+// Updater looks up and downloads releases.
+type Updater struct {
+	Client *http.Client
+	API    string
+	Repo   string
+}
+
+// New returns an updater for the public xplain releases.
+func New() *Updater {
+	return &Updater{Client: http.DefaultClient, API: DefaultAPI, Repo: DefaultRepo}
+}
+
+// Latest fetches the newest published release.
+func (u *Updater) Latest(ctx context.Context) (*Release, error) {
+	url := fmt.Sprintf("%s/repos/%s/releases/latest", strings.TrimRight(u.API, "/"), u.Repo)
+	body, err := u.get(ctx, url, "application/vnd.github+json")
+	if err != nil {
+		return nil, err
+	}
+	var rel Release
+	if err := json.Unmarshal(body, &rel); err != nil {
+		return nil, fmt.Errorf("selfupdate: decode release: %w", err)
+	}
+	if rel.Tag == "" {
+		return nil, errors.New("selfupdate: release has no tag")
+	}
+	return &rel, nil
+}
+
+// Download fetches the release archive for goos/goarch, verifies it against the release's
+// SHA-256 checksums file and returns the xplain binary it contains.
+func (u *Updater) Download(ctx context.Context, rel *Release, goos, goarch string) ([]byte, error) {
+	archive, ok := rel.archive(goos, goarch)
+	if !ok {
+		return nil, fmt.Errorf("selfupdate: release %s has no build for %s/%s", rel.Tag, goos, goarch)
+	}
+	sums, ok := rel.asset(fmt.Sprintf("%s_%s_checksums.txt", binaryName, rel.Version()))
+	if !ok {
+		return nil, fmt.Errorf("selfupdate: release %s publishes no checksums; refusing to install an unverified binary", rel.Tag)
+	}
+
+	data, err := u.get(ctx, archive.URL, "application/octet-stream")
+	if err != nil {
+		return nil, err
+	}
+	list, err := u.get(ctx, sums.URL, "application/octet-stream")
+	if err != nil {
+		return nil, err
+	}
+	want, err := checksum(list, archive.Name)
+	if err != nil {
+		return nil, err
+	}
+	got := sha256.Sum256(data)
+	if hex.EncodeToString(got[:]) != want {
+		return nil, fmt.Errorf("selfupdate: checksum mismatch for %s", archive.Name)
+	}
+
+	exe := binaryName
+	if goos == "windows" {
+		exe += ".exe"
+	}
+	if strings.HasSuffix(archive.Name, ".zip") {
+		return fromZip(data, exe)
+	}
+	return fromTarGz(data, exe)
+}
+
+// ManagedBy names the package manager owning the binary at path, or returns "" for a standalone
+// install. Managed installs must be upgraded through their package manager instead.
+func ManagedBy(path string) string {
+	slashed := strings.ReplaceAll(path, `\`, "/")
+	lower := strings.ToLower(slashed)
+	switch {
+	case strings.Contains(slashed, "/Cellar/") || strings.Contains(slashed, "/homebrew/"):
+		return "Homebrew (brew upgrade xplain)"
+	case strings.Contains(lower, "/scoop/apps/"):
+		return "Scoop (scoop update xplain)"
+	case strings.Contains(slashed, "/nix/store/"):
+		return "Nix"
+	default:
+		return ""
+	}
+}
+
+// Replace swaps the executable at path for binary. The new file is written next to the old one
+// and renamed into place; on Windows the running executable is moved aside first, since it
+// cannot be overwritten while in use, and left behind as <name>.old.
+func Replace(path string, binary []byte) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("selfupdate: stat %s: %w", path, err)
+	}
+	dir := filepath.Dir(path)
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+".new-*")
+	if err != nil {
+		return fmt.Errorf("selfupdate: create temp file: %w", err)
+	}
+	defer func() {
+		_ = os.Remove(tmp.Name())
+	}()
+	_, err = tmp.Write(binary)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(tmp.Name(), info.Mode().Perm()|0o111)
+	}
+	if err != nil {
+		return fmt.Errorf("selfupdate: write new binary: %w", err)
+	}
+
+	old := path + ".old"
+	_ = os.Remove(old)
+	if err := os.Rename(path, old); err != nil {
+		return fmt.Errorf("selfupdate: move current binary aside: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		_ = os.Rename(old, path)
+		return fmt.Errorf("selfupdate: install new binary: %w", err)
+	}
+	// Windows keeps the running executable locked; it is removed on the next update instead.
+	_ = os.Remove(old)
+	return nil
+}
+
+func (r *Release) asset(name string) (Asset, bool) {
+	for _, a := range r.Assets {
+		if a.Name == name {
+			return a, true
+		}
+	}
+	return Asset{}, false
+}
+
+// archive finds the build for goos/goarch, named <binary>_<version>_<os>_<arch>.tar.gz or .zip.
+func (r *Release) archive(goos, goarch string) (Asset, bool) {
+	base := fmt.Sprintf("%s_%s_%s_%s", binaryName, r.Version(), goos, goarch)
+	for _, ext := range []string{".tar.gz", ".zip"} {
+		if a, ok := r.asset(base + ext); ok {
+			return a, true
+		}
+	}
+	return Asset{}, false
+}
+
+func (u *Updater) get(ctx context.Context, url, accept string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("selfupdate: %w", err)
+	}
+	req.Header.Set("Accept", accept)
+	if token := os.Getenv("GITHUB_TOKEN"); token != "" && strings.HasPrefix(url, u.API) {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	client := u.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("selfupdate: fetch %s: %w", url, err)
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("selfupdate: fetch %s: %s", url, resp.Status)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxDownload))
+	if err != nil {
+		return nil, fmt.Errorf("selfupdate: read %s: %w", url, err)
+	}
+	return body, nil
+}
+
+// checksum finds the SHA-256 listed for name in a "<hex>  <file>" checksums file.
+func checksum(list []byte, name string) (string, error) {
+	scanner := bufio.NewScanner(bytes.NewReader(list))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 && strings.TrimPrefix(fields[1], "*") == name {
+			return strings.ToLower(fields[0]), nil
+		}
+	}
+	return "", fmt.Errorf("selfupdate: no checksum listed for %s", name)
+}
+
+func fromTarGz(data []byte, exe string) ([]byte, error) {
+	gz, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("selfupdate: open archive: %w", err)
+	}
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("selfupdate: read archive: %w", err)
+		}
+		if hdr.Typeflag == tar.TypeReg && filepath.Base(hdr.Name) == exe {
+			return io.ReadAll(io.LimitReader(tr, maxDownload))
+		}
+	}
+	return nil, fmt.Errorf("selfupdate: archive does not contain %s", exe)
+}
+
+func fromZip(data []byte, exe string) ([]byte, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("selfupdate: open archive: %w", err)
+	}
+	for _, f := range zr.File {
+		if path := filepath.Base(f.Name); path != exe || f.FileInfo().IsDir() {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, fmt.Errorf("selfupdate: read archive: %w", err)
+		}
+		defer func() {
+			_ = rc.Close()
+		}()
+		return io.ReadAll(io.LimitReader(rc, maxDownload))
+	}
+	return nil, fmt.Errorf("selfupdate: archive does not contain %s", exe)
+}
@@ -0,0 +1,123 @@
+package selfupdate_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mickamy/xplain/internal/selfupdate"
+)
+
+func TestDownloadVerifiesChecksum(t *testing.T) {
+	binary := []byte("#!/bin/sh\necho xplain v1.2.0\n")
+	archive := tarGz(t, "xplain", binary)
+	sum := sha256.Sum256(archive)
+	checksums := hex.EncodeToString(sum[:]) + "  xplain_1.2.0_linux_amd64.tar.gz\n"
+
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/repos/mickamy/xplain/releases/latest":
+			_, _ = fmt.Fprintf(w, `{"tag_name":"v1.2.0","assets":[
+				{"name":"xplain_1.2.0_linux_amd64.tar.gz","browser_download_url":"%[1]s/a.tar.gz"},
+				{"name":"xplain_1.2.0_checksums.txt","browser_download_url":"%[1]s/sums.txt"}]}`, srv.URL)
+		case "/a.tar.gz":
+			_, _ = w.Write(archive)
+		case "/sums.txt":
+			_, _ = w.Write([]byte(checksums))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	updater := &selfupdate.Updater{Client: srv.Client(), API: srv.URL, Repo: selfupdate.DefaultRepo}
+	ctx := context.Background()
+	release, err := updater.Latest(ctx)
+	if err != nil {
+		t.Fatalf("latest: %v", err)
+	}
+	if release.Version() != "1.2.0" {
+		t.Fatalf("expected version 1.2.0, got %q", release.Version())
+	}
+	got, err := updater.Download(ctx, release, "linux", "amd64")
+	if err != nil {
+		t.Fatalf("download: %v", err)
+	}
+	if !bytes.Equal(got, binary) {
+		t.Fatalf("unexpected binary %q", got)
+	}
+	if _, err := updater.Download(ctx, release, "plan9", "arm"); err == nil {
+		t.Fatalf("expected missing platform builds to fail")
+	}
+
+	checksums = strings.Repeat("0", 64) + "  xplain_1.2.0_linux_amd64.tar.gz\n"
+	if _, err := updater.Download(ctx, release, "linux", "amd64"); err == nil || !strings.Contains(err.Error(), "checksum mismatch") {
+		t.Fatalf("expected a checksum mismatch, got %v", err)
+	}
+}
+
+func TestReplace(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "xplain")
+	if err := os.WriteFile(path, []byte("old"), 0o755); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	if err := selfupdate.Replace(path, []byte("new")); err != nil {
+		t.Fatalf("replace: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil || string(data) != "new" {
+		t.Fatalf("expected the new binary, got %q (%v)", data, err)
+	}
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm()&0o100 == 0 {
+		t.Fatalf("expected the new binary to be executable, got %v (%v)", info.Mode(), err)
+	}
+}
+
+func TestManagedBy(t *testing.T) {
+	cases := map[string]bool{
+		"/opt/homebrew/Cellar/xplain/1.2.0/bin/xplain":      true,
+		`C:\Users\dev\scoop\apps\xplain\current\xplain.exe`: true,
+		"/usr/local/bin/xplain":                             false,
+	}
+	for path, managed := range cases {
+		if got := selfupdate.ManagedBy(path) != ""; got != managed {
+			t.Errorf("ManagedBy(%q) managed = %v, want %v", path, got, managed)
+		}
+	}
+}
+
+func tarGz(t *testing.T, name string, content []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for _, f := range []struct {
+		name string
+		body []byte
+	}{{"README.md", []byte("readme")}, {name, content}} {
+		if err := tw.WriteHeader(&tar.Header{Name: f.name, Mode: 0o755, Size: int64(len(f.body)), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatalf("tar header: %v", err)
+		}
+		if _, err := tw.Write(f.body); err != nil {
+			t.Fatalf("tar write: %v", err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatalf("tar close: %v", err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatalf("gzip close: %v", err)
+	}
+	return buf.Bytes()
+}
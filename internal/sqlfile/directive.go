@@ -0,0 +1,44 @@
+package sqlfile
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+var directiveLine = regexp.MustCompile(`(?m)^\s*--\s*xplain:\s*(.+?)\s*$`)
+
+// Directives are run settings and assertions declared in SQL comments, e.g.
+//
+//	-- xplain: timeout=60s
+//	-- xplain: expect-index orders_status_idx
+//	-- xplain: max-time 200ms
+type Directives struct {
+	Timeout time.Duration
+	// Assertions holds every other directive verbatim; they are parsed by the assert package.
+	Assertions []string
+}
+
+// ParseDirectives collects the "-- xplain:" comment lines of a statement.
+func ParseDirectives(sql string) (Directives, error) {
+	var d Directives
+	for _, m := range directiveLine.FindAllStringSubmatch(sql, -1) {
+		body := m[1]
+		name, value, ok := strings.Cut(body, "=")
+		if !ok || strings.ContainsAny(name, " \t") {
+			name, value, _ = strings.Cut(body, " ")
+		}
+		switch strings.TrimSpace(name) {
+		case "timeout":
+			timeout, err := time.ParseDuration(strings.TrimSpace(value))
+			if err != nil {
+				return Directives{}, fmt.Errorf("directive %q: %w", body, err)
+			}
+			d.Timeout = timeout
+		default:
+			d.Assertions = append(d.Assertions, body)
+		}
+	}
+	return d, nil
+}
@@ -0,0 +1,130 @@
+package sqlfile
+
+import "strings"
+
+// Statement is one statement of a SQL script. Start and End are byte offsets into the script,
+// spanning the statement's leading comments (and so its directives) but not the terminating ";".
+type Statement struct {
+	Text  string
+	Start int
+	End   int
+}
+
+// Split breaks a script into statements at top-level semicolons, ignoring those inside string
+// literals, quoted identifiers, dollar-quoted bodies and comments. Statements consisting only of
+// comments or whitespace are dropped.
+func Split(src string) []Statement {
+	var (
+		out   []Statement
+		start int
+	)
+	emit := func(end int) {
+		text := src[start:end]
+		trimmed := strings.TrimSpace(text)
+		if trimmed != "" && hasCode(trimmed) {
+			offset := start + strings.Index(text, trimmed)
+			out = append(out, Statement{Text: trimmed, Start: offset, End: offset + len(trimmed)})
+		}
+	}
+
+	for i := 0; i < len(src); {
+		switch c := src[i]; {
+		case c == '\'' || c == '"':
+			i = skipQuoted(src, i, c)
+		case c == '-' && strings.HasPrefix(src[i:], "--"):
+			if nl := strings.IndexByte(src[i:], '\n'); nl >= 0 {
+				i += nl + 1
+			} else {
+				i = len(src)
+			}
+		case c == '/' && strings.HasPrefix(src[i:], "/*"):
+			i = skipBlockComment(src, i)
+		case c == '$':
+			i = skipDollarQuoted(src, i)
+		case c == ';':
+			emit(i)
+			i++
+			start = i
+		default:
+			i++
+		}
+	}
+	emit(len(src))
+	return out
+}
+
+// skipQuoted returns the offset after the literal or identifier opened at i; doubled quotes escape.
+func skipQuoted(src string, i int, quote byte) int {
+	for j := i + 1; j < len(src); j++ {
+		if src[j] != quote {
+			continue
+		}
+		if j+1 < len(src) && src[j+1] == quote {
+			j++
+			continue
+		}
+		return j + 1
+	}
+	return len(src)
+}
+
+// skipBlockComment returns the offset after the /* */ comment opened at i; they may nest.
+func skipBlockComment(src string, i int) int {
+	depth := 0
+	for j := i; j < len(src)-1; j++ {
+		switch src[j : j+2] {
+		case "/*":
+			depth++
+			j++
+		case "*/":
+			depth--
+			j++
+			if depth == 0 {
+				return j + 1
+			}
+		}
+	}
+	return len(src)
+}
+
+// skipDollarQuoted returns the offset after a $tag$ ... $tag$ body opened at i, or i+1 when the
+// dollar sign is a positional parameter such as $1.
+func skipDollarQuoted(src string, i int) int {
+	end := i + 1
+	for end < len(src) && (src[end] == '_' || isAlnum(src[end])) {
+		end++
+	}
+	if end >= len(src) || src[end] != '$' || (end > i+1 && src[i+1] >= '0' && src[i+1] <= '9') {
+		return i + 1
+	}
+	tag := src[i : end+1]
+	if close := strings.Index(src[end+1:], tag); close >= 0 {
+		return end + 1 + close + len(tag)
+	}
+	return len(src)
+}
+
+func isAlnum(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
+
+// hasCode reports whether text holds anything besides comments.
+func hasCode(text string) bool {
+	for i := 0; i < len(text); {
+		switch {
+		case strings.HasPrefix(text[i:], "--"):
+			nl := strings.IndexByte(text[i:], '\n')
+			if nl < 0 {
+				return false
+			}
+			i += nl + 1
+		case strings.HasPrefix(text[i:], "/*"):
+			i = skipBlockComment(text, i)
+		case text[i] == ' ' || text[i] == '\t' || text[i] == '\n' || text[i] == '\r':
+			i++
+		default:
+			return true
+		}
+	}
+	return false
+}
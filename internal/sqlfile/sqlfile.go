@@ -0,0 +1,105 @@
+package sqlfile
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// Vars holds template variables; it implements flag.Value so --var name=value can be repeated.
+type Vars map[string]string
+
+// String renders the variables in name=value form.
+func (v Vars) String() string {
+	parts := make([]string, 0, len(v))
+	for name, value := range v {
+		parts = append(parts, name+"="+value)
+	}
+	slices.Sort(parts)
+	return strings.Join(parts, ",")
+}
+
+// Set parses one name=value pair.
+func (v Vars) Set(raw string) error {
+	name, value, ok := strings.Cut(raw, "=")
+	name = strings.TrimSpace(name)
+	if !ok || name == "" {
+		return fmt.Errorf("invalid variable %q (expected name=value)", raw)
+	}
+	v[name] = value
+	return nil
+}
+
+var (
+	// placeholder matches the three placeholder forms, a template action in groups 1 and 2, a sqlc
+	// argument in group 3 and an ERB tag in group 4. Anything else between {{ and }}, such as a 2D array
+	// literal '{{1,2},{3,4}}', is plain SQL.
+	placeholder = regexp.MustCompile(`\{\{\s*(quote\s+)?\.([A-Za-z_][A-Za-z0-9_]*)\s*\}\}` +
+		`|sqlc\.n?arg\(\s*'?([A-Za-z_][A-Za-z0-9_]*)'?\s*\)` +
+		`|<%=\s*([A-Za-z_][A-Za-z0-9_]*)\s*%>`)
+	numericText = regexp.MustCompile(`^-?\d+(\.\d+)?$`)
+)
+
+// Load reads a SQL file and renders its placeholders with vars.
+func Load(path string, vars Vars) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("read sql file: %w", err)
+	}
+	text, err := Render(string(data), vars)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", path, err)
+	}
+	return text, nil
+}
+
+// Render substitutes placeholders in a statement:
+//   - Go templates, {{ .tenant_id }} inserted verbatim, or {{ quote .status }} as a string literal;
+//   - sqlc placeholders, sqlc.arg(tenant_id), inserted as a literal (numbers bare, text quoted);
+//   - ERB tags, <%= tenant_id %>, inserted verbatim.
+//
+// Every referenced variable must be provided. All three forms are filled in by one pass over the
+// statement, so a value containing {{ is inserted as text, never read as a placeholder, and braces that
+// are not one of the template actions above are left alone.
+func Render(sql string, vars Vars) (string, error) {
+	var missing []string
+	lookup := func(name string) string {
+		value, ok := vars[name]
+		if !ok && !slices.Contains(missing, name) {
+			missing = append(missing, name)
+		}
+		return value
+	}
+
+	text := placeholder.ReplaceAllStringFunc(sql, func(match string) string {
+		m := placeholder.FindStringSubmatch(match)
+		switch {
+		case m[2] != "" && m[1] != "":
+			return quote(lookup(m[2]))
+		case m[2] != "":
+			return lookup(m[2])
+		case m[3] != "":
+			return literal(lookup(m[3]))
+		default:
+			return lookup(m[4])
+		}
+	})
+
+	if len(missing) > 0 {
+		return "", fmt.Errorf("missing sql variables %s (pass them with --var name=value)", strings.Join(missing, ", "))
+	}
+	return text, nil
+}
+
+func literal(value string) string {
+	if numericText.MatchString(value) {
+		return value
+	}
+	return quote(value)
+}
+
+func quote(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", "''") + "'"
+}
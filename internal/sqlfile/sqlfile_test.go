@@ -0,0 +1,139 @@
+package sqlfile_test
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mickamy/xplain/internal/sqlfile"
+)
+
+func TestRender(t *testing.T) {
+	vars := sqlfile.Vars{}
+	for _, raw := range []string{"tenant_id=42", "status=it's shipped"} {
+		if err := vars.Set(raw); err != nil {
+			t.Fatalf("set %q: %v", raw, err)
+		}
+	}
+
+	tests := []struct {
+		name string
+		sql  string
+		want string
+	}{
+		{"go template", "SELECT * FROM orders WHERE tenant_id = {{ .tenant_id }} AND status = {{ quote .status }}", "SELECT * FROM orders WHERE tenant_id = 42 AND status = 'it''s shipped'"},
+		{"sqlc", "SELECT * FROM orders WHERE tenant_id = sqlc.arg(tenant_id) AND status = sqlc.narg('status')", "SELECT * FROM orders WHERE tenant_id = 42 AND status = 'it''s shipped'"},
+		{"erb", "SELECT * FROM orders WHERE tenant_id = <%= tenant_id %>", "SELECT * FROM orders WHERE tenant_id = 42"},
+		{"plain", "SELECT 1", "SELECT 1"},
+	}
+	for _, tt := range tests {
+		got, err := sqlfile.Render(tt.sql, vars)
+		if err != nil {
+			t.Fatalf("%s: render: %v", tt.name, err)
+		}
+		if got != tt.want {
+			t.Fatalf("%s: expected %q, got %q", tt.name, tt.want, got)
+		}
+	}
+}
+
+func TestRenderTemplateLikeValues(t *testing.T) {
+	vars := sqlfile.Vars{"filter": `{"tags": {{"a"}}}`, "raw": "{{ .filter }}"}
+	tests := map[string]string{
+		"SELECT sqlc.arg(filter)::jsonb":            `SELECT '{"tags": {{"a"}}}'::jsonb`,
+		"SELECT <%= raw %>":                         "SELECT {{ .filter }}",
+		"SELECT {{ quote .filter }}, sqlc.arg(raw)": `SELECT '{"tags": {{"a"}}}', '{{ .filter }}'`,
+	}
+	for sql, want := range tests {
+		got, err := sqlfile.Render(sql, vars)
+		if err != nil {
+			t.Fatalf("render %q: %v", sql, err)
+		}
+		if got != want {
+			t.Fatalf("render %q: expected %q, got %q", sql, want, got)
+		}
+	}
+}
+
+func TestRenderArrayLiteral(t *testing.T) {
+	// Braces that are not a template action, like a 2D array literal, are plain SQL with or without vars.
+	tests := []struct {
+		sql  string
+		vars sqlfile.Vars
+		want string
+	}{
+		{"SELECT '{{1,2},{3,4}}'::int[]", nil, "SELECT '{{1,2},{3,4}}'::int[]"},
+		{"SELECT '{{1,2},{3,4}}'::int[] WHERE id = sqlc.arg(id)", sqlfile.Vars{"id": "7"}, "SELECT '{{1,2},{3,4}}'::int[] WHERE id = 7"},
+		{"SELECT '{{1,2},{3,4}}'::int[], {{ quote .name }}", sqlfile.Vars{"name": "x"}, "SELECT '{{1,2},{3,4}}'::int[], 'x'"},
+	}
+	for _, tt := range tests {
+		got, err := sqlfile.Render(tt.sql, tt.vars)
+		if err != nil {
+			t.Fatalf("render %q: %v", tt.sql, err)
+		}
+		if got != tt.want {
+			t.Fatalf("render %q: expected %q, got %q", tt.sql, tt.want, got)
+		}
+	}
+}
+
+func TestRenderMissingVariable(t *testing.T) {
+	for _, sql := range []string{"SELECT {{ .region }}", "SELECT sqlc.arg(region)", "SELECT <%= region %>"} {
+		_, err := sqlfile.Render(sql, nil)
+		if err == nil || !strings.Contains(err.Error(), "region") {
+			t.Fatalf("expected missing variable error for %q, got %v", sql, err)
+		}
+	}
+	if err := (sqlfile.Vars{}).Set("novalue"); err == nil {
+		t.Fatalf("expected invalid variable to fail")
+	}
+}
+
+func TestParseDirectives(t *testing.T) {
+	sql := `-- xplain: timeout=60s
+-- xplain: expect-index orders_status_idx
+--xplain: max-time 200ms
+-- unrelated comment
+SELECT * FROM orders WHERE status = 'paid'; -- xplain: not-a-directive-mid-line`
+
+	d, err := sqlfile.ParseDirectives(sql)
+	if err != nil {
+		t.Fatalf("parse directives: %v", err)
+	}
+	if d.Timeout != 60*time.Second {
+		t.Fatalf("expected 60s timeout, got %v", d.Timeout)
+	}
+	want := []string{"expect-index orders_status_idx", "max-time 200ms"}
+	if !slices.Equal(d.Assertions, want) {
+		t.Fatalf("expected assertions %v, got %v", want, d.Assertions)
+	}
+
+	if _, err := sqlfile.ParseDirectives("-- xplain: timeout=soon"); err == nil {
+		t.Fatalf("expected invalid timeout to fail")
+	}
+}
+
+func TestSplit(t *testing.T) {
+	src := "-- xplain: timeout=5s\nSELECT ';' AS semi, \"a;b\" FROM t; /* ; */\n" +
+		"CREATE FUNCTION f() RETURNS int AS $body$ SELECT 1; $body$ LANGUAGE sql;\n" +
+		"SELECT $1::int;\n-- trailing note only\n"
+	got := sqlfile.Split(src)
+	if len(got) != 3 {
+		t.Fatalf("expected 3 statements, got %d: %+v", len(got), got)
+	}
+	if !strings.HasPrefix(got[0].Text, "-- xplain: timeout=5s\nSELECT ';'") || !strings.HasSuffix(got[0].Text, "FROM t") {
+		t.Fatalf("unexpected first statement %q", got[0].Text)
+	}
+	if !strings.HasSuffix(got[1].Text, "$body$ LANGUAGE sql") || !strings.HasPrefix(got[1].Text, "/* ; */") {
+		t.Fatalf("unexpected second statement %q", got[1].Text)
+	}
+	for _, stmt := range got {
+		if src[stmt.Start:stmt.End] != stmt.Text {
+			t.Fatalf("offsets %d..%d do not match %q", stmt.Start, stmt.End, stmt.Text)
+		}
+	}
+	if got[2].Text != "SELECT $1::int" {
+		t.Fatalf("unexpected third statement %q", got[2].Text)
+	}
+}
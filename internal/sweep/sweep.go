@@ -0,0 +1,220 @@
+package sweep
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/mickamy/xplain/internal/analyzer"
+	"github.com/mickamy/xplain/internal/model"
+)
+
+// Setting is the server setting the sweep varies.
+const Setting = "max_parallel_workers_per_gather"
+
+// minSpeedup is how much faster than serial a parallel run must be before parallelism counts as helping.
+const minSpeedup = 1.1
+
+// Run is the plan captured with max_parallel_workers_per_gather set to Workers.
+type Run struct {
+	Workers  int
+	Analysis *analyzer.PlanAnalysis
+}
+
+// Report compares the runs of a worker count sweep.
+type Report struct {
+	Entries []Entry `json:"runs"`
+	// Best is the worker count of the fastest run.
+	Best    int    `json:"best_workers"`
+	Verdict string `json:"verdict"`
+}
+
+// Entry summarizes one run of the sweep.
+type Entry struct {
+	Workers int `json:"workers"`
+	// Planned and Launched add up the workers of every Gather and Gather Merge node.
+	Planned     int     `json:"workers_planned"`
+	Launched    int     `json:"workers_launched"`
+	ExecutionMs float64 `json:"execution_ms"`
+	PlanningMs  float64 `json:"planning_ms"`
+	// Speedup is the serial (or first) run's execution time divided by this run's.
+	Speedup float64 `json:"speedup"`
+	Shape   string  `json:"shape"`
+	// SameShape reports whether the plan, ignoring Gather nodes and the Partial/Parallel variants, matches
+	// the serial plan.
+	SameShape bool `json:"same_shape"`
+}
+
+// Compare turns the runs into a report. Speedups are relative to the run with 0 workers, or the first run
+// when the sweep has none.
+func Compare(runs []Run) *Report {
+	report := &Report{}
+	if len(runs) == 0 {
+		return report
+	}
+	baseline := runs[0]
+	for _, run := range runs {
+		if run.Workers == 0 {
+			baseline = run
+			break
+		}
+	}
+	baseMs := executionMs(baseline.Analysis)
+	baseShape := serialShape(baseline.Analysis)
+
+	best := -1
+	for _, run := range runs {
+		entry := Entry{Workers: run.Workers, ExecutionMs: executionMs(run.Analysis)}
+		if run.Analysis != nil {
+			entry.PlanningMs = run.Analysis.PlanningTimeMs
+			if run.Analysis.Root != nil {
+				entry.Shape = Shape(run.Analysis.Root.Node)
+			}
+			for _, n := range run.Analysis.Nodes() {
+				entry.Planned += int(n.Node.WorkersPlanned)
+				entry.Launched += int(n.Node.WorkersLaunched)
+			}
+		}
+		entry.SameShape = serialShape(run.Analysis) == baseShape
+		if entry.ExecutionMs > 0 {
+			entry.Speedup = baseMs / entry.ExecutionMs
+		}
+		report.Entries = append(report.Entries, entry)
+		if best < 0 || entry.ExecutionMs < report.Entries[best].ExecutionMs {
+			best = len(report.Entries) - 1
+		}
+	}
+	report.Best = report.Entries[best].Workers
+	report.Verdict = verdict(report.Entries, report.Entries[best], baseline.Workers)
+	return report
+}
+
+func verdict(entries []Entry, best Entry, baseline int) string {
+	parallel := false
+	for _, e := range entries {
+		if e.Workers != baseline && e.Planned > 0 {
+			parallel = true
+		}
+	}
+	switch {
+	case !parallel:
+		return "The planner chose no parallel plan at any worker count; parallelism does not apply here."
+	case best.Workers == baseline:
+		return fmt.Sprintf("Parallelism does not help: %d workers was fastest.", baseline)
+	case best.Speedup < minSpeedup:
+		return fmt.Sprintf("Parallelism barely helps: %d workers was only %.2fx faster than %d.", best.Workers, best.Speedup, baseline)
+	}
+	text := fmt.Sprintf("Parallelism helps: %d workers ran %.2fx faster than %d.", best.Workers, best.Speedup, baseline)
+	if best.Launched < best.Planned {
+		text += fmt.Sprintf(" Only %d of %d planned workers launched; max_parallel_workers may be exhausted.", best.Launched, best.Planned)
+	}
+	return text
+}
+
+// Shape renders a plan tree compactly, e.g. "Gather(Parallel Seq Scan on orders)".
+func Shape(node *model.PlanNode) string {
+	var b strings.Builder
+	writeShape(&b, node, false)
+	return b.String()
+}
+
+// serialShape renders the plan with the parallel machinery removed, so runs that only added workers
+// to the same strategy compare equal.
+func serialShape(analysis *analyzer.PlanAnalysis) string {
+	if analysis == nil || analysis.Root == nil {
+		return ""
+	}
+	var b strings.Builder
+	writeShape(&b, analysis.Root.Node, true)
+	return b.String()
+}
+
+func writeShape(b *strings.Builder, node *model.PlanNode, serial bool) {
+	if node == nil {
+		return
+	}
+	if serial && (node.NodeType == "Gather" || node.NodeType == "Gather Merge") && len(node.Children) == 1 {
+		writeShape(b, node.Children[0], serial)
+		return
+	}
+	name := node.NodeType
+	if serial {
+		name = strings.TrimPrefix(name, "Parallel ")
+		if node.PartialMode == "Partial" {
+			// The Finalize node above already stands for the aggregate the serial plan runs.
+			b.WriteString(shapeChildren(node, serial))
+			return
+		}
+	}
+	b.WriteString(name)
+	if node.RelationName != "" {
+		b.WriteString(" on " + node.RelationName)
+	}
+	if len(node.Children) > 0 {
+		b.WriteString("(" + shapeChildren(node, serial) + ")")
+	}
+}
+
+func shapeChildren(node *model.PlanNode, serial bool) string {
+	parts := make([]string, 0, len(node.Children))
+	for _, child := range node.Children {
+		var b strings.Builder
+		writeShape(&b, child, serial)
+		parts = append(parts, b.String())
+	}
+	return strings.Join(parts, ", ")
+}
+
+func executionMs(analysis *analyzer.PlanAnalysis) float64 {
+	if analysis == nil {
+		return 0
+	}
+	if analysis.ExecutionTimeMs > 0 {
+		return analysis.ExecutionTimeMs
+	}
+	return analysis.TotalTimeMs
+}
+
+// Markdown renders the comparison as a Markdown document.
+func (r *Report) Markdown() string {
+	var b strings.Builder
+	_, _ = fmt.Fprintf(&b, "# xplain parallel sweep (%s)\n\n", Setting)
+	b.WriteString("| Workers | Planned | Launched | Execution (ms) | Speedup | Plan |\n")
+	b.WriteString("|---:|---:|---:|---:|---:|---|\n")
+	for _, e := range r.Entries {
+		plan := "`" + e.Shape + "`"
+		if !e.SameShape {
+			plan += " (different strategy)"
+		}
+		_, _ = fmt.Fprintf(&b, "| %d | %d | %d | %.3f | %.2fx | %s |\n", e.Workers, e.Planned, e.Launched, e.ExecutionMs, e.Speedup, plan)
+	}
+	if r.Verdict != "" {
+		_, _ = fmt.Fprintf(&b, "\n%s\n", r.Verdict)
+	}
+	return b.String()
+}
+
+// Text renders the comparison as an aligned terminal table.
+func (r *Report) Text() string {
+	var b strings.Builder
+	_, _ = fmt.Fprintf(&b, "%7s %7s %8s %14s %8s  %s\n", "Workers", "Planned", "Launched", "Execution ms", "Speedup", "Plan")
+	for _, e := range r.Entries {
+		plan := e.Shape
+		if !e.SameShape {
+			plan += "  [different strategy]"
+		}
+		_, _ = fmt.Fprintf(&b, "%7d %7d %8d %14.3f %7.2fx  %s\n", e.Workers, e.Planned, e.Launched, e.ExecutionMs, e.Speedup, plan)
+	}
+	if r.Verdict != "" {
+		_, _ = fmt.Fprintf(&b, "\n%s\n", r.Verdict)
+	}
+	return b.String()
+}
+
+// JSON marshals the report into an indented JSON document.
+func (r *Report) JSON() ([]byte, error) {
+	if r == nil {
+		return nil, fmt.Errorf("nil report")
+	}
+	return json.MarshalIndent(r, "", "  ")
+}
@@ -0,0 +1,65 @@
+package sweep_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mickamy/xplain/internal/analyzer"
+	"github.com/mickamy/xplain/internal/parser"
+	"github.com/mickamy/xplain/internal/sweep"
+)
+
+const (
+	serialPlan = `[{"Plan": {"Node Type": "Aggregate", "Strategy": "Plain", "Actual Total Time": 80.0, "Actual Loops": 1,
+  "Plans": [{"Node Type": "Seq Scan", "Relation Name": "orders", "Actual Total Time": 70.0, "Actual Loops": 1}]},
+  "Planning Time": 0.2, "Execution Time": 80.5}]`
+	parallelPlan = `[{"Plan": {"Node Type": "Aggregate", "Strategy": "Plain", "Partial Mode": "Finalize", "Actual Total Time": 30.0, "Actual Loops": 1,
+  "Plans": [{"Node Type": "Gather", "Workers Planned": 2, "Workers Launched": 2, "Actual Total Time": 29.0, "Actual Loops": 1,
+    "Plans": [{"Node Type": "Aggregate", "Strategy": "Plain", "Partial Mode": "Partial", "Actual Total Time": 25.0, "Actual Loops": 3,
+      "Plans": [{"Node Type": "Seq Scan", "Parallel Aware": true, "Relation Name": "orders", "Actual Total Time": 22.0, "Actual Loops": 3}]}]}]},
+  "Planning Time": 0.3, "Execution Time": 32.2}]`
+)
+
+func analyze(t *testing.T, input string) *analyzer.PlanAnalysis {
+	t.Helper()
+	plan, err := parser.Parse(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	analysis, err := analyzer.Analyze(plan)
+	if err != nil {
+		t.Fatalf("analyze: %v", err)
+	}
+	return analysis
+}
+
+func TestCompare(t *testing.T) {
+	report := sweep.Compare([]sweep.Run{
+		{Workers: 0, Analysis: analyze(t, serialPlan)},
+		{Workers: 2, Analysis: analyze(t, parallelPlan)},
+	})
+	if len(report.Entries) != 2 || report.Best != 2 {
+		t.Fatalf("expected 2 workers to win, got %+v", report)
+	}
+	serial, parallel := report.Entries[0], report.Entries[1]
+	if serial.Speedup != 1 || parallel.Speedup < 2.4 || parallel.Speedup > 2.6 {
+		t.Fatalf("unexpected speedups %v and %v", serial.Speedup, parallel.Speedup)
+	}
+	if parallel.Planned != 2 || parallel.Launched != 2 || !parallel.SameShape {
+		t.Fatalf("expected the parallel run to keep the serial strategy with 2 workers, got %+v", parallel)
+	}
+	if !strings.HasPrefix(report.Verdict, "Parallelism helps: 2 workers") {
+		t.Fatalf("unexpected verdict %q", report.Verdict)
+	}
+	if md := report.Markdown(); !strings.Contains(md, "`Aggregate(Gather(Aggregate(Seq Scan on orders)))`") {
+		t.Fatalf("expected the plan shape in the markdown, got:\n%s", md)
+	}
+
+	same := sweep.Compare([]sweep.Run{
+		{Workers: 0, Analysis: analyze(t, serialPlan)},
+		{Workers: 4, Analysis: analyze(t, serialPlan)},
+	})
+	if !strings.Contains(same.Verdict, "no parallel plan") {
+		t.Fatalf("expected no parallel plan verdict, got %q", same.Verdict)
+	}
+}
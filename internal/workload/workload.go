@@ -0,0 +1,309 @@
+package workload
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/mickamy/xplain/internal/advisor"
+	"github.com/mickamy/xplain/internal/analyzer"
+	"github.com/mickamy/xplain/internal/insight"
+)
+
+// maxRows caps each Markdown table; the JSON report keeps every entry.
+const maxRows = 10
+
+// Plan is one analyzed plan of the workload, labelled by the file it was loaded from.
+type Plan struct {
+	Path     string
+	Analysis *analyzer.PlanAnalysis
+}
+
+// Report aggregates where time goes across a set of plans.
+type Report struct {
+	Plans     int         `json:"plans"`
+	TotalMs   float64     `json:"total_ms"`
+	Relations []Bucket    `json:"relations"`
+	Operators []Bucket    `json:"operators"`
+	Hotspots  []Hotspot   `json:"hotspots"`
+	Indexes   []Candidate `json:"index_candidates"`
+	// Consolidated is the smallest set of indexes found that serves every candidate.
+	Consolidated []Consolidation `json:"consolidated_indexes"`
+}
+
+// Bucket is the exclusive time spent on one relation or operator type across all plans.
+type Bucket struct {
+	Name    string  `json:"name"`
+	TimeMs  float64 `json:"time_ms"`
+	Percent float64 `json:"percent"`
+	Plans   int     `json:"plans"`
+}
+
+// Hotspot is an operator that ranks among the hottest nodes of one or more plans.
+type Hotspot struct {
+	Signature string   `json:"signature"`
+	TimeMs    float64  `json:"time_ms"`
+	Plans     []string `json:"plans"`
+}
+
+// Candidate is a CREATE INDEX proposal together with the plans whose insights suggested it.
+type Candidate struct {
+	DDL   string        `json:"ddl"`
+	Rules []string      `json:"rules"`
+	Plans []string      `json:"plans"`
+	Index advisor.Index `json:"-"`
+}
+
+// Consolidation is one index of the covering set, the candidates it replaces and the plans it benefits.
+type Consolidation struct {
+	DDL      string   `json:"ddl"`
+	Replaces []string `json:"replaces"`
+	Plans    []string `json:"plans"`
+}
+
+// Summarize aggregates the plans into a workload report.
+func Summarize(plans []Plan) *Report {
+	report := &Report{Plans: len(plans)}
+	// Shares are relative to the summed self time: parallel workers can push it past wall-clock time.
+	var selfMs float64
+	relations := newBuckets()
+	operators := newBuckets()
+	hotspots := map[string]*Hotspot{}
+	candidates := map[string]*Candidate{}
+
+	for _, p := range plans {
+		if p.Analysis == nil || p.Analysis.Root == nil {
+			continue
+		}
+		report.TotalMs += p.Analysis.TotalTimeMs
+		walk(p.Analysis.Root, func(n *analyzer.NodeStats) {
+			selfMs += n.ExclusiveTimeMs
+			operators.add(n.Node.NodeType, p.Path, n.ExclusiveTimeMs)
+			if name := relation(n); name != "" {
+				relations.add(name, p.Path, n.ExclusiveTimeMs)
+			}
+		})
+		for _, n := range p.Analysis.HotNodes {
+			sig := signature(n)
+			h, ok := hotspots[sig]
+			if !ok {
+				h = &Hotspot{Signature: sig}
+				hotspots[sig] = h
+			}
+			h.TimeMs += n.ExclusiveTimeMs
+			h.Plans = appendUnique(h.Plans, p.Path)
+		}
+		for _, msg := range insight.BuildMessages(p.Analysis) {
+			if msg.Index == nil || msg.Acknowledged != "" {
+				continue
+			}
+			ddl := msg.Index.DDL()
+			c, ok := candidates[ddl]
+			if !ok {
+				c = &Candidate{DDL: ddl, Index: *msg.Index}
+				candidates[ddl] = c
+			}
+			c.Rules = appendUnique(c.Rules, msg.Rule)
+			c.Plans = appendUnique(c.Plans, p.Path)
+		}
+	}
+
+	report.Relations = relations.sorted(selfMs)
+	report.Operators = operators.sorted(selfMs)
+	for _, h := range hotspots {
+		report.Hotspots = append(report.Hotspots, *h)
+	}
+	sort.Slice(report.Hotspots, func(i, j int) bool {
+		a, b := report.Hotspots[i], report.Hotspots[j]
+		if len(a.Plans) != len(b.Plans) {
+			return len(a.Plans) > len(b.Plans)
+		}
+		if a.TimeMs != b.TimeMs {
+			return a.TimeMs > b.TimeMs
+		}
+		return a.Signature < b.Signature
+	})
+	for _, c := range candidates {
+		report.Indexes = append(report.Indexes, *c)
+	}
+	sort.Slice(report.Indexes, func(i, j int) bool {
+		a, b := report.Indexes[i], report.Indexes[j]
+		if len(a.Plans) != len(b.Plans) {
+			return len(a.Plans) > len(b.Plans)
+		}
+		return a.DDL < b.DDL
+	})
+	report.Consolidated = consolidate(report.Indexes)
+	return report
+}
+
+func consolidate(candidates []Candidate) []Consolidation {
+	byDDL := map[string]Candidate{}
+	proposals := make([]advisor.Index, 0, len(candidates))
+	for _, c := range candidates {
+		byDDL[c.DDL] = c
+		proposals = append(proposals, c.Index)
+	}
+	var out []Consolidation
+	for _, set := range advisor.Consolidate(proposals) {
+		entry := Consolidation{DDL: set.Index.DDL()}
+		for _, served := range set.Serves {
+			c := byDDL[served.DDL()]
+			entry.Replaces = append(entry.Replaces, c.DDL)
+			for _, plan := range c.Plans {
+				entry.Plans = appendUnique(entry.Plans, plan)
+			}
+		}
+		out = append(out, entry)
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		return len(out[i].Plans) > len(out[j].Plans)
+	})
+	return out
+}
+
+// Markdown renders the report as a Markdown document.
+func (r *Report) Markdown() string {
+	var b strings.Builder
+	b.WriteString("# xplain workload\n\n")
+	b.WriteString("## Summary\n")
+	_, _ = fmt.Fprintf(&b, "- Plans: %d\n", r.Plans)
+	_, _ = fmt.Fprintf(&b, "- Total execution: %.3f ms\n\n", r.TotalMs)
+
+	writeBuckets(&b, "Time by relation", "Relation", r.Relations)
+	writeBuckets(&b, "Time by operator", "Operator", r.Operators)
+
+	b.WriteString("### Frequent hotspots\n")
+	if len(r.Hotspots) == 0 {
+		b.WriteString("- None\n")
+	} else {
+		b.WriteString("| Operator | Plans | Self (ms) | Seen in |\n")
+		b.WriteString("|---|---:|---:|---|\n")
+		for _, h := range head(r.Hotspots) {
+			_, _ = fmt.Fprintf(&b, "| %s | %d | %.2f | %s |\n", h.Signature, len(h.Plans), h.TimeMs, strings.Join(h.Plans, ", "))
+		}
+	}
+
+	b.WriteString("\n### Missing-index candidates\n")
+	if len(r.Indexes) == 0 {
+		b.WriteString("- None proposed\n")
+	} else {
+		b.WriteString("| Index | Plans | Rules | Seen in |\n")
+		b.WriteString("|---|---:|---|---|\n")
+		for _, c := range head(r.Indexes) {
+			_, _ = fmt.Fprintf(&b, "| `%s` | %d | %s | %s |\n", c.DDL, len(c.Plans), strings.Join(c.Rules, ", "), strings.Join(c.Plans, ", "))
+		}
+	}
+
+	if len(r.Consolidated) > 0 && len(r.Consolidated) < len(r.Indexes) {
+		_, _ = fmt.Fprintf(&b, "\n### Consolidated index set\n%d indexes serve all %d candidates:\n\n", len(r.Consolidated), len(r.Indexes))
+		b.WriteString("| Index | Replaces | Benefits |\n")
+		b.WriteString("|---|---|---|\n")
+		for _, c := range head(r.Consolidated) {
+			replaces := "–"
+			if len(c.Replaces) > 1 || c.Replaces[0] != c.DDL {
+				replaces = "`" + strings.Join(c.Replaces, "`<br>`") + "`"
+			}
+			_, _ = fmt.Fprintf(&b, "| `%s` | %s | %s |\n", c.DDL, replaces, strings.Join(c.Plans, ", "))
+		}
+	}
+	return b.String()
+}
+
+// JSON marshals the workload report into an indented JSON document.
+func (r *Report) JSON() ([]byte, error) {
+	if r == nil {
+		return nil, fmt.Errorf("nil report")
+	}
+	return json.MarshalIndent(r, "", "  ")
+}
+
+func writeBuckets(b *strings.Builder, title, label string, buckets []Bucket) {
+	_, _ = fmt.Fprintf(b, "### %s\n", title)
+	if len(buckets) == 0 {
+		b.WriteString("- None\n\n")
+		return
+	}
+	_, _ = fmt.Fprintf(b, "| %s | Self (ms) | Share | Plans |\n", label)
+	b.WriteString("|---|---:|---:|---:|\n")
+	for _, bucket := range head(buckets) {
+		_, _ = fmt.Fprintf(b, "| %s | %.2f | %.1f%% | %d |\n", bucket.Name, bucket.TimeMs, bucket.Percent, bucket.Plans)
+	}
+	b.WriteString("\n")
+}
+
+func head[T any](items []T) []T {
+	if len(items) > maxRows {
+		return items[:maxRows]
+	}
+	return items
+}
+
+type buckets struct {
+	time  map[string]float64
+	plans map[string][]string
+}
+
+func newBuckets() buckets {
+	return buckets{time: map[string]float64{}, plans: map[string][]string{}}
+}
+
+func (b buckets) add(name, plan string, ms float64) {
+	b.time[name] += ms
+	b.plans[name] = appendUnique(b.plans[name], plan)
+}
+
+func (b buckets) sorted(total float64) []Bucket {
+	out := make([]Bucket, 0, len(b.time))
+	for name, ms := range b.time {
+		bucket := Bucket{Name: name, TimeMs: ms, Plans: len(b.plans[name])}
+		if total > 0 {
+			bucket.Percent = ms / total * 100
+		}
+		out = append(out, bucket)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].TimeMs != out[j].TimeMs {
+			return out[i].TimeMs > out[j].TimeMs
+		}
+		return out[i].Name < out[j].Name
+	})
+	return out
+}
+
+func relation(n *analyzer.NodeStats) string {
+	if n.Node.RelationName == "" {
+		return ""
+	}
+	if n.Node.Schema != "" {
+		return n.Node.Schema + "." + n.Node.RelationName
+	}
+	return n.Node.RelationName
+}
+
+func signature(n *analyzer.NodeStats) string {
+	if name := relation(n); name != "" {
+		return n.Node.NodeType + " on " + name
+	}
+	return n.Node.NodeType
+}
+
+func walk(n *analyzer.NodeStats, fn func(*analyzer.NodeStats)) {
+	if n == nil || n.Node == nil {
+		return
+	}
+	fn(n)
+	for _, child := range n.Children {
+		walk(child, fn)
+	}
+}
+
+func appendUnique(list []string, value string) []string {
+	for _, v := range list {
+		if v == value {
+			return list
+		}
+	}
+	return append(list, value)
+}
@@ -0,0 +1,57 @@
+package workload_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/mickamy/xplain/internal/workload"
+	"github.com/mickamy/xplain/test"
+)
+
+func TestSummarize(t *testing.T) {
+	var plans []workload.Plan
+	for _, name := range []string{"pgbench_hot.json", "nested_loop_noindex.json", "pagination_offset.json", "sort_orders.json"} {
+		plans = append(plans, workload.Plan{Path: name, Analysis: test.LoadSampleAnalysis(t, name)})
+	}
+
+	report := workload.Summarize(plans)
+	if report.Plans != 4 {
+		t.Fatalf("expected 4 plans, got %d", report.Plans)
+	}
+	if len(report.Relations) == 0 || report.Relations[0].Name != "pgbench_accounts" || report.Relations[0].Plans != 2 {
+		t.Fatalf("expected pgbench_accounts to lead the relations across 2 plans, got %+v", report.Relations)
+	}
+	if len(report.Hotspots) == 0 || len(report.Hotspots[0].Plans) < 2 {
+		t.Fatalf("expected the top hotspot to recur across plans, got %+v", report.Hotspots)
+	}
+	if len(report.Indexes) == 0 {
+		t.Fatalf("expected index candidates")
+	}
+	shared := report.Indexes[0]
+	if shared.DDL != "CREATE INDEX ON pgbench_accounts (bid, abalance DESC);" || len(shared.Plans) != 2 {
+		t.Fatalf("expected the pgbench_accounts index to be shared by 2 plans, got %+v", shared)
+	}
+
+	if len(report.Consolidated) != len(report.Indexes) {
+		t.Fatalf("expected no overlapping candidates to merge, got %+v", report.Consolidated)
+	}
+
+	md := report.Markdown()
+	for _, want := range []string{"# xplain workload", "### Time by relation", "### Frequent hotspots", "`CREATE INDEX ON orders (status, created_at DESC);`"} {
+		if !strings.Contains(md, want) {
+			t.Fatalf("expected %q in markdown:\n%s", want, md)
+		}
+	}
+}
+
+func TestMarkdownCapsIndexCandidates(t *testing.T) {
+	report := &workload.Report{}
+	for i := range 12 {
+		report.Indexes = append(report.Indexes, workload.Candidate{DDL: fmt.Sprintf("CREATE INDEX ON t%d (id);", i), Plans: []string{"a.json"}})
+	}
+	md := report.Markdown()
+	if !strings.Contains(md, "CREATE INDEX ON t9 (id);") || strings.Contains(md, "CREATE INDEX ON t10 (id);") {
+		t.Fatalf("expected the candidates table capped at 10 rows:\n%s", md)
+	}
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/mickamy/xplain/internal/analyzer"
+	"github.com/mickamy/xplain/internal/assert"
+	"github.com/mickamy/xplain/internal/ignore"
+	"github.com/mickamy/xplain/internal/lsp"
+	"github.com/mickamy/xplain/internal/parser"
+	"github.com/mickamy/xplain/internal/runner"
+	"github.com/mickamy/xplain/internal/sqlfile"
+)
+
+func lspCommand(args []string) error {
+	fs := flag.NewFlagSet("lsp", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	fs.Usage = func() {
+		_, _ = fmt.Fprintf(os.Stdout, "Usage: xplain lsp --url <url> [--on-save] [--allow-writes]\n\n"+
+			"Speaks the Language Server Protocol on stdin/stdout. Editors run the \"xplain.analyze\" code action on a\n"+
+			"statement (or save the file with --on-save) to see its insights as diagnostics.\n\nOptions:\n")
+		fs.PrintDefaults()
+	}
+
+	var (
+		urlFlag     = fs.String("url", os.Getenv("DATABASE_URL"), "PostgreSQL connection string of the development database; defaults to $DATABASE_URL")
+		service     = fs.String("service", "", "Connection service name from ~/.pg_service.conf or $PGSERVICEFILE; overrides --url")
+		onSave      = fs.Bool("on-save", false, "Analyze every statement of a file when it is saved")
+		allowWrites = fs.Bool("allow-writes", false, "Also analyze data-modifying statements (EXPLAIN ANALYZE executes them)")
+		timeout     = fs.Duration("timeout", 30*time.Second, "Per-statement timeout, unless a -- xplain: timeout= directive sets one")
+		verbose     = fs.Bool("verbose", false, "Run EXPLAIN VERBOSE to capture per-node output columns")
+		useCatalog  = fs.Bool("catalog", true, "Inspect existing indexes on scanned tables to refine index advice")
+		ignoreFile  = fs.String("ignore-file", ignore.DefaultPath, "File acknowledging insights by rule and node selector")
+		configPath  = fs.String("config", "", "Path to configuration file (JSON). Falls back to $XPLAIN_CONFIG")
+		vars        = sqlfile.Vars{}
+	)
+	fs.Var(vars, "var", "SQL template variable as name=value (repeatable)")
+
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			fs.SetOutput(os.Stdout)
+			fs.Usage()
+			return nil
+		}
+		return err
+	}
+	if err := applyConfigPath(*configPath); err != nil {
+		return err
+	}
+	connection := resolveConnection(*urlFlag, *service)
+	if connection == "" {
+		return fmt.Errorf("--url or --service is required, or set $DATABASE_URL or $PGSERVICE")
+	}
+	acks, err := ignore.Load(*ignoreFile, *ignoreFile == ignore.DefaultPath)
+	if err != nil {
+		return err
+	}
+
+	analyze := func(ctx context.Context, sql string) (*lsp.Report, error) {
+		sqlText, err := sqlfile.Render(sql, vars)
+		if err != nil {
+			return nil, err
+		}
+		directiveTimeout, assertions, err := sqlChecks(sqlText)
+		if err != nil {
+			return nil, err
+		}
+		limit := *timeout
+		if directiveTimeout > 0 {
+			limit = directiveTimeout
+		}
+		result, err := runner.Run(ctx, connection, sqlText, runner.Options{
+			Timeout: limit, Verbose: *verbose, ReadOnly: !*allowWrites,
+		})
+		if err != nil {
+			return nil, err
+		}
+		plan, err := parser.ParseJSON(bytes.NewReader(result.Plan))
+		if err != nil {
+			return nil, err
+		}
+		plan.Metadata = &result.Metadata
+		if plan.QueryText == "" {
+			plan.QueryText = sqlText
+		}
+		opts, err := analysisOptions()
+		if err != nil {
+			return nil, err
+		}
+		analysis, err := analyzer.AnalyzeWith(plan, opts)
+		if err != nil {
+			return nil, err
+		}
+		if *useCatalog {
+			attachCatalog(ctx, connection, analysis, limit)
+		}
+		analysis.Acknowledgements = acks
+		return &lsp.Report{Analysis: analysis, Assertions: assert.Evaluate(analysis, assertions)}, nil
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	version, _ := resolveVersion()
+	return lsp.Serve(ctx, os.Stdin, os.Stdout, lsp.Options{
+		Analyze:     analyze,
+		OnSave:      *onSave,
+		AllowWrites: *allowWrites,
+		Version:     version,
+	})
+}
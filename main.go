@@ -9,17 +9,31 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"path"
+	"path/filepath"
 	"runtime/debug"
 	"strings"
+	"time"
 
 	"github.com/mickamy/xplain/internal/analyzer"
+	"github.com/mickamy/xplain/internal/assert"
+	"github.com/mickamy/xplain/internal/catalog"
+	"github.com/mickamy/xplain/internal/clipboard"
 	"github.com/mickamy/xplain/internal/config"
 	"github.com/mickamy/xplain/internal/diff"
+	"github.com/mickamy/xplain/internal/focus"
+	"github.com/mickamy/xplain/internal/history"
+	"github.com/mickamy/xplain/internal/ignore"
+	"github.com/mickamy/xplain/internal/insight"
 	"github.com/mickamy/xplain/internal/model"
+	"github.com/mickamy/xplain/internal/numfmt"
 	"github.com/mickamy/xplain/internal/parser"
+	"github.com/mickamy/xplain/internal/pglog"
+	"github.com/mickamy/xplain/internal/redact"
 	"github.com/mickamy/xplain/internal/render/html"
 	"github.com/mickamy/xplain/internal/render/tui"
 	"github.com/mickamy/xplain/internal/runner"
+	"github.com/mickamy/xplain/internal/sqlfile"
 )
 
 var version = "dev"
@@ -43,6 +57,36 @@ func main() {
 		err = reportCommand(args)
 	case "diff":
 		err = diffCommand(args)
+	case "extract":
+		err = extractCommand(args)
+	case "ci":
+		err = ciCommand(args)
+	case "changed":
+		err = changedCommand(args)
+	case "manifest":
+		err = manifestCommand(args)
+	case "workload":
+		err = workloadCommand(args)
+	case "parallel":
+		err = parallelCommand(args)
+	case "prepared":
+		err = preparedCommand(args)
+	case "env":
+		err = envCommand(args)
+	case "query":
+		err = queryCommand(args)
+	case "lsp":
+		err = lspCommand(args)
+	case "stats":
+		err = statsCommand(args)
+	case "score":
+		err = scoreCommand(args)
+	case "thresholds":
+		err = thresholdsCommand(args)
+	case "config":
+		err = configCommand(args)
+	case "self-update":
+		err = selfUpdateCommand(args)
 	case "version":
 		err = versionCommand(args)
 	case "help", "-h", "--help":
@@ -55,7 +99,11 @@ func main() {
 	}
 
 	if err != nil {
-		_, _ = fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		_, _ = fmt.Fprintf(os.Stderr, "Error: %v\n", redact.Error(err))
+		var exitErr *exitCodeError
+		if errors.As(err, &exitErr) {
+			os.Exit(exitErr.code)
+		}
 		os.Exit(1)
 	}
 }
@@ -67,11 +115,26 @@ Usage:
   xplain <command> [options]
 
 Commands:
-  run      Execute EXPLAIN (ANALYZE, BUFFERS, FORMAT JSON) for a query
-  analyze  Run EXPLAIN and render a report in one step
-  report   Render a plan report (TUI or HTML)
-  diff     Compare two plans and emit a Markdown summary
-  version  Show CLI version information
+  run         Execute EXPLAIN (ANALYZE, BUFFERS, FORMAT JSON) for a query
+  analyze     Run EXPLAIN and render a report in one step
+  report      Render a plan report (TUI or HTML)
+  diff        Compare two plans and emit a Markdown summary
+  extract     Write a plan's subtree as a standalone plan JSON
+  ci          Check changed .sql files against stored baseline plans
+  changed     List .sql files changed between two git refs
+  manifest    Analyze or diff every query listed in a manifest
+  workload    Aggregate time, hotspots and index candidates across many plans
+  parallel    Rerun a query at several parallel worker counts and compare time and plan shape
+  prepared    Execute a prepared statement repeatedly and report generic plan flips
+  env         Snapshot planner settings, extensions and table statistics ages for diff
+  query       Register named queries that other commands accept as --name
+  lsp         Serve plan diagnostics for SQL files to editors (Language Server Protocol)
+  stats       Summarize saved runs: statements analyzed, regressions caught, improvements
+  score       Grade a plan from 0 to 100, optionally as a shields.io badge
+  thresholds  Learn per-query diff thresholds from history and write them to the config
+  config      Validate a configuration file or write a starter one
+  self-update Replace this binary with the latest checksum-verified release
+  version     Show CLI version information
 
 Use "xplain <command> -h" for command-specific help.`)
 }
@@ -84,11 +147,38 @@ func applyConfigPath(path string) error {
 	return config.Apply(path)
 }
 
+// resolveConnection picks the connection string for commands that talk to PostgreSQL: --service wins, then
+// --url (or $DATABASE_URL), then $PGSERVICE. A password missing from either is looked up in ~/.pgpass (or
+// $PGPASSFILE) when connecting, as psql does.
+func resolveConnection(url, service string) string {
+	if service = strings.TrimSpace(service); service != "" {
+		return runner.ServiceDSN(service)
+	}
+	if url = strings.TrimSpace(url); url != "" {
+		return url
+	}
+	if service = strings.TrimSpace(os.Getenv("PGSERVICE")); service != "" {
+		return runner.ServiceDSN(service)
+	}
+	return ""
+}
+
+// countSet counts the non-empty values, for flags that are mutually exclusive.
+func countSet(values ...string) int {
+	n := 0
+	for _, v := range values {
+		if v != "" {
+			n++
+		}
+	}
+	return n
+}
+
 func runCommand(args []string) error {
 	fs := flag.NewFlagSet("run", flag.ContinueOnError)
 	fs.SetOutput(io.Discard)
 	fs.Usage = func() {
-		_, _ = fmt.Fprintf(os.Stdout, "Usage: xplain run --url <url> --sql <file> [--out plan.json]\n\nOptions:\n")
+		_, _ = fmt.Fprintf(os.Stdout, "Usage: xplain run --url <url> (--sql <file> | --name <name>) [--out plan.json] [--raw] [--check]\n\nOptions:\n")
 		fs.PrintDefaults()
 	}
 
@@ -96,11 +186,19 @@ func runCommand(args []string) error {
 
 	var (
 		urlFlag    = fs.String("url", envURL, "PostgreSQL connection string; defaults to $DATABASE_URL")
+		service    = fs.String("service", "", "Connection service name from ~/.pg_service.conf or $PGSERVICEFILE; overrides --url")
 		sqlPath    = fs.String("sql", "", "Path to the SQL file to EXPLAIN")
-		outPath    = fs.String("out", "", "Path to write the resulting JSON (defaults to stdout)")
+		queryName  = fs.String("name", "", "Name of a query registered with xplain query add, instead of --sql")
+		outPath    = fs.String("out", "", "Path to write the resulting JSON, or \"clipboard\" (defaults to stdout)")
+		raw        = fs.Bool("raw", false, "Write plain EXPLAIN JSON without the xplain metadata envelope")
+		verbose    = fs.Bool("verbose", false, "Run EXPLAIN VERBOSE to capture per-node output columns")
+		clientTime = fs.Bool("client-time", false, "Also run the statement read-only, fetching every row, to measure the client round trip")
+		checkOnly  = fs.Bool("check", false, "Only check that the role can EXPLAIN the statement (privileges and relations) without running it")
 		timeout    = fs.Duration("timeout", 0, "Optional execution timeout, e.g. 45s")
 		configPath = fs.String("config", "", "Path to configuration file (JSON). Falls back to $XPLAIN_CONFIG")
+		vars       = sqlfile.Vars{}
 	)
+	fs.Var(vars, "var", "SQL template variable as name=value (repeatable)")
 
 	if err := fs.Parse(args); err != nil {
 		if errors.Is(err, flag.ErrHelp) {
@@ -116,42 +214,97 @@ func runCommand(args []string) error {
 	if err := applyConfigPath(*configPath); err != nil {
 		return err
 	}
-	connection := strings.TrimSpace(*urlFlag)
+	connection := resolveConnection(*urlFlag, *service)
 	if connection == "" {
-		return fmt.Errorf("--url is required or set $DATABASE_URL")
+		return fmt.Errorf("--url or --service is required, or set $DATABASE_URL or $PGSERVICE")
 	}
-	if *sqlPath == "" {
-		return fmt.Errorf("--sql is required")
+	var (
+		sqlText string
+		err     error
+	)
+	switch {
+	case *sqlPath != "" && *queryName != "":
+		return fmt.Errorf("specify only one of --sql or --name")
+	case *sqlPath != "":
+		sqlText, err = sqlfile.Load(*sqlPath, vars)
+	case *queryName != "":
+		sqlText, err = loadNamedQuery(*queryName, vars)
+	default:
+		return fmt.Errorf("--sql or --name is required")
 	}
-
-	sqlBytes, err := os.ReadFile(*sqlPath)
 	if err != nil {
-		return fmt.Errorf("read sql file: %w", err)
+		return err
+	}
+	directiveTimeout, assertions, err := sqlChecks(sqlText)
+	if err != nil {
+		return err
+	}
+	if *timeout == 0 {
+		*timeout = directiveTimeout
 	}
 
 	ctx := context.Background()
-	result, err := runner.Run(ctx, connection, string(sqlBytes), runner.Options{Timeout: *timeout})
+	if *checkOnly {
+		meta, err := runner.Check(ctx, connection, sqlText, runner.Options{Timeout: *timeout})
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(os.Stdout, "Preflight passed (%s): the role can EXPLAIN the statement.\n", meta.Summary())
+		return err
+	}
+	result, err := runner.Run(ctx, connection, sqlText, runner.Options{Timeout: *timeout, Verbose: *verbose, ClientTiming: *clientTime})
 	if err != nil {
 		return err
 	}
 
-	pretty, err := indentJSON(result)
+	var pretty []byte
+	if *raw {
+		pretty, err = indentJSON(result.Plan)
+	} else {
+		pretty, err = result.Envelope()
+	}
 	if err != nil {
 		return err
 	}
 
 	if *outPath == "" {
 		_, err = os.Stdout.Write(pretty)
+	} else {
+		err = writeOutput(*outPath, pretty)
+	}
+	if err != nil || len(assertions) == 0 {
 		return err
 	}
-	return os.WriteFile(*outPath, pretty, 0o644)
+	return runAssertions(result, sqlText, assertions)
+}
+
+// runAssertions checks the "-- xplain: assert" directives of a statement against its captured plan, as
+// analyze does, reporting them on stderr so the plan written to stdout stays valid JSON.
+func runAssertions(result *runner.Result, sqlText string, assertions []assert.Assertion) error {
+	plan, err := parser.ParseJSON(bytes.NewReader(result.Plan))
+	if err != nil {
+		return err
+	}
+	plan.Metadata = &result.Metadata
+	if plan.QueryText == "" {
+		plan.QueryText = sqlText
+	}
+	opts, err := analysisOptions()
+	if err != nil {
+		return err
+	}
+	analysis, err := analyzer.AnalyzeWith(plan, opts)
+	if err != nil {
+		return err
+	}
+	return reportAssertions(os.Stderr, assert.Evaluate(analysis, assertions))
 }
 
 func analyzeCommand(args []string) error {
 	fs := flag.NewFlagSet("analyze", flag.ContinueOnError)
 	fs.SetOutput(io.Discard)
 	fs.Usage = func() {
-		_, _ = fmt.Fprintf(os.Stdout, "Usage: xplain analyze --url <url> (--sql file.sql | --query \"SELECT ...\") [--mode tui|html]\n\nOptions:\n")
+		_, _ = fmt.Fprintf(os.Stdout, "Usage: xplain analyze --url <url> (--sql file.sql | --query \"SELECT ...\" | --name <name>) [--mode tui|html]\n\nOptions:\n")
 		fs.PrintDefaults()
 	}
 
@@ -159,18 +312,40 @@ func analyzeCommand(args []string) error {
 
 	var (
 		urlFlag    = fs.String("url", envURL, "PostgreSQL connection string; defaults to $DATABASE_URL")
+		service    = fs.String("service", "", "Connection service name from ~/.pg_service.conf or $PGSERVICEFILE; overrides --url")
 		sqlPath    = fs.String("sql", "", "Path to the SQL file to EXPLAIN")
 		inlineSQL  = fs.String("query", "", "Inline SQL string to EXPLAIN")
+		queryName  = fs.String("name", "", "Name of a query registered with xplain query add, instead of --sql or --query")
 		mode       = fs.String("mode", "tui", "Output mode: tui or html")
-		outPath    = fs.String("out", "", "Output path (stdout if omitted)")
-		title      = fs.String("title", "xplain report", "Report title (HTML)")
-		color      = fs.Bool("color", true, "Enable ANSI colors for TUI output")
+		outPath    = fs.String("out", "", "Output path, or \"clipboard\" (stdout if omitted)")
+		title      = fs.String("title", "xplain report", "Report title (HTML); supports {{.Fingerprint}}, {{.Date}}, {{.Time}}, {{.Database}}, {{.Host}}, {{.User}}, {{.ServerVersion}}")
+		color      = fs.Bool("color", false, "Force ANSI colors on or off for TUI output (detected from the terminal when omitted)")
 		maxDepth   = fs.Int("max-depth", 0, "Limit tree depth (TUI)")
 		warnings   = fs.Bool("warnings", true, "Show warnings (TUI)")
 		includeCSS = fs.Bool("css", true, "Include inline styles (HTML)")
+		contrast   = fs.Bool("high-contrast", false, "Use solid borders and a colour-blind safe palette instead of tinted heat gradients (HTML)")
+		locale     = fs.String("locale", "", "Digit grouping and decimal separator for times, rows and sizes, e.g. en, de, fr_FR, or auto for $LANG")
+		units      = fs.String("units", "", "Time units: ms, s, or auto for seconds and minutes as times grow (default auto in the TUI, ms in HTML)")
+		precision  = fs.Int("precision", -1, "Decimals for times in the TUI (default 3 in the header, 2 per node)")
+		severity   = fs.String("min-severity", "", "Only show insights at least this urgent: info, warning or critical (default all)")
+		timing     = fs.String("parallel-time", "", "Time of nodes below a Gather: wall for elapsed time, cpu to sum every process (default from config, wall)")
+		hotBy      = fs.String("hot-by", "", "Rank hot nodes by exclusive (self) time, inclusive time, own buffers or io time (default from config, exclusive)")
+		hotLimit   = fs.Int("hot-limit", 0, "Keep at most this many hot nodes (default from config, 5)")
+		hotMin     = fs.Float64("hot-min-percent", 0, "Share of the ranking a node needs to count as hot, e.g. 0.05, or 0 for no threshold (default from config, 0.10)")
+		percentOf  = fs.String("percent-of", "", "Base of node percentages: root for the plan root's time, execution for the reported Execution Time (default root)")
+		verbose    = fs.Bool("verbose", false, "Run EXPLAIN VERBOSE to capture per-node output columns")
+		clientTime = fs.Bool("client-time", false, "Also run the statement read-only, fetching every row, to measure the client round trip")
 		timeout    = fs.Duration("timeout", 0, "Optional execution timeout, e.g. 45s")
+		useCatalog = fs.Bool("catalog", true, "Inspect existing indexes on scanned tables to refine index advice")
+		save       = fs.Bool("save", false, "Also save the plan envelope, an HTML report and a history entry")
+		saveDir    = fs.String("save-dir", "", "Output directory for --save (default from config, .xplain)")
+		ignoreFile = fs.String("ignore-file", ignore.DefaultPath, "File acknowledging insights by rule and node selector")
 		configPath = fs.String("config", "", "Path to configuration file (JSON). Falls back to $XPLAIN_CONFIG")
+		vars       = sqlfile.Vars{}
+		checks     assert.List
 	)
+	fs.Var(vars, "var", "SQL template variable as name=value (repeatable)")
+	fs.Var(&checks, "assert", "Assertion such as 'execution_time_ms < 100' or 'no-seq-scan:orders' (repeatable)")
 
 	if err := fs.Parse(args); err != nil {
 		if errors.Is(err, flag.ErrHelp) {
@@ -183,98 +358,200 @@ func analyzeCommand(args []string) error {
 	if err := applyConfigPath(*configPath); err != nil {
 		return err
 	}
+	if err := useParallelTime(*timing); err != nil {
+		return err
+	}
+	if err := useHotSelection(fs, *hotBy, *hotLimit, *hotMin); err != nil {
+		return err
+	}
+	numbers, err := numberFormat(*locale, *units)
+	if err != nil {
+		return err
+	}
+	minSeverity, err := insight.ParseSeverity(*severity)
+	if err != nil {
+		return err
+	}
+	percentBase, err := analyzer.ParsePercentBase(*percentOf)
+	if err != nil {
+		return err
+	}
 
-	connection := strings.TrimSpace(*urlFlag)
+	connection := resolveConnection(*urlFlag, *service)
 	if connection == "" {
-		return fmt.Errorf("--url is required or set $DATABASE_URL")
+		return fmt.Errorf("--url or --service is required, or set $DATABASE_URL or $PGSERVICE")
 	}
 
-	if *sqlPath != "" && *inlineSQL != "" {
-		return fmt.Errorf("specify only one of --sql or --query")
+	if countSet(*sqlPath, *inlineSQL, *queryName) > 1 {
+		return fmt.Errorf("specify only one of --sql, --query or --name")
 	}
 
 	var sqlText string
 	if *sqlPath != "" {
-		data, err := os.ReadFile(*sqlPath)
+		data, err := sqlfile.Load(*sqlPath, vars)
 		if err != nil {
-			return fmt.Errorf("read sql file: %w", err)
+			return err
 		}
-		sqlText = string(data)
+		sqlText = data
 	} else if *inlineSQL != "" {
-		sqlText = *inlineSQL
+		data, err := sqlfile.Render(*inlineSQL, vars)
+		if err != nil {
+			return err
+		}
+		sqlText = data
+	} else if *queryName != "" {
+		data, err := loadNamedQuery(*queryName, vars)
+		if err != nil {
+			return err
+		}
+		sqlText = data
 	} else {
-		return fmt.Errorf("--sql or --query is required")
+		return fmt.Errorf("--sql, --query or --name is required")
+	}
+	directiveTimeout, assertions, err := sqlChecks(sqlText)
+	if err != nil {
+		return err
 	}
+	if *timeout == 0 {
+		*timeout = directiveTimeout
+	}
+	assertions = append(assertions, checks...)
 
 	ctx := context.Background()
-	result, err := runner.Run(ctx, connection, sqlText, runner.Options{Timeout: *timeout})
+	result, err := runner.Run(ctx, connection, sqlText, runner.Options{Timeout: *timeout, Verbose: *verbose, ClientTiming: *clientTime})
 	if err != nil {
 		return err
 	}
 
-	_, analysis, err := parseAnalysisReader(bytes.NewReader(result))
+	plan, err := parser.ParseJSON(bytes.NewReader(result.Plan))
+	if err != nil {
+		return err
+	}
+	plan.Metadata = &result.Metadata
+	if plan.QueryText == "" {
+		plan.QueryText = sqlText
+	}
+	opts, err := analysisOptions()
 	if err != nil {
 		return err
 	}
+	analysis, err := analyzer.AnalyzeWith(plan, opts)
+	if err != nil {
+		return err
+	}
+	analysis.Name = *queryName
+	if *useCatalog {
+		attachCatalog(ctx, connection, analysis, *timeout)
+	}
+	if analysis.Acknowledgements, err = ignore.Load(*ignoreFile, *ignoreFile == ignore.DefaultPath); err != nil {
+		return err
+	}
+	if *save {
+		dir := *saveDir
+		if dir == "" {
+			dir = config.Active().Output.Dir
+		}
+		if err := saveRun(dir, *queryName, result, analysis, *title); err != nil {
+			return err
+		}
+	}
 
 	switch *mode {
 	case "tui":
 		target := io.Writer(os.Stdout)
 		if *outPath != "" {
-			file, err := os.Create(*outPath)
+			file, err := createOutput(*outPath)
 			if err != nil {
-				return fmt.Errorf("create output: %w", err)
+				return err
 			}
 			defer func() {
 				_ = file.Close()
 			}()
 			target = file
 		}
-		return tui.Render(target, analysis, tui.Options{
-			EnableColor:  *color,
+		if err := tui.Render(target, analysis, tui.Options{
+			EnableColor:  colorChoice(fs, *color, target),
 			MaxDepth:     *maxDepth,
 			ShowWarnings: *warnings,
-		})
+			Numbers:      numbers,
+			Precision:    *precision,
+			MinSeverity:  minSeverity,
+			PercentBase:  percentBase,
+		}); err != nil {
+			return err
+		}
+		if err := finishOutput(target); err != nil {
+			return err
+		}
 	case "html":
 		target := io.Writer(os.Stdout)
 		if *outPath != "" {
-			file, err := os.Create(*outPath)
+			file, err := createOutput(*outPath)
 			if err != nil {
-				return fmt.Errorf("create output: %w", err)
+				return err
 			}
 			defer func() {
 				_ = file.Close()
 			}()
 			target = file
 		}
-		return html.Render(target, analysis, html.Options{
+		if err := html.Render(target, analysis, html.Options{
 			Title:         *title,
 			IncludeStyles: *includeCSS,
-		})
+			HighContrast:  *contrast,
+			Numbers:       numbers,
+			MinSeverity:   minSeverity,
+			PercentBase:   percentBase,
+		}); err != nil {
+			return err
+		}
+		if err := finishOutput(target); err != nil {
+			return err
+		}
 	default:
 		return fmt.Errorf("unknown mode %q (expected tui or html)", *mode)
 	}
+	return reportAssertions(os.Stderr, assert.Evaluate(analysis, assertions))
 }
 
 func reportCommand(args []string) error {
 	fs := flag.NewFlagSet("report", flag.ContinueOnError)
 	fs.SetOutput(io.Discard)
 	fs.Usage = func() {
-		_, _ = fmt.Fprintf(os.Stdout, "Usage: xplain report --input plan.json [--mode tui|html] [--out file]\n\nOptions:\n")
+		_, _ = fmt.Fprintf(os.Stdout, "Usage: xplain report --input plan.json [--from explain|auto-explain|csvlog|pgbadger] [--plan N] [--focus 0.1] [--mode tui|html] [--out file]\n\nOptions:\n")
 		fs.PrintDefaults()
 	}
 
 	var (
-		input      = fs.String("input", "", "Path to EXPLAIN JSON input")
-		output     = fs.String("out", "", "Output path (stdout if omitted)")
+		input      = fs.String("input", "", "Path to EXPLAIN input (JSON, YAML or text), or \"clipboard\"")
+		format     = fs.String("format", "auto", "Input format: auto, json, yaml, text or cockroach")
+		from       = fs.String("from", "explain", "Input source: explain, or auto-explain, csvlog or pgbadger to extract auto_explain plans from a server log, csvlog file or pgBadger JSON export")
+		planIndex  = fs.Int("plan", 0, "1-based plan to report when the input holds several, e.g. a JSON array or auto_explain log (all when omitted; TUI only)")
+		output     = fs.String("out", "", "Output path, or \"clipboard\" (stdout if omitted)")
 		mode       = fs.String("mode", "tui", "Output mode: tui or html")
-		title      = fs.String("title", "xplain report", "Report title (HTML)")
-		color      = fs.Bool("color", true, "Enable ANSI colors for TUI output")
+		title      = fs.String("title", "xplain report", "Report title (HTML); supports {{.Fingerprint}}, {{.Date}}, {{.Time}}, {{.Database}}, {{.Host}}, {{.User}}, {{.ServerVersion}}")
+		color      = fs.Bool("color", false, "Force ANSI colors on or off for TUI output (detected from the terminal when omitted)")
 		maxDepth   = fs.Int("max-depth", 0, "Limit tree depth (TUI)")
 		warnings   = fs.Bool("warnings", true, "Show warnings (TUI)")
 		includeCSS = fs.Bool("css", true, "Include inline styles (HTML)")
+		contrast   = fs.Bool("high-contrast", false, "Use solid borders and a colour-blind safe palette instead of tinted heat gradients (HTML)")
+		locale     = fs.String("locale", "", "Digit grouping and decimal separator for times, rows and sizes, e.g. en, de, fr_FR, or auto for $LANG")
+		units      = fs.String("units", "", "Time units: ms, s, or auto for seconds and minutes as times grow (default auto in the TUI, ms in HTML)")
+		precision  = fs.Int("precision", -1, "Decimals for times in the TUI (default 3 in the header, 2 per node)")
+		severity   = fs.String("min-severity", "", "Only show insights at least this urgent: info, warning or critical (default all)")
+		timing     = fs.String("parallel-time", "", "Time of nodes below a Gather: wall for elapsed time, cpu to sum every process (default from config, wall)")
+		hotBy      = fs.String("hot-by", "", "Rank hot nodes by exclusive (self) time, inclusive time, own buffers or io time (default from config, exclusive)")
+		hotLimit   = fs.Int("hot-limit", 0, "Keep at most this many hot nodes (default from config, 5)")
+		hotMin     = fs.Float64("hot-min-percent", 0, "Share of the ranking a node needs to count as hot, e.g. 0.05, or 0 for no threshold (default from config, 0.10)")
+		percentOf  = fs.String("percent-of", "", "Base of node percentages: root for the plan root's time, execution for the reported Execution Time (default root)")
 		configPath = fs.String("config", "", "Path to configuration file (JSON). Falls back to $XPLAIN_CONFIG")
+		tempLog    = fs.String("temp-log", "", "Server log with log_temp_files entries to attribute on-disk spill sizes")
+		ignoreFile = fs.String("ignore-file", ignore.DefaultPath, "File acknowledging insights by rule and node selector")
+		strict     = fs.Bool("strict", false, "Validate JSON/YAML input against the EXPLAIN schema: unknown top-level keys warn, mistyped or missing fields fail")
+		focusSpec  = fs.String("focus", "", "Render only the subtree under a node: a path such as 0.1.2 or 'relation=orders,type=Seq Scan' (first match)")
+		checks     assert.List
 	)
+	fs.Var(&checks, "assert", "Assertion such as 'execution_time_ms < 100' or 'no-seq-scan:orders' (repeatable)")
 
 	if err := fs.Parse(args); err != nil {
 		if errors.Is(err, flag.ErrHelp) {
@@ -287,70 +564,270 @@ func reportCommand(args []string) error {
 	if err := applyConfigPath(*configPath); err != nil {
 		return err
 	}
+	if err := useParallelTime(*timing); err != nil {
+		return err
+	}
+	if err := useHotSelection(fs, *hotBy, *hotLimit, *hotMin); err != nil {
+		return err
+	}
+	numbers, err := numberFormat(*locale, *units)
+	if err != nil {
+		return err
+	}
+	minSeverity, err := insight.ParseSeverity(*severity)
+	if err != nil {
+		return err
+	}
+	percentBase, err := analyzer.ParsePercentBase(*percentOf)
+	if err != nil {
+		return err
+	}
 	if *input == "" {
 		return fmt.Errorf("--input is required")
 	}
+	var selector *focus.Selector
+	if *focusSpec != "" {
+		s, err := focus.Parse(*focusSpec)
+		if err != nil {
+			return err
+		}
+		selector = &s
+	}
 
-	_, analysis, err := loadAnalysis(*input)
+	plans, err := loadReportPlans(*input, *from, parser.Options{Format: *format, Strict: *strict})
 	if err != nil {
 		return err
 	}
+	if *planIndex < 0 || *planIndex > len(plans) {
+		return fmt.Errorf("--plan %d out of range (input holds %d plans)", *planIndex, len(plans))
+	}
+	if *planIndex > 0 {
+		plans = plans[*planIndex-1 : *planIndex]
+	}
+	acks, err := ignore.Load(*ignoreFile, *ignoreFile == ignore.DefaultPath)
+	if err != nil {
+		return err
+	}
+	var results []assert.Result
+	for i, plan := range plans {
+		if *tempLog != "" {
+			if err := attachTempLog(*tempLog, plan.analysis); err != nil {
+				return err
+			}
+		}
+		plan.analysis.Acknowledgements = acks
+		// Directives travel with the query text of envelopes and auto_explain output.
+		_, assertions, err := sqlChecks(plan.analysis.QueryText)
+		if err != nil {
+			return err
+		}
+		results = append(results, assert.Evaluate(plan.analysis, append(assertions, checks...))...)
+		if selector != nil {
+			// Assertions cover the whole statement; only the rendering is narrowed.
+			focused, err := selector.Apply(plan.analysis)
+			if err != nil {
+				return err
+			}
+			focused.AttachTempFiles(plan.analysis.TempFiles)
+			plans[i].analysis = focused
+		}
+	}
 
 	switch *mode {
 	case "tui":
 		target := io.Writer(os.Stdout)
 		if *output != "" {
-			file, err := os.Create(*output)
+			file, err := createOutput(*output)
 			if err != nil {
-				return fmt.Errorf("create output: %w", err)
+				return err
 			}
 			defer func() {
 				_ = file.Close()
 			}()
 			target = file
 		}
-		return tui.Render(target, analysis, tui.Options{
-			EnableColor:  *color,
-			MaxDepth:     *maxDepth,
-			ShowWarnings: *warnings,
-		})
+		for i, plan := range plans {
+			if len(plans) > 1 {
+				if i > 0 {
+					_, _ = fmt.Fprintln(target)
+				}
+				_, _ = fmt.Fprintf(target, "== %s ==\n\n", plan.label)
+			}
+			if err := tui.Render(target, plan.analysis, tui.Options{
+				EnableColor:  colorChoice(fs, *color, target),
+				MaxDepth:     *maxDepth,
+				ShowWarnings: *warnings,
+				Numbers:      numbers,
+				Precision:    *precision,
+				MinSeverity:  minSeverity,
+				PercentBase:  percentBase,
+			}); err != nil {
+				return err
+			}
+		}
+		if err := finishOutput(target); err != nil {
+			return err
+		}
 	case "html":
+		if len(plans) > 1 {
+			return fmt.Errorf("input holds %d plans; choose one with --plan for HTML output", len(plans))
+		}
 		target := io.Writer(os.Stdout)
 		if *output != "" {
-			file, err := os.Create(*output)
+			file, err := createOutput(*output)
 			if err != nil {
-				return fmt.Errorf("create output: %w", err)
+				return err
 			}
 			defer func() {
 				_ = file.Close()
 			}()
 			target = file
 		}
-		return html.Render(target, analysis, html.Options{
+		if err := html.Render(target, plans[0].analysis, html.Options{
 			Title:         *title,
 			IncludeStyles: *includeCSS,
-		})
+			HighContrast:  *contrast,
+			Numbers:       numbers,
+			MinSeverity:   minSeverity,
+			PercentBase:   percentBase,
+		}); err != nil {
+			return err
+		}
+		if err := finishOutput(target); err != nil {
+			return err
+		}
 	default:
 		return fmt.Errorf("unknown mode %q (expected tui or html)", *mode)
 	}
+	return reportAssertions(os.Stderr, results)
+}
+
+// reportPlan is one analysis selected by xplain report, labelled for multi-plan output.
+type reportPlan struct {
+	label    string
+	analysis *analyzer.PlanAnalysis
+}
+
+// loadReportPlans reads the analyses a report covers: every plan of the EXPLAIN document (JSON may
+// hold several), or every plan auto_explain wrote to a server log, csvlog file or pgBadger export.
+func loadReportPlans(input, from string, opts parser.Options) ([]reportPlan, error) {
+	if from != "explain" && !logSources[from] {
+		return nil, fmt.Errorf("unknown source %q (expected explain, auto-explain, csvlog or pgbadger)", from)
+	}
+	if opts.Strict && from != "explain" {
+		return nil, fmt.Errorf("--strict applies to EXPLAIN documents, not --from %s", from)
+	}
+	data, err := readInput(input)
+	if err != nil {
+		return nil, err
+	}
+	if from == "explain" {
+		return explainPlans(input, data, opts)
+	}
+
+	logged, err := loggedPlans(input, data, from)
+	if err != nil {
+		return nil, err
+	}
+	plans := make([]reportPlan, 0, len(logged))
+	for i, entry := range logged {
+		_, analysis, err := parseAnalysisReader(strings.NewReader(entry.Text), opts.Format)
+		if err != nil {
+			return nil, fmt.Errorf("auto_explain plan %s: %w", loggedPosition(i, entry), err)
+		}
+		plans = append(plans, reportPlan{
+			label:    fmt.Sprintf("auto_explain plan %d of %d (%s, %.3f ms)", i+1, len(logged), loggedPosition(i, entry), entry.DurationMs),
+			analysis: analysis,
+		})
+	}
+	return plans, nil
+}
+
+// logSources are the --from values holding auto_explain output rather than an EXPLAIN document.
+var logSources = map[string]bool{"auto-explain": true, "csvlog": true, "pgbadger": true}
+
+// loggedPlans extracts the auto_explain plans of a stderr or jsonlog server log (auto-explain), a
+// csvlog file or a pgBadger JSON export.
+func loggedPlans(input string, data []byte, from string) ([]pglog.Plan, error) {
+	var (
+		plans []pglog.Plan
+		err   error
+	)
+	switch from {
+	case "csvlog":
+		plans, err = pglog.CSVLog(bytes.NewReader(data))
+	case "pgbadger":
+		plans, err = pglog.PgBadger(bytes.NewReader(data))
+	default:
+		plans, err = pglog.AutoExplain(bytes.NewReader(data))
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(plans) == 0 {
+		return nil, fmt.Errorf("no auto_explain plans found in %s", input)
+	}
+	return plans, nil
+}
+
+// loggedPosition locates a logged plan: its log line, or its position for pgBadger exports, which keep none.
+func loggedPosition(i int, plan pglog.Plan) string {
+	if plan.Line > 0 {
+		return fmt.Sprintf("line %d", plan.Line)
+	}
+	return fmt.Sprintf("#%d", i+1)
+}
+
+// explainPlans analyzes each plan of an EXPLAIN document. Strict-mode warnings go to stderr.
+func explainPlans(input string, data []byte, opts parser.Options) ([]reportPlan, error) {
+	explains, warnings, err := parser.ParseAllWith(bytes.NewReader(data), opts)
+	for _, warning := range warnings {
+		_, _ = fmt.Fprintf(os.Stderr, "%s: warning: %s\n", input, warning)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	plans := make([]reportPlan, 0, len(explains))
+	for i, explain := range explains {
+		analysis, err := analyzePlan(explain)
+		if err != nil {
+			return nil, fmt.Errorf("plan %d: %w", i+1, err)
+		}
+		label := input
+		if len(explains) > 1 {
+			label = fmt.Sprintf("%s: plan %d of %d", input, i+1, len(explains))
+		}
+		plans = append(plans, reportPlan{label: label, analysis: analysis})
+	}
+	return plans, nil
 }
 
 func diffCommand(args []string) error {
 	fs := flag.NewFlagSet("diff", flag.ContinueOnError)
 	fs.SetOutput(io.Discard)
 	fs.Usage = func() {
-		_, _ = fmt.Fprintf(os.Stdout, "Usage: xplain diff --base base.json --target target.json [--format md]\n\nOptions:\n")
+		_, _ = fmt.Fprintf(os.Stdout, "Usage: xplain diff --base base.json --target target.json [--format md|json|html] [--links] [--details]\n\nOptions:\n")
 		fs.PrintDefaults()
 	}
 
 	var (
-		basePath   = fs.String("base", "", "Path to baseline EXPLAIN JSON")
-		targetPath = fs.String("target", "", "Path to target EXPLAIN JSON")
-		format     = fs.String("format", "md", "Output format (md)")
-		output     = fs.String("out", "", "Output path (stdout if omitted)")
+		basePath   = fs.String("base", "", "Path to baseline EXPLAIN (JSON, YAML or text)")
+		targetPath = fs.String("target", "", "Path to target EXPLAIN (JSON, YAML or text)")
+		format     = fs.String("format", "md", "Output format: md, json, or html for the target tree coloured by change")
+		output     = fs.String("out", "", "Output path, or \"clipboard\" (stdout if omitted)")
+		title      = fs.String("title", "xplain diff", "Report title (HTML)")
+		contrast   = fs.Bool("high-contrast", false, "Use solid, patterned borders and a colour-blind safe palette (HTML)")
+		locale     = fs.String("locale", "", "Digit grouping and decimal separator, e.g. en, de, fr_FR, or auto for $LANG (HTML)")
+		units      = fs.String("units", "ms", "Time units: ms, s, or auto for seconds and minutes as times grow (HTML)")
 		minDelta   = fs.Float64("min-delta", 0, "Minimum self-time delta in ms to report (default from config)")
 		minPct     = fs.Float64("min-percent", 0, "Minimum percent change to report (default from config)")
 		maxItems   = fs.Int("limit", 0, "Maximum rows per section (default from config)")
+		links      = fs.Bool("links", false, "Link the base and target plan files relative to the output (md)")
+		details    = fs.Bool("details", false, "Embed both plan trees in collapsible <details> blocks (md)")
+		serial     = fs.Bool("serial", false, "Normalize parallel plans before comparing: fold Gather nodes and partial aggregates away")
+		baseEnv    = fs.String("base-env", "", "Environment snapshot (xplain env) taken alongside the base plan")
+		targetEnv  = fs.String("target-env", "", "Environment snapshot (xplain env) taken alongside the target plan")
 		configPath = fs.String("config", "", "Path to configuration file (JSON). Falls back to $XPLAIN_CONFIG")
 	)
 
@@ -365,9 +842,19 @@ func diffCommand(args []string) error {
 	if err := applyConfigPath(*configPath); err != nil {
 		return err
 	}
+	numbers, err := numberFormat(*locale, *units)
+	if err != nil {
+		return err
+	}
 	if *basePath == "" || *targetPath == "" {
 		return fmt.Errorf("--base and --target are required")
 	}
+	if *serial {
+		// Folding Gathers away compares the work each operator did, summed over its processes.
+		if err := useParallelTime(string(analyzer.CPUTime)); err != nil {
+			return err
+		}
+	}
 
 	_, baseAnalysis, err := loadAnalysis(*basePath)
 	if err != nil {
@@ -377,11 +864,22 @@ func diffCommand(args []string) error {
 	if err != nil {
 		return fmt.Errorf("load target: %w", err)
 	}
+	baseEnvironment, err := loadEnvironment(*baseEnv)
+	if err != nil {
+		return fmt.Errorf("load base environment: %w", err)
+	}
+	targetEnvironment, err := loadEnvironment(*targetEnv)
+	if err != nil {
+		return fmt.Errorf("load target environment: %w", err)
+	}
 
 	report, err := diff.Compare(baseAnalysis, targetAnalysis, diff.Options{
 		MinSelfTimeDeltaMs: *minDelta,
 		MinPercentChange:   *minPct,
 		MaxItems:           *maxItems,
+		Serial:             *serial,
+		BaseEnvironment:    baseEnvironment,
+		TargetEnvironment:  targetEnvironment,
 	})
 	if err != nil {
 		return err
@@ -389,12 +887,17 @@ func diffCommand(args []string) error {
 
 	switch *format {
 	case "md", "markdown":
+		attachments, err := diffAttachments(*basePath, *targetPath, *output, baseAnalysis, targetAnalysis, *links, *details)
+		if err != nil {
+			return err
+		}
+		report.Attachments = attachments
 		content := report.Markdown()
 		if *output == "" {
 			fmt.Print(content)
 			return nil
 		}
-		return os.WriteFile(*output, []byte(content), 0o644)
+		return writeOutput(*output, []byte(content))
 	case "json":
 		payload, err := report.JSON()
 		if err != nil {
@@ -405,12 +908,74 @@ func diffCommand(args []string) error {
 			os.Stdout.WriteString("\n")
 			return nil
 		}
-		return os.WriteFile(*output, payload, 0o644)
+		return writeOutput(*output, payload)
+	case "html":
+		var buf bytes.Buffer
+		if err := html.RenderDiff(&buf, report, html.Options{Title: *title, IncludeStyles: true, HighContrast: *contrast, Numbers: numbers}); err != nil {
+			return err
+		}
+		if *output == "" {
+			_, err = os.Stdout.Write(buf.Bytes())
+			return err
+		}
+		return writeOutput(*output, buf.Bytes())
 	default:
 		return fmt.Errorf("unsupported format %q", *format)
 	}
 }
 
+// diffAttachments builds the optional plan references for diff Markdown. Links are relative to the
+// output file's directory (or the working directory when writing to stdout) so they resolve in a PR.
+func diffAttachments(basePath, targetPath, output string, base, target *analyzer.PlanAnalysis, links, details bool) ([]diff.Attachment, error) {
+	if !links && !details {
+		return nil, nil
+	}
+	outDir := "."
+	if output != "" {
+		outDir = filepath.Dir(output)
+	}
+	var attachments []diff.Attachment
+	for _, side := range []struct {
+		label    string
+		path     string
+		analysis *analyzer.PlanAnalysis
+	}{{"Base", basePath, base}, {"Target", targetPath, target}} {
+		attachment := diff.Attachment{Label: side.label}
+		if links {
+			rel, err := relativeLink(outDir, side.path)
+			if err != nil {
+				return nil, err
+			}
+			attachment.Link = rel
+		}
+		if details {
+			var tree bytes.Buffer
+			if err := tui.Render(&tree, side.analysis, tui.Options{Precision: -1}); err != nil {
+				return nil, err
+			}
+			attachment.Tree = tree.String()
+		}
+		attachments = append(attachments, attachment)
+	}
+	return attachments, nil
+}
+
+func relativeLink(fromDir, target string) (string, error) {
+	absFrom, err := filepath.Abs(fromDir)
+	if err != nil {
+		return "", err
+	}
+	absTarget, err := filepath.Abs(target)
+	if err != nil {
+		return "", err
+	}
+	rel, err := filepath.Rel(absFrom, absTarget)
+	if err != nil {
+		return "", fmt.Errorf("link %s: %w", target, err)
+	}
+	return filepath.ToSlash(rel), nil
+}
+
 func versionCommand(args []string) error {
 	fs := flag.NewFlagSet("version", flag.ContinueOnError)
 	fs.SetOutput(io.Discard)
@@ -487,16 +1052,157 @@ func resolveVersion() (string, string) {
 	return v, strings.Join(details, ", ")
 }
 
-func loadAnalysis(path string) (*model.Explain, *analyzer.PlanAnalysis, error) {
+// saveRun writes <dir>/<fingerprint>/<timestamp>.json and .html and records the run in the history log,
+// under the registered query name when the statement came from the registry.
+func saveRun(dir, name string, result *runner.Result, analysis *analyzer.PlanAnalysis, title string) error {
+	entry := history.FromAnalysis(analysis)
+	entry.Name = name
+	stamp := entry.CapturedAt.Format("20060102T150405Z")
+	entry.Plan = path.Join(entry.Fingerprint, stamp+".json")
+	entry.Report = path.Join(entry.Fingerprint, stamp+".html")
+
+	if err := os.MkdirAll(filepath.Join(dir, entry.Fingerprint), 0o755); err != nil {
+		return fmt.Errorf("save: create dir: %w", err)
+	}
+	envelope, err := result.Envelope()
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(entry.PlanPath(dir), envelope, 0o644); err != nil {
+		return fmt.Errorf("save: write plan: %w", err)
+	}
+
+	var report bytes.Buffer
+	if err := html.Render(&report, analysis, html.Options{Title: title, IncludeStyles: true}); err != nil {
+		return err
+	}
+	if err := os.WriteFile(entry.ReportPath(dir), report.Bytes(), 0o644); err != nil {
+		return fmt.Errorf("save: write report: %w", err)
+	}
+
+	if err := history.Append(dir, entry); err != nil {
+		return err
+	}
+	_, _ = fmt.Fprintf(os.Stderr, "Saved %s and %s under %s\n", entry.Plan, entry.Report, dir)
+	return nil
+}
+
+// colorChoice resolves --color: an explicit value wins, otherwise colors are used only when the
+// target is a terminal that can render them.
+func colorChoice(fs *flag.FlagSet, enabled bool, target io.Writer) bool {
+	explicit := false
+	fs.Visit(func(f *flag.Flag) {
+		explicit = explicit || f.Name == "color"
+	})
+	if explicit {
+		return enabled
+	}
+	return tui.ColorSupported(target)
+}
+
+// numberFormat resolves the --locale and --units flags.
+func numberFormat(locale, units string) (numfmt.Format, error) {
+	l, err := numfmt.ParseLocale(locale)
+	if err != nil {
+		return numfmt.Format{}, err
+	}
+	u, err := numfmt.ParseUnits(units)
+	if err != nil {
+		return numfmt.Format{}, err
+	}
+	return numfmt.Format{Locale: l, Units: u}, nil
+}
+
+// createOutput creates an --out file, accepting either path separator and creating missing parent directories.
+// "clipboard" collects the output and copies it to the system clipboard when closed.
+func createOutput(name string) (io.WriteCloser, error) {
+	if name == clipboard.Name {
+		return clipboard.NewWriter()
+	}
+	path := filepath.Clean(filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("create output dir: %w", err)
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("create output: %w", err)
+	}
+	return file, nil
+}
+
+// finishOutput closes an --out target once rendering succeeded, so a failed clipboard copy or a short
+// write is reported instead of being lost in a deferred Close.
+func finishOutput(target io.Writer) error {
+	if closer, ok := target.(io.Closer); ok && target != io.Writer(os.Stdout) {
+		return closer.Close()
+	}
+	return nil
+}
+
+// writeOutput writes content to an --out path, or to the system clipboard for "clipboard".
+func writeOutput(name string, content []byte) error {
+	if name == clipboard.Name {
+		return clipboard.Write(content)
+	}
+	return os.WriteFile(name, content, 0o644)
+}
+
+// attachCatalog loads index metadata for the plan's relations; failures only downgrade the advice.
+func attachCatalog(ctx context.Context, dsn string, analysis *analyzer.PlanAnalysis, timeout time.Duration) {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	cat, err := catalog.Load(ctx, dsn, analysis.Relations())
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Warning: %v\n", redact.Error(err))
+		return
+	}
+	analysis.Catalog = cat
+}
+
+// attachTempLog reads temp file entries from a server log and attributes those of the analyzed statement.
+func attachTempLog(path string, analysis *analyzer.PlanAnalysis) error {
 	file, err := os.Open(path)
 	if err != nil {
-		return nil, nil, fmt.Errorf("open %s: %w", path, err)
+		return fmt.Errorf("open %s: %w", path, err)
 	}
 	defer func() {
 		_ = file.Close()
 	}()
 
-	return parseAnalysisReader(file)
+	files, err := pglog.TempFiles(file)
+	if err != nil {
+		return err
+	}
+	analysis.AttachTempFiles(pglog.ForStatement(files, analysis.QueryText))
+	return nil
+}
+
+func loadAnalysis(path string) (*model.Explain, *analyzer.PlanAnalysis, error) {
+	return loadAnalysisFormat(path, "auto")
+}
+
+// loadAnalysisFormat is loadAnalysis for a plan in the given format: auto, json, yaml, text or cockroach.
+func loadAnalysisFormat(path, format string) (*model.Explain, *analyzer.PlanAnalysis, error) {
+	data, err := readInput(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	return parseAnalysisReader(bytes.NewReader(data), format)
+}
+
+// readInput reads an input file, or the system clipboard when path is "clipboard".
+func readInput(path string) ([]byte, error) {
+	if path == clipboard.Name {
+		return clipboard.Read()
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("open %s: %w", path, err)
+	}
+	return data, nil
 }
 
 func indentJSON(data []byte) ([]byte, error) {
@@ -508,15 +1214,92 @@ func indentJSON(data []byte) ([]byte, error) {
 	return out.Bytes(), nil
 }
 
-func parseAnalysisReader(r io.Reader) (*model.Explain, *analyzer.PlanAnalysis, error) {
-	plan, err := parser.ParseJSON(r)
+func parseAnalysisReader(r io.Reader, format string) (*model.Explain, *analyzer.PlanAnalysis, error) {
+	var (
+		plan *model.Explain
+		err  error
+	)
+	if format == "auto" {
+		plan, err = parser.Parse(r)
+	} else {
+		plan, err = parser.ParseFormat(r, format)
+	}
 	if err != nil {
 		return nil, nil, err
 	}
-
-	analysis, err := analyzer.Analyze(plan)
+	analysis, err := analyzePlan(plan)
 	if err != nil {
 		return nil, nil, err
 	}
 	return plan, analysis, nil
 }
+
+func analyzePlan(plan *model.Explain) (*analyzer.PlanAnalysis, error) {
+	// Envelopes captured before output.redact_hosts was enabled still name their host.
+	if plan.Metadata != nil && config.Active().Output.RedactHosts {
+		plan.Metadata.Host, plan.Metadata.Port = "", 0
+	}
+	opts, err := analysisOptions()
+	if err != nil {
+		return nil, err
+	}
+	return analyzer.AnalyzeWith(plan, opts)
+}
+
+// analysisOptions reads the analyzer settings from the active configuration.
+func analysisOptions() (analyzer.Options, error) {
+	active := config.Active()
+	cfg := active.Analysis
+	timing, err := analyzer.ParseTimeAttribution(cfg.ParallelTime)
+	if err != nil {
+		return analyzer.Options{}, fmt.Errorf("config analysis.parallel_time: %w", err)
+	}
+	by, err := analyzer.ParseHotRanking(cfg.HotBy)
+	if err != nil {
+		return analyzer.Options{}, fmt.Errorf("config analysis.hot_by: %w", err)
+	}
+	minPercent := cfg.HotMinPercent
+	hot := analyzer.HotSelection{By: by, Limit: cfg.HotLimit, MinPercent: &minPercent}
+	return analyzer.Options{Time: timing, Hot: hot, CostSkew: active.Insights.CostSkewFactor}, nil
+}
+
+// useHotSelection overrides the analysis.hot_* settings for this run; an empty --hot-by, a zero --hot-limit
+// and an absent --hot-min-percent keep the configured ones, while --hot-min-percent 0 drops the threshold.
+func useHotSelection(fs *flag.FlagSet, by string, limit int, minPercent float64) error {
+	cfg := config.Active()
+	if by != "" {
+		ranking, err := analyzer.ParseHotRanking(by)
+		if err != nil {
+			return err
+		}
+		cfg.Analysis.HotBy = string(ranking)
+	}
+	if limit > 0 {
+		cfg.Analysis.HotLimit = limit
+	}
+	if minPercent < 0 || minPercent > 1 {
+		return fmt.Errorf("--hot-min-percent %g must be a fraction between 0 and 1", minPercent)
+	}
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name == "hot-min-percent" {
+			cfg.Analysis.HotMinPercent = minPercent
+		}
+	})
+	config.Use(cfg)
+	return nil
+}
+
+// useParallelTime overrides analysis.parallel_time for this run; empty keeps the configured value.
+func useParallelTime(value string) error {
+	if value == "" {
+		return nil
+	}
+	timing, err := analyzer.ParseTimeAttribution(value)
+	if err != nil {
+		return err
+	}
+	cfg := config.Active()
+	cfg.Analysis.ParallelTime = string(timing)
+	config.Use(cfg)
+	return nil
+}
@@ -0,0 +1,221 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/mickamy/xplain/internal/analyzer"
+	"github.com/mickamy/xplain/internal/assert"
+	"github.com/mickamy/xplain/internal/config"
+	"github.com/mickamy/xplain/internal/ignore"
+	"github.com/mickamy/xplain/internal/insight"
+	"github.com/mickamy/xplain/internal/manifest"
+	"github.com/mickamy/xplain/internal/model"
+	"github.com/mickamy/xplain/internal/parser"
+	"github.com/mickamy/xplain/internal/runner"
+	"github.com/mickamy/xplain/internal/sqlfile"
+)
+
+func manifestCommand(args []string) error {
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		manifestUsage()
+		if len(args) > 0 && (args[0] == "-h" || args[0] == "--help") {
+			return nil
+		}
+		return fmt.Errorf("manifest: expected a subcommand (analyze or diff)")
+	}
+
+	sub := args[0]
+	fs := flag.NewFlagSet("manifest "+sub, flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	fs.Usage = func() {
+		manifestUsage()
+		_, _ = fmt.Fprintf(os.Stdout, "\nOptions:\n")
+		fs.PrintDefaults()
+	}
+
+	envURL := os.Getenv("DATABASE_URL")
+
+	var (
+		path        = fs.String("manifest", "xplain.manifest.json", "Path to the query manifest (JSON)")
+		queries     = fs.String("query", "", "Comma-separated query names to run (default: all)")
+		urlFlag     = fs.String("url", envURL, "Connection string for queries without a profile; defaults to $DATABASE_URL")
+		service     = fs.String("service", "", "Connection service name from ~/.pg_service.conf or $PGSERVICEFILE; overrides --url")
+		outDir      = fs.String("out", "", "Directory for captured plans (analyze; default <output dir>/manifest)")
+		baselineDir = fs.String("baselines", "", "Directory holding baseline plans (diff; default from config, .xplain/baselines)")
+		update      = fs.Bool("update-baselines", false, "Write the captured plans as the new baselines (diff)")
+		failPercent = fs.Float64("fail-percent", 20, "Fail when execution time regresses by more than this percent (diff)")
+		summaryPath = fs.String("summary", os.Getenv("GITHUB_STEP_SUMMARY"), "Append the Markdown summary to this file (diff); defaults to $GITHUB_STEP_SUMMARY")
+		timeout     = fs.Duration("timeout", 0, "Optional execution timeout per query, e.g. 45s")
+		format      = fs.String("format", "md", "Output format (diff): md, or diagnostics for one JSON finding per line (editor problem matchers)")
+		configPath  = fs.String("config", "", "Path to configuration file (JSON). Falls back to $XPLAIN_CONFIG")
+		ignoreFile  = fs.String("ignore-file", ignore.DefaultPath, "File acknowledging insights by rule and node selector (analyze)")
+		vars        = sqlfile.Vars{}
+		checks      assert.List
+	)
+	fs.Var(vars, "var", "SQL template variable as name=value (repeatable); overrides manifest vars")
+	fs.Var(&checks, "assert", "Assertion checked for every query (diff), e.g. 'no-seq-scan' (repeatable)")
+
+	if err := fs.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			fs.SetOutput(os.Stdout)
+			fs.Usage()
+			return nil
+		}
+		return err
+	}
+	if err := applyConfigPath(*configPath); err != nil {
+		return err
+	}
+
+	m, err := manifest.Load(*path)
+	if err != nil {
+		return err
+	}
+	var names []string
+	if *queries != "" {
+		for name := range strings.SplitSeq(*queries, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				names = append(names, name)
+			}
+		}
+	}
+	selected, err := m.Select(names)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	fallback := resolveConnection(*urlFlag, *service)
+	switch sub {
+	case "analyze":
+		if *outDir == "" {
+			*outDir = filepath.Join(config.Active().Output.Dir, "manifest")
+		}
+		acks, err := ignore.Load(*ignoreFile, *ignoreFile == ignore.DefaultPath)
+		if err != nil {
+			return err
+		}
+		return manifestAnalyze(ctx, m, selected, fallback, *outDir, vars, acks, runner.Options{Timeout: *timeout})
+	case "diff":
+		if err := checkCIFormat(*format); err != nil {
+			return err
+		}
+		if *baselineDir == "" {
+			*baselineDir = config.Active().Output.BaselineDir
+		}
+		results := make([]ciResult, 0, len(selected))
+		for _, q := range selected {
+			dsn, err := m.DSN(q, fallback)
+			if err != nil {
+				results = append(results, ciResult{label: q.Name, sqlPath: m.SQLPath(q), err: err})
+				continue
+			}
+			target := ciTarget{
+				label:        q.Name,
+				name:         q.Name,
+				sqlPath:      m.SQLPath(q),
+				baselinePath: filepath.Join(*baselineDir, q.Name+".json"),
+				vars:         m.Vars(q, vars),
+				assertions:   checks,
+			}
+			results = append(results, ciCheck(ctx, dsn, target, *update, *failPercent, *timeout))
+		}
+		return ciFinish(results, "# xplain manifest diff", *format, *summaryPath, *failPercent)
+	default:
+		manifestUsage()
+		return fmt.Errorf("manifest: unknown subcommand %q (expected analyze or diff)", sub)
+	}
+}
+
+func manifestUsage() {
+	_, _ = fmt.Fprintf(os.Stdout, `Usage: xplain manifest <subcommand> --manifest xplain.manifest.json [--query a,b]
+
+Subcommands:
+  analyze  Capture and save a plan for every manifest query and print a summary table
+  diff     Compare every manifest query against its baseline plan
+`)
+}
+
+// manifestAnalyze captures each query, saves the envelope as <outDir>/<name>.json and prints a Markdown table.
+func manifestAnalyze(ctx context.Context, m *manifest.Manifest, queries []manifest.Query, fallback, outDir string, vars sqlfile.Vars, acks []model.Acknowledgement, opts runner.Options) error {
+	if err := os.MkdirAll(outDir, 0o755); err != nil {
+		return fmt.Errorf("create output dir: %w", err)
+	}
+
+	var b strings.Builder
+	b.WriteString("| Query | Profile | Execution (ms) | Critical | Warnings | Plan |\n")
+	b.WriteString("|---|---|---:|---:|---:|---|\n")
+	failed := 0
+	for _, q := range queries {
+		analysis, planPath, err := manifestCapture(ctx, m, q, fallback, outDir, m.Vars(q, vars), opts)
+		if err != nil {
+			failed++
+			_, _ = fmt.Fprintf(&b, "| %s | %s | – | – | – | ❌ %s |\n", q.Name, q.Profile, markdownCell(err.Error()))
+			continue
+		}
+		analysis.Acknowledgements = acks
+		var critical, warnings int
+		for _, msg := range insight.BuildMessages(analysis) {
+			if msg.Acknowledged != "" {
+				continue
+			}
+			switch msg.Severity {
+			case insight.SeverityCritical:
+				critical++
+			case insight.SeverityWarning:
+				warnings++
+			}
+		}
+		_, _ = fmt.Fprintf(&b, "| %s | %s | %.2f | %d | %d | %s |\n", q.Name, q.Profile, analysis.TotalTimeMs, critical, warnings, planPath)
+	}
+	fmt.Print(b.String())
+	if failed > 0 {
+		return fmt.Errorf("%d of %d queries failed", failed, len(queries))
+	}
+	return nil
+}
+
+func manifestCapture(ctx context.Context, m *manifest.Manifest, q manifest.Query, fallback, outDir string, vars sqlfile.Vars, opts runner.Options) (*analyzer.PlanAnalysis, string, error) {
+	dsn, err := m.DSN(q, fallback)
+	if err != nil {
+		return nil, "", err
+	}
+	sqlText, err := sqlfile.Load(m.SQLPath(q), vars)
+	if err != nil {
+		return nil, "", err
+	}
+	result, err := runner.Run(ctx, dsn, sqlText, opts)
+	if err != nil {
+		return nil, "", err
+	}
+	envelope, err := result.Envelope()
+	if err != nil {
+		return nil, "", err
+	}
+	planPath := filepath.Join(outDir, q.Name+".json")
+	if err := os.WriteFile(planPath, envelope, 0o644); err != nil {
+		return nil, "", fmt.Errorf("write plan: %w", err)
+	}
+	plan, err := parser.ParseJSON(bytes.NewReader(envelope))
+	if err != nil {
+		return nil, "", err
+	}
+	analysisOpts, err := analysisOptions()
+	if err != nil {
+		return nil, "", err
+	}
+	analysis, err := analyzer.AnalyzeWith(plan, analysisOpts)
+	if err != nil {
+		return nil, "", err
+	}
+	analysis.Name = q.Name
+	return analysis, planPath, nil
+}
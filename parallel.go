@@ -0,0 +1,181 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mickamy/xplain/internal/analyzer"
+	"github.com/mickamy/xplain/internal/parser"
+	"github.com/mickamy/xplain/internal/runner"
+	"github.com/mickamy/xplain/internal/sqlfile"
+	"github.com/mickamy/xplain/internal/sweep"
+)
+
+func parallelCommand(args []string) error {
+	fs := flag.NewFlagSet("parallel", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	fs.Usage = func() {
+		_, _ = fmt.Fprintf(os.Stdout, "Usage: xplain parallel --url <url> (--sql file.sql | --query \"SELECT ...\" | --name <name>) [--workers 0,2,4] [--format text|md|json]\n\n"+
+			"Runs EXPLAIN ANALYZE once per max_parallel_workers_per_gather value and compares execution time and plan shape.\n\nOptions:\n")
+		fs.PrintDefaults()
+	}
+
+	envURL := os.Getenv("DATABASE_URL")
+
+	var (
+		urlFlag    = fs.String("url", envURL, "PostgreSQL connection string; defaults to $DATABASE_URL")
+		service    = fs.String("service", "", "Connection service name from ~/.pg_service.conf or $PGSERVICEFILE; overrides --url")
+		sqlPath    = fs.String("sql", "", "Path to the SQL file to EXPLAIN")
+		queryName  = fs.String("name", "", "Name of a query registered with xplain query add, instead of --sql or --query")
+		inlineSQL  = fs.String("query", "", "Inline SQL string to EXPLAIN")
+		workers    = fs.String("workers", "0,2,4", "Comma-separated max_parallel_workers_per_gather values to try")
+		repeat     = fs.Int("repeat", 1, "Runs per worker count; the fastest is kept")
+		format     = fs.String("format", "text", "Output format (text, md or json)")
+		outPath    = fs.String("out", "", "Output path, or \"clipboard\" (stdout if omitted)")
+		timeout    = fs.Duration("timeout", 0, "Optional execution timeout per run, e.g. 45s")
+		configPath = fs.String("config", "", "Path to configuration file (JSON). Falls back to $XPLAIN_CONFIG")
+		vars       = sqlfile.Vars{}
+	)
+	fs.Var(vars, "var", "SQL template variable as name=value (repeatable)")
+
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			fs.SetOutput(os.Stdout)
+			fs.Usage()
+			return nil
+		}
+		return err
+	}
+	if err := applyConfigPath(*configPath); err != nil {
+		return err
+	}
+	switch *format {
+	case "text", "md", "markdown", "json":
+	default:
+		return fmt.Errorf("unsupported format %q", *format)
+	}
+
+	connection := resolveConnection(*urlFlag, *service)
+	if connection == "" {
+		return fmt.Errorf("--url or --service is required, or set $DATABASE_URL or $PGSERVICE")
+	}
+	counts, err := parseWorkerCounts(*workers)
+	if err != nil {
+		return err
+	}
+	if *repeat < 1 {
+		return fmt.Errorf("--repeat must be at least 1")
+	}
+
+	var sqlText string
+	switch {
+	case countSet(*sqlPath, *inlineSQL, *queryName) > 1:
+		return fmt.Errorf("specify only one of --sql, --query or --name")
+	case *sqlPath != "":
+		if sqlText, err = sqlfile.Load(*sqlPath, vars); err != nil {
+			return err
+		}
+	case *inlineSQL != "":
+		if sqlText, err = sqlfile.Render(*inlineSQL, vars); err != nil {
+			return err
+		}
+	case *queryName != "":
+		if sqlText, err = loadNamedQuery(*queryName, vars); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("--sql, --query or --name is required")
+	}
+	directives, err := sqlfile.ParseDirectives(sqlText)
+	if err != nil {
+		return err
+	}
+	if *timeout == 0 {
+		*timeout = directives.Timeout
+	}
+
+	ctx := context.Background()
+	runs := make([]sweep.Run, 0, len(counts))
+	for _, count := range counts {
+		analysis, err := fastestRun(ctx, connection, sqlText, count, *repeat, *timeout)
+		if err != nil {
+			return fmt.Errorf("%s=%d: %w", sweep.Setting, count, err)
+		}
+		runs = append(runs, sweep.Run{Workers: count, Analysis: analysis})
+	}
+	report := sweep.Compare(runs)
+
+	var content []byte
+	switch *format {
+	case "text":
+		content = []byte(report.Text())
+	case "md", "markdown":
+		content = []byte(report.Markdown())
+	case "json":
+		if content, err = report.JSON(); err != nil {
+			return err
+		}
+		content = append(content, '\n')
+	}
+	if *outPath == "" {
+		_, err = os.Stdout.Write(content)
+		return err
+	}
+	return writeOutput(*outPath, content)
+}
+
+// fastestRun executes the statement repeat times with the given worker count and keeps the quickest plan.
+func fastestRun(ctx context.Context, dsn, sqlText string, workers, repeat int, timeout time.Duration) (*analyzer.PlanAnalysis, error) {
+	opts := runner.Options{Timeout: timeout, Settings: map[string]string{sweep.Setting: strconv.Itoa(workers)}}
+	analysisOpts, err := analysisOptions()
+	if err != nil {
+		return nil, err
+	}
+	var best *analyzer.PlanAnalysis
+	for range repeat {
+		result, err := runner.Run(ctx, dsn, sqlText, opts)
+		if err != nil {
+			return nil, err
+		}
+		plan, err := parser.ParseJSON(bytes.NewReader(result.Plan))
+		if err != nil {
+			return nil, err
+		}
+		plan.Metadata = &result.Metadata
+		analysis, err := analyzer.AnalyzeWith(plan, analysisOpts)
+		if err != nil {
+			return nil, err
+		}
+		if best == nil || analysis.ExecutionTimeMs < best.ExecutionTimeMs {
+			best = analysis
+		}
+	}
+	return best, nil
+}
+
+func parseWorkerCounts(spec string) ([]int, error) {
+	var counts []int
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("invalid worker count %q", part)
+		}
+		counts = append(counts, n)
+	}
+	if len(counts) < 2 {
+		return nil, fmt.Errorf("--workers needs at least two counts to compare, e.g. 0,2,4")
+	}
+	return counts, nil
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/mickamy/xplain/internal/analyzer"
+	"github.com/mickamy/xplain/internal/parser"
+	"github.com/mickamy/xplain/internal/prepared"
+	"github.com/mickamy/xplain/internal/runner"
+	"github.com/mickamy/xplain/internal/sqlfile"
+)
+
+// stringList collects the values of a repeatable flag in order.
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ", ")
+}
+
+func (l *stringList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+func preparedCommand(args []string) error {
+	fs := flag.NewFlagSet("prepared", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	fs.Usage = func() {
+		_, _ = fmt.Fprintf(os.Stdout, "Usage: xplain prepared --url <url> (--sql file.sql | --query \"SELECT ... $1\" | --name <name>) --param value [--executions 10]\n\n"+
+			"Prepares the statement and runs EXPLAIN ANALYZE EXECUTE repeatedly in one session to show whether and when\n"+
+			"PostgreSQL switches from custom to a generic plan (normally from the sixth execution) and what it costs.\n\nOptions:\n")
+		fs.PrintDefaults()
+	}
+
+	envURL := os.Getenv("DATABASE_URL")
+
+	var (
+		urlFlag    = fs.String("url", envURL, "PostgreSQL connection string; defaults to $DATABASE_URL")
+		service    = fs.String("service", "", "Connection service name from ~/.pg_service.conf or $PGSERVICEFILE; overrides --url")
+		sqlPath    = fs.String("sql", "", "Path to the SQL file to prepare; parameters are written $1, $2, ...")
+		queryName  = fs.String("name", "", "Name of a query registered with xplain query add, instead of --sql or --query")
+		inlineSQL  = fs.String("query", "", "Inline SQL string to prepare")
+		executions = fs.Int("executions", 10, "Number of EXECUTE runs")
+		format     = fs.String("format", "text", "Output format (text, md or json)")
+		outPath    = fs.String("out", "", "Output path, or \"clipboard\" (stdout if omitted)")
+		failSlower = fs.Bool("fail-on-regression", false, "Exit with status 2 when the plan flip made executions slower")
+		timeout    = fs.Duration("timeout", 0, "Optional timeout for the whole session, e.g. 45s")
+		configPath = fs.String("config", "", "Path to configuration file (JSON). Falls back to $XPLAIN_CONFIG")
+		vars       = sqlfile.Vars{}
+		params     stringList
+	)
+	fs.Var(vars, "var", "SQL template variable as name=value (repeatable)")
+	fs.Var(&params, "param", "Value bound to the next $n parameter, in order (repeatable)")
+
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			fs.SetOutput(os.Stdout)
+			fs.Usage()
+			return nil
+		}
+		return err
+	}
+	if err := applyConfigPath(*configPath); err != nil {
+		return err
+	}
+	switch *format {
+	case "text", "md", "markdown", "json":
+	default:
+		return fmt.Errorf("unsupported format %q", *format)
+	}
+
+	connection := resolveConnection(*urlFlag, *service)
+	if connection == "" {
+		return fmt.Errorf("--url or --service is required, or set $DATABASE_URL or $PGSERVICE")
+	}
+
+	var (
+		sqlText string
+		err     error
+	)
+	switch {
+	case countSet(*sqlPath, *inlineSQL, *queryName) > 1:
+		return fmt.Errorf("specify only one of --sql, --query or --name")
+	case *sqlPath != "":
+		if sqlText, err = sqlfile.Load(*sqlPath, vars); err != nil {
+			return err
+		}
+	case *inlineSQL != "":
+		if sqlText, err = sqlfile.Render(*inlineSQL, vars); err != nil {
+			return err
+		}
+	case *queryName != "":
+		if sqlText, err = loadNamedQuery(*queryName, vars); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("--sql, --query or --name is required")
+	}
+	directives, err := sqlfile.ParseDirectives(sqlText)
+	if err != nil {
+		return err
+	}
+	if *timeout == 0 {
+		*timeout = directives.Timeout
+	}
+
+	results, err := runner.RunPrepared(context.Background(), connection, sqlText, params, *executions, runner.Options{Timeout: *timeout})
+	if err != nil {
+		return err
+	}
+	opts, err := analysisOptions()
+	if err != nil {
+		return err
+	}
+	analyses := make([]*analyzer.PlanAnalysis, 0, len(results))
+	for _, result := range results {
+		plan, err := parser.ParseJSON(bytes.NewReader(result.Plan))
+		if err != nil {
+			return err
+		}
+		plan.Metadata = &result.Metadata
+		analysis, err := analyzer.AnalyzeWith(plan, opts)
+		if err != nil {
+			return err
+		}
+		analyses = append(analyses, analysis)
+	}
+	report := prepared.Detect(analyses)
+
+	var content []byte
+	switch *format {
+	case "text":
+		content = []byte(report.Text())
+	case "md", "markdown":
+		content = []byte(report.Markdown())
+	case "json":
+		if content, err = report.JSON(); err != nil {
+			return err
+		}
+		content = append(content, '\n')
+	}
+	if *outPath == "" {
+		_, err = os.Stdout.Write(content)
+	} else {
+		err = writeOutput(*outPath, content)
+	}
+	if err != nil {
+		return err
+	}
+	if *failSlower && report.Regressed {
+		return &exitCodeError{code: 2, msg: fmt.Sprintf("plan flipped at execution %d and got slower", report.FlipAt)}
+	}
+	return nil
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/mickamy/xplain/internal/config"
+	"github.com/mickamy/xplain/internal/registry"
+	"github.com/mickamy/xplain/internal/sqlfile"
+)
+
+func queryCommand(args []string) error {
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		queryUsage()
+		if len(args) > 0 && (args[0] == "-h" || args[0] == "--help") {
+			return nil
+		}
+		return fmt.Errorf("query: expected a subcommand (add, list, show or remove)")
+	}
+
+	sub := args[0]
+	fs := flag.NewFlagSet("query "+sub, flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	fs.Usage = func() {
+		queryUsage()
+		_, _ = fmt.Fprintf(os.Stdout, "\nOptions:\n")
+		fs.PrintDefaults()
+	}
+
+	var (
+		sqlPath    = fs.String("sql", "", "Path to the SQL file to register (add)")
+		inlineSQL  = fs.String("query", "", "Inline SQL string to register (add)")
+		replace    = fs.Bool("replace", false, "Overwrite a query already registered under the name (add)")
+		dir        = fs.String("dir", "", "Directory holding queries.json (default from config, .xplain)")
+		configPath = fs.String("config", "", "Path to configuration file (JSON). Falls back to $XPLAIN_CONFIG")
+	)
+
+	// The name comes first ("query add checkout-search --sql ..."), but may also follow the flags.
+	rest := args[1:]
+	var name string
+	if len(rest) > 0 && !strings.HasPrefix(rest[0], "-") {
+		name, rest = rest[0], rest[1:]
+	}
+	if err := fs.Parse(rest); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			fs.SetOutput(os.Stdout)
+			fs.Usage()
+			return nil
+		}
+		return err
+	}
+	if name == "" {
+		name = fs.Arg(0)
+	}
+	if err := applyConfigPath(*configPath); err != nil {
+		return err
+	}
+	if *dir == "" {
+		*dir = config.Active().Output.Dir
+	}
+
+	reg, err := registry.Load(*dir)
+	if err != nil {
+		return err
+	}
+	if sub != "list" && name == "" {
+		return fmt.Errorf("query %s: a query name is required", sub)
+	}
+
+	switch sub {
+	case "add":
+		var sqlText string
+		switch {
+		case *sqlPath != "" && *inlineSQL != "":
+			return fmt.Errorf("specify only one of --sql or --query")
+		case *sqlPath != "":
+			data, err := os.ReadFile(*sqlPath)
+			if err != nil {
+				return fmt.Errorf("read sql: %w", err)
+			}
+			sqlText = string(data)
+		case *inlineSQL != "":
+			sqlText = *inlineSQL
+		default:
+			return fmt.Errorf("--sql or --query is required")
+		}
+		if strings.TrimSpace(sqlText) == "" {
+			return fmt.Errorf("query add: the statement is empty")
+		}
+		q, err := reg.Add(name, sqlText, *sqlPath, *replace)
+		if err != nil {
+			return err
+		}
+		if err := reg.Save(); err != nil {
+			return err
+		}
+		_, _ = fmt.Fprintf(os.Stderr, "Registered %s (fingerprint %s) in %s\n", name, q.Fingerprint, *dir)
+		return nil
+	case "list":
+		for _, n := range reg.Names() {
+			q := reg.Queries[n]
+			source := q.Source
+			if source == "" {
+				source = "inline"
+			}
+			_, _ = fmt.Fprintf(os.Stdout, "%-24s %s  %s\n", n, q.Fingerprint, source)
+		}
+		return nil
+	case "show":
+		q, err := reg.Get(name)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(os.Stdout, strings.TrimRight(q.SQL, "\n"))
+		return err
+	case "remove":
+		if err := reg.Remove(name); err != nil {
+			return err
+		}
+		return reg.Save()
+	default:
+		queryUsage()
+		return fmt.Errorf("query: unknown subcommand %q", sub)
+	}
+}
+
+func queryUsage() {
+	_, _ = fmt.Fprintf(os.Stdout, `Usage: xplain query <subcommand> [name] [options]
+
+Subcommands:
+  add     Register a statement under a name: xplain query add checkout-search --sql checkout_search.sql
+  list    List the registered names with their fingerprints and source files
+  show    Print the statement registered under a name
+  remove  Drop a name from the registry
+
+run, analyze, parallel and prepared accept --name <name> in place of --sql or --query. The registry is
+<output dir>/queries.json; commit it so the names resolve the same way on every machine.
+`)
+}
+
+// loadNamedQuery renders the statement registered under name with the template variables.
+func loadNamedQuery(name string, vars sqlfile.Vars) (string, error) {
+	reg, err := registry.Load(config.Active().Output.Dir)
+	if err != nil {
+		return "", err
+	}
+	q, err := reg.Get(name)
+	if err != nil {
+		return "", err
+	}
+	return sqlfile.Render(q.SQL, vars)
+}
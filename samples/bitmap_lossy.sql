@@ -0,0 +1,4 @@
+-- with work_mem = '64kB'
+SELECT count(*), sum(abalance)
+FROM pgbench_accounts
+WHERE abalance BETWEEN -500 AND 500;
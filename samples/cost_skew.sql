@@ -0,0 +1,5 @@
+SELECT p.category, sum(o.quantity)
+FROM order_items o
+JOIN products p ON p.id = o.product_id
+WHERE o.created_at >= date '2026-09-01'
+GROUP BY p.category;
@@ -0,0 +1,9 @@
+-- A materialized CTE read by two CTE Scans: the first materializes it, the second rereads the stored rows.
+EXPLAIN (ANALYZE, BUFFERS, FORMAT JSON)
+WITH totals AS MATERIALIZED (
+  SELECT customer_id, sum(total) AS spent FROM orders GROUP BY customer_id
+)
+SELECT a.customer_id, a.spent, b.spent AS referrer_spent
+FROM totals a
+JOIN totals b ON b.customer_id = a.customer_id % 1000
+WHERE a.spent > 1000;
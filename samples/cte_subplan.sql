@@ -0,0 +1,10 @@
+WITH recent_orders AS MATERIALIZED (
+    SELECT id, customer_id, total
+    FROM orders
+    WHERE created_at > now() - interval '7 days'
+)
+SELECT r.id,
+       r.total,
+       (SELECT count(*) FROM order_items i WHERE i.order_id = r.id) AS items
+FROM recent_orders r
+WHERE r.total > 100;
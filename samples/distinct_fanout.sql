@@ -0,0 +1,4 @@
+SELECT DISTINCT c.id, c.name
+FROM customers c
+JOIN orders o ON o.customer_id = c.id
+WHERE o.created_at >= now() - interval '30 days';
@@ -0,0 +1,2 @@
+DELETE FROM customers
+WHERE created_at < now() - interval '5 years';
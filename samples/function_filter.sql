@@ -0,0 +1,3 @@
+SELECT id, email
+FROM users
+WHERE lower(email) = 'alice@example.com';
@@ -0,0 +1,4 @@
+-- after bulk updates to January's orders and before autovacuum got to the table
+SELECT count(*), sum(total)
+FROM orders
+WHERE created_at >= '2024-01-01' AND created_at < '2024-02-01';
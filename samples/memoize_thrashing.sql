@@ -0,0 +1,4 @@
+-- with work_mem = '64kB'
+SELECT count(*), sum(c.credit_limit)
+FROM orders o
+JOIN customers c ON c.id = o.customer_id;
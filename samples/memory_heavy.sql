@@ -0,0 +1,5 @@
+SELECT o.id, o.created_at, o.total, c.name, c.email
+FROM orders o
+JOIN customers c ON c.id = o.customer_id
+WHERE o.created_at >= now() - interval '90 days'
+ORDER BY c.name, o.created_at;
@@ -0,0 +1,4 @@
+SELECT b.id, b.balance, c.owner
+FROM billing.accounts b
+JOIN crm.accounts c ON c.external_id = b.id
+WHERE b.balance < 0;
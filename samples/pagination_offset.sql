@@ -0,0 +1,6 @@
+SELECT id, customer_id, status, created_at
+FROM orders
+WHERE status = 'shipped'
+ORDER BY created_at DESC
+OFFSET 10000
+LIMIT 20;
@@ -0,0 +1,4 @@
+-- A parallel scan of an append-only table whose recent rows sit at its end: the worker that reaches
+-- those blocks returns nearly all of them while the others filter.
+EXPLAIN (ANALYZE, VERBOSE, BUFFERS, FORMAT JSON)
+SELECT id, account_id, amount FROM events WHERE created_at > now() - interval '1 day';
@@ -0,0 +1,3 @@
+-- PostgreSQL 17+: SERIALIZE measures detoasting and converting the wide body column to text output.
+EXPLAIN (ANALYZE, BUFFERS, SERIALIZE, FORMAT JSON)
+SELECT id, title, body FROM documents;
@@ -0,0 +1,4 @@
+SELECT *
+FROM events
+WHERE tenant_id = 42
+  AND occurred_at >= now() - interval '1 hour';
@@ -0,0 +1,4 @@
+SELECT e.name, m.name AS manager
+FROM employees e
+JOIN employees m ON m.id = e.manager_id
+WHERE e.department_id = 7;
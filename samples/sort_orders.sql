@@ -0,0 +1,4 @@
+SELECT customer_id, created_at, total
+FROM orders
+WHERE status = 'shipped'
+ORDER BY customer_id, created_at DESC;
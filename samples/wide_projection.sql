@@ -0,0 +1,3 @@
+SELECT o.id, c.name
+FROM orders o
+JOIN (SELECT * FROM customers) c ON c.id = o.customer_id;
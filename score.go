@@ -0,0 +1,100 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/mickamy/xplain/internal/ignore"
+	"github.com/mickamy/xplain/internal/insight"
+	"github.com/mickamy/xplain/internal/render/badge"
+)
+
+func scoreCommand(args []string) error {
+	fs := flag.NewFlagSet("score", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	fs.Usage = func() {
+		_, _ = fmt.Fprintf(os.Stdout, "Usage: xplain score --input plan.json [--format text|json|badge|svg] [--out file]\n\nOptions:\n")
+		fs.PrintDefaults()
+	}
+
+	var (
+		input      = fs.String("input", "", "Path to EXPLAIN input (JSON, YAML or text), or \"clipboard\"")
+		format     = fs.String("format", "text", "Output format: text, json, badge (shields.io endpoint JSON) or svg")
+		output     = fs.String("out", "", "Output path, or \"clipboard\" (stdout if omitted)")
+		label      = fs.String("label", badge.DefaultLabel, "Badge label (badge and svg)")
+		ignoreFile = fs.String("ignore-file", ignore.DefaultPath, "File acknowledging insights by rule and node selector")
+		configPath = fs.String("config", "", "Path to configuration file (JSON). Falls back to $XPLAIN_CONFIG")
+	)
+
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			fs.SetOutput(os.Stdout)
+			fs.Usage()
+			return nil
+		}
+		return err
+	}
+	if err := applyConfigPath(*configPath); err != nil {
+		return err
+	}
+	if *input == "" {
+		return fmt.Errorf("--input is required")
+	}
+	switch *format {
+	case "text", "json", "badge", "svg":
+	default:
+		return fmt.Errorf("unsupported format %q (expected text, json, badge or svg)", *format)
+	}
+
+	_, analysis, err := loadAnalysis(*input)
+	if err != nil {
+		return err
+	}
+	if analysis.Acknowledgements, err = ignore.Load(*ignoreFile, *ignoreFile == ignore.DefaultPath); err != nil {
+		return err
+	}
+	score := insight.ScorePlan(analysis)
+
+	target := io.Writer(os.Stdout)
+	if *output != "" {
+		file, err := createOutput(*output)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			_ = file.Close()
+		}()
+		target = file
+	}
+	opts := badge.Options{Label: *label}
+	switch *format {
+	case "text":
+		writeScore(target, score)
+	case "json":
+		encoder := json.NewEncoder(target)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(score); err != nil {
+			return err
+		}
+	case "badge":
+		if err := badge.RenderJSON(target, score, opts); err != nil {
+			return err
+		}
+	case "svg":
+		if err := badge.RenderSVG(target, score, opts); err != nil {
+			return err
+		}
+	}
+	return finishOutput(target)
+}
+
+func writeScore(w io.Writer, score insight.Score) {
+	_, _ = fmt.Fprintf(w, "Score %d/100\n", score.Value)
+	for _, p := range score.Penalties {
+		_, _ = fmt.Fprintf(w, "  -%d %s (%d finding(s))\n", p.Points, p.Category, p.Findings)
+	}
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/mickamy/xplain/internal/selfupdate"
+)
+
+func selfUpdateCommand(args []string) error {
+	fs := flag.NewFlagSet("self-update", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	fs.Usage = func() {
+		_, _ = fmt.Fprintf(os.Stdout, "Usage: xplain self-update [--check] [--force]\n\nOptions:\n")
+		fs.PrintDefaults()
+	}
+
+	var (
+		check   = fs.Bool("check", false, "Only report whether a newer release is available")
+		force   = fs.Bool("force", false, "Reinstall even when already up to date, or when running a development build")
+		timeout = fs.Duration("timeout", 2*time.Minute, "Timeout for the release lookup and download")
+	)
+
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			fs.SetOutput(os.Stdout)
+			fs.Usage()
+			return nil
+		}
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	updater := selfupdate.New()
+	release, err := updater.Latest(ctx)
+	if err != nil {
+		return err
+	}
+	current, _ := resolveVersion()
+	upToDate := strings.TrimPrefix(current, "v") == release.Version()
+	if *check {
+		if upToDate {
+			fmt.Printf("xplain %s is up to date\n", current)
+		} else {
+			fmt.Printf("xplain %s is available (running %s)\n", release.Tag, current)
+		}
+		return nil
+	}
+	if upToDate && !*force {
+		fmt.Printf("xplain %s is up to date\n", current)
+		return nil
+	}
+	if current == "dev" && !*force {
+		return fmt.Errorf("running a development build; pass --force to replace it with %s", release.Tag)
+	}
+
+	exe, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("locate executable: %w", err)
+	}
+	if resolved, err := filepath.EvalSymlinks(exe); err == nil {
+		exe = resolved
+	}
+	if manager := selfupdate.ManagedBy(exe); manager != "" {
+		return fmt.Errorf("%s is managed by %s; update it there instead", exe, manager)
+	}
+
+	binary, err := updater.Download(ctx, release, runtime.GOOS, runtime.GOARCH)
+	if err != nil {
+		return err
+	}
+	if err := selfupdate.Replace(exe, binary); err != nil {
+		return err
+	}
+	fmt.Printf("Updated %s from %s to %s (checksum verified)\n", exe, current, release.Tag)
+	return nil
+}
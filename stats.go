@@ -0,0 +1,114 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/mickamy/xplain/internal/config"
+	"github.com/mickamy/xplain/internal/history"
+)
+
+func statsCommand(args []string) error {
+	fs := flag.NewFlagSet("stats", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	fs.Usage = func() {
+		_, _ = fmt.Fprintf(os.Stdout, "Usage: xplain stats [--dir .xplain] [--since 2026-01-01] [--format text|json]\n\nOptions:\n")
+		fs.PrintDefaults()
+	}
+
+	var (
+		dir        = fs.String("dir", "", "Output directory holding history.jsonl (default from config, .xplain)")
+		since      = fs.String("since", "", "Only count runs captured on or after this date (YYYY-MM-DD)")
+		format     = fs.String("format", "text", "Output format (text or json)")
+		top        = fs.Int("top", 5, "Number of most improved statements to list")
+		configPath = fs.String("config", "", "Path to configuration file (JSON). Falls back to $XPLAIN_CONFIG")
+	)
+
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			fs.SetOutput(os.Stdout)
+			fs.Usage()
+			return nil
+		}
+		return err
+	}
+	if err := applyConfigPath(*configPath); err != nil {
+		return err
+	}
+	if *dir == "" {
+		*dir = config.Active().Output.Dir
+	}
+
+	entries, err := history.Load(*dir)
+	if err != nil {
+		return err
+	}
+	if *since != "" {
+		cutoff, err := time.Parse(time.DateOnly, *since)
+		if err != nil {
+			return fmt.Errorf("invalid --since %q (expected YYYY-MM-DD)", *since)
+		}
+		kept := entries[:0]
+		for _, entry := range entries {
+			if !entry.CapturedAt.Before(cutoff) {
+				kept = append(kept, entry)
+			}
+		}
+		entries = kept
+	}
+
+	diffCfg := config.Active().Diff
+	stats := history.Summarize(entries, history.StatsOptions{
+		MinDeltaMs: diffCfg.MinSelfDeltaMs,
+		MinPercent: diffCfg.MinPercentChange,
+		Top:        *top,
+	})
+
+	switch *format {
+	case "text":
+		writeStats(os.Stdout, *dir, stats)
+		return nil
+	case "json":
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(stats)
+	default:
+		return fmt.Errorf("unsupported format %q", *format)
+	}
+}
+
+func writeStats(w io.Writer, dir string, stats history.Stats) {
+	if stats.Runs == 0 {
+		_, _ = fmt.Fprintf(w, "No saved runs in %s yet; record some with `xplain analyze --save`.\n", dir)
+		return
+	}
+	_, _ = fmt.Fprintf(w, "Runs analyzed     %d across %d statements (%s to %s)\n", stats.Runs, stats.Queries,
+		stats.First.Format(time.DateOnly), stats.Last.Format(time.DateOnly))
+	if len(stats.Databases) > 0 {
+		_, _ = fmt.Fprintf(w, "Databases         %s\n", strings.Join(stats.Databases, ", "))
+	}
+	_, _ = fmt.Fprintf(w, "Regressions       %d runs slower than the previous one, in %d statements\n", stats.Regressions, stats.RegressedQueries)
+	if stats.Improved == 0 {
+		_, _ = fmt.Fprintln(w, "Improvements      none yet")
+		return
+	}
+	_, _ = fmt.Fprintf(w, "Improvements      %d statements, %.1f%% faster on average, %.2f ms saved per execution\n",
+		stats.Improved, stats.AverageImprovement, stats.SavedMs)
+	_, _ = fmt.Fprintln(w, "\nMost improved:")
+	for _, q := range stats.MostImproved {
+		label := q.Query
+		if label == "" {
+			label = q.Fingerprint
+		}
+		if runes := []rune(label); len(runes) > 60 {
+			label = string(runes[:57]) + "..."
+		}
+		_, _ = fmt.Fprintf(w, "  %6.1f%%  %.2f ms -> %.2f ms  (%d runs)  %s\n", q.Change, q.FirstMs, q.LatestMs, q.Runs, label)
+	}
+}
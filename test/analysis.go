@@ -40,6 +40,12 @@ func RootPath(t *testing.T) string {
 
 // LoadSampleAnalysis loads and analyzes a plan relative to the repository rootPath.
 func LoadSampleAnalysis(t *testing.T, rel string) *analyzer.PlanAnalysis {
+	t.Helper()
+	return LoadSampleAnalysisWith(t, rel, analyzer.Options{})
+}
+
+// LoadSampleAnalysisWith is LoadSampleAnalysis with explicit analyzer options.
+func LoadSampleAnalysisWith(t *testing.T, rel string, opts analyzer.Options) *analyzer.PlanAnalysis {
 	t.Helper()
 	root := RootPath(t)
 	f, err := os.Open(filepath.Join(root, "samples", rel))
@@ -48,11 +54,11 @@ func LoadSampleAnalysis(t *testing.T, rel string) *analyzer.PlanAnalysis {
 	}
 	defer func() { _ = f.Close() }()
 
-	plan, err := parser.ParseJSON(f)
+	plan, err := parser.Parse(f)
 	if err != nil {
 		t.Fatalf("parse plan: %v", err)
 	}
-	analysis, err := analyzer.Analyze(plan)
+	analysis, err := analyzer.AnalyzeWith(plan, opts)
 	if err != nil {
 		t.Fatalf("analyze plan: %v", err)
 	}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/mickamy/xplain/internal/config"
+	"github.com/mickamy/xplain/internal/history"
+)
+
+func thresholdsCommand(args []string) error {
+	fs := flag.NewFlagSet("thresholds", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	fs.Usage = func() {
+		_, _ = fmt.Fprintf(os.Stdout, "Usage: xplain thresholds --config xplain.json [--dir .xplain] [--sigma 3] [--dry-run]\n\n"+
+			"Learns per-query diff thresholds from the run-to-run variance in history.jsonl and writes them to the\n"+
+			"config file under diff.queries.\n\nOptions:\n")
+		fs.PrintDefaults()
+	}
+
+	var (
+		configPath = fs.String("config", "", "Configuration file (JSON) to update; created when missing. Falls back to $XPLAIN_CONFIG")
+		dir        = fs.String("dir", "", "Output directory holding history.jsonl (default from config, .xplain)")
+		minRuns    = fs.Int("min-runs", 5, "Runs a query needs before its thresholds are learned")
+		window     = fs.Int("window", 20, "Only use the most recent runs of each query (0 for all)")
+		sigma      = fs.Float64("sigma", 3, "Standard deviations a change must exceed to be reported")
+		dryRun     = fs.Bool("dry-run", false, "Print the learned thresholds without writing the config")
+	)
+
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			fs.SetOutput(os.Stdout)
+			fs.Usage()
+			return nil
+		}
+		return err
+	}
+	path := strings.TrimSpace(*configPath)
+	if path == "" {
+		path = strings.TrimSpace(os.Getenv("XPLAIN_CONFIG"))
+	}
+	if path == "" && !*dryRun {
+		return fmt.Errorf("--config is required (the file the thresholds are written to) or set $XPLAIN_CONFIG")
+	}
+	if path != "" {
+		if err := config.Apply(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	if *dir == "" {
+		*dir = config.Active().Output.Dir
+	}
+
+	entries, err := history.Load(*dir)
+	if err != nil {
+		return err
+	}
+	learned := history.LearnThresholds(entries, history.ThresholdOptions{MinRuns: *minRuns, Window: *window, Sigma: *sigma})
+	if len(learned) == 0 {
+		_, _ = fmt.Fprintf(os.Stdout, "No query in %s has %d or more runs yet; nothing to learn.\n", *dir, *minRuns)
+		return nil
+	}
+
+	writeThresholds(os.Stdout, learned)
+	if *dryRun {
+		return nil
+	}
+	if err := saveThresholds(path, learned); err != nil {
+		return err
+	}
+	_, _ = fmt.Fprintf(os.Stdout, "\nWrote %d per-query thresholds to %s\n", len(learned), path)
+	return nil
+}
+
+func writeThresholds(w io.Writer, learned []history.Threshold) {
+	_, _ = fmt.Fprintf(w, "%-12s %5s %10s %10s %10s %8s  %s\n", "Fingerprint", "Runs", "Mean ms", "Stddev ms", "Min delta", "Min %", "Query")
+	for _, t := range learned {
+		query := t.Query
+		if len(query) > 60 {
+			query = query[:57] + "..."
+		}
+		_, _ = fmt.Fprintf(w, "%-12s %5d %10.3f %10.3f %10.2f %8.1f  %s\n",
+			shortFingerprint(t.Fingerprint), t.Runs, t.MeanMs, t.StdDevMs, t.MinSelfDeltaMs, t.MinPercentChange, query)
+	}
+}
+
+func shortFingerprint(fp string) string {
+	if len(fp) > 12 {
+		return fp[:12]
+	}
+	return fp
+}
+
+// saveThresholds merges the learned thresholds into diff.queries of the config file at path, leaving the
+// rest of the file as it is. It warns on stderr when comments inside diff.queries are dropped.
+func saveThresholds(path string, learned []history.Threshold) error {
+	data, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("read config: %w", err)
+	}
+	queries := map[string]config.QueryDiffConfig{}
+	for _, t := range learned {
+		queries[t.Fingerprint] = config.QueryDiffConfig{
+			Query:            t.Query,
+			MinSelfDeltaMs:   t.MinSelfDeltaMs,
+			MinPercentChange: t.MinPercentChange,
+			CriticalDeltaMs:  t.CriticalDeltaMs,
+			WarningDeltaMs:   t.WarningDeltaMs,
+		}
+	}
+	out, dropped, err := config.MergeDiffQueries(data, queries)
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	if dropped {
+		_, _ = fmt.Fprintf(os.Stderr, "Warning: the comments inside diff.queries of %s are not kept when the thresholds are written.\n", path)
+	}
+	if err := os.WriteFile(path, out, 0o644); err != nil {
+		return fmt.Errorf("write config: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/mickamy/xplain/internal/ignore"
+	"github.com/mickamy/xplain/internal/workload"
+)
+
+func workloadCommand(args []string) error {
+	fs := flag.NewFlagSet("workload", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	fs.Usage = func() {
+		_, _ = fmt.Fprintf(os.Stdout, "Usage: xplain workload --input 'plans/*.json' [--from explain|auto-explain|csvlog|pgbadger] [--format md|json] [--out file]\n\nOptions:\n")
+		fs.PrintDefaults()
+	}
+
+	var (
+		input      = fs.String("input", "", "Glob matching the EXPLAIN plans (JSON, YAML or text) to aggregate; further paths may follow as arguments")
+		from       = fs.String("from", "explain", "Input source: explain, or auto-explain, csvlog or pgbadger to aggregate every auto_explain plan of the matched logs or pgBadger JSON exports")
+		format     = fs.String("format", "md", "Output format (md or json)")
+		output     = fs.String("out", "", "Output path, or \"clipboard\" (stdout if omitted)")
+		configPath = fs.String("config", "", "Path to configuration file (JSON). Falls back to $XPLAIN_CONFIG")
+		ignoreFile = fs.String("ignore-file", ignore.DefaultPath, "File acknowledging insights by rule and node selector")
+	)
+
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			fs.SetOutput(os.Stdout)
+			fs.Usage()
+			return nil
+		}
+		return err
+	}
+	if err := applyConfigPath(*configPath); err != nil {
+		return err
+	}
+
+	paths, err := workloadPaths(*input, fs.Args())
+	if err != nil {
+		return err
+	}
+	acks, err := ignore.Load(*ignoreFile, *ignoreFile == ignore.DefaultPath)
+	if err != nil {
+		return err
+	}
+
+	if *from != "explain" && !logSources[*from] {
+		return fmt.Errorf("unknown source %q (expected explain, auto-explain, csvlog or pgbadger)", *from)
+	}
+
+	plans := make([]workload.Plan, 0, len(paths))
+	for _, path := range paths {
+		if *from != "explain" {
+			logged, err := loggedWorkloadPlans(path, *from)
+			if err != nil {
+				return fmt.Errorf("load %s: %w", path, err)
+			}
+			for _, plan := range logged {
+				plan.Analysis.Acknowledgements = acks
+				plans = append(plans, plan)
+			}
+			continue
+		}
+		_, analysis, err := loadAnalysis(path)
+		if err != nil {
+			return fmt.Errorf("load %s: %w", path, err)
+		}
+		analysis.Acknowledgements = acks
+		plans = append(plans, workload.Plan{Path: filepath.ToSlash(path), Analysis: analysis})
+	}
+	report := workload.Summarize(plans)
+
+	var content []byte
+	switch *format {
+	case "md", "markdown":
+		content = []byte(report.Markdown())
+	case "json":
+		if content, err = report.JSON(); err != nil {
+			return err
+		}
+		content = append(content, '\n')
+	default:
+		return fmt.Errorf("unsupported format %q", *format)
+	}
+	if *output == "" {
+		_, err = os.Stdout.Write(content)
+		return err
+	}
+	return writeOutput(*output, content)
+}
+
+// loggedWorkloadPlans analyzes every auto_explain plan of a log or pgBadger export, naming each after
+// the file and its position in it.
+func loggedWorkloadPlans(path, from string) ([]workload.Plan, error) {
+	data, err := readInput(path)
+	if err != nil {
+		return nil, err
+	}
+	logged, err := loggedPlans(path, data, from)
+	if err != nil {
+		return nil, err
+	}
+	plans := make([]workload.Plan, 0, len(logged))
+	for i, entry := range logged {
+		_, analysis, err := parseAnalysisReader(strings.NewReader(entry.Text), "auto")
+		if err != nil {
+			return nil, fmt.Errorf("auto_explain plan %s: %w", loggedPosition(i, entry), err)
+		}
+		plans = append(plans, workload.Plan{
+			Path:     fmt.Sprintf("%s (%s)", filepath.ToSlash(path), loggedPosition(i, entry)),
+			Analysis: analysis,
+		})
+	}
+	return plans, nil
+}
+
+// workloadPaths expands the --input glob and appends any positional paths, dropping duplicates.
+func workloadPaths(pattern string, extra []string) ([]string, error) {
+	var paths []string
+	if pattern != "" {
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --input pattern: %w", err)
+		}
+		sort.Strings(matches)
+		paths = append(paths, matches...)
+	}
+	paths = append(paths, extra...)
+
+	seen := map[string]bool{}
+	unique := paths[:0]
+	for _, p := range paths {
+		if !seen[p] {
+			seen[p] = true
+			unique = append(unique, p)
+		}
+	}
+	if len(unique) == 0 {
+		if pattern != "" {
+			return nil, fmt.Errorf("no plans match %q", pattern)
+		}
+		return nil, fmt.Errorf("--input is required")
+	}
+	return unique, nil
+}